@@ -0,0 +1,330 @@
+// Package api holds the OpenAPI spec of the server's http api and generates the typed client in zfcclient from it.
+// Only the parts of OpenAPI 3 the spec uses are read.
+package api
+
+import (
+	_ "embed"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+//go:embed openapi.yaml
+var Spec []byte
+
+type (
+	// Document an OpenAPI 3 document
+	Document struct {
+		OpenAPI    string     `yaml:"openapi"`
+		Info       Info       `yaml:"info"`
+		Servers    []Server   `yaml:"servers"`
+		Tags       []Tag      `yaml:"tags"`
+		Paths      Paths      `yaml:"paths"`
+		Components Components `yaml:"components"`
+	}
+
+	// Server where the api is served
+	Server struct {
+		URL string `yaml:"url"`
+	}
+
+	// Tag a group of operations
+	Tag struct {
+		Name        string `yaml:"name"`
+		Description string `yaml:"description"`
+	}
+
+	// Info what the api is
+	Info struct {
+		Title       string `yaml:"title"`
+		Description string `yaml:"description"`
+		Version     string `yaml:"version"`
+	}
+
+	// Paths the paths in the order they're in the spec
+	Paths []Path
+
+	// Path the operations on a path, in the order they're in the spec
+	Path struct {
+		Path       string
+		Operations []Operation
+	}
+
+	// Operation one method on a path, OperationID is the name of the handler that serves it
+	Operation struct {
+		Method      string                `yaml:"-"`
+		OperationID string                `yaml:"operationId"`
+		Summary     string                `yaml:"summary"`
+		Description string                `yaml:"description"`
+		Tags        []string              `yaml:"tags"`
+		Security    []map[string][]string `yaml:"security"`
+		Parameters  []Parameter           `yaml:"parameters"`
+		RequestBody *RequestBody          `yaml:"requestBody"`
+		Responses   map[string]Response   `yaml:"responses"`
+	}
+
+	// Parameter a path or query parameter, or a reference to one in the components
+	Parameter struct {
+		Ref         string  `yaml:"$ref"`
+		Name        string  `yaml:"name"`
+		In          string  `yaml:"in"`
+		Description string  `yaml:"description"`
+		Required    bool    `yaml:"required"`
+		Schema      *Schema `yaml:"schema"`
+	}
+
+	// RequestBody the json body of a request
+	RequestBody struct {
+		Required bool                 `yaml:"required"`
+		Content  map[string]MediaType `yaml:"content"`
+	}
+
+	// Response one status of an operation, or a reference to one in the components
+	Response struct {
+		Ref         string               `yaml:"$ref"`
+		Description string               `yaml:"description"`
+		Content     map[string]MediaType `yaml:"content"`
+	}
+
+	// MediaType the schema of a body
+	MediaType struct {
+		Schema *Schema `yaml:"schema"`
+	}
+
+	// Components what the paths refer to
+	Components struct {
+		Schemas         Properties                `yaml:"schemas"`
+		Parameters      map[string]Parameter      `yaml:"parameters"`
+		Responses       map[string]Response       `yaml:"responses"`
+		SecuritySchemes map[string]SecurityScheme `yaml:"securitySchemes"`
+	}
+
+	// SecurityScheme how a request is authenticated
+	SecurityScheme struct {
+		Type        string `yaml:"type"`
+		In          string `yaml:"in"`
+		Name        string `yaml:"name"`
+		Scheme      string `yaml:"scheme"`
+		Description string `yaml:"description"`
+	}
+
+	// Schema a json schema, or a reference to one in the components
+	Schema struct {
+		Ref                  string                `yaml:"$ref"`
+		Type                 string                `yaml:"type"`
+		Format               string                `yaml:"format"`
+		Description          string                `yaml:"description"`
+		Nullable             bool                  `yaml:"nullable"`
+		Enum                 []string              `yaml:"enum"`
+		Items                *Schema               `yaml:"items"`
+		Properties           Properties            `yaml:"properties"`
+		Required             []string              `yaml:"required"`
+		AdditionalProperties *AdditionalProperties `yaml:"additionalProperties"`
+		AllOf                []*Schema             `yaml:"allOf"`
+		OneOf                []*Schema             `yaml:"oneOf"`
+	}
+
+	// Properties named schemas in the order they're in the spec
+	Properties []Property
+
+	// Property a named schema
+	Property struct {
+		Name   string
+		Schema *Schema
+	}
+
+	// AdditionalProperties the schema of an object's values, nil for any json when it's just true
+	AdditionalProperties struct {
+		Schema *Schema
+	}
+)
+
+var methods = []string{"get", "put", "post", "delete", "patch"}
+
+// Load parses the embedded spec
+func Load() (*Document, error) {
+	return Parse(Spec)
+}
+
+// Parse parses an OpenAPI 3 document and checks everything it refers to is there
+func Parse(spec []byte) (*Document, error) {
+	doc := Document{}
+	err := yaml.UnmarshalStrict(spec, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, path := range doc.Paths {
+		for _, op := range path.Operations {
+			if op.OperationID == "" {
+				return nil, fmt.Errorf("%s %s has no operationId", op.Method, path.Path)
+			}
+			for _, param := range op.Parameters {
+				if _, err := doc.Parameter(param); err != nil {
+					return nil, fmt.Errorf("%s: %w", op.OperationID, err)
+				}
+			}
+			for code, response := range op.Responses {
+				if _, err := doc.Response(response); err != nil {
+					return nil, fmt.Errorf("%s %s: %w", op.OperationID, code, err)
+				}
+			}
+		}
+	}
+
+	return &doc, nil
+}
+
+// Parameter the parameter, looked up in the components if it's a reference
+func (doc *Document) Parameter(param Parameter) (Parameter, error) {
+	if param.Ref == "" {
+		return param, nil
+	}
+	found, ok := doc.Components.Parameters[strings.TrimPrefix(param.Ref, "#/components/parameters/")]
+	if !ok {
+		return param, fmt.Errorf("No parameter %s", param.Ref)
+	}
+	return found, nil
+}
+
+// Response the response, looked up in the components if it's a reference
+func (doc *Document) Response(response Response) (Response, error) {
+	if response.Ref == "" {
+		return response, nil
+	}
+	found, ok := doc.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+	if !ok {
+		return response, fmt.Errorf("No response %s", response.Ref)
+	}
+	return found, nil
+}
+
+// Schema the schema in the components a reference is to, nil if there's none
+func (doc *Document) Schema(ref string) *Schema {
+	for _, schema := range doc.Components.Schemas {
+		if "#/components/schemas/"+schema.Name == ref {
+			return schema.Schema
+		}
+	}
+	return nil
+}
+
+// SuccessResponse the operation's 2xx response and its status
+func (op Operation) SuccessResponse() (string, Response) {
+	for code, response := range op.Responses {
+		if strings.HasPrefix(code, "2") {
+			return code, response
+		}
+	}
+	return "", Response{}
+}
+
+// JSONSchema the schema of the json body, nil if there's none
+func (r Response) JSONSchema() *Schema {
+	return r.Content["application/json"].Schema
+}
+
+// JSONSchema the schema of the json body, nil if there's none
+func (r *RequestBody) JSONSchema() *Schema {
+	if r == nil {
+		return nil
+	}
+	return r.Content["application/json"].Schema
+}
+
+// RefName the name of the component schema a reference is to
+func RefName(ref string) string {
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+// IsRequired returns true if the object schema requires the property
+func (s *Schema) IsRequired(name string) bool {
+	for _, required := range s.Required {
+		if required == name {
+			return true
+		}
+	}
+	return false
+}
+
+// UnmarshalYAML keeps the operations in the order they're in the spec
+func (p *Paths) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	items := yaml.MapSlice{}
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		path := Path{Path: fmt.Sprint(item.Key)}
+		operations, ok := item.Value.(yaml.MapSlice)
+		if !ok {
+			return fmt.Errorf("Path %s isn't a map", path.Path)
+		}
+		for _, operation := range operations {
+			method := fmt.Sprint(operation.Key)
+			if !contains(methods, method) {
+				return fmt.Errorf("Path %s has unknown method %s", path.Path, method)
+			}
+			op := Operation{}
+			if err := remarshal(operation.Value, &op); err != nil {
+				return fmt.Errorf("%s %s: %w", method, path.Path, err)
+			}
+			op.Method = strings.ToUpper(method)
+			path.Operations = append(path.Operations, op)
+		}
+		*p = append(*p, path)
+	}
+
+	return nil
+}
+
+// UnmarshalYAML keeps the properties in the order they're in the spec
+func (p *Properties) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	items := yaml.MapSlice{}
+	if err := unmarshal(&items); err != nil {
+		return err
+	}
+
+	for _, item := range items {
+		property := Property{Name: fmt.Sprint(item.Key), Schema: &Schema{}}
+		if err := remarshal(item.Value, property.Schema); err != nil {
+			return fmt.Errorf("%s: %w", property.Name, err)
+		}
+		*p = append(*p, property)
+	}
+
+	return nil
+}
+
+// UnmarshalYAML reads true as any json, otherwise the schema of the values
+func (a *AdditionalProperties) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	anyJSON := false
+	if err := unmarshal(&anyJSON); err == nil {
+		if !anyJSON {
+			return fmt.Errorf("additionalProperties false isn't supported")
+		}
+		return nil
+	}
+
+	a.Schema = &Schema{}
+	return unmarshal(a.Schema)
+}
+
+// remarshal decodes a value from a MapSlice into the struct, strictly like the rest of the document
+func remarshal(value interface{}, out interface{}) error {
+	b, err := yaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return yaml.UnmarshalStrict(b, out)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,31 @@
+// Command gen writes the typed client of the api spec into the zfcclient package
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+
+	"github.com/reliablestaking/zombie-fight-club-server/api"
+	"github.com/sirupsen/logrus"
+)
+
+func main() {
+	out := flag.String("out", "zfcclient.gen.go", "file to write the client to")
+	pkg := flag.String("package", "zfcclient", "package of the client")
+	flag.Parse()
+
+	doc, err := api.Load()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading api spec")
+	}
+
+	src, err := api.GenerateClient(doc, *pkg)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error generating client")
+	}
+
+	err = ioutil.WriteFile(*out, src, 0644)
+	if err != nil {
+		logrus.WithError(err).Fatalf("Error writing %s", *out)
+	}
+}
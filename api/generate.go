@@ -0,0 +1,431 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ClientType the type in the client package the generated methods are on
+const ClientType = "ZfcClient"
+
+var pathParamRegexp = regexp.MustCompile(`{([^}]+)}`)
+
+// generator writes the client source, remembering which imports it used
+type generator struct {
+	doc     *Document
+	imports map[string]bool
+	buf     bytes.Buffer
+}
+
+// GenerateClient generates the types of the spec's schemas and a method on ZfcClient for every operation, the
+// source is for the pkg package next to the hand written ZfcClient
+func GenerateClient(doc *Document, pkg string) ([]byte, error) {
+	g := generator{doc: doc, imports: map[string]bool{}}
+
+	g.printf("type (\n")
+	for i, schema := range doc.Components.Schemas {
+		if i > 0 {
+			g.printf("\n")
+		}
+		err := g.schemaType(schema.Name, schema.Schema)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", schema.Name, err)
+		}
+	}
+	for _, path := range doc.Paths {
+		for _, op := range path.Operations {
+			err := g.paramsType(op)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op.OperationID, err)
+			}
+		}
+	}
+	g.printf(")\n")
+	types := append([]byte{}, g.buf.Bytes()...)
+
+	g.buf.Reset()
+	for _, path := range doc.Paths {
+		for _, op := range path.Operations {
+			err := g.method(path.Path, op)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", op.OperationID, err)
+			}
+		}
+	}
+	methods := g.buf.Bytes()
+
+	out := bytes.Buffer{}
+	fmt.Fprintf(&out, "// Code generated by go run ./api/gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	imports := make([]string, 0)
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+	fmt.Fprintf(&out, "import (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(&out, "%q\n", imp)
+	}
+	fmt.Fprintf(&out, ")\n\n")
+	out.Write(types)
+	out.WriteString("\n")
+	out.Write(methods)
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("Generated client doesn't parse: %w", err)
+	}
+	return src, nil
+}
+
+func (g *generator) printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// schemaType declares the struct of a component schema, refs in allOf are embedded
+func (g *generator) schemaType(name string, schema *Schema) error {
+	g.comment(name, schema.Description)
+	g.printf("%s struct {\n", name)
+
+	parts := []*Schema{schema}
+	if len(schema.AllOf) > 0 {
+		parts = schema.AllOf
+	}
+	for _, part := range parts {
+		if part.Ref != "" {
+			if g.doc.Schema(part.Ref) == nil {
+				return fmt.Errorf("No schema %s", part.Ref)
+			}
+			g.printf("%s\n", RefName(part.Ref))
+			continue
+		}
+		if part.Type != "object" {
+			return fmt.Errorf("Only object schemas become types")
+		}
+		for _, property := range part.Properties {
+			fieldType, err := g.goType(property.Schema, part.IsRequired(property.Name))
+			if err != nil {
+				return fmt.Errorf("%s: %w", property.Name, err)
+			}
+			tag := property.Name
+			if !part.IsRequired(property.Name) {
+				tag += ",omitempty"
+			}
+			if property.Schema.Description != "" {
+				g.printf("// %s\n", property.Schema.Description)
+			}
+			g.printf("%s %s `json:\"%s\"`\n", goName(property.Name), fieldType, tag)
+		}
+	}
+
+	g.printf("}\n")
+	return nil
+}
+
+// paramsType declares the query parameters of an operation, optional ones are left out when they're not set
+func (g *generator) paramsType(op Operation) error {
+	params, err := g.params(op, "query")
+	if err != nil || len(params) == 0 {
+		return err
+	}
+
+	g.printf("\n// %sParams the query parameters of %s\n", op.OperationID, op.OperationID)
+	g.printf("%sParams struct {\n", op.OperationID)
+	for _, param := range params {
+		paramType, err := g.goType(param.Schema, param.Required || param.Schema.Type == "string")
+		if err != nil {
+			return fmt.Errorf("%s: %w", param.Name, err)
+		}
+		if param.Description != "" {
+			g.printf("// %s\n", param.Description)
+		}
+		g.printf("%s %s\n", goName(param.Name), paramType)
+	}
+	g.printf("}\n")
+
+	return nil
+}
+
+// method writes the client method of an operation
+func (g *generator) method(path string, op Operation) error {
+	g.imports["context"] = true
+	g.imports["net/http"] = true
+
+	args := []string{"ctx context.Context"}
+
+	// path parameters, in the order they're in the path
+	pathFormat := path
+	pathArgs := make([]string, 0)
+	pathParams, err := g.params(op, "path")
+	if err != nil {
+		return err
+	}
+	for _, match := range pathParamRegexp.FindAllStringSubmatch(path, -1) {
+		var param *Parameter
+		for i := range pathParams {
+			if pathParams[i].Name == match[1] {
+				param = &pathParams[i]
+			}
+		}
+		if param == nil {
+			return fmt.Errorf("No path parameter %s", match[1])
+		}
+		argName := lowerFirst(goName(param.Name))
+		switch param.Schema.Type {
+		case "integer":
+			args = append(args, argName+" int")
+			pathFormat = strings.Replace(pathFormat, match[0], "%d", 1)
+			pathArgs = append(pathArgs, argName)
+		case "string":
+			args = append(args, argName+" string")
+			pathFormat = strings.Replace(pathFormat, match[0], "%s", 1)
+			pathArgs = append(pathArgs, "url.PathEscape("+argName+")")
+			g.imports["net/url"] = true
+		default:
+			return fmt.Errorf("Path parameter %s can't be a %s", param.Name, param.Schema.Type)
+		}
+	}
+	if len(pathParams) != len(pathArgs) {
+		return fmt.Errorf("Path parameters that aren't in the path")
+	}
+
+	queryParams, err := g.params(op, "query")
+	if err != nil {
+		return err
+	}
+	if len(queryParams) > 0 {
+		args = append(args, "params "+op.OperationID+"Params")
+	}
+
+	body := "nil"
+	if schema := op.RequestBody.JSONSchema(); schema != nil {
+		bodyType, err := g.goType(schema, true)
+		if err != nil {
+			return fmt.Errorf("request body: %w", err)
+		}
+		args = append(args, "body "+bodyType)
+		body = "body"
+	}
+
+	// the success response decides what's returned
+	code, response := op.SuccessResponse()
+	if code == "" {
+		return fmt.Errorf("No success response")
+	}
+	response, _ = g.doc.Response(response)
+	returns, out, zero, declareOut := "error", "nil", "", ""
+	if schema := response.JSONSchema(); schema != nil {
+		resultType, err := g.goType(schema, true)
+		if err != nil {
+			return fmt.Errorf("response: %w", err)
+		}
+		if schema.Ref != "" {
+			returns, out, zero, declareOut = "(*"+resultType+", error)", "out", "nil", "out := new("+resultType+")"
+		} else {
+			returns, out, zero, declareOut = "("+resultType+", error)", "&out", "nil", "var out "+resultType
+		}
+	}
+	g.comment(op.OperationID, op.Summary)
+	g.printf("func (client %s) %s(%s) %s {\n", ClientType, op.OperationID, strings.Join(args, ", "), returns)
+	if declareOut != "" {
+		g.printf("%s\n", declareOut)
+	}
+
+	pathExpr := fmt.Sprintf("%q", pathFormat)
+	if len(pathArgs) > 0 {
+		g.imports["fmt"] = true
+		pathExpr = fmt.Sprintf("fmt.Sprintf(%q, %s)", pathFormat, strings.Join(pathArgs, ", "))
+	}
+
+	query := "nil"
+	if len(queryParams) > 0 {
+		g.imports["net/url"] = true
+		query = "query"
+		g.printf("query := url.Values{}\n")
+		for _, param := range queryParams {
+			err := g.setQuery(param)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	call := fmt.Sprintf("client.do(ctx, http.Method%s, %s, %s, %s, %s)", methodName(op.Method), pathExpr, query, body, out)
+	if zero == "" {
+		g.printf("return %s\n}\n\n", call)
+		return nil
+	}
+	g.printf("err := %s\n", call)
+	g.printf("if err != nil {\nreturn %s, err\n}\n", zero)
+	g.printf("return out, nil\n}\n\n")
+
+	return nil
+}
+
+// setQuery writes setting a query parameter from the params struct
+func (g *generator) setQuery(param Parameter) error {
+	field := "params." + goName(param.Name)
+	value := field
+	switch param.Schema.Type {
+	case "string":
+		g.printf("if %s != \"\" {\n", field)
+	case "integer", "boolean":
+		g.imports["strconv"] = true
+		if !param.Required {
+			g.printf("if %s != nil {\n", field)
+			value = "*" + field
+		}
+		if param.Schema.Type == "integer" {
+			if param.Schema.Format == "int64" {
+				value = "strconv.FormatInt(" + value + ", 10)"
+			} else {
+				value = "strconv.Itoa(" + value + ")"
+			}
+		} else {
+			value = "strconv.FormatBool(" + value + ")"
+		}
+	default:
+		return fmt.Errorf("Query parameter %s can't be a %s", param.Name, param.Schema.Type)
+	}
+
+	g.printf("query.Set(%q, %s)\n", param.Name, value)
+	if param.Schema.Type == "string" || !param.Required {
+		g.printf("}\n")
+	}
+	return nil
+}
+
+// params the operation's parameters in the place, references looked up
+func (g *generator) params(op Operation, in string) ([]Parameter, error) {
+	params := make([]Parameter, 0)
+	for _, param := range op.Parameters {
+		param, err := g.doc.Parameter(param)
+		if err != nil {
+			return nil, err
+		}
+		if param.Schema == nil {
+			return nil, fmt.Errorf("Parameter %s has no schema", param.Name)
+		}
+		if param.In == in {
+			params = append(params, param)
+		}
+	}
+	return params, nil
+}
+
+// goType the go type of a schema. Optional values that have a meaningful zero value are pointers, strings, slices
+// and maps aren't and are left out when empty
+func (g *generator) goType(schema *Schema, required bool) (string, error) {
+	pointer := ""
+	if !required || schema.Nullable {
+		pointer = "*"
+	}
+
+	switch {
+	case schema.Ref != "":
+		if g.doc.Schema(schema.Ref) == nil {
+			return "", fmt.Errorf("No schema %s", schema.Ref)
+		}
+		return pointer + RefName(schema.Ref), nil
+	case len(schema.OneOf) > 0:
+		// one of several types, the caller knows which from what they asked for
+		g.imports["encoding/json"] = true
+		return "json.RawMessage", nil
+	}
+
+	switch schema.Type {
+	case "string":
+		if schema.Format == "date-time" {
+			g.imports["time"] = true
+			return pointer + "time.Time", nil
+		}
+		if schema.Nullable {
+			return "*string", nil
+		}
+		return "string", nil
+	case "integer":
+		if schema.Format == "int64" {
+			return pointer + "int64", nil
+		}
+		return pointer + "int", nil
+	case "number":
+		return pointer + "float64", nil
+	case "boolean":
+		return pointer + "bool", nil
+	case "array":
+		if schema.Items == nil {
+			return "", fmt.Errorf("Array without items")
+		}
+		itemType, err := g.goType(schema.Items, true)
+		if err != nil {
+			return "", err
+		}
+		return "[]" + itemType, nil
+	case "object":
+		if schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil {
+			valueType, err := g.goType(schema.AdditionalProperties.Schema, true)
+			if err != nil {
+				return "", err
+			}
+			return "map[string]" + valueType, nil
+		}
+		if len(schema.Properties) > 0 {
+			return "", fmt.Errorf("Inline objects need to be component schemas")
+		}
+		g.imports["encoding/json"] = true
+		return "json.RawMessage", nil
+	}
+
+	return "", fmt.Errorf("Unknown type %q", schema.Type)
+}
+
+// comment writes a doc comment starting with the name
+func (g *generator) comment(name string, description string) {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		g.printf("// %s\n", name)
+		return
+	}
+	lines := strings.Split(lowerFirst(description), "\n")
+	g.printf("// %s %s\n", name, lines[0])
+	for _, line := range lines[1:] {
+		g.printf("// %s\n", line)
+	}
+}
+
+// goName the exported go name of a json name, ids are ID
+func goName(name string) string {
+	runes := []rune(name)
+	runes[0] = unicode.ToUpper(runes[0])
+	name = string(runes)
+
+	out := strings.Builder{}
+	for i := 0; i < len(name); i++ {
+		if strings.HasPrefix(name[i:], "Id") && (i+2 == len(name) || unicode.IsUpper(rune(name[i+2]))) {
+			out.WriteString("ID")
+			i++
+			continue
+		}
+		out.WriteByte(name[i])
+	}
+	return out.String()
+}
+
+func lowerFirst(s string) string {
+	runes := []rune(s)
+	if len(runes) > 1 && unicode.IsUpper(runes[1]) {
+		// an initialism like ID stays as it is
+		return s
+	}
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+func methodName(method string) string {
+	return string(method[0]) + strings.ToLower(method[1:])
+}
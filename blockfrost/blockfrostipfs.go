@@ -70,11 +70,11 @@ func (client BlockfrostClient) IpfsAdd(filePath string) (*IpfsAddResponse, error
 	fileName := filepath.Base(filePath)
 
 	if fw, err = w.CreateFormFile("file", "alien.jpg"); err != nil {
-		logrus.Errorf("Error creating form file", err)
+		logrus.WithError(err).Error("Error creating form file")
 		return nil, err
 	}
 	if _, err = io.Copy(fw, file); err != nil {
-		logrus.Errorf("Error copying file", err)
+		logrus.WithError(err).Error("Error copying file")
 		return nil, err
 	}
 	w.Close()
@@ -88,7 +88,7 @@ func (client BlockfrostClient) IpfsAdd(filePath string) (*IpfsAddResponse, error
 
 	resp, err := client.HttpClient.Do(req)
 	if err != nil {
-		logrus.Errorf("Error posting request", err)
+		logrus.WithError(err).Error("Error posting request")
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -125,7 +125,7 @@ func (client BlockfrostClient) IpfsPin(hash string) error {
 
 	resp, err := client.HttpClient.Do(req)
 	if err != nil {
-		logrus.Errorf("Error posting request", err)
+		logrus.WithError(err).Error("Error posting request")
 		return err
 	}
 	defer resp.Body.Close()
@@ -160,7 +160,7 @@ func (client BlockfrostClient) SubmitTransaction(cborHex string) (string, error)
 
 	resp, err := client.HttpClient.Do(req)
 	if err != nil {
-		logrus.Errorf("Error posting request", err)
+		logrus.WithError(err).Error("Error posting request")
 		return "", err
 	}
 	defer resp.Body.Close()
@@ -223,7 +223,7 @@ func (client BlockfrostClient) GetTransaction(tx string) (*Transaction, error) {
 
 	resp, err := client.HttpClient.Do(req)
 	if err != nil {
-		logrus.Errorf("Error posting request", err)
+		logrus.WithError(err).Error("Error posting request")
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -0,0 +1,32 @@
+package cardanocli
+
+type (
+	// Builder builds, prices and signs transactions as text envelope files
+	Builder interface {
+		BuildTransaction(fileName string, txsIn []string, txsOut []string, ttl int, fee int, metadataFile string, mints []string, scriptFile string, scriptFile2 string) error
+		CalculateFee(txDraftFile string, txInCount int, txOutCount int, witnessCount int) (int, error)
+		SignTransaction(txFile string, signingKey1 string, signingKey2 string, signingKey3 string, outFile string) error
+		GetTransactionId(txFile string) (string, error)
+	}
+
+	// CliBuilder builder that shells out to cardano-cli
+	CliBuilder struct{}
+)
+
+var _ Builder = CliBuilder{}
+
+func (CliBuilder) BuildTransaction(fileName string, txsIn []string, txsOut []string, ttl int, fee int, metadataFile string, mints []string, scriptFile string, scriptFile2 string) error {
+	return BuildTransaction(fileName, txsIn, txsOut, ttl, fee, metadataFile, mints, scriptFile, scriptFile2)
+}
+
+func (CliBuilder) CalculateFee(txDraftFile string, txInCount int, txOutCount int, witnessCount int) (int, error) {
+	return CalculateFee(txDraftFile, txInCount, txOutCount, witnessCount)
+}
+
+func (CliBuilder) SignTransaction(txFile string, signingKey1 string, signingKey2 string, signingKey3 string, outFile string) error {
+	return SignTransaction(txFile, signingKey1, signingKey2, signingKey3, outFile)
+}
+
+func (CliBuilder) GetTransactionId(txFile string) (string, error) {
+	return GetTransactionId(txFile)
+}
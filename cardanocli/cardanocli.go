@@ -176,7 +176,7 @@ func SubmitTransaction(txFile string) error {
 }
 
 func GetTransactionId(txFile string) (string, error) {
-	logrus.Info("Submitting transaction")
+	logrus.Info("Getting transaction id")
 
 	args := make([]string, 0)
 	args = append(args, "transaction")
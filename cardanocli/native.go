@@ -0,0 +1,574 @@
+package cardanocli
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/reliablestaking/zombie-fight-club-server/hdwallet"
+	"github.com/reliablestaking/zombie-fight-club-server/internal/cbor"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/crypto/blake2b"
+)
+
+type (
+	// NativeBuilder builds and signs transactions in go, writing the same text envelopes cardano-cli does
+	NativeBuilder struct {
+		MinFeeA   int
+		MinFeeB   int
+		MaxTxSize int
+	}
+
+	textEnvelope struct {
+		Type        string `json:"type"`
+		Description string `json:"description"`
+		CborHex     string `json:"cborHex"`
+	}
+
+	nativeScript struct {
+		Type     string         `json:"type"`
+		KeyHash  string         `json:"keyHash"`
+		Slot     int64          `json:"slot"`
+		Required int64          `json:"required"`
+		Scripts  []nativeScript `json:"scripts"`
+	}
+
+	// asset quantity of one native asset
+	asset struct {
+		policyID []byte
+		name     []byte
+		quantity int64
+	}
+)
+
+const (
+	unwitnessedTxType = "Unwitnessed Tx BabbageEra"
+	witnessedTxType   = "Witnessed Tx BabbageEra"
+	txDescription     = "Ledger Cddl Format"
+
+	// size of one [vkey, signature] witness
+	vkeyWitnessSize = 101
+	// room for the witness set key and array header, and for the fee and ttl growing from the draft's zeros
+	feeSizeMargin = 24
+
+	maxMetadataStringLength = 64
+
+	// kL kR A cc
+	extendedSigningKeySize = 128
+)
+
+var _ Builder = NativeBuilder{}
+
+// BuildTransaction writes an unsigned tx with the mint scripts already in its witness set
+func (b NativeBuilder) BuildTransaction(fileName string, txsIn []string, txsOut []string, ttl int, fee int, metadataFile string, mints []string, scriptFile string, scriptFile2 string) error {
+	logrus.Info("Building transaction")
+
+	inputs, err := encodeTxIns(txsIn)
+	if err != nil {
+		return err
+	}
+
+	outputs := make([][]byte, 0)
+	for _, txOut := range txsOut {
+		output, err := encodeTxOut(txOut)
+		if err != nil {
+			return err
+		}
+		outputs = append(outputs, output)
+	}
+
+	body := [][2][]byte{
+		{cbor.Int(0), cbor.ArrayOf(inputs...)},
+		{cbor.Int(1), cbor.ArrayOf(outputs...)},
+		{cbor.Int(2), cbor.Int(int64(fee))},
+		{cbor.Int(3), cbor.Int(int64(ttl))},
+	}
+
+	auxData := []byte{0xf6}
+	if metadataFile != "" {
+		auxData, err = encodeMetadataFile(metadataFile)
+		if err != nil {
+			return err
+		}
+		body = append(body, [2][]byte{cbor.Int(7), cbor.ByteString(blake2b256(auxData))})
+	}
+
+	scripts := make([][]byte, 0)
+	policyIDs := make(map[string]bool)
+	for _, file := range []string{scriptFile, scriptFile2} {
+		if file == "" {
+			continue
+		}
+		script, err := loadNativeScript(file)
+		if err != nil {
+			return err
+		}
+		scripts = append(scripts, script)
+		policyIDs[hex.EncodeToString(policyID(script))] = true
+	}
+
+	if len(mints) > 0 {
+		minted := make([]asset, 0)
+		for _, mint := range mints {
+			mintAsset, err := parseAsset(mint)
+			if err != nil {
+				return err
+			}
+			if !policyIDs[hex.EncodeToString(mintAsset.policyID)] {
+				return fmt.Errorf("No mint script for policy %x", mintAsset.policyID)
+			}
+			minted = append(minted, *mintAsset)
+		}
+		body = append(body, [2][]byte{cbor.Int(9), encodeMultiAsset(minted)})
+	}
+
+	witnesses := make([][2][]byte, 0)
+	if len(scripts) > 0 {
+		witnesses = append(witnesses, [2][]byte{cbor.Int(1), cbor.ArrayOf(scripts...)})
+	}
+
+	tx := cbor.ArrayOf(cbor.MapOf(body...), cbor.MapOf(witnesses...), cbor.Bool(true), auxData)
+	if b.MaxTxSize > 0 && len(tx) > b.MaxTxSize {
+		return fmt.Errorf("Transaction of %d bytes is over the max size of %d", len(tx), b.MaxTxSize)
+	}
+
+	return writeTextEnvelope(fileName, unwitnessedTxType, tx)
+}
+
+// CalculateFee min fee for the tx once the given number of key witnesses are added
+func (b NativeBuilder) CalculateFee(txDraftFile string, txInCount int, txOutCount int, witnessCount int) (int, error) {
+	if b.MinFeeA == 0 {
+		return 0, fmt.Errorf("No protocol params set for fee calculation")
+	}
+
+	tx, _, err := readTextEnvelope(txDraftFile)
+	if err != nil {
+		return 0, err
+	}
+
+	size := len(tx) + witnessCount*vkeyWitnessSize + feeSizeMargin
+	fee := b.MinFeeA*size + b.MinFeeB
+	logrus.Infof("Calculated fee %d for a tx of about %d bytes", fee, size)
+
+	return fee, nil
+}
+
+// SignTransaction adds a vkey witness for each signing key, empty key files are skipped
+func (b NativeBuilder) SignTransaction(txFile string, signingKey1 string, signingKey2 string, signingKey3 string, outFile string) error {
+	logrus.Info("Signing transaction")
+
+	tx, _, err := readTextEnvelope(txFile)
+	if err != nil {
+		return err
+	}
+	parts, err := cbor.Split(tx, cbor.Array)
+	if err != nil {
+		return err
+	}
+	if len(parts) != 4 {
+		return fmt.Errorf("Expected 4 parts in tx but found %d", len(parts))
+	}
+	bodyHash := blake2b256(parts[0])
+
+	witnessMap, err := cbor.Split(parts[1], cbor.Map)
+	if err != nil {
+		return err
+	}
+
+	// keep any existing vkey witnesses and every other witness type
+	vkeyWitnesses := make([][]byte, 0)
+	otherWitnesses := make([][2][]byte, 0)
+	for i := 0; i < len(witnessMap); i += 2 {
+		if bytes.Equal(witnessMap[i], cbor.Int(0)) {
+			vkeyWitnesses, err = cbor.Split(witnessMap[i+1], cbor.Array)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		otherWitnesses = append(otherWitnesses, [2][]byte{witnessMap[i], witnessMap[i+1]})
+	}
+
+	for _, keyFile := range []string{signingKey1, signingKey2, signingKey3} {
+		if keyFile == "" {
+			continue
+		}
+		vkey, signature, err := signWithKeyFile(keyFile, bodyHash)
+		if err != nil {
+			return err
+		}
+		witness := cbor.ArrayOf(cbor.ByteString(vkey), cbor.ByteString(signature))
+		vkeyWitnesses = append(vkeyWitnesses, witness)
+	}
+
+	witnesses := append([][2][]byte{{cbor.Int(0), cbor.ArrayOf(vkeyWitnesses...)}}, otherWitnesses...)
+	signed := cbor.ArrayOf(parts[0], cbor.MapOf(witnesses...), parts[2], parts[3])
+
+	return writeTextEnvelope(outFile, witnessedTxType, signed)
+}
+
+// GetTransactionId hash of the tx body
+func (b NativeBuilder) GetTransactionId(txFile string) (string, error) {
+	tx, _, err := readTextEnvelope(txFile)
+	if err != nil {
+		return "", err
+	}
+	parts, err := cbor.Split(tx, cbor.Array)
+	if err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(blake2b256(parts[0])), nil
+}
+
+func blake2b256(data []byte) []byte {
+	hash := blake2b.Sum256(data)
+	return hash[:]
+}
+
+// policyID hash of a native script, tagged with 0 for native scripts
+func policyID(script []byte) []byte {
+	hasher, _ := blake2b.New(28, nil)
+	hasher.Write([]byte{0})
+	hasher.Write(script)
+	return hasher.Sum(nil)
+}
+
+func writeTextEnvelope(fileName string, envelopeType string, raw []byte) error {
+	envelope, err := json.MarshalIndent(textEnvelope{
+		Type:        envelopeType,
+		Description: txDescription,
+		CborHex:     hex.EncodeToString(raw),
+	}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, envelope, 0644)
+}
+
+func readTextEnvelope(fileName string) ([]byte, string, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, "", err
+	}
+
+	envelope := textEnvelope{}
+	err = json.Unmarshal(content, &envelope)
+	if err != nil {
+		return nil, "", err
+	}
+
+	raw, err := hex.DecodeString(envelope.CborHex)
+	if err != nil {
+		return nil, "", err
+	}
+
+	return raw, envelope.Type, nil
+}
+
+// signWithKeyFile signs with a normal ed25519 or an extended (hd wallet) signing key file, returning the vkey and signature
+func signWithKeyFile(fileName string, message []byte) ([]byte, []byte, error) {
+	encoded, _, err := readTextEnvelope(fileName)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	major, length, headLength, err := cbor.ReadHead(encoded)
+	if err != nil {
+		return nil, nil, err
+	}
+	if major != cbor.Bytes || len(encoded) != headLength+int(length) {
+		return nil, nil, fmt.Errorf("Signing key %s isn't a byte string", fileName)
+	}
+
+	switch length {
+	case ed25519.SeedSize:
+		key := ed25519.NewKeyFromSeed(encoded[headLength:])
+		return key.Public().(ed25519.PublicKey), ed25519.Sign(key, message), nil
+	case extendedSigningKeySize:
+		key, err := hdwallet.ParseExtendedSigningKey(encoded[headLength:])
+		if err != nil {
+			return nil, nil, err
+		}
+		vkey, err := key.PublicKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		signature, err := key.Sign(message)
+		return vkey, signature, err
+	}
+
+	return nil, nil, fmt.Errorf("Signing key %s isn't a 32 byte ed25519 or 128 byte extended key", fileName)
+}
+
+func loadNativeScript(fileName string) ([]byte, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	script := nativeScript{}
+	err = json.Unmarshal(content, &script)
+	if err != nil {
+		return nil, err
+	}
+
+	return encodeNativeScript(script)
+}
+
+func encodeNativeScript(script nativeScript) ([]byte, error) {
+	scripts := make([][]byte, 0)
+	for _, child := range script.Scripts {
+		encoded, err := encodeNativeScript(child)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, encoded)
+	}
+
+	switch script.Type {
+	case "sig":
+		keyHash, err := hex.DecodeString(script.KeyHash)
+		if err != nil || len(keyHash) != 28 {
+			return nil, fmt.Errorf("Invalid key hash %s in script", script.KeyHash)
+		}
+		return cbor.ArrayOf(cbor.Int(0), cbor.ByteString(keyHash)), nil
+	case "all":
+		return cbor.ArrayOf(cbor.Int(1), cbor.ArrayOf(scripts...)), nil
+	case "any":
+		return cbor.ArrayOf(cbor.Int(2), cbor.ArrayOf(scripts...)), nil
+	case "atLeast":
+		return cbor.ArrayOf(cbor.Int(3), cbor.Int(script.Required), cbor.ArrayOf(scripts...)), nil
+	case "after":
+		return cbor.ArrayOf(cbor.Int(4), cbor.Int(script.Slot)), nil
+	case "before":
+		return cbor.ArrayOf(cbor.Int(5), cbor.Int(script.Slot)), nil
+	}
+
+	return nil, fmt.Errorf("Unknown script type %s", script.Type)
+}
+
+// encodeTxIns encodes hash#index inputs, sorted the way the ledger orders the input set
+func encodeTxIns(txsIn []string) ([][]byte, error) {
+	sorted := append([]string{}, txsIn...)
+	sort.Strings(sorted)
+
+	inputs := make([][]byte, 0)
+	for _, txIn := range sorted {
+		parts := strings.Split(txIn, "#")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Invalid tx in %s", txIn)
+		}
+		txHash, err := hex.DecodeString(parts[0])
+		if err != nil || len(txHash) != 32 {
+			return nil, fmt.Errorf("Invalid tx hash in %s", txIn)
+		}
+		index, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("Invalid index in %s", txIn)
+		}
+		inputs = append(inputs, cbor.ArrayOf(cbor.ByteString(txHash), cbor.Int(int64(index))))
+	}
+
+	return inputs, nil
+}
+
+// encodeTxOut encodes address+lovelace[+quantity policy.name...]
+func encodeTxOut(txOut string) ([]byte, error) {
+	parts := strings.Split(txOut, "+")
+	if len(parts) < 2 {
+		return nil, fmt.Errorf("Invalid tx out %s", txOut)
+	}
+
+	_, address, err := hdwallet.Bech32Decode(parts[0])
+	if err != nil {
+		return nil, err
+	}
+	lovelace, err := strconv.ParseInt(strings.TrimSpace(parts[1]), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid lovelace in %s", txOut)
+	}
+
+	if len(parts) == 2 {
+		return cbor.ArrayOf(cbor.ByteString(address), cbor.Int(lovelace)), nil
+	}
+
+	assets := make([]asset, 0)
+	for _, part := range parts[2:] {
+		outAsset, err := parseAsset(part)
+		if err != nil {
+			return nil, err
+		}
+		assets = append(assets, *outAsset)
+	}
+
+	value := cbor.ArrayOf(cbor.Int(lovelace), encodeMultiAsset(assets))
+	return cbor.ArrayOf(cbor.ByteString(address), value), nil
+}
+
+// parseAsset parses "quantity policy.name", names are ascii like cardano-cli accepted them
+func parseAsset(value string) (*asset, error) {
+	fields := strings.Fields(value)
+	if len(fields) != 2 {
+		return nil, fmt.Errorf("Invalid asset %s", value)
+	}
+	quantity, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid asset quantity in %s", value)
+	}
+
+	policyAndName := strings.SplitN(fields[1], ".", 2)
+	policy, err := hex.DecodeString(policyAndName[0])
+	if err != nil || len(policy) != 28 {
+		return nil, fmt.Errorf("Invalid policy id in %s", value)
+	}
+	name := []byte{}
+	if len(policyAndName) == 2 {
+		name = []byte(policyAndName[1])
+	}
+
+	return &asset{policyID: policy, name: name, quantity: quantity}, nil
+}
+
+// encodeMultiAsset encodes policy => name => quantity with both levels sorted
+func encodeMultiAsset(assets []asset) []byte {
+	sort.Slice(assets, func(i, j int) bool {
+		if c := bytes.Compare(assets[i].policyID, assets[j].policyID); c != 0 {
+			return c < 0
+		}
+		return bytes.Compare(assets[i].name, assets[j].name) < 0
+	})
+
+	policies := make([][2][]byte, 0)
+	for i := 0; i < len(assets); {
+		names := make([][2][]byte, 0)
+		j := i
+		for ; j < len(assets) && bytes.Equal(assets[j].policyID, assets[i].policyID); j++ {
+			names = append(names, [2][]byte{cbor.ByteString(assets[j].name), cbor.Int(assets[j].quantity)})
+		}
+		policies = append(policies, [2][]byte{cbor.ByteString(assets[i].policyID), cbor.MapOf(names...)})
+		i = j
+	}
+
+	return cbor.MapOf(policies...)
+}
+
+// encodeMetadataFile converts tx metadata json the way cardano-cli's no schema mode does, keeping key order
+func encodeMetadataFile(fileName string) ([]byte, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(content))
+	decoder.UseNumber()
+
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		return nil, fmt.Errorf("Metadata must be a json object")
+	}
+
+	labels := make([][2][]byte, 0)
+	for decoder.More() {
+		key, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		label, err := strconv.ParseUint(key.(string), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Metadata label %s isn't a number", key)
+		}
+		value, err := encodeMetadataValue(decoder)
+		if err != nil {
+			return nil, err
+		}
+		labels = append(labels, [2][]byte{cbor.Head(cbor.Uint, label), value})
+	}
+
+	return cbor.MapOf(labels...), nil
+}
+
+func encodeMetadataValue(decoder *json.Decoder) ([]byte, error) {
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return nil, fmt.Errorf("Unexpected end of metadata")
+	} else if err != nil {
+		return nil, err
+	}
+
+	switch value := token.(type) {
+	case json.Delim:
+		items := make([][]byte, 0)
+		pairs := make([][2][]byte, 0)
+		for decoder.More() {
+			if value == '{' {
+				key, err := decoder.Token()
+				if err != nil {
+					return nil, err
+				}
+				encodedKey, err := encodeMetadataString(key.(string))
+				if err != nil {
+					return nil, err
+				}
+				encodedValue, err := encodeMetadataValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				pairs = append(pairs, [2][]byte{encodedKey, encodedValue})
+			} else {
+				item, err := encodeMetadataValue(decoder)
+				if err != nil {
+					return nil, err
+				}
+				items = append(items, item)
+			}
+		}
+		// closing delimiter
+		if _, err := decoder.Token(); err != nil {
+			return nil, err
+		}
+		if value == '{' {
+			return cbor.MapOf(pairs...), nil
+		}
+		return cbor.ArrayOf(items...), nil
+	case json.Number:
+		n, err := value.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("Metadata number %s isn't an integer", value)
+		}
+		return cbor.Int(n), nil
+	case string:
+		return encodeMetadataString(value)
+	}
+
+	return nil, fmt.Errorf("Metadata value %v isn't supported", token)
+}
+
+// encodeMetadataString strings starting with 0x are bytes, both are limited to 64 bytes
+func encodeMetadataString(value string) ([]byte, error) {
+	if strings.HasPrefix(value, "0x") {
+		decoded, err := hex.DecodeString(value[2:])
+		if err == nil {
+			if len(decoded) > maxMetadataStringLength {
+				return nil, fmt.Errorf("Metadata bytes %s are over %d bytes", value, maxMetadataStringLength)
+			}
+			return cbor.ByteString(decoded), nil
+		}
+	}
+
+	if len(value) > maxMetadataStringLength {
+		return nil, fmt.Errorf("Metadata string %s is over %d bytes", value, maxMetadataStringLength)
+	}
+	return cbor.TextString(value), nil
+}
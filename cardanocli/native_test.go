@@ -0,0 +1,161 @@
+package cardanocli
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/reliablestaking/zombie-fight-club-server/hdwallet"
+	"github.com/reliablestaking/zombie-fight-club-server/internal/cbor"
+	"golang.org/x/crypto/blake2b"
+)
+
+const testAddress = "addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x"
+
+func TestNativeBuilderMintRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	// key and the policy it controls
+	seed := make([]byte, ed25519.SeedSize)
+	seed[0] = 1
+	key := ed25519.NewKeyFromSeed(seed)
+	keyHash, _ := blake2b.New(28, nil)
+	keyHash.Write(key.Public().(ed25519.PublicKey))
+
+	writeFile(t, dir+"/payment.skey", fmt.Sprintf(`{"type":"PaymentSigningKeyShelley_ed25519","description":"","cborHex":"5820%x"}`, seed))
+	writeFile(t, dir+"/policy.script", fmt.Sprintf(`{"type":"all","scripts":[{"type":"before","slot":90000000},{"type":"sig","keyHash":"%x"}]}`, keyHash.Sum(nil)))
+	script, err := loadNativeScript(dir + "/policy.script")
+	if err != nil {
+		t.Fatalf("Error loading script %v", err)
+	}
+	policy := hex.EncodeToString(policyID(script))
+
+	writeFile(t, dir+"/metadata.json", fmt.Sprintf(`{"721":{"%s":{"Fight1":{"name":"Fight #1","image":["ipfs://","cid"],"number":1}}}}`, policy))
+
+	txIn := []string{"8d6e7b0b5b0bd56f3b3e2a9c6ac9c7f6d2b1b3f8b0e9e1a2c3d4e5f6a7b8c9d0#1"}
+	txOut := func(royalty int) []string {
+		return []string{fmt.Sprintf("%s+%d", testAddress, royalty), fmt.Sprintf("%s+%d+1 %s.Fight1", testAddress, 1500000, policy)}
+	}
+	mints := []string{fmt.Sprintf("1 %s.Fight1", policy)}
+
+	builder := NativeBuilder{MinFeeA: 44, MinFeeB: 155381, MaxTxSize: 16384}
+	err = builder.BuildTransaction(dir+"/tx.draft", txIn, txOut(0), 0, 0, dir+"/metadata.json", mints, dir+"/policy.script", "")
+	if err != nil {
+		t.Fatalf("Error building draft %v", err)
+	}
+	fee, err := builder.CalculateFee(dir+"/tx.draft", 1, 2, 1)
+	if err != nil {
+		t.Fatalf("Error calculating fee %v", err)
+	}
+
+	err = builder.BuildTransaction(dir+"/tx.raw", txIn, txOut(10000000-1500000-fee), 50000000, fee, dir+"/metadata.json", mints, dir+"/policy.script", "")
+	if err != nil {
+		t.Fatalf("Error building tx %v", err)
+	}
+	err = builder.SignTransaction(dir+"/tx.raw", dir+"/payment.skey", "", "", dir+"/tx.signed")
+	if err != nil {
+		t.Fatalf("Error signing tx %v", err)
+	}
+
+	// the signed tx is fully paid for by the estimated fee
+	signed, envelopeType, _ := readTextEnvelope(dir + "/tx.signed")
+	if envelopeType != witnessedTxType {
+		t.Errorf("Expected %s but got %s", witnessedTxType, envelopeType)
+	}
+	if minFee := builder.MinFeeA*len(signed) + builder.MinFeeB; fee < minFee {
+		t.Errorf("Fee %d is below the min fee %d of the signed tx", fee, minFee)
+	}
+
+	// same hex flow the engine uses
+	content, _ := os.ReadFile(dir + "/tx.signed")
+	signedTx := struct {
+		Hex string `json:"cborHex"`
+	}{}
+	json.Unmarshal(content, &signedTx)
+	if signedTx.Hex != hex.EncodeToString(signed) {
+		t.Error("Signed tx hex doesn't round trip")
+	}
+
+	parts, err := cbor.Split(signed, cbor.Array)
+	if err != nil || len(parts) != 4 {
+		t.Fatalf("Expected 4 tx parts but got %d / %v", len(parts), err)
+	}
+
+	txID, _ := builder.GetTransactionId(dir + "/tx.signed")
+	if rawID, _ := builder.GetTransactionId(dir + "/tx.raw"); rawID != txID {
+		t.Errorf("Signing changed the tx id from %s to %s", rawID, txID)
+	}
+
+	witnesses, _ := cbor.Split(parts[1], cbor.Map)
+	if len(witnesses) != 4 {
+		t.Fatalf("Expected vkey and script witnesses but got %d items", len(witnesses))
+	}
+	vkeyWitnesses, _ := cbor.Split(witnesses[1], cbor.Array)
+	witness, _ := cbor.Split(vkeyWitnesses[0], cbor.Array)
+	bodyHash, _ := hex.DecodeString(txID)
+	if !ed25519.Verify(key.Public().(ed25519.PublicKey), bodyHash, witness[1][2:]) {
+		t.Error("Witness signature doesn't verify against the tx id")
+	}
+
+	// metadata hash in the body matches the aux data
+	body, _ := cbor.Split(parts[0], cbor.Map)
+	auxHash := blake2b.Sum256(parts[3])
+	if hex.EncodeToString(body[9]) != "5820"+hex.EncodeToString(auxHash[:]) {
+		t.Error("Aux data hash doesn't match the metadata")
+	}
+}
+
+func TestNativeBuilderSignsWithExtendedKey(t *testing.T) {
+	dir := t.TempDir()
+
+	// derived the way per fight payment addresses are
+	accountKey := &hdwallet.ExtendedPrivateKey{Key: bytes.Repeat([]byte{0x48}, 64), ChainCode: bytes.Repeat([]byte{1}, 32)}
+	public, _ := accountKey.PublicKey()
+	wallet := hdwallet.Wallet{Account: hdwallet.ExtendedPublicKey{Key: public, ChainCode: accountKey.ChainCode}, AccountKey: accountKey}
+	if err := wallet.WritePaymentSigningKey(3, dir+"/payment.skey"); err != nil {
+		t.Fatalf("Error writing key %v", err)
+	}
+
+	builder := NativeBuilder{MinFeeA: 44, MinFeeB: 155381}
+	txIn := []string{"8d6e7b0b5b0bd56f3b3e2a9c6ac9c7f6d2b1b3f8b0e9e1a2c3d4e5f6a7b8c9d0#0"}
+	if err := builder.BuildTransaction(dir+"/tx.raw", txIn, []string{testAddress + "+5000000"}, 1000, 200000, "", nil, "", ""); err != nil {
+		t.Fatalf("Error building tx %v", err)
+	}
+	if err := builder.SignTransaction(dir+"/tx.raw", dir+"/payment.skey", "", "", dir+"/tx.signed"); err != nil {
+		t.Fatalf("Error signing tx %v", err)
+	}
+
+	signed, _, _ := readTextEnvelope(dir + "/tx.signed")
+	parts, _ := cbor.Split(signed, cbor.Array)
+	witnesses, _ := cbor.Split(parts[1], cbor.Map)
+	vkeyWitnesses, _ := cbor.Split(witnesses[1], cbor.Array)
+	witness, _ := cbor.Split(vkeyWitnesses[0], cbor.Array)
+
+	signingKey, _ := wallet.PaymentSigningKey(3)
+	vkey, _ := signingKey.PublicKey()
+	if !bytes.Equal(witness[0][2:], vkey) || !ed25519.Verify(vkey, blake2b256(parts[0]), witness[1][2:]) {
+		t.Error("Witness doesn't verify against the derived key")
+	}
+}
+
+func TestNativeBuilderRejectsUnknownPolicy(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir+"/policy.script", `{"type":"before","slot":1}`)
+
+	builder := NativeBuilder{MinFeeA: 44, MinFeeB: 155381}
+	mints := []string{"1 aabbccddeeff00112233445566778899aabbccddeeff001122334455.Fight1"}
+	err := builder.BuildTransaction(dir+"/tx.raw", nil, nil, 0, 0, "", mints, dir+"/policy.script", "")
+	if err == nil {
+		t.Error("Expected error minting without the policy script")
+	}
+}
+
+func writeFile(t *testing.T, fileName string, content string) {
+	if err := os.WriteFile(fileName, []byte(content), 0644); err != nil {
+		t.Fatalf("Error writing %s %v", fileName, err)
+	}
+}
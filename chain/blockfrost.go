@@ -0,0 +1,129 @@
+package chain
+
+import (
+	"context"
+
+	bfg "github.com/blockfrost/blockfrost-go"
+	"github.com/reliablestaking/zombie-fight-club-server/blockfrost"
+)
+
+type (
+	// BlockfrostChain chain provider backed by the blockfrost api
+	BlockfrostChain struct {
+		Api    bfg.APIClient
+		Client blockfrost.BlockfrostClient
+	}
+)
+
+var _ ChainProvider = BlockfrostChain{}
+
+// NewBlockfrostChain builds a chain provider from both blockfrost clients
+func NewBlockfrostChain(api bfg.APIClient, client blockfrost.BlockfrostClient) BlockfrostChain {
+	return BlockfrostChain{
+		Api:    api,
+		Client: client,
+	}
+}
+
+func (c BlockfrostChain) AddressUTXOs(ctx context.Context, address string) ([]Utxo, error) {
+	allUtxos := make([]Utxo, 0)
+
+	page := 1
+	for {
+		utxos, err := c.Api.AddressUTXOs(ctx, address, bfg.APIQueryParams{Count: 100, Page: page})
+		page++
+		if err != nil {
+			return nil, err
+		}
+
+		if len(utxos) == 0 {
+			return allUtxos, nil
+		}
+
+		for _, utxo := range utxos {
+			amounts := make([]Amount, 0)
+			for _, amount := range utxo.Amount {
+				amounts = append(amounts, Amount{Unit: amount.Unit, Quantity: amount.Quantity})
+			}
+
+			allUtxos = append(allUtxos, Utxo{
+				TxHash:      utxo.TxHash,
+				OutputIndex: utxo.OutputIndex,
+				Address:     address,
+				Amount:      amounts,
+			})
+		}
+	}
+}
+
+func (c BlockfrostChain) TransactionInputs(ctx context.Context, txHash string) ([]Utxo, error) {
+	txUtxos, err := c.Api.TransactionUTXOs(ctx, txHash)
+	if err != nil {
+		return nil, err
+	}
+
+	inputs := make([]Utxo, 0)
+	for _, input := range txUtxos.Inputs {
+		amounts := make([]Amount, 0)
+		for _, amount := range input.Amount {
+			amounts = append(amounts, Amount{Unit: amount.Unit, Quantity: amount.Quantity})
+		}
+
+		inputs = append(inputs, Utxo{
+			TxHash:      input.TxHash,
+			OutputIndex: int(input.OutputIndex),
+			Address:     input.Address,
+			Amount:      amounts,
+		})
+	}
+
+	return inputs, nil
+}
+
+func (c BlockfrostChain) Tip(ctx context.Context) (*Tip, error) {
+	block, err := c.Api.BlockLatest(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Tip{Hash: block.Hash, Height: block.Height, Slot: block.Slot}, nil
+}
+
+func (c BlockfrostChain) AssetHolders(ctx context.Context, asset string) ([]AssetHolder, error) {
+	addresses, err := c.Client.GetAddressesForAsset(asset)
+	if err != nil {
+		return nil, err
+	}
+	if addresses == nil {
+		return nil, nil
+	}
+
+	holders := make([]AssetHolder, 0)
+	for _, address := range addresses {
+		holders = append(holders, AssetHolder{Address: address.Address, Quantity: address.Quantity})
+	}
+
+	return holders, nil
+}
+
+func (c BlockfrostChain) SubmitTransaction(ctx context.Context, cborHex string) (string, error) {
+	return c.Client.SubmitTransaction(cborHex)
+}
+
+func (c BlockfrostChain) TransactionConfirmed(ctx context.Context, txHash string) (bool, error) {
+	transaction, err := c.Client.GetTransaction(txHash)
+	if err != nil {
+		return false, err
+	}
+
+	return transaction != nil, nil
+}
+
+func (c BlockfrostChain) ProtocolParams(ctx context.Context) (*ProtocolParams, error) {
+	params, err := c.Api.LatestEpochParameters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ProtocolParams{MinFeeA: params.MinFeeA, MinFeeB: params.MinFeeB, MaxTxSize: params.MaxTxSize}, nil
+}
@@ -0,0 +1,62 @@
+package chain
+
+import (
+	"context"
+)
+
+type (
+	// ChainProvider everything the server needs to read from and write to the chain
+	ChainProvider interface {
+		// AddressUTXOs gets every unspent output sitting at an address
+		AddressUTXOs(ctx context.Context, address string) ([]Utxo, error)
+		// TransactionInputs gets the outputs a transaction spent, i.e. who paid
+		TransactionInputs(ctx context.Context, txHash string) ([]Utxo, error)
+		// Tip gets the latest block
+		Tip(ctx context.Context) (*Tip, error)
+		// AssetHolders gets the addresses holding an asset, nil if the asset doesn't exist
+		AssetHolders(ctx context.Context, asset string) ([]AssetHolder, error)
+		// SubmitTransaction submits a signed tx and returns what the backend responded with
+		SubmitTransaction(ctx context.Context, cborHex string) (string, error)
+		// TransactionConfirmed returns true once the tx is in a block
+		TransactionConfirmed(ctx context.Context, txHash string) (bool, error)
+		// ProtocolParams gets the current fee and size parameters
+		ProtocolParams(ctx context.Context) (*ProtocolParams, error)
+	}
+
+	// Utxo a transaction output
+	Utxo struct {
+		TxHash      string
+		OutputIndex int
+		Address     string
+		Amount      []Amount
+	}
+
+	// Amount quantity of a unit, lovelace or policy id + hex asset name
+	Amount struct {
+		Unit     string
+		Quantity string
+	}
+
+	// Tip latest block
+	Tip struct {
+		Hash   string
+		Height int
+		Slot   int
+	}
+
+	// ProtocolParams protocol parameters needed to build transactions
+	ProtocolParams struct {
+		MinFeeA   int
+		MinFeeB   int
+		MaxTxSize int
+	}
+
+	// AssetHolder address holding an asset
+	AssetHolder struct {
+		Address  string
+		Quantity string
+	}
+)
+
+// Lovelace unit for ada amounts
+const Lovelace = "lovelace"
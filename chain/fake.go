@@ -0,0 +1,207 @@
+package chain
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+type (
+	// FakeChain in-process chain for tests, payments and confirmations are scripted by the test
+	FakeChain struct {
+		mu        sync.Mutex
+		slot      int
+		txCount   int
+		utxos     map[string][]Utxo
+		inputs    map[string][]Utxo
+		assets    map[string][]AssetHolder
+		submitted []string
+		confirmed map[string]bool
+
+		// SubmitError returned from SubmitTransaction when set
+		SubmitError error
+	}
+)
+
+var _ ChainProvider = &FakeChain{}
+
+// NewFakeChain builds an empty chain at slot 1000
+func NewFakeChain() *FakeChain {
+	return &FakeChain{
+		slot:      1000,
+		utxos:     make(map[string][]Utxo),
+		inputs:    make(map[string][]Utxo),
+		assets:    make(map[string][]AssetHolder),
+		confirmed: make(map[string]bool),
+	}
+}
+
+// Pay adds a confirmed tx sending lovelace from one address to another and returns the new utxo
+func (c *FakeChain) Pay(fromAddress string, toAddress string, lovelace int) Utxo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.txCount++
+	txHash := fmt.Sprintf("%064x", c.txCount)
+
+	// the paying side is a made up utxo, it only matters for where refunds go
+	c.inputs[txHash] = []Utxo{{
+		TxHash:  fmt.Sprintf("%064x", 1<<32+c.txCount),
+		Address: fromAddress,
+		Amount:  []Amount{{Unit: Lovelace, Quantity: strconv.Itoa(lovelace)}},
+	}}
+
+	utxo := Utxo{
+		TxHash:      txHash,
+		OutputIndex: 0,
+		Address:     toAddress,
+		Amount:      []Amount{{Unit: Lovelace, Quantity: strconv.Itoa(lovelace)}},
+	}
+	c.utxos[toAddress] = append(c.utxos[toAddress], utxo)
+	c.confirmed[txHash] = true
+
+	return utxo
+}
+
+// AddOutput adds an output of a tx the server built, such as its change, and confirms the tx
+func (c *FakeChain) AddOutput(txHash string, outputIndex int, fromAddress string, toAddress string, lovelace int) Utxo {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inputs[txHash] = []Utxo{{TxHash: fmt.Sprintf("%064x", 1<<33+c.txCount), Address: fromAddress}}
+
+	utxo := Utxo{
+		TxHash:      txHash,
+		OutputIndex: outputIndex,
+		Address:     toAddress,
+		Amount:      []Amount{{Unit: Lovelace, Quantity: strconv.Itoa(lovelace)}},
+	}
+	c.utxos[toAddress] = append(c.utxos[toAddress], utxo)
+	c.confirmed[txHash] = true
+
+	return utxo
+}
+
+// AddAsset puts a native asset on an existing utxo
+func (c *FakeChain) AddAsset(txHash string, outputIndex int, amount Amount) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for address, utxos := range c.utxos {
+		for i, utxo := range utxos {
+			if utxo.TxHash == txHash && utxo.OutputIndex == outputIndex {
+				c.utxos[address][i].Amount = append(utxo.Amount, amount)
+			}
+		}
+	}
+}
+
+// Spend removes a utxo, as if a tx consumed it
+func (c *FakeChain) Spend(txHash string, outputIndex int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for address, utxos := range c.utxos {
+		remaining := make([]Utxo, 0)
+		for _, utxo := range utxos {
+			if utxo.TxHash != txHash || utxo.OutputIndex != outputIndex {
+				remaining = append(remaining, utxo)
+			}
+		}
+		c.utxos[address] = remaining
+	}
+}
+
+// SetAssetHolder makes an address the only holder of an asset
+func (c *FakeChain) SetAssetHolder(asset string, address string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.assets[asset] = []AssetHolder{{Address: address, Quantity: "1"}}
+}
+
+// AdvanceSlots moves the tip forward
+func (c *FakeChain) AdvanceSlots(slots int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.slot += slots
+}
+
+// Confirm puts a submitted tx in a block
+func (c *FakeChain) Confirm(txHash string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.confirmed[txHash] = true
+}
+
+// Submitted gets the cbor of every submitted tx, in order
+func (c *FakeChain) Submitted() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]string{}, c.submitted...)
+}
+
+func (c *FakeChain) AddressUTXOs(ctx context.Context, address string) ([]Utxo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return append([]Utxo{}, c.utxos[address]...), nil
+}
+
+func (c *FakeChain) TransactionInputs(ctx context.Context, txHash string) ([]Utxo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	inputs, found := c.inputs[txHash]
+	if !found {
+		return nil, fmt.Errorf("Transaction %s not found", txHash)
+	}
+
+	return append([]Utxo{}, inputs...), nil
+}
+
+func (c *FakeChain) Tip(ctx context.Context) (*Tip, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return &Tip{Hash: fmt.Sprintf("%064x", c.slot), Height: c.slot / 20, Slot: c.slot}, nil
+}
+
+func (c *FakeChain) AssetHolders(ctx context.Context, asset string) ([]AssetHolder, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	holders, found := c.assets[asset]
+	if !found {
+		return nil, nil
+	}
+
+	return append([]AssetHolder{}, holders...), nil
+}
+
+func (c *FakeChain) SubmitTransaction(ctx context.Context, cborHex string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.SubmitError != nil {
+		return "", c.SubmitError
+	}
+	c.submitted = append(c.submitted, cborHex)
+
+	return cborHex, nil
+}
+
+func (c *FakeChain) TransactionConfirmed(ctx context.Context, txHash string) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.confirmed[txHash], nil
+}
+
+func (c *FakeChain) ProtocolParams(ctx context.Context) (*ProtocolParams, error) {
+	return &ProtocolParams{MinFeeA: 44, MinFeeB: 155381, MaxTxSize: 16384}, nil
+}
@@ -0,0 +1,187 @@
+package cip8
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"fmt"
+
+	"github.com/reliablestaking/zombie-fight-club-server/hdwallet"
+	"github.com/reliablestaking/zombie-fight-club-server/internal/cbor"
+	"golang.org/x/crypto/blake2b"
+)
+
+// CIP-8 message signing as done by CIP-30 wallets' signData, which return a COSE_Sign1 signature and the COSE_Key
+// with the public key it was signed with
+
+const (
+	// COSE header and key labels
+	headerAlgorithm = 1
+	keyType         = 1
+	keyAlgorithm    = 3
+	keyCurve        = -1
+	keyX            = -2
+
+	algorithmEdDSA = -8
+	keyTypeOKP     = 1
+	curveEd25519   = 6
+
+	// CIP-19 address type of a reward address with a key hash credential
+	addressTypeReward = 0x0e
+)
+
+type (
+	// SignedData what a verified signature was over
+	SignedData struct {
+		// raw bytes of the address the wallet signed with
+		Address []byte
+		Payload []byte
+		// the ed25519 key the address's credential is the hash of
+		PublicKey []byte
+	}
+)
+
+// Verify checks that the COSE_Sign1 is a signature by the COSE_Key's public key over its payload and that the key is
+// the one behind the address in the protected header. Both are the raw cbor bytes returned by signData
+func Verify(coseSign1 []byte, coseKey []byte) (*SignedData, error) {
+	publicKey, err := parseKey(coseKey)
+	if err != nil {
+		return nil, err
+	}
+
+	decoded, err := cbor.Decode(coseSign1)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid COSE_Sign1: %v", err)
+	}
+	parts, ok := decoded.([]interface{})
+	if !ok || len(parts) != 4 {
+		return nil, fmt.Errorf("COSE_Sign1 isn't an array of 4")
+	}
+	protected, ok := parts[0].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("COSE_Sign1 protected header isn't a byte string")
+	}
+	unprotected, ok := parts[1].(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("COSE_Sign1 unprotected header isn't a map")
+	}
+	payload, ok := parts[2].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("COSE_Sign1 payload is detached or isn't a byte string")
+	}
+	signature, ok := parts[3].([]byte)
+	if !ok || len(signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("COSE_Sign1 signature isn't an ed25519 signature")
+	}
+
+	// signData puts the address in the protected header so it's signed too
+	decoded, err = cbor.Decode(protected)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid COSE_Sign1 protected header: %v", err)
+	}
+	headers, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("COSE_Sign1 protected header isn't a map")
+	}
+	if headers[int64(headerAlgorithm)] != int64(algorithmEdDSA) {
+		return nil, fmt.Errorf("COSE_Sign1 algorithm isn't EdDSA")
+	}
+	address, ok := headers["address"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("COSE_Sign1 has no address")
+	}
+	if hashed, _ := unprotected["hashed"].(bool); hashed {
+		return nil, fmt.Errorf("Hashed payloads aren't supported")
+	}
+
+	// Sig_structure = ["Signature1", protected, external_aad, payload]
+	sigStructure := cbor.ArrayOf(cbor.TextString("Signature1"), cbor.ByteString(protected), cbor.ByteString([]byte{}), cbor.ByteString(payload))
+	if !ed25519.Verify(publicKey, sigStructure, signature) {
+		return nil, fmt.Errorf("Signature doesn't match")
+	}
+
+	// shelley addresses with a key hash as their first credential, base, pointer, enterprise and reward
+	if len(address) < 29 {
+		return nil, fmt.Errorf("Address is too short")
+	}
+	switch address[0] >> 4 {
+	case 0x00, 0x02, 0x04, 0x06, addressTypeReward:
+	default:
+		return nil, fmt.Errorf("Address type %d isn't signed by a key", address[0]>>4)
+	}
+	if !bytes.Equal(blake2b224(publicKey), address[1:29]) {
+		return nil, fmt.Errorf("Address wasn't signed by its own key")
+	}
+
+	return &SignedData{Address: address, Payload: payload, PublicKey: publicKey}, nil
+}
+
+// Sign signs a payload for an address the way signData does, returning the COSE_Sign1 and COSE_Key
+func Sign(privateKey ed25519.PrivateKey, address []byte, payload []byte) ([]byte, []byte) {
+	publicKey := privateKey.Public().(ed25519.PublicKey)
+
+	protected := cbor.MapOf(
+		[2][]byte{cbor.Int(headerAlgorithm), cbor.Int(algorithmEdDSA)},
+		[2][]byte{cbor.TextString("address"), cbor.ByteString(address)},
+	)
+	sigStructure := cbor.ArrayOf(cbor.TextString("Signature1"), cbor.ByteString(protected), cbor.ByteString([]byte{}), cbor.ByteString(payload))
+	signature := ed25519.Sign(privateKey, sigStructure)
+
+	coseSign1 := cbor.ArrayOf(
+		cbor.ByteString(protected),
+		cbor.MapOf([2][]byte{cbor.TextString("hashed"), cbor.Bool(false)}),
+		cbor.ByteString(payload),
+		cbor.ByteString(signature),
+	)
+	coseKey := cbor.MapOf(
+		[2][]byte{cbor.Int(keyType), cbor.Int(keyTypeOKP)},
+		[2][]byte{cbor.Int(keyAlgorithm), cbor.Int(algorithmEdDSA)},
+		[2][]byte{cbor.Int(keyCurve), cbor.Int(curveEd25519)},
+		[2][]byte{cbor.Int(keyX), cbor.ByteString(publicKey)},
+	)
+
+	return coseSign1, coseKey
+}
+
+// StakeAddress bech32 encodes a reward address, an error for any other address
+func StakeAddress(address []byte) (string, error) {
+	if len(address) != 29 || address[0]>>4 != addressTypeReward {
+		return "", fmt.Errorf("Address isn't a stake address")
+	}
+
+	hrp := "stake_test"
+	if address[0]&0x0f == 1 {
+		hrp = "stake"
+	}
+
+	return hdwallet.Bech32Encode(hrp, address)
+}
+
+// parseKey gets the public key out of an ed25519 COSE_Key
+func parseKey(coseKey []byte) (ed25519.PublicKey, error) {
+	decoded, err := cbor.Decode(coseKey)
+	if err != nil {
+		return nil, fmt.Errorf("Invalid COSE_Key: %v", err)
+	}
+	key, ok := decoded.(map[interface{}]interface{})
+	if !ok {
+		return nil, fmt.Errorf("COSE_Key isn't a map")
+	}
+	if key[int64(keyType)] != int64(keyTypeOKP) || key[int64(keyCurve)] != int64(curveEd25519) {
+		return nil, fmt.Errorf("COSE_Key isn't an ed25519 key")
+	}
+	if algorithm, found := key[int64(keyAlgorithm)]; found && algorithm != int64(algorithmEdDSA) {
+		return nil, fmt.Errorf("COSE_Key algorithm isn't EdDSA")
+	}
+	x, ok := key[int64(keyX)].([]byte)
+	if !ok || len(x) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("COSE_Key has no ed25519 public key")
+	}
+
+	return ed25519.PublicKey(x), nil
+}
+
+func blake2b224(data []byte) []byte {
+	hasher, _ := blake2b.New(28, nil)
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
@@ -0,0 +1,79 @@
+package cip8
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"strings"
+	"testing"
+)
+
+func testKey(seed string) ed25519.PrivateKey {
+	hash := sha256.Sum256([]byte(seed))
+	return ed25519.NewKeyFromSeed(hash[:])
+}
+
+func testStakeAddress(key ed25519.PrivateKey) []byte {
+	return append([]byte{addressTypeReward<<4 | 1}, blake2b224(key.Public().(ed25519.PublicKey))...)
+}
+
+func TestVerify(t *testing.T) {
+	key := testKey("stake")
+	address := testStakeAddress(key)
+	coseSign1, coseKey := Sign(key, address, []byte("log me in"))
+
+	signed, err := Verify(coseSign1, coseKey)
+	if err != nil {
+		t.Fatalf("Error verifying signature %v", err)
+	}
+	if !bytes.Equal(signed.Address, address) || string(signed.Payload) != "log me in" {
+		t.Errorf("Expected the address and payload back but got %v", signed)
+	}
+
+	stakeAddress, err := StakeAddress(signed.Address)
+	if err != nil || !strings.HasPrefix(stakeAddress, "stake1") {
+		t.Errorf("Expected a mainnet stake address but got %s %v", stakeAddress, err)
+	}
+	testnet := append([]byte{addressTypeReward << 4}, address[1:]...)
+	if stakeAddress, _ := StakeAddress(testnet); !strings.HasPrefix(stakeAddress, "stake_test1") {
+		t.Errorf("Expected a testnet stake address but got %s", stakeAddress)
+	}
+	if _, err := StakeAddress(append([]byte{0x61}, address[1:]...)); err == nil {
+		t.Errorf("Expected an enterprise address not to be a stake address")
+	}
+}
+
+func TestVerifyRejects(t *testing.T) {
+	key := testKey("stake")
+	other := testKey("other")
+	address := testStakeAddress(key)
+	coseSign1, coseKey := Sign(key, address, []byte("log me in"))
+
+	// someone else's key over the same address
+	otherSign1, otherKey := Sign(other, address, []byte("log me in"))
+
+	// the payload swapped out after signing
+	tampered := bytes.Replace(coseSign1, []byte("log me in"), []byte("log me on"), 1)
+
+	tests := []struct {
+		name      string
+		coseSign1 []byte
+		coseKey   []byte
+		contains  string
+	}{
+		{"not an array", []byte{0x01}, coseKey, "isn't an array"},
+		{"wrong key", coseSign1, otherKey, "doesn't match"},
+		{"tampered payload", tampered, coseKey, "doesn't match"},
+		{"not the address's key", otherSign1, otherKey, "its own key"},
+		{"truncated key", coseSign1, coseKey[:len(coseKey)-1], "Invalid COSE_Key"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := Verify(test.coseSign1, test.coseKey)
+			if err == nil || !strings.Contains(err.Error(), test.contains) {
+				t.Errorf("Expected an error containing %q but got %v", test.contains, err)
+			}
+		})
+	}
+}
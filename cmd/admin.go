@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var adminCmd = &cobra.Command{
+	Use:   "admin",
+	Short: "Manage the admin api",
+	Long:  "Manage who may use the admin api",
+}
+
+var adminRoleCmd = &cobra.Command{
+	Use:   "role [user-id] [role]",
+	Short: "Set a user's role",
+	Long:  "Give a user the VIEWER, OPERATOR or ADMIN role on the admin api, NONE takes it away. Use it to make the first admin",
+	Args:  cobra.ExactArgs(2),
+	Run:   adminRole,
+}
+
+func init() {
+	adminCmd.AddCommand(adminRoleCmd)
+	serveCmd.AddCommand(adminCmd)
+}
+
+func adminRole(cmd *cobra.Command, args []string) {
+	userID, err := strconv.Atoi(args[0])
+	if err != nil {
+		logrus.WithError(err).Fatalf("Invalid user id %s", args[0])
+	}
+	role := db.UserRole(strings.ToUpper(args[1]))
+	if role == "NONE" {
+		role = ""
+	}
+	if role != "" && !role.Valid() {
+		logrus.Fatalf("Unknown role %s", args[1])
+	}
+
+	database := connectDatabase()
+	defer database.Close()
+	requireCurrentSchema(database)
+
+	store := db.PostgresStore{
+		Db: database,
+	}
+	err = store.SetUserRole(context.Background(), userID, role)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error setting role")
+	}
+	logrus.Infof("User %d has role %q", userID, role)
+}
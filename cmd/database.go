@@ -0,0 +1,47 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/jmoiron/sqlx"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+
+	_ "github.com/lib/pq"
+)
+
+// connectDatabase opens the postgres connection configured through the DB_* env vars
+func connectDatabase() *sqlx.DB {
+	portInt := 5432
+	port := os.Getenv("DB_PORT")
+	if port != "" {
+		portInt, _ = strconv.Atoi(port)
+	}
+	sslmode := "disable"
+	if os.Getenv("DB_SSL") == "true" {
+		sslmode = "require"
+	}
+	pgCon := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		os.Getenv("DB_ADDR"),
+		portInt,
+		os.Getenv("DB_USER"),
+		os.Getenv("DB_PASS"),
+		os.Getenv("DB_NAME"),
+		sslmode)
+	database, err := sqlx.Connect("postgres", pgCon)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error connecting to db...")
+	}
+
+	return database
+}
+
+// requireCurrentSchema refuses to continue if there are migrations still to apply
+func requireCurrentSchema(database *sqlx.DB) {
+	err := db.CheckSchemaCurrent(database)
+	if err != nil {
+		logrus.WithError(err).Fatal("Run 'server migrate up' before starting, after 'server migrate baseline' if the database was created from the old createDb.sql")
+	}
+}
@@ -0,0 +1,122 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Manage database schema",
+	Long:  "Apply, roll back or inspect the versioned database migrations",
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending migrations",
+	Long:  "Apply all pending migrations",
+	Args:  cobra.NoArgs,
+	Run:   migrateUp,
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down [steps]",
+	Short: "Roll back migrations",
+	Long:  "Roll back the latest applied migration, or the given number of migrations",
+	Args:  cobra.MaximumNArgs(1),
+	Run:   migrateDown,
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show migration status",
+	Long:  "Show which migrations have been applied",
+	Args:  cobra.NoArgs,
+	Run:   migrateStatus,
+}
+
+var migrateBaselineCmd = &cobra.Command{
+	Use:   "baseline",
+	Short: "Adopt a database created before migrations",
+	Long:  "Mark the initial migration as applied on a database created from the old db/createDb.sql, then run 'migrate up'",
+	Args:  cobra.NoArgs,
+	Run:   migrateBaseline,
+}
+
+func init() {
+	migrateCmd.AddCommand(migrateUpCmd)
+	migrateCmd.AddCommand(migrateDownCmd)
+	migrateCmd.AddCommand(migrateStatusCmd)
+	migrateCmd.AddCommand(migrateBaselineCmd)
+	serveCmd.AddCommand(migrateCmd)
+}
+
+func migrateUp(cmd *cobra.Command, args []string) {
+	database := connectDatabase()
+	defer database.Close()
+
+	applied, err := db.MigrateUp(context.Background(), database)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error applying migrations")
+	}
+
+	if len(applied) == 0 {
+		logrus.Info("Schema already up to date")
+		return
+	}
+	logrus.Infof("Applied %d migrations", len(applied))
+}
+
+func migrateDown(cmd *cobra.Command, args []string) {
+	steps := 1
+	if len(args) == 1 {
+		var err error
+		steps, err = strconv.Atoi(args[0])
+		if err != nil || steps < 1 {
+			logrus.Fatalf("Invalid number of steps %s", args[0])
+		}
+	}
+
+	database := connectDatabase()
+	defer database.Close()
+
+	rolledBack, err := db.MigrateDown(context.Background(), database, steps)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error rolling back migrations")
+	}
+	logrus.Infof("Rolled back %d migrations", len(rolledBack))
+}
+
+func migrateBaseline(cmd *cobra.Command, args []string) {
+	database := connectDatabase()
+	defer database.Close()
+
+	baseline, err := db.Baseline(context.Background(), database)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error baselining database")
+	}
+	logrus.Infof("Marked %04d_%s as applied, run 'server migrate up' for the rest", baseline.Version, baseline.Name)
+}
+
+func migrateStatus(cmd *cobra.Command, args []string) {
+	database := connectDatabase()
+	defer database.Close()
+
+	statuses, err := db.GetMigrationStatus(database)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting migration status")
+	}
+
+	for _, status := range statuses {
+		appliedAt := "pending"
+		if status.Applied && status.AppliedAt.Valid {
+			appliedAt = status.AppliedAt.Time.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%04d_%-40s %s\n", status.Version, status.Name, appliedAt)
+	}
+}
@@ -1,13 +1,18 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
+	"time"
 
-	"github.com/jmoiron/sqlx"
 	"github.com/reliablestaking/zombie-fight-club-server/blockfrost"
+	"github.com/reliablestaking/zombie-fight-club-server/cardanocli"
+	"github.com/reliablestaking/zombie-fight-club-server/chain"
 	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/hdwallet"
 	"github.com/reliablestaking/zombie-fight-club-server/imagebuilder"
 	"github.com/reliablestaking/zombie-fight-club-server/metadata"
 	"github.com/reliablestaking/zombie-fight-club-server/nftkeyme"
@@ -18,8 +23,6 @@ import (
 	"github.com/spf13/cobra"
 	"golang.org/x/oauth2"
 
-	_ "github.com/lib/pq"
-
 	bfg "github.com/blockfrost/blockfrost-go"
 )
 
@@ -36,28 +39,10 @@ func init() {
 
 func mint(cmd *cobra.Command, args []string) {
 	// init database
-	portInt := 5432
-	port := os.Getenv("DB_PORT")
-	if port != "" {
-		portInt, _ = strconv.Atoi(port)
-	}
-	sslmode := "disable"
-	if os.Getenv("DB_SSL") == "true" {
-		sslmode = "require"
-	}
-	pgCon := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		os.Getenv("DB_ADDR"),
-		portInt,
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASS"),
-		os.Getenv("DB_NAME"),
-		sslmode)
-	database, err := sqlx.Connect("postgres", pgCon)
-	if err != nil {
-		logrus.WithError(err).Fatal("Error connecting to db...")
-	}
+	database := connectDatabase()
 	defer database.Close()
-	store := db.Store{
+	requireCurrentSchema(database)
+	store := db.PostgresStore{
 		Db: database,
 	}
 
@@ -96,6 +81,24 @@ func mint(cmd *cobra.Command, args []string) {
 		logrus.Fatalf("Error loaindg zc strength %v", err)
 	}
 
+	// plain trait strengths unless there's a rules file
+	var fightRules *metadata.Rules
+	if rulesFile := os.Getenv("FIGHT_RULES_FILE"); rulesFile != "" {
+		fightRules, err = metadata.LoadRules(rulesFile)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Error loading fight rules %s", rulesFile)
+		}
+		logrus.Infof("Loaded version %d fight rules with %d rule sets", fightRules.Version, len(fightRules.RuleSets))
+
+		if metadata.CountWeaknesses(*zcTraitStrength, *zhTraitStrength) == 0 {
+			for name, ruleSet := range fightRules.RuleSets {
+				if ruleSet.WeaknessModifier != 0 {
+					logrus.Warnf("Rule set %s has a weakness modifier but no trait in the csvs has a weakness, it won't apply", name)
+				}
+			}
+		}
+	}
+
 	zcMeta, err := metadata.LoadZombieChainsMeta("metadata")
 	if err != nil {
 		logrus.Fatalf("Error loaindg zc meta %v", err)
@@ -117,14 +120,60 @@ func mint(cmd *cobra.Command, args []string) {
 		logrus.Fatal("No alien policy id found")
 	}
 
-	brianSplit := os.Getenv("BRIAN_SPLIT_ADDRESS")
-	if brianSplit == "" {
-		logrus.Fatal("No brian split found")
+	baseCostString := os.Getenv("BASE_COST_ADA")
+	baseCostInt, err := strconv.Atoi(baseCostString)
+	if err != nil {
+		logrus.WithError(err).Fatalf("Couldn't parse base cost string %s", baseCostString)
 	}
 
-	royaltySplit := os.Getenv("ROYALTY_SPLIT_ADDRESS")
-	if royaltySplit == "" {
-		logrus.Fatal("No royalty split found")
+	paymentTolerance, err := loadPaymentTolerance()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading payment tolerance")
+	}
+
+	pricing, err := loadPricing(baseCostInt, *paymentTolerance)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading pricing")
+	}
+
+	paymentWindow, err := loadPaymentWindow()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading payment window")
+	}
+
+	// the minter spends from derived addresses so it needs the account signing key too
+	paymentWallet, err := loadPaymentWallet(true)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading payment wallet")
+	}
+
+	wagers, err := loadWagers()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading wagers")
+	}
+
+	// the indexer and the send address checks look the zombies and hunters up by policy
+	nftIndex, err := loadNftIndex()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading nft index")
+	}
+	zombiePolicyId := os.Getenv("ZOMBIE_POLICY_ID")
+	hunterPolicyId := os.Getenv("HUNTER_POLICY_ID")
+	if zombiePolicyId == "" || hunterPolicyId == "" {
+		logrus.Fatal("No zombie or hunter policy id found")
+	}
+
+	chainProvider := chain.NewBlockfrostChain(api, blockfrost.NewClientFromEnvironment())
+
+	// cardano-cli by default, the native builder only needs the current protocol params
+	var txBuilder cardanocli.Builder = cardanocli.CliBuilder{}
+	if os.Getenv("TX_BUILDER") == "native" {
+		params, err := chainProvider.ProtocolParams(context.Background())
+		if err != nil {
+			logrus.WithError(err).Fatal("Error getting protocol params")
+		}
+		txBuilder = cardanocli.NativeBuilder{MinFeeA: params.MinFeeA, MinFeeB: params.MinFeeB, MaxTxSize: params.MaxTxSize}
+		logrus.Info("Using native tx builder")
 	}
 
 	// init server
@@ -135,20 +184,190 @@ func mint(cmd *cobra.Command, args []string) {
 		Store:                     store,
 		NftkeymeClient:            nftkeyme.NewClientFromEnvironment(),
 		ImageBuilderClient:        imagebuilder.NewClientFromEnvironment(),
-		BlockforstIpfsClient:      blockfrost.NewClientFromEnvironment(),
+		BaseCostAda:               baseCostInt,
 		PaymentAddress:            paymentAddress,
-		BlockfrostClient:          api,
+		PaymentTolerance:          *paymentTolerance,
+		PaymentWindow:             paymentWindow,
+		PaymentWallet:             paymentWallet,
+		Wagers:                    *wagers,
+		NftIndex:                  *nftIndex,
+		ZombiePolicyId:            zombiePolicyId,
+		HunterPolicyId:            hunterPolicyId,
+		Chain:                     chainProvider,
+		TxBuilder:                 txBuilder,
 		ZombieMetaStruct:          zcMeta,
 		HunterMetaStruct:          zhMeta,
 		ZombieChainTraitStrength:  *zcTraitStrength,
 		ZombieHunterTraitStrength: *zhTraitStrength,
+		FightRules:                fightRules,
 		ZfcPolicyID:               zfcPolicyID,
 		AlienPolicyID:             alienPolicyID,
-		BrianSplitAddress:         brianSplit,
-		RoyaltySplitAddress:       royaltySplit,
+		Pricing:                   *pricing,
 		NftStorageClient:          nftstorage.NewClientFromEnvironment(),
 	}
 
 	// start minter
 	server.RunMintingEngine()
 }
+
+// loadPaymentTolerance reads how far off a payment may be, both default to exact payments only
+func loadPaymentTolerance() (*server.PaymentTolerance, error) {
+	tolerance := server.PaymentTolerance{}
+
+	under := os.Getenv("PAYMENT_UNDERPAY_TOLERANCE_LOVELACE")
+	if under != "" {
+		underInt, err := strconv.ParseInt(under, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		tolerance.UnderLovelace = underInt
+	}
+
+	over := os.Getenv("PAYMENT_OVERPAY_TOLERANCE_LOVELACE")
+	if over != "" {
+		overInt, err := strconv.ParseInt(over, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		tolerance.OverLovelace = overInt
+	}
+
+	return &tolerance, nil
+}
+
+// loadPricing reads the split table, the artist and mint output amounts default to what they were before they were configurable.
+// The split has to fit in the base cost
+func loadPricing(baseCostAda int, tolerance server.PaymentTolerance) (*server.Pricing, error) {
+	brianSplit := os.Getenv("BRIAN_SPLIT_ADDRESS")
+	if brianSplit == "" {
+		return nil, fmt.Errorf("No brian split found")
+	}
+
+	royaltySplit := os.Getenv("ROYALTY_SPLIT_ADDRESS")
+	if royaltySplit == "" {
+		return nil, fmt.Errorf("No royalty split found")
+	}
+
+	pricing := server.DefaultPricing(brianSplit, royaltySplit)
+	pricing.TreasuryAddress = os.Getenv("TREASURY_SPLIT_ADDRESS")
+
+	amounts := map[string]*int64{
+		"ARTIST_SPLIT_LOVELACE":   &pricing.ArtistLovelace,
+		"TREASURY_SPLIT_LOVELACE": &pricing.TreasuryLovelace,
+		"MINT_OUTPUT_LOVELACE":    &pricing.MintOutputLovelace,
+	}
+	for name, amount := range amounts {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		lovelace, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Couldn't parse %s %s", name, value)
+		}
+		*amount = lovelace
+	}
+
+	return &pricing, pricing.Validate(baseCostAda, tolerance)
+}
+
+// loadPaymentWindow reads how many minutes a fight waits for payment, 0 leaves the server default
+func loadPaymentWindow() (time.Duration, error) {
+	window := os.Getenv("PAYMENT_WINDOW_MINUTES")
+	if window == "" {
+		return 0, nil
+	}
+
+	minutes, err := strconv.Atoi(window)
+	if err != nil {
+		return 0, err
+	}
+	if minutes <= 0 {
+		return 0, fmt.Errorf("Payment window must be positive but is %d", minutes)
+	}
+
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// loadPaymentWallet reads the account key fights get their own payment address from, nil to share PAYMENT_ADDRESS
+func loadPaymentWallet(withSigningKey bool) (*hdwallet.Wallet, error) {
+	xpub := os.Getenv("PAYMENT_ACCOUNT_XPUB")
+	if xpub == "" {
+		return nil, nil
+	}
+
+	account, err := hdwallet.ParseExtendedPublicKey(xpub)
+	if err != nil {
+		return nil, err
+	}
+	wallet := hdwallet.Wallet{
+		Account: *account,
+		Mainnet: os.Getenv("TESTNET") != "true",
+	}
+
+	keyFile := os.Getenv("PAYMENT_ACCOUNT_XSK_FILE")
+	if withSigningKey {
+		if keyFile == "" {
+			return nil, fmt.Errorf("PAYMENT_ACCOUNT_XSK_FILE is needed to spend from derived addresses")
+		}
+		keyBytes, err := os.ReadFile(keyFile)
+		if err != nil {
+			return nil, err
+		}
+		accountKey, err := hdwallet.ParseExtendedPrivateKey(strings.TrimSpace(string(keyBytes)))
+		if err != nil {
+			return nil, err
+		}
+
+		// a key for another account would derive addresses the minter can't spend from
+		public, err := accountKey.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		if string(public) != string(account.Key) {
+			return nil, fmt.Errorf("PAYMENT_ACCOUNT_XSK_FILE doesn't match PAYMENT_ACCOUNT_XPUB")
+		}
+		wallet.AccountKey = accountKey
+	}
+
+	return &wallet, nil
+}
+
+// loadWagers reads the house's cut of staked challenges, no fee by default and paid to PAYMENT_ADDRESS unless
+// WAGER_HOUSE_ADDRESS is set
+func loadWagers() (*server.Wagers, error) {
+	wagers := server.Wagers{HouseAddress: os.Getenv("WAGER_HOUSE_ADDRESS")}
+
+	percent := os.Getenv("WAGER_HOUSE_FEE_PERCENT")
+	if percent != "" {
+		percentInt, err := strconv.Atoi(percent)
+		if err != nil {
+			return nil, err
+		}
+		if percentInt < 0 || percentInt >= 100 {
+			return nil, fmt.Errorf("Wager house fee must be between 0 and 99 percent but is %d", percentInt)
+		}
+		wagers.HouseFeePercent = percentInt
+	}
+
+	return &wagers, nil
+}
+
+// loadNftIndex reads how many nfts the indexer checks each pass, 100 by default and 0 to turn it off
+func loadNftIndex() (*server.NftIndex, error) {
+	index := server.NftIndex{BatchSize: 100}
+
+	batchSize := os.Getenv("NFT_INDEX_BATCH_SIZE")
+	if batchSize != "" {
+		batchSizeInt, err := strconv.Atoi(batchSize)
+		if err != nil {
+			return nil, err
+		}
+		if batchSizeInt < 0 {
+			return nil, fmt.Errorf("Nft index batch size can't be negative but is %d", batchSizeInt)
+		}
+		index.BatchSize = batchSizeInt
+	}
+
+	return &index, nil
+}
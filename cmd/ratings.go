@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"context"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var ratingsCmd = &cobra.Command{
+	Use:   "ratings",
+	Short: "Manage nft ratings",
+	Long:  "Manage the glicko-2 ratings of zombies and hunters",
+}
+
+var ratingsBackfillCmd = &cobra.Command{
+	Use:   "backfill",
+	Short: "Rebuild ratings from past fights",
+	Long:  "Reset every rating and the rating history, then replay every fought fight in the order they were created",
+	Args:  cobra.NoArgs,
+	Run:   ratingsBackfill,
+}
+
+func init() {
+	ratingsCmd.AddCommand(ratingsBackfillCmd)
+	serveCmd.AddCommand(ratingsCmd)
+}
+
+func ratingsBackfill(cmd *cobra.Command, args []string) {
+	database := connectDatabase()
+	defer database.Close()
+	requireCurrentSchema(database)
+
+	store := db.PostgresStore{
+		Db: database,
+	}
+	rated, err := store.BackfillRatings(context.Background())
+	if err != nil {
+		logrus.WithError(err).Fatal("Error backfilling ratings")
+	}
+	logrus.Infof("Rated %d fights", rated)
+}
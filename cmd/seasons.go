@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var seasonsCmd = &cobra.Command{
+	Use:   "seasons",
+	Short: "Manage seasons",
+	Long:  "Create, list and close the seasons fights are counted in",
+}
+
+var seasonsCreateCmd = &cobra.Command{
+	Use:   "create [name] [start] [end]",
+	Short: "Create a season",
+	Long:  "Create a season running from start until end, dates are RFC3339 or YYYY-MM-DD in UTC",
+	Args:  cobra.ExactArgs(3),
+	Run:   seasonsCreate,
+}
+
+var seasonsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List seasons",
+	Long:  "List every season, latest first",
+	Args:  cobra.NoArgs,
+	Run:   seasonsList,
+}
+
+var seasonsCloseCmd = &cobra.Command{
+	Use:   "close [id]",
+	Short: "Close a season",
+	Long:  "Stop counting fights toward a season and snapshot its final standings",
+	Args:  cobra.ExactArgs(1),
+	Run:   seasonsClose,
+}
+
+func init() {
+	seasonsCmd.AddCommand(seasonsCreateCmd)
+	seasonsCmd.AddCommand(seasonsListCmd)
+	seasonsCmd.AddCommand(seasonsCloseCmd)
+	serveCmd.AddCommand(seasonsCmd)
+}
+
+// parseSeasonDate parses an RFC3339 time or a UTC date
+func parseSeasonDate(value string) (time.Time, error) {
+	if date, err := time.Parse("2006-01-02", value); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+func seasonsCreate(cmd *cobra.Command, args []string) {
+	start, err := parseSeasonDate(args[1])
+	if err != nil {
+		logrus.WithError(err).Fatalf("Invalid start %s", args[1])
+	}
+	end, err := parseSeasonDate(args[2])
+	if err != nil {
+		logrus.WithError(err).Fatalf("Invalid end %s", args[2])
+	}
+
+	database := connectDatabase()
+	defer database.Close()
+	requireCurrentSchema(database)
+
+	store := db.PostgresStore{
+		Db: database,
+	}
+	id, err := store.CreateSeason(context.Background(), args[0], start, end)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating season")
+	}
+	logrus.Infof("Created season %d", id)
+}
+
+func seasonsList(cmd *cobra.Command, args []string) {
+	database := connectDatabase()
+	defer database.Close()
+	requireCurrentSchema(database)
+
+	store := db.PostgresStore{
+		Db: database,
+	}
+	seasons, err := store.GetSeasons()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting seasons")
+	}
+
+	for _, season := range seasons {
+		closed := "open"
+		if season.ClosedDate.Valid {
+			closed = "closed " + season.ClosedDate.Time.Format("2006-01-02 15:04:05")
+		}
+		fmt.Printf("%4d %-32s %s - %s %s\n", season.ID, season.Name, season.StartDate.Format(time.RFC3339), season.EndDate.Format(time.RFC3339), closed)
+	}
+}
+
+func seasonsClose(cmd *cobra.Command, args []string) {
+	seasonID, err := strconv.Atoi(args[0])
+	if err != nil {
+		logrus.Fatalf("Invalid season id %s", args[0])
+	}
+
+	database := connectDatabase()
+	defer database.Close()
+	requireCurrentSchema(database)
+
+	store := db.PostgresStore{
+		Db: database,
+	}
+	ranked, err := store.CloseSeason(context.Background(), seasonID)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error closing season")
+	}
+	logrus.Infof("Closed season %d with %d nfts in its standings", seasonID, ranked)
+}
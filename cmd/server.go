@@ -10,8 +10,8 @@ import (
 	"time"
 
 	bfg "github.com/blockfrost/blockfrost-go"
-	"github.com/jmoiron/sqlx"
 	"github.com/reliablestaking/zombie-fight-club-server/blockfrost"
+	"github.com/reliablestaking/zombie-fight-club-server/chain"
 	db "github.com/reliablestaking/zombie-fight-club-server/db"
 	"github.com/reliablestaking/zombie-fight-club-server/imagebuilder"
 	"github.com/reliablestaking/zombie-fight-club-server/nftkeyme"
@@ -21,8 +21,6 @@ import (
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"golang.org/x/oauth2"
-
-	_ "github.com/lib/pq"
 )
 
 var (
@@ -46,28 +44,10 @@ var serveCmd = &cobra.Command{
 
 func serve(cmd *cobra.Command, args []string) {
 	// init database
-	portInt := 5432
-	port := os.Getenv("DB_PORT")
-	if port != "" {
-		portInt, _ = strconv.Atoi(port)
-	}
-	sslmode := "disable"
-	if os.Getenv("DB_SSL") == "true" {
-		sslmode = "require"
-	}
-	pgCon := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		os.Getenv("DB_ADDR"),
-		portInt,
-		os.Getenv("DB_USER"),
-		os.Getenv("DB_PASS"),
-		os.Getenv("DB_NAME"),
-		sslmode)
-	database, err := sqlx.Connect("postgres", pgCon)
-	if err != nil {
-		logrus.WithError(err).Fatal("Error connecting to db...")
-	}
+	database := connectDatabase()
 	defer database.Close()
-	store := db.Store{
+	requireCurrentSchema(database)
+	store := db.PostgresStore{
 		Db: database,
 	}
 
@@ -112,6 +92,37 @@ func serve(cmd *cobra.Command, args []string) {
 		clientOptions,
 	)
 
+	paymentWindow, err := loadPaymentWindow()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading payment window")
+	}
+
+	paymentWallet, err := loadPaymentWallet(false)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading payment wallet")
+	}
+
+	matchmaking, err := loadMatchmaking()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading matchmaking")
+	}
+
+	challengeTTL, err := loadChallengeTTL()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading challenge ttl")
+	}
+
+	fightLimits, err := loadFightLimits()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading fight limits")
+	}
+
+	// the fee is fixed on each wager when its challenge is accepted
+	wagers, err := loadWagers()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading wagers")
+	}
+
 	cache := cache.New(30*time.Minute, 60*time.Minute)
 
 	hydraClient, err := server.NewHydraClientFromEnv()
@@ -121,22 +132,27 @@ func serve(cmd *cobra.Command, args []string) {
 
 	// init server
 	server := server.Server{
-		Sha1ver:              sha1ver,
-		BuildTime:            buildTime,
-		NftkeymeOauthConfig:  nftkeymeOauthConfig,
-		Store:                store,
-		NftkeymeClient:       nftkeyme.NewClientFromEnvironment(),
-		ImageBuilderClient:   imagebuilder.NewClientFromEnvironment(),
-		BlockforstIpfsClient: blockfrost.NewClientFromEnvironment(),
-		ZombiePolicyId:       zombiePolicyId,
-		HunterPolicyId:       hunterPolicyId,
-		ZombieMeta:           loadZcCsv(),
-		HunterMeta:           loadHunterCsv(),
-		BaseCostAda:          baseCostInt,
-		PaymentAddress:       paymentAddress,
-		BlockfrostClient:     api,
-		LeaderCache:          cache,
-		HydraClient:          *hydraClient,
+		Sha1ver:             sha1ver,
+		BuildTime:           buildTime,
+		NftkeymeOauthConfig: nftkeymeOauthConfig,
+		Store:               store,
+		NftkeymeClient:      nftkeyme.NewClientFromEnvironment(),
+		ImageBuilderClient:  imagebuilder.NewClientFromEnvironment(),
+		ZombiePolicyId:      zombiePolicyId,
+		HunterPolicyId:      hunterPolicyId,
+		ZombieMeta:          loadZcCsv(),
+		HunterMeta:          loadHunterCsv(),
+		BaseCostAda:         baseCostInt,
+		PaymentAddress:      paymentAddress,
+		PaymentWindow:       paymentWindow,
+		PaymentWallet:       paymentWallet,
+		Matchmaking:         matchmaking,
+		ChallengeTTL:        challengeTTL,
+		FightLimits:         *fightLimits,
+		Wagers:              *wagers,
+		Chain:               chain.NewBlockfrostChain(api, blockfrost.NewClientFromEnvironment()),
+		LeaderCache:         cache,
+		HydraClient:         *hydraClient,
 	}
 
 	// start server
@@ -200,3 +216,86 @@ func loadHunterCsv() map[string]string {
 
 	return zcs
 }
+
+// loadMatchmaking reads how queued nfts are paired, anything not set uses the server's defaults
+func loadMatchmaking() (server.Matchmaking, error) {
+	matchmaking := server.Matchmaking{
+		By: os.Getenv("MATCHMAKING_BY"),
+	}
+	if matchmaking.By != "" && matchmaking.By != server.MatchmakingByRating && matchmaking.By != server.MatchmakingByRecord {
+		return matchmaking, fmt.Errorf("Matchmaking by must be %s or %s but is %s", server.MatchmakingByRating, server.MatchmakingByRecord, matchmaking.By)
+	}
+
+	if window := os.Getenv("MATCHMAKING_WINDOW"); window != "" {
+		var err error
+		matchmaking.Window, err = strconv.ParseFloat(window, 64)
+		if err != nil {
+			return matchmaking, err
+		}
+		if matchmaking.Window <= 0 {
+			return matchmaking, fmt.Errorf("Matchmaking window must be positive but is %s", window)
+		}
+	}
+
+	if queueTime := os.Getenv("MATCHMAKING_QUEUE_MINUTES"); queueTime != "" {
+		minutes, err := strconv.Atoi(queueTime)
+		if err != nil {
+			return matchmaking, err
+		}
+		if minutes <= 0 {
+			return matchmaking, fmt.Errorf("Matchmaking queue time must be positive but is %d", minutes)
+		}
+		matchmaking.QueueTime = time.Duration(minutes) * time.Minute
+	}
+
+	return matchmaking, nil
+}
+
+// loadChallengeTTL reads how long a challenge waits for a response, 0 for the server's default
+func loadChallengeTTL() (time.Duration, error) {
+	ttl := os.Getenv("CHALLENGE_TTL_HOURS")
+	if ttl == "" {
+		return 0, nil
+	}
+
+	hours, err := strconv.Atoi(ttl)
+	if err != nil {
+		return 0, err
+	}
+	if hours <= 0 {
+		return 0, fmt.Errorf("Challenge ttl must be positive but is %d", hours)
+	}
+
+	return time.Duration(hours) * time.Hour, nil
+}
+
+// loadFightLimits reads the nft cooldown in minutes and the daily fight limit per user, both off unless set
+func loadFightLimits() (*server.FightLimits, error) {
+	limits := server.FightLimits{}
+
+	cooldown := os.Getenv("FIGHT_COOLDOWN_MINUTES")
+	if cooldown != "" {
+		minutes, err := strconv.Atoi(cooldown)
+		if err != nil {
+			return nil, err
+		}
+		if minutes < 0 {
+			return nil, fmt.Errorf("Fight cooldown can't be negative but is %d", minutes)
+		}
+		limits.NftCooldown = time.Duration(minutes) * time.Minute
+	}
+
+	daily := os.Getenv("FIGHT_DAILY_LIMIT")
+	if daily != "" {
+		limit, err := strconv.Atoi(daily)
+		if err != nil {
+			return nil, err
+		}
+		if limit < 0 {
+			return nil, fmt.Errorf("Daily fight limit can't be negative but is %d", limit)
+		}
+		limits.DailyLimit = limit
+	}
+
+	return &limits, nil
+}
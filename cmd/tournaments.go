@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/tournament"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+)
+
+var tournamentsCmd = &cobra.Command{
+	Use:   "tournaments",
+	Short: "Manage tournaments",
+	Long:  "Create and list the tournaments the minting engine runs",
+}
+
+var tournamentsCreateCmd = &cobra.Command{
+	Use:   "create [name]",
+	Short: "Create a tournament",
+	Long:  "Create a tournament open for registration, entry fees are paid to its own derived address and pay for its fights, what's left is the prize pool",
+	Args:  cobra.ExactArgs(1),
+	Run:   tournamentsCreate,
+}
+
+var tournamentsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List tournaments",
+	Long:  "List every tournament, latest first",
+	Args:  cobra.NoArgs,
+	Run:   tournamentsList,
+}
+
+func init() {
+	flags := tournamentsCreateCmd.Flags()
+	flags.String("format", string(tournament.FormatSingleElimination), "SINGLE_ELIMINATION, DOUBLE_ELIMINATION or ROUND_ROBIN")
+	flags.Int("size", 8, "most entries the bracket takes, a power of 2 for elimination")
+	flags.Int("entry-fee-ada", 0, "entry fee in ada")
+	flags.Int("fight-cost-ada", 0, "what each fight costs the prize pool in ada, defaults to BASE_COST_ADA")
+	flags.String("prize-split", "70,30", "percent of the prize pool per place")
+	flags.String("registration-start", "", "when registration opens, RFC3339 or YYYY-MM-DD in UTC, defaults to now")
+	flags.String("registration-end", "", "when registration closes, RFC3339 or YYYY-MM-DD in UTC")
+
+	tournamentsCmd.AddCommand(tournamentsCreateCmd)
+	tournamentsCmd.AddCommand(tournamentsListCmd)
+	serveCmd.AddCommand(tournamentsCmd)
+}
+
+func tournamentsCreate(cmd *cobra.Command, args []string) {
+	flags := cmd.Flags()
+	format, _ := flags.GetString("format")
+	size, _ := flags.GetInt("size")
+	entryFeeAda, _ := flags.GetInt("entry-fee-ada")
+	fightCostAda, _ := flags.GetInt("fight-cost-ada")
+	prizeSplit, _ := flags.GetString("prize-split")
+	startString, _ := flags.GetString("registration-start")
+	endString, _ := flags.GetString("registration-end")
+
+	start := time.Now()
+	if startString != "" {
+		var err error
+		start, err = parseSeasonDate(startString)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Invalid registration start %s", startString)
+		}
+	}
+	end, err := parseSeasonDate(endString)
+	if err != nil {
+		logrus.WithError(err).Fatalf("Invalid registration end %s", endString)
+	}
+
+	if fightCostAda == 0 {
+		baseCostString := os.Getenv("BASE_COST_ADA")
+		fightCostAda, err = strconv.Atoi(baseCostString)
+		if err != nil {
+			logrus.WithError(err).Fatalf("Couldn't parse base cost string %s", baseCostString)
+		}
+	}
+
+	// the prize pool is held at its own address so it's never mixed up with fight payments
+	wallet, err := loadPaymentWallet(false)
+	if err != nil {
+		logrus.WithError(err).Fatal("Error loading payment wallet")
+	}
+	if wallet == nil {
+		logrus.Fatal("PAYMENT_ACCOUNT_XPUB is needed to give the tournament its own address")
+	}
+
+	database := connectDatabase()
+	defer database.Close()
+	requireCurrentSchema(database)
+
+	store := db.PostgresStore{
+		Db: database,
+	}
+	index, err := store.NextPaymentAddressIndex()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error reserving payment address index")
+	}
+	address, err := wallet.PaymentAddress(uint32(index))
+	if err != nil {
+		logrus.WithError(err).Fatalf("Error deriving payment address %d", index)
+	}
+
+	id, err := store.CreateTournament(context.Background(), db.Tournament{
+		Name:                args[0],
+		Format:              format,
+		BracketSize:         size,
+		EntryFeeLovelace:    int64(entryFeeAda) * 1000000,
+		FightCostLovelace:   int64(fightCostAda) * 1000000,
+		PrizeSplit:          prizeSplit,
+		RegistrationStart:   start,
+		RegistrationEnd:     end,
+		PaymentAddress:      address,
+		PaymentAddressIndex: index,
+	})
+	if err != nil {
+		logrus.WithError(err).Fatal("Error creating tournament")
+	}
+	logrus.Infof("Created tournament %d paid to %s", id, address)
+}
+
+func tournamentsList(cmd *cobra.Command, args []string) {
+	database := connectDatabase()
+	defer database.Close()
+	requireCurrentSchema(database)
+
+	store := db.PostgresStore{
+		Db: database,
+	}
+	tournaments, err := store.GetTournaments()
+	if err != nil {
+		logrus.WithError(err).Fatal("Error getting tournaments")
+	}
+
+	for _, t := range tournaments {
+		fmt.Printf("%4d %-32s %-18s %3d %-12s %s - %s %s\n", t.ID, t.Name, t.Format, t.BracketSize, t.Status,
+			t.RegistrationStart.Format(time.RFC3339), t.RegistrationEnd.Format(time.RFC3339), t.PaymentAddress)
+	}
+}
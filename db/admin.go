@@ -0,0 +1,334 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// UserRole what a user may do on the admin api, each role may do everything the roles before it may
+	UserRole string
+
+	// AdminAudit one change made through the admin api, StatusCode is what the api answered, 0 if that was never
+	// recorded
+	AdminAudit struct {
+		ID          int       `db:"id"`
+		UserID      int       `db:"zfc_user_id"`
+		Action      string    `db:"action"`
+		Target      string    `db:"target"`
+		Detail      string    `db:"detail"`
+		StatusCode  int       `db:"status_code"`
+		CreatedDate time.Time `db:"created_date"`
+	}
+
+	// EngineHeartbeat when an engine last went through a pass and how many passes in a row failed
+	EngineHeartbeat struct {
+		Engine              string       `db:"engine"`
+		LastPassDate        time.Time    `db:"last_pass_date"`
+		LastSuccessDate     sql.NullTime `db:"last_success_date"`
+		ConsecutiveFailures int          `db:"consecutive_failures"`
+	}
+
+	statusCount struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+)
+
+// VIEWER < OPERATOR < ADMIN, players have no role
+const (
+	UserRoleViewer   UserRole = "VIEWER"
+	UserRoleOperator UserRole = "OPERATOR"
+	UserRoleAdmin    UserRole = "ADMIN"
+)
+
+var userRoleRanks = map[UserRole]int{
+	UserRoleViewer:   1,
+	UserRoleOperator: 2,
+	UserRoleAdmin:    3,
+}
+
+// Valid returns true if the role is one of the admin roles
+func (r UserRole) Valid() bool {
+	_, found := userRoleRanks[r]
+	return found
+}
+
+// Allows returns true if a user with this role may do what needs the required role
+func (r UserRole) Allows(required UserRole) bool {
+	rank, found := userRoleRanks[r]
+	return found && rank >= userRoleRanks[required]
+}
+
+// every column of a fight, for looking one up on its own
+const selectFightSql = `SELECT f.id,
+							f.status,
+							f.created_date,
+							f.minted_date,
+							znft.name as zombie_name,
+							hnft.name as hunter_name,
+							f.payment_address,
+							f.payment_amount_lovelace,
+							f.payment_address_index,
+							f.incoming_utxo,
+							f.incoming_utxo_index,
+							f.ipfs_fight,
+							f.background,
+							f.zombie_record,
+							f.hunter_record,
+							f.zombie_ko,
+							f.hunter_ko,
+							f.zclifebar,
+							f.zhlifebar,
+							f.collection,
+							f.site,
+							f.twitter,
+							f.copyright,
+							f.hunter_send_address,
+							f.hunter_amount_ada,
+							f.zombie_send_address,
+							f.zombie_amount_ada,
+							f.tx_id,
+							f.tweet_id,
+							f.tweet_attempted,
+							f.signed_tx_hash,
+							f.signed_tx_ttl,
+							f.season_id,
+							f.tournament_id,
+							f.regenerate_images,
+							f.seed_commitment,
+							f.seed_secret,
+							f.seed_tx_hash,
+							f.seed
+							FROM fight f
+							LEFT JOIN nft znft ON znft.id = f.zombie_nft_id
+							LEFT JOIN nft hnft ON hnft.id = f.hunter_nft_id`
+
+// SetUserRole gives the user a role on the admin api, an empty role takes it away
+func (s PostgresStore) SetUserRole(ctx context.Context, userID int, role UserRole) error {
+	result, err := s.Db.ExecContext(ctx, "UPDATE zfc_user SET role = NULLIF($2, '') WHERE id = $1", userID, role)
+	if err != nil {
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("No user with id %d", userID)
+	}
+
+	return nil
+}
+
+// InsertAdminAudit records a change made through the admin api before it's made, returning its id
+func (s PostgresStore) InsertAdminAudit(ctx context.Context, audit AdminAudit) (int, error) {
+	var id int
+
+	insertAuditQuery := `INSERT INTO admin_audit (zfc_user_id, action, target, detail, status_code, created_date) VALUES($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	err := s.Db.QueryRowContext(ctx, insertAuditQuery, audit.UserID, audit.Action, audit.Target, audit.Detail, audit.StatusCode, time.Now()).Scan(&id)
+	if err != nil {
+		logrus.New().WithError(err).Error("Inserting admin audit")
+		return id, err
+	}
+
+	return id, nil
+}
+
+// FinishAdminAudit records what the api answered to an audited change
+func (s PostgresStore) FinishAdminAudit(ctx context.Context, auditID int, statusCode int, detail string) error {
+	_, err := s.Db.ExecContext(ctx, "UPDATE admin_audit SET status_code = $2, detail = $3 WHERE id = $1", auditID, statusCode, detail)
+	if err != nil {
+		logrus.New().WithError(err).Error("Finishing admin audit")
+		return err
+	}
+
+	return nil
+}
+
+// GetAdminAudits gets up to limit admin changes, latest first
+func (s PostgresStore) GetAdminAudits(limit int) ([]AdminAudit, error) {
+	audits := make([]AdminAudit, 0)
+
+	err := s.Db.Select(&audits, "SELECT * FROM admin_audit ORDER BY id desc LIMIT $1", limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return audits, nil
+		}
+		return nil, err
+	}
+
+	return audits, nil
+}
+
+// GetFight gets a fight by id, nil if there isn't one
+func (s PostgresStore) GetFight(fightID int) (*FightDb, error) {
+	fight := FightDb{}
+
+	err := s.Db.Get(&fight, selectFightSql+" WHERE f.id = $1", fightID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &fight, nil
+}
+
+// GetFightsByStatus gets up to limit fights in a status, oldest first
+func (s PostgresStore) GetFightsByStatus(status FightStatus, limit int) ([]FightDb, error) {
+	fights := make([]FightDb, 0)
+
+	err := s.Db.Select(&fights, selectFightSql+" WHERE f.status = $1 ORDER BY f.id asc LIMIT $2", status, limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fights, nil
+		}
+		return nil, err
+	}
+
+	return fights, nil
+}
+
+// CountFightsByStatus how many fights are in each status, statuses without fights are left out
+func (s PostgresStore) CountFightsByStatus() (map[FightStatus]int, error) {
+	counts := make([]statusCount, 0)
+
+	err := s.Db.Select(&counts, "SELECT status, count(*) as count FROM fight GROUP BY status")
+	if err != nil {
+		return nil, err
+	}
+
+	byStatus := make(map[FightStatus]int)
+	for _, count := range counts {
+		byStatus[FightStatus(count.Status)] = count.Count
+	}
+
+	return byStatus, nil
+}
+
+// CountRefundsByStatus how many refunds are in each status, statuses without refunds are left out
+func (s PostgresStore) CountRefundsByStatus() (map[RefundStatus]int, error) {
+	counts := make([]statusCount, 0)
+
+	err := s.Db.Select(&counts, "SELECT status, count(*) as count FROM refund GROUP BY status")
+	if err != nil {
+		return nil, err
+	}
+
+	byStatus := make(map[RefundStatus]int)
+	for _, count := range counts {
+		byStatus[RefundStatus(count.Status)] = count.Count
+	}
+
+	return byStatus, nil
+}
+
+// ForceRefund records a utxo as owed back to its sender. A refund for the utxo that ran out of attempts is tried again
+// from scratch, any other refund for it is left as it is. Returns the utxo's refund
+func (s PostgresStore) ForceRefund(ctx context.Context, refund Refund) (*Refund, error) {
+	upsertRefundQuery := `INSERT INTO refund (tx_hash, output_index, sender_address, lovelace, assets, payment_address, payment_address_index, status, sender_account)
+							VALUES($1, $2, $3, $4, $5, $6, $7, $8, $10)
+							ON CONFLICT (tx_hash, output_index) DO UPDATE SET status = $8,
+																		attempts = 0,
+																		last_error = null,
+																		refund_tx_hash = null,
+																		refund_tx_ttl = null,
+																		updated_date = NOW()
+							WHERE refund.status = $9`
+
+	_, err := s.Db.ExecContext(ctx, upsertRefundQuery, refund.TxHash, refund.OutputIndex, refund.SenderAddress, refund.Lovelace, refund.Assets,
+		refund.PaymentAddress, refund.PaymentAddressIndex, RefundStatusPending, RefundStatusFailed, refund.SenderAccount)
+	if err != nil {
+		logrus.New().WithError(err).Error("Forcing refund")
+		return nil, err
+	}
+
+	return s.GetRefundForUtxo(refund.TxHash, refund.OutputIndex)
+}
+
+// RequestImageRegeneration flags a staged fight without a signed mint tx for the minter to rebuild its images,
+// returns false if the fight isn't one
+func (s PostgresStore) RequestImageRegeneration(ctx context.Context, fightID int) (bool, error) {
+	updateSql := `UPDATE fight SET regenerate_images = true WHERE id = $1 AND status = $2 AND signed_tx_hash IS NULL`
+
+	result, err := s.Db.ExecContext(ctx, updateSql, fightID, FightStatusStaged)
+	if err != nil {
+		return false, err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return updated > 0, nil
+}
+
+// SetFightImages replaces the images of a staged fight and its alien and clears the regeneration flag, nothing
+// changes once a mint tx is signed for it
+func (s PostgresStore) SetFightImages(ctx context.Context, fightID int, alienID int, alienIpfs string, fightIpfs string, background string) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	updateFightSql := `UPDATE fight SET ipfs_fight = $2, background = $3, regenerate_images = false
+						WHERE id = $1 AND status = $4 AND signed_tx_hash IS NULL`
+	result, err := tx.ExecContext(ctx, updateFightSql, fightID, fightIpfs, background, FightStatusStaged)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating fight images")
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return fmt.Errorf("Fight %d isn't staged without a signed mint tx", fightID)
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE zfc_alien SET ipfs_hash = $1 WHERE id = $2 AND fight_id = $3", alienIpfs, alienID, fightID)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating alien image")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// RecordEnginePass records that the engine went through a pass
+func (s PostgresStore) RecordEnginePass(ctx context.Context, engine string, passed time.Time, failed bool) error {
+	upsertSql := `INSERT INTO engine_heartbeat (engine, last_pass_date, last_success_date, consecutive_failures)
+					VALUES($1, $2, CASE WHEN $3 THEN NULL ELSE $2 END, CASE WHEN $3 THEN 1 ELSE 0 END)
+					ON CONFLICT (engine) DO UPDATE SET last_pass_date = $2,
+												last_success_date = CASE WHEN $3 THEN engine_heartbeat.last_success_date ELSE $2 END,
+												consecutive_failures = CASE WHEN $3 THEN engine_heartbeat.consecutive_failures + 1 ELSE 0 END`
+
+	_, err := s.Db.ExecContext(ctx, upsertSql, engine, passed, failed)
+	return err
+}
+
+// GetEngineHeartbeats gets the latest pass of every engine that ever ran
+func (s PostgresStore) GetEngineHeartbeats() ([]EngineHeartbeat, error) {
+	heartbeats := make([]EngineHeartbeat, 0)
+
+	err := s.Db.Select(&heartbeats, "SELECT * FROM engine_heartbeat ORDER BY engine")
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return heartbeats, nil
+		}
+		return nil, err
+	}
+
+	return heartbeats, nil
+}
@@ -0,0 +1,211 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// ChallengeStatus PENDING waits on the opponent and COUNTERED on the challenger, the rest are closed
+	ChallengeStatus string
+
+	// Challenge one owner's nft challenging another owner's nft, nft names and types are joined in
+	Challenge struct {
+		ID                int             `db:"id"`
+		ChallengerUserID  int             `db:"challenger_user_id"`
+		ChallengerNftID   int             `db:"challenger_nft_id"`
+		ChallengerNftName string          `db:"challenger_nft_name"`
+		ChallengerNftType string          `db:"challenger_nft_type"`
+		OpponentUserID    int             `db:"opponent_user_id"`
+		OpponentNftID     int             `db:"opponent_nft_id"`
+		OpponentNftName   string          `db:"opponent_nft_name"`
+		OpponentNftType   string          `db:"opponent_nft_type"`
+		StakeLovelace     int64           `db:"stake_lovelace"`
+		Status            ChallengeStatus `db:"status"`
+		FightID           sql.NullInt64   `db:"fight_id"`
+		CreatedDate       time.Time       `db:"created_date"`
+		UpdatedDate       time.Time       `db:"updated_date"`
+		ExpiresDate       time.Time       `db:"expires_date"`
+	}
+
+	// execer runs a statement on the db or inside a tx
+	execer interface {
+		ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	}
+)
+
+const (
+	ChallengeStatusPending   ChallengeStatus = "PENDING"
+	ChallengeStatusCountered ChallengeStatus = "COUNTERED"
+	ChallengeStatusAccepted  ChallengeStatus = "ACCEPTED"
+	ChallengeStatusDeclined  ChallengeStatus = "DECLINED"
+	ChallengeStatusCancelled ChallengeStatus = "CANCELLED"
+	ChallengeStatusExpired   ChallengeStatus = "EXPIRED"
+
+	selectChallengeSql = `SELECT ch.*, cn.name as challenger_nft_name, cn.nft_type as challenger_nft_type,
+							opn.name as opponent_nft_name, opn.nft_type as opponent_nft_type
+						FROM challenge ch
+						JOIN nft cn ON cn.id = ch.challenger_nft_id
+						JOIN nft opn ON opn.id = ch.opponent_nft_id`
+)
+
+// Open whether the challenge is still waiting on a response
+func (c Challenge) Open() bool {
+	return c.Status == ChallengeStatusPending || c.Status == ChallengeStatusCountered
+}
+
+// AwaitingUserID the user whose turn it is to respond, 0 once the challenge is closed
+func (c Challenge) AwaitingUserID() int {
+	switch c.Status {
+	case ChallengeStatusPending:
+		return c.OpponentUserID
+	case ChallengeStatusCountered:
+		return c.ChallengerUserID
+	}
+	return 0
+}
+
+// CreateChallenge persist a new pending challenge, returns its id. The same two nfts can't have two open challenges.
+func (s PostgresStore) CreateChallenge(ctx context.Context, challenge Challenge) (int, error) {
+	var id int
+
+	insertChallengeSql := `INSERT INTO challenge (challenger_user_id, challenger_nft_id, opponent_user_id, opponent_nft_id, stake_lovelace, status, created_date, updated_date, expires_date)
+							VALUES ($1, $2, $3, $4, $5, $6, $7, $7, $8) RETURNING id`
+
+	err := s.Db.QueryRowContext(ctx, insertChallengeSql, challenge.ChallengerUserID, challenge.ChallengerNftID, challenge.OpponentUserID,
+		challenge.OpponentNftID, challenge.StakeLovelace, ChallengeStatusPending, time.Now(), challenge.ExpiresDate).Scan(&id)
+	if err != nil {
+		logrus.New().WithError(err).Error("Inserting challenge")
+		return id, err
+	}
+
+	return id, nil
+}
+
+// GetChallenge get a challenge by id, nil if there isn't one
+func (s PostgresStore) GetChallenge(challengeID int) (*Challenge, error) {
+	challenge := Challenge{}
+
+	err := s.Db.Get(&challenge, selectChallengeSql+" WHERE ch.id = $1", challengeID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &challenge, nil
+}
+
+// GetChallengesForUser get the latest challenges a user sent or received, newest first
+func (s PostgresStore) GetChallengesForUser(userID int, limit int) ([]Challenge, error) {
+	challenges := make([]Challenge, 0)
+
+	err := s.Db.Select(&challenges, selectChallengeSql+" WHERE ch.challenger_user_id = $1 OR ch.opponent_user_id = $1 ORDER BY ch.id DESC LIMIT $2", userID, limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return challenges, nil
+		}
+		return nil, err
+	}
+
+	return challenges, nil
+}
+
+// CounterChallenge answers an open challenge with a different stake, handing the turn to the other side and
+// starting the expiry over. Fails if the challenge isn't in the status the counter was made against anymore.
+func (s PostgresStore) CounterChallenge(ctx context.Context, challengeID int, from ChallengeStatus, stakeLovelace int64, expiresDate time.Time) error {
+	to := ChallengeStatusCountered
+	if from == ChallengeStatusCountered {
+		to = ChallengeStatusPending
+	}
+
+	return s.updateChallenge(ctx, s.Db, challengeID, from, "status = $1, stake_lovelace = $2, expires_date = $3", to, stakeLovelace, expiresDate)
+}
+
+// CloseChallenge declines or cancels an open challenge, failing if it isn't in the expected status anymore
+func (s PostgresStore) CloseChallenge(ctx context.Context, challengeID int, from ChallengeStatus, to ChallengeStatus) error {
+	return s.updateChallenge(ctx, s.Db, challengeID, from, "status = $1", to)
+}
+
+// AcceptChallenge persist the fight an accepted challenge is fought in, and the wager on it if the challenge was
+// staked, failing if the challenge isn't in the status it was accepted in anymore
+func (s PostgresStore) AcceptChallenge(ctx context.Context, challengeID int, from ChallengeStatus, fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User, wager *Wager) (int, error) {
+	var id int
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return id, err
+	}
+	defer tx.Rollback()
+
+	id, err = insertFight(ctx, tx, fight, hunterUser, zombieUser, mintingUser, FightActorAPI, fmt.Sprintf("Fight accepted for challenge %d", challengeID))
+	if err != nil {
+		return id, err
+	}
+
+	err = s.updateChallenge(ctx, tx, challengeID, from, "status = $1, fight_id = $2", ChallengeStatusAccepted, id)
+	if err != nil {
+		return id, err
+	}
+
+	if wager != nil {
+		wager.FightID = id
+		wager.ChallengeID = sql.NullInt64{Int64: int64(challengeID), Valid: true}
+		if _, err = insertWager(ctx, tx, *wager); err != nil {
+			return id, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return id, err
+	}
+
+	return id, nil
+}
+
+// ExpireChallenges expires every open challenge past its expiry, returns how many
+func (s PostgresStore) ExpireChallenges(ctx context.Context, now time.Time) (int, error) {
+	result, err := s.Db.ExecContext(ctx, "UPDATE challenge SET status = $1, updated_date = $2 WHERE status in ($3, $4) AND expires_date <= $2",
+		ChallengeStatusExpired, now, ChallengeStatusPending, ChallengeStatusCountered)
+	if err != nil {
+		logrus.New().WithError(err).Error("Expiring challenges")
+		return 0, err
+	}
+
+	expired, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(expired), nil
+}
+
+// updateChallenge sets columns on a challenge still in the from status and not expired, the placeholders in set come
+// first
+func (s PostgresStore) updateChallenge(ctx context.Context, db execer, challengeID int, from ChallengeStatus, set string, args ...interface{}) error {
+	n := len(args)
+	updateChallengeSql := fmt.Sprintf("UPDATE challenge SET %s, updated_date = $%d WHERE id = $%d AND status = $%d AND expires_date > $%d", set, n+1, n+2, n+3, n+1)
+
+	result, err := db.ExecContext(ctx, updateChallengeSql, append(args, time.Now(), challengeID, from)...)
+	if err != nil {
+		logrus.New().WithError(err).Errorf("Updating challenge %d", challengeID)
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return fmt.Errorf("Challenge %d isn't %s anymore", challengeID, from)
+	}
+
+	return nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/sirupsen/logrus"
@@ -12,7 +13,7 @@ import (
 type (
 	//FightDto dto for fight
 	FightDto struct {
-		ID                    int
+		ID                    int        `json:"ID"`
 		ZombieName            string     `json:"zombieName"`
 		HunterName            string     `json:"hunterName"`
 		PaymentAmountLovelace int64      `json:"paymentAmountLovelace"`
@@ -27,8 +28,27 @@ type (
 		Winner                string     `json:"winner"`
 		Loser                 string     `json:"loser"`
 		TweetLink             string     `json:"tweetLink"`
-		HunterSendAddress     string
-		ZombieSendAddress     string
+		HunterSendAddress     string     `json:"HunterSendAddress"`
+		ZombieSendAddress     string     `json:"ZombieSendAddress"`
+
+		// hd wallet index the payment address was derived from, if it was
+		PaymentAddressIndex sql.NullInt64 `json:"-"`
+
+		// tournament the fight decides a match in, paid for from its prize pool
+		TournamentID sql.NullInt64 `json:"-"`
+
+		// commit-reveal proof of the outcome, the secret is never bound or rendered from here
+		Seed       *FightSeed `json:"seed,omitempty"`
+		SeedSecret string     `json:"-"`
+	}
+
+	// FightSeed commit-reveal proof for a fight's outcome. Commitment is sha256(secret), published when the fight is
+	// created, and Seed is sha256(secret || payment tx hash) in bytes. The secret is revealed once the outcome is decided.
+	FightSeed struct {
+		Commitment    string `json:"commitment"`
+		Secret        string `json:"secret,omitempty"`
+		PaymentTxHash string `json:"paymentTxHash,omitempty"`
+		Seed          string `json:"seed,omitempty"`
 	}
 
 	//FightDb struct for fight db
@@ -36,7 +56,7 @@ type (
 		ID                    int            `db:"id"`
 		ZombieName            string         `db:"zombie_name"`
 		HunterName            string         `db:"hunter_name"`
-		Status                string         `db:"status"`
+		Status                FightStatus    `db:"status"`
 		CreatedDate           time.Time      `db:"created_date"`
 		MintedDate            sql.NullTime   `db:"minted_date"`
 		PaymentAmountLovelace int64          `db:"payment_amount_lovelace"`
@@ -62,6 +82,19 @@ type (
 		ZombieSendAddress     sql.NullString `db:"zombie_send_address"`
 		TxID                  sql.NullString `db:"tx_id"`
 		TweetID               sql.NullString `db:"tweet_id"`
+		TweetAttempted        bool           `db:"tweet_attempted"`
+		SignedTxHash          sql.NullString `db:"signed_tx_hash"`
+		SignedTxCbor          sql.NullString `db:"signed_tx_cbor"`
+		SignedTxTTL           sql.NullInt64  `db:"signed_tx_ttl"`
+		PaymentAddressIndex   sql.NullInt64  `db:"payment_address_index"`
+		SeasonID              sql.NullInt64  `db:"season_id"`
+		TournamentID          sql.NullInt64  `db:"tournament_id"`
+		RegenerateImages      bool           `db:"regenerate_images"`
+
+		SeedCommitment sql.NullString `db:"seed_commitment"`
+		SeedSecret     sql.NullString `db:"seed_secret"`
+		SeedTxHash     sql.NullString `db:"seed_tx_hash"`
+		Seed           sql.NullString `db:"seed"`
 	}
 
 	//Alient struct for zfc alien
@@ -86,7 +119,32 @@ type (
 )
 
 // CreateFight persist a new fight
-func (s Store) CreateFight(fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User) (int, error) {
+func (s PostgresStore) CreateFight(fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User) (int, error) {
+	var id int
+
+	ctx := context.Background()
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return id, err
+	}
+	defer tx.Rollback()
+
+	id, err = insertFight(ctx, tx, fight, hunterUser, zombieUser, mintingUser, FightActorAPI, fmt.Sprintf("Fight created by user %d", mintingUser.ID))
+	if err != nil {
+		return id, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return id, err
+	}
+
+	return id, nil
+}
+
+// insertFight inserts a pending fight and its first event, tagged with the season it was created in
+func insertFight(ctx context.Context, tx *sql.Tx, fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User, actor FightActor, reason string) (int, error) {
 	var id int
 
 	insertUserQuery := `INSERT INTO fight ( hunter_user_id,
@@ -101,22 +159,40 @@ func (s Store) CreateFight(fight FightDto, hunterUser UserNfts, zombieUser UserN
 											minting_user_id,
 											hunter_send_address,
 											zombie_send_address,
-											created_date) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+											created_date,
+											payment_address_index,
+											seed_commitment,
+											seed_secret,
+											tournament_id,
+											season_id) VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17,
+												(SELECT id FROM season WHERE start_date <= $13 AND end_date > $13 AND closed_date IS NULL))
 											RETURNING id`
 
-	err := s.Db.QueryRowx(insertUserQuery, hunterUser.UserID, hunterUser.NftID, hunterUser.ListAmount,
+	seedCommitment := sql.NullString{}
+	if fight.Seed != nil {
+		seedCommitment = sql.NullString{String: fight.Seed.Commitment, Valid: true}
+	}
+
+	err := tx.QueryRowContext(ctx, insertUserQuery, hunterUser.UserID, hunterUser.NftID, hunterUser.ListAmount,
 		zombieUser.UserID, zombieUser.NftID, zombieUser.ListAmount,
-		fight.PaymentAmountLovelace, fight.PaymentAddress, fight.Status,
-		mintingUser.ID, fight.HunterSendAddress, fight.ZombieSendAddress, time.Now()).Scan(&id)
+		fight.PaymentAmountLovelace, fight.PaymentAddress, FightStatusPending,
+		mintingUser.ID, fight.HunterSendAddress, fight.ZombieSendAddress, time.Now(), fight.PaymentAddressIndex,
+		seedCommitment, sql.NullString{String: fight.SeedSecret, Valid: fight.SeedSecret != ""}, fight.TournamentID).Scan(&id)
 	if err != nil {
 		return id, err
 	}
 
+	err = insertFightEvent(ctx, tx, id, sql.NullString{}, FightStatusPending, actor, reason)
+	if err != nil {
+		logrus.New().WithError(err).Error("Recording fight event")
+		return id, err
+	}
+
 	return id, nil
 }
 
 //GetFightsForUser get fights for user
-func (s Store) GetFightsForUser(user User) ([]FightDb, error) {
+func (s PostgresStore) GetFightsForUser(user User) ([]FightDb, error) {
 	fights := make([]FightDb, 0)
 
 	userNftQuery := `SELECT f.id,
@@ -131,7 +207,12 @@ func (s Store) GetFightsForUser(user User) ([]FightDb, error) {
 							a.ipfs_hash as ipfs_alien,
 							f.zclifebar,
 							f.zhlifebar,
-							f.tweet_id
+							f.tweet_id,
+							f.tournament_id,
+							f.seed_commitment,
+							f.seed_secret,
+							f.seed_tx_hash,
+							f.seed
 							FROM fight f
 							LEFT JOIN nft znft ON znft.id = f.zombie_nft_id
 							LEFT JOIN nft hnft ON hnft.id = f.hunter_nft_id
@@ -152,7 +233,7 @@ func (s Store) GetFightsForUser(user User) ([]FightDb, error) {
 }
 
 //GetFightsForUserAndId get fights for user and id
-func (s Store) GetFightsForUserAndId(user User, fightId int) ([]FightDb, error) {
+func (s PostgresStore) GetFightsForUserAndId(user User, fightId int) ([]FightDb, error) {
 	fights := make([]FightDb, 0)
 
 	userNftQuery := `SELECT f.id,
@@ -162,7 +243,11 @@ func (s Store) GetFightsForUserAndId(user User, fightId int) ([]FightDb, error)
 							znft.name as zombie_name,
 							hnft.name as hunter_name,
 							f.payment_address,
-							f.payment_amount_lovelace
+							f.payment_amount_lovelace,
+							f.seed_commitment,
+							f.seed_secret,
+							f.seed_tx_hash,
+							f.seed
 							FROM fight f
 							LEFT JOIN nft znft ON znft.id = f.zombie_nft_id
 							LEFT JOIN nft hnft ON hnft.id = f.hunter_nft_id
@@ -180,7 +265,7 @@ func (s Store) GetFightsForUserAndId(user User, fightId int) ([]FightDb, error)
 }
 
 //GetFightForUtxo get fight for utxo and index
-func (s Store) GetFightForUtxo(utxo string, index int) ([]FightDb, error) {
+func (s PostgresStore) GetFightForUtxo(utxo string, index int) ([]FightDb, error) {
 	fights := make([]FightDb, 0)
 
 	userNftQuery := `SELECT f.id,
@@ -194,7 +279,8 @@ func (s Store) GetFightForUtxo(utxo string, index int) ([]FightDb, error) {
 							FROM fight f
 							LEFT JOIN nft znft ON znft.id = f.zombie_nft_id
 							LEFT JOIN nft hnft ON hnft.id = f.hunter_nft_id
-							WHERE f.incoming_utxo = $1 and f.incoming_utxo_index = $2`
+							WHERE (f.incoming_utxo = $1 and f.incoming_utxo_index = $2)
+							OR f.id IN (SELECT fp.fight_id FROM fight_payment fp WHERE fp.tx_hash = $1 and fp.output_index = $2)`
 
 	err := s.Db.Select(&fights, userNftQuery, utxo, index)
 	if err != nil {
@@ -208,7 +294,7 @@ func (s Store) GetFightForUtxo(utxo string, index int) ([]FightDb, error) {
 }
 
 //GetQueuedFight get fight for utxo and index
-func (s Store) GetQueuedFight() ([]FightDb, error) {
+func (s PostgresStore) GetQueuedFight() ([]FightDb, error) {
 	fights := make([]FightDb, 0)
 
 	userNftQuery := `SELECT f.id,
@@ -218,13 +304,23 @@ func (s Store) GetQueuedFight() ([]FightDb, error) {
 							znft.name as zombie_name,
 							hnft.name as hunter_name,
 							f.payment_address,
-							f.payment_amount_lovelace
+							f.payment_amount_lovelace,
+							f.incoming_utxo,
+							f.incoming_utxo_index,
+							f.season_id,
+							f.hunter_send_address,
+							f.zombie_send_address,
+							f.tournament_id,
+							f.seed_commitment,
+							f.seed_secret,
+							f.seed_tx_hash,
+							f.seed
 							FROM fight f
 							LEFT JOIN nft znft ON znft.id = f.zombie_nft_id
 							LEFT JOIN nft hnft ON hnft.id = f.hunter_nft_id
-							WHERE f.status = 'QUEUED'`
+							WHERE f.status = $1`
 
-	err := s.Db.Select(&fights, userNftQuery)
+	err := s.Db.Select(&fights, userNftQuery, FightStatusQueued)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fights, nil
@@ -236,7 +332,7 @@ func (s Store) GetQueuedFight() ([]FightDb, error) {
 }
 
 //GetStagedFights get fight for utxo and index
-func (s Store) GetStagedFights() ([]FightDb, error) {
+func (s PostgresStore) GetStagedFights() ([]FightDb, error) {
 	fights := make([]FightDb, 0)
 
 	userNftQuery := `SELECT f.id,
@@ -264,13 +360,25 @@ func (s Store) GetStagedFights() ([]FightDb, error) {
 							f.hunter_send_address,
 							f.hunter_amount_ada,
 							f.zombie_send_address,
-							f.zombie_amount_ada
+							f.zombie_amount_ada,
+							f.tweet_id,
+							f.tweet_attempted,
+							f.signed_tx_hash,
+							f.signed_tx_cbor,
+							f.signed_tx_ttl,
+							f.payment_address_index,
+							f.tournament_id,
+							f.regenerate_images,
+							f.seed_commitment,
+							f.seed_secret,
+							f.seed_tx_hash,
+							f.seed
 							FROM fight f
 							LEFT JOIN nft znft ON znft.id = f.zombie_nft_id
 							LEFT JOIN nft hnft ON hnft.id = f.hunter_nft_id
-							WHERE f.status = 'STAGED'`
+							WHERE f.status = $1`
 
-	err := s.Db.Select(&fights, userNftQuery)
+	err := s.Db.Select(&fights, userNftQuery, FightStatusStaged)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fights, nil
@@ -282,7 +390,7 @@ func (s Store) GetStagedFights() ([]FightDb, error) {
 }
 
 //GetMintedFights get minted fights
-func (s Store) GetMintedFights() ([]FightDb, error) {
+func (s PostgresStore) GetMintedFights() ([]FightDb, error) {
 	fights := make([]FightDb, 0)
 
 	userNftQuery := `SELECT f.id,
@@ -315,9 +423,9 @@ func (s Store) GetMintedFights() ([]FightDb, error) {
 							FROM fight f
 							LEFT JOIN nft znft ON znft.id = f.zombie_nft_id
 							LEFT JOIN nft hnft ON hnft.id = f.hunter_nft_id
-							WHERE f.status = 'MINTED'`
+							WHERE f.status = $1`
 
-	err := s.Db.Select(&fights, userNftQuery)
+	err := s.Db.Select(&fights, userNftQuery, FightStatusMinted)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return fights, nil
@@ -328,8 +436,8 @@ func (s Store) GetMintedFights() ([]FightDb, error) {
 	return fights, nil
 }
 
-//GetFightForPaymentLastFifteen get fight for utxo and index
-func (s Store) GetFightForPaymentLastFifteen(lovelace int64) (*FightDb, error) {
+//GetUnpaidFightForAmount get unpaid fight created within the payment window for the amount
+func (s PostgresStore) GetUnpaidFightForAmount(lovelace int64, window time.Duration) (*FightDb, error) {
 	fights := make([]FightDb, 0)
 
 	userNftQuery := `SELECT f.id,
@@ -343,9 +451,9 @@ func (s Store) GetFightForPaymentLastFifteen(lovelace int64) (*FightDb, error) {
 							FROM fight f
 							LEFT JOIN nft znft ON znft.id = f.zombie_nft_id
 							LEFT JOIN nft hnft ON hnft.id = f.hunter_nft_id
-							WHERE f.incoming_utxo is null and f.payment_amount_lovelace = $1 and f.created_date > NOW()::timestamp - INTERVAL '20 minutes'`
+							WHERE f.incoming_utxo is null and f.payment_amount_lovelace = $1 and f.created_date > $2 and f.tournament_id is null`
 
-	err := s.Db.Select(&fights, userNftQuery, lovelace)
+	err := s.Db.Select(&fights, userNftQuery, lovelace, time.Now().Add(-window))
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -363,7 +471,7 @@ func (s Store) GetFightForPaymentLastFifteen(lovelace int64) (*FightDb, error) {
 }
 
 //GetNextAvailableAlien get next available alien
-func (s Store) GetNextAvailableAlien() (*Alien, error) {
+func (s PostgresStore) GetNextAvailableAlien() (*Alien, error) {
 	aliens := make([]Alien, 0)
 
 	userNftQuery := `SELECT *
@@ -388,7 +496,7 @@ func (s Store) GetNextAvailableAlien() (*Alien, error) {
 }
 
 //GetAlienByFightId get alient by fight id
-func (s Store) GetAlienByFightId(fightId int) (*Alien, error) {
+func (s PostgresStore) GetAlienByFightId(fightId int) (*Alien, error) {
 	aliens := make([]Alien, 0)
 
 	userNftQuery := `SELECT *
@@ -412,7 +520,39 @@ func (s Store) GetAlienByFightId(fightId int) (*Alien, error) {
 	return &aliens[0], nil
 }
 
-func (s Store) MoveFightFromPendingToQueued(ctx context.Context, fightID int, alienID int, utxo string, utxoIndex int) error {
+// SetFightSeed records the seed a fight's outcome is drawn from, keeping the first one if it's already set
+func (s PostgresStore) SetFightSeed(ctx context.Context, fightID int, paymentTxHash string, seed string) error {
+	updateFightSql := `UPDATE fight SET seed_tx_hash = $1, seed = $2 WHERE id = $3 AND seed is null`
+
+	_, err := s.Db.ExecContext(ctx, updateFightSql, paymentTxHash, seed, fightID)
+	if err != nil {
+		logrus.New().WithError(err).Error("Setting fight seed")
+		return err
+	}
+
+	return nil
+}
+
+// GetFightCombatLog the fight's combat log json, empty until the fight has been fought
+func (s PostgresStore) GetFightCombatLog(ctx context.Context, fightID int) (string, error) {
+	combatLog := sql.NullString{}
+	err := s.Db.GetContext(ctx, &combatLog, "SELECT combat_log FROM fight WHERE id = $1", fightID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return combatLog.String, nil
+}
+
+// MoveFightFromPendingToQueued assigns the alien and records every payment, the first one is the fight's incoming utxo
+func (s PostgresStore) MoveFightFromPendingToQueued(ctx context.Context, fightID int, alienID int, payments []FightPayment) error {
+	if len(payments) == 0 {
+		return fmt.Errorf("No payments for fight %d", fightID)
+	}
+
 	tx, err := s.Db.BeginTx(ctx, nil)
 	if err != nil {
 		logrus.New().WithError(err).Error("Beginning tx")
@@ -428,11 +568,25 @@ func (s Store) MoveFightFromPendingToQueued(ctx context.Context, fightID int, al
 	}
 
 	// update fight
-	_, err = tx.ExecContext(ctx, "UPDATE fight SET status = $1, incoming_utxo = $2, incoming_utxo_index = $3 WHERE id = $4", "QUEUED", utxo, utxoIndex, fightID)
+	err = transitionFight(ctx, tx, fightID, FightStatusQueued, FightActorEngine, paymentReason(payments))
 	if err != nil {
 		logrus.New().WithError(err).Error("Updating fight status")
 		return err
 	}
+	_, err = tx.ExecContext(ctx, "UPDATE fight SET incoming_utxo = $1, incoming_utxo_index = $2 WHERE id = $3", payments[0].TxHash, payments[0].OutputIndex, fightID)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating fight utxo")
+		return err
+	}
+
+	insertPaymentQuery := `INSERT INTO fight_payment (fight_id, tx_hash, output_index, sender_address, lovelace, assets, created_date) VALUES($1, $2, $3, $4, $5, $6, $7)`
+	for _, payment := range payments {
+		_, err = tx.ExecContext(ctx, insertPaymentQuery, fightID, payment.TxHash, payment.OutputIndex, payment.SenderAddress, payment.Lovelace, payment.Assets, time.Now())
+		if err != nil {
+			logrus.New().WithError(err).Error("Inserting fight payment")
+			return err
+		}
+	}
 
 	// Commit the transaction.
 	if err = tx.Commit(); err != nil {
@@ -443,7 +597,15 @@ func (s Store) MoveFightFromPendingToQueued(ctx context.Context, fightID int, al
 	return nil
 }
 
-func (s Store) MoveFightFromQueuedToStaged(ctx context.Context, alienID int, alienIpfs string, fightID int, fightIpfs, background, zombieRecord, hunterRecord string, zcLifeBar, zhLifeBar int, zombieKo, hunterKo, zombieBeahup, hunterBeatup bool, winningNft string, losingNft string) error {
+func paymentReason(payments []FightPayment) string {
+	utxos := make([]string, 0)
+	for _, payment := range payments {
+		utxos = append(utxos, fmt.Sprintf("%s#%d", payment.TxHash, payment.OutputIndex))
+	}
+	return fmt.Sprintf("Payment received in utxo %s", strings.Join(utxos, ", "))
+}
+
+func (s PostgresStore) MoveFightFromQueuedToStaged(ctx context.Context, alienID int, alienIpfs string, fightID int, fightIpfs, background, zombieRecord, hunterRecord string, zcLifeBar, zhLifeBar int, zombieKo, hunterKo, zombieBeahup, hunterBeatup bool, combatLog string, winningNft string, losingNft string) error {
 	tx, err := s.Db.BeginTx(ctx, nil)
 	if err != nil {
 		logrus.New().WithError(err).Error("Beginning tx")
@@ -451,6 +613,13 @@ func (s Store) MoveFightFromQueuedToStaged(ctx context.Context, alienID int, ali
 	}
 	defer tx.Rollback()
 
+	// update fight status first so a fight can't be staged twice
+	err = transitionFight(ctx, tx, fightID, FightStatusStaged, FightActorEngine, fmt.Sprintf("%s defeated %s", winningNft, losingNft))
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating fight status")
+		return err
+	}
+
 	// update alien ipfs
 	_, err = tx.ExecContext(ctx, "UPDATE zfc_alien SET ipfs_hash = $1 WHERE id = $2", alienIpfs, alienID)
 	if err != nil {
@@ -469,22 +638,32 @@ func (s Store) MoveFightFromQueuedToStaged(ctx context.Context, alienID int, ali
 		logrus.New().WithError(err).Error("Setting nft winner")
 		return err
 	}
+	err = rateFight(ctx, tx, fightID, winningNft, losingNft)
+	if err != nil {
+		logrus.New().WithError(err).Error("Rating fight")
+		return err
+	}
+	err = recordSeasonResult(ctx, tx, fightID, winningNft, losingNft)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating season records")
+		return err
+	}
 
-	updateFightSql := `UPDATE fight SET status = $1,
-									ipfs_fight = $2,
-									background = $3,
-									zhLifeBar = $4,
-									zcLifeBar = $5,
-									hunter_record = $6,
-									zombie_record = $7,
-									hunter_ko = $8,
-									zombie_ko = $9,
-									hunter_beatup = $10,
-									zombie_beatup = $11
+	updateFightSql := `UPDATE fight SET ipfs_fight = $1,
+									background = $2,
+									zhLifeBar = $3,
+									zcLifeBar = $4,
+									hunter_record = $5,
+									zombie_record = $6,
+									hunter_ko = $7,
+									zombie_ko = $8,
+									hunter_beatup = $9,
+									zombie_beatup = $10,
+									combat_log = $11
 									WHERE id = $12`
 
 	// update fight
-	_, err = tx.ExecContext(ctx, updateFightSql, "STAGED", fightIpfs, background, zhLifeBar, zcLifeBar, hunterRecord, zombieRecord, hunterKo, zombieKo, hunterBeatup, zombieBeahup, fightID)
+	_, err = tx.ExecContext(ctx, updateFightSql, fightIpfs, background, zhLifeBar, zcLifeBar, hunterRecord, zombieRecord, hunterKo, zombieKo, hunterBeatup, zombieBeahup, combatLog, fightID)
 	if err != nil {
 		logrus.New().WithError(err).Error("Updating fight status")
 		return err
@@ -499,7 +678,7 @@ func (s Store) MoveFightFromQueuedToStaged(ctx context.Context, alienID int, ali
 	return nil
 }
 
-func (s Store) MoveFightFromStagedToMinted(ctx context.Context, fightID int, txHash string) error {
+func (s PostgresStore) MoveFightFromStagedToMinted(ctx context.Context, fightID int, txHash string) error {
 	tx, err := s.Db.BeginTx(ctx, nil)
 	if err != nil {
 		logrus.New().WithError(err).Error("Beginning tx")
@@ -507,13 +686,18 @@ func (s Store) MoveFightFromStagedToMinted(ctx context.Context, fightID int, txH
 	}
 	defer tx.Rollback()
 
-	updateFightSql := `UPDATE fight SET status = $1,
-									minted_date = $2,
-									tx_id = $3
-									WHERE id = $4`
+	err = transitionFight(ctx, tx, fightID, FightStatusMinted, FightActorEngine, fmt.Sprintf("Mint submitted in tx %s", txHash))
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating fight status")
+		return err
+	}
+
+	updateFightSql := `UPDATE fight SET minted_date = $1,
+									tx_id = $2
+									WHERE id = $3`
 
 	// update fight
-	_, err = tx.ExecContext(ctx, updateFightSql, "MINTED", time.Now(), txHash, fightID)
+	_, err = tx.ExecContext(ctx, updateFightSql, time.Now(), txHash, fightID)
 	if err != nil {
 		logrus.New().WithError(err).Error("Updating fight status")
 		return err
@@ -528,7 +712,7 @@ func (s Store) MoveFightFromStagedToMinted(ctx context.Context, fightID int, txH
 	return nil
 }
 
-func (s Store) MoveFightFromMintedToConfirmed(ctx context.Context, fightID int) error {
+func (s PostgresStore) MoveFightFromMintedToConfirmed(ctx context.Context, fightID int) error {
 	tx, err := s.Db.BeginTx(ctx, nil)
 	if err != nil {
 		logrus.New().WithError(err).Error("Beginning tx")
@@ -536,11 +720,8 @@ func (s Store) MoveFightFromMintedToConfirmed(ctx context.Context, fightID int)
 	}
 	defer tx.Rollback()
 
-	updateFightSql := `UPDATE fight SET status = $1
-										WHERE id = $2`
-
 	// update fight
-	_, err = tx.ExecContext(ctx, updateFightSql, "CONFIRMED", fightID)
+	err = transitionFight(ctx, tx, fightID, FightStatusConfirmed, FightActorEngine, "Mint tx found on chain")
 	if err != nil {
 		logrus.New().WithError(err).Error("Updating fight status")
 		return err
@@ -555,7 +736,7 @@ func (s Store) MoveFightFromMintedToConfirmed(ctx context.Context, fightID int)
 	return nil
 }
 
-func (s Store) UpdateTweetID(ctx context.Context, fightID int, tweetID string) error {
+func (s PostgresStore) UpdateTweetID(ctx context.Context, fightID int, tweetID string) error {
 	updateFightSql := `UPDATE fight SET tweet_id = $1
 										WHERE id = $2`
 
@@ -568,3 +749,66 @@ func (s Store) UpdateTweetID(ctx context.Context, fightID int, tweetID string) e
 
 	return nil
 }
+
+// SetAlienIpfs records the uploaded alien image before the fight is staged
+func (s PostgresStore) SetAlienIpfs(ctx context.Context, alienID int, alienIpfs string) error {
+	_, err := s.Db.ExecContext(ctx, "UPDATE zfc_alien SET ipfs_hash = $1 WHERE id = $2", alienIpfs, alienID)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating alien ipfs")
+		return err
+	}
+
+	return nil
+}
+
+// MarkTweetAttempted records that the fight has been tweeted, or tried to be
+func (s PostgresStore) MarkTweetAttempted(ctx context.Context, fightID int) error {
+	_, err := s.Db.ExecContext(ctx, "UPDATE fight SET tweet_attempted = true WHERE id = $1", fightID)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating tweet attempted")
+		return err
+	}
+
+	return nil
+}
+
+// SetSignedMintTx checkpoints the signed mint tx before it is submitted
+func (s PostgresStore) SetSignedMintTx(ctx context.Context, fightID int, txHash string, cborHex string, ttl int) error {
+	updateFightSql := `UPDATE fight SET signed_tx_hash = $1,
+										signed_tx_cbor = $2,
+										signed_tx_ttl = $3
+										WHERE id = $4 AND status = $5 AND signed_tx_hash is null`
+
+	result, err := s.Db.ExecContext(ctx, updateFightSql, txHash, cborHex, ttl, fightID, FightStatusStaged)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating signed tx")
+		return err
+	}
+
+	// another signed tx is already checkpointed, never replace it with a different one
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return fmt.Errorf("Fight %d already has a signed tx or isn't staged", fightID)
+	}
+
+	return nil
+}
+
+// ClearSignedMintTx drops a signed mint tx that expired without reaching the chain
+func (s PostgresStore) ClearSignedMintTx(ctx context.Context, fightID int, txHash string) error {
+	updateFightSql := `UPDATE fight SET signed_tx_hash = null,
+										signed_tx_cbor = null,
+										signed_tx_ttl = null
+										WHERE id = $1 AND status = $2 AND signed_tx_hash = $3`
+
+	_, err := s.Db.ExecContext(ctx, updateFightSql, fightID, FightStatusStaged, txHash)
+	if err != nil {
+		logrus.New().WithError(err).Error("Clearing signed tx")
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,113 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+type (
+	// FightStatus status of a fight as it moves from payment to confirmed mint
+	FightStatus string
+
+	// FightActor who caused a fight to change status
+	FightActor string
+
+	// FightEvent struct to hold one recorded fight status transition
+	FightEvent struct {
+		ID          int            `db:"id"`
+		FightID     int            `db:"fight_id"`
+		FromStatus  sql.NullString `db:"from_status"`
+		ToStatus    FightStatus    `db:"to_status"`
+		Actor       FightActor     `db:"actor"`
+		Reason      string         `db:"reason"`
+		CreatedDate time.Time      `db:"created_date"`
+	}
+
+	// IllegalTransitionError returned when a fight can't move from its current status to the requested one
+	IllegalTransitionError struct {
+		FightID int
+		From    FightStatus
+		To      FightStatus
+	}
+)
+
+// PENDING > QUEUED > STAGED > MINTED > CONFIRMED, a pending or queued fight is VOID when a fighter changes hands
+const (
+	FightStatusPending   FightStatus = "PENDING"
+	FightStatusQueued    FightStatus = "QUEUED"
+	FightStatusStaged    FightStatus = "STAGED"
+	FightStatusMinted    FightStatus = "MINTED"
+	FightStatusConfirmed FightStatus = "CONFIRMED"
+	FightStatusVoid      FightStatus = "VOID"
+
+	FightActorEngine FightActor = "ENGINE"
+	FightActorAPI    FightActor = "API"
+)
+
+// fightTransitions statuses each status is allowed to move to
+var fightTransitions = map[FightStatus][]FightStatus{
+	FightStatusPending:   {FightStatusQueued, FightStatusVoid},
+	FightStatusQueued:    {FightStatusStaged, FightStatusVoid},
+	FightStatusStaged:    {FightStatusMinted},
+	FightStatusMinted:    {FightStatusConfirmed},
+	FightStatusConfirmed: {},
+	FightStatusVoid:      {},
+}
+
+// CanTransitionTo returns true if a fight in this status may move to the provided status
+func (from FightStatus) CanTransitionTo(to FightStatus) bool {
+	for _, allowed := range fightTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+func (e IllegalTransitionError) Error() string {
+	return fmt.Sprintf("Fight %d can't move from %s to %s", e.FightID, e.From, e.To)
+}
+
+// transitionFight moves the locked fight row to a new status and records the event, inside the caller's tx
+func transitionFight(ctx context.Context, tx *sql.Tx, fightID int, to FightStatus, actor FightActor, reason string) error {
+	var from FightStatus
+	err := tx.QueryRowContext(ctx, "SELECT status FROM fight WHERE id = $1 FOR UPDATE", fightID).Scan(&from)
+	if err != nil {
+		return err
+	}
+
+	if !from.CanTransitionTo(to) {
+		return IllegalTransitionError{FightID: fightID, From: from, To: to}
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE fight SET status = $1 WHERE id = $2", to, fightID)
+	if err != nil {
+		return err
+	}
+
+	return insertFightEvent(ctx, tx, fightID, sql.NullString{String: string(from), Valid: true}, to, actor, reason)
+}
+
+func insertFightEvent(ctx context.Context, tx *sql.Tx, fightID int, from sql.NullString, to FightStatus, actor FightActor, reason string) error {
+	insertEventQuery := `INSERT INTO fight_event (fight_id, from_status, to_status, actor, reason, created_date) VALUES($1, $2, $3, $4, $5, $6)`
+
+	_, err := tx.ExecContext(ctx, insertEventQuery, fightID, from, to, actor, reason, time.Now())
+	return err
+}
+
+// GetFightEvents gets the status history of a fight, oldest first
+func (s PostgresStore) GetFightEvents(fightID int) ([]FightEvent, error) {
+	events := make([]FightEvent, 0)
+
+	err := s.Db.Select(&events, "SELECT * FROM fight_event WHERE fight_id = $1 ORDER BY id asc", fightID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return events, nil
+		}
+		return nil, err
+	}
+
+	return events, nil
+}
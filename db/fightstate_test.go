@@ -0,0 +1,59 @@
+package store
+
+import (
+	"context"
+	"testing"
+)
+
+func TestFightStatusTransitions(t *testing.T) {
+	allowed := [][2]FightStatus{
+		{FightStatusPending, FightStatusQueued},
+		{FightStatusQueued, FightStatusStaged},
+		{FightStatusStaged, FightStatusMinted},
+		{FightStatusMinted, FightStatusConfirmed},
+		{FightStatusPending, FightStatusVoid},
+		{FightStatusQueued, FightStatusVoid},
+	}
+	for _, transition := range allowed {
+		if !transition[0].CanTransitionTo(transition[1]) {
+			t.Errorf("Expected %s > %s to be allowed", transition[0], transition[1])
+		}
+	}
+
+	illegal := [][2]FightStatus{
+		{FightStatusPending, FightStatusMinted},
+		{FightStatusQueued, FightStatusQueued},
+		{FightStatusConfirmed, FightStatusMinted},
+		{FightStatusMinted, FightStatusStaged},
+		{FightStatusStaged, FightStatusVoid},
+		{FightStatusVoid, FightStatusQueued},
+	}
+	for _, transition := range illegal {
+		if transition[0].CanTransitionTo(transition[1]) {
+			t.Errorf("Expected %s > %s to be rejected", transition[0], transition[1])
+		}
+	}
+}
+
+func TestMemoryStoreRejectsIllegalTransition(t *testing.T) {
+	s := NewMemoryStore()
+	zombie := s.AddNft("ZombieChains00001", "Zombie")
+	hunter := s.AddNft("ZombieHunter00001", "Hunter")
+	s.InsertUser("user", "", "")
+	user, _ := s.GetUserByNftkeyID("user")
+
+	fightID, err := s.CreateFight(FightDto{PaymentAmountLovelace: 12000000}, UserNfts{UserID: user.ID, NftID: hunter.ID}, UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+	if err != nil {
+		t.Fatalf("Error creating fight %v", err)
+	}
+
+	err = s.MoveFightFromStagedToMinted(context.Background(), fightID, "tx")
+	if _, ok := err.(IllegalTransitionError); !ok {
+		t.Fatalf("Expected illegal transition but got %v", err)
+	}
+
+	events, _ := s.GetFightEvents(fightID)
+	if len(events) != 1 || events[0].ToStatus != FightStatusPending || events[0].Actor != FightActorAPI {
+		t.Fatalf("Expected only the creation event but got %v", events)
+	}
+}
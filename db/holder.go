@@ -0,0 +1,126 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type (
+	// NftHolder where an nft is held on chain, the account is the stake address or the address itself when it has no
+	// stake part
+	NftHolder struct {
+		Address string
+		Account string
+	}
+)
+
+// GetNftsToIndex the nfts whose holder was checked longest ago, never checked first
+func (s PostgresStore) GetNftsToIndex(limit int) ([]Nft, error) {
+	nfts := make([]Nft, 0)
+
+	err := s.Db.Select(&nfts, "SELECT * FROM nft ORDER BY holder_checked_date NULLS FIRST, id LIMIT $1", limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return nfts, nil
+}
+
+// SetNftHolder record where the nft is held as of checked, nil when nothing holds it. Returns the account that held
+// it before
+func (s PostgresStore) SetNftHolder(ctx context.Context, nftID int, holder *NftHolder, checked time.Time) (sql.NullString, error) {
+	var previous sql.NullString
+
+	address, account := sql.NullString{}, sql.NullString{}
+	if holder != nil {
+		address = sql.NullString{String: holder.Address, Valid: true}
+		account = sql.NullString{String: holder.Account, Valid: true}
+	}
+
+	updateSql := `UPDATE nft n SET holder_address = $2, holder_account = $3, holder_checked_date = $4
+					FROM (SELECT id, holder_account FROM nft WHERE id = $1 FOR UPDATE) old
+					WHERE n.id = old.id RETURNING old.holder_account`
+	err := s.Db.QueryRowContext(ctx, updateSql, nftID, address, account, checked).Scan(&previous)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+
+	return previous, nil
+}
+
+// GetUserAccounts the accounts a user holds nfts in, including a wallet user's stake address
+func (s PostgresStore) GetUserAccounts(userID int) ([]string, error) {
+	accounts := make([]string, 0)
+
+	err := s.Db.Select(&accounts, `SELECT account FROM zfc_user_account WHERE zfc_user_id = $1
+									UNION SELECT stake_address FROM zfc_user WHERE id = $1 AND stake_address IS NOT NULL`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+// AddUserAccount record that a user proved they control an account, it's taken off any other user that had it
+func (s PostgresStore) AddUserAccount(userID int, account string) error {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM zfc_user_account WHERE account = $1 AND zfc_user_id <> $2", account, userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO zfc_user_account (zfc_user_id, account, proven) VALUES ($1, $2, true)
+						ON CONFLICT (zfc_user_id, account) DO UPDATE SET proven = true`, userID, account)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetNftkeymeAccounts replaces the accounts a user was given from what nftkey.me says they own. An account someone
+// proved or that's a wallet user's stake address stays theirs
+func (s PostgresStore) SetNftkeymeAccounts(userID int, accounts []string) error {
+	tx, err := s.Db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("DELETE FROM zfc_user_account WHERE zfc_user_id = $1 AND NOT proven", userID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO zfc_user_account (zfc_user_id, account)
+						SELECT $1::integer, a.account FROM unnest($2::varchar[]) AS a(account)
+						WHERE NOT EXISTS (SELECT 1 FROM zfc_user_account ua WHERE ua.account = a.account AND ua.proven)
+						AND NOT EXISTS (SELECT 1 FROM zfc_user u WHERE u.stake_address = a.account AND u.id <> $1)
+						ON CONFLICT DO NOTHING`, userID, pq.Array(accounts))
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// GetNftsHeldByUser the nfts the index has in any of the user's accounts
+func (s PostgresStore) GetNftsHeldByUser(userID int) ([]Nft, error) {
+	nfts := make([]Nft, 0)
+
+	err := s.Db.Select(&nfts, `SELECT * FROM nft WHERE holder_account IN (
+									SELECT account FROM zfc_user_account WHERE zfc_user_id = $1
+									UNION SELECT stake_address FROM zfc_user WHERE id = $1 AND stake_address IS NOT NULL)
+								ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return nfts, nil
+}
@@ -0,0 +1,50 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+)
+
+// GetNftLastFightDate when the nft's latest paid fight was created, nil if it never fought
+func (s PostgresStore) GetNftLastFightDate(nftID int) (*time.Time, error) {
+	var last sql.NullTime
+
+	err := s.Db.Get(&last, "SELECT max(created_date) FROM fight WHERE (zombie_nft_id = $1 OR hunter_nft_id = $1) AND status NOT IN ($2, $3)", nftID, FightStatusPending, FightStatusVoid)
+	if err != nil {
+		return nil, err
+	}
+	if !last.Valid {
+		return nil, nil
+	}
+
+	return &last.Time, nil
+}
+
+// CountNftFights how many paid fights the nft is in that were created from from up to but not including to
+func (s PostgresStore) CountNftFights(nftID int, from time.Time, to time.Time) (int, error) {
+	var count int
+
+	err := s.Db.Get(&count, `SELECT count(*) FROM fight WHERE (zombie_nft_id = $1 OR hunter_nft_id = $1) AND status NOT IN ($2, $5)
+								AND created_date >= $3 AND created_date < $4`, nftID, FightStatusPending, from, to, FightStatusVoid)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// GetUserFightDates when each fight the user created since was created, paid or not, oldest first. Tournament fights
+// don't count
+func (s PostgresStore) GetUserFightDates(userID int, since time.Time) ([]time.Time, error) {
+	dates := make([]time.Time, 0)
+
+	err := s.Db.Select(&dates, "SELECT created_date FROM fight WHERE minting_user_id = $1 AND created_date > $2 AND tournament_id IS NULL ORDER BY created_date", userID, since)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return dates, nil
+		}
+		return nil, err
+	}
+
+	return dates, nil
+}
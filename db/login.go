@@ -0,0 +1,62 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+type (
+	// LoginNonce a nonce handed out for a stake address to sign
+	LoginNonce struct {
+		Nonce        string       `db:"nonce"`
+		StakeAddress string       `db:"stake_address"`
+		CreatedDate  time.Time    `db:"created_date"`
+		ExpiresDate  time.Time    `db:"expires_date"`
+		UsedDate     sql.NullTime `db:"used_date"`
+	}
+)
+
+// GetUserByStakeAddress Gets a wallet user using the stake address they log in with
+func (s PostgresStore) GetUserByStakeAddress(stakeAddress string) (*User, error) {
+	user := User{}
+	err := s.Db.Get(&user, selectUserSql+" where stake_address = $1", stakeAddress)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &user, nil
+}
+
+// InsertWalletUser inserts a new user logging in with their wallet
+func (s PostgresStore) InsertWalletUser(stakeAddress string) error {
+	_, err := s.Db.Exec(`INSERT INTO zfc_user (stake_address) VALUES($1)`, stakeAddress)
+	return err
+}
+
+// CreateLoginNonce persist a nonce for a stake address to sign
+func (s PostgresStore) CreateLoginNonce(ctx context.Context, nonce LoginNonce) error {
+	_, err := s.Db.ExecContext(ctx, `INSERT INTO login_nonce (nonce, stake_address, created_date, expires_date) VALUES ($1, $2, $3, $4)`,
+		nonce.Nonce, nonce.StakeAddress, time.Now(), nonce.ExpiresDate)
+	return err
+}
+
+// UseLoginNonce marks a nonce handed out for the stake address used, false if there is no such nonce or it was
+// already used or expired
+func (s PostgresStore) UseLoginNonce(ctx context.Context, nonce string, stakeAddress string, now time.Time) (bool, error) {
+	result, err := s.Db.ExecContext(ctx, `UPDATE login_nonce SET used_date = $1
+							WHERE nonce = $2 AND stake_address = $3 AND used_date IS NULL AND expires_date > $1`, now, nonce, stakeAddress)
+	if err != nil {
+		return false, err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return rows == 1, nil
+}
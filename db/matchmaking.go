@@ -0,0 +1,174 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// MatchmakingStatus status of a queued nft, QUEUED until it's matched, expires or is taken out of the queue
+	MatchmakingStatus string
+
+	// MatchmakingEntry a user's own nft waiting to be paired with a listed opponent, nft name and type are joined in
+	MatchmakingEntry struct {
+		ID          int               `db:"id"`
+		UserID      int               `db:"zfc_user_id"`
+		NftID       int               `db:"nft_id"`
+		NftName     string            `db:"name"`
+		NftType     string            `db:"nft_type"`
+		Status      MatchmakingStatus `db:"status"`
+		FightID     sql.NullInt64     `db:"fight_id"`
+		CreatedDate time.Time         `db:"created_date"`
+		ExpiresDate time.Time         `db:"expires_date"`
+		MatchedDate sql.NullTime      `db:"matched_date"`
+	}
+
+	// MatchmakingOpponent a listed nft with its owner and rating
+	MatchmakingOpponent struct {
+		UserNfts
+		Rating          float64 `db:"rating"`
+		RatingDeviation float64 `db:"rating_deviation"`
+	}
+)
+
+const (
+	MatchmakingStatusQueued    MatchmakingStatus = "QUEUED"
+	MatchmakingStatusMatched   MatchmakingStatus = "MATCHED"
+	MatchmakingStatusExpired   MatchmakingStatus = "EXPIRED"
+	MatchmakingStatusCancelled MatchmakingStatus = "CANCELLED"
+)
+
+// QueueMatchmakingEntry persist a new queued entry, returns its id. An nft already in the queue can't be queued again.
+func (s PostgresStore) QueueMatchmakingEntry(ctx context.Context, entry MatchmakingEntry) (int, error) {
+	var id int
+
+	insertEntrySql := `INSERT INTO matchmaking_entry (zfc_user_id, nft_id, status, created_date, expires_date)
+						VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	err := s.Db.QueryRowContext(ctx, insertEntrySql, entry.UserID, entry.NftID, MatchmakingStatusQueued, time.Now(), entry.ExpiresDate).Scan(&id)
+	if err != nil {
+		logrus.New().WithError(err).Error("Inserting matchmaking entry")
+		return id, err
+	}
+
+	return id, nil
+}
+
+// GetMatchmakingEntriesForUser get a user's latest entries, newest first
+func (s PostgresStore) GetMatchmakingEntriesForUser(userID int, limit int) ([]MatchmakingEntry, error) {
+	entries := make([]MatchmakingEntry, 0)
+
+	entrySql := `SELECT e.*, n.name, n.nft_type FROM matchmaking_entry e
+					JOIN nft n ON n.id = e.nft_id
+					WHERE e.zfc_user_id = $1
+					ORDER BY e.id DESC LIMIT $2`
+	err := s.Db.Select(&entries, entrySql, userID, limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entries, nil
+		}
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// GetMatchmakingOpponents get every listed nft of a type that isn't the user's own
+func (s PostgresStore) GetMatchmakingOpponents(nftType string, userID int) ([]MatchmakingOpponent, error) {
+	opponents := make([]MatchmakingOpponent, 0)
+
+	opponentSql := `SELECT un.zfc_user_id, un.nft_id, un.amount_ada, un.listed_date, n.name, n.nft_type, n.wins, n.loses, n.rating, n.rating_deviation
+					FROM zfc_user_nft un
+					JOIN nft n ON n.id = un.nft_id
+					WHERE un.amount_ada is not null AND n.nft_type = $1 AND un.zfc_user_id <> $2
+					ORDER BY un.listed_date, n.id`
+	err := s.Db.Select(&opponents, opponentSql, nftType, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return opponents, nil
+		}
+		return nil, err
+	}
+
+	return opponents, nil
+}
+
+// MatchMatchmakingEntry persist the fight a queued entry was matched to, failing if the entry isn't queued anymore
+func (s PostgresStore) MatchMatchmakingEntry(ctx context.Context, entryID int, fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User) (int, error) {
+	var id int
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return id, err
+	}
+	defer tx.Rollback()
+
+	id, err = insertFight(ctx, tx, fight, hunterUser, zombieUser, mintingUser, FightActorAPI, fmt.Sprintf("Fight matched for matchmaking entry %d", entryID))
+	if err != nil {
+		return id, err
+	}
+
+	updateEntrySql := `UPDATE matchmaking_entry SET status = $1, fight_id = $2, matched_date = $3
+						WHERE id = $4 AND status = $5 AND expires_date > $3`
+	result, err := tx.ExecContext(ctx, updateEntrySql, MatchmakingStatusMatched, id, time.Now(), entryID, MatchmakingStatusQueued)
+	if err != nil {
+		logrus.New().WithError(err).Error("Matching matchmaking entry")
+		return id, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return id, err
+	}
+	if updated != 1 {
+		return id, fmt.Errorf("Matchmaking entry %d isn't queued anymore", entryID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return id, err
+	}
+
+	return id, nil
+}
+
+// CancelMatchmakingEntry takes a user's queued entry out of the queue
+func (s PostgresStore) CancelMatchmakingEntry(ctx context.Context, userID int, entryID int) error {
+	result, err := s.Db.ExecContext(ctx, "UPDATE matchmaking_entry SET status = $1 WHERE id = $2 AND zfc_user_id = $3 AND status = $4",
+		MatchmakingStatusCancelled, entryID, userID, MatchmakingStatusQueued)
+	if err != nil {
+		logrus.New().WithError(err).Error("Cancelling matchmaking entry")
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return fmt.Errorf("Matchmaking entry %d isn't queued for user %d", entryID, userID)
+	}
+
+	return nil
+}
+
+// ExpireMatchmakingEntries expires every queued entry past its expiry, returns how many
+func (s PostgresStore) ExpireMatchmakingEntries(ctx context.Context, now time.Time) (int, error) {
+	result, err := s.Db.ExecContext(ctx, "UPDATE matchmaking_entry SET status = $1 WHERE status = $2 AND expires_date <= $3",
+		MatchmakingStatusExpired, MatchmakingStatusQueued, now)
+	if err != nil {
+		logrus.New().WithError(err).Error("Expiring matchmaking entries")
+		return 0, err
+	}
+
+	expired, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return int(expired), nil
+}
@@ -0,0 +1,2923 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/reliablestaking/zombie-fight-club-server/glicko"
+)
+
+type (
+	// MemoryStore in-memory Store used to test handlers and the minting engine without postgres
+	MemoryStore struct {
+		mu sync.Mutex
+
+		users        map[int]*User
+		loginNonces  []*LoginNonce
+		userAccounts []memoryUserAccount
+		nfts         map[int]*Nft
+		userNfts     []*memoryUserNft
+		fights       map[int]*memoryFight
+		aliens       map[int]*Alien
+		events       []FightEvent
+		payments     []FightPayment
+		refunds      []*Refund
+		heldPayments map[string]time.Time
+
+		ratingHistory []RatingHistory
+
+		seasons         []*Season
+		seasonRecords   []*memorySeasonRecord
+		seasonStandings []SeasonStanding
+
+		tournaments       []*Tournament
+		tournamentEntries []*TournamentEntry
+		tournamentMatches []*TournamentMatch
+
+		matchmakingEntries []*MatchmakingEntry
+		challenges         []*Challenge
+
+		wagers        []*Wager
+		wagerDeposits []*WagerDeposit
+		wagerEvents   []WagerEvent
+
+		audits     []AdminAudit
+		heartbeats map[string]*EngineHeartbeat
+
+		nextUserID  int
+		nextNftID   int
+		nextFightID int
+		nextAlienID int
+
+		nextPaymentAddressIndex int
+	}
+
+	memoryUserNft struct {
+		UserID     int
+		NftID      int
+		ListAmount sql.NullInt16
+		ListDate   sql.NullTime
+	}
+
+	memoryUserAccount struct {
+		UserID  int
+		Account string
+		Proven  bool
+	}
+
+	memorySeasonRecord struct {
+		SeasonID int
+		NftID    int
+		Wins     int
+		Loses    int
+	}
+
+	memoryFight struct {
+		FightDb
+		HunterUserID   int
+		HunterNftID    int
+		ZombieUserID   int
+		ZombieNftID    int
+		MintingUserID  int
+		ZombieBeatup   bool
+		HunterBeatup   bool
+		CombatLog      sql.NullString
+		memoryRowOrder int
+	}
+)
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		users:       make(map[int]*User),
+		nfts:        make(map[int]*Nft),
+		userNfts:    make([]*memoryUserNft, 0),
+		fights:      make(map[int]*memoryFight),
+		aliens:      make(map[int]*Alien),
+		events:      make([]FightEvent, 0),
+		payments:    make([]FightPayment, 0),
+		refunds:     make([]*Refund, 0),
+		heartbeats:  make(map[string]*EngineHeartbeat),
+		nextUserID:  1,
+		nextNftID:   1,
+		nextFightID: 1,
+		nextAlienID: 1,
+	}
+}
+
+// AddNft seeds an nft, the equivalent of importing the nft table
+func (s *MemoryStore) AddNft(name string, nftType string) *Nft {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nft := &Nft{
+		ID:               s.nextNftID,
+		NftName:          name,
+		NftType:          nftType,
+		Rating:           glicko.DefaultRating,
+		RatingDeviation:  glicko.DefaultDeviation,
+		RatingVolatility: glicko.DefaultVolatility,
+	}
+	s.nfts[nft.ID] = nft
+	s.nextNftID++
+
+	copied := *nft
+	return &copied
+}
+
+// SetNftRating sets an nft's rating and deviation as if it had been fought
+func (s *MemoryStore) SetNftRating(nftID int, rating float64, deviation float64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if nft, found := s.nfts[nftID]; found {
+		nft.Rating, nft.RatingDeviation = rating, deviation
+	}
+}
+
+// AddAlien seeds an unassigned alien, the equivalent of importing the zfc_alien table
+func (s *MemoryStore) AddAlien(alien Alien) *Alien {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alien.ID = s.nextAlienID
+	if alien.Collection == "" {
+		alien.Collection = "Zombie Fight Club Aliens"
+	}
+	if alien.Site == "" {
+		alien.Site = "https://zombiechains.io/"
+	}
+	if alien.Twitter == "" {
+		alien.Twitter = "https://twitter.com/ZombieChains"
+	}
+	if alien.Copyright == "" {
+		alien.Copyright = "2022 Zombie Chains"
+	}
+	s.aliens[alien.ID] = &alien
+	s.nextAlienID++
+
+	copied := alien
+	return &copied
+}
+
+// GetUserByNftkeyID Gets a user using their nftkey id
+func (s *MemoryStore) GetUserByNftkeyID(nftKeyUserID string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		// wallet users have no nftkey id
+		if user.NftkeymeID == nftKeyUserID && !user.StakeAddress.Valid {
+			copied := *user
+			return &copied, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetUserByID Gets a user using their id
+func (s *MemoryStore) GetUserByID(userID int) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, found := s.users[userID]
+	if !found {
+		return nil, nil
+	}
+
+	copied := *user
+	return &copied, nil
+}
+
+// InsertUser inserts a new user
+func (s *MemoryStore) InsertUser(nftkeyID, accessToken, refreshToken string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.NftkeymeID == nftkeyID {
+			return fmt.Errorf("duplicate key value violates unique constraint on nftkeyme_id %s", nftkeyID)
+		}
+	}
+
+	s.users[s.nextUserID] = &User{
+		ID:                   s.nextUserID,
+		NftkeymeID:           nftkeyID,
+		NftkeymeAccessToken:  accessToken,
+		NftkeymeRefreshToken: refreshToken,
+	}
+	s.nextUserID++
+
+	return nil
+}
+
+// UpdatedUser updates a user's tokens
+func (s *MemoryStore) UpdatedUser(nftkeyID, accessToken, refreshToken string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.NftkeymeID == nftkeyID {
+			user.NftkeymeAccessToken = accessToken
+			user.NftkeymeRefreshToken = refreshToken
+		}
+	}
+
+	return nil
+}
+
+// SetLastAssetCheckTime updates last asset check time
+func (s *MemoryStore) SetLastAssetCheckTime(userID int, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user, found := s.users[userID]; found {
+		user.LastAssetCheckTime = sql.NullTime{Time: now, Valid: true}
+	}
+
+	return nil
+}
+
+// GetUserByStakeAddress Gets a wallet user using the stake address they log in with
+func (s *MemoryStore) GetUserByStakeAddress(stakeAddress string) (*User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.StakeAddress.Valid && user.StakeAddress.String == stakeAddress {
+			copied := *user
+			return &copied, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// InsertWalletUser inserts a new user logging in with their wallet
+func (s *MemoryStore) InsertWalletUser(stakeAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.users {
+		if user.StakeAddress.Valid && user.StakeAddress.String == stakeAddress {
+			return fmt.Errorf("duplicate key value violates unique constraint on stake_address %s", stakeAddress)
+		}
+	}
+
+	s.users[s.nextUserID] = &User{
+		ID:           s.nextUserID,
+		StakeAddress: sql.NullString{String: stakeAddress, Valid: true},
+	}
+	s.nextUserID++
+
+	return nil
+}
+
+// CreateLoginNonce persist a nonce for a stake address to sign
+func (s *MemoryStore) CreateLoginNonce(ctx context.Context, nonce LoginNonce) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.loginNonces {
+		if existing.Nonce == nonce.Nonce {
+			return fmt.Errorf("duplicate key value violates unique constraint on login_nonce %s", nonce.Nonce)
+		}
+	}
+
+	nonce.CreatedDate = time.Now()
+	nonce.UsedDate = sql.NullTime{}
+	s.loginNonces = append(s.loginNonces, &nonce)
+
+	return nil
+}
+
+// UseLoginNonce marks a nonce handed out for the stake address used, false if there is no such nonce or it was
+// already used or expired
+func (s *MemoryStore) UseLoginNonce(ctx context.Context, nonce string, stakeAddress string, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.loginNonces {
+		if existing.Nonce == nonce && existing.StakeAddress == stakeAddress && !existing.UsedDate.Valid && existing.ExpiresDate.After(now) {
+			existing.UsedDate = sql.NullTime{Time: now, Valid: true}
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+// GetNftsOwnedByUser Gets owned nfts by user
+func (s *MemoryStore) GetNftsOwnedByUser(userID int) ([]UserNfts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userNfts := make([]UserNfts, 0)
+	for _, userNft := range s.userNfts {
+		if userNft.UserID == userID {
+			userNfts = append(userNfts, s.joinUserNft(userNft))
+		}
+	}
+
+	return userNfts, nil
+}
+
+// InsertZcNftOwnedByUser inserts nft owned by user
+func (s *MemoryStore) InsertZcNftOwnedByUser(userID int, nftID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, userNft := range s.userNfts {
+		if userNft.UserID == userID && userNft.NftID == nftID {
+			return fmt.Errorf("duplicate key value violates unique constraint on zfc_user_nft %d/%d", userID, nftID)
+		}
+	}
+	if _, found := s.users[userID]; !found {
+		return fmt.Errorf("No user with id %d", userID)
+	}
+	if _, found := s.nfts[nftID]; !found {
+		return fmt.Errorf("No nft with id %d", nftID)
+	}
+
+	s.userNfts = append(s.userNfts, &memoryUserNft{UserID: userID, NftID: nftID})
+
+	return nil
+}
+
+// RemoveZcNftNotOwnedByUser removes nft not owned by user
+func (s *MemoryStore) RemoveZcNftNotOwnedByUser(userID int, nftID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeUserNfts(func(userNft *memoryUserNft) bool {
+		return userNft.UserID != userID && userNft.NftID == nftID
+	})
+
+	return nil
+}
+
+// RemoveZcNftOwnedByUser remove nft owned by user
+func (s *MemoryStore) RemoveZcNftOwnedByUser(userID int, nftID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.removeUserNfts(func(userNft *memoryUserNft) bool {
+		return userNft.UserID == userID && userNft.NftID == nftID
+	})
+
+	return nil
+}
+
+// UpdateNftListPrice update list price
+func (s *MemoryStore) UpdateNftListPrice(listPrice *int16, userID, nftID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, userNft := range s.userNfts {
+		if userNft.UserID == userID && userNft.NftID == nftID {
+			if listPrice == nil {
+				userNft.ListAmount = sql.NullInt16{}
+			} else {
+				userNft.ListAmount = sql.NullInt16{Int16: *listPrice, Valid: true}
+			}
+			userNft.ListDate = sql.NullTime{Time: time.Now(), Valid: true}
+		}
+	}
+
+	return nil
+}
+
+// GetNftByName get nft by name
+func (s *MemoryStore) GetNftByName(name string) (*Nft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nft := s.nftByName(name)
+	if nft == nil {
+		return nil, nil
+	}
+
+	copied := *nft
+	return &copied, nil
+}
+
+// GetListedNfts get all nfts listed
+func (s *MemoryStore) GetListedNfts(nftType string, limit int, random bool) ([]UserNfts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userNfts := make([]UserNfts, 0)
+	for _, userNft := range s.userNfts {
+		joined := s.joinUserNft(userNft)
+		if joined.ListAmount.Valid && joined.NftType == nftType {
+			userNfts = append(userNfts, joined)
+		}
+	}
+
+	if random {
+		rand.Shuffle(len(userNfts), func(i, j int) {
+			userNfts[i], userNfts[j] = userNfts[j], userNfts[i]
+		})
+	}
+	if len(userNfts) > limit {
+		userNfts = userNfts[:limit]
+	}
+
+	return userNfts, nil
+}
+
+// GetListedNftsByName get listed nfts with a name containing the provided name
+func (s *MemoryStore) GetListedNftsByName(nftType string, name string) ([]UserNfts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userNfts := make([]UserNfts, 0)
+	for _, userNft := range s.userNfts {
+		joined := s.joinUserNft(userNft)
+		if joined.ListAmount.Valid && joined.NftType == nftType && strings.Contains(joined.NftName, name) {
+			userNfts = append(userNfts, joined)
+		}
+	}
+
+	return userNfts, nil
+}
+
+// GetListedNftByName get nft by name
+func (s *MemoryStore) GetListedNftByName(name string) ([]UserNfts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userNfts := make([]UserNfts, 0)
+	for _, userNft := range s.userNfts {
+		joined := s.joinUserNft(userNft)
+		if joined.NftName == name {
+			userNfts = append(userNfts, joined)
+		}
+	}
+
+	return userNfts, nil
+}
+
+// GetNftMostWins get nfts with the most wins
+func (s *MemoryStore) GetNftMostWins(limit int) ([]Nft, error) {
+	return s.sortedNfts(limit, nil, func(a, b Nft) bool {
+		return a.Wins > b.Wins
+	}), nil
+}
+
+// GetNftMostLoses get nfts with the most loses
+func (s *MemoryStore) GetNftMostLoses(limit int) ([]Nft, error) {
+	return s.sortedNfts(limit, nil, func(a, b Nft) bool {
+		return a.Loses > b.Loses
+	}), nil
+}
+
+// GetNftHighestPercentMinimum get nfts with the best win percent and at least minimum wins
+func (s *MemoryStore) GetNftHighestPercentMinimum(minimum int, limit int) ([]Nft, error) {
+	nfts := s.sortedNfts(limit, func(nft Nft) bool {
+		return nft.Wins >= minimum
+	}, func(a, b Nft) bool {
+		return winPercent(a) > winPercent(b)
+	})
+
+	for i := range nfts {
+		nfts[i].Percent = sql.NullFloat64{Float64: winPercent(nfts[i]), Valid: true}
+	}
+
+	return nfts, nil
+}
+
+// GetNftHighestRating get nfts with the highest conservative rating, rating less twice the deviation
+func (s *MemoryStore) GetNftHighestRating(limit int) ([]Nft, error) {
+	return s.sortedNfts(limit, nil, func(a, b Nft) bool {
+		return a.GlickoRating().Conservative() > b.GlickoRating().Conservative()
+	}), nil
+}
+
+// GetNftRatingHistory an nft's ratings after each of its fights, oldest first
+func (s *MemoryStore) GetNftRatingHistory(nftID int) ([]RatingHistory, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	history := make([]RatingHistory, 0)
+	for _, rating := range s.ratingHistory {
+		if rating.NftID == nftID {
+			history = append(history, rating)
+		}
+	}
+	return history, nil
+}
+
+// BackfillRatings resets every rating and replays every fought fight in the order they were created, returns the
+// number of fights rated
+func (s *MemoryStore) BackfillRatings(ctx context.Context) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ratingHistory = nil
+	for _, nft := range s.nfts {
+		nft.Rating, nft.RatingDeviation, nft.RatingVolatility = glicko.DefaultRating, glicko.DefaultDeviation, glicko.DefaultVolatility
+	}
+
+	fought := make([]*memoryFight, 0)
+	for _, fight := range s.fights {
+		if fight.Status == FightStatusStaged || fight.Status == FightStatusMinted || fight.Status == FightStatusConfirmed {
+			fought = append(fought, fight)
+		}
+	}
+	sort.Slice(fought, func(i, j int) bool {
+		if fought[i].CreatedDate.Equal(fought[j].CreatedDate) {
+			return fought[i].ID < fought[j].ID
+		}
+		return fought[i].CreatedDate.Before(fought[j].CreatedDate)
+	})
+
+	for _, fight := range fought {
+		// the winner has the bigger life bar, same as the minted metadata
+		winner, loser := s.nfts[fight.HunterNftID], s.nfts[fight.ZombieNftID]
+		if fight.ZombieLifeBar.Int64 > fight.HunterLifeBar.Int64 {
+			winner, loser = loser, winner
+		}
+		if winner == nil || loser == nil {
+			return 0, fmt.Errorf("Fight %d is missing an nft", fight.ID)
+		}
+		if err := s.rateFight(fight.ID, winner.NftName, loser.NftName); err != nil {
+			return 0, err
+		}
+	}
+
+	return len(fought), nil
+}
+
+// rateFight updates both nfts' ratings for the fight's result, callers hold the lock
+func (s *MemoryStore) rateFight(fightID int, winningNft string, losingNft string) error {
+	winner, loser := s.nftByName(winningNft), s.nftByName(losingNft)
+	if winner == nil || loser == nil {
+		return fmt.Errorf("No nft to rate for %s or %s", winningNft, losingNft)
+	}
+
+	winnerRating, loserRating := rateFightResult(winner.GlickoRating(), loser.GlickoRating())
+	for _, rated := range []struct {
+		nft    *Nft
+		rating glicko.Rating
+	}{{winner, winnerRating}, {loser, loserRating}} {
+		rated.nft.Rating, rated.nft.RatingDeviation, rated.nft.RatingVolatility = rated.rating.Rating, rated.rating.Deviation, rated.rating.Volatility
+		s.ratingHistory = append(s.ratingHistory, RatingHistory{
+			ID:               len(s.ratingHistory) + 1,
+			NftID:            rated.nft.ID,
+			FightID:          fightID,
+			Rating:           rated.rating.Rating,
+			RatingDeviation:  rated.rating.Deviation,
+			RatingVolatility: rated.rating.Volatility,
+			CreatedDate:      time.Now(),
+		})
+	}
+
+	return nil
+}
+
+// CreateSeason persist a new season, returns its id
+func (s *MemoryStore) CreateSeason(ctx context.Context, name string, start time.Time, end time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seasons := make([]Season, 0, len(s.seasons))
+	for _, season := range s.seasons {
+		seasons = append(seasons, *season)
+	}
+	if err := validateSeason(name, start, end, seasons); err != nil {
+		return 0, err
+	}
+
+	season := &Season{
+		ID:          len(s.seasons) + 1,
+		Name:        name,
+		StartDate:   start,
+		EndDate:     end,
+		CreatedDate: time.Now(),
+	}
+	s.seasons = append(s.seasons, season)
+
+	return season.ID, nil
+}
+
+// GetSeasons get every season, latest first
+func (s *MemoryStore) GetSeasons() ([]Season, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	seasons := make([]Season, 0, len(s.seasons))
+	for _, season := range s.seasons {
+		seasons = append(seasons, *season)
+	}
+	sort.Slice(seasons, func(i, j int) bool {
+		return seasons[i].StartDate.After(seasons[j].StartDate)
+	})
+
+	return seasons, nil
+}
+
+// GetSeason get a season by id, nil if there isn't one
+func (s *MemoryStore) GetSeason(seasonID int) (*Season, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, season := range s.seasons {
+		if season.ID == seasonID {
+			copied := *season
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+// GetCurrentSeason get the open season fights created at now are tagged with, nil outside of a season
+func (s *MemoryStore) GetCurrentSeason(now time.Time) (*Season, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if season := s.currentSeason(now); season != nil {
+		copied := *season
+		return &copied, nil
+	}
+	return nil, nil
+}
+
+// CloseSeason stops counting fights toward the season and snapshots its final standings, returns the number of nfts
+// ranked
+func (s *MemoryStore) CloseSeason(ctx context.Context, seasonID int) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var season *Season
+	for _, existing := range s.seasons {
+		if existing.ID == seasonID && !existing.ClosedDate.Valid {
+			season = existing
+		}
+	}
+	if season == nil {
+		return 0, fmt.Errorf("Season %d doesn't exist or is already closed", seasonID)
+	}
+	season.ClosedDate = sql.NullTime{Time: time.Now(), Valid: true}
+
+	records := make([]*memorySeasonRecord, 0)
+	for _, record := range s.seasonRecords {
+		if record.SeasonID == seasonID {
+			records = append(records, record)
+		}
+	}
+	sort.Slice(records, func(i, j int) bool {
+		if records[i].Wins != records[j].Wins {
+			return records[i].Wins > records[j].Wins
+		}
+		if records[i].Loses != records[j].Loses {
+			return records[i].Loses < records[j].Loses
+		}
+		return records[i].NftID < records[j].NftID
+	})
+	for i, record := range records {
+		nft := s.nfts[record.NftID]
+		s.seasonStandings = append(s.seasonStandings, SeasonStanding{
+			SeasonID:        seasonID,
+			NftID:           record.NftID,
+			NftName:         nft.NftName,
+			NftType:         nft.NftType,
+			Rank:            i + 1,
+			Wins:            record.Wins,
+			Loses:           record.Loses,
+			Rating:          nft.Rating,
+			RatingDeviation: nft.RatingDeviation,
+		})
+	}
+
+	return len(records), nil
+}
+
+// GetSeasonStandings a closed season's final standings, best first
+func (s *MemoryStore) GetSeasonStandings(seasonID int) ([]SeasonStanding, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	standings := make([]SeasonStanding, 0)
+	for _, standing := range s.seasonStandings {
+		if standing.SeasonID == seasonID {
+			standings = append(standings, standing)
+		}
+	}
+	return standings, nil
+}
+
+// GetSeasonMostWins get nfts with the most wins in a season
+func (s *MemoryStore) GetSeasonMostWins(seasonID int, limit int) ([]Nft, error) {
+	return s.sortedSeasonNfts(seasonID, limit, nil, func(a, b Nft) bool {
+		return a.Wins > b.Wins
+	}), nil
+}
+
+// GetSeasonMostLoses get nfts with the most loses in a season
+func (s *MemoryStore) GetSeasonMostLoses(seasonID int, limit int) ([]Nft, error) {
+	return s.sortedSeasonNfts(seasonID, limit, nil, func(a, b Nft) bool {
+		return a.Loses > b.Loses
+	}), nil
+}
+
+// GetSeasonHighestPercentMinimum get nfts with the best win percent in a season and at least minimum wins in it
+func (s *MemoryStore) GetSeasonHighestPercentMinimum(seasonID int, minimum int, limit int) ([]Nft, error) {
+	nfts := s.sortedSeasonNfts(seasonID, limit, func(nft Nft) bool {
+		return nft.Wins >= minimum
+	}, func(a, b Nft) bool {
+		return winPercent(a) > winPercent(b)
+	})
+
+	for i := range nfts {
+		nfts[i].Percent = sql.NullFloat64{Float64: winPercent(nfts[i]), Valid: true}
+	}
+
+	return nfts, nil
+}
+
+// recordSeasonResult counts the fight toward its season's records, callers hold the lock
+func (s *MemoryStore) recordSeasonResult(fight *memoryFight, winningNft string, losingNft string) {
+	if !fight.SeasonID.Valid {
+		return
+	}
+	for _, season := range s.seasons {
+		if season.ID == int(fight.SeasonID.Int64) && season.ClosedDate.Valid {
+			return
+		}
+	}
+
+	for _, result := range []struct {
+		nft  *Nft
+		wins int
+	}{{s.nftByName(winningNft), 1}, {s.nftByName(losingNft), 0}} {
+		if result.nft == nil {
+			continue
+		}
+		record := s.seasonRecord(int(fight.SeasonID.Int64), result.nft.ID)
+		record.Wins += result.wins
+		record.Loses += 1 - result.wins
+	}
+}
+
+// CreateTournament persist a new tournament open for registration, returns its id
+func (s *MemoryStore) CreateTournament(ctx context.Context, t Tournament) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := validateTournament(t); err != nil {
+		return 0, err
+	}
+	for _, existing := range s.tournaments {
+		if existing.Name == t.Name {
+			return 0, fmt.Errorf("duplicate key value violates unique constraint on tournament name %s", t.Name)
+		}
+	}
+
+	t.ID = len(s.tournaments) + 1
+	t.Status = TournamentStatusRegistration
+	t.CreatedDate = time.Now()
+	s.tournaments = append(s.tournaments, &t)
+
+	return t.ID, nil
+}
+
+// GetTournaments get every tournament, latest first
+func (s *MemoryStore) GetTournaments() ([]Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tournaments := make([]Tournament, 0)
+	for _, t := range s.tournaments {
+		tournaments = append(tournaments, *t)
+	}
+	sort.SliceStable(tournaments, func(i, j int) bool {
+		if tournaments[i].RegistrationStart.Equal(tournaments[j].RegistrationStart) {
+			return tournaments[i].ID > tournaments[j].ID
+		}
+		return tournaments[i].RegistrationStart.After(tournaments[j].RegistrationStart)
+	})
+
+	return tournaments, nil
+}
+
+// GetActiveTournaments get tournaments the minting engine still has work to do for, oldest first
+func (s *MemoryStore) GetActiveTournaments() ([]Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	tournaments := make([]Tournament, 0)
+	for _, t := range s.tournaments {
+		if t.Status == TournamentStatusRegistration || t.Status == TournamentStatusRunning || t.Status == TournamentStatusFinished {
+			tournaments = append(tournaments, *t)
+		}
+	}
+
+	return tournaments, nil
+}
+
+// GetTournament get a tournament by id, nil if there isn't one
+func (s *MemoryStore) GetTournament(tournamentID int) (*Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tournament(tournamentID)
+	if t == nil {
+		return nil, nil
+	}
+
+	copied := *t
+	return &copied, nil
+}
+
+// CreateTournamentEntry persist a new unpaid entry, returns its id
+func (s *MemoryStore) CreateTournamentEntry(ctx context.Context, entry TournamentEntry) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tournament(entry.TournamentID) == nil {
+		return 0, fmt.Errorf("No tournament with id %d", entry.TournamentID)
+	}
+
+	entry.ID = len(s.tournamentEntries) + 1
+	entry.CreatedDate = time.Now()
+	s.tournamentEntries = append(s.tournamentEntries, &entry)
+
+	return entry.ID, nil
+}
+
+// GetTournamentEntries get a tournament's entries, paid or not, in the order they were made
+func (s *MemoryStore) GetTournamentEntries(tournamentID int) ([]TournamentEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]TournamentEntry, 0)
+	for _, entry := range s.tournamentEntries {
+		if entry.TournamentID == tournamentID {
+			entries = append(entries, s.joinTournamentEntry(entry))
+		}
+	}
+
+	return entries, nil
+}
+
+// GetTournamentEntryForUtxo get the entry a utxo paid for, nil if it didn't pay for one
+func (s *MemoryStore) GetTournamentEntryForUtxo(txHash string, outputIndex int) (*TournamentEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.tournamentEntries {
+		if entry.PaymentTxHash.String == txHash && entry.PaymentOutputIndex.Valid && entry.PaymentOutputIndex.Int64 == int64(outputIndex) {
+			joined := s.joinTournamentEntry(entry)
+			return &joined, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// PayTournamentEntry records the utxo that paid an entry's fee
+func (s *MemoryStore) PayTournamentEntry(ctx context.Context, entryID int, payment FightPayment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.tournamentEntries {
+		if entry.PaymentTxHash.String == payment.TxHash && entry.PaymentOutputIndex.Int64 == int64(payment.OutputIndex) && entry.Paid() {
+			return fmt.Errorf("duplicate key value violates unique constraint on tournament entry payment %s#%d", payment.TxHash, payment.OutputIndex)
+		}
+	}
+
+	entry := s.tournamentEntry(entryID)
+	if entry == nil || entry.Paid() {
+		return fmt.Errorf("Tournament entry %d is already paid", entryID)
+	}
+	entry.PaymentTxHash = sql.NullString{String: payment.TxHash, Valid: true}
+	entry.PaymentOutputIndex = sql.NullInt64{Int64: int64(payment.OutputIndex), Valid: true}
+	entry.PaymentLovelace = sql.NullInt64{Int64: payment.Lovelace, Valid: true}
+	entry.SenderAddress = sql.NullString{String: payment.SenderAddress, Valid: true}
+	entry.PaidDate = sql.NullTime{Time: time.Now(), Valid: true}
+
+	return nil
+}
+
+// StartTournament closes registration, seeding the paid entries and laying out the bracket. Seeds are by entry id.
+func (s *MemoryStore) StartTournament(ctx context.Context, tournamentID int, seeds map[int]int, matches []TournamentMatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.transitionTournament(tournamentID, TournamentStatusRegistration, TournamentStatusRunning)
+	if err != nil {
+		return err
+	}
+	t.StartedDate = sql.NullTime{Time: time.Now(), Valid: true}
+
+	for entryID, seed := range seeds {
+		if entry := s.tournamentEntry(entryID); entry != nil && entry.TournamentID == tournamentID {
+			entry.Seed = sql.NullInt64{Int64: int64(seed), Valid: true}
+		}
+	}
+	for _, match := range matches {
+		match.ID = len(s.tournamentMatches) + 1
+		match.TournamentID = tournamentID
+		match.FightID = sql.NullInt64{}
+		match.ZombieEntryID = sql.NullInt64{}
+		s.tournamentMatches = append(s.tournamentMatches, &match)
+	}
+
+	return nil
+}
+
+// CancelTournament calls off a tournament that never started
+func (s *MemoryStore) CancelTournament(ctx context.Context, tournamentID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.transitionTournament(tournamentID, TournamentStatusRegistration, TournamentStatusCancelled)
+	if err != nil {
+		return err
+	}
+	t.FinishedDate = sql.NullTime{Time: time.Now(), Valid: true}
+
+	return nil
+}
+
+// GetTournamentMatches get a tournament's bracket in match order
+func (s *MemoryStore) GetTournamentMatches(tournamentID int) ([]TournamentMatch, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	matches := make([]TournamentMatch, 0)
+	for _, match := range s.tournamentMatches {
+		if match.TournamentID != tournamentID {
+			continue
+		}
+
+		joined := *match
+		if fight, found := s.fights[int(match.FightID.Int64)]; found && match.FightID.Valid {
+			joined.FightStatus = sql.NullString{String: string(fight.Status), Valid: true}
+			joined.ZombieLifeBar = fight.ZombieLifeBar
+			joined.HunterLifeBar = fight.HunterLifeBar
+			joined.FightTxID = fight.TxID
+		}
+		matches = append(matches, joined)
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		return matches[i].MatchNumber < matches[j].MatchNumber
+	})
+
+	return matches, nil
+}
+
+// UpdateTournamentMatches saves the entries and results of matches as the bracket advances, decided matches are never
+// changed again
+func (s *MemoryStore) UpdateTournamentMatches(ctx context.Context, matches []TournamentMatch) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, match := range matches {
+		for _, existing := range s.tournamentMatches {
+			if existing.ID != match.ID || existing.Decided {
+				continue
+			}
+			existing.TopEntryID = match.TopEntryID
+			existing.BottomEntryID = match.BottomEntryID
+			existing.WinnerEntryID = match.WinnerEntryID
+			existing.LoserEntryID = match.LoserEntryID
+			existing.Decided = match.Decided
+		}
+	}
+
+	return nil
+}
+
+// CreateTournamentFight persist the fight deciding a match, paid for later from the tournament's prize pool
+func (s *MemoryStore) CreateTournamentFight(ctx context.Context, matchID int, zombieEntryID int, fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var match *TournamentMatch
+	for _, existing := range s.tournamentMatches {
+		if existing.ID == matchID {
+			match = existing
+		}
+	}
+	if match == nil || match.FightID.Valid {
+		return 0, fmt.Errorf("Tournament match %d already has a fight", matchID)
+	}
+
+	row := s.insertFight(fight, hunterUser, zombieUser, mintingUser, FightActorEngine, fmt.Sprintf("Fight created for tournament %d match %d", fight.TournamentID.Int64, matchID))
+	match.FightID = sql.NullInt64{Int64: int64(row.ID), Valid: true}
+	match.ZombieEntryID = sql.NullInt64{Int64: int64(zombieEntryID), Valid: true}
+
+	return row.ID, nil
+}
+
+// FinishTournament records where the entries placed, by entry id, once every match is decided
+func (s *MemoryStore) FinishTournament(ctx context.Context, tournamentID int, places map[int]int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t, err := s.transitionTournament(tournamentID, TournamentStatusRunning, TournamentStatusFinished)
+	if err != nil {
+		return err
+	}
+	t.FinishedDate = sql.NullTime{Time: time.Now(), Valid: true}
+
+	for entryID, place := range places {
+		if entry := s.tournamentEntry(entryID); entry != nil && entry.TournamentID == tournamentID {
+			entry.Place = sql.NullInt64{Int64: int64(place), Valid: true}
+		}
+	}
+
+	return nil
+}
+
+// SetTournamentPayoutTx checkpoints the signed prize payout tx before it is submitted
+func (s *MemoryStore) SetTournamentPayoutTx(ctx context.Context, tournamentID int, txHash string, cborHex string, ttl int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tournament(tournamentID)
+	if t == nil || t.Status != TournamentStatusFinished || t.PayoutTxHash.Valid {
+		return fmt.Errorf("Tournament %d already has a payout tx or isn't finished", tournamentID)
+	}
+	t.PayoutTxHash = sql.NullString{String: txHash, Valid: true}
+	t.PayoutTxCbor = sql.NullString{String: cborHex, Valid: true}
+	t.PayoutTxTTL = sql.NullInt64{Int64: int64(ttl), Valid: true}
+
+	return nil
+}
+
+// ClearTournamentPayoutTx drops a payout tx that expired without reaching the chain
+func (s *MemoryStore) ClearTournamentPayoutTx(ctx context.Context, tournamentID int, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t := s.tournament(tournamentID)
+	if t != nil && t.Status == TournamentStatusFinished && t.PayoutTxHash.String == txHash {
+		t.PayoutTxHash = sql.NullString{}
+		t.PayoutTxCbor = sql.NullString{}
+		t.PayoutTxTTL = sql.NullInt64{}
+	}
+
+	return nil
+}
+
+// MarkTournamentPaid records the payout tx is on chain
+func (s *MemoryStore) MarkTournamentPaid(ctx context.Context, tournamentID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.transitionTournament(tournamentID, TournamentStatusFinished, TournamentStatusPaid)
+	return err
+}
+
+// QueueMatchmakingEntry persist a new queued entry, returns its id. An nft already in the queue can't be queued again.
+func (s *MemoryStore) QueueMatchmakingEntry(ctx context.Context, entry MatchmakingEntry) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.matchmakingEntries {
+		if existing.NftID == entry.NftID && existing.Status == MatchmakingStatusQueued {
+			return 0, fmt.Errorf("Nft %d is already queued", entry.NftID)
+		}
+	}
+
+	entry.ID = len(s.matchmakingEntries) + 1
+	entry.Status = MatchmakingStatusQueued
+	entry.FightID = sql.NullInt64{}
+	entry.CreatedDate = time.Now()
+	entry.MatchedDate = sql.NullTime{}
+	s.matchmakingEntries = append(s.matchmakingEntries, &entry)
+
+	return entry.ID, nil
+}
+
+// GetMatchmakingEntriesForUser get a user's latest entries, newest first
+func (s *MemoryStore) GetMatchmakingEntriesForUser(userID int, limit int) ([]MatchmakingEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]MatchmakingEntry, 0)
+	for i := len(s.matchmakingEntries) - 1; i >= 0 && len(entries) < limit; i-- {
+		entry := *s.matchmakingEntries[i]
+		if entry.UserID != userID {
+			continue
+		}
+		if nft, found := s.nfts[entry.NftID]; found {
+			entry.NftName = nft.NftName
+			entry.NftType = nft.NftType
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
+
+// GetMatchmakingOpponents get every listed nft of a type that isn't the user's own
+func (s *MemoryStore) GetMatchmakingOpponents(nftType string, userID int) ([]MatchmakingOpponent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	opponents := make([]MatchmakingOpponent, 0)
+	for _, userNft := range s.userNfts {
+		joined := s.joinUserNft(userNft)
+		if !joined.ListAmount.Valid || joined.NftType != nftType || joined.UserID == userID {
+			continue
+		}
+		nft := s.nfts[joined.NftID]
+		opponents = append(opponents, MatchmakingOpponent{UserNfts: joined, Rating: nft.Rating, RatingDeviation: nft.RatingDeviation})
+	}
+	sort.SliceStable(opponents, func(i, j int) bool {
+		if opponents[i].ListDate.Time.Equal(opponents[j].ListDate.Time) {
+			return opponents[i].NftID < opponents[j].NftID
+		}
+		return opponents[i].ListDate.Time.Before(opponents[j].ListDate.Time)
+	})
+
+	return opponents, nil
+}
+
+// MatchMatchmakingEntry persist the fight a queued entry was matched to, failing if the entry isn't queued anymore
+func (s *MemoryStore) MatchMatchmakingEntry(ctx context.Context, entryID int, fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	var entry *MatchmakingEntry
+	for _, existing := range s.matchmakingEntries {
+		if existing.ID == entryID && existing.Status == MatchmakingStatusQueued && existing.ExpiresDate.After(now) {
+			entry = existing
+		}
+	}
+	if entry == nil {
+		return 0, fmt.Errorf("Matchmaking entry %d isn't queued anymore", entryID)
+	}
+
+	row := s.insertFight(fight, hunterUser, zombieUser, mintingUser, FightActorAPI, fmt.Sprintf("Fight matched for matchmaking entry %d", entryID))
+	entry.Status = MatchmakingStatusMatched
+	entry.FightID = sql.NullInt64{Int64: int64(row.ID), Valid: true}
+	entry.MatchedDate = sql.NullTime{Time: now, Valid: true}
+
+	return row.ID, nil
+}
+
+// CancelMatchmakingEntry takes a user's queued entry out of the queue
+func (s *MemoryStore) CancelMatchmakingEntry(ctx context.Context, userID int, entryID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, entry := range s.matchmakingEntries {
+		if entry.ID == entryID && entry.UserID == userID && entry.Status == MatchmakingStatusQueued {
+			entry.Status = MatchmakingStatusCancelled
+			return nil
+		}
+	}
+
+	return fmt.Errorf("Matchmaking entry %d isn't queued for user %d", entryID, userID)
+}
+
+// ExpireMatchmakingEntries expires every queued entry past its expiry, returns how many
+func (s *MemoryStore) ExpireMatchmakingEntries(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := 0
+	for _, entry := range s.matchmakingEntries {
+		if entry.Status == MatchmakingStatusQueued && !entry.ExpiresDate.After(now) {
+			entry.Status = MatchmakingStatusExpired
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// CreateChallenge persist a new pending challenge, returns its id. The same two nfts can't have two open challenges.
+func (s *MemoryStore) CreateChallenge(ctx context.Context, challenge Challenge) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, existing := range s.challenges {
+		if existing.ChallengerNftID == challenge.ChallengerNftID && existing.OpponentNftID == challenge.OpponentNftID && existing.Open() {
+			return 0, fmt.Errorf("Nft %d already challenges nft %d", challenge.ChallengerNftID, challenge.OpponentNftID)
+		}
+	}
+
+	challenge.ID = len(s.challenges) + 1
+	challenge.Status = ChallengeStatusPending
+	challenge.FightID = sql.NullInt64{}
+	challenge.CreatedDate = time.Now()
+	challenge.UpdatedDate = challenge.CreatedDate
+	s.challenges = append(s.challenges, &challenge)
+
+	return challenge.ID, nil
+}
+
+// GetChallenge get a challenge by id, nil if there isn't one
+func (s *MemoryStore) GetChallenge(challengeID int) (*Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, challenge := range s.challenges {
+		if challenge.ID == challengeID {
+			joined := s.joinChallenge(*challenge)
+			return &joined, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetChallengesForUser get the latest challenges a user sent or received, newest first
+func (s *MemoryStore) GetChallengesForUser(userID int, limit int) ([]Challenge, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenges := make([]Challenge, 0)
+	for i := len(s.challenges) - 1; i >= 0 && len(challenges) < limit; i-- {
+		challenge := *s.challenges[i]
+		if challenge.ChallengerUserID == userID || challenge.OpponentUserID == userID {
+			challenges = append(challenges, s.joinChallenge(challenge))
+		}
+	}
+
+	return challenges, nil
+}
+
+// CounterChallenge answers an open challenge with a different stake, handing the turn to the other side and
+// starting the expiry over. Fails if the challenge isn't in the status the counter was made against anymore.
+func (s *MemoryStore) CounterChallenge(ctx context.Context, challengeID int, from ChallengeStatus, stakeLovelace int64, expiresDate time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, err := s.openChallenge(challengeID, from)
+	if err != nil {
+		return err
+	}
+
+	challenge.Status = ChallengeStatusCountered
+	if from == ChallengeStatusCountered {
+		challenge.Status = ChallengeStatusPending
+	}
+	challenge.StakeLovelace = stakeLovelace
+	challenge.ExpiresDate = expiresDate
+	challenge.UpdatedDate = time.Now()
+
+	return nil
+}
+
+// CloseChallenge declines or cancels an open challenge, failing if it isn't in the expected status anymore
+func (s *MemoryStore) CloseChallenge(ctx context.Context, challengeID int, from ChallengeStatus, to ChallengeStatus) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, err := s.openChallenge(challengeID, from)
+	if err != nil {
+		return err
+	}
+
+	challenge.Status = to
+	challenge.UpdatedDate = time.Now()
+
+	return nil
+}
+
+// AcceptChallenge persist the fight an accepted challenge is fought in, and the wager on it if the challenge was
+// staked, failing if the challenge isn't in the status it was accepted in anymore
+func (s *MemoryStore) AcceptChallenge(ctx context.Context, challengeID int, from ChallengeStatus, fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User, wager *Wager) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	challenge, err := s.openChallenge(challengeID, from)
+	if err != nil {
+		return 0, err
+	}
+
+	row := s.insertFight(fight, hunterUser, zombieUser, mintingUser, FightActorAPI, fmt.Sprintf("Fight accepted for challenge %d", challengeID))
+	challenge.Status = ChallengeStatusAccepted
+	challenge.FightID = sql.NullInt64{Int64: int64(row.ID), Valid: true}
+	challenge.UpdatedDate = time.Now()
+
+	if wager != nil {
+		wager.FightID = row.ID
+		wager.ChallengeID = sql.NullInt64{Int64: int64(challengeID), Valid: true}
+		s.insertWager(*wager)
+	}
+
+	return row.ID, nil
+}
+
+// ExpireChallenges expires every open challenge past its expiry, returns how many
+func (s *MemoryStore) ExpireChallenges(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expired := 0
+	for _, challenge := range s.challenges {
+		if challenge.Open() && !challenge.ExpiresDate.After(now) {
+			challenge.Status = ChallengeStatusExpired
+			challenge.UpdatedDate = now
+			expired++
+		}
+	}
+
+	return expired, nil
+}
+
+// openChallenge finds a challenge still in the from status and not expired, call with the lock held
+func (s *MemoryStore) openChallenge(challengeID int, from ChallengeStatus) (*Challenge, error) {
+	for _, challenge := range s.challenges {
+		if challenge.ID == challengeID && challenge.Status == from && challenge.ExpiresDate.After(time.Now()) {
+			return challenge, nil
+		}
+	}
+
+	return nil, fmt.Errorf("Challenge %d isn't %s anymore", challengeID, from)
+}
+
+// joinChallenge fills in the nft names and types, call with the lock held
+func (s *MemoryStore) joinChallenge(challenge Challenge) Challenge {
+	if nft, found := s.nfts[challenge.ChallengerNftID]; found {
+		challenge.ChallengerNftName, challenge.ChallengerNftType = nft.NftName, nft.NftType
+	}
+	if nft, found := s.nfts[challenge.OpponentNftID]; found {
+		challenge.OpponentNftName, challenge.OpponentNftType = nft.NftName, nft.NftType
+	}
+	return challenge
+}
+
+// insertWager adds a wager awaiting its deposits, call with the lock held
+func (s *MemoryStore) insertWager(wager Wager) {
+	wager.ID = len(s.wagers) + 1
+	wager.Status = WagerStatusAwaitingDeposits
+	wager.CreatedDate = time.Now()
+	for i := range wager.Deposits {
+		deposit := wager.Deposits[i]
+		deposit.ID = len(s.wagerDeposits) + 1
+		deposit.WagerID = wager.ID
+		s.wagerDeposits = append(s.wagerDeposits, &deposit)
+	}
+	wager.Deposits = nil
+	s.wagers = append(s.wagers, &wager)
+	s.insertWagerEvent(wager.ID, WagerEventCreated, fmt.Sprintf("Stake of %d lovelace a side on fight %d", wager.StakeLovelace, wager.FightID))
+}
+
+// GetWagerForFight get the wager on a fight, nil if there isn't one
+func (s *MemoryStore) GetWagerForFight(fightID int) (*Wager, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, wager := range s.wagers {
+		if wager.FightID == fightID {
+			joined := s.joinWager(wager)
+			return &joined, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetWagersToProcess get the wagers still awaiting deposits or payout, and the settled ones created since, oldest first
+func (s *MemoryStore) GetWagersToProcess(since time.Time) ([]Wager, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wagers := make([]Wager, 0)
+	for _, wager := range s.wagers {
+		if wager.Status == WagerStatusAwaitingDeposits || wager.Status == WagerStatusFunded || wager.CreatedDate.After(since) {
+			wagers = append(wagers, s.joinWager(wager))
+		}
+	}
+
+	return wagers, nil
+}
+
+// GetWagerDeposits get both sides' deposits of a wager
+func (s *MemoryStore) GetWagerDeposits(wagerID int) ([]WagerDeposit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deposits := make([]WagerDeposit, 0)
+	for _, deposit := range s.wagerDeposits {
+		if deposit.WagerID == wagerID {
+			deposits = append(deposits, *deposit)
+		}
+	}
+
+	return deposits, nil
+}
+
+// GetWagerDepositForUtxo get the deposit a utxo paid, nil if it didn't pay one
+func (s *MemoryStore) GetWagerDepositForUtxo(txHash string, outputIndex int) (*WagerDeposit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, deposit := range s.wagerDeposits {
+		if deposit.TxHash.String == txHash && deposit.OutputIndex.Valid && int(deposit.OutputIndex.Int64) == outputIndex {
+			found := *deposit
+			return &found, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// GetWagerEvents get every step of a wager, oldest first
+func (s *MemoryStore) GetWagerEvents(wagerID int) ([]WagerEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]WagerEvent, 0)
+	for _, event := range s.wagerEvents {
+		if event.WagerID == wagerID {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// PayWagerDeposit records the utxo that paid a deposit, the wager is funded once both sides are paid
+func (s *MemoryStore) PayWagerDeposit(ctx context.Context, depositID int, payment FightPayment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var paid *WagerDeposit
+	for _, deposit := range s.wagerDeposits {
+		if deposit.ID == depositID {
+			paid = deposit
+		}
+	}
+	if paid == nil || paid.Paid() {
+		return fmt.Errorf("Wager deposit %d is already paid", depositID)
+	}
+
+	paid.TxHash = sql.NullString{String: payment.TxHash, Valid: true}
+	paid.OutputIndex = sql.NullInt64{Int64: int64(payment.OutputIndex), Valid: true}
+	paid.SenderAddress = sql.NullString{String: payment.SenderAddress, Valid: true}
+	paid.Lovelace = sql.NullInt64{Int64: payment.Lovelace, Valid: true}
+	paid.PaidDate = sql.NullTime{Time: time.Now(), Valid: true}
+	s.insertWagerEvent(paid.WagerID, WagerEventDeposit, fmt.Sprintf("%s side paid %d lovelace with %s#%d from %s", paid.Side, payment.Lovelace, payment.TxHash, payment.OutputIndex, payment.SenderAddress))
+
+	for _, deposit := range s.wagerDeposits {
+		if deposit.WagerID == paid.WagerID && !deposit.Paid() {
+			return nil
+		}
+	}
+
+	return s.transitionWager(paid.WagerID, WagerStatusAwaitingDeposits, WagerStatusFunded, WagerEventFunded, "Both deposits paid")
+}
+
+// RecordWagerRefund records a utxo at one of a wager's addresses as owed back, doing nothing if it already is
+func (s *MemoryStore) RecordWagerRefund(ctx context.Context, wagerID int, refund Refund) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.refundForUtxo(refund.TxHash, refund.OutputIndex) != nil {
+		return nil
+	}
+	now := time.Now()
+	refund.ID = len(s.refunds) + 1
+	refund.Status = RefundStatusPending
+	refund.Attempts = 0
+	refund.CreatedDate = now
+	refund.UpdatedDate = now
+	s.refunds = append(s.refunds, &refund)
+	s.insertWagerEvent(wagerID, WagerEventRefund, fmt.Sprintf("%d lovelace in %s#%d owed back to %s", refund.Lovelace, refund.TxHash, refund.OutputIndex, refund.SenderAddress))
+
+	return nil
+}
+
+// VoidWager calls off a wager that's awaiting deposits or funded but never paid out, its deposits are refunded
+// separately
+func (s *MemoryStore) VoidWager(ctx context.Context, wagerID int, from WagerStatus, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.transitionWager(wagerID, from, WagerStatusVoid, WagerEventVoided, reason)
+}
+
+// SetWagerPayoutTx checkpoints the signed payout tx of a funded wager before it is submitted
+func (s *MemoryStore) SetWagerPayoutTx(ctx context.Context, wagerID int, payout WagerPayout, txHash string, cborHex string, ttl int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wager := s.wager(wagerID)
+	if wager == nil || wager.Status != WagerStatusFunded || wager.PayoutTxHash.Valid {
+		return fmt.Errorf("Wager %d already has a payout tx or isn't funded", wagerID)
+	}
+	wager.WinnerSide = sql.NullString{String: string(payout.WinnerSide), Valid: true}
+	wager.PayoutAddress = sql.NullString{String: payout.Address, Valid: true}
+	wager.PayoutLovelace = sql.NullInt64{Int64: payout.Lovelace, Valid: true}
+	wager.HouseFeeLovelace = sql.NullInt64{Int64: payout.HouseFeeLovelace, Valid: true}
+	wager.PayoutTxHash = sql.NullString{String: txHash, Valid: true}
+	wager.PayoutTxCbor = sql.NullString{String: cborHex, Valid: true}
+	wager.PayoutTxTTL = sql.NullInt64{Int64: int64(ttl), Valid: true}
+	s.insertWagerEvent(wagerID, WagerEventPayoutSubmitted, fmt.Sprintf("%s side won, %d lovelace to %s and %d lovelace house fee in tx %s",
+		payout.WinnerSide, payout.Lovelace, payout.Address, payout.HouseFeeLovelace, txHash))
+
+	return nil
+}
+
+// ClearWagerPayoutTx drops a payout tx that expired without reaching the chain
+func (s *MemoryStore) ClearWagerPayoutTx(ctx context.Context, wagerID int, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wager := s.wager(wagerID)
+	if wager != nil && wager.Status == WagerStatusFunded && wager.PayoutTxHash.String == txHash {
+		wager.PayoutTxHash = sql.NullString{}
+		wager.PayoutTxCbor = sql.NullString{}
+		wager.PayoutTxTTL = sql.NullInt64{}
+		s.insertWagerEvent(wagerID, WagerEventPayoutExpired, fmt.Sprintf("Payout tx %s expired", txHash))
+	}
+
+	return nil
+}
+
+// MarkWagerPaid records the payout tx is on chain
+func (s *MemoryStore) MarkWagerPaid(ctx context.Context, wagerID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.transitionWager(wagerID, WagerStatusFunded, WagerStatusPaid, WagerEventPaid, "Payout tx is on chain")
+}
+
+func (s *MemoryStore) wager(wagerID int) *Wager {
+	for _, wager := range s.wagers {
+		if wager.ID == wagerID {
+			return wager
+		}
+	}
+	return nil
+}
+
+// joinWager fills in the fight's status and life bars, call with the lock held
+func (s *MemoryStore) joinWager(wager *Wager) Wager {
+	joined := *wager
+	if fight, found := s.fights[wager.FightID]; found {
+		joined.FightStatus = sql.NullString{String: string(fight.Status), Valid: true}
+		joined.ZombieLifeBar = fight.ZombieLifeBar
+		joined.HunterLifeBar = fight.HunterLifeBar
+	}
+	return joined
+}
+
+// transitionWager moves a wager between statuses and records the event, call with the lock held
+func (s *MemoryStore) transitionWager(wagerID int, from WagerStatus, to WagerStatus, event WagerEventType, detail string) error {
+	wager := s.wager(wagerID)
+	if wager == nil || wager.Status != from {
+		return fmt.Errorf("Wager %d isn't %s", wagerID, from)
+	}
+	wager.Status = to
+	if to == WagerStatusPaid || to == WagerStatusVoid {
+		wager.SettledDate = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+	s.insertWagerEvent(wagerID, event, detail)
+	return nil
+}
+
+func (s *MemoryStore) insertWagerEvent(wagerID int, event WagerEventType, detail string) {
+	s.wagerEvents = append(s.wagerEvents, WagerEvent{ID: len(s.wagerEvents) + 1, WagerID: wagerID, Event: event, Detail: detail, CreatedDate: time.Now()})
+}
+
+// GetNftLastFightDate when the nft's latest paid fight was created, nil if it never fought
+func (s *MemoryStore) GetNftLastFightDate(nftID int) (*time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var last *time.Time
+	for _, fight := range s.fights {
+		if (fight.ZombieNftID == nftID || fight.HunterNftID == nftID) && fight.Status != FightStatusPending && fight.Status != FightStatusVoid && (last == nil || fight.CreatedDate.After(*last)) {
+			created := fight.CreatedDate
+			last = &created
+		}
+	}
+
+	return last, nil
+}
+
+// CountNftFights how many paid fights the nft is in that were created from from up to but not including to
+func (s *MemoryStore) CountNftFights(nftID int, from time.Time, to time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := 0
+	for _, fight := range s.fights {
+		if (fight.ZombieNftID == nftID || fight.HunterNftID == nftID) && fight.Status != FightStatusPending && fight.Status != FightStatusVoid && !fight.CreatedDate.Before(from) && fight.CreatedDate.Before(to) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// GetUserFightDates when each fight the user created since was created, paid or not, oldest first. Tournament fights
+// don't count
+func (s *MemoryStore) GetUserFightDates(userID int, since time.Time) ([]time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	dates := make([]time.Time, 0)
+	for _, fight := range s.fights {
+		if fight.MintingUserID == userID && fight.CreatedDate.After(since) && !fight.TournamentID.Valid {
+			dates = append(dates, fight.CreatedDate)
+		}
+	}
+	sort.Slice(dates, func(i, j int) bool {
+		return dates[i].Before(dates[j])
+	})
+
+	return dates, nil
+}
+
+// GetNftsToIndex the nfts whose holder was checked longest ago, never checked first
+func (s *MemoryStore) GetNftsToIndex(limit int) ([]Nft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nfts := make([]Nft, 0)
+	for _, nft := range s.nfts {
+		nfts = append(nfts, *nft)
+	}
+	sort.Slice(nfts, func(i, j int) bool {
+		a, b := nfts[i].HolderCheckedDate, nfts[j].HolderCheckedDate
+		if a.Valid != b.Valid {
+			return !a.Valid
+		}
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		return nfts[i].ID < nfts[j].ID
+	})
+
+	if len(nfts) > limit {
+		nfts = nfts[:limit]
+	}
+	return nfts, nil
+}
+
+// SetNftHolder record where the nft is held as of checked, nil when nothing holds it. Returns the account that held
+// it before
+func (s *MemoryStore) SetNftHolder(ctx context.Context, nftID int, holder *NftHolder, checked time.Time) (sql.NullString, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nft, found := s.nfts[nftID]
+	if !found {
+		return sql.NullString{}, sql.ErrNoRows
+	}
+
+	previous := nft.HolderAccount
+	nft.HolderAddress, nft.HolderAccount = sql.NullString{}, sql.NullString{}
+	if holder != nil {
+		nft.HolderAddress = sql.NullString{String: holder.Address, Valid: true}
+		nft.HolderAccount = sql.NullString{String: holder.Account, Valid: true}
+	}
+	nft.HolderCheckedDate = sql.NullTime{Time: checked, Valid: true}
+
+	return previous, nil
+}
+
+// GetUserAccounts the accounts a user holds nfts in, including a wallet user's stake address
+func (s *MemoryStore) GetUserAccounts(userID int) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.userAccountsOf(userID), nil
+}
+
+// userAccountsOf is called with the lock held
+func (s *MemoryStore) userAccountsOf(userID int) []string {
+	accounts := make([]string, 0)
+	for _, userAccount := range s.userAccounts {
+		if userAccount.UserID == userID {
+			accounts = append(accounts, userAccount.Account)
+		}
+	}
+	if user, found := s.users[userID]; found && user.StakeAddress.Valid {
+		accounts = append(accounts, user.StakeAddress.String)
+	}
+
+	return accounts
+}
+
+func containsAccount(accounts []string, account string) bool {
+	for _, a := range accounts {
+		if a == account {
+			return true
+		}
+	}
+	return false
+}
+
+// AddUserAccount record that a user proved they control an account, it's taken off any other user that had it
+func (s *MemoryStore) AddUserAccount(userID int, account string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	userAccounts := make([]memoryUserAccount, 0)
+	for _, userAccount := range s.userAccounts {
+		if userAccount.Account != account {
+			userAccounts = append(userAccounts, userAccount)
+		}
+	}
+	s.userAccounts = append(userAccounts, memoryUserAccount{UserID: userID, Account: account, Proven: true})
+
+	return nil
+}
+
+// SetNftkeymeAccounts replaces the accounts a user was given from what nftkey.me says they own. An account someone
+// proved or that's a wallet user's stake address stays theirs
+func (s *MemoryStore) SetNftkeymeAccounts(userID int, accounts []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	claimed := make(map[string]bool)
+	userAccounts := make([]memoryUserAccount, 0)
+	for _, userAccount := range s.userAccounts {
+		if userAccount.Proven {
+			claimed[userAccount.Account] = true
+		}
+		if userAccount.UserID != userID || userAccount.Proven {
+			userAccounts = append(userAccounts, userAccount)
+		}
+	}
+	for _, user := range s.users {
+		if user.ID != userID && user.StakeAddress.Valid {
+			claimed[user.StakeAddress.String] = true
+		}
+	}
+
+	for _, account := range accounts {
+		if !claimed[account] {
+			userAccounts = append(userAccounts, memoryUserAccount{UserID: userID, Account: account})
+			claimed[account] = true
+		}
+	}
+	s.userAccounts = userAccounts
+
+	return nil
+}
+
+// GetNftsHeldByUser the nfts the index has in any of the user's accounts
+func (s *MemoryStore) GetNftsHeldByUser(userID int) ([]Nft, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accounts := s.userAccountsOf(userID)
+	nfts := make([]Nft, 0)
+	for _, nft := range s.nfts {
+		for _, account := range accounts {
+			if nft.HolderAccount.Valid && nft.HolderAccount.String == account {
+				nfts = append(nfts, *nft)
+				break
+			}
+		}
+	}
+	sort.Slice(nfts, func(i, j int) bool { return nfts[i].ID < nfts[j].ID })
+
+	return nfts, nil
+}
+
+// RemoveNftListingsNotHeldBy removes the nft from every user who doesn't hold the account, from every user when the
+// account isn't valid
+func (s *MemoryStore) RemoveNftListingsNotHeldBy(ctx context.Context, nftID int, account sql.NullString) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.userNfts)
+	s.removeUserNfts(func(userNft *memoryUserNft) bool {
+		return userNft.NftID == nftID && !(account.Valid && containsAccount(s.userAccountsOf(userNft.UserID), account.String))
+	})
+
+	return int64(before - len(s.userNfts)), nil
+}
+
+// GetOpenFightsForNft gets the nft's fights that are waiting on payment or on being fought, tournament fights aren't
+// included
+func (s *MemoryStore) GetOpenFightsForNft(nftID int) ([]FightDb, error) {
+	return s.selectFights(func(f *memoryFight) bool {
+		return (f.ZombieNftID == nftID || f.HunterNftID == nftID) && (f.Status == FightStatusPending || f.Status == FightStatusQueued) && !f.TournamentID.Valid
+	}), nil
+}
+
+// VoidFight calls off a pending or queued fight, its alien goes back to the pool and its payments are owed back
+func (s *MemoryStore) VoidFight(ctx context.Context, fightID int, actor FightActor, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found {
+		return fmt.Errorf("No fight with id %d", fightID)
+	}
+
+	err := s.transitionFight(fight, FightStatusVoid, actor, reason)
+	if err != nil {
+		return err
+	}
+
+	if alien := s.alienForFight(fightID); alien != nil {
+		alien.FightID = sql.NullInt64{}
+	}
+	now := time.Now()
+	for _, payment := range s.payments {
+		if payment.FightID != fightID || s.refundForUtxo(payment.TxHash, payment.OutputIndex) != nil {
+			continue
+		}
+		s.refunds = append(s.refunds, &Refund{
+			ID:                  len(s.refunds) + 1,
+			TxHash:              payment.TxHash,
+			OutputIndex:         payment.OutputIndex,
+			SenderAddress:       payment.SenderAddress,
+			Lovelace:            payment.Lovelace,
+			Assets:              payment.Assets,
+			PaymentAddress:      fight.PaymentAddress,
+			PaymentAddressIndex: fight.PaymentAddressIndex,
+			Status:              RefundStatusPending,
+			CreatedDate:         now,
+			UpdatedDate:         now,
+		})
+	}
+
+	return nil
+}
+
+// SetFightSendAddresses points the fight's payouts at new addresses, never once a mint tx is signed for it
+func (s *MemoryStore) SetFightSendAddresses(ctx context.Context, fightID int, zombieSendAddress string, hunterSendAddress string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found || fight.SignedTxHash.Valid {
+		return nil
+	}
+	fight.ZombieSendAddress = sql.NullString{String: zombieSendAddress, Valid: true}
+	fight.HunterSendAddress = sql.NullString{String: hunterSendAddress, Valid: true}
+
+	return nil
+}
+
+// SetUserRole gives the user a role on the admin api, an empty role takes it away
+func (s *MemoryStore) SetUserRole(ctx context.Context, userID int, role UserRole) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, found := s.users[userID]
+	if !found {
+		return fmt.Errorf("No user with id %d", userID)
+	}
+	user.Role = role
+
+	return nil
+}
+
+// InsertAdminAudit records a change made through the admin api before it's made, returning its id
+func (s *MemoryStore) InsertAdminAudit(ctx context.Context, audit AdminAudit) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	audit.ID = len(s.audits) + 1
+	audit.CreatedDate = time.Now()
+	s.audits = append(s.audits, audit)
+
+	return audit.ID, nil
+}
+
+// FinishAdminAudit records what the api answered to an audited change
+func (s *MemoryStore) FinishAdminAudit(ctx context.Context, auditID int, statusCode int, detail string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if auditID < 1 || auditID > len(s.audits) {
+		return fmt.Errorf("No admin audit with id %d", auditID)
+	}
+	s.audits[auditID-1].StatusCode = statusCode
+	s.audits[auditID-1].Detail = detail
+
+	return nil
+}
+
+// GetAdminAudits gets up to limit admin changes, latest first
+func (s *MemoryStore) GetAdminAudits(limit int) ([]AdminAudit, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	audits := make([]AdminAudit, 0)
+	for i := len(s.audits) - 1; i >= 0 && len(audits) < limit; i-- {
+		audits = append(audits, s.audits[i])
+	}
+
+	return audits, nil
+}
+
+// GetFight gets a fight by id, nil if there isn't one
+func (s *MemoryStore) GetFight(fightID int) (*FightDb, error) {
+	fights := s.selectFights(func(f *memoryFight) bool {
+		return f.ID == fightID
+	})
+	if len(fights) == 0 {
+		return nil, nil
+	}
+
+	return &fights[0], nil
+}
+
+// GetFightsByStatus gets up to limit fights in a status, oldest first
+func (s *MemoryStore) GetFightsByStatus(status FightStatus, limit int) ([]FightDb, error) {
+	fights := s.selectFights(func(f *memoryFight) bool {
+		return f.Status == status
+	})
+	if len(fights) > limit {
+		fights = fights[:limit]
+	}
+
+	return fights, nil
+}
+
+// CountFightsByStatus how many fights are in each status, statuses without fights are left out
+func (s *MemoryStore) CountFightsByStatus() (map[FightStatus]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[FightStatus]int)
+	for _, fight := range s.fights {
+		counts[fight.Status]++
+	}
+
+	return counts, nil
+}
+
+// CountRefundsByStatus how many refunds are in each status, statuses without refunds are left out
+func (s *MemoryStore) CountRefundsByStatus() (map[RefundStatus]int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counts := make(map[RefundStatus]int)
+	for _, refund := range s.refunds {
+		counts[refund.Status]++
+	}
+
+	return counts, nil
+}
+
+// ForceRefund records a utxo as owed back, a refund for it that ran out of attempts is tried again from scratch
+func (s *MemoryStore) ForceRefund(ctx context.Context, refund Refund) (*Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	existing := s.refundForUtxo(refund.TxHash, refund.OutputIndex)
+	if existing == nil {
+		refund.ID = len(s.refunds) + 1
+		refund.Status = RefundStatusPending
+		refund.Attempts = 0
+		refund.CreatedDate = now
+		refund.UpdatedDate = now
+		s.refunds = append(s.refunds, &refund)
+		existing = &refund
+	} else if existing.Status == RefundStatusFailed {
+		existing.Status = RefundStatusPending
+		existing.Attempts = 0
+		existing.LastError = sql.NullString{}
+		existing.RefundTxHash = sql.NullString{}
+		existing.RefundTxTTL = sql.NullInt64{}
+		existing.UpdatedDate = now
+	}
+
+	copied := *existing
+	return &copied, nil
+}
+
+// RequestImageRegeneration flags a staged fight without a signed mint tx for the minter to rebuild its images
+func (s *MemoryStore) RequestImageRegeneration(ctx context.Context, fightID int) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found || fight.Status != FightStatusStaged || fight.SignedTxHash.Valid {
+		return false, nil
+	}
+	fight.RegenerateImages = true
+
+	return true, nil
+}
+
+// SetFightImages replaces the images of a staged fight and its alien and clears the regeneration flag
+func (s *MemoryStore) SetFightImages(ctx context.Context, fightID int, alienID int, alienIpfs string, fightIpfs string, background string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found || fight.Status != FightStatusStaged || fight.SignedTxHash.Valid {
+		return fmt.Errorf("Fight %d isn't staged without a signed mint tx", fightID)
+	}
+	fight.IPFS = sql.NullString{String: fightIpfs, Valid: true}
+	fight.Background = sql.NullString{String: background, Valid: true}
+	fight.RegenerateImages = false
+	if alien, found := s.aliens[alienID]; found && alien.FightID.Int64 == int64(fightID) {
+		alien.Ipfs = sql.NullString{String: alienIpfs, Valid: true}
+	}
+
+	return nil
+}
+
+// RecordEnginePass records that the engine went through a pass
+func (s *MemoryStore) RecordEnginePass(ctx context.Context, engine string, passed time.Time, failed bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	heartbeat, found := s.heartbeats[engine]
+	if !found {
+		heartbeat = &EngineHeartbeat{Engine: engine}
+		s.heartbeats[engine] = heartbeat
+	}
+	heartbeat.LastPassDate = passed
+	if failed {
+		heartbeat.ConsecutiveFailures++
+	} else {
+		heartbeat.LastSuccessDate = sql.NullTime{Time: passed, Valid: true}
+		heartbeat.ConsecutiveFailures = 0
+	}
+
+	return nil
+}
+
+// GetEngineHeartbeats gets the latest pass of every engine that ever ran
+func (s *MemoryStore) GetEngineHeartbeats() ([]EngineHeartbeat, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	heartbeats := make([]EngineHeartbeat, 0, len(s.heartbeats))
+	for _, heartbeat := range s.heartbeats {
+		heartbeats = append(heartbeats, *heartbeat)
+	}
+	sort.Slice(heartbeats, func(i, j int) bool { return heartbeats[i].Engine < heartbeats[j].Engine })
+
+	return heartbeats, nil
+}
+
+// CreateFight persist a new fight
+func (s *MemoryStore) CreateFight(fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	row := s.insertFight(fight, hunterUser, zombieUser, mintingUser, FightActorAPI, fmt.Sprintf("Fight created by user %d", mintingUser.ID))
+
+	return row.ID, nil
+}
+
+// insertFight adds a pending fight and its first event, tagged with the season it was created in
+func (s *MemoryStore) insertFight(fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User, actor FightActor, reason string) *memoryFight {
+	row := &memoryFight{
+		FightDb: FightDb{
+			ID:                    s.nextFightID,
+			Status:                FightStatusPending,
+			CreatedDate:           time.Now(),
+			PaymentAmountLovelace: fight.PaymentAmountLovelace,
+			PaymentAddress:        fight.PaymentAddress,
+			PaymentAddressIndex:   fight.PaymentAddressIndex,
+			TournamentID:          fight.TournamentID,
+			Collection:            "Zombie Fight Club",
+			Site:                  "https://zombiechains.io/",
+			Twitter:               "https://twitter.com/ZombieChains",
+			Copyright:             "2022 Zombie Chains",
+			HunterAmountAda:       int(hunterUser.ListAmount.Int16),
+			HunterSendAddress:     sql.NullString{String: fight.HunterSendAddress, Valid: true},
+			ZombieAmountAda:       int(zombieUser.ListAmount.Int16),
+			ZombieSendAddress:     sql.NullString{String: fight.ZombieSendAddress, Valid: true},
+			SeedSecret:            sql.NullString{String: fight.SeedSecret, Valid: fight.SeedSecret != ""},
+		},
+		HunterUserID:   hunterUser.UserID,
+		HunterNftID:    hunterUser.NftID,
+		ZombieUserID:   zombieUser.UserID,
+		ZombieNftID:    zombieUser.NftID,
+		MintingUserID:  mintingUser.ID,
+		memoryRowOrder: s.nextFightID,
+	}
+	if fight.Seed != nil {
+		row.SeedCommitment = sql.NullString{String: fight.Seed.Commitment, Valid: true}
+	}
+	if season := s.currentSeason(row.CreatedDate); season != nil {
+		row.SeasonID = sql.NullInt64{Int64: int64(season.ID), Valid: true}
+	}
+	s.fights[row.ID] = row
+	s.nextFightID++
+	s.recordEvent(row.ID, sql.NullString{}, FightStatusPending, actor, reason)
+
+	return row
+}
+
+// GetFightsForUser get the latest 25 fights for user
+func (s *MemoryStore) GetFightsForUser(user User) ([]FightDb, error) {
+	fights := s.selectFights(func(f *memoryFight) bool {
+		return f.MintingUserID == user.ID
+	})
+
+	sort.SliceStable(fights, func(i, j int) bool {
+		return fights[i].CreatedDate.After(fights[j].CreatedDate)
+	})
+	if len(fights) > 25 {
+		fights = fights[:25]
+	}
+
+	return fights, nil
+}
+
+// GetFightsForUserAndId get fights for user and id
+func (s *MemoryStore) GetFightsForUserAndId(user User, fightId int) ([]FightDb, error) {
+	return s.selectFights(func(f *memoryFight) bool {
+		return f.MintingUserID == user.ID && f.ID == fightId
+	}), nil
+}
+
+// GetFightForUtxo get fight for utxo and index
+func (s *MemoryStore) GetFightForUtxo(utxo string, index int) ([]FightDb, error) {
+	s.mu.Lock()
+	paidFights := make(map[int]bool)
+	for _, payment := range s.payments {
+		if payment.TxHash == utxo && payment.OutputIndex == index {
+			paidFights[payment.FightID] = true
+		}
+	}
+	s.mu.Unlock()
+
+	return s.selectFights(func(f *memoryFight) bool {
+		return (f.IncomingUtxo.Valid && f.IncomingUtxo.String == utxo && f.IncomingUtxoInt.Int64 == int64(index)) || paidFights[f.ID]
+	}), nil
+}
+
+// GetQueuedFight get queued fights
+func (s *MemoryStore) GetQueuedFight() ([]FightDb, error) {
+	return s.selectFights(func(f *memoryFight) bool {
+		return f.Status == FightStatusQueued
+	}), nil
+}
+
+// GetStagedFights get staged fights
+func (s *MemoryStore) GetStagedFights() ([]FightDb, error) {
+	return s.selectFights(func(f *memoryFight) bool {
+		return f.Status == FightStatusStaged
+	}), nil
+}
+
+// GetMintedFights get minted fights
+func (s *MemoryStore) GetMintedFights() ([]FightDb, error) {
+	return s.selectFights(func(f *memoryFight) bool {
+		return f.Status == FightStatusMinted
+	}), nil
+}
+
+// GetUnpaidFightForAmount get unpaid fight created within the payment window for the amount
+func (s *MemoryStore) GetUnpaidFightForAmount(lovelace int64, window time.Duration) (*FightDb, error) {
+	cutoff := time.Now().Add(-window)
+	fights := s.selectFights(func(f *memoryFight) bool {
+		return !f.IncomingUtxo.Valid && f.PaymentAmountLovelace == lovelace && f.CreatedDate.After(cutoff) && !f.TournamentID.Valid
+	})
+
+	if len(fights) > 1 {
+		return nil, fmt.Errorf("More than one row returned")
+	} else if len(fights) == 0 {
+		return nil, nil
+	}
+
+	return &fights[0], nil
+}
+
+// GetNextAvailableAlien get next available alien
+func (s *MemoryStore) GetNextAvailableAlien() (*Alien, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var next *Alien
+	for _, alien := range s.aliens {
+		if !alien.FightID.Valid && (next == nil || alien.ID < next.ID) {
+			next = alien
+		}
+	}
+	if next == nil {
+		return nil, nil
+	}
+
+	copied := *next
+	return &copied, nil
+}
+
+// GetAlienByFightId get alien by fight id
+func (s *MemoryStore) GetAlienByFightId(fightId int) (*Alien, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alien := s.alienForFight(fightId)
+	if alien == nil {
+		return nil, nil
+	}
+
+	copied := *alien
+	return &copied, nil
+}
+
+// MoveFightFromPendingToQueued assigns the alien and incoming utxo to the fight
+func (s *MemoryStore) MoveFightFromPendingToQueued(ctx context.Context, fightID int, alienID int, payments []FightPayment) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if len(payments) == 0 {
+		return fmt.Errorf("No payments for fight %d", fightID)
+	}
+	fight, found := s.fights[fightID]
+	if !found {
+		return fmt.Errorf("No fight with id %d", fightID)
+	}
+	alien, found := s.aliens[alienID]
+	if !found {
+		return fmt.Errorf("No alien with id %d", alienID)
+	}
+	for _, payment := range payments {
+		for _, existing := range s.payments {
+			if existing.TxHash == payment.TxHash && existing.OutputIndex == payment.OutputIndex {
+				return fmt.Errorf("duplicate key value violates unique constraint on fight payment %s#%d", payment.TxHash, payment.OutputIndex)
+			}
+		}
+	}
+
+	err := s.transitionFight(fight, FightStatusQueued, FightActorEngine, paymentReason(payments))
+	if err != nil {
+		return err
+	}
+
+	alien.FightID = sql.NullInt64{Int64: int64(fightID), Valid: true}
+	fight.IncomingUtxo = sql.NullString{String: payments[0].TxHash, Valid: true}
+	fight.IncomingUtxoInt = sql.NullInt64{Int64: int64(payments[0].OutputIndex), Valid: true}
+	for _, payment := range payments {
+		payment.ID = len(s.payments) + 1
+		payment.FightID = fightID
+		payment.CreatedDate = time.Now()
+		s.payments = append(s.payments, payment)
+	}
+
+	return nil
+}
+
+// MoveFightFromQueuedToStaged records the fight outcome and updates both records
+func (s *MemoryStore) MoveFightFromQueuedToStaged(ctx context.Context, alienID int, alienIpfs string, fightID int, fightIpfs, background, zombieRecord, hunterRecord string, zcLifeBar, zhLifeBar int, zombieKo, hunterKo, zombieBeahup, hunterBeatup bool, combatLog string, winningNft string, losingNft string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found {
+		return fmt.Errorf("No fight with id %d", fightID)
+	}
+	alien, found := s.aliens[alienID]
+	if !found {
+		return fmt.Errorf("No alien with id %d", alienID)
+	}
+
+	err := s.transitionFight(fight, FightStatusStaged, FightActorEngine, fmt.Sprintf("%s defeated %s", winningNft, losingNft))
+	if err != nil {
+		return err
+	}
+
+	alien.Ipfs = sql.NullString{String: alienIpfs, Valid: true}
+	if winner := s.nftByName(winningNft); winner != nil {
+		winner.Wins++
+	}
+	if loser := s.nftByName(losingNft); loser != nil {
+		loser.Loses++
+	}
+	if err := s.rateFight(fightID, winningNft, losingNft); err != nil {
+		return err
+	}
+	s.recordSeasonResult(fight, winningNft, losingNft)
+
+	fight.IPFS = sql.NullString{String: fightIpfs, Valid: true}
+	fight.Background = sql.NullString{String: background, Valid: true}
+	fight.HunterLifeBar = sql.NullInt64{Int64: int64(zhLifeBar), Valid: true}
+	fight.ZombieLifeBar = sql.NullInt64{Int64: int64(zcLifeBar), Valid: true}
+	fight.HunterRecord = sql.NullString{String: hunterRecord, Valid: true}
+	fight.ZombieRecord = sql.NullString{String: zombieRecord, Valid: true}
+	fight.HunterKo = sql.NullBool{Bool: hunterKo, Valid: true}
+	fight.ZombieKo = sql.NullBool{Bool: zombieKo, Valid: true}
+	fight.HunterBeatup = hunterBeatup
+	fight.ZombieBeatup = zombieBeahup
+	fight.CombatLog = sql.NullString{String: combatLog, Valid: true}
+
+	return nil
+}
+
+// MoveFightFromStagedToMinted records the submitted mint transaction
+func (s *MemoryStore) MoveFightFromStagedToMinted(ctx context.Context, fightID int, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found {
+		return fmt.Errorf("No fight with id %d", fightID)
+	}
+
+	err := s.transitionFight(fight, FightStatusMinted, FightActorEngine, fmt.Sprintf("Mint submitted in tx %s", txHash))
+	if err != nil {
+		return err
+	}
+
+	fight.MintedDate = sql.NullTime{Time: time.Now(), Valid: true}
+	fight.TxID = sql.NullString{String: txHash, Valid: true}
+
+	return nil
+}
+
+// MoveFightFromMintedToConfirmed marks the mint transaction as on chain
+func (s *MemoryStore) MoveFightFromMintedToConfirmed(ctx context.Context, fightID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found {
+		return fmt.Errorf("No fight with id %d", fightID)
+	}
+
+	return s.transitionFight(fight, FightStatusConfirmed, FightActorEngine, "Mint tx found on chain")
+}
+
+// UpdateTweetID sets the tweet for the fight
+func (s *MemoryStore) UpdateTweetID(ctx context.Context, fightID int, tweetID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found {
+		return fmt.Errorf("No fight with id %d", fightID)
+	}
+
+	fight.TweetID = sql.NullString{String: tweetID, Valid: true}
+
+	return nil
+}
+
+// SetAlienIpfs records the uploaded alien image before the fight is staged
+func (s *MemoryStore) SetAlienIpfs(ctx context.Context, alienID int, alienIpfs string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	alien, found := s.aliens[alienID]
+	if !found {
+		return fmt.Errorf("No alien with id %d", alienID)
+	}
+	alien.Ipfs = sql.NullString{String: alienIpfs, Valid: true}
+
+	return nil
+}
+
+// MarkTweetAttempted records that the fight has been tweeted, or tried to be
+func (s *MemoryStore) MarkTweetAttempted(ctx context.Context, fightID int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found {
+		return fmt.Errorf("No fight with id %d", fightID)
+	}
+	fight.TweetAttempted = true
+
+	return nil
+}
+
+// SetFightSeed records the seed a fight's outcome is drawn from, keeping the first one if it's already set
+func (s *MemoryStore) SetFightSeed(ctx context.Context, fightID int, paymentTxHash string, seed string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if found && !fight.Seed.Valid {
+		fight.SeedTxHash = sql.NullString{String: paymentTxHash, Valid: true}
+		fight.Seed = sql.NullString{String: seed, Valid: true}
+	}
+
+	return nil
+}
+
+// GetFightCombatLog the fight's combat log json, empty until the fight has been fought
+func (s *MemoryStore) GetFightCombatLog(ctx context.Context, fightID int) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found {
+		return "", nil
+	}
+	return fight.CombatLog.String, nil
+}
+
+// SetSignedMintTx checkpoints the signed mint tx before it is submitted
+func (s *MemoryStore) SetSignedMintTx(ctx context.Context, fightID int, txHash string, cborHex string, ttl int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if !found || fight.Status != FightStatusStaged || fight.SignedTxHash.Valid {
+		return fmt.Errorf("Fight %d already has a signed tx or isn't staged", fightID)
+	}
+	fight.SignedTxHash = sql.NullString{String: txHash, Valid: true}
+	fight.SignedTxCbor = sql.NullString{String: cborHex, Valid: true}
+	fight.SignedTxTTL = sql.NullInt64{Int64: int64(ttl), Valid: true}
+
+	return nil
+}
+
+// ClearSignedMintTx drops a signed mint tx that expired without reaching the chain
+func (s *MemoryStore) ClearSignedMintTx(ctx context.Context, fightID int, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fight, found := s.fights[fightID]
+	if found && fight.Status == FightStatusStaged && fight.SignedTxHash.String == txHash {
+		fight.SignedTxHash = sql.NullString{}
+		fight.SignedTxCbor = sql.NullString{}
+		fight.SignedTxTTL = sql.NullInt64{}
+	}
+
+	return nil
+}
+
+// GetFightPayments gets the utxos that paid for a fight, oldest first
+func (s *MemoryStore) GetFightPayments(fightID int) ([]FightPayment, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	payments := make([]FightPayment, 0)
+	for _, payment := range s.payments {
+		if payment.FightID == fightID {
+			payments = append(payments, payment)
+		}
+	}
+
+	return payments, nil
+}
+
+// GetFightsAwaitingPayment gets unpaid fights created within the payment window, tournament fights are paid from
+// their prize pool instead. Fights voided before they were paid are included so payments to them can be returned, and
+// fights with their own address whatever their status so payments that come in after they were paid can be too
+func (s *MemoryStore) GetFightsAwaitingPayment(window time.Duration) ([]FightDb, error) {
+	cutoff := time.Now().Add(-window)
+	return s.selectFights(func(f *memoryFight) bool {
+		unpaid := !f.IncomingUtxo.Valid && (f.Status == FightStatusPending || f.Status == FightStatusVoid)
+		return (unpaid || f.PaymentAddressIndex.Valid) && f.CreatedDate.After(cutoff) && !f.TournamentID.Valid
+	}), nil
+}
+
+// NextPaymentAddressIndex reserves the next hd wallet index for a fight's payment address
+func (s *MemoryStore) NextPaymentAddressIndex() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	index := s.nextPaymentAddressIndex
+	s.nextPaymentAddressIndex++
+
+	return index, nil
+}
+
+// HoldPayment records that a utxo is held for the rest of its payment to arrive, returns when it was first held
+func (s *MemoryStore) HoldPayment(ctx context.Context, txHash string, outputIndex int, seen time.Time) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.heldPayments == nil {
+		s.heldPayments = make(map[string]time.Time)
+	}
+	key := fmt.Sprintf("%s#%d", txHash, outputIndex)
+	if firstSeen, found := s.heldPayments[key]; found {
+		return firstSeen, nil
+	}
+	s.heldPayments[key] = seen
+
+	return seen, nil
+}
+
+// RecordRefund records a utxo as owed back to its sender, doing nothing if it's already recorded
+func (s *MemoryStore) RecordRefund(ctx context.Context, refund Refund) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.refundForUtxo(refund.TxHash, refund.OutputIndex) != nil {
+		return nil
+	}
+	now := time.Now()
+	refund.ID = len(s.refunds) + 1
+	refund.Status = RefundStatusPending
+	refund.Attempts = 0
+	refund.CreatedDate = now
+	refund.UpdatedDate = now
+	s.refunds = append(s.refunds, &refund)
+
+	return nil
+}
+
+// GetRefundForUtxo gets the refund for a utxo, nil if it isn't owed back
+func (s *MemoryStore) GetRefundForUtxo(txHash string, outputIndex int) (*Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refund := s.refundForUtxo(txHash, outputIndex)
+	if refund == nil {
+		return nil, nil
+	}
+	copied := *refund
+	return &copied, nil
+}
+
+// GetRefundsByStatus gets up to limit refunds in a status, least recently updated first so refunds that keep failing
+// don't hold up the rest
+func (s *MemoryStore) GetRefundsByStatus(status RefundStatus, limit int) ([]Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	refunds := make([]Refund, 0)
+	for _, refund := range s.refunds {
+		if refund.Status == status {
+			refunds = append(refunds, *refund)
+		}
+	}
+	sort.SliceStable(refunds, func(i, j int) bool {
+		return refunds[i].UpdatedDate.Before(refunds[j].UpdatedDate)
+	})
+	if len(refunds) > limit {
+		refunds = refunds[:limit]
+	}
+
+	return refunds, nil
+}
+
+// GetRefundsForUser gets refunds owed to any address the user has paid for a fight or tournament entry from or to one
+// of their accounts, newest first
+func (s *MemoryStore) GetRefundsForUser(userID int) ([]Refund, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	addresses := make(map[string]bool)
+	for _, payment := range s.payments {
+		fight, found := s.fights[payment.FightID]
+		if found && fight.MintingUserID == userID && payment.SenderAddress != "" {
+			addresses[payment.SenderAddress] = true
+		}
+	}
+	for _, entry := range s.tournamentEntries {
+		if entry.UserID == userID && entry.SenderAddress.Valid {
+			addresses[entry.SenderAddress.String] = true
+		}
+	}
+
+	accounts := make(map[string]bool)
+	for _, account := range s.userAccountsOf(userID) {
+		accounts[account] = true
+	}
+
+	refunds := make([]Refund, 0)
+	for i := len(s.refunds) - 1; i >= 0; i-- {
+		if addresses[s.refunds[i].SenderAddress] || accounts[s.refunds[i].SenderAccount] {
+			refunds = append(refunds, *s.refunds[i])
+		}
+	}
+
+	return refunds, nil
+}
+
+// MarkRefundsSubmitted checkpoints the signed refund tx before it's submitted, counting an attempt for each refund
+func (s *MemoryStore) MarkRefundsSubmitted(ctx context.Context, refundIDs []int, txHash string, ttl int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range refundIDs {
+		if id < 1 || id > len(s.refunds) || s.refunds[id-1].Status != RefundStatusPending {
+			return fmt.Errorf("Refund %d is no longer pending", id)
+		}
+	}
+	for _, id := range refundIDs {
+		refund := s.refunds[id-1]
+		refund.Status = RefundStatusSubmitted
+		refund.RefundTxHash = sql.NullString{String: txHash, Valid: true}
+		refund.RefundTxTTL = sql.NullInt64{Int64: int64(ttl), Valid: true}
+		refund.Attempts++
+		refund.UpdatedDate = time.Now()
+	}
+
+	return nil
+}
+
+// ReleaseRefunds puts refunds in a tx that failed or expired back to pending, or failed once out of attempts
+func (s *MemoryStore) ReleaseRefunds(ctx context.Context, txHash string, reason string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, refund := range s.refunds {
+		if refund.Status != RefundStatusSubmitted || refund.RefundTxHash.String != txHash {
+			continue
+		}
+		refund.Status = RefundStatusPending
+		if refund.Attempts >= MaxRefundAttempts {
+			refund.Status = RefundStatusFailed
+		}
+		refund.RefundTxHash = sql.NullString{}
+		refund.RefundTxTTL = sql.NullInt64{}
+		refund.LastError = sql.NullString{String: reason, Valid: true}
+		refund.UpdatedDate = time.Now()
+	}
+
+	return nil
+}
+
+// FailRefunds counts an attempt for pending refunds that couldn't be put in a tx, they're failed once out of attempts
+// or straight away when final
+func (s *MemoryStore) FailRefunds(ctx context.Context, refundIDs []int, reason string, final bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, id := range refundIDs {
+		if id < 1 || id > len(s.refunds) || s.refunds[id-1].Status != RefundStatusPending {
+			continue
+		}
+		refund := s.refunds[id-1]
+		refund.Attempts++
+		if final || refund.Attempts >= MaxRefundAttempts {
+			refund.Status = RefundStatusFailed
+		}
+		refund.LastError = sql.NullString{String: reason, Valid: true}
+		refund.UpdatedDate = time.Now()
+	}
+
+	return nil
+}
+
+// ConfirmRefunds marks the refunds in a tx as confirmed on chain
+func (s *MemoryStore) ConfirmRefunds(ctx context.Context, txHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for _, refund := range s.refunds {
+		if refund.Status == RefundStatusSubmitted && refund.RefundTxHash.String == txHash {
+			refund.Status = RefundStatusConfirmed
+			refund.ConfirmedDate = sql.NullTime{Time: now, Valid: true}
+			refund.UpdatedDate = now
+		}
+	}
+
+	return nil
+}
+
+// GetFightEvents gets the status history of a fight, oldest first
+func (s *MemoryStore) GetFightEvents(fightID int) ([]FightEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	events := make([]FightEvent, 0)
+	for _, event := range s.events {
+		if event.FightID == fightID {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+func (s *MemoryStore) transitionFight(fight *memoryFight, to FightStatus, actor FightActor, reason string) error {
+	from := fight.Status
+	if !from.CanTransitionTo(to) {
+		return IllegalTransitionError{FightID: fight.ID, From: from, To: to}
+	}
+
+	fight.Status = to
+	s.recordEvent(fight.ID, sql.NullString{String: string(from), Valid: true}, to, actor, reason)
+
+	return nil
+}
+
+func (s *MemoryStore) recordEvent(fightID int, from sql.NullString, to FightStatus, actor FightActor, reason string) {
+	s.events = append(s.events, FightEvent{
+		ID:          len(s.events) + 1,
+		FightID:     fightID,
+		FromStatus:  from,
+		ToStatus:    to,
+		Actor:       actor,
+		Reason:      reason,
+		CreatedDate: time.Now(),
+	})
+}
+
+// selectFights returns joined copies of the fights matching filter, in insert order
+func (s *MemoryStore) selectFights(filter func(f *memoryFight) bool) []FightDb {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	rows := make([]*memoryFight, 0)
+	for _, fight := range s.fights {
+		if filter(fight) {
+			rows = append(rows, fight)
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].memoryRowOrder < rows[j].memoryRowOrder
+	})
+
+	fights := make([]FightDb, 0, len(rows))
+	for _, row := range rows {
+		fights = append(fights, s.joinFight(row))
+	}
+
+	return fights
+}
+
+func (s *MemoryStore) joinFight(row *memoryFight) FightDb {
+	fight := row.FightDb
+	if zombie, found := s.nfts[row.ZombieNftID]; found {
+		fight.ZombieName = zombie.NftName
+	}
+	if hunter, found := s.nfts[row.HunterNftID]; found {
+		fight.HunterName = hunter.NftName
+	}
+	if alien := s.alienForFight(row.ID); alien != nil {
+		fight.IPFSAlien = alien.Ipfs
+	}
+
+	return fight
+}
+
+func (s *MemoryStore) joinUserNft(userNft *memoryUserNft) UserNfts {
+	joined := UserNfts{
+		UserID:     userNft.UserID,
+		NftID:      userNft.NftID,
+		ListAmount: userNft.ListAmount,
+		ListDate:   userNft.ListDate,
+	}
+	if nft, found := s.nfts[userNft.NftID]; found {
+		joined.NftName = nft.NftName
+		joined.NftType = nft.NftType
+		joined.Wins = nft.Wins
+		joined.Loses = nft.Loses
+	}
+
+	return joined
+}
+
+func (s *MemoryStore) removeUserNfts(matches func(userNft *memoryUserNft) bool) {
+	kept := make([]*memoryUserNft, 0, len(s.userNfts))
+	for _, userNft := range s.userNfts {
+		if !matches(userNft) {
+			kept = append(kept, userNft)
+		}
+	}
+	s.userNfts = kept
+}
+
+func (s *MemoryStore) nftByName(name string) *Nft {
+	for _, nft := range s.nfts {
+		if nft.NftName == name {
+			return nft
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) refundForUtxo(txHash string, outputIndex int) *Refund {
+	for _, refund := range s.refunds {
+		if refund.TxHash == txHash && refund.OutputIndex == outputIndex {
+			return refund
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) alienForFight(fightID int) *Alien {
+	for _, alien := range s.aliens {
+		if alien.FightID.Valid && alien.FightID.Int64 == int64(fightID) {
+			return alien
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) currentSeason(now time.Time) *Season {
+	for _, season := range s.seasons {
+		if season.Open(now) {
+			return season
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) seasonRecord(seasonID int, nftID int) *memorySeasonRecord {
+	for _, record := range s.seasonRecords {
+		if record.SeasonID == seasonID && record.NftID == nftID {
+			return record
+		}
+	}
+	record := &memorySeasonRecord{SeasonID: seasonID, NftID: nftID}
+	s.seasonRecords = append(s.seasonRecords, record)
+	return record
+}
+
+// sortedSeasonNfts nfts that fought in the season with their season records in place of their all time records
+func (s *MemoryStore) sortedSeasonNfts(seasonID int, limit int, filter func(nft Nft) bool, less func(a, b Nft) bool) []Nft {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nfts := make([]Nft, 0)
+	for _, record := range s.seasonRecords {
+		nft, found := s.nfts[record.NftID]
+		if record.SeasonID != seasonID || !found {
+			continue
+		}
+		seasonNft := *nft
+		seasonNft.Wins, seasonNft.Loses = record.Wins, record.Loses
+		if filter == nil || filter(seasonNft) {
+			nfts = append(nfts, seasonNft)
+		}
+	}
+
+	return limitNfts(sortNfts(nfts, less), limit)
+}
+
+func (s *MemoryStore) sortedNfts(limit int, filter func(nft Nft) bool, less func(a, b Nft) bool) []Nft {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nfts := make([]Nft, 0)
+	for _, nft := range s.nfts {
+		if filter == nil || filter(*nft) {
+			nfts = append(nfts, *nft)
+		}
+	}
+
+	return limitNfts(sortNfts(nfts, less), limit)
+}
+
+func sortNfts(nfts []Nft, less func(a, b Nft) bool) []Nft {
+	sort.SliceStable(nfts, func(i, j int) bool {
+		if less(nfts[i], nfts[j]) == less(nfts[j], nfts[i]) {
+			return nfts[i].ID < nfts[j].ID
+		}
+		return less(nfts[i], nfts[j])
+	})
+	return nfts
+}
+
+func limitNfts(nfts []Nft, limit int) []Nft {
+	if len(nfts) > limit {
+		nfts = nfts[:limit]
+	}
+	return nfts
+}
+
+func (s *MemoryStore) tournament(tournamentID int) *Tournament {
+	for _, t := range s.tournaments {
+		if t.ID == tournamentID {
+			return t
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) tournamentEntry(entryID int) *TournamentEntry {
+	for _, entry := range s.tournamentEntries {
+		if entry.ID == entryID {
+			return entry
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) joinTournamentEntry(entry *TournamentEntry) TournamentEntry {
+	joined := *entry
+	if zombie, found := s.nfts[entry.ZombieNftID]; found {
+		joined.ZombieName = zombie.NftName
+	}
+	if hunter, found := s.nfts[entry.HunterNftID]; found {
+		joined.HunterName = hunter.NftName
+	}
+	return joined
+}
+
+func (s *MemoryStore) transitionTournament(tournamentID int, from TournamentStatus, to TournamentStatus) (*Tournament, error) {
+	t := s.tournament(tournamentID)
+	if t == nil || t.Status != from {
+		return nil, fmt.Errorf("Tournament %d can't move to %s, it isn't %s", tournamentID, to, from)
+	}
+	t.Status = to
+	return t, nil
+}
+
+func winPercent(nft Nft) float64 {
+	if nft.Wins+nft.Loses == 0 {
+		return 0
+	}
+	return float64(nft.Wins) / float64(nft.Wins+nft.Loses) * 100
+}
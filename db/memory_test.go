@@ -0,0 +1,186 @@
+package store
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreFightLifecycle(t *testing.T) {
+	s := NewMemoryStore()
+	zombie := s.AddNft("ZombieChains00001", "Zombie")
+	hunter := s.AddNft("ZombieHunter00001", "Hunter")
+	alien := s.AddAlien(Alien{Name: "Alien00001"})
+
+	if err := s.InsertUser("user", "", ""); err != nil {
+		t.Fatalf("Error inserting user %v", err)
+	}
+	user, _ := s.GetUserByNftkeyID("user")
+
+	fightID, err := s.CreateFight(FightDto{Status: "PENDING", PaymentAmountLovelace: 12000123, PaymentAddress: "addr"},
+		UserNfts{UserID: user.ID, NftID: hunter.ID}, UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+	if err != nil {
+		t.Fatalf("Error creating fight %v", err)
+	}
+
+	pending, err := s.GetUnpaidFightForAmount(12000123, 20*time.Minute)
+	if err != nil || pending == nil || pending.ID != fightID {
+		t.Fatalf("Expected pending fight %d for payment but got %v / %v", fightID, pending, err)
+	}
+
+	ctx := context.Background()
+	if err := s.MoveFightFromPendingToQueued(ctx, fightID, alien.ID, []FightPayment{{TxHash: "txhash", OutputIndex: 0, Lovelace: 12000123}}); err != nil {
+		t.Fatalf("Error queueing %v", err)
+	}
+	if paid, _ := s.GetUnpaidFightForAmount(12000123, 20*time.Minute); paid != nil {
+		t.Error("Paid fight should no longer match payments")
+	}
+	if next, _ := s.GetNextAvailableAlien(); next != nil {
+		t.Error("Alien should be assigned to the fight")
+	}
+	if byUtxo, _ := s.GetFightForUtxo("txhash", 0); len(byUtxo) != 1 {
+		t.Errorf("Expected one fight for utxo but got %d", len(byUtxo))
+	}
+
+	err = s.MoveFightFromQueuedToStaged(ctx, alien.ID, "alienipfs", fightID, "fightipfs", "Ring", "001-000", "000-001", 60, 20, false, false, false, true, "{}", zombie.NftName, hunter.NftName)
+	if err != nil {
+		t.Fatalf("Error staging %v", err)
+	}
+	staged, _ := s.GetStagedFights()
+	if len(staged) != 1 || staged[0].ZombieName != zombie.NftName || !staged[0].IncomingUtxo.Valid {
+		t.Fatalf("Unexpected staged fights %v", staged)
+	}
+	if winner, _ := s.GetNftByName(zombie.NftName); winner.Wins != 1 {
+		t.Errorf("Expected zombie to have 1 win but has %d", winner.Wins)
+	}
+	if loser, _ := s.GetNftByName(hunter.NftName); loser.Loses != 1 {
+		t.Errorf("Expected hunter to have 1 loss but has %d", loser.Loses)
+	}
+	if leaders, _ := s.GetNftHighestRating(2); leaders[0].NftName != zombie.NftName || leaders[0].Rating <= 1500 || leaders[1].Rating >= 1500 {
+		t.Errorf("Expected the winner rated above 1500 and the loser below but got %v", leaders)
+	}
+	if history, _ := s.GetNftRatingHistory(zombie.ID); len(history) != 1 || history[0].FightID != fightID {
+		t.Errorf("Expected one rating in the zombie's history but got %v", history)
+	}
+
+	if err := s.MoveFightFromStagedToMinted(ctx, fightID, "minttx"); err != nil {
+		t.Fatalf("Error minting %v", err)
+	}
+	if err := s.MoveFightFromMintedToConfirmed(ctx, fightID); err != nil {
+		t.Fatalf("Error confirming %v", err)
+	}
+
+	events, _ := s.GetFightEvents(fightID)
+	if len(events) != 5 {
+		t.Fatalf("Expected 5 fight events but got %d", len(events))
+	}
+	if events[4].FromStatus.String != string(FightStatusMinted) || events[4].ToStatus != FightStatusConfirmed || events[4].Actor != FightActorEngine {
+		t.Errorf("Unexpected last event %v", events[4])
+	}
+
+	fights, _ := s.GetFightsForUser(*user)
+	if len(fights) != 1 || fights[0].Status != FightStatusConfirmed || fights[0].IPFSAlien.String != "alienipfs" {
+		t.Fatalf("Unexpected fights for user %v", fights)
+	}
+
+	// replaying rebuilds the same ratings from scratch
+	rated, _ := s.GetNftByName(zombie.NftName)
+	if count, err := s.BackfillRatings(ctx); err != nil || count != 1 {
+		t.Fatalf("Expected to backfill one fight but got %d / %v", count, err)
+	}
+	if backfilled, _ := s.GetNftByName(zombie.NftName); backfilled.Rating != rated.Rating || backfilled.RatingDeviation != rated.RatingDeviation {
+		t.Errorf("Expected backfill to give %v but got %v", rated, backfilled)
+	}
+	if history, _ := s.GetNftRatingHistory(zombie.ID); len(history) != 1 {
+		t.Errorf("Expected the history rebuilt with one rating but got %d", len(history))
+	}
+}
+
+func TestMemoryStoreSignedMintTxCheckpoint(t *testing.T) {
+	s := NewMemoryStore()
+	zombie := s.AddNft("ZombieChains00001", "Zombie")
+	hunter := s.AddNft("ZombieHunter00001", "Hunter")
+	alien := s.AddAlien(Alien{Name: "Alien00001"})
+	s.InsertUser("user", "", "")
+	user, _ := s.GetUserByNftkeyID("user")
+
+	fightID, _ := s.CreateFight(FightDto{PaymentAmountLovelace: 12000123}, UserNfts{UserID: user.ID, NftID: hunter.ID}, UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+
+	ctx := context.Background()
+	if err := s.SetSignedMintTx(ctx, fightID, "hash1", "cbor1", 100); err == nil {
+		t.Error("Expected error checkpointing a fight that isn't staged")
+	}
+
+	s.MoveFightFromPendingToQueued(ctx, fightID, alien.ID, []FightPayment{{TxHash: "txhash", OutputIndex: 0, Lovelace: 12000123}})
+	s.MoveFightFromQueuedToStaged(ctx, alien.ID, "alienipfs", fightID, "fightipfs", "Ring", "001-000", "000-001", 60, 20, false, false, false, true, "{}", zombie.NftName, hunter.NftName)
+
+	if err := s.SetSignedMintTx(ctx, fightID, "hash1", "cbor1", 100); err != nil {
+		t.Fatalf("Error checkpointing signed tx %v", err)
+	}
+	if err := s.SetSignedMintTx(ctx, fightID, "hash2", "cbor2", 200); err == nil {
+		t.Error("Expected error replacing a checkpointed signed tx")
+	}
+
+	// clearing with another hash leaves the checkpoint alone
+	s.ClearSignedMintTx(ctx, fightID, "hash2")
+	staged, _ := s.GetStagedFights()
+	if len(staged) != 1 || staged[0].SignedTxHash.String != "hash1" || staged[0].SignedTxTTL.Int64 != 100 {
+		t.Fatalf("Unexpected staged fights %v", staged)
+	}
+
+	s.ClearSignedMintTx(ctx, fightID, "hash1")
+	if err := s.SetSignedMintTx(ctx, fightID, "hash2", "cbor2", 200); err != nil {
+		t.Fatalf("Error checkpointing new signed tx %v", err)
+	}
+}
+
+func TestMemoryStoreSeasons(t *testing.T) {
+	s := NewMemoryStore()
+	zombie := s.AddNft("ZombieChains00001", "Zombie")
+	hunter := s.AddNft("ZombieHunter00001", "Hunter")
+	alien := s.AddAlien(Alien{Name: "Alien00001"})
+	s.InsertUser("user", "", "")
+	user, _ := s.GetUserByNftkeyID("user")
+
+	ctx := context.Background()
+	now := time.Now()
+	seasonID, err := s.CreateSeason(ctx, "Season 1", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("Error creating season %v", err)
+	}
+	if _, err := s.CreateSeason(ctx, "Season 2", now, now.Add(2*time.Hour)); err == nil {
+		t.Error("Expected error creating an overlapping season")
+	}
+	if current, _ := s.GetCurrentSeason(now); current == nil || current.ID != seasonID {
+		t.Fatalf("Expected season %d to be running but got %v", seasonID, current)
+	}
+
+	fightID, _ := s.CreateFight(FightDto{PaymentAmountLovelace: 12000123}, UserNfts{UserID: user.ID, NftID: hunter.ID}, UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+	s.MoveFightFromPendingToQueued(ctx, fightID, alien.ID, []FightPayment{{TxHash: "txhash", OutputIndex: 0, Lovelace: 12000123}})
+	queued, _ := s.GetQueuedFight()
+	if len(queued) != 1 || queued[0].SeasonID.Int64 != int64(seasonID) {
+		t.Fatalf("Expected the fight tagged with season %d but got %v", seasonID, queued)
+	}
+	s.MoveFightFromQueuedToStaged(ctx, alien.ID, "alienipfs", fightID, "fightipfs", "Ring", "001-000", "000-001", 60, 20, false, false, false, true, "{}", zombie.NftName, hunter.NftName)
+
+	if wins, _ := s.GetSeasonMostWins(seasonID, 10); len(wins) != 2 || wins[0].NftName != zombie.NftName || wins[0].Wins != 1 {
+		t.Errorf("Expected the zombie to lead the season's wins but got %v", wins)
+	}
+	if percent, _ := s.GetSeasonHighestPercentMinimum(seasonID, 1, 10); len(percent) != 1 || percent[0].Percent.Float64 != 100 {
+		t.Errorf("Expected the zombie alone with 100 percent but got %v", percent)
+	}
+
+	if ranked, err := s.CloseSeason(ctx, seasonID); err != nil || ranked != 2 {
+		t.Fatalf("Expected to rank 2 nfts but got %d / %v", ranked, err)
+	}
+	if _, err := s.CloseSeason(ctx, seasonID); err == nil {
+		t.Error("Expected error closing a closed season")
+	}
+	standings, _ := s.GetSeasonStandings(seasonID)
+	if len(standings) != 2 || standings[0].NftName != zombie.NftName || standings[0].Rank != 1 || standings[1].Loses != 1 || standings[0].Rating <= 1500 {
+		t.Errorf("Unexpected standings %v", standings)
+	}
+	if current, _ := s.GetCurrentSeason(now); current != nil {
+		t.Errorf("Expected no season running after closing but got %v", current)
+	}
+}
@@ -0,0 +1,318 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sirupsen/logrus"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+type (
+	// Migration struct to hold a versioned schema change
+	Migration struct {
+		Version int
+		Name    string
+		Up      string
+		Down    string
+	}
+
+	// MigrationStatus struct to hold whether a migration has been applied
+	MigrationStatus struct {
+		Version   int
+		Name      string
+		Applied   bool
+		AppliedAt sql.NullTime
+	}
+)
+
+const createMigrationsTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version     integer PRIMARY KEY,
+    name        varchar(128) not null,
+    applied_at  timestamptz DEFAULT NOW()
+)`
+
+// LoadMigrations loads the embedded migrations ordered by version
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		// file names look like 0001_initial.up.sql
+		fileName := entry.Name()
+		parts := strings.SplitN(fileName, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("Migration %s has no version prefix", fileName)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("Migration %s has invalid version: %v", fileName, err)
+		}
+
+		var direction, name string
+		if strings.HasSuffix(parts[1], ".up.sql") {
+			direction = "up"
+			name = strings.TrimSuffix(parts[1], ".up.sql")
+		} else if strings.HasSuffix(parts[1], ".down.sql") {
+			direction = "down"
+			name = strings.TrimSuffix(parts[1], ".down.sql")
+		} else {
+			return nil, fmt.Errorf("Migration %s must end in .up.sql or .down.sql", fileName)
+		}
+
+		contents, err := fs.ReadFile(migrationFiles, "migrations/"+fileName)
+		if err != nil {
+			return nil, err
+		}
+
+		migration, found := byVersion[version]
+		if !found {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		} else if migration.Name != name {
+			return nil, fmt.Errorf("Migration version %d used by both %s and %s", version, migration.Name, name)
+		}
+
+		if direction == "up" {
+			migration.Up = string(contents)
+		} else {
+			migration.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("Migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
+
+// GetMigrationStatus returns every known migration and whether it has been applied
+func GetMigrationStatus(database *sqlx.DB) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := getAppliedMigrations(database)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		status := MigrationStatus{
+			Version: migration.Version,
+			Name:    migration.Name,
+		}
+		if appliedAt, found := applied[migration.Version]; found {
+			status.Applied = true
+			status.AppliedAt = appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, nil
+}
+
+// MigrateUp applies every pending migration in order, returning the ones applied
+func MigrateUp(ctx context.Context, database *sqlx.DB) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := getAppliedMigrations(database)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]Migration, 0)
+	for _, migration := range migrations {
+		if _, found := applied[migration.Version]; found {
+			continue
+		}
+
+		logrus.Infof("Applying migration %04d_%s", migration.Version, migration.Name)
+		err = runMigration(ctx, database, migration.Up, "INSERT INTO schema_migrations (version, name, applied_at) VALUES($1, $2, $3)", migration.Version, migration.Name, time.Now())
+		if err != nil {
+			return done, fmt.Errorf("Applying migration %04d_%s: %v", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// MigrateDown rolls back the latest applied migrations, up to steps of them
+func MigrateDown(ctx context.Context, database *sqlx.DB, steps int) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	applied, err := getAppliedMigrations(database)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]Migration, 0)
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := migrations[i]
+		if _, found := applied[migration.Version]; !found {
+			continue
+		}
+
+		logrus.Infof("Rolling back migration %04d_%s", migration.Version, migration.Name)
+		err = runMigration(ctx, database, migration.Down, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		if err != nil {
+			return done, fmt.Errorf("Rolling back migration %04d_%s: %v", migration.Version, migration.Name, err)
+		}
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// baselineVersion the migration a database created from the old db/createDb.sql already has, 0001 is that script
+const baselineVersion = 1
+
+// Baseline records the first migration as applied on a database that was created before migrations existed, it
+// refuses if any migration is already recorded or if the tables the first migration creates aren't all there
+func Baseline(ctx context.Context, database *sqlx.DB) (*Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	baseline := migrations[0]
+	if baseline.Version != baselineVersion {
+		return nil, fmt.Errorf("Expected migration %04d to baseline but found %04d_%s", baselineVersion, baseline.Version, baseline.Name)
+	}
+
+	applied, err := getAppliedMigrations(database)
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) > 0 {
+		return nil, fmt.Errorf("Database already has %d migrations applied, nothing to baseline", len(applied))
+	}
+
+	missing := make([]string, 0)
+	for _, table := range createdTables(baseline.Up) {
+		var exists bool
+		err = database.GetContext(ctx, &exists, "SELECT to_regclass($1) IS NOT NULL", table)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			missing = append(missing, table)
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("Database is missing tables %s, run 'server migrate up' instead", strings.Join(missing, ", "))
+	}
+
+	logrus.Infof("Marking migration %04d_%s as applied", baseline.Version, baseline.Name)
+	_, err = database.ExecContext(ctx, "INSERT INTO schema_migrations (version, name, applied_at) VALUES($1, $2, $3)", baseline.Version, baseline.Name, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &baseline, nil
+}
+
+// createdTables the names of the tables a script creates, in order
+func createdTables(script string) []string {
+	tables := make([]string, 0)
+	for _, line := range strings.Split(script, "\n") {
+		fields := strings.Fields(strings.ToLower(line))
+		if len(fields) >= 3 && fields[0] == "create" && fields[1] == "table" {
+			tables = append(tables, strings.TrimSuffix(fields[2], "("))
+		}
+	}
+	return tables
+}
+
+// CheckSchemaCurrent returns an error if any embedded migration has not been applied
+func CheckSchemaCurrent(database *sqlx.DB) error {
+	statuses, err := GetMigrationStatus(database)
+	if err != nil {
+		return err
+	}
+
+	pending := make([]string, 0)
+	for _, status := range statuses {
+		if !status.Applied {
+			pending = append(pending, fmt.Sprintf("%04d_%s", status.Version, status.Name))
+		}
+	}
+
+	if len(pending) > 0 {
+		return fmt.Errorf("Database schema is behind, pending migrations: %s", strings.Join(pending, ", "))
+	}
+
+	return nil
+}
+
+func getAppliedMigrations(database *sqlx.DB) (map[int]sql.NullTime, error) {
+	_, err := database.Exec(createMigrationsTable)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := []struct {
+		Version   int          `db:"version"`
+		AppliedAt sql.NullTime `db:"applied_at"`
+	}{}
+	err = database.Select(&rows, "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]sql.NullTime)
+	for _, row := range rows {
+		applied[row.Version] = row.AppliedAt
+	}
+
+	return applied, nil
+}
+
+func runMigration(ctx context.Context, database *sqlx.DB, script string, bookkeeping string, args ...interface{}) error {
+	tx, err := database.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, bookkeeping, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
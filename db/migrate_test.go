@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/jmoiron/sqlx"
+
+	_ "github.com/lib/pq"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("Error loading migrations %v", err)
+	}
+
+	if len(migrations) == 0 {
+		t.Fatal("No migrations found")
+	}
+
+	for i, migration := range migrations {
+		if migration.Version != i+1 {
+			t.Errorf("Expected migration version %d but found %d (%s)", i+1, migration.Version, migration.Name)
+		}
+		if migration.Up == "" || migration.Down == "" {
+			t.Errorf("Migration %d is missing an up or down script", migration.Version)
+		}
+	}
+}
+
+func TestBaselineCreatesWhatCreateDbDid(t *testing.T) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		t.Fatalf("Error loading migrations %v", err)
+	}
+	createDb, err := os.ReadFile("testdata/createDb.sql")
+	if err != nil {
+		t.Fatalf("Error reading createDb.sql %v", err)
+	}
+
+	expected := []string{"zfc_user", "nft", "zfc_user_nft", "fight", "zfc_alien"}
+	if tables := createdTables(string(createDb)); !reflect.DeepEqual(tables, expected) {
+		t.Errorf("Expected createDb.sql to create %v but got %v", expected, tables)
+	}
+	if tables := createdTables(migrations[0].Up); !reflect.DeepEqual(tables, expected) {
+		t.Errorf("Expected %04d_%s to create %v but got %v", migrations[0].Version, migrations[0].Name, expected, tables)
+	}
+}
+
+// testDatabase connects to the throwaway database in TEST_DATABASE_URL and drops everything in it, skips without one
+func testDatabase(t *testing.T) *sqlx.DB {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+
+	database, err := sqlx.Connect("postgres", url)
+	if err != nil {
+		t.Fatalf("Error connecting to test db %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	_, err = database.Exec("DROP SCHEMA public CASCADE; CREATE SCHEMA public")
+	if err != nil {
+		t.Fatalf("Error clearing test db %v", err)
+	}
+
+	return database
+}
+
+func TestBaselineAdoptsCreateDbDatabase(t *testing.T) {
+	database := testDatabase(t)
+	ctx := context.Background()
+
+	createDb, err := os.ReadFile("testdata/createDb.sql")
+	if err != nil {
+		t.Fatalf("Error reading createDb.sql %v", err)
+	}
+	if _, err = database.Exec(string(createDb)); err != nil {
+		t.Fatalf("Error running createDb.sql %v", err)
+	}
+	if _, err = database.Exec("INSERT INTO zfc_user (nftkeyme_id) VALUES ('existing-user')"); err != nil {
+		t.Fatalf("Error inserting user %v", err)
+	}
+
+	// up on its own fails, the tables are already there
+	if _, err = MigrateUp(ctx, database); err == nil {
+		t.Fatal("Expected migrate up to fail before baselining")
+	}
+
+	baseline, err := Baseline(ctx, database)
+	if err != nil {
+		t.Fatalf("Error baselining %v", err)
+	}
+	if baseline.Version != 1 {
+		t.Errorf("Expected 0001 baselined but got %04d_%s", baseline.Version, baseline.Name)
+	}
+	if _, err = Baseline(ctx, database); err == nil {
+		t.Error("Expected a second baseline to be refused")
+	}
+
+	if _, err = MigrateUp(ctx, database); err != nil {
+		t.Fatalf("Error migrating up after baseline %v", err)
+	}
+	if err = CheckSchemaCurrent(database); err != nil {
+		t.Fatalf("Expected schema current %v", err)
+	}
+
+	var users int
+	if err = database.Get(&users, "SELECT count(*) FROM zfc_user WHERE nftkeyme_id = 'existing-user'"); err != nil || users != 1 {
+		t.Errorf("Expected the existing user kept but found %d (%v)", users, err)
+	}
+}
+
+func TestBaselineRefusesEmptyDatabase(t *testing.T) {
+	database := testDatabase(t)
+
+	if _, err := Baseline(context.Background(), database); err == nil {
+		t.Fatal("Expected baseline to be refused without the initial tables")
+	}
+
+	statuses, err := GetMigrationStatus(database)
+	if err != nil {
+		t.Fatalf("Error getting status %v", err)
+	}
+	if statuses[0].Applied {
+		t.Error("Expected nothing marked applied")
+	}
+}
@@ -0,0 +1,5 @@
+drop table if exists zfc_alien;
+drop table if exists fight;
+drop table if exists zfc_user_nft;
+drop table if exists nft;
+drop table if exists zfc_user;
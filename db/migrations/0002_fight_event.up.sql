@@ -0,0 +1,16 @@
+create table fight_event (
+    id                         serial PRIMARY KEY,
+    fight_id                   integer not null,
+    from_status                varchar(64),
+    to_status                  varchar(64) not null,
+    actor                      varchar(32) not null,
+    reason                     varchar(256) not null DEFAULT '',
+    created_date               timestamptz DEFAULT NOW(),
+    CONSTRAINT FK_fight_event_fight_id FOREIGN KEY(fight_id) REFERENCES fight(id)
+);
+
+create index fight_event_fight_id_idx on fight_event(fight_id);
+
+-- fights that existed before history was kept start at their current status
+insert into fight_event (fight_id, from_status, to_status, actor, reason, created_date)
+    select id, null, status, 'MIGRATION', 'Status before event history', created_date from fight;
@@ -0,0 +1,4 @@
+alter table fight drop column if exists signed_tx_ttl;
+alter table fight drop column if exists signed_tx_cbor;
+alter table fight drop column if exists signed_tx_hash;
+alter table fight drop column if exists tweet_attempted;
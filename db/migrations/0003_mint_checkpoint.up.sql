@@ -0,0 +1,8 @@
+-- alien upload is checkpointed on zfc_alien.ipfs_hash, the rest of the pipeline on the fight
+alter table fight add column tweet_attempted boolean not null DEFAULT false;
+alter table fight add column signed_tx_hash varchar(128);
+alter table fight add column signed_tx_cbor text;
+alter table fight add column signed_tx_ttl bigint;
+
+-- fights already tweeted or minted before checkpoints existed
+update fight set tweet_attempted = true where status != 'PENDING' and status != 'QUEUED';
@@ -0,0 +1 @@
+drop table if exists fight_payment;
@@ -0,0 +1,18 @@
+create table fight_payment (
+    id                         serial PRIMARY KEY,
+    fight_id                   integer not null,
+    tx_hash                    varchar(128) not null,
+    output_index               integer not null,
+    sender_address             varchar(256) not null DEFAULT '',
+    lovelace                   bigint not null,
+    assets                     text not null DEFAULT '[]',
+    created_date               timestamptz DEFAULT NOW(),
+    UNIQUE(tx_hash, output_index),
+    CONSTRAINT FK_fight_payment_fight_id FOREIGN KEY(fight_id) REFERENCES fight(id)
+);
+
+create index fight_payment_fight_id_idx on fight_payment(fight_id);
+
+-- fights paid before payments were kept were always paid by one exact utxo
+insert into fight_payment (fight_id, tx_hash, output_index, lovelace, created_date)
+    select id, incoming_utxo, incoming_utxo_index, payment_amount_lovelace, created_date from fight where incoming_utxo is not null;
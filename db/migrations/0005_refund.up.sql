@@ -0,0 +1,21 @@
+-- utxos at the payment address that didn't pay for a fight and are owed back to the sender
+create table refund (
+    id                         serial PRIMARY KEY,
+    tx_hash                    varchar(128) not null,
+    output_index               integer not null,
+    sender_address             varchar(256) not null,
+    lovelace                   bigint not null,
+    assets                     text not null DEFAULT '[]',
+    status                     varchar(32) not null DEFAULT 'PENDING',
+    attempts                   integer not null DEFAULT 0,
+    refund_tx_hash             varchar(128),
+    refund_tx_ttl              bigint,
+    last_error                 text,
+    created_date               timestamptz DEFAULT NOW(),
+    updated_date               timestamptz DEFAULT NOW(),
+    confirmed_date             timestamptz,
+    UNIQUE(tx_hash, output_index)
+);
+
+create index refund_status_idx on refund(status);
+create index refund_sender_address_idx on refund(sender_address);
@@ -0,0 +1,6 @@
+alter table refund drop column if exists payment_address_index;
+alter table refund drop column if exists payment_address;
+
+drop sequence if exists fight_payment_address_index_seq;
+drop index if exists fight_payment_address_index_idx;
+alter table fight drop column if exists payment_address_index;
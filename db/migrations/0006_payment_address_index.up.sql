@@ -0,0 +1,8 @@
+-- fights paying to their own hd wallet address keep the derivation index, m/1852'/1815'/account'/0/index
+alter table fight add column payment_address_index integer;
+create unique index fight_payment_address_index_idx on fight(payment_address_index) where payment_address_index is not null;
+create sequence fight_payment_address_index_seq minvalue 0 start 0;
+
+-- refunds are spent from the address they were paid to
+alter table refund add column payment_address varchar(128) not null DEFAULT '';
+alter table refund add column payment_address_index integer;
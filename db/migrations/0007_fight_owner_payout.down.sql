@@ -0,0 +1 @@
+-- the recorded list prices were never charged, there is nothing to restore
@@ -0,0 +1,3 @@
+-- owners are now paid their list price out of each fight, fights created before weren't charged the fake
+-- list price they recorded so there is nothing to pay out for them
+update fight set hunter_amount_ada = 0, zombie_amount_ada = 0 where status in ('PENDING', 'QUEUED', 'STAGED');
@@ -0,0 +1,4 @@
+alter table fight drop column if exists seed;
+alter table fight drop column if exists seed_tx_hash;
+alter table fight drop column if exists seed_secret;
+alter table fight drop column if exists seed_commitment;
@@ -0,0 +1,6 @@
+-- commit-reveal seed for the fight's outcome, the commitment is sha256(secret) and is published when the fight is
+-- created, the seed is sha256(secret || payment tx hash) once the fight is paid
+alter table fight add column seed_commitment varchar(64);
+alter table fight add column seed_secret varchar(64);
+alter table fight add column seed_tx_hash varchar(64);
+alter table fight add column seed varchar(64);
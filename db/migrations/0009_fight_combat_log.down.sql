@@ -0,0 +1 @@
+alter table fight drop column if exists combat_log;
@@ -0,0 +1,2 @@
+-- round by round log of how the fight played out, json from metadata.CombatLog
+alter table fight add column combat_log text;
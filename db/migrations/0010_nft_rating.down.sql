@@ -0,0 +1,4 @@
+drop table if exists nft_rating_history;
+alter table nft drop column if exists rating_volatility;
+alter table nft drop column if exists rating_deviation;
+alter table nft drop column if exists rating;
@@ -0,0 +1,18 @@
+-- glicko-2 rating per nft, updated when a fight is staged, run 'server ratings backfill' to rate past fights
+alter table nft add column rating double precision not null DEFAULT 1500;
+alter table nft add column rating_deviation double precision not null DEFAULT 350;
+alter table nft add column rating_volatility double precision not null DEFAULT 0.06;
+
+create table nft_rating_history (
+    id                         serial PRIMARY KEY,
+    nft_id                     integer not null,
+    fight_id                   integer not null,
+    rating                     double precision not null,
+    rating_deviation           double precision not null,
+    rating_volatility          double precision not null,
+    created_date               timestamptz DEFAULT NOW(),
+    CONSTRAINT FK_nft_rating_history_nft_id FOREIGN KEY(nft_id) REFERENCES nft(id),
+    CONSTRAINT FK_nft_rating_history_fight_id FOREIGN KEY(fight_id) REFERENCES fight(id)
+);
+
+create index nft_rating_history_nft_id_idx on nft_rating_history(nft_id);
@@ -0,0 +1,5 @@
+drop table if exists season_standing;
+drop table if exists season_record;
+alter table fight drop constraint if exists FK_fight_season_id;
+alter table fight drop column if exists season_id;
+drop table if exists season;
@@ -0,0 +1,38 @@
+-- seasons run from start_date to end_date, fights created in an open season are tagged with it and counted in its
+-- records until 'server seasons close' snapshots the final standings
+create table season (
+    id                         serial PRIMARY KEY,
+    name                       varchar(64) not null,
+    start_date                 timestamptz not null,
+    end_date                   timestamptz not null,
+    closed_date                timestamptz,
+    created_date               timestamptz DEFAULT NOW(),
+    UNIQUE(name),
+    CHECK(end_date > start_date)
+);
+
+alter table fight add column season_id integer;
+alter table fight add CONSTRAINT FK_fight_season_id FOREIGN KEY(season_id) REFERENCES season(id);
+
+create table season_record (
+    season_id                  integer not null,
+    nft_id                     integer not null,
+    wins                       integer not null DEFAULT 0,
+    loses                      integer not null DEFAULT 0,
+    PRIMARY KEY(season_id, nft_id),
+    CONSTRAINT FK_season_record_season_id FOREIGN KEY(season_id) REFERENCES season(id),
+    CONSTRAINT FK_season_record_nft_id FOREIGN KEY(nft_id) REFERENCES nft(id)
+);
+
+create table season_standing (
+    season_id                  integer not null,
+    nft_id                     integer not null,
+    rank                       integer not null,
+    wins                       integer not null,
+    loses                      integer not null,
+    rating                     double precision not null,
+    rating_deviation           double precision not null,
+    PRIMARY KEY(season_id, nft_id),
+    CONSTRAINT FK_season_standing_season_id FOREIGN KEY(season_id) REFERENCES season(id),
+    CONSTRAINT FK_season_standing_nft_id FOREIGN KEY(nft_id) REFERENCES nft(id)
+);
@@ -0,0 +1,9 @@
+drop index if exists fight_payment_address_index_idx;
+create unique index fight_payment_address_index_idx on fight(payment_address_index) where payment_address_index is not null;
+
+alter table fight drop constraint if exists FK_fight_tournament_id;
+alter table fight drop column if exists tournament_id;
+
+drop table if exists tournament_match;
+drop table if exists tournament_entry;
+drop table if exists tournament;
@@ -0,0 +1,76 @@
+-- tournaments collect entry fees at their own hd wallet address, the prize pool, which pays for every fight and
+-- whatever is left is paid out by place once the last fight is minted
+create table tournament (
+    id                         serial PRIMARY KEY,
+    name                       varchar(64) not null,
+    format                     varchar(32) not null,
+    bracket_size               integer not null,
+    entry_fee_lovelace         bigint not null,
+    fight_cost_lovelace        bigint not null,
+    prize_split                varchar(64) not null,
+    registration_start         timestamptz not null,
+    registration_end           timestamptz not null,
+    status                     varchar(16) not null DEFAULT 'REGISTRATION',
+    payment_address            varchar(128) not null,
+    payment_address_index      integer not null,
+    payout_tx_hash             varchar(64),
+    payout_tx_cbor             text,
+    payout_tx_ttl              integer,
+    created_date               timestamptz DEFAULT NOW(),
+    started_date               timestamptz,
+    finished_date              timestamptz,
+    UNIQUE(name),
+    CHECK(registration_end > registration_start)
+);
+
+-- an entry is one user's team of a zombie and a hunter, unpaid entries lapse after the payment window
+create table tournament_entry (
+    id                         serial PRIMARY KEY,
+    tournament_id              integer not null,
+    zfc_user_id                integer not null,
+    zombie_nft_id              integer not null,
+    hunter_nft_id              integer not null,
+    payment_amount_lovelace    bigint not null,
+    payment_tx_hash            varchar(64),
+    payment_output_index       integer,
+    payment_lovelace           bigint,
+    sender_address             varchar(128),
+    paid_date                  timestamptz,
+    seed                       integer,
+    place                      integer,
+    created_date               timestamptz DEFAULT NOW(),
+    UNIQUE(payment_tx_hash, payment_output_index),
+    CONSTRAINT FK_tournament_entry_tournament_id FOREIGN KEY(tournament_id) REFERENCES tournament(id),
+    CONSTRAINT FK_tournament_entry_zfc_user_id FOREIGN KEY(zfc_user_id) REFERENCES zfc_user(id),
+    CONSTRAINT FK_tournament_entry_zombie_nft_id FOREIGN KEY(zombie_nft_id) REFERENCES nft(id),
+    CONSTRAINT FK_tournament_entry_hunter_nft_id FOREIGN KEY(hunter_nft_id) REFERENCES nft(id)
+);
+
+-- sources are seed:n, winner:match or loser:match, entries are filled in as the bracket advances
+create table tournament_match (
+    id                         serial PRIMARY KEY,
+    tournament_id              integer not null,
+    match_number               integer not null,
+    bracket                    varchar(16) not null,
+    round                      integer not null,
+    top_source                 varchar(32) not null,
+    bottom_source              varchar(32) not null,
+    top_entry_id               integer,
+    bottom_entry_id            integer,
+    winner_entry_id            integer,
+    loser_entry_id             integer,
+    decided                    boolean not null DEFAULT false,
+    fight_id                   integer,
+    zombie_entry_id            integer,
+    UNIQUE(tournament_id, match_number),
+    UNIQUE(fight_id),
+    CONSTRAINT FK_tournament_match_tournament_id FOREIGN KEY(tournament_id) REFERENCES tournament(id),
+    CONSTRAINT FK_tournament_match_fight_id FOREIGN KEY(fight_id) REFERENCES fight(id)
+);
+
+alter table fight add column tournament_id integer;
+alter table fight add CONSTRAINT FK_fight_tournament_id FOREIGN KEY(tournament_id) REFERENCES tournament(id);
+
+-- tournament fights are all paid from their tournament's address
+drop index if exists fight_payment_address_index_idx;
+create unique index fight_payment_address_index_idx on fight(payment_address_index) where payment_address_index is not null and tournament_id is null;
@@ -0,0 +1,20 @@
+-- a user's own zombie or hunter waiting to be paired with a listed opponent, a match creates the fight for the user
+-- to pay and entries nobody was found for expire
+create table matchmaking_entry (
+    id                         serial PRIMARY KEY,
+    zfc_user_id                integer not null,
+    nft_id                     integer not null,
+    status                     varchar(16) not null DEFAULT 'QUEUED',
+    fight_id                   integer,
+    created_date               timestamptz DEFAULT NOW(),
+    expires_date               timestamptz not null,
+    matched_date               timestamptz,
+    UNIQUE(fight_id),
+    CONSTRAINT FK_matchmaking_entry_zfc_user_id FOREIGN KEY(zfc_user_id) REFERENCES zfc_user(id),
+    CONSTRAINT FK_matchmaking_entry_nft_id FOREIGN KEY(nft_id) REFERENCES nft(id),
+    CONSTRAINT FK_matchmaking_entry_fight_id FOREIGN KEY(fight_id) REFERENCES fight(id)
+);
+
+-- an nft waits in the queue once at a time
+create unique index matchmaking_entry_queued_idx on matchmaking_entry(nft_id) where status = 'QUEUED';
+create index matchmaking_entry_zfc_user_id_idx on matchmaking_entry(zfc_user_id);
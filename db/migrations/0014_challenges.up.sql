@@ -0,0 +1,27 @@
+-- a user's own zombie or hunter challenging another owner's nft, the owner accepts, declines or counters with a
+-- different stake and the two take turns until one accepts, declines or the challenge expires. Accepting creates the
+-- fight for the challenger to pay
+create table challenge (
+    id                         serial PRIMARY KEY,
+    challenger_user_id         integer not null,
+    challenger_nft_id          integer not null,
+    opponent_user_id           integer not null,
+    opponent_nft_id            integer not null,
+    stake_lovelace             bigint not null DEFAULT 0,
+    status                     varchar(16) not null DEFAULT 'PENDING',
+    fight_id                   integer,
+    created_date               timestamptz DEFAULT NOW(),
+    updated_date               timestamptz DEFAULT NOW(),
+    expires_date               timestamptz not null,
+    UNIQUE(fight_id),
+    CONSTRAINT FK_challenge_challenger_user_id FOREIGN KEY(challenger_user_id) REFERENCES zfc_user(id),
+    CONSTRAINT FK_challenge_challenger_nft_id FOREIGN KEY(challenger_nft_id) REFERENCES nft(id),
+    CONSTRAINT FK_challenge_opponent_user_id FOREIGN KEY(opponent_user_id) REFERENCES zfc_user(id),
+    CONSTRAINT FK_challenge_opponent_nft_id FOREIGN KEY(opponent_nft_id) REFERENCES nft(id),
+    CONSTRAINT FK_challenge_fight_id FOREIGN KEY(fight_id) REFERENCES fight(id)
+);
+
+-- the same two nfts have one open challenge at a time
+create unique index challenge_open_idx on challenge(challenger_nft_id, opponent_nft_id) where status in ('PENDING', 'COUNTERED');
+create index challenge_challenger_user_id_idx on challenge(challenger_user_id);
+create index challenge_opponent_user_id_idx on challenge(opponent_user_id);
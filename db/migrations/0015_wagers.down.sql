@@ -0,0 +1,3 @@
+drop table if exists wager_event;
+drop table if exists wager_deposit;
+drop table if exists wager;
@@ -0,0 +1,55 @@
+-- a stake both owners of an accepted challenge put on its fight, each side deposits to its own derived address. The
+-- winner's owner is paid both deposits less the house fee, a wager missing a deposit at expiry is void and refunded
+create table wager (
+    id                         serial PRIMARY KEY,
+    fight_id                   integer not null,
+    challenge_id               integer,
+    stake_lovelace             bigint not null,
+    house_fee_percent          integer not null DEFAULT 0,
+    status                     varchar(20) not null DEFAULT 'AWAITING_DEPOSITS',
+    winner_side                varchar(8),
+    payout_address             varchar(128),
+    payout_lovelace            bigint,
+    house_fee_lovelace         bigint,
+    payout_tx_hash             varchar(64),
+    payout_tx_cbor             text,
+    payout_tx_ttl              integer,
+    created_date               timestamptz DEFAULT NOW(),
+    expires_date               timestamptz not null,
+    settled_date               timestamptz,
+    UNIQUE(fight_id),
+    UNIQUE(challenge_id),
+    CONSTRAINT FK_wager_fight_id FOREIGN KEY(fight_id) REFERENCES fight(id),
+    CONSTRAINT FK_wager_challenge_id FOREIGN KEY(challenge_id) REFERENCES challenge(id)
+);
+
+create table wager_deposit (
+    id                         serial PRIMARY KEY,
+    wager_id                   integer not null,
+    side                       varchar(8) not null,
+    zfc_user_id                integer not null,
+    payment_address            varchar(128) not null,
+    payment_address_index      integer not null,
+    tx_hash                    varchar(64),
+    output_index               integer,
+    sender_address             varchar(128),
+    lovelace                   bigint,
+    paid_date                  timestamptz,
+    UNIQUE(wager_id, side),
+    UNIQUE(tx_hash, output_index),
+    CONSTRAINT FK_wager_deposit_wager_id FOREIGN KEY(wager_id) REFERENCES wager(id),
+    CONSTRAINT FK_wager_deposit_zfc_user_id FOREIGN KEY(zfc_user_id) REFERENCES zfc_user(id)
+);
+
+-- every step a wager takes, for audit
+create table wager_event (
+    id                         serial PRIMARY KEY,
+    wager_id                   integer not null,
+    event                      varchar(32) not null,
+    detail                     text not null,
+    created_date               timestamptz DEFAULT NOW(),
+    CONSTRAINT FK_wager_event_wager_id FOREIGN KEY(wager_id) REFERENCES wager(id)
+);
+
+create index wager_status_idx on wager(status);
+create index wager_event_wager_id_idx on wager_event(wager_id);
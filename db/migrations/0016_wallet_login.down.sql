@@ -0,0 +1,5 @@
+drop table if exists login_nonce;
+-- fails while wallet users without an nftkey.me id are left
+alter table zfc_user drop constraint if exists zfc_user_stake_address_key;
+alter table zfc_user drop column if exists stake_address;
+alter table zfc_user alter column nftkeyme_id set not null;
@@ -0,0 +1,14 @@
+-- users can log in by signing a nonce with their wallet instead of through nftkey.me, a wallet user is known by the
+-- stake address they signed with and has no nftkey.me id
+alter table zfc_user alter column nftkeyme_id drop not null;
+alter table zfc_user add column stake_address varchar(128);
+alter table zfc_user add constraint zfc_user_stake_address_key UNIQUE(stake_address);
+
+-- a nonce handed out for a stake address to sign, used at most once before it expires
+create table login_nonce (
+    nonce                      varchar(64) PRIMARY KEY,
+    stake_address              varchar(128) not null,
+    created_date               timestamptz DEFAULT NOW(),
+    expires_date               timestamptz not null,
+    used_date                  timestamptz
+);
@@ -0,0 +1,6 @@
+drop table if exists zfc_user_account;
+drop index if exists nft_holder_checked_date_idx;
+drop index if exists nft_holder_account_idx;
+alter table nft drop column if exists holder_checked_date;
+alter table nft drop column if exists holder_account;
+alter table nft drop column if exists holder_address;
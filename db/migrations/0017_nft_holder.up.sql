@@ -0,0 +1,22 @@
+-- where each zombie and hunter is held on chain, kept up to date by the indexer. The account is the holder's stake
+-- address, or the address itself when it has no stake part
+alter table nft add column holder_address varchar(128);
+alter table nft add column holder_account varchar(128);
+alter table nft add column holder_checked_date timestamptz;
+create index nft_holder_account_idx on nft(holder_account);
+create index nft_holder_checked_date_idx on nft(holder_checked_date nulls first);
+
+-- the accounts a user holds nfts in, a wallet user's stake address is theirs without being listed here. nftkey.me
+-- users get theirs from the nfts nftkey.me says they own
+create table zfc_user_account (
+    zfc_user_id                integer not null,
+    account                    varchar(128) not null,
+    created_date               timestamptz DEFAULT NOW(),
+    UNIQUE(zfc_user_id, account),
+    CONSTRAINT FK_zfc_user_account_user_id FOREIGN KEY(zfc_user_id) REFERENCES zfc_user(id)
+);
+
+create index zfc_user_account_account_idx on zfc_user_account(account);
+
+-- check every nftkey.me user's assets again so their accounts are filled in
+update zfc_user set last_asset_check_time = null;
@@ -0,0 +1,5 @@
+drop table if exists engine_heartbeat;
+drop index if exists admin_audit_created_date_idx;
+drop table if exists admin_audit;
+alter table fight drop column if exists regenerate_images;
+alter table zfc_user drop column if exists role;
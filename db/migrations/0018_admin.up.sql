@@ -0,0 +1,27 @@
+-- what a user may do on the admin api, null for players. VIEWER < OPERATOR < ADMIN
+alter table zfc_user add column role varchar(16);
+
+-- set by an operator, the minter rebuilds and uploads the images of a staged fight before it's minted
+alter table fight add column regenerate_images boolean not null default false;
+
+-- every change made through the admin api, including the ones that were turned away
+create table admin_audit (
+    id                         serial primary key,
+    zfc_user_id                integer not null,
+    action                     varchar(128) not null,
+    target                     varchar(128) not null DEFAULT '',
+    detail                     text not null DEFAULT '',
+    status_code                integer not null,
+    created_date               timestamptz DEFAULT NOW(),
+    CONSTRAINT FK_admin_audit_user_id FOREIGN KEY(zfc_user_id) REFERENCES zfc_user(id)
+);
+
+create index admin_audit_created_date_idx on admin_audit(created_date);
+
+-- each engine records its passes so the admin api can tell whether it's running
+create table engine_heartbeat (
+    engine                     varchar(32) primary key,
+    last_pass_date             timestamptz not null,
+    last_success_date          timestamptz,
+    consecutive_failures       integer not null DEFAULT 0
+);
@@ -0,0 +1 @@
+alter table zfc_user_account drop column if exists proven;
@@ -0,0 +1,3 @@
+-- an account a user signed for with their wallet is proven and taken off anyone else, the ones learned from what
+-- nftkey.me says a user owns are kept but give way to a proven one
+alter table zfc_user_account add column proven boolean not null DEFAULT false;
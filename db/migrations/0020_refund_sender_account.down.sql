@@ -0,0 +1,2 @@
+drop index if exists refund_sender_account_idx;
+alter table refund drop column if exists sender_account;
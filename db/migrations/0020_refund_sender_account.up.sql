@@ -0,0 +1,6 @@
+-- the stake address a refund's sender pays from, or the address itself without a stake part, so users see refunds
+-- for payments that never matched one of their fights. Empty for refunds recorded before and for a fight's own
+-- payments, those are found through the fight
+alter table refund add column sender_account varchar(256) not null DEFAULT '';
+
+create index refund_sender_account_idx on refund(sender_account);
@@ -0,0 +1,8 @@
+-- when a utxo at the shared payment address was first held for the rest of its payment, it's returned once held
+-- longer than the payment window. Rows stay after the utxo is matched or returned
+create table held_payment (
+    tx_hash             varchar(128) not null,
+    output_index        integer not null,
+    first_seen_date     timestamptz not null DEFAULT NOW(),
+    PRIMARY KEY(tx_hash, output_index)
+);
@@ -24,11 +24,20 @@ type (
 		Wins    int             `db:"wins"`
 		Loses   int             `db:"loses"`
 		Percent sql.NullFloat64 `db:"winpercent"`
+
+		Rating           float64 `db:"rating"`
+		RatingDeviation  float64 `db:"rating_deviation"`
+		RatingVolatility float64 `db:"rating_volatility"`
+
+		// where the nft is held on chain, set by the indexer
+		HolderAddress     sql.NullString `db:"holder_address"`
+		HolderAccount     sql.NullString `db:"holder_account"`
+		HolderCheckedDate sql.NullTime   `db:"holder_checked_date"`
 	}
 )
 
 // GetNftsOwnedByUser Gets owned nfts by user
-func (s Store) GetNftsOwnedByUser(userID int) ([]UserNfts, error) {
+func (s PostgresStore) GetNftsOwnedByUser(userID int) ([]UserNfts, error) {
 	userNfts := make([]UserNfts, 0)
 
 	userNftQuery := `SELECT un.zfc_user_id, un.nft_id, un.amount_ada, n.name, n.nft_type, n.wins, n.loses FROM zfc_user_nft un
@@ -46,7 +55,7 @@ func (s Store) GetNftsOwnedByUser(userID int) ([]UserNfts, error) {
 }
 
 // InsertZcNftOwnedByUser inserts nft owned by user
-func (s Store) InsertZcNftOwnedByUser(userID int, nftID int) error {
+func (s PostgresStore) InsertZcNftOwnedByUser(userID int, nftID int) error {
 	userNftInsert := `INSERT INTO zfc_user_nft (zfc_user_id,nft_id) VALUES($1, $2)`
 
 	rows, err := s.Db.Query(userNftInsert, userID, nftID)
@@ -59,7 +68,7 @@ func (s Store) InsertZcNftOwnedByUser(userID int, nftID int) error {
 }
 
 // RemoveZcNftNotOwnedByUser removes nft not owned by user
-func (s Store) RemoveZcNftNotOwnedByUser(userID int, nftID int) error {
+func (s PostgresStore) RemoveZcNftNotOwnedByUser(userID int, nftID int) error {
 	userNftInsert := `DELETE FROM zfc_user_nft WHERE zfc_user_id != $1 AND nft_id = $2`
 
 	rows, err := s.Db.Query(userNftInsert, userID, nftID)
@@ -72,7 +81,7 @@ func (s Store) RemoveZcNftNotOwnedByUser(userID int, nftID int) error {
 }
 
 // RemoveZcNftOwnedByUser remove nft owned by user
-func (s Store) RemoveZcNftOwnedByUser(userID int, nftID int) error {
+func (s PostgresStore) RemoveZcNftOwnedByUser(userID int, nftID int) error {
 	userNftInsert := `DELETE FROM zfc_user_nft WHERE zfc_user_id = $1 AND nft_id = $2`
 
 	rows, err := s.Db.Query(userNftInsert, userID, nftID)
@@ -85,7 +94,7 @@ func (s Store) RemoveZcNftOwnedByUser(userID int, nftID int) error {
 }
 
 // UpdateNftListPrice update list price
-func (s Store) UpdateNftListPrice(listPrice *int16, userID, nftID int) error {
+func (s PostgresStore) UpdateNftListPrice(listPrice *int16, userID, nftID int) error {
 	userNftInsert := `UPDATE zfc_user_nft SET amount_ada = $1, listed_date = now() WHERE zfc_user_id = $2 AND nft_id = $3`
 
 	rows, err := s.Db.Query(userNftInsert, listPrice, userID, nftID)
@@ -97,7 +106,7 @@ func (s Store) UpdateNftListPrice(listPrice *int16, userID, nftID int) error {
 	return nil
 }
 
-func (s Store) GetNftByName(name string) (*Nft, error) {
+func (s PostgresStore) GetNftByName(name string) (*Nft, error) {
 	nft := Nft{}
 
 	err := s.Db.Get(&nft, "SELECT * FROM nft WHERE name = $1", name)
@@ -112,7 +121,7 @@ func (s Store) GetNftByName(name string) (*Nft, error) {
 }
 
 //GetListedNfts get all nfts listed
-func (s Store) GetListedNfts(nftType string, limit int, random bool) ([]UserNfts, error) {
+func (s PostgresStore) GetListedNfts(nftType string, limit int, random bool) ([]UserNfts, error) {
 	userNfts := make([]UserNfts, 0)
 
 	// get listed nfts
@@ -136,7 +145,7 @@ func (s Store) GetListedNfts(nftType string, limit int, random bool) ([]UserNfts
 	return userNfts, nil
 }
 
-func (s Store) GetListedNftsByName(nftType string, name string) ([]UserNfts, error) {
+func (s PostgresStore) GetListedNftsByName(nftType string, name string) ([]UserNfts, error) {
 	userNfts := make([]UserNfts, 0)
 
 	// get listed nfts
@@ -157,7 +166,7 @@ func (s Store) GetListedNftsByName(nftType string, name string) ([]UserNfts, err
 }
 
 //GetListedNftByName get nft by name
-func (s Store) GetListedNftByName(name string) ([]UserNfts, error) {
+func (s PostgresStore) GetListedNftByName(name string) ([]UserNfts, error) {
 	userNfts := make([]UserNfts, 0)
 
 	userNftQuery := `SELECT un.zfc_user_id, un.nft_id, un.amount_ada, n.name, n.nft_type FROM zfc_user_nft un
@@ -174,7 +183,7 @@ func (s Store) GetListedNftByName(name string) ([]UserNfts, error) {
 	return userNfts, nil
 }
 
-func (s Store) GetNftMostWins(limit int) ([]Nft, error) {
+func (s PostgresStore) GetNftMostWins(limit int) ([]Nft, error) {
 	nfts := make([]Nft, 0)
 
 	err := s.Db.Select(&nfts, "SELECT * FROM nft ORDER BY wins DESC LIMIT $1", limit)
@@ -188,7 +197,7 @@ func (s Store) GetNftMostWins(limit int) ([]Nft, error) {
 	return nfts, nil
 }
 
-func (s Store) GetNftMostLoses(limit int) ([]Nft, error) {
+func (s PostgresStore) GetNftMostLoses(limit int) ([]Nft, error) {
 	nfts := make([]Nft, 0)
 
 	err := s.Db.Select(&nfts, "SELECT * FROM nft ORDER BY loses DESC LIMIT $1", limit)
@@ -202,7 +211,7 @@ func (s Store) GetNftMostLoses(limit int) ([]Nft, error) {
 	return nfts, nil
 }
 
-func (s Store) GetNftHighestPercentMinimum(minimum int, limit int) ([]Nft, error) {
+func (s PostgresStore) GetNftHighestPercentMinimum(minimum int, limit int) ([]Nft, error) {
 	nfts := make([]Nft, 0)
 
 	err := s.Db.Select(&nfts, "SELECT id, name, nft_type, wins, loses, (wins/(wins+loses)::float)*100 as winpercent FROM nft WHERE wins >= $1 ORDER BY winpercent DESC LIMIT $2", minimum, limit)
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+type (
+	// FightPayment one utxo that paid, or helped pay, for a fight
+	FightPayment struct {
+		ID            int           `db:"id"`
+		FightID       int           `db:"fight_id"`
+		TxHash        string        `db:"tx_hash"`
+		OutputIndex   int           `db:"output_index"`
+		SenderAddress string        `db:"sender_address"`
+		Lovelace      int64         `db:"lovelace"`
+		Assets        PaymentAssets `db:"assets"`
+		CreatedDate   time.Time     `db:"created_date"`
+	}
+
+	// PaymentAssets native assets that came along with a payment and have to be sent back
+	PaymentAssets []PaymentAsset
+
+	// PaymentAsset quantity of a policy id + hex asset name unit
+	PaymentAsset struct {
+		Unit     string `json:"unit"`
+		Quantity string `json:"quantity"`
+	}
+)
+
+// Value stores assets as json text
+func (a PaymentAssets) Value() (driver.Value, error) {
+	if a == nil {
+		return "[]", nil
+	}
+	b, err := json.Marshal(a)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+// Scan reads assets from json text
+func (a *PaymentAssets) Scan(src interface{}) error {
+	switch value := src.(type) {
+	case string:
+		return json.Unmarshal([]byte(value), a)
+	case []byte:
+		return json.Unmarshal(value, a)
+	case nil:
+		*a = nil
+		return nil
+	}
+	return fmt.Errorf("Can't scan %T into payment assets", src)
+}
+
+// NextPaymentAddressIndex reserves the next hd wallet index for a fight's payment address
+func (s PostgresStore) NextPaymentAddressIndex() (int, error) {
+	index := 0
+	err := s.Db.Get(&index, "SELECT nextval('fight_payment_address_index_seq')")
+	if err != nil {
+		return 0, err
+	}
+
+	return index, nil
+}
+
+// GetFightPayments gets the utxos that paid for a fight, oldest first
+func (s PostgresStore) GetFightPayments(fightID int) ([]FightPayment, error) {
+	payments := make([]FightPayment, 0)
+
+	err := s.Db.Select(&payments, "SELECT * FROM fight_payment WHERE fight_id = $1 ORDER BY id asc", fightID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return payments, nil
+		}
+		return nil, err
+	}
+
+	return payments, nil
+}
+
+// HoldPayment records that a utxo is held for the rest of its payment to arrive, returns when it was first held
+func (s PostgresStore) HoldPayment(ctx context.Context, txHash string, outputIndex int, seen time.Time) (time.Time, error) {
+	var firstSeen time.Time
+
+	upsertSql := `INSERT INTO held_payment (tx_hash, output_index, first_seen_date) VALUES($1, $2, $3)
+					ON CONFLICT (tx_hash, output_index) DO UPDATE SET first_seen_date = held_payment.first_seen_date
+					RETURNING first_seen_date`
+	err := s.Db.QueryRowContext(ctx, upsertSql, txHash, outputIndex, seen).Scan(&firstSeen)
+	if err != nil {
+		return firstSeen, err
+	}
+
+	return firstSeen, nil
+}
+
+// GetFightsAwaitingPayment gets unpaid fights created within the payment window, tournament fights are paid from
+// their prize pool instead. Fights voided before they were paid are included so payments to them can be returned, and
+// fights with their own address whatever their status so payments that come in after they were paid can be too
+func (s PostgresStore) GetFightsAwaitingPayment(window time.Duration) ([]FightDb, error) {
+	fights := make([]FightDb, 0)
+
+	userNftQuery := `SELECT f.id,
+							f.status,
+							f.created_date,
+							f.minted_date,
+							znft.name as zombie_name,
+							hnft.name as hunter_name,
+							f.payment_address,
+							f.payment_amount_lovelace,
+							f.payment_address_index
+							FROM fight f
+							LEFT JOIN nft znft ON znft.id = f.zombie_nft_id
+							LEFT JOIN nft hnft ON hnft.id = f.hunter_nft_id
+							WHERE ((f.incoming_utxo is null and f.status IN ($1, $3)) or f.payment_address_index is not null)
+								and f.created_date > $2 and f.tournament_id is null
+							ORDER BY f.id asc`
+
+	err := s.Db.Select(&fights, userNftQuery, FightStatusPending, time.Now().Add(-window), FightStatusVoid)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fights, nil
+		}
+		return nil, err
+	}
+
+	return fights, nil
+}
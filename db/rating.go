@@ -0,0 +1,163 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/reliablestaking/zombie-fight-club-server/glicko"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// RatingHistory an nft's rating right after a fight
+	RatingHistory struct {
+		ID               int       `db:"id"`
+		NftID            int       `db:"nft_id"`
+		FightID          int       `db:"fight_id"`
+		Rating           float64   `db:"rating"`
+		RatingDeviation  float64   `db:"rating_deviation"`
+		RatingVolatility float64   `db:"rating_volatility"`
+		CreatedDate      time.Time `db:"created_date"`
+	}
+)
+
+// GlickoRating the nft's rating for glicko
+func (n Nft) GlickoRating() glicko.Rating {
+	return glicko.Rating{Rating: n.Rating, Deviation: n.RatingDeviation, Volatility: n.RatingVolatility}
+}
+
+// rateFightResult both nfts' ratings after the winner beat the loser, each fight is its own rating period
+func rateFightResult(winner glicko.Rating, loser glicko.Rating) (glicko.Rating, glicko.Rating) {
+	return glicko.Rate(winner, glicko.Result{Opponent: loser, Score: 1}), glicko.Rate(loser, glicko.Result{Opponent: winner, Score: 0})
+}
+
+// rateFight updates both nfts' ratings for the fight's result and records them in the history
+func rateFight(ctx context.Context, tx *sql.Tx, fightID int, winningNft string, losingNft string) error {
+	ratings := make([]Nft, 2)
+	for i, name := range []string{winningNft, losingNft} {
+		row := tx.QueryRowContext(ctx, "SELECT id, rating, rating_deviation, rating_volatility FROM nft WHERE name = $1 FOR UPDATE", name)
+		err := row.Scan(&ratings[i].ID, &ratings[i].Rating, &ratings[i].RatingDeviation, &ratings[i].RatingVolatility)
+		if err != nil {
+			return fmt.Errorf("Error getting rating for %s: %w", name, err)
+		}
+	}
+
+	winner, loser := rateFightResult(ratings[0].GlickoRating(), ratings[1].GlickoRating())
+	for i, rating := range []glicko.Rating{winner, loser} {
+		_, err := tx.ExecContext(ctx, "UPDATE nft SET rating = $1, rating_deviation = $2, rating_volatility = $3 WHERE id = $4",
+			rating.Rating, rating.Deviation, rating.Volatility, ratings[i].ID)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, "INSERT INTO nft_rating_history (nft_id, fight_id, rating, rating_deviation, rating_volatility) VALUES ($1, $2, $3, $4, $5)",
+			ratings[i].ID, fightID, rating.Rating, rating.Deviation, rating.Volatility)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetNftHighestRating get nfts with the highest conservative rating, rating less twice the deviation
+func (s PostgresStore) GetNftHighestRating(limit int) ([]Nft, error) {
+	nfts := make([]Nft, 0)
+
+	err := s.Db.Select(&nfts, "SELECT * FROM nft ORDER BY rating - 2 * rating_deviation DESC, id LIMIT $1", limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return nfts, nil
+}
+
+// GetNftRatingHistory an nft's ratings after each of its fights, oldest first
+func (s PostgresStore) GetNftRatingHistory(nftID int) ([]RatingHistory, error) {
+	history := make([]RatingHistory, 0)
+
+	err := s.Db.Select(&history, "SELECT * FROM nft_rating_history WHERE nft_id = $1 ORDER BY id", nftID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return history, nil
+		}
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// BackfillRatings resets every rating and replays every fought fight in the order they were created, returns the
+// number of fights rated
+func (s PostgresStore) BackfillRatings(ctx context.Context) (int, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, "DELETE FROM nft_rating_history")
+	if err != nil {
+		logrus.New().WithError(err).Error("Clearing rating history")
+		return 0, err
+	}
+	_, err = tx.ExecContext(ctx, "UPDATE nft SET rating = $1, rating_deviation = $2, rating_volatility = $3",
+		glicko.DefaultRating, glicko.DefaultDeviation, glicko.DefaultVolatility)
+	if err != nil {
+		logrus.New().WithError(err).Error("Resetting ratings")
+		return 0, err
+	}
+
+	// the winner has the bigger life bar, same as the minted metadata
+	foughtSql := `SELECT f.id,
+					CASE WHEN f.zcLifeBar > f.zhLifeBar THEN znft.name ELSE hnft.name END,
+					CASE WHEN f.zcLifeBar > f.zhLifeBar THEN hnft.name ELSE znft.name END
+					FROM fight f
+					JOIN nft znft ON znft.id = f.zombie_nft_id
+					JOIN nft hnft ON hnft.id = f.hunter_nft_id
+					WHERE f.status IN ($1, $2, $3)
+					ORDER BY f.created_date, f.id`
+	rows, err := tx.QueryContext(ctx, foughtSql, FightStatusStaged, FightStatusMinted, FightStatusConfirmed)
+	if err != nil {
+		logrus.New().WithError(err).Error("Getting fought fights")
+		return 0, err
+	}
+	type fought struct {
+		fightID    int
+		winningNft string
+		losingNft  string
+	}
+	fights := make([]fought, 0)
+	for rows.Next() {
+		f := fought{}
+		if err = rows.Scan(&f.fightID, &f.winningNft, &f.losingNft); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		fights = append(fights, f)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, f := range fights {
+		err = rateFight(ctx, tx, f.fightID, f.winningNft, f.losingNft)
+		if err != nil {
+			logrus.New().WithError(err).Errorf("Rating fight %d", f.fightID)
+			return 0, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return 0, err
+	}
+
+	return len(fights), nil
+}
@@ -0,0 +1,201 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// RefundStatus status of a refund as it moves from owed to confirmed on chain
+	RefundStatus string
+
+	// Refund one utxo at a payment address that didn't pay for a fight and is owed back to the sender, SenderAccount is
+	// the stake address the sender paid from. PaymentAddressIndex is set when the address is derived from the hd wallet
+	Refund struct {
+		ID                  int            `db:"id"`
+		TxHash              string         `db:"tx_hash"`
+		OutputIndex         int            `db:"output_index"`
+		SenderAddress       string         `db:"sender_address"`
+		SenderAccount       string         `db:"sender_account"`
+		Lovelace            int64          `db:"lovelace"`
+		Assets              PaymentAssets  `db:"assets"`
+		PaymentAddress      string         `db:"payment_address"`
+		PaymentAddressIndex sql.NullInt64  `db:"payment_address_index"`
+		Status              RefundStatus   `db:"status"`
+		Attempts            int            `db:"attempts"`
+		RefundTxHash        sql.NullString `db:"refund_tx_hash"`
+		RefundTxTTL         sql.NullInt64  `db:"refund_tx_ttl"`
+		LastError           sql.NullString `db:"last_error"`
+		CreatedDate         time.Time      `db:"created_date"`
+		UpdatedDate         time.Time      `db:"updated_date"`
+		ConfirmedDate       sql.NullTime   `db:"confirmed_date"`
+	}
+)
+
+// PENDING > SUBMITTED > CONFIRMED, back to PENDING when a submission fails or expires, FAILED once out of attempts or
+// when the refund can never be returned
+const (
+	RefundStatusPending   RefundStatus = "PENDING"
+	RefundStatusSubmitted RefundStatus = "SUBMITTED"
+	RefundStatusConfirmed RefundStatus = "CONFIRMED"
+	RefundStatusFailed    RefundStatus = "FAILED"
+
+	// MaxRefundAttempts submissions before a refund is left for someone to return by hand
+	MaxRefundAttempts = 5
+)
+
+// RecordRefund records a utxo as owed back to its sender, doing nothing if it's already recorded
+func (s PostgresStore) RecordRefund(ctx context.Context, refund Refund) error {
+	insertRefundQuery := `INSERT INTO refund (tx_hash, output_index, sender_address, lovelace, assets, payment_address, payment_address_index, status, sender_account)
+							VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (tx_hash, output_index) DO NOTHING`
+
+	_, err := s.Db.ExecContext(ctx, insertRefundQuery, refund.TxHash, refund.OutputIndex, refund.SenderAddress, refund.Lovelace, refund.Assets,
+		refund.PaymentAddress, refund.PaymentAddressIndex, RefundStatusPending, refund.SenderAccount)
+	if err != nil {
+		logrus.New().WithError(err).Error("Inserting refund")
+		return err
+	}
+
+	return nil
+}
+
+// GetRefundForUtxo gets the refund for a utxo, nil if it isn't owed back
+func (s PostgresStore) GetRefundForUtxo(txHash string, outputIndex int) (*Refund, error) {
+	refund := Refund{}
+
+	err := s.Db.Get(&refund, "SELECT * FROM refund WHERE tx_hash = $1 AND output_index = $2", txHash, outputIndex)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &refund, nil
+}
+
+// GetRefundsByStatus gets up to limit refunds in a status, least recently updated first so refunds that keep failing
+// don't hold up the rest
+func (s PostgresStore) GetRefundsByStatus(status RefundStatus, limit int) ([]Refund, error) {
+	refunds := make([]Refund, 0)
+
+	err := s.Db.Select(&refunds, "SELECT * FROM refund WHERE status = $1 ORDER BY updated_date asc, id asc LIMIT $2", status, limit)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return refunds, nil
+		}
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+// GetRefundsForUser gets refunds owed to any address the user has paid for a fight or tournament entry from or to one
+// of their accounts, newest first
+func (s PostgresStore) GetRefundsForUser(userID int) ([]Refund, error) {
+	refunds := make([]Refund, 0)
+
+	refundQuery := `SELECT * FROM refund WHERE sender_address IN (
+							SELECT fp.sender_address FROM fight_payment fp
+							JOIN fight f ON f.id = fp.fight_id
+							WHERE f.minting_user_id = $1 AND fp.sender_address != ''
+							UNION SELECT te.sender_address FROM tournament_entry te
+							WHERE te.zfc_user_id = $1 AND te.sender_address IS NOT NULL)
+						OR sender_account IN (
+							SELECT account FROM zfc_user_account WHERE zfc_user_id = $1
+							UNION SELECT stake_address FROM zfc_user WHERE id = $1 AND stake_address IS NOT NULL)
+						ORDER BY id desc`
+
+	err := s.Db.Select(&refunds, refundQuery, userID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return refunds, nil
+		}
+		return nil, err
+	}
+
+	return refunds, nil
+}
+
+// MarkRefundsSubmitted checkpoints the signed refund tx before it's submitted, counting an attempt for each refund
+func (s PostgresStore) MarkRefundsSubmitted(ctx context.Context, refundIDs []int, txHash string, ttl int) error {
+	updateRefundSql := `UPDATE refund SET status = $1,
+										refund_tx_hash = $2,
+										refund_tx_ttl = $3,
+										attempts = attempts + 1,
+										updated_date = $4
+										WHERE id = ANY($5) AND status = $6`
+
+	result, err := s.Db.ExecContext(ctx, updateRefundSql, RefundStatusSubmitted, txHash, ttl, time.Now(), pq.Array(refundIDs), RefundStatusPending)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating submitted refunds")
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if int(updated) != len(refundIDs) {
+		return fmt.Errorf("Only %d of %d refunds were still pending", updated, len(refundIDs))
+	}
+
+	return nil
+}
+
+// ReleaseRefunds puts refunds in a tx that failed or expired back to pending, or failed once out of attempts
+func (s PostgresStore) ReleaseRefunds(ctx context.Context, txHash string, reason string) error {
+	updateRefundSql := `UPDATE refund SET status = CASE WHEN attempts >= $1 THEN $2 ELSE $3 END,
+										refund_tx_hash = null,
+										refund_tx_ttl = null,
+										last_error = $4,
+										updated_date = $5
+										WHERE refund_tx_hash = $6 AND status = $7`
+
+	_, err := s.Db.ExecContext(ctx, updateRefundSql, MaxRefundAttempts, RefundStatusFailed, RefundStatusPending, reason, time.Now(), txHash, RefundStatusSubmitted)
+	if err != nil {
+		logrus.New().WithError(err).Error("Releasing refunds")
+		return err
+	}
+
+	return nil
+}
+
+// FailRefunds counts an attempt for pending refunds that couldn't be put in a tx, they're failed once out of attempts
+// or straight away when final
+func (s PostgresStore) FailRefunds(ctx context.Context, refundIDs []int, reason string, final bool) error {
+	updateRefundSql := `UPDATE refund SET status = CASE WHEN $1 OR attempts + 1 >= $2 THEN $3 ELSE $4 END,
+										attempts = attempts + 1,
+										last_error = $5,
+										updated_date = $6
+										WHERE id = ANY($7) AND status = $4`
+
+	_, err := s.Db.ExecContext(ctx, updateRefundSql, final, MaxRefundAttempts, RefundStatusFailed, RefundStatusPending, reason, time.Now(), pq.Array(refundIDs))
+	if err != nil {
+		logrus.New().WithError(err).Error("Failing refunds")
+		return err
+	}
+
+	return nil
+}
+
+// ConfirmRefunds marks the refunds in a tx as confirmed on chain
+func (s PostgresStore) ConfirmRefunds(ctx context.Context, txHash string) error {
+	updateRefundSql := `UPDATE refund SET status = $1,
+										confirmed_date = $2,
+										updated_date = $2
+										WHERE refund_tx_hash = $3 AND status = $4`
+
+	_, err := s.Db.ExecContext(ctx, updateRefundSql, RefundStatusConfirmed, time.Now(), txHash, RefundStatusSubmitted)
+	if err != nil {
+		logrus.New().WithError(err).Error("Confirming refunds")
+		return err
+	}
+
+	return nil
+}
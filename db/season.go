@@ -0,0 +1,279 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// Season a stretch of time with its own records and leaderboards, ClosedDate is set once its standings are final
+	Season struct {
+		ID          int          `db:"id"`
+		Name        string       `db:"name"`
+		StartDate   time.Time    `db:"start_date"`
+		EndDate     time.Time    `db:"end_date"`
+		ClosedDate  sql.NullTime `db:"closed_date"`
+		CreatedDate time.Time    `db:"created_date"`
+	}
+
+	// SeasonStanding an nft's final place in a closed season, with its rating when the season closed
+	SeasonStanding struct {
+		SeasonID        int     `db:"season_id"`
+		NftID           int     `db:"nft_id"`
+		NftName         string  `db:"name"`
+		NftType         string  `db:"nft_type"`
+		Rank            int     `db:"rank"`
+		Wins            int     `db:"wins"`
+		Loses           int     `db:"loses"`
+		Rating          float64 `db:"rating"`
+		RatingDeviation float64 `db:"rating_deviation"`
+	}
+)
+
+// Open whether fights created at now are tagged with the season
+func (s Season) Open(now time.Time) bool {
+	return !s.ClosedDate.Valid && !now.Before(s.StartDate) && now.Before(s.EndDate)
+}
+
+// validateSeason checks a new season's dates against the existing seasons, seasons can't overlap
+func validateSeason(name string, start time.Time, end time.Time, seasons []Season) error {
+	if name == "" {
+		return fmt.Errorf("Season needs a name")
+	}
+	if !end.After(start) {
+		return fmt.Errorf("Season %s has to end after it starts", name)
+	}
+	for _, season := range seasons {
+		if season.Name == name {
+			return fmt.Errorf("Season %s already exists", name)
+		}
+		if start.Before(season.EndDate) && season.StartDate.Before(end) {
+			return fmt.Errorf("Season %s overlaps season %s", name, season.Name)
+		}
+	}
+	return nil
+}
+
+// CreateSeason persist a new season, returns its id
+func (s PostgresStore) CreateSeason(ctx context.Context, name string, start time.Time, end time.Time) (int, error) {
+	var id int
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return id, err
+	}
+	defer tx.Rollback()
+
+	// lock out other new seasons while checking for overlaps
+	_, err = tx.ExecContext(ctx, "LOCK TABLE season IN SHARE ROW EXCLUSIVE MODE")
+	if err != nil {
+		return id, err
+	}
+	rows, err := tx.QueryContext(ctx, "SELECT name, start_date, end_date FROM season")
+	if err != nil {
+		return id, err
+	}
+	seasons := make([]Season, 0)
+	for rows.Next() {
+		season := Season{}
+		if err = rows.Scan(&season.Name, &season.StartDate, &season.EndDate); err != nil {
+			rows.Close()
+			return id, err
+		}
+		seasons = append(seasons, season)
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return id, err
+	}
+	if err = validateSeason(name, start, end, seasons); err != nil {
+		return id, err
+	}
+
+	err = tx.QueryRowContext(ctx, "INSERT INTO season (name, start_date, end_date) VALUES ($1, $2, $3) RETURNING id", name, start, end).Scan(&id)
+	if err != nil {
+		return id, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return id, err
+	}
+
+	return id, nil
+}
+
+// GetSeasons get every season, latest first
+func (s PostgresStore) GetSeasons() ([]Season, error) {
+	seasons := make([]Season, 0)
+
+	err := s.Db.Select(&seasons, "SELECT * FROM season ORDER BY start_date DESC")
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return seasons, nil
+		}
+		return nil, err
+	}
+
+	return seasons, nil
+}
+
+// GetSeason get a season by id, nil if there isn't one
+func (s PostgresStore) GetSeason(seasonID int) (*Season, error) {
+	season := Season{}
+
+	err := s.Db.Get(&season, "SELECT * FROM season WHERE id = $1", seasonID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &season, nil
+}
+
+// GetCurrentSeason get the open season fights created at now are tagged with, nil outside of a season
+func (s PostgresStore) GetCurrentSeason(now time.Time) (*Season, error) {
+	season := Season{}
+
+	err := s.Db.Get(&season, "SELECT * FROM season WHERE start_date <= $1 AND end_date > $1 AND closed_date IS NULL", now)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &season, nil
+}
+
+// CloseSeason stops counting fights toward the season and snapshots its final standings, returns the number of nfts
+// ranked
+func (s PostgresStore) CloseSeason(ctx context.Context, seasonID int) (int, error) {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// closing first locks the season's records against fights being staged
+	result, err := tx.ExecContext(ctx, "UPDATE season SET closed_date = $1 WHERE id = $2 AND closed_date IS NULL", time.Now(), seasonID)
+	if err != nil {
+		logrus.New().WithError(err).Error("Closing season")
+		return 0, err
+	}
+	closed, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if closed == 0 {
+		return 0, fmt.Errorf("Season %d doesn't exist or is already closed", seasonID)
+	}
+
+	standingsSql := `INSERT INTO season_standing (season_id, nft_id, rank, wins, loses, rating, rating_deviation)
+						SELECT r.season_id, r.nft_id, row_number() OVER (ORDER BY r.wins DESC, r.loses, r.nft_id), r.wins, r.loses, n.rating, n.rating_deviation
+						FROM season_record r
+						JOIN nft n ON n.id = r.nft_id
+						WHERE r.season_id = $1`
+	result, err = tx.ExecContext(ctx, standingsSql, seasonID)
+	if err != nil {
+		logrus.New().WithError(err).Error("Snapshotting standings")
+		return 0, err
+	}
+	ranked, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return 0, err
+	}
+
+	return int(ranked), nil
+}
+
+// GetSeasonStandings a closed season's final standings, best first
+func (s PostgresStore) GetSeasonStandings(seasonID int) ([]SeasonStanding, error) {
+	standings := make([]SeasonStanding, 0)
+
+	standingsSql := `SELECT st.*, n.name, n.nft_type FROM season_standing st
+						JOIN nft n ON n.id = st.nft_id
+						WHERE st.season_id = $1
+						ORDER BY st.rank`
+	err := s.Db.Select(&standings, standingsSql, seasonID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return standings, nil
+		}
+		return nil, err
+	}
+
+	return standings, nil
+}
+
+// GetSeasonMostWins get nfts with the most wins in a season
+func (s PostgresStore) GetSeasonMostWins(seasonID int, limit int) ([]Nft, error) {
+	return s.selectSeasonNfts("ORDER BY r.wins DESC, n.id LIMIT $2", seasonID, limit)
+}
+
+// GetSeasonMostLoses get nfts with the most loses in a season
+func (s PostgresStore) GetSeasonMostLoses(seasonID int, limit int) ([]Nft, error) {
+	return s.selectSeasonNfts("ORDER BY r.loses DESC, n.id LIMIT $2", seasonID, limit)
+}
+
+// GetSeasonHighestPercentMinimum get nfts with the best win percent in a season and at least minimum wins in it
+func (s PostgresStore) GetSeasonHighestPercentMinimum(seasonID int, minimum int, limit int) ([]Nft, error) {
+	return s.selectSeasonNfts("AND r.wins >= $3 ORDER BY winpercent DESC, n.id LIMIT $2", seasonID, limit, minimum)
+}
+
+// selectSeasonNfts nfts with their records in a season in place of their all time records, conditions follow the
+// season filter
+func (s PostgresStore) selectSeasonNfts(conditions string, seasonID int, args ...interface{}) ([]Nft, error) {
+	nfts := make([]Nft, 0)
+
+	seasonNftSql := `SELECT n.id, n.name, n.nft_type, r.wins, r.loses, (r.wins/(r.wins+r.loses)::float)*100 as winpercent
+						FROM season_record r
+						JOIN nft n ON n.id = r.nft_id
+						WHERE r.season_id = $1 ` + conditions
+	err := s.Db.Select(&nfts, seasonNftSql, append([]interface{}{seasonID}, args...)...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nfts, nil
+		}
+		return nil, err
+	}
+
+	return nfts, nil
+}
+
+// recordSeasonResult counts the fight toward its season's records, fights outside of a season or in a closed one
+// aren't counted
+func recordSeasonResult(ctx context.Context, tx *sql.Tx, fightID int, winningNft string, losingNft string) error {
+	recordSql := `INSERT INTO season_record (season_id, nft_id, wins, loses)
+					SELECT f.season_id, n.id, $3, $4
+					FROM fight f
+					JOIN season s ON s.id = f.season_id AND s.closed_date IS NULL
+					JOIN nft n ON n.name = $2
+					WHERE f.id = $1
+					FOR SHARE OF s
+					ON CONFLICT (season_id, nft_id) DO UPDATE SET wins = season_record.wins + $3, loses = season_record.loses + $4`
+
+	_, err := tx.ExecContext(ctx, recordSql, fightID, winningNft, 1, 0)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, recordSql, fightID, losingNft, 0, 1)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
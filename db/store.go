@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"time"
 
@@ -8,8 +9,177 @@ import (
 )
 
 type (
-	// Store struct to store Db
-	Store struct {
+	// Store interface over everything the server and minting engine persist
+	Store interface {
+		// users
+		GetUserByNftkeyID(nftKeyUserID string) (*User, error)
+		GetUserByID(userID int) (*User, error)
+		InsertUser(nftkeyID, accessToken, refreshToken string) error
+		UpdatedUser(nftkeyID, accessToken, refreshToken string) error
+		SetLastAssetCheckTime(userID int, now time.Time) error
+
+		// wallet login
+		GetUserByStakeAddress(stakeAddress string) (*User, error)
+		InsertWalletUser(stakeAddress string) error
+		CreateLoginNonce(ctx context.Context, nonce LoginNonce) error
+		UseLoginNonce(ctx context.Context, nonce string, stakeAddress string, now time.Time) (bool, error)
+
+		// nft ownership and listings
+		GetNftsOwnedByUser(userID int) ([]UserNfts, error)
+		InsertZcNftOwnedByUser(userID int, nftID int) error
+		RemoveZcNftNotOwnedByUser(userID int, nftID int) error
+		RemoveZcNftOwnedByUser(userID int, nftID int) error
+		UpdateNftListPrice(listPrice *int16, userID, nftID int) error
+		GetNftByName(name string) (*Nft, error)
+		GetListedNfts(nftType string, limit int, random bool) ([]UserNfts, error)
+		GetListedNftsByName(nftType string, name string) ([]UserNfts, error)
+		GetListedNftByName(name string) ([]UserNfts, error)
+
+		// leaderboards
+		GetNftMostWins(limit int) ([]Nft, error)
+		GetNftMostLoses(limit int) ([]Nft, error)
+		GetNftHighestPercentMinimum(minimum int, limit int) ([]Nft, error)
+		GetNftHighestRating(limit int) ([]Nft, error)
+
+		// seasons
+		CreateSeason(ctx context.Context, name string, start time.Time, end time.Time) (int, error)
+		GetSeasons() ([]Season, error)
+		GetSeason(seasonID int) (*Season, error)
+		GetCurrentSeason(now time.Time) (*Season, error)
+		CloseSeason(ctx context.Context, seasonID int) (int, error)
+		GetSeasonStandings(seasonID int) ([]SeasonStanding, error)
+		GetSeasonMostWins(seasonID int, limit int) ([]Nft, error)
+		GetSeasonMostLoses(seasonID int, limit int) ([]Nft, error)
+		GetSeasonHighestPercentMinimum(seasonID int, minimum int, limit int) ([]Nft, error)
+
+		// tournaments
+		CreateTournament(ctx context.Context, t Tournament) (int, error)
+		GetTournaments() ([]Tournament, error)
+		GetActiveTournaments() ([]Tournament, error)
+		GetTournament(tournamentID int) (*Tournament, error)
+		CreateTournamentEntry(ctx context.Context, entry TournamentEntry) (int, error)
+		GetTournamentEntries(tournamentID int) ([]TournamentEntry, error)
+		GetTournamentEntryForUtxo(txHash string, outputIndex int) (*TournamentEntry, error)
+		PayTournamentEntry(ctx context.Context, entryID int, payment FightPayment) error
+		StartTournament(ctx context.Context, tournamentID int, seeds map[int]int, matches []TournamentMatch) error
+		CancelTournament(ctx context.Context, tournamentID int) error
+		GetTournamentMatches(tournamentID int) ([]TournamentMatch, error)
+		UpdateTournamentMatches(ctx context.Context, matches []TournamentMatch) error
+		CreateTournamentFight(ctx context.Context, matchID int, zombieEntryID int, fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User) (int, error)
+		FinishTournament(ctx context.Context, tournamentID int, places map[int]int) error
+		SetTournamentPayoutTx(ctx context.Context, tournamentID int, txHash string, cborHex string, ttl int) error
+		ClearTournamentPayoutTx(ctx context.Context, tournamentID int, txHash string) error
+		MarkTournamentPaid(ctx context.Context, tournamentID int) error
+
+		// matchmaking
+		QueueMatchmakingEntry(ctx context.Context, entry MatchmakingEntry) (int, error)
+		GetMatchmakingEntriesForUser(userID int, limit int) ([]MatchmakingEntry, error)
+		GetMatchmakingOpponents(nftType string, userID int) ([]MatchmakingOpponent, error)
+		MatchMatchmakingEntry(ctx context.Context, entryID int, fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User) (int, error)
+		CancelMatchmakingEntry(ctx context.Context, userID int, entryID int) error
+		ExpireMatchmakingEntries(ctx context.Context, now time.Time) (int, error)
+
+		// challenges
+		CreateChallenge(ctx context.Context, challenge Challenge) (int, error)
+		GetChallenge(challengeID int) (*Challenge, error)
+		GetChallengesForUser(userID int, limit int) ([]Challenge, error)
+		CounterChallenge(ctx context.Context, challengeID int, from ChallengeStatus, stakeLovelace int64, expiresDate time.Time) error
+		CloseChallenge(ctx context.Context, challengeID int, from ChallengeStatus, to ChallengeStatus) error
+		AcceptChallenge(ctx context.Context, challengeID int, from ChallengeStatus, fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User, wager *Wager) (int, error)
+		ExpireChallenges(ctx context.Context, now time.Time) (int, error)
+
+		// wagers
+		GetWagerForFight(fightID int) (*Wager, error)
+		GetWagersToProcess(since time.Time) ([]Wager, error)
+		GetWagerDeposits(wagerID int) ([]WagerDeposit, error)
+		GetWagerDepositForUtxo(txHash string, outputIndex int) (*WagerDeposit, error)
+		GetWagerEvents(wagerID int) ([]WagerEvent, error)
+		PayWagerDeposit(ctx context.Context, depositID int, payment FightPayment) error
+		RecordWagerRefund(ctx context.Context, wagerID int, refund Refund) error
+		VoidWager(ctx context.Context, wagerID int, from WagerStatus, reason string) error
+		SetWagerPayoutTx(ctx context.Context, wagerID int, payout WagerPayout, txHash string, cborHex string, ttl int) error
+		ClearWagerPayoutTx(ctx context.Context, wagerID int, txHash string) error
+		MarkWagerPaid(ctx context.Context, wagerID int) error
+
+		// nft holders
+		GetNftsToIndex(limit int) ([]Nft, error)
+		SetNftHolder(ctx context.Context, nftID int, holder *NftHolder, checked time.Time) (sql.NullString, error)
+		GetUserAccounts(userID int) ([]string, error)
+		AddUserAccount(userID int, account string) error
+		SetNftkeymeAccounts(userID int, accounts []string) error
+		GetNftsHeldByUser(userID int) ([]Nft, error)
+
+		// nft transfers
+		RemoveNftListingsNotHeldBy(ctx context.Context, nftID int, account sql.NullString) (int64, error)
+		GetOpenFightsForNft(nftID int) ([]FightDb, error)
+		VoidFight(ctx context.Context, fightID int, actor FightActor, reason string) error
+		SetFightSendAddresses(ctx context.Context, fightID int, zombieSendAddress string, hunterSendAddress string) error
+
+		// fight limits
+		GetNftLastFightDate(nftID int) (*time.Time, error)
+		CountNftFights(nftID int, from time.Time, to time.Time) (int, error)
+		GetUserFightDates(userID int, since time.Time) ([]time.Time, error)
+
+		// ratings
+		GetNftRatingHistory(nftID int) ([]RatingHistory, error)
+		BackfillRatings(ctx context.Context) (int, error)
+
+		// fight lifecycle
+		CreateFight(fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User) (int, error)
+		GetFightsForUser(user User) ([]FightDb, error)
+		GetFightsForUserAndId(user User, fightId int) ([]FightDb, error)
+		GetFightForUtxo(utxo string, index int) ([]FightDb, error)
+		GetQueuedFight() ([]FightDb, error)
+		GetStagedFights() ([]FightDb, error)
+		GetMintedFights() ([]FightDb, error)
+		GetUnpaidFightForAmount(lovelace int64, window time.Duration) (*FightDb, error)
+		GetNextAvailableAlien() (*Alien, error)
+		GetAlienByFightId(fightId int) (*Alien, error)
+		MoveFightFromPendingToQueued(ctx context.Context, fightID int, alienID int, payments []FightPayment) error
+		MoveFightFromQueuedToStaged(ctx context.Context, alienID int, alienIpfs string, fightID int, fightIpfs, background, zombieRecord, hunterRecord string, zcLifeBar, zhLifeBar int, zombieKo, hunterKo, zombieBeahup, hunterBeatup bool, combatLog string, winningNft string, losingNft string) error
+		MoveFightFromStagedToMinted(ctx context.Context, fightID int, txHash string) error
+		MoveFightFromMintedToConfirmed(ctx context.Context, fightID int) error
+		UpdateTweetID(ctx context.Context, fightID int, tweetID string) error
+		SetAlienIpfs(ctx context.Context, alienID int, alienIpfs string) error
+		MarkTweetAttempted(ctx context.Context, fightID int) error
+		SetFightSeed(ctx context.Context, fightID int, paymentTxHash string, seed string) error
+		GetFightCombatLog(ctx context.Context, fightID int) (string, error)
+		SetSignedMintTx(ctx context.Context, fightID int, txHash string, cborHex string, ttl int) error
+		ClearSignedMintTx(ctx context.Context, fightID int, txHash string) error
+		GetFightEvents(fightID int) ([]FightEvent, error)
+		GetFightPayments(fightID int) ([]FightPayment, error)
+		GetFightsAwaitingPayment(window time.Duration) ([]FightDb, error)
+		NextPaymentAddressIndex() (int, error)
+		HoldPayment(ctx context.Context, txHash string, outputIndex int, seen time.Time) (time.Time, error)
+
+		// refunds
+		RecordRefund(ctx context.Context, refund Refund) error
+		GetRefundForUtxo(txHash string, outputIndex int) (*Refund, error)
+		GetRefundsByStatus(status RefundStatus, limit int) ([]Refund, error)
+		GetRefundsForUser(userID int) ([]Refund, error)
+		MarkRefundsSubmitted(ctx context.Context, refundIDs []int, txHash string, ttl int) error
+		ReleaseRefunds(ctx context.Context, txHash string, reason string) error
+		FailRefunds(ctx context.Context, refundIDs []int, reason string, final bool) error
+		ConfirmRefunds(ctx context.Context, txHash string) error
+
+		// admin
+		SetUserRole(ctx context.Context, userID int, role UserRole) error
+		InsertAdminAudit(ctx context.Context, audit AdminAudit) (int, error)
+		FinishAdminAudit(ctx context.Context, auditID int, statusCode int, detail string) error
+		GetAdminAudits(limit int) ([]AdminAudit, error)
+		GetFight(fightID int) (*FightDb, error)
+		GetFightsByStatus(status FightStatus, limit int) ([]FightDb, error)
+		CountFightsByStatus() (map[FightStatus]int, error)
+		CountRefundsByStatus() (map[RefundStatus]int, error)
+		ForceRefund(ctx context.Context, refund Refund) (*Refund, error)
+		RequestImageRegeneration(ctx context.Context, fightID int) (bool, error)
+		SetFightImages(ctx context.Context, fightID int, alienID int, alienIpfs string, fightIpfs string, background string) error
+		RecordEnginePass(ctx context.Context, engine string, passed time.Time, failed bool) error
+		GetEngineHeartbeats() ([]EngineHeartbeat, error)
+	}
+
+	// PostgresStore struct to store Db
+	PostgresStore struct {
 		Db *sqlx.DB
 	}
 
@@ -20,13 +190,31 @@ type (
 		NftkeymeAccessToken  string       `db:"nftkeyme_access_token"`
 		NftkeymeRefreshToken string       `db:"nftkeyme_refresh_token"`
 		LastAssetCheckTime   sql.NullTime `db:"last_asset_check_time"`
+		// set for users who log in with their wallet instead of nftkey.me
+		StakeAddress sql.NullString `db:"stake_address"`
+		// empty for players, see UserRole
+		Role UserRole `db:"role"`
 	}
 )
 
+// wallet users have no nftkey.me id or tokens
+const selectUserSql = `SELECT id, COALESCE(nftkeyme_id, '') as nftkeyme_id, COALESCE(nftkeyme_access_token, '') as nftkeyme_access_token,
+						COALESCE(nftkeyme_refresh_token, '') as nftkeyme_refresh_token, last_asset_check_time, stake_address,
+						COALESCE(role, '') as role
+					FROM zfc_user`
+
+// ServiceAccount whether the user is a service account, which logs in with a bearer token and has no tokens or wallet
+// of its own
+func (u User) ServiceAccount() bool {
+	return u.NftkeymeAccessToken == "" && !u.StakeAddress.Valid
+}
+
+var _ Store = PostgresStore{}
+
 // GetUserByNftkeyID Gets a user using their nftkey id
-func (s Store) GetUserByNftkeyID(nftKeyUserID string) (*User, error) {
+func (s PostgresStore) GetUserByNftkeyID(nftKeyUserID string) (*User, error) {
 	discordUser := User{}
-	err := s.Db.Get(&discordUser, "SELECT * FROM zfc_user where nftkeyme_id = $1", nftKeyUserID)
+	err := s.Db.Get(&discordUser, selectUserSql+" where nftkeyme_id = $1", nftKeyUserID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -38,9 +226,9 @@ func (s Store) GetUserByNftkeyID(nftKeyUserID string) (*User, error) {
 }
 
 // GetUserByNftkeyID Gets a user using their nftkey id
-func (s Store) GetUserByID(userID int) (*User, error) {
+func (s PostgresStore) GetUserByID(userID int) (*User, error) {
 	discordUser := User{}
-	err := s.Db.Get(&discordUser, "SELECT * FROM zfc_user where id = $1", userID)
+	err := s.Db.Get(&discordUser, selectUserSql+" where id = $1", userID)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, nil
@@ -52,7 +240,7 @@ func (s Store) GetUserByID(userID int) (*User, error) {
 }
 
 // InsertUser inserts a new user into the db
-func (s Store) InsertUser(nftkeyID, accessToken, refreshToken string) error {
+func (s PostgresStore) InsertUser(nftkeyID, accessToken, refreshToken string) error {
 	insertUserQuery := `INSERT INTO zfc_user (nftkeyme_id,nftkeyme_access_token,nftkeyme_refresh_token) VALUES($1, $2, $3)`
 
 	rows, err := s.Db.Query(insertUserQuery, nftkeyID, accessToken, refreshToken)
@@ -65,7 +253,7 @@ func (s Store) InsertUser(nftkeyID, accessToken, refreshToken string) error {
 }
 
 // UpdatedUser updates a new user in the db
-func (s Store) UpdatedUser(nftkeyID, accessToken, refreshToken string) error {
+func (s PostgresStore) UpdatedUser(nftkeyID, accessToken, refreshToken string) error {
 	insertUserQuery := `UPDATE zfc_user SET nftkeyme_access_token = $1, nftkeyme_refresh_token = $2 WHERE nftkeyme_id = $3`
 
 	rows, err := s.Db.Query(insertUserQuery, accessToken, refreshToken, nftkeyID)
@@ -78,10 +266,10 @@ func (s Store) UpdatedUser(nftkeyID, accessToken, refreshToken string) error {
 }
 
 // SetLastAssetCheckTime updates last asset check time
-func (s Store) SetLastAssetCheckTime(nftkeyID string, now time.Time) error {
-	insertUserQuery := `UPDATE zfc_user SET last_asset_check_time = $1 WHERE nftkeyme_id = $2`
+func (s PostgresStore) SetLastAssetCheckTime(userID int, now time.Time) error {
+	insertUserQuery := `UPDATE zfc_user SET last_asset_check_time = $1 WHERE id = $2`
 
-	rows, err := s.Db.Query(insertUserQuery, now, nftkeyID)
+	rows, err := s.Db.Query(insertUserQuery, now, userID)
 	if err != nil {
 		return err
 	}
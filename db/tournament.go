@@ -0,0 +1,555 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/reliablestaking/zombie-fight-club-server/tournament"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// TournamentStatus status of a tournament from registration to paying out its prizes
+	TournamentStatus string
+
+	// Tournament a bracket of entries fighting for the prize pool of their entry fees, paid to PaymentAddress
+	Tournament struct {
+		ID                  int              `db:"id"`
+		Name                string           `db:"name"`
+		Format              string           `db:"format"`
+		BracketSize         int              `db:"bracket_size"`
+		EntryFeeLovelace    int64            `db:"entry_fee_lovelace"`
+		FightCostLovelace   int64            `db:"fight_cost_lovelace"`
+		PrizeSplit          string           `db:"prize_split"`
+		RegistrationStart   time.Time        `db:"registration_start"`
+		RegistrationEnd     time.Time        `db:"registration_end"`
+		Status              TournamentStatus `db:"status"`
+		PaymentAddress      string           `db:"payment_address"`
+		PaymentAddressIndex int              `db:"payment_address_index"`
+		PayoutTxHash        sql.NullString   `db:"payout_tx_hash"`
+		PayoutTxCbor        sql.NullString   `db:"payout_tx_cbor"`
+		PayoutTxTTL         sql.NullInt64    `db:"payout_tx_ttl"`
+		CreatedDate         time.Time        `db:"created_date"`
+		StartedDate         sql.NullTime     `db:"started_date"`
+		FinishedDate        sql.NullTime     `db:"finished_date"`
+	}
+
+	// TournamentEntry one user's zombie and hunter entered in a tournament, paid once its fee is found on chain
+	TournamentEntry struct {
+		ID                    int            `db:"id"`
+		TournamentID          int            `db:"tournament_id"`
+		UserID                int            `db:"zfc_user_id"`
+		ZombieNftID           int            `db:"zombie_nft_id"`
+		HunterNftID           int            `db:"hunter_nft_id"`
+		ZombieName            string         `db:"zombie_name"`
+		HunterName            string         `db:"hunter_name"`
+		PaymentAmountLovelace int64          `db:"payment_amount_lovelace"`
+		PaymentTxHash         sql.NullString `db:"payment_tx_hash"`
+		PaymentOutputIndex    sql.NullInt64  `db:"payment_output_index"`
+		PaymentLovelace       sql.NullInt64  `db:"payment_lovelace"`
+		SenderAddress         sql.NullString `db:"sender_address"`
+		PaidDate              sql.NullTime   `db:"paid_date"`
+		Seed                  sql.NullInt64  `db:"seed"`
+		Place                 sql.NullInt64  `db:"place"`
+		CreatedDate           time.Time      `db:"created_date"`
+	}
+
+	// TournamentMatch one match of a tournament's bracket with the fight that decides it, the fight's status, life
+	// bars and mint tx are joined in
+	TournamentMatch struct {
+		ID            int            `db:"id"`
+		TournamentID  int            `db:"tournament_id"`
+		MatchNumber   int            `db:"match_number"`
+		Bracket       string         `db:"bracket"`
+		Round         int            `db:"round"`
+		TopSource     string         `db:"top_source"`
+		BottomSource  string         `db:"bottom_source"`
+		TopEntryID    sql.NullInt64  `db:"top_entry_id"`
+		BottomEntryID sql.NullInt64  `db:"bottom_entry_id"`
+		WinnerEntryID sql.NullInt64  `db:"winner_entry_id"`
+		LoserEntryID  sql.NullInt64  `db:"loser_entry_id"`
+		Decided       bool           `db:"decided"`
+		FightID       sql.NullInt64  `db:"fight_id"`
+		ZombieEntryID sql.NullInt64  `db:"zombie_entry_id"`
+		FightStatus   sql.NullString `db:"fight_status"`
+		ZombieLifeBar sql.NullInt64  `db:"zclifebar"`
+		HunterLifeBar sql.NullInt64  `db:"zhlifebar"`
+		FightTxID     sql.NullString `db:"fight_tx_id"`
+	}
+)
+
+// REGISTRATION > RUNNING > FINISHED > PAID, or REGISTRATION > CANCELLED when too few entries paid
+const (
+	TournamentStatusRegistration TournamentStatus = "REGISTRATION"
+	TournamentStatusRunning      TournamentStatus = "RUNNING"
+	TournamentStatusFinished     TournamentStatus = "FINISHED"
+	TournamentStatusPaid         TournamentStatus = "PAID"
+	TournamentStatusCancelled    TournamentStatus = "CANCELLED"
+)
+
+// Paid whether the entry's fee has been found on chain
+func (e TournamentEntry) Paid() bool {
+	return e.PaymentTxHash.Valid
+}
+
+// validateTournament checks the bracket can be laid out and the entry fees pay for every fight in it
+func validateTournament(t Tournament) error {
+	if t.Name == "" {
+		return fmt.Errorf("Tournament needs a name")
+	}
+	if !t.RegistrationEnd.After(t.RegistrationStart) {
+		return fmt.Errorf("Registration for %s has to end after it starts", t.Name)
+	}
+
+	format, err := tournament.ParseFormat(t.Format)
+	if err != nil {
+		return err
+	}
+	matches, err := tournament.Generate(format, t.BracketSize)
+	if err != nil {
+		return err
+	}
+
+	split, err := tournament.ParseSplit(t.PrizeSplit)
+	if err != nil {
+		return err
+	}
+	places := 2
+	if format == tournament.FormatRoundRobin {
+		places = t.BracketSize
+	}
+	if len(split) > places {
+		return fmt.Errorf("A %s only places %d entries but the split pays %d", format, places, len(split))
+	}
+
+	if t.FightCostLovelace <= 0 {
+		return fmt.Errorf("Fights have to cost something")
+	}
+	if t.EntryFeeLovelace*int64(t.BracketSize) < t.FightCostLovelace*int64(len(matches)) {
+		return fmt.Errorf("%d entry fees of %d lovelace don't pay for %d fights of %d lovelace", t.BracketSize, t.EntryFeeLovelace, len(matches), t.FightCostLovelace)
+	}
+
+	return nil
+}
+
+// CreateTournament persist a new tournament open for registration, returns its id
+func (s PostgresStore) CreateTournament(ctx context.Context, t Tournament) (int, error) {
+	var id int
+
+	if err := validateTournament(t); err != nil {
+		return id, err
+	}
+
+	insertTournamentSql := `INSERT INTO tournament (name, format, bracket_size, entry_fee_lovelace, fight_cost_lovelace, prize_split,
+								registration_start, registration_end, status, payment_address, payment_address_index)
+								VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id`
+
+	err := s.Db.QueryRowContext(ctx, insertTournamentSql, t.Name, t.Format, t.BracketSize, t.EntryFeeLovelace, t.FightCostLovelace, t.PrizeSplit,
+		t.RegistrationStart, t.RegistrationEnd, TournamentStatusRegistration, t.PaymentAddress, t.PaymentAddressIndex).Scan(&id)
+	if err != nil {
+		logrus.New().WithError(err).Error("Inserting tournament")
+		return id, err
+	}
+
+	return id, nil
+}
+
+// GetTournaments get every tournament, latest first
+func (s PostgresStore) GetTournaments() ([]Tournament, error) {
+	tournaments := make([]Tournament, 0)
+
+	err := s.Db.Select(&tournaments, "SELECT * FROM tournament ORDER BY registration_start DESC, id DESC")
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return tournaments, nil
+		}
+		return nil, err
+	}
+
+	return tournaments, nil
+}
+
+// GetActiveTournaments get tournaments the minting engine still has work to do for, oldest first
+func (s PostgresStore) GetActiveTournaments() ([]Tournament, error) {
+	tournaments := make([]Tournament, 0)
+
+	statuses := []string{string(TournamentStatusRegistration), string(TournamentStatusRunning), string(TournamentStatusFinished)}
+	err := s.Db.Select(&tournaments, "SELECT * FROM tournament WHERE status = ANY($1) ORDER BY id", pq.Array(statuses))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return tournaments, nil
+		}
+		return nil, err
+	}
+
+	return tournaments, nil
+}
+
+// GetTournament get a tournament by id, nil if there isn't one
+func (s PostgresStore) GetTournament(tournamentID int) (*Tournament, error) {
+	t := Tournament{}
+
+	err := s.Db.Get(&t, "SELECT * FROM tournament WHERE id = $1", tournamentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &t, nil
+}
+
+// CreateTournamentEntry persist a new unpaid entry, returns its id
+func (s PostgresStore) CreateTournamentEntry(ctx context.Context, entry TournamentEntry) (int, error) {
+	var id int
+
+	insertEntrySql := `INSERT INTO tournament_entry (tournament_id, zfc_user_id, zombie_nft_id, hunter_nft_id, payment_amount_lovelace, created_date)
+						VALUES ($1, $2, $3, $4, $5, $6) RETURNING id`
+
+	err := s.Db.QueryRowContext(ctx, insertEntrySql, entry.TournamentID, entry.UserID, entry.ZombieNftID, entry.HunterNftID, entry.PaymentAmountLovelace, time.Now()).Scan(&id)
+	if err != nil {
+		logrus.New().WithError(err).Error("Inserting tournament entry")
+		return id, err
+	}
+
+	return id, nil
+}
+
+// GetTournamentEntries get a tournament's entries, paid or not, in the order they were made
+func (s PostgresStore) GetTournamentEntries(tournamentID int) ([]TournamentEntry, error) {
+	return s.selectTournamentEntries("WHERE e.tournament_id = $1 ORDER BY e.id", tournamentID)
+}
+
+// GetTournamentEntryForUtxo get the entry a utxo paid for, nil if it didn't pay for one
+func (s PostgresStore) GetTournamentEntryForUtxo(txHash string, outputIndex int) (*TournamentEntry, error) {
+	entries, err := s.selectTournamentEntries("WHERE e.payment_tx_hash = $1 AND e.payment_output_index = $2", txHash, outputIndex)
+	if err != nil {
+		return nil, err
+	}
+	if len(entries) == 0 {
+		return nil, nil
+	}
+
+	return &entries[0], nil
+}
+
+func (s PostgresStore) selectTournamentEntries(conditions string, args ...interface{}) ([]TournamentEntry, error) {
+	entries := make([]TournamentEntry, 0)
+
+	entrySql := `SELECT e.*, znft.name as zombie_name, hnft.name as hunter_name
+					FROM tournament_entry e
+					JOIN nft znft ON znft.id = e.zombie_nft_id
+					JOIN nft hnft ON hnft.id = e.hunter_nft_id ` + conditions
+	err := s.Db.Select(&entries, entrySql, args...)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return entries, nil
+		}
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// PayTournamentEntry records the utxo that paid an entry's fee
+func (s PostgresStore) PayTournamentEntry(ctx context.Context, entryID int, payment FightPayment) error {
+	updateEntrySql := `UPDATE tournament_entry SET payment_tx_hash = $1,
+										payment_output_index = $2,
+										payment_lovelace = $3,
+										sender_address = $4,
+										paid_date = $5
+										WHERE id = $6 AND payment_tx_hash IS NULL`
+
+	result, err := s.Db.ExecContext(ctx, updateEntrySql, payment.TxHash, payment.OutputIndex, payment.Lovelace, payment.SenderAddress, time.Now(), entryID)
+	if err != nil {
+		logrus.New().WithError(err).Error("Paying tournament entry")
+		return err
+	}
+
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return fmt.Errorf("Tournament entry %d is already paid", entryID)
+	}
+
+	return nil
+}
+
+// StartTournament closes registration, seeding the paid entries and laying out the bracket. Seeds are by entry id.
+func (s PostgresStore) StartTournament(ctx context.Context, tournamentID int, seeds map[int]int, matches []TournamentMatch) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	// updating the status first keeps a tournament from being started twice
+	err = transitionTournament(ctx, tx, tournamentID, TournamentStatusRegistration, TournamentStatusRunning, "started_date")
+	if err != nil {
+		return err
+	}
+
+	for entryID, seed := range seeds {
+		_, err = tx.ExecContext(ctx, "UPDATE tournament_entry SET seed = $1 WHERE id = $2 AND tournament_id = $3", seed, entryID, tournamentID)
+		if err != nil {
+			logrus.New().WithError(err).Error("Seeding tournament entry")
+			return err
+		}
+	}
+
+	insertMatchSql := `INSERT INTO tournament_match (tournament_id, match_number, bracket, round, top_source, bottom_source,
+							top_entry_id, bottom_entry_id, winner_entry_id, loser_entry_id, decided)
+							VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)`
+	for _, match := range matches {
+		_, err = tx.ExecContext(ctx, insertMatchSql, tournamentID, match.MatchNumber, match.Bracket, match.Round, match.TopSource, match.BottomSource,
+			match.TopEntryID, match.BottomEntryID, match.WinnerEntryID, match.LoserEntryID, match.Decided)
+		if err != nil {
+			logrus.New().WithError(err).Error("Inserting tournament match")
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// CancelTournament calls off a tournament that never started
+func (s PostgresStore) CancelTournament(ctx context.Context, tournamentID int) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	err = transitionTournament(ctx, tx, tournamentID, TournamentStatusRegistration, TournamentStatusCancelled, "finished_date")
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// GetTournamentMatches get a tournament's bracket in match order
+func (s PostgresStore) GetTournamentMatches(tournamentID int) ([]TournamentMatch, error) {
+	matches := make([]TournamentMatch, 0)
+
+	matchSql := `SELECT m.*, f.status as fight_status, f.zclifebar, f.zhlifebar, f.tx_id as fight_tx_id
+					FROM tournament_match m
+					LEFT JOIN fight f ON f.id = m.fight_id
+					WHERE m.tournament_id = $1
+					ORDER BY m.match_number`
+	err := s.Db.Select(&matches, matchSql, tournamentID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return matches, nil
+		}
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// UpdateTournamentMatches saves the entries and results of matches as the bracket advances, decided matches are never
+// changed again
+func (s PostgresStore) UpdateTournamentMatches(ctx context.Context, matches []TournamentMatch) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	updateMatchSql := `UPDATE tournament_match SET top_entry_id = $1,
+										bottom_entry_id = $2,
+										winner_entry_id = $3,
+										loser_entry_id = $4,
+										decided = $5
+										WHERE id = $6 AND decided = false`
+	for _, match := range matches {
+		_, err = tx.ExecContext(ctx, updateMatchSql, match.TopEntryID, match.BottomEntryID, match.WinnerEntryID, match.LoserEntryID, match.Decided, match.ID)
+		if err != nil {
+			logrus.New().WithError(err).Error("Updating tournament match")
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// CreateTournamentFight persist the fight deciding a match, paid for later from the tournament's prize pool
+func (s PostgresStore) CreateTournamentFight(ctx context.Context, matchID int, zombieEntryID int, fight FightDto, hunterUser UserNfts, zombieUser UserNfts, mintingUser User) (int, error) {
+	var id int
+
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return id, err
+	}
+	defer tx.Rollback()
+
+	id, err = insertFight(ctx, tx, fight, hunterUser, zombieUser, mintingUser, FightActorEngine, fmt.Sprintf("Fight created for tournament %d match %d", fight.TournamentID.Int64, matchID))
+	if err != nil {
+		return id, err
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE tournament_match SET fight_id = $1, zombie_entry_id = $2 WHERE id = $3 AND fight_id IS NULL", id, zombieEntryID, matchID)
+	if err != nil {
+		logrus.New().WithError(err).Error("Setting match fight")
+		return id, err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return id, err
+	}
+	if updated != 1 {
+		return id, fmt.Errorf("Tournament match %d already has a fight", matchID)
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return id, err
+	}
+
+	return id, nil
+}
+
+// FinishTournament records where the entries placed, by entry id, once every match is decided
+func (s PostgresStore) FinishTournament(ctx context.Context, tournamentID int, places map[int]int) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	err = transitionTournament(ctx, tx, tournamentID, TournamentStatusRunning, TournamentStatusFinished, "finished_date")
+	if err != nil {
+		return err
+	}
+
+	for entryID, place := range places {
+		_, err = tx.ExecContext(ctx, "UPDATE tournament_entry SET place = $1 WHERE id = $2 AND tournament_id = $3", place, entryID, tournamentID)
+		if err != nil {
+			logrus.New().WithError(err).Error("Placing tournament entry")
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// SetTournamentPayoutTx checkpoints the signed prize payout tx before it is submitted
+func (s PostgresStore) SetTournamentPayoutTx(ctx context.Context, tournamentID int, txHash string, cborHex string, ttl int) error {
+	updateTournamentSql := `UPDATE tournament SET payout_tx_hash = $1,
+										payout_tx_cbor = $2,
+										payout_tx_ttl = $3
+										WHERE id = $4 AND status = $5 AND payout_tx_hash is null`
+
+	result, err := s.Db.ExecContext(ctx, updateTournamentSql, txHash, cborHex, ttl, tournamentID, TournamentStatusFinished)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating payout tx")
+		return err
+	}
+
+	// never replace a checkpointed payout with a different one
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return fmt.Errorf("Tournament %d already has a payout tx or isn't finished", tournamentID)
+	}
+
+	return nil
+}
+
+// ClearTournamentPayoutTx drops a payout tx that expired without reaching the chain
+func (s PostgresStore) ClearTournamentPayoutTx(ctx context.Context, tournamentID int, txHash string) error {
+	updateTournamentSql := `UPDATE tournament SET payout_tx_hash = null,
+										payout_tx_cbor = null,
+										payout_tx_ttl = null
+										WHERE id = $1 AND status = $2 AND payout_tx_hash = $3`
+
+	_, err := s.Db.ExecContext(ctx, updateTournamentSql, tournamentID, TournamentStatusFinished, txHash)
+	if err != nil {
+		logrus.New().WithError(err).Error("Clearing payout tx")
+		return err
+	}
+
+	return nil
+}
+
+// MarkTournamentPaid records the payout tx is on chain
+func (s PostgresStore) MarkTournamentPaid(ctx context.Context, tournamentID int) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	err = transitionTournament(ctx, tx, tournamentID, TournamentStatusFinished, TournamentStatusPaid, "")
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// transitionTournament moves a tournament between statuses, failing if it isn't in the from status. dateColumn is
+// set to now if given.
+func transitionTournament(ctx context.Context, tx *sql.Tx, tournamentID int, from TournamentStatus, to TournamentStatus, dateColumn string) error {
+	updateTournamentSql := "UPDATE tournament SET status = $1 WHERE id = $2 AND status = $3"
+	args := []interface{}{to, tournamentID, from}
+	if dateColumn != "" {
+		updateTournamentSql = fmt.Sprintf("UPDATE tournament SET status = $1, %s = $4 WHERE id = $2 AND status = $3", dateColumn)
+		args = append(args, time.Now())
+	}
+
+	result, err := tx.ExecContext(ctx, updateTournamentSql, args...)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating tournament status")
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return fmt.Errorf("Tournament %d can't move to %s, it isn't %s", tournamentID, to, from)
+	}
+
+	return nil
+}
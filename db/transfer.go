@@ -0,0 +1,102 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/sirupsen/logrus"
+)
+
+// RemoveNftListingsNotHeldBy removes the nft from every user who doesn't hold the account, from every user when the
+// account isn't valid. Returns how many listings were removed
+func (s PostgresStore) RemoveNftListingsNotHeldBy(ctx context.Context, nftID int, account sql.NullString) (int64, error) {
+	deleteSql := `DELETE FROM zfc_user_nft un WHERE un.nft_id = $1 AND NOT EXISTS (
+						SELECT 1 FROM zfc_user_account a WHERE a.zfc_user_id = un.zfc_user_id AND a.account = $2
+						UNION SELECT 1 FROM zfc_user u WHERE u.id = un.zfc_user_id AND u.stake_address = $2)`
+
+	result, err := s.Db.ExecContext(ctx, deleteSql, nftID, account)
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// GetOpenFightsForNft gets the nft's fights that are waiting on payment or on being fought, tournament fights are
+// paid from their prize pool and aren't included
+func (s PostgresStore) GetOpenFightsForNft(nftID int) ([]FightDb, error) {
+	fights := make([]FightDb, 0)
+
+	openFightsQuery := `SELECT f.id,
+							f.status,
+							f.created_date,
+							znft.name as zombie_name,
+							hnft.name as hunter_name,
+							f.payment_address,
+							f.payment_address_index,
+							f.payment_amount_lovelace,
+							f.incoming_utxo,
+							f.incoming_utxo_index,
+							f.hunter_send_address,
+							f.zombie_send_address,
+							f.tournament_id
+							FROM fight f
+							LEFT JOIN nft znft ON znft.id = f.zombie_nft_id
+							LEFT JOIN nft hnft ON hnft.id = f.hunter_nft_id
+							WHERE (f.zombie_nft_id = $1 OR f.hunter_nft_id = $1) AND f.status IN ($2, $3) AND f.tournament_id IS NULL
+							ORDER BY f.id asc`
+
+	err := s.Db.Select(&fights, openFightsQuery, nftID, FightStatusPending, FightStatusQueued)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fights, nil
+		}
+		return nil, err
+	}
+
+	return fights, nil
+}
+
+// VoidFight calls off a pending or queued fight in one tx, its alien goes back to the pool and every utxo that paid
+// for it is recorded as owed back
+func (s PostgresStore) VoidFight(ctx context.Context, fightID int, actor FightActor, reason string) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	err = transitionFight(ctx, tx, fightID, FightStatusVoid, actor, reason)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating fight status")
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, "UPDATE zfc_alien SET fight_id = NULL WHERE fight_id = $1", fightID)
+	if err != nil {
+		logrus.New().WithError(err).Error("Releasing alien")
+		return err
+	}
+
+	insertRefundsQuery := `INSERT INTO refund (tx_hash, output_index, sender_address, lovelace, assets, payment_address, payment_address_index, status)
+							SELECT fp.tx_hash, fp.output_index, fp.sender_address, fp.lovelace, fp.assets, f.payment_address, f.payment_address_index, $2
+							FROM fight_payment fp JOIN fight f ON f.id = fp.fight_id
+							WHERE fp.fight_id = $1
+							ON CONFLICT (tx_hash, output_index) DO NOTHING`
+	_, err = tx.ExecContext(ctx, insertRefundsQuery, fightID, RefundStatusPending)
+	if err != nil {
+		logrus.New().WithError(err).Error("Inserting refunds")
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SetFightSendAddresses points the fight's payouts at new addresses, never once a mint tx is signed for it
+func (s PostgresStore) SetFightSendAddresses(ctx context.Context, fightID int, zombieSendAddress string, hunterSendAddress string) error {
+	updateSql := `UPDATE fight SET zombie_send_address = $2, hunter_send_address = $3 WHERE id = $1 AND signed_tx_hash IS NULL`
+
+	_, err := s.Db.ExecContext(ctx, updateSql, fightID, zombieSendAddress, hunterSendAddress)
+	return err
+}
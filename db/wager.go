@@ -0,0 +1,455 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// WagerStatus AWAITING_DEPOSITS > FUNDED > PAID, or VOID when a deposit never arrives or the fight is never paid
+	WagerStatus string
+
+	// WagerSide the side of the fight a deposit backs
+	WagerSide string
+
+	// WagerEventType a step in a wager's life
+	WagerEventType string
+
+	// Wager a stake both owners put on a fight, the fight's status and life bars are joined in
+	Wager struct {
+		ID               int            `db:"id"`
+		FightID          int            `db:"fight_id"`
+		ChallengeID      sql.NullInt64  `db:"challenge_id"`
+		StakeLovelace    int64          `db:"stake_lovelace"`
+		HouseFeePercent  int            `db:"house_fee_percent"`
+		Status           WagerStatus    `db:"status"`
+		WinnerSide       sql.NullString `db:"winner_side"`
+		PayoutAddress    sql.NullString `db:"payout_address"`
+		PayoutLovelace   sql.NullInt64  `db:"payout_lovelace"`
+		HouseFeeLovelace sql.NullInt64  `db:"house_fee_lovelace"`
+		PayoutTxHash     sql.NullString `db:"payout_tx_hash"`
+		PayoutTxCbor     sql.NullString `db:"payout_tx_cbor"`
+		PayoutTxTTL      sql.NullInt64  `db:"payout_tx_ttl"`
+		CreatedDate      time.Time      `db:"created_date"`
+		ExpiresDate      time.Time      `db:"expires_date"`
+		SettledDate      sql.NullTime   `db:"settled_date"`
+		FightStatus      sql.NullString `db:"fight_status"`
+		ZombieLifeBar    sql.NullInt64  `db:"zclifebar"`
+		HunterLifeBar    sql.NullInt64  `db:"zhlifebar"`
+
+		// Deposits the deposits to create along with a new wager
+		Deposits []WagerDeposit `db:"-"`
+	}
+
+	// WagerDeposit one side's stake, paid to its own derived address
+	WagerDeposit struct {
+		ID                  int            `db:"id"`
+		WagerID             int            `db:"wager_id"`
+		Side                WagerSide      `db:"side"`
+		UserID              int            `db:"zfc_user_id"`
+		PaymentAddress      string         `db:"payment_address"`
+		PaymentAddressIndex int            `db:"payment_address_index"`
+		TxHash              sql.NullString `db:"tx_hash"`
+		OutputIndex         sql.NullInt64  `db:"output_index"`
+		SenderAddress       sql.NullString `db:"sender_address"`
+		Lovelace            sql.NullInt64  `db:"lovelace"`
+		PaidDate            sql.NullTime   `db:"paid_date"`
+	}
+
+	// WagerEvent one recorded step of a wager
+	WagerEvent struct {
+		ID          int            `db:"id"`
+		WagerID     int            `db:"wager_id"`
+		Event       WagerEventType `db:"event"`
+		Detail      string         `db:"detail"`
+		CreatedDate time.Time      `db:"created_date"`
+	}
+
+	// WagerPayout who a funded wager pays and how much
+	WagerPayout struct {
+		WinnerSide       WagerSide
+		Address          string
+		Lovelace         int64
+		HouseFeeLovelace int64
+	}
+)
+
+const (
+	WagerStatusAwaitingDeposits WagerStatus = "AWAITING_DEPOSITS"
+	WagerStatusFunded           WagerStatus = "FUNDED"
+	WagerStatusPaid             WagerStatus = "PAID"
+	WagerStatusVoid             WagerStatus = "VOID"
+
+	WagerSideZombie WagerSide = "ZOMBIE"
+	WagerSideHunter WagerSide = "HUNTER"
+
+	WagerEventCreated         WagerEventType = "CREATED"
+	WagerEventDeposit         WagerEventType = "DEPOSIT_RECEIVED"
+	WagerEventFunded          WagerEventType = "FUNDED"
+	WagerEventRefund          WagerEventType = "REFUND_RECORDED"
+	WagerEventVoided          WagerEventType = "VOIDED"
+	WagerEventPayoutSubmitted WagerEventType = "PAYOUT_SUBMITTED"
+	WagerEventPayoutExpired   WagerEventType = "PAYOUT_EXPIRED"
+	WagerEventPaid            WagerEventType = "PAID"
+
+	selectWagerSql = `SELECT w.*, f.status as fight_status, f.zclifebar, f.zhlifebar FROM wager w
+						JOIN fight f ON f.id = w.fight_id`
+)
+
+// Paid whether the deposit has been found on chain
+func (d WagerDeposit) Paid() bool {
+	return d.TxHash.Valid
+}
+
+// insertWager persists a new wager awaiting its deposits, inside the caller's tx
+func insertWager(ctx context.Context, tx *sql.Tx, wager Wager) (int, error) {
+	var id int
+
+	insertWagerSql := `INSERT INTO wager (fight_id, challenge_id, stake_lovelace, house_fee_percent, status, created_date, expires_date)
+						VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`
+	err := tx.QueryRowContext(ctx, insertWagerSql, wager.FightID, wager.ChallengeID, wager.StakeLovelace, wager.HouseFeePercent,
+		WagerStatusAwaitingDeposits, time.Now(), wager.ExpiresDate).Scan(&id)
+	if err != nil {
+		logrus.New().WithError(err).Error("Inserting wager")
+		return id, err
+	}
+
+	insertDepositSql := `INSERT INTO wager_deposit (wager_id, side, zfc_user_id, payment_address, payment_address_index)
+						VALUES ($1, $2, $3, $4, $5)`
+	for _, deposit := range wager.Deposits {
+		_, err = tx.ExecContext(ctx, insertDepositSql, id, deposit.Side, deposit.UserID, deposit.PaymentAddress, deposit.PaymentAddressIndex)
+		if err != nil {
+			logrus.New().WithError(err).Error("Inserting wager deposit")
+			return id, err
+		}
+	}
+
+	return id, insertWagerEvent(ctx, tx, id, WagerEventCreated, fmt.Sprintf("Stake of %d lovelace a side on fight %d", wager.StakeLovelace, wager.FightID))
+}
+
+func insertWagerEvent(ctx context.Context, db execer, wagerID int, event WagerEventType, detail string) error {
+	_, err := db.ExecContext(ctx, "INSERT INTO wager_event (wager_id, event, detail, created_date) VALUES ($1, $2, $3, $4)", wagerID, event, detail, time.Now())
+	if err != nil {
+		logrus.New().WithError(err).Error("Inserting wager event")
+	}
+	return err
+}
+
+// GetWagerForFight get the wager on a fight, nil if there isn't one
+func (s PostgresStore) GetWagerForFight(fightID int) (*Wager, error) {
+	wager := Wager{}
+
+	err := s.Db.Get(&wager, selectWagerSql+" WHERE w.fight_id = $1", fightID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &wager, nil
+}
+
+// GetWagersToProcess get the wagers still awaiting deposits or payout, and the settled ones created since, oldest first
+func (s PostgresStore) GetWagersToProcess(since time.Time) ([]Wager, error) {
+	wagers := make([]Wager, 0)
+
+	err := s.Db.Select(&wagers, selectWagerSql+" WHERE w.status in ($1, $2) OR w.created_date > $3 ORDER BY w.id",
+		WagerStatusAwaitingDeposits, WagerStatusFunded, since)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return wagers, nil
+		}
+		return nil, err
+	}
+
+	return wagers, nil
+}
+
+// GetWagerDeposits get both sides' deposits of a wager
+func (s PostgresStore) GetWagerDeposits(wagerID int) ([]WagerDeposit, error) {
+	deposits := make([]WagerDeposit, 0)
+
+	err := s.Db.Select(&deposits, "SELECT * FROM wager_deposit WHERE wager_id = $1 ORDER BY id", wagerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return deposits, nil
+		}
+		return nil, err
+	}
+
+	return deposits, nil
+}
+
+// GetWagerDepositForUtxo get the deposit a utxo paid, nil if it didn't pay one
+func (s PostgresStore) GetWagerDepositForUtxo(txHash string, outputIndex int) (*WagerDeposit, error) {
+	deposit := WagerDeposit{}
+
+	err := s.Db.Get(&deposit, "SELECT * FROM wager_deposit WHERE tx_hash = $1 AND output_index = $2", txHash, outputIndex)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &deposit, nil
+}
+
+// GetWagerEvents get every step of a wager, oldest first
+func (s PostgresStore) GetWagerEvents(wagerID int) ([]WagerEvent, error) {
+	events := make([]WagerEvent, 0)
+
+	err := s.Db.Select(&events, "SELECT * FROM wager_event WHERE wager_id = $1 ORDER BY id", wagerID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return events, nil
+		}
+		return nil, err
+	}
+
+	return events, nil
+}
+
+// PayWagerDeposit records the utxo that paid a deposit, the wager is funded once both sides are paid
+func (s PostgresStore) PayWagerDeposit(ctx context.Context, depositID int, payment FightPayment) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	var wagerID int
+	var side WagerSide
+	updateDepositSql := `UPDATE wager_deposit SET tx_hash = $1, output_index = $2, sender_address = $3, lovelace = $4, paid_date = $5
+							WHERE id = $6 AND tx_hash IS NULL RETURNING wager_id, side`
+	err = tx.QueryRowContext(ctx, updateDepositSql, payment.TxHash, payment.OutputIndex, payment.SenderAddress, payment.Lovelace, time.Now(), depositID).Scan(&wagerID, &side)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return fmt.Errorf("Wager deposit %d is already paid", depositID)
+		}
+		logrus.New().WithError(err).Error("Paying wager deposit")
+		return err
+	}
+	err = insertWagerEvent(ctx, tx, wagerID, WagerEventDeposit, fmt.Sprintf("%s side paid %d lovelace with %s#%d from %s", side, payment.Lovelace, payment.TxHash, payment.OutputIndex, payment.SenderAddress))
+	if err != nil {
+		return err
+	}
+
+	var unpaid int
+	err = tx.QueryRowContext(ctx, "SELECT count(*) FROM wager_deposit WHERE wager_id = $1 AND tx_hash IS NULL", wagerID).Scan(&unpaid)
+	if err != nil {
+		return err
+	}
+	if unpaid == 0 {
+		err = transitionWager(ctx, tx, wagerID, WagerStatusAwaitingDeposits, WagerStatusFunded, WagerEventFunded, "Both deposits paid")
+		if err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// RecordWagerRefund records a utxo at one of a wager's addresses as owed back, doing nothing if it already is
+func (s PostgresStore) RecordWagerRefund(ctx context.Context, wagerID int, refund Refund) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	insertRefundQuery := `INSERT INTO refund (tx_hash, output_index, sender_address, lovelace, assets, payment_address, payment_address_index, status, sender_account)
+							VALUES($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (tx_hash, output_index) DO NOTHING`
+	result, err := tx.ExecContext(ctx, insertRefundQuery, refund.TxHash, refund.OutputIndex, refund.SenderAddress, refund.Lovelace, refund.Assets,
+		refund.PaymentAddress, refund.PaymentAddressIndex, RefundStatusPending, refund.SenderAccount)
+	if err != nil {
+		logrus.New().WithError(err).Error("Inserting refund")
+		return err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if inserted == 0 {
+		return nil
+	}
+
+	err = insertWagerEvent(ctx, tx, wagerID, WagerEventRefund, fmt.Sprintf("%d lovelace in %s#%d owed back to %s", refund.Lovelace, refund.TxHash, refund.OutputIndex, refund.SenderAddress))
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// VoidWager calls off a wager that's awaiting deposits or funded but never paid out, its deposits are refunded
+// separately
+func (s PostgresStore) VoidWager(ctx context.Context, wagerID int, from WagerStatus, reason string) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	err = transitionWager(ctx, tx, wagerID, from, WagerStatusVoid, WagerEventVoided, reason)
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// SetWagerPayoutTx checkpoints the signed payout tx of a funded wager before it is submitted
+func (s PostgresStore) SetWagerPayoutTx(ctx context.Context, wagerID int, payout WagerPayout, txHash string, cborHex string, ttl int) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	updateWagerSql := `UPDATE wager SET winner_side = $1,
+									payout_address = $2,
+									payout_lovelace = $3,
+									house_fee_lovelace = $4,
+									payout_tx_hash = $5,
+									payout_tx_cbor = $6,
+									payout_tx_ttl = $7
+									WHERE id = $8 AND status = $9 AND payout_tx_hash is null`
+	result, err := tx.ExecContext(ctx, updateWagerSql, payout.WinnerSide, payout.Address, payout.Lovelace, payout.HouseFeeLovelace,
+		txHash, cborHex, ttl, wagerID, WagerStatusFunded)
+	if err != nil {
+		logrus.New().WithError(err).Error("Updating payout tx")
+		return err
+	}
+
+	// never replace a checkpointed payout with a different one
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return fmt.Errorf("Wager %d already has a payout tx or isn't funded", wagerID)
+	}
+
+	err = insertWagerEvent(ctx, tx, wagerID, WagerEventPayoutSubmitted, fmt.Sprintf("%s side won, %d lovelace to %s and %d lovelace house fee in tx %s",
+		payout.WinnerSide, payout.Lovelace, payout.Address, payout.HouseFeeLovelace, txHash))
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// ClearWagerPayoutTx drops a payout tx that expired without reaching the chain
+func (s PostgresStore) ClearWagerPayoutTx(ctx context.Context, wagerID int, txHash string) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	updateWagerSql := `UPDATE wager SET payout_tx_hash = null,
+									payout_tx_cbor = null,
+									payout_tx_ttl = null
+									WHERE id = $1 AND status = $2 AND payout_tx_hash = $3`
+	result, err := tx.ExecContext(ctx, updateWagerSql, wagerID, WagerStatusFunded, txHash)
+	if err != nil {
+		logrus.New().WithError(err).Error("Clearing payout tx")
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated == 0 {
+		return nil
+	}
+
+	err = insertWagerEvent(ctx, tx, wagerID, WagerEventPayoutExpired, fmt.Sprintf("Payout tx %s expired", txHash))
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// MarkWagerPaid records the payout tx is on chain
+func (s PostgresStore) MarkWagerPaid(ctx context.Context, wagerID int) error {
+	tx, err := s.Db.BeginTx(ctx, nil)
+	if err != nil {
+		logrus.New().WithError(err).Error("Beginning tx")
+		return err
+	}
+	defer tx.Rollback()
+
+	err = transitionWager(ctx, tx, wagerID, WagerStatusFunded, WagerStatusPaid, WagerEventPaid, "Payout tx is on chain")
+	if err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		logrus.New().WithError(err).Error("Committing tx")
+		return err
+	}
+
+	return nil
+}
+
+// transitionWager moves a wager between statuses and records the event, failing if it isn't in the from status.
+// Leaving the open statuses settles it.
+func transitionWager(ctx context.Context, tx *sql.Tx, wagerID int, from WagerStatus, to WagerStatus, event WagerEventType, detail string) error {
+	settledDate := sql.NullTime{}
+	if to == WagerStatusPaid || to == WagerStatusVoid {
+		settledDate = sql.NullTime{Time: time.Now(), Valid: true}
+	}
+
+	result, err := tx.ExecContext(ctx, "UPDATE wager SET status = $1, settled_date = $2 WHERE id = $3 AND status = $4", to, settledDate, wagerID, from)
+	if err != nil {
+		logrus.New().WithError(err).Errorf("Moving wager %d to %s", wagerID, to)
+		return err
+	}
+	updated, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if updated != 1 {
+		return fmt.Errorf("Wager %d isn't %s", wagerID, from)
+	}
+
+	return insertWagerEvent(ctx, tx, wagerID, event, detail)
+}
@@ -0,0 +1,118 @@
+// Package glicko Glicko-2 ratings, see http://www.glicko.net/glicko/glicko2.pdf
+package glicko
+
+import "math"
+
+type (
+	// Rating a player's rating, how sure we are of it and how erratic they are
+	Rating struct {
+		Rating     float64 `json:"rating"`
+		Deviation  float64 `json:"deviation"`
+		Volatility float64 `json:"volatility"`
+	}
+
+	// Result one game against an opponent, score is 1 for a win and 0 for a loss
+	Result struct {
+		Opponent Rating
+		Score    float64
+	}
+)
+
+const (
+	DefaultRating     = 1500
+	DefaultDeviation  = 350
+	DefaultVolatility = 0.06
+
+	// tau constrains how fast volatility moves, the paper suggests 0.3 to 1.2
+	tau = 0.5
+	// convergence tolerance for the volatility iteration
+	epsilon = 0.000001
+	// scale between glicko and glicko-2
+	scale = 173.7178
+)
+
+// Default the rating of a player who hasn't played
+func Default() Rating {
+	return Rating{Rating: DefaultRating, Deviation: DefaultDeviation, Volatility: DefaultVolatility}
+}
+
+// Rate the player's new rating after one rating period of results, with no results only the deviation grows
+func Rate(player Rating, results ...Result) Rating {
+	mu := (player.Rating - DefaultRating) / scale
+	phi := player.Deviation / scale
+	sigma := player.Volatility
+
+	if len(results) == 0 {
+		return Rating{Rating: player.Rating, Deviation: math.Min(math.Sqrt(phi*phi+sigma*sigma)*scale, DefaultDeviation), Volatility: sigma}
+	}
+
+	// estimated variance and improvement from the results
+	vInverse := 0.0
+	improvement := 0.0
+	for _, result := range results {
+		opponentMu := (result.Opponent.Rating - DefaultRating) / scale
+		opponentG := g(result.Opponent.Deviation / scale)
+		expected := 1 / (1 + math.Exp(-opponentG*(mu-opponentMu)))
+		vInverse += opponentG * opponentG * expected * (1 - expected)
+		improvement += opponentG * (result.Score - expected)
+	}
+	v := 1 / vInverse
+	delta := v * improvement
+
+	sigma = volatility(phi, sigma, v, delta)
+
+	phiStar := math.Sqrt(phi*phi + sigma*sigma)
+	phi = 1 / math.Sqrt(1/(phiStar*phiStar)+1/v)
+	mu = mu + phi*phi*improvement
+
+	return Rating{
+		Rating:     mu*scale + DefaultRating,
+		Deviation:  math.Min(phi*scale, DefaultDeviation),
+		Volatility: sigma,
+	}
+}
+
+// Conservative rating we're fairly sure the player is at least as good as, what leaderboards rank by so a few lucky
+// wins don't top them
+func (r Rating) Conservative() float64 {
+	return r.Rating - 2*r.Deviation
+}
+
+func g(phi float64) float64 {
+	return 1 / math.Sqrt(1+3*phi*phi/(math.Pi*math.Pi))
+}
+
+// volatility new volatility by the Illinois algorithm, step 5 of the paper
+func volatility(phi float64, sigma float64, v float64, delta float64) float64 {
+	a := math.Log(sigma * sigma)
+	f := func(x float64) float64 {
+		ex := math.Exp(x)
+		return ex*(delta*delta-phi*phi-v-ex)/(2*math.Pow(phi*phi+v+ex, 2)) - (x-a)/(tau*tau)
+	}
+
+	A := a
+	var B float64
+	if delta*delta > phi*phi+v {
+		B = math.Log(delta*delta - phi*phi - v)
+	} else {
+		k := 1.0
+		for f(a-k*tau) < 0 {
+			k++
+		}
+		B = a - k*tau
+	}
+
+	fA, fB := f(A), f(B)
+	for math.Abs(B-A) > epsilon {
+		C := A + (A-B)*fA/(fB-fA)
+		fC := f(C)
+		if fC*fB <= 0 {
+			A, fA = B, fB
+		} else {
+			fA = fA / 2
+		}
+		B, fB = C, fC
+	}
+
+	return math.Exp(A / 2)
+}
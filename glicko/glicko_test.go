@@ -0,0 +1,26 @@
+package glicko
+
+import (
+	"math"
+	"testing"
+)
+
+func TestRate(t *testing.T) {
+	// worked example from the glicko-2 paper
+	player := Rating{Rating: 1500, Deviation: 200, Volatility: 0.06}
+	rated := Rate(player,
+		Result{Opponent: Rating{Rating: 1400, Deviation: 30}, Score: 1},
+		Result{Opponent: Rating{Rating: 1550, Deviation: 100}, Score: 0},
+		Result{Opponent: Rating{Rating: 1700, Deviation: 300}, Score: 0},
+	)
+
+	if math.Abs(rated.Rating-1464.06) > 0.01 || math.Abs(rated.Deviation-151.52) > 0.01 || math.Abs(rated.Volatility-0.05999) > 0.00001 {
+		t.Errorf("Expected 1464.06 / 151.52 / 0.05999 but got %v", rated)
+	}
+
+	// not playing only makes us less sure
+	idle := Rate(player)
+	if idle.Rating != player.Rating || idle.Deviation <= player.Deviation {
+		t.Errorf("Expected only the deviation to grow but got %v", idle)
+	}
+}
@@ -0,0 +1,114 @@
+package hdwallet
+
+import (
+	"fmt"
+	"strings"
+)
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+func bech32Polymod(values []byte) uint32 {
+	generator := []uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>uint(i))&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32ExpandHrp(hrp string) []byte {
+	values := make([]byte, 0, len(hrp)*2+1)
+	for _, c := range hrp {
+		values = append(values, byte(c>>5))
+	}
+	values = append(values, 0)
+	for _, c := range hrp {
+		values = append(values, byte(c&31))
+	}
+	return values
+}
+
+// convertBits regroups fromBits wide words into toBits wide words
+func convertBits(data []byte, fromBits uint, toBits uint, pad bool) ([]byte, error) {
+	out := make([]byte, 0, len(data)*int(fromBits)/int(toBits)+1)
+	acc, bits := uint32(0), uint(0)
+	maxValue := uint32(1)<<toBits - 1
+	for _, value := range data {
+		acc = acc<<fromBits | uint32(value)
+		bits += fromBits
+		for bits >= toBits {
+			bits -= toBits
+			out = append(out, byte(acc>>bits&maxValue))
+		}
+	}
+	if pad {
+		if bits > 0 {
+			out = append(out, byte(acc<<(toBits-bits)&maxValue))
+		}
+	} else if bits >= fromBits || (acc<<(toBits-bits))&maxValue != 0 {
+		return nil, fmt.Errorf("Invalid padding")
+	}
+	return out, nil
+}
+
+// Bech32Encode encodes data with the human readable part, without bech32's 90 character limit since cardano keys are longer
+func Bech32Encode(hrp string, data []byte) (string, error) {
+	words, err := convertBits(data, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+
+	values := append(bech32ExpandHrp(hrp), words...)
+	polymod := bech32Polymod(append(values, 0, 0, 0, 0, 0, 0)) ^ 1
+
+	var b strings.Builder
+	b.WriteString(hrp)
+	b.WriteString("1")
+	for _, word := range words {
+		b.WriteByte(bech32Charset[word])
+	}
+	for i := 0; i < 6; i++ {
+		b.WriteByte(bech32Charset[(polymod>>uint(5*(5-i)))&31])
+	}
+
+	return b.String(), nil
+}
+
+// Bech32Decode decodes a bech32 string to its human readable part and data
+func Bech32Decode(value string) (string, []byte, error) {
+	if strings.ToLower(value) != value {
+		return "", nil, fmt.Errorf("%s isn't lower case", value)
+	}
+
+	separator := strings.LastIndex(value, "1")
+	if separator < 1 || separator+7 > len(value) {
+		return "", nil, fmt.Errorf("%s isn't bech32", value)
+	}
+
+	hrp := value[:separator]
+	words := make([]byte, 0, len(value)-separator-1)
+	for _, c := range value[separator+1:] {
+		index := strings.IndexRune(bech32Charset, c)
+		if index < 0 {
+			return "", nil, fmt.Errorf("%s has invalid character %c", value, c)
+		}
+		words = append(words, byte(index))
+	}
+
+	if bech32Polymod(append(bech32ExpandHrp(hrp), words...)) != 1 {
+		return "", nil, fmt.Errorf("%s has an invalid checksum", value)
+	}
+
+	data, err := convertBits(words[:len(words)-6], 5, 8, false)
+	if err != nil {
+		return "", nil, fmt.Errorf("%s has invalid padding", value)
+	}
+
+	return hrp, data, nil
+}
@@ -0,0 +1,347 @@
+package hdwallet
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"filippo.io/edwards25519"
+	"golang.org/x/crypto/blake2b"
+)
+
+type (
+	// ExtendedPublicKey BIP32-Ed25519 public key and chain code
+	ExtendedPublicKey struct {
+		Key       []byte
+		ChainCode []byte
+	}
+
+	// ExtendedPrivateKey BIP32-Ed25519 private key (kL, kR) and chain code
+	ExtendedPrivateKey struct {
+		Key       []byte
+		ChainCode []byte
+	}
+
+	// Wallet derives CIP-1852 addresses from an account key, m/1852'/1815'/account'
+	Wallet struct {
+		Account ExtendedPublicKey
+		// AccountKey only needed to spend from derived addresses
+		AccountKey *ExtendedPrivateKey
+		Mainnet    bool
+	}
+
+	textEnvelope struct {
+		Type        string `json:"type"`
+		Description string `json:"description"`
+		CborHex     string `json:"cborHex"`
+	}
+)
+
+const (
+	// HardenedIndex first hardened child index
+	HardenedIndex = uint32(0x80000000)
+
+	// CIP-1852 roles under the account
+	RoleExternal = uint32(0)
+	RoleStaking  = uint32(2)
+
+	extendedSigningKeyType = "PaymentExtendedSigningKeyShelley_ed25519_bip32"
+)
+
+// ParseExtendedPublicKey parses a bech32 (acct_xvk, xpub) or hex encoded 64 byte extended public key
+func ParseExtendedPublicKey(value string) (*ExtendedPublicKey, error) {
+	raw, err := decodeKey(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 64 {
+		return nil, fmt.Errorf("Extended public key should be 64 bytes but is %d", len(raw))
+	}
+	if _, err := new(edwards25519.Point).SetBytes(raw[:32]); err != nil {
+		return nil, fmt.Errorf("Extended public key isn't a valid point")
+	}
+
+	return &ExtendedPublicKey{Key: raw[:32], ChainCode: raw[32:]}, nil
+}
+
+// ParseExtendedPrivateKey parses a bech32 (acct_xsk, xprv) or hex encoded 96 byte extended private key
+func ParseExtendedPrivateKey(value string) (*ExtendedPrivateKey, error) {
+	raw, err := decodeKey(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(raw) != 96 {
+		return nil, fmt.Errorf("Extended private key should be 96 bytes but is %d", len(raw))
+	}
+
+	return &ExtendedPrivateKey{Key: raw[:64], ChainCode: raw[64:]}, nil
+}
+
+func decodeKey(value string) ([]byte, error) {
+	value = strings.TrimSpace(value)
+	if raw, err := hex.DecodeString(value); err == nil {
+		return raw, nil
+	}
+
+	_, raw, err := Bech32Decode(value)
+	return raw, err
+}
+
+// Child soft derivation of a public key, hardened indexes need the private key
+func (k ExtendedPublicKey) Child(index uint32) (*ExtendedPublicKey, error) {
+	if index >= HardenedIndex {
+		return nil, fmt.Errorf("Can't derive hardened index %d from a public key", index)
+	}
+
+	z := hmacSha512(k.ChainCode, []byte{0x02}, k.Key, indexBytes(index))
+	c := hmacSha512(k.ChainCode, []byte{0x03}, k.Key, indexBytes(index))
+
+	parent, err := new(edwards25519.Point).SetBytes(k.Key)
+	if err != nil {
+		return nil, err
+	}
+	tweak, err := scalarFromLE(mul8(z[:28]))
+	if err != nil {
+		return nil, err
+	}
+	child := new(edwards25519.Point).Add(parent, new(edwards25519.Point).ScalarBaseMult(tweak))
+
+	return &ExtendedPublicKey{Key: child.Bytes(), ChainCode: c[32:]}, nil
+}
+
+// Child derivation of a private key, hardened if index >= HardenedIndex
+func (k ExtendedPrivateKey) Child(index uint32) (*ExtendedPrivateKey, error) {
+	var z, c []byte
+	if index >= HardenedIndex {
+		z = hmacSha512(k.ChainCode, []byte{0x00}, k.Key, indexBytes(index))
+		c = hmacSha512(k.ChainCode, []byte{0x01}, k.Key, indexBytes(index))
+	} else {
+		public, err := k.PublicKey()
+		if err != nil {
+			return nil, err
+		}
+		z = hmacSha512(k.ChainCode, []byte{0x02}, public, indexBytes(index))
+		c = hmacSha512(k.ChainCode, []byte{0x03}, public, indexBytes(index))
+	}
+
+	// kL' = 8 * zL + kL, kR' = zR + kR mod 2^256
+	key := make([]byte, 64)
+	copy(key[:32], addLE(mul8(z[:28]), k.Key[:32]))
+	copy(key[32:], addLE(z[32:], k.Key[32:]))
+
+	return &ExtendedPrivateKey{Key: key, ChainCode: c[32:]}, nil
+}
+
+// PublicKey kL * G
+func (k ExtendedPrivateKey) PublicKey() ([]byte, error) {
+	kL, err := scalarFromLE(k.Key[:32])
+	if err != nil {
+		return nil, err
+	}
+	return new(edwards25519.Point).ScalarBaseMult(kL).Bytes(), nil
+}
+
+// Sign ed25519 signature using the extended key, verifies against PublicKey like any other ed25519 signature
+func (k ExtendedPrivateKey) Sign(message []byte) ([]byte, error) {
+	public, err := k.PublicKey()
+	if err != nil {
+		return nil, err
+	}
+	kL, err := scalarFromLE(k.Key[:32])
+	if err != nil {
+		return nil, err
+	}
+
+	rHash := sha512.New()
+	rHash.Write(k.Key[32:])
+	rHash.Write(message)
+	r, err := new(edwards25519.Scalar).SetUniformBytes(rHash.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	R := new(edwards25519.Point).ScalarBaseMult(r).Bytes()
+
+	hHash := sha512.New()
+	hHash.Write(R)
+	hHash.Write(public)
+	hHash.Write(message)
+	h, err := new(edwards25519.Scalar).SetUniformBytes(hHash.Sum(nil))
+	if err != nil {
+		return nil, err
+	}
+	S := new(edwards25519.Scalar).MultiplyAdd(h, kL, r)
+
+	return append(R, S.Bytes()...), nil
+}
+
+// PaymentAddress base address paying to account/0/index and staking to account/2/0
+func (w Wallet) PaymentAddress(index uint32) (string, error) {
+	payment, err := w.publicKey(RoleExternal, index)
+	if err != nil {
+		return "", err
+	}
+	stake, err := w.publicKey(RoleStaking, 0)
+	if err != nil {
+		return "", err
+	}
+
+	return baseAddress(payment, stake, w.Mainnet)
+}
+
+// PaymentSigningKey private key for account/0/index
+func (w Wallet) PaymentSigningKey(index uint32) (*ExtendedPrivateKey, error) {
+	if w.AccountKey == nil {
+		return nil, fmt.Errorf("No account signing key to spend from derived address %d", index)
+	}
+
+	role, err := w.AccountKey.Child(RoleExternal)
+	if err != nil {
+		return nil, err
+	}
+	return role.Child(index)
+}
+
+// WritePaymentSigningKey writes the key for account/0/index as a cardano-cli extended signing key file
+func (w Wallet) WritePaymentSigningKey(index uint32, fileName string) error {
+	key, err := w.PaymentSigningKey(index)
+	if err != nil {
+		return err
+	}
+	public, err := key.PublicKey()
+	if err != nil {
+		return err
+	}
+
+	// kL kR A cc, the layout cardano-cli uses for extended keys
+	raw := append(append(append([]byte{}, key.Key...), public...), key.ChainCode...)
+	envelope, err := json.MarshalIndent(textEnvelope{
+		Type:        extendedSigningKeyType,
+		Description: "",
+		CborHex:     "5880" + hex.EncodeToString(raw),
+	}, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, envelope, 0600)
+}
+
+// ParseExtendedSigningKey reads the 128 byte key out of a cardano-cli extended signing key file's cbor
+func ParseExtendedSigningKey(raw []byte) (*ExtendedPrivateKey, error) {
+	if len(raw) != 128 {
+		return nil, fmt.Errorf("Extended signing key should be 128 bytes but is %d", len(raw))
+	}
+	return &ExtendedPrivateKey{Key: raw[:64], ChainCode: raw[96:]}, nil
+}
+
+func (w Wallet) publicKey(role uint32, index uint32) ([]byte, error) {
+	roleKey, err := w.Account.Child(role)
+	if err != nil {
+		return nil, err
+	}
+	key, err := roleKey.Child(index)
+	if err != nil {
+		return nil, err
+	}
+	return key.Key, nil
+}
+
+// baseAddress CIP-19 type 0 address, key payment and key stake credentials
+func baseAddress(paymentKey []byte, stakeKey []byte, mainnet bool) (string, error) {
+	header := byte(0x00)
+	hrp := "addr_test"
+	if mainnet {
+		header = 0x01
+		hrp = "addr"
+	}
+
+	address := append([]byte{header}, blake2b224(paymentKey)...)
+	address = append(address, blake2b224(stakeKey)...)
+
+	return Bech32Encode(hrp, address)
+}
+
+// StakeAddressOf the stake address of a CIP-19 base address, empty for addresses without a stake part or that aren't
+// shelley addresses
+func StakeAddressOf(address string) string {
+	_, data, err := Bech32Decode(address)
+	if err != nil || len(data) != 57 || data[0]>>4 > 0x03 {
+		return ""
+	}
+
+	// types 0 and 1 have a key hash stake credential, 2 and 3 a script hash
+	header := byte(0xe0)
+	if data[0]>>4 >= 0x02 {
+		header = 0xf0
+	}
+	network := data[0] & 0x0f
+	hrp := "stake_test"
+	if network == 1 {
+		hrp = "stake"
+	}
+
+	stakeAddress, err := Bech32Encode(hrp, append([]byte{header | network}, data[29:]...))
+	if err != nil {
+		return ""
+	}
+	return stakeAddress
+}
+
+func blake2b224(data []byte) []byte {
+	hasher, _ := blake2b.New(28, nil)
+	hasher.Write(data)
+	return hasher.Sum(nil)
+}
+
+func hmacSha512(key []byte, parts ...[]byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	for _, part := range parts {
+		mac.Write(part)
+	}
+	return mac.Sum(nil)
+}
+
+func indexBytes(index uint32) []byte {
+	b := make([]byte, 4)
+	binary.LittleEndian.PutUint32(b, index)
+	return b
+}
+
+// mul8 multiplies a little endian number by 8, the result is 32 bytes
+func mul8(value []byte) []byte {
+	out := make([]byte, 32)
+	carry := byte(0)
+	for i := 0; i < 32; i++ {
+		b := byte(0)
+		if i < len(value) {
+			b = value[i]
+		}
+		out[i] = b<<3 | carry
+		carry = b >> 5
+	}
+	return out
+}
+
+// addLE adds two 32 byte little endian numbers mod 2^256
+func addLE(a []byte, b []byte) []byte {
+	out := make([]byte, 32)
+	carry := uint16(0)
+	for i := 0; i < 32; i++ {
+		sum := uint16(a[i]) + uint16(b[i]) + carry
+		out[i] = byte(sum)
+		carry = sum >> 8
+	}
+	return out
+}
+
+// scalarFromLE reduces a 32 byte little endian number mod the group order
+func scalarFromLE(value []byte) (*edwards25519.Scalar, error) {
+	wide := make([]byte, 64)
+	copy(wide, value)
+	return new(edwards25519.Scalar).SetUniformBytes(wide)
+}
@@ -0,0 +1,184 @@
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/hex"
+	"testing"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// testAccountKey an icarus style account key from a fixed seed
+func testAccountKey() *ExtendedPrivateKey {
+	seed := sha512.Sum512([]byte("zombie fight club"))
+	key := append([]byte{}, seed[:]...)
+	key[0] &= 0xf8
+	key[31] &= 0x1f
+	key[31] |= 0x40
+	chainCode := sha512.Sum512(seed[:])
+	return &ExtendedPrivateKey{Key: key, ChainCode: chainCode[:32]}
+}
+
+func TestBaseAddress(t *testing.T) {
+	// CIP-19 test vectors
+	_, paymentKey, _ := Bech32Decode("addr_vk1w0l2sr2zgfm26ztc6nl9xy8ghsk5sh6ldwemlpmp9xylzy4dtf7st80zhd")
+	_, stakeKey, _ := Bech32Decode("stake_vk1px4j0r2fk7ux5p23shz8f3y5y2qam7s954rgf3lg5merqcj6aetsft99wu")
+
+	address, err := baseAddress(paymentKey, stakeKey, true)
+	if err != nil {
+		t.Fatalf("Error building address %v", err)
+	}
+	if address != "addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x" {
+		t.Errorf("Unexpected address %s", address)
+	}
+}
+
+func TestStakeAddressOf(t *testing.T) {
+	// CIP-19 test vectors
+	tests := map[string]string{
+		"addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x": "stake1uyehkck0lajq8gr28t9uxnuvgcqrc6070x3k9r8048z8y5gh6ffgw",
+		"addr1vx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzers66hrl8":                                              "",
+		"stake1uyehkck0lajq8gr28t9uxnuvgcqrc6070x3k9r8048z8y5gh6ffgw":                                             "",
+		"not an address": "",
+	}
+
+	for address, expected := range tests {
+		if stakeAddress := StakeAddressOf(address); stakeAddress != expected {
+			t.Errorf("Expected %s to have stake address %q but got %q", address, expected, stakeAddress)
+		}
+	}
+}
+
+func TestPublicDerivationMatchesPrivate(t *testing.T) {
+	accountKey := testAccountKey()
+	public, _ := accountKey.PublicKey()
+	wallet := Wallet{Account: ExtendedPublicKey{Key: public, ChainCode: accountKey.ChainCode}, AccountKey: accountKey, Mainnet: true}
+
+	for _, index := range []uint32{0, 1, 7, 1000} {
+		fromPublic, err := wallet.publicKey(RoleExternal, index)
+		if err != nil {
+			t.Fatalf("Error deriving public key %v", err)
+		}
+		signingKey, err := wallet.PaymentSigningKey(index)
+		if err != nil {
+			t.Fatalf("Error deriving signing key %v", err)
+		}
+		fromPrivate, _ := signingKey.PublicKey()
+		if !bytes.Equal(fromPublic, fromPrivate) {
+			t.Errorf("Index %d public derivation %x doesn't match private %x", index, fromPublic, fromPrivate)
+		}
+
+		message := []byte("tx body hash")
+		signature, _ := signingKey.Sign(message)
+		if !ed25519.Verify(fromPublic, message, signature) {
+			t.Errorf("Index %d signature doesn't verify", index)
+		}
+	}
+
+	first, _ := wallet.PaymentAddress(0)
+	second, _ := wallet.PaymentAddress(1)
+	if first == second || first[:5] != "addr1" {
+		t.Errorf("Expected distinct mainnet addresses but got %s and %s", first, second)
+	}
+
+	if _, err := wallet.Account.Child(HardenedIndex); err == nil {
+		t.Error("Expected error deriving a hardened index from a public key")
+	}
+}
+
+// cip19Root icarus root key of the CIP-19 test vectors' mnemonic "test walk nut penalty hip pave soap entry language
+// right filter choice", the hex is its entropy
+func cip19Root() *ExtendedPrivateKey {
+	entropy, _ := hex.DecodeString("df9ed25ed146bf43336a5d7cf7395994")
+	master := pbkdf2.Key([]byte(""), entropy, 4096, 96, sha512.New)
+	master[0] &= 0xf8
+	master[31] &= 0x1f
+	master[31] |= 0x40
+	return &ExtendedPrivateKey{Key: master[:64], ChainCode: master[64:]}
+}
+
+func accountXvk(root *ExtendedPrivateKey, account uint32) string {
+	key := root
+	for _, index := range []uint32{HardenedIndex + 1852, HardenedIndex + 1815, HardenedIndex + account} {
+		key, _ = key.Child(index)
+	}
+	public, _ := key.PublicKey()
+	encoded, _ := Bech32Encode("acct_xvk", append(public, key.ChainCode...))
+	return encoded
+}
+
+func TestCip1852KnownAnswer(t *testing.T) {
+	// account keys m/1852'/1815'/0' and m/1852'/1815'/1' of the CIP-19 mnemonic
+	account0 := "acct_xvk1eame4ge0x5yrwpuqs5eyw89kfmjpgfkfh02xzdx6c2k9k2swcr5clf0u634tm82x6nv2j750x3j7938g70ya4k0lv6pr59s7etw2vpqgfmule"
+	account1 := accountXvk(cip19Root(), 1)
+	if derived := accountXvk(cip19Root(), 0); derived != account0 {
+		t.Fatalf("Expected account key %s but derived %s", account0, derived)
+	}
+
+	// only the public account key is used from here on, like PAYMENT_ACCOUNT_XPUB
+	payments, _ := ParseExtendedPublicKey(account0)
+	stakes, _ := ParseExtendedPublicKey(account1)
+	tests := []struct {
+		account  *ExtendedPublicKey
+		role     uint32
+		hrp      string
+		expected string
+	}{
+		// CIP-19's payment key is m/1852'/1815'/0'/0/0 and its stake key m/1852'/1815'/1'/2/0
+		{payments, RoleExternal, "addr_vk", "addr_vk1w0l2sr2zgfm26ztc6nl9xy8ghsk5sh6ldwemlpmp9xylzy4dtf7st80zhd"},
+		{stakes, RoleStaking, "stake_vk", "stake_vk1px4j0r2fk7ux5p23shz8f3y5y2qam7s954rgf3lg5merqcj6aetsft99wu"},
+	}
+	keys := make([][]byte, 0)
+	for _, test := range tests {
+		key, err := Wallet{Account: *test.account}.publicKey(test.role, 0)
+		if err != nil {
+			t.Fatalf("Error deriving key %v", err)
+		}
+		if encoded, _ := Bech32Encode(test.hrp, key); encoded != test.expected {
+			t.Errorf("Expected %s but derived %s", test.expected, encoded)
+		}
+		keys = append(keys, key)
+	}
+
+	address, _ := baseAddress(keys[0], keys[1], true)
+	if address != "addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x" {
+		t.Errorf("Unexpected address %s", address)
+	}
+}
+
+func TestParseExtendedPublicKey(t *testing.T) {
+	accountKey := testAccountKey()
+	public, _ := accountKey.PublicKey()
+	encoded, _ := Bech32Encode("acct_xvk", append(public, accountKey.ChainCode...))
+
+	parsed, err := ParseExtendedPublicKey(encoded)
+	if err != nil {
+		t.Fatalf("Error parsing key %v", err)
+	}
+	if !bytes.Equal(parsed.Key, public) || !bytes.Equal(parsed.ChainCode, accountKey.ChainCode) {
+		t.Error("Parsed key doesn't match")
+	}
+
+	if _, err := ParseExtendedPublicKey(encoded[:len(encoded)-1] + "q"); err == nil {
+		t.Error("Expected checksum error")
+	}
+}
+
+func TestBech32Decode(t *testing.T) {
+	address := "addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x"
+	hrp, data, err := Bech32Decode(address)
+	if err != nil {
+		t.Fatalf("Error decoding address %v", err)
+	}
+
+	expected := "019493315cd92eb5d8c4304e67b7e16ae36d61d34502694657811a2c8e337b62cfff6403a06a3acbc34f8c46003c69fe79a3628cefa9c47251"
+	if hrp != "addr" || hex.EncodeToString(data) != expected {
+		t.Errorf("Expected addr %s but got %s %x", expected, hrp, data)
+	}
+
+	if _, _, err := Bech32Decode(address[:len(address)-1] + "q"); err == nil {
+		t.Error("Expected checksum error")
+	}
+}
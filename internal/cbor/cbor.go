@@ -0,0 +1,270 @@
+package cbor
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// just enough cbor to write transactions and the structures cip8 signs over, split them back into their raw parts
+// and read COSE_Sign1 and COSE_Key structures. Raw parts matter because tx ids and signatures are over the exact bytes
+
+// major types
+const (
+	Uint   = 0
+	NegInt = 1
+	Bytes  = 2
+	Text   = 3
+	Array  = 4
+	Map    = 5
+	Tag    = 6
+	Simple = 7
+)
+
+// simple values
+const (
+	simpleFalse = 20
+	simpleTrue  = 21
+	simpleNull  = 22
+)
+
+// Head encodes the major type and argument that start every item
+func Head(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		b := []byte{major<<5 | 25, 0, 0}
+		binary.BigEndian.PutUint16(b[1:], uint16(n))
+		return b
+	case n <= 0xffffffff:
+		b := []byte{major<<5 | 26, 0, 0, 0, 0}
+		binary.BigEndian.PutUint32(b[1:], uint32(n))
+		return b
+	default:
+		b := []byte{major<<5 | 27, 0, 0, 0, 0, 0, 0, 0, 0}
+		binary.BigEndian.PutUint64(b[1:], n)
+		return b
+	}
+}
+
+func Int(n int64) []byte {
+	if n < 0 {
+		return Head(NegInt, uint64(-1-n))
+	}
+	return Head(Uint, uint64(n))
+}
+
+func Bool(b bool) []byte {
+	if b {
+		return Head(Simple, simpleTrue)
+	}
+	return Head(Simple, simpleFalse)
+}
+
+func ByteString(b []byte) []byte {
+	return append(Head(Bytes, uint64(len(b))), b...)
+}
+
+func TextString(s string) []byte {
+	return append(Head(Text, uint64(len(s))), s...)
+}
+
+// ArrayOf wraps already encoded items in an array
+func ArrayOf(items ...[]byte) []byte {
+	out := Head(Array, uint64(len(items)))
+	for _, item := range items {
+		out = append(out, item...)
+	}
+	return out
+}
+
+// MapOf wraps already encoded key, value pairs in a map, keeping their order
+func MapOf(pairs ...[2][]byte) []byte {
+	out := Head(Map, uint64(len(pairs)))
+	for _, pair := range pairs {
+		out = append(out, pair[0]...)
+		out = append(out, pair[1]...)
+	}
+	return out
+}
+
+// ReadHead reads the major type and argument of the item at the start of data and returns the head's length
+func ReadHead(data []byte) (byte, uint64, int, error) {
+	if len(data) == 0 {
+		return 0, 0, 0, fmt.Errorf("Unexpected end of cbor")
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+	switch {
+	case info < 24:
+		return major, uint64(info), 1, nil
+	case info <= 27:
+		size := 1 << (info - 24)
+		if len(data) < 1+size {
+			return 0, 0, 0, fmt.Errorf("Unexpected end of cbor")
+		}
+		n := uint64(0)
+		for _, b := range data[1 : 1+size] {
+			n = n<<8 | uint64(b)
+		}
+		return major, n, 1 + size, nil
+	default:
+		return 0, 0, 0, fmt.Errorf("Indefinite length cbor not supported")
+	}
+}
+
+// ItemLength length in bytes of the item at the start of data
+func ItemLength(data []byte) (int, error) {
+	major, n, headLength, err := ReadHead(data)
+	if err != nil {
+		return 0, err
+	}
+
+	switch major {
+	case Uint, NegInt, Simple:
+		return headLength, nil
+	case Bytes, Text:
+		if uint64(len(data)-headLength) < n {
+			return 0, fmt.Errorf("Unexpected end of cbor")
+		}
+		return headLength + int(n), nil
+	case Tag:
+		itemLength, err := ItemLength(data[headLength:])
+		return headLength + itemLength, err
+	}
+
+	// arrays and maps, maps have two items per entry
+	items := n
+	if major == Map {
+		items = n * 2
+	}
+	length := headLength
+	for i := uint64(0); i < items; i++ {
+		itemLength, err := ItemLength(data[length:])
+		if err != nil {
+			return 0, err
+		}
+		length += itemLength
+	}
+
+	return length, nil
+}
+
+// Split splits an array or map into its raw items, map keys and values alternate
+func Split(data []byte, expectedMajor byte) ([][]byte, error) {
+	major, n, headLength, err := ReadHead(data)
+	if err != nil {
+		return nil, err
+	}
+	if major != expectedMajor {
+		return nil, fmt.Errorf("Expected cbor major type %d but found %d", expectedMajor, major)
+	}
+
+	if major == Map {
+		n = n * 2
+	}
+	items := make([][]byte, 0, n)
+	offset := headLength
+	for i := uint64(0); i < n; i++ {
+		itemLength, err := ItemLength(data[offset:])
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, data[offset:offset+itemLength])
+		offset += itemLength
+	}
+
+	return items, nil
+}
+
+// Decode decodes a single item that must take up all of data
+func Decode(data []byte) (interface{}, error) {
+	value, length, err := decodeItem(data)
+	if err != nil {
+		return nil, err
+	}
+	if length != len(data) {
+		return nil, fmt.Errorf("Unexpected %d bytes after cbor", len(data)-length)
+	}
+	return value, nil
+}
+
+// decodeItem decodes the item at the start of data into int64, []byte, string, []interface{},
+// map[interface{}]interface{}, bool or nil and returns its length. Tags are dropped
+func decodeItem(data []byte) (interface{}, int, error) {
+	major, n, headLength, err := ReadHead(data)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch major {
+	case Uint, NegInt:
+		if n > 1<<63-1 {
+			return nil, 0, fmt.Errorf("Cbor integer out of range")
+		}
+		if major == NegInt {
+			return -1 - int64(n), headLength, nil
+		}
+		return int64(n), headLength, nil
+	case Bytes, Text:
+		if uint64(len(data)-headLength) < n {
+			return nil, 0, fmt.Errorf("Unexpected end of cbor")
+		}
+		end := headLength + int(n)
+		if major == Text {
+			return string(data[headLength:end]), end, nil
+		}
+		return append([]byte{}, data[headLength:end]...), end, nil
+	case Array:
+		items := make([]interface{}, 0)
+		length := headLength
+		for i := uint64(0); i < n; i++ {
+			item, itemLength, err := decodeItem(data[length:])
+			if err != nil {
+				return nil, 0, err
+			}
+			items = append(items, item)
+			length += itemLength
+		}
+		return items, length, nil
+	case Map:
+		entries := make(map[interface{}]interface{})
+		length := headLength
+		for i := uint64(0); i < n; i++ {
+			key, keyLength, err := decodeItem(data[length:])
+			if err != nil {
+				return nil, 0, err
+			}
+			length += keyLength
+			value, valueLength, err := decodeItem(data[length:])
+			if err != nil {
+				return nil, 0, err
+			}
+			length += valueLength
+
+			switch key.(type) {
+			case int64, string:
+				entries[key] = value
+			default:
+				return nil, 0, fmt.Errorf("Unsupported cbor map key %T", key)
+			}
+		}
+		return entries, length, nil
+	case Tag:
+		item, itemLength, err := decodeItem(data[headLength:])
+		return item, headLength + itemLength, err
+	}
+
+	switch n {
+	case simpleFalse:
+		return false, headLength, nil
+	case simpleTrue:
+		return true, headLength, nil
+	case simpleNull:
+		return nil, headLength, nil
+	}
+	return nil, 0, fmt.Errorf("Unsupported cbor simple value %d", n)
+}
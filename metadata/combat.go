@@ -0,0 +1,179 @@
+package metadata
+
+type (
+	// CombatLog round by round account of a fight, the life bars, knock outs and beat ups on the fight image come from it
+	CombatLog struct {
+		Version        int        `json:"version"`
+		ZombieStrength int        `json:"zombieStrength"`
+		HunterStrength int        `json:"hunterStrength"`
+		Breakdown      *Breakdown `json:"breakdown,omitempty"`
+		Rounds         []Round    `json:"rounds"`
+		Winner         string     `json:"winner"`
+		ZombieHealth   int        `json:"zombieHealth"`
+		HunterHealth   int        `json:"hunterHealth"`
+		ZombieLifeBar  int        `json:"zombieLifeBar"`
+		HunterLifeBar  int        `json:"hunterLifeBar"`
+		ZombieKO       bool       `json:"zombieKo"`
+		HunterKO       bool       `json:"hunterKo"`
+		ZombieBeatup   bool       `json:"zombieBeatup"`
+		HunterBeatup   bool       `json:"hunterBeatup"`
+	}
+
+	// Round the attacks in one round, in the order they happened
+	Round struct {
+		Number  int      `json:"number"`
+		Attacks []Attack `json:"attacks"`
+	}
+
+	// Attack one fighter's swing at the other and both healths after it
+	Attack struct {
+		Attacker     string `json:"attacker"`
+		Action       string `json:"action"`
+		Damage       int    `json:"damage"`
+		ZombieHealth int    `json:"zombieHealth"`
+		HunterHealth int    `json:"hunterHealth"`
+	}
+)
+
+const (
+	// CombatLogVersion bumped whenever the simulation changes so old logs can still be told apart
+	CombatLogVersion = 1
+
+	ActionHit      = "hit"
+	ActionBlock    = "block"
+	ActionCritical = "critical"
+	ActionKnockout = "knockout"
+
+	maxRounds  = 8
+	fullHealth = 100
+
+	// strength differences past this don't make a fighter any better
+	maxEdge = 150
+	// loser finishing this far behind the winner looks beat up
+	beatupHealth = 20
+)
+
+// SimulateFight fights both strengths over up to eight rounds, drawing from the fight's rand. Each round both
+// fighters attack in a random order, the stronger one blocks, crits and hits harder more often. The fight ends
+// early on a knock out, otherwise the most health left wins with ties going to the stronger fighter, then the zombie.
+func SimulateFight(zombieStrength int, hunterStrength int, rnd *Rand) CombatLog {
+	log := CombatLog{
+		Version:        CombatLogVersion,
+		ZombieStrength: zombieStrength,
+		HunterStrength: hunterStrength,
+		Rounds:         make([]Round, 0),
+		ZombieHealth:   fullHealth,
+		HunterHealth:   fullHealth,
+	}
+
+	health := map[string]*int{SideZombie: &log.ZombieHealth, SideHunter: &log.HunterHealth}
+	strength := map[string]int{SideZombie: zombieStrength, SideHunter: hunterStrength}
+
+	knockedOut := ""
+	for number := 1; number <= maxRounds && knockedOut == ""; number++ {
+		round := Round{Number: number, Attacks: make([]Attack, 0)}
+
+		order := []string{SideZombie, SideHunter}
+		if rnd.Intn(2) == 1 {
+			order = []string{SideHunter, SideZombie}
+		}
+
+		for _, attacker := range order {
+			defender := opponent(attacker)
+			action, damage := attack(strength[attacker]-strength[defender], rnd)
+			if damage > *health[defender] {
+				damage = *health[defender]
+			}
+			*health[defender] -= damage
+			round.Attacks = append(round.Attacks, Attack{Attacker: attacker, Action: action, Damage: damage, ZombieHealth: log.ZombieHealth, HunterHealth: log.HunterHealth})
+
+			if *health[defender] == 0 {
+				knockedOut = defender
+				round.Attacks = append(round.Attacks, Attack{Attacker: attacker, Action: ActionKnockout, ZombieHealth: log.ZombieHealth, HunterHealth: log.HunterHealth})
+				break
+			}
+		}
+
+		log.Rounds = append(log.Rounds, round)
+	}
+
+	switch {
+	case knockedOut != "":
+		log.Winner = opponent(knockedOut)
+	case log.ZombieHealth != log.HunterHealth:
+		log.Winner = SideZombie
+		if log.HunterHealth > log.ZombieHealth {
+			log.Winner = SideHunter
+		}
+	default:
+		log.Winner = SideZombie
+		if hunterStrength > zombieStrength {
+			log.Winner = SideHunter
+		}
+	}
+
+	log.setResult(knockedOut != "")
+
+	return log
+}
+
+// attack an attacker's action and damage given how much stronger they are than the defender
+func attack(difference int, rnd *Rand) (string, int) {
+	edge := difference
+	if edge > maxEdge {
+		edge = maxEdge
+	} else if edge < -maxEdge {
+		edge = -maxEdge
+	}
+
+	// 10% to 40% of attacks are blocked, the weaker the attacker the more
+	if rnd.Intn(100) < 25-edge/10 {
+		return ActionBlock, 0
+	}
+
+	damage := 4 + rnd.Intn(8) + edge/15
+	if damage < 1 {
+		damage = 1
+	}
+
+	// 0% to 20% of hits are criticals for double damage
+	if rnd.Intn(100) < 10+edge/15 {
+		return ActionCritical, damage * 2
+	}
+	return ActionHit, damage
+}
+
+// setResult life bars in tens for the fight image, the winner's always ahead of the loser's and only a knock
+// out takes a life bar to zero
+func (log *CombatLog) setResult(knockOut bool) {
+	winnerHealth, loserHealth := log.ZombieHealth, log.HunterHealth
+	if log.Winner == SideHunter {
+		winnerHealth, loserHealth = loserHealth, winnerHealth
+	}
+
+	loserLifeBar := 0
+	if !knockOut {
+		loserLifeBar = loserHealth / 10 * 10
+		if loserLifeBar < 10 {
+			loserLifeBar = 10
+		} else if loserLifeBar >= fullHealth {
+			loserLifeBar = fullHealth - 10
+		}
+	}
+	winnerLifeBar := (winnerHealth + 9) / 10 * 10
+	if winnerLifeBar <= loserLifeBar {
+		winnerLifeBar = loserLifeBar + 10
+	}
+	if winnerLifeBar > fullHealth {
+		winnerLifeBar = fullHealth
+	}
+	beatup := winnerHealth-loserHealth > beatupHealth
+
+	if log.Winner == SideZombie {
+		log.ZombieLifeBar, log.HunterLifeBar = winnerLifeBar, loserLifeBar
+		log.HunterKO, log.HunterBeatup = knockOut, beatup
+	} else {
+		log.HunterLifeBar, log.ZombieLifeBar = winnerLifeBar, loserLifeBar
+		log.ZombieKO, log.ZombieBeatup = knockOut, beatup
+	}
+}
@@ -0,0 +1,53 @@
+package metadata
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestSimulateFight(t *testing.T) {
+	strongerWins := 0
+	knockouts := 0
+	for i := 0; i < 500; i++ {
+		log := SimulateFight(450, 300, NewRand([]byte(fmt.Sprintf("seed %d", i))))
+
+		zombieHealth, hunterHealth := 100, 100
+		for _, round := range log.Rounds {
+			for _, attack := range round.Attacks {
+				if attack.Attacker == SideZombie {
+					hunterHealth -= attack.Damage
+				} else {
+					zombieHealth -= attack.Damage
+				}
+				if attack.ZombieHealth != zombieHealth || attack.HunterHealth != hunterHealth {
+					t.Fatalf("Health in the log doesn't add up %v", log)
+				}
+			}
+		}
+		if zombieHealth != log.ZombieHealth || hunterHealth != log.HunterHealth {
+			t.Fatalf("Final health doesn't match the log %v", log)
+		}
+
+		// the fight image and minting tell the winner by life bar
+		if (log.Winner == SideZombie) != (log.ZombieLifeBar > log.HunterLifeBar) {
+			t.Fatalf("Winner %s doesn't have the bigger life bar %v", log.Winner, log)
+		}
+		if log.HunterKO != (log.Winner == SideZombie && log.HunterHealth == 0) || log.ZombieKO != (log.Winner == SideHunter && log.ZombieHealth == 0) {
+			t.Fatalf("Knock out doesn't match health %v", log)
+		}
+
+		if log.Winner == SideZombie {
+			strongerWins++
+		}
+		if log.HunterKO || log.ZombieKO {
+			knockouts++
+		}
+	}
+
+	if strongerWins < 450 {
+		t.Errorf("Expected a 150 point edge to win almost always but won %d of 500", strongerWins)
+	}
+	if knockouts == 0 {
+		t.Error("Expected a 150 point edge to knock out sometimes")
+	}
+}
@@ -54,7 +54,7 @@ func TestFightCalculations(t *testing.T) {
 	for i := 1; i <= 10000; i++ {
 		zombieName := fmt.Sprintf("ZombieChains%05d", i)
 		zombieMeta := zcMeta[zombieName]
-		strength := determineZcStrenth(zombieMeta, *zcTraitStrength, 0)
+		strength := determineZcStrenth(zombieMeta, *zcTraitStrength, 0, nil)
 		//logrus.Infof("Zombie %s has strength %d", zombieName, strength)
 		totalZcStrength += strength
 
@@ -71,7 +71,7 @@ func TestFightCalculations(t *testing.T) {
 	for i := 1; i <= 10000; i++ {
 		hunterName := fmt.Sprintf("ZombieHunter%05d", i)
 		hunterMeta := zhMeta[hunterName]
-		strength := determineZhStrength(hunterMeta, *zhTraitStrength, 0)
+		strength := determineZhStrength(hunterMeta, *zhTraitStrength, 0, nil)
 
 		if strength > zcMax {
 			zcMax = strength
@@ -139,7 +139,7 @@ func TestFightCalculations(t *testing.T) {
 	// for i := 1; i <= 10000; i++ {
 	// 	hunterName := fmt.Sprintf("ZombieHunter%05d", i)
 	// 	hunterMeta := zhMeta[hunterName]
-	// 	strength := determineZhStrength(hunterMeta, *zhTraitStrength, 0)
+	// 	strength := determineZhStrength(hunterMeta, *zhTraitStrength, 0, nil)
 	// 	//logrus.Infof("Hunter %s has strength %d", hunterName, strength)
 	// 	totalZhStrength += strength
 	// }
@@ -176,7 +176,7 @@ func buildHunterStrength(zhMeta map[string]ZombieHunter, zhTraitStrength *Zombie
 	for i := 1; i <= 10000; i++ {
 		hunterName := fmt.Sprintf("ZombieHunter%05d", i)
 		hunterMeta := zhMeta[hunterName]
-		strength := determineZhStrength(hunterMeta, *zhTraitStrength, 0)
+		strength := determineZhStrength(hunterMeta, *zhTraitStrength, 0, nil)
 		//logrus.Infof("Zombie %s has strength %d", zombieName, strength)
 		totalZhStrength += strength
 
@@ -222,6 +222,7 @@ func buildHunterStrength(zhMeta map[string]ZombieHunter, zhTraitStrength *Zombie
 
 func TestSimulateFights(t *testing.T) {
 	rand.Seed(time.Now().UnixNano())
+	rnd := NewRand([]byte(time.Now().String()))
 
 	zcMeta, err := LoadZombieChainsMeta(".")
 	if err != nil {
@@ -259,7 +260,7 @@ func TestSimulateFights(t *testing.T) {
 		zombieName := fmt.Sprintf("ZombieChains%05d", randomZombieNumber)
 		hunterName := fmt.Sprintf("ZombieHunter%05d", randomHunterNumber)
 
-		zombieWin, hunterWin, difference := fightZombieAndHunter(zombieName, hunterName, zcMeta, zhMeta, *zcTraitStrength, *zhTraitStrength, 120)
+		zombieWin, hunterWin, difference := fightZombieAndHunter(zombieName, hunterName, zcMeta, zhMeta, *zcTraitStrength, *zhTraitStrength, 120, rnd)
 		logrus.Info(difference)
 		totalDifference += difference
 		if zombieWin {
@@ -288,8 +289,11 @@ func TestSimulateFights(t *testing.T) {
 			}
 		}
 
-		_, _, ko := DetermineLifeBar(difference)
-		if ko {
+		if zombieWin {
+			if log := SimulateFight(difference, 0, rnd); log.HunterKO {
+				numKnockouts++
+			}
+		} else if log := SimulateFight(0, difference, rnd); log.ZombieKO {
 			numKnockouts++
 		}
 	}
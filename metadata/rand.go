@@ -0,0 +1,40 @@
+package metadata
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+)
+
+// Rand deterministic random numbers from a fight's seed so anyone can replay a fight. Block i is
+// sha256(seed || i as an 8 byte big endian counter) and each number takes the next 8 bytes of the blocks as a
+// big endian uint64 mod n.
+type Rand struct {
+	seed    []byte
+	counter uint64
+	buffer  []byte
+}
+
+// NewRand random numbers from seed
+func NewRand(seed []byte) *Rand {
+	return &Rand{seed: append([]byte{}, seed...)}
+}
+
+// Intn number in [0, n)
+func (r *Rand) Intn(n int) int {
+	if n <= 0 {
+		panic("invalid argument to Intn")
+	}
+
+	if len(r.buffer) < 8 {
+		counter := make([]byte, 8)
+		binary.BigEndian.PutUint64(counter, r.counter)
+		block := sha256.Sum256(append(append([]byte{}, r.seed...), counter...))
+		r.buffer = append(r.buffer, block[:]...)
+		r.counter++
+	}
+
+	value := binary.BigEndian.Uint64(r.buffer[:8])
+	r.buffer = r.buffer[8:]
+
+	return int(value % uint64(n))
+}
@@ -0,0 +1,33 @@
+package metadata
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+func TestRandReplays(t *testing.T) {
+	seed := []byte("fight seed")
+
+	// first number is the first 8 bytes of sha256(seed || 0)
+	block := sha256.Sum256(append(append([]byte{}, seed...), 0, 0, 0, 0, 0, 0, 0, 0))
+	expected := int(binary.BigEndian.Uint64(block[:8]) % 1000)
+	if first := NewRand(seed).Intn(1000); first != expected {
+		t.Errorf("Expected %d but got %d", expected, first)
+	}
+
+	first := NewRand(seed)
+	second := NewRand(seed)
+	for i := 0; i < 20; i++ {
+		if a, b := first.Intn(120), second.Intn(120); a != b {
+			t.Fatalf("Draw %d differs %d / %d", i, a, b)
+		}
+	}
+
+	fight := SimulateFight(400, 300, NewRand(seed))
+	replay := SimulateFight(400, 300, NewRand(seed))
+	if !reflect.DeepEqual(fight, replay) {
+		t.Error("Expected the same combat log from the same seed")
+	}
+}
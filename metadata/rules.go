@@ -0,0 +1,362 @@
+package metadata
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+)
+
+type (
+	// Rules versioned fight rules, with a rule set per season and a default for fights outside of one
+	Rules struct {
+		Version  int                `json:"version"`
+		Default  string             `json:"default"`
+		Seasons  map[string]string  `json:"seasons"`
+		RuleSets map[string]RuleSet `json:"ruleSets"`
+	}
+
+	// RuleSet modifiers applied on top of the trait strengths
+	RuleSet struct {
+		Randomness int `json:"randomness"`
+		// applied when one of a fighter's traits is weak to one of the opponent's, per the trait csv
+		WeaknessModifier int         `json:"weaknessModifier"`
+		Matchups         []Matchup   `json:"matchups"`
+		HomeField        []HomeField `json:"homeField"`
+		Fatigue          *Fatigue    `json:"fatigue"`
+	}
+
+	// Fatigue modifier for a fighter that already fought within the window, per earlier fight up to max
+	Fatigue struct {
+		WindowMinutes int `json:"windowMinutes"`
+		PerFight      int `json:"perFight"`
+		Max           int `json:"max"`
+	}
+
+	// Matchup modifier for a fighter whose trait meets an opponent's trait, negative for a weakness and positive for a bonus
+	Matchup struct {
+		Side          string `json:"side"`
+		Trait         string `json:"trait"`
+		Value         string `json:"value"`
+		OpponentTrait string `json:"opponentTrait"`
+		OpponentValue string `json:"opponentValue"`
+		Modifier      int    `json:"modifier"`
+	}
+
+	// HomeField modifier for a fighter on their home background
+	HomeField struct {
+		Side       string `json:"side"`
+		Background string `json:"background"`
+		Modifier   int    `json:"modifier"`
+	}
+
+	// Modifier one line of a fight's strength breakdown
+	Modifier struct {
+		Side        string `json:"side"`
+		Source      string `json:"source"`
+		Description string `json:"description"`
+		Value       int    `json:"value"`
+	}
+
+	// Breakdown how both strengths in a fight were reached
+	Breakdown struct {
+		RulesVersion   int        `json:"rulesVersion"`
+		RuleSet        string     `json:"ruleSet"`
+		ZombieStrength int        `json:"zombieStrength"`
+		HunterStrength int        `json:"hunterStrength"`
+		Modifiers      []Modifier `json:"modifiers"`
+	}
+)
+
+const (
+	// RulesVersion the rules file version this server understands
+	RulesVersion = 1
+
+	SideZombie = "zombie"
+	SideHunter = "hunter"
+
+	defaultRuleSet    = "base"
+	defaultRandomness = 120
+)
+
+// DefaultRules plain trait strengths plus noise, how fights worked before rules were configurable
+func DefaultRules() Rules {
+	return Rules{
+		Version:  RulesVersion,
+		Default:  defaultRuleSet,
+		RuleSets: map[string]RuleSet{defaultRuleSet: {Randomness: defaultRandomness}},
+	}
+}
+
+// LoadRules reads and validates a rules file
+func LoadRules(fileName string) (*Rules, error) {
+	content, err := os.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := Rules{}
+	err = json.Unmarshal(content, &rules)
+	if err != nil {
+		return nil, err
+	}
+
+	return &rules, rules.Validate()
+}
+
+// Validate checks the version and that every rule refers to a side and trait that exist
+func (r Rules) Validate() error {
+	if r.Version != RulesVersion {
+		return fmt.Errorf("Rules version %d isn't supported, expected %d", r.Version, RulesVersion)
+	}
+	if _, ok := r.RuleSets[r.Default]; !ok {
+		return fmt.Errorf("Default rule set %s doesn't exist", r.Default)
+	}
+	for season, name := range r.Seasons {
+		if _, ok := r.RuleSets[name]; !ok {
+			return fmt.Errorf("Rule set %s for season %s doesn't exist", name, season)
+		}
+	}
+
+	for name, ruleSet := range r.RuleSets {
+		if ruleSet.Randomness < 0 {
+			return fmt.Errorf("Rule set %s has negative randomness", name)
+		}
+		for _, matchup := range ruleSet.Matchups {
+			if !validTrait(matchup.Side, matchup.Trait) || !validTrait(opponent(matchup.Side), matchup.OpponentTrait) {
+				return fmt.Errorf("Rule set %s has a matchup for unknown traits %s %s vs %s", name, matchup.Side, matchup.Trait, matchup.OpponentTrait)
+			}
+			if matchup.Value == "" || matchup.OpponentValue == "" {
+				return fmt.Errorf("Rule set %s has a matchup for %s vs %s without values", name, matchup.Trait, matchup.OpponentTrait)
+			}
+		}
+		for _, homeField := range ruleSet.HomeField {
+			if !validTrait(homeField.Side, "background") || homeField.Background == "" {
+				return fmt.Errorf("Rule set %s has an invalid home field for %s %s", name, homeField.Side, homeField.Background)
+			}
+		}
+		if fatigue := ruleSet.Fatigue; fatigue != nil {
+			if fatigue.WindowMinutes <= 0 {
+				return fmt.Errorf("Rule set %s has a fatigue window of %d minutes", name, fatigue.WindowMinutes)
+			}
+			if fatigue.PerFight > 0 || fatigue.Max > 0 {
+				return fmt.Errorf("Rule set %s has a positive fatigue modifier, fatigue can only weaken", name)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Window how far back earlier fights count toward fatigue
+func (f Fatigue) Window() time.Duration {
+	return time.Duration(f.WindowMinutes) * time.Minute
+}
+
+// Modifier the fatigue of a fighter with fights earlier fights in the window, capped at max when it's set
+func (f Fatigue) Modifier(fights int) int {
+	modifier := fights * f.PerFight
+	if f.Max != 0 && modifier < f.Max {
+		return f.Max
+	}
+	return modifier
+}
+
+// RuleSet the rule set for a season, the default one outside of a season or for a season without its own
+func (r Rules) RuleSet(season string) (string, RuleSet) {
+	name, ok := r.Seasons[season]
+	if !ok {
+		name = r.Default
+	}
+	return name, r.RuleSets[name]
+}
+
+// FightZombieAndHunterReturnStrength strengths of both fighters under a season's rules with the modifiers that made them,
+// recentFights is how many times each side fought within the rule set's fatigue window and luck is drawn from the
+// fight's rand
+func FightZombieAndHunterReturnStrength(zombieName string, hunterName string, zombieMeta map[string]ZombieChain, hunterMeta map[string]ZombieHunter, zcStrengthCalc ZombieChainTraitStrength, zhStrengthCalc ZombieHunterTraitStrength, rules Rules, season string, recentFights map[string]int, rnd *Rand) (int, int, Breakdown) {
+	name, ruleSet := rules.RuleSet(season)
+	breakdown := Breakdown{RulesVersion: rules.Version, RuleSet: name, Modifiers: make([]Modifier, 0)}
+
+	zombieTraits := zombieMeta[zombieName].traits()
+	hunterTraits := hunterMeta[hunterName].traits()
+	strengths := map[string]map[string]map[string]TraitStrength{
+		SideZombie: zcStrengthCalc.byTrait(),
+		SideHunter: zhStrengthCalc.byTrait(),
+	}
+	traits := map[string]map[string]string{SideZombie: zombieTraits, SideHunter: hunterTraits}
+
+	add := func(side string, source string, value int, description string, args ...interface{}) {
+		if value == 0 && source != "traits" {
+			return
+		}
+		breakdown.Modifiers = append(breakdown.Modifiers, Modifier{Side: side, Source: source, Description: fmt.Sprintf(description, args...), Value: value})
+	}
+
+	for _, side := range []string{SideZombie, SideHunter} {
+		base := 0
+		for _, trait := range sortedTraits(traits[side]) {
+			base += strengths[side][trait][traits[side][trait]].Strength
+		}
+		add(side, "traits", base, "Trait strengths of %s", map[string]string{SideZombie: zombieName, SideHunter: hunterName}[side])
+
+		// weaknesses from the trait csv, each weak trait counts once
+		for _, trait := range sortedTraits(traits[side]) {
+			value := traits[side][trait]
+			weakness := strengths[side][trait][value].Weakness
+			if weakness == "" {
+				continue
+			}
+			for _, opponentTrait := range sortedTraits(traits[opponent(side)]) {
+				if opponentValue := traits[opponent(side)][opponentTrait]; opponentValue == weakness {
+					add(side, "weakness", ruleSet.WeaknessModifier, "%s %s is weak to %s %s", trait, value, opponentTrait, opponentValue)
+					break
+				}
+			}
+		}
+
+		for _, matchup := range ruleSet.Matchups {
+			if matchup.Side == side && traits[side][matchup.Trait] == matchup.Value && traits[opponent(side)][matchup.OpponentTrait] == matchup.OpponentValue {
+				add(side, "matchup", matchup.Modifier, "%s %s vs %s %s", matchup.Trait, matchup.Value, matchup.OpponentTrait, matchup.OpponentValue)
+			}
+		}
+
+		for _, homeField := range ruleSet.HomeField {
+			if homeField.Side == side && traits[side]["background"] == homeField.Background {
+				add(side, "homeField", homeField.Modifier, "Fighting at home on %s", homeField.Background)
+			}
+		}
+
+		if ruleSet.Fatigue != nil && recentFights[side] > 0 {
+			add(side, "fatigue", ruleSet.Fatigue.Modifier(recentFights[side]), "%d fights in the last %d minutes", recentFights[side], ruleSet.Fatigue.WindowMinutes)
+		}
+	}
+
+	// noise last so it's applied the same way as before rules
+	for _, modifier := range breakdown.Modifiers {
+		if modifier.Side == SideZombie {
+			breakdown.ZombieStrength += modifier.Value
+		} else {
+			breakdown.HunterStrength += modifier.Value
+		}
+	}
+	zombieRandom := alterRandomly(breakdown.ZombieStrength, ruleSet.Randomness, rnd) - breakdown.ZombieStrength
+	add(SideZombie, "random", zombieRandom, "Luck")
+	hunterRandom := alterRandomly(breakdown.HunterStrength, ruleSet.Randomness, rnd) - breakdown.HunterStrength
+	add(SideHunter, "random", hunterRandom, "Luck")
+	breakdown.ZombieStrength += zombieRandom
+	breakdown.HunterStrength += hunterRandom
+
+	return breakdown.ZombieStrength, breakdown.HunterStrength, breakdown
+}
+
+func sortedTraits(traits map[string]string) []string {
+	names := make([]string, 0, len(traits))
+	for name := range traits {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func opponent(side string) string {
+	if side == SideZombie {
+		return SideHunter
+	}
+	return SideZombie
+}
+
+func validTrait(side string, trait string) bool {
+	if side == SideZombie {
+		_, ok := ZombieChain{}.traits()[trait]
+		return ok
+	}
+	if side == SideHunter {
+		_, ok := ZombieHunter{}.traits()[trait]
+		return ok
+	}
+	return false
+}
+
+// traits by the trait names used in the csv and rules files
+func (zc ZombieChain) traits() map[string]string {
+	return map[string]string{
+		"background": zc.Background,
+		"hat":        zc.Hat,
+		"eyes":       zc.Eyes,
+		"nose":       zc.Nose,
+		"skin":       zc.Skin,
+		"mouth":      zc.Mouth,
+		"chain":      zc.Chain,
+		"weapon":     zc.Weapon,
+		"clothing":   zc.Clothing,
+		"earrings":   zc.Earrings,
+	}
+}
+
+// CountWeaknesses how many trait values in the trait csvs name a weakness. The shipped csvs leave the column empty, so
+// a weaknessModifier does nothing until weaknesses are filled in
+func CountWeaknesses(zcStrength ZombieChainTraitStrength, zhStrength ZombieHunterTraitStrength) int {
+	count := 0
+	for _, byTrait := range []map[string]map[string]TraitStrength{zcStrength.byTrait(), zhStrength.byTrait()} {
+		for _, values := range byTrait {
+			for _, strength := range values {
+				if strength.Weakness != "" {
+					count++
+				}
+			}
+		}
+	}
+	return count
+}
+
+func (s ZombieChainTraitStrength) byTrait() map[string]map[string]TraitStrength {
+	return map[string]map[string]TraitStrength{
+		"background": s.Background,
+		"hat":        s.Hat,
+		"eyes":       s.Eyes,
+		"nose":       s.Nose,
+		"skin":       s.Skin,
+		"mouth":      s.Mouth,
+		"chain":      s.Chain,
+		"weapon":     s.Weapon,
+		"clothing":   s.Clothing,
+		"earrings":   s.Earrings,
+	}
+}
+
+// traits by the trait names used in the csv and rules files
+func (zh ZombieHunter) traits() map[string]string {
+	return map[string]string{
+		"background":   zh.Background,
+		"gender":       zh.Gender,
+		"hat":          zh.Hat,
+		"eyes":         zh.Eyes,
+		"skin":         zh.Skin,
+		"mouth":        zh.Mouth,
+		"chain":        zh.Chain,
+		"left-weapon":  zh.LeftWeapon,
+		"right-weapon": zh.RightWeapon,
+		"clothing":     zh.Clothing,
+		"earrings":     zh.Earrings,
+		"loot":         zh.Loot,
+	}
+}
+
+func (s ZombieHunterTraitStrength) byTrait() map[string]map[string]TraitStrength {
+	return map[string]map[string]TraitStrength{
+		"background":   s.Background,
+		"gender":       s.Gender,
+		"hat":          s.Hat,
+		"eyes":         s.Eyes,
+		"skin":         s.Skin,
+		"mouth":        s.Mouth,
+		"chain":        s.Chain,
+		"left-weapon":  s.LeftWeapon,
+		"right-weapon": s.RightWeapon,
+		"clothing":     s.Clothing,
+		"earrings":     s.Earrings,
+		"loot":         s.Loot,
+	}
+}
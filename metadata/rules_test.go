@@ -0,0 +1,139 @@
+package metadata
+
+import (
+	"os"
+	"testing"
+)
+
+func TestFightZombieAndHunterReturnStrength(t *testing.T) {
+	zombieMeta := map[string]ZombieChain{"ZombieChains00001": {Background: "Moon", Weapon: "Sword"}}
+	hunterMeta := map[string]ZombieHunter{"ZombieHunter00001": {Background: "Rainbow", RightWeapon: "Shield", Loot: "Garlic"}}
+	zcStrength := ZombieChainTraitStrength{
+		Background: map[string]TraitStrength{"Moon": {Name: "Moon", Strength: 5}},
+		Weapon:     map[string]TraitStrength{"Sword": {Name: "Sword", Strength: 20, Weakness: "Garlic"}},
+	}
+	zhStrength := ZombieHunterTraitStrength{
+		Background:  map[string]TraitStrength{"Rainbow": {Name: "Rainbow", Strength: 15}},
+		RightWeapon: map[string]TraitStrength{"Shield": {Name: "Shield", Strength: 10}},
+	}
+
+	rules := Rules{
+		Version: RulesVersion,
+		Default: "base",
+		Seasons: map[string]string{"1": "moon"},
+		RuleSets: map[string]RuleSet{
+			"base": {},
+			"moon": {
+				WeaknessModifier: -8,
+				Matchups:         []Matchup{{Side: SideHunter, Trait: "right-weapon", Value: "Shield", OpponentTrait: "weapon", OpponentValue: "Sword", Modifier: 6}},
+				HomeField:        []HomeField{{Side: SideZombie, Background: "Moon", Modifier: 4}},
+			},
+		},
+	}
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("Expected rules to be valid %v", err)
+	}
+
+	zombie, hunter, breakdown := FightZombieAndHunterReturnStrength("ZombieChains00001", "ZombieHunter00001", zombieMeta, hunterMeta, zcStrength, zhStrength, rules, "", nil, NewRand(nil))
+	if zombie != 25 || hunter != 25 || breakdown.RuleSet != "base" || len(breakdown.Modifiers) != 2 {
+		t.Errorf("Expected plain trait strengths outside of a season but got %d / %d %v", zombie, hunter, breakdown)
+	}
+
+	// 25 - 8 weak to garlic + 4 at home vs 25 + 6 shield against sword
+	zombie, hunter, breakdown = FightZombieAndHunterReturnStrength("ZombieChains00001", "ZombieHunter00001", zombieMeta, hunterMeta, zcStrength, zhStrength, rules, "1", nil, NewRand(nil))
+	if zombie != 21 || hunter != 31 || breakdown.ZombieStrength != zombie || breakdown.HunterStrength != hunter {
+		t.Errorf("Expected 21 / 31 but got %d / %d", zombie, hunter)
+	}
+	sources := make([]string, 0)
+	for _, modifier := range breakdown.Modifiers {
+		sources = append(sources, modifier.Side+" "+modifier.Source)
+	}
+	expected := []string{"zombie traits", "zombie weakness", "zombie homeField", "hunter traits", "hunter matchup"}
+	if len(sources) != len(expected) {
+		t.Fatalf("Expected modifiers %v but got %v", expected, sources)
+	}
+	for i := range expected {
+		if sources[i] != expected[i] {
+			t.Errorf("Expected modifiers %v but got %v", expected, sources)
+			break
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	fileName := t.TempDir() + "/rules.json"
+	os.WriteFile(fileName, []byte(`{"version":1,"default":"base","ruleSets":{"base":{"randomness":120,
+		"matchups":[{"side":"zombie","trait":"weapon","value":"Sword","opponentTrait":"left-weapon","opponentValue":"Axe","modifier":-5}]}}}`), 0644)
+
+	rules, err := LoadRules(fileName)
+	if err != nil {
+		t.Fatalf("Error loading rules %v", err)
+	}
+	if _, ruleSet := rules.RuleSet("unknown season"); ruleSet.Randomness != 120 || len(ruleSet.Matchups) != 1 {
+		t.Errorf("Expected the default rule set but got %v", ruleSet)
+	}
+
+	invalid := []string{
+		`{"version":2,"default":"base","ruleSets":{"base":{}}}`,
+		`{"version":1,"default":"missing","ruleSets":{"base":{}}}`,
+		`{"version":1,"default":"base","seasons":{"1":"missing"},"ruleSets":{"base":{}}}`,
+		`{"version":1,"default":"base","ruleSets":{"base":{"matchups":[{"side":"zombie","trait":"loot","value":"Garlic","opponentTrait":"hat","opponentValue":"Cap"}]}}}`,
+		`{"version":1,"default":"base","ruleSets":{"base":{"fatigue":{"perFight":-5}}}}`,
+		`{"version":1,"default":"base","ruleSets":{"base":{"fatigue":{"windowMinutes":60,"perFight":5}}}}`,
+	}
+	for _, content := range invalid {
+		os.WriteFile(fileName, []byte(content), 0644)
+		if _, err := LoadRules(fileName); err == nil {
+			t.Errorf("Expected error loading %s", content)
+		}
+	}
+}
+
+func TestCountWeaknesses(t *testing.T) {
+	zcStrength, err := LoadZombieChainsFightStrength(".")
+	if err != nil {
+		t.Fatalf("Error loading zc strength %v", err)
+	}
+	zhStrength, err := LoadZombieHunterFightStrength(".")
+	if err != nil {
+		t.Fatalf("Error loading zh strength %v", err)
+	}
+	if count := CountWeaknesses(*zcStrength, *zhStrength); count != 0 {
+		t.Errorf("Expected the shipped csvs without weaknesses but found %d, update the readme", count)
+	}
+
+	zcStrength.Weapon["Sword"] = TraitStrength{Name: "Sword", Strength: 20, Weakness: "Garlic"}
+	if count := CountWeaknesses(*zcStrength, *zhStrength); count != 1 {
+		t.Errorf("Expected one weakness but found %d", count)
+	}
+}
+
+func TestFatigue(t *testing.T) {
+	zombieMeta := map[string]ZombieChain{"ZombieChains00001": {Weapon: "Sword"}}
+	hunterMeta := map[string]ZombieHunter{"ZombieHunter00001": {RightWeapon: "Shield"}}
+	zcStrength := ZombieChainTraitStrength{Weapon: map[string]TraitStrength{"Sword": {Name: "Sword", Strength: 50}}}
+	zhStrength := ZombieHunterTraitStrength{RightWeapon: map[string]TraitStrength{"Shield": {Name: "Shield", Strength: 50}}}
+	rules := Rules{
+		Version:  RulesVersion,
+		Default:  "base",
+		RuleSets: map[string]RuleSet{"base": {Fatigue: &Fatigue{WindowMinutes: 60, PerFight: -4, Max: -10}}},
+	}
+	if err := rules.Validate(); err != nil {
+		t.Fatalf("Expected rules to be valid %v", err)
+	}
+
+	// the zombie's 5 earlier fights are capped at -10, the rested hunter isn't touched
+	zombie, hunter, breakdown := FightZombieAndHunterReturnStrength("ZombieChains00001", "ZombieHunter00001", zombieMeta, hunterMeta, zcStrength, zhStrength, rules, "",
+		map[string]int{SideZombie: 5}, NewRand(nil))
+	if zombie != 40 || hunter != 50 {
+		t.Errorf("Expected 40 / 50 but got %d / %d", zombie, hunter)
+	}
+	last := breakdown.Modifiers[len(breakdown.Modifiers)-1]
+	if len(breakdown.Modifiers) != 3 || breakdown.Modifiers[1].Source != "fatigue" || breakdown.Modifiers[1].Value != -10 || last.Side != SideHunter {
+		t.Errorf("Expected a zombie fatigue modifier but got %v", breakdown.Modifiers)
+	}
+
+	if modifier := (Fatigue{WindowMinutes: 60, PerFight: -4, Max: -10}).Modifier(2); modifier != -8 {
+		t.Errorf("Expected -8 for two fights but got %d", modifier)
+	}
+}
@@ -3,7 +3,6 @@ package metadata
 import (
 	"encoding/csv"
 	"io"
-	"math/rand"
 	"os"
 	"strconv"
 
@@ -171,6 +170,9 @@ func LoadZombieChainsFightStrength(path string) (*ZombieChainTraitStrength, erro
 			Name:     record[1],
 			Strength: strength,
 		}
+		if len(record) > 4 {
+			traitStrength.Weakness = record[4]
+		}
 
 		traitType := record[0]
 		if traitType == "background" {
@@ -299,6 +301,9 @@ func LoadZombieHunterFightStrength(path string) (*ZombieHunterTraitStrength, err
 			Name:     record[1],
 			Strength: strength,
 		}
+		if len(record) > 4 {
+			traitStrength.Weakness = record[4]
+		}
 
 		traitType := record[0]
 		if traitType == "background" {
@@ -330,7 +335,7 @@ func LoadZombieHunterFightStrength(path string) (*ZombieHunterTraitStrength, err
 }
 
 //TODO: error is strength is 0
-func determineZcStrenth(zc ZombieChain, strength ZombieChainTraitStrength, randomness int) int {
+func determineZcStrenth(zc ZombieChain, strength ZombieChainTraitStrength, randomness int, rnd *Rand) int {
 	background := strength.Background[zc.Background].Strength
 	chain := strength.Chain[zc.Chain].Strength
 	clothing := strength.Clothing[zc.Clothing].Strength
@@ -343,10 +348,10 @@ func determineZcStrenth(zc ZombieChain, strength ZombieChainTraitStrength, rando
 	weapon := strength.Weapon[zc.Weapon].Strength
 
 	power := background + chain + clothing + earring + eyes + hat + mouth + nose + skin + weapon
-	return alterRandomly(power, randomness)
+	return alterRandomly(power, randomness, rnd)
 }
 
-func determineZhStrength(zc ZombieHunter, strength ZombieHunterTraitStrength, randomness int) int {
+func determineZhStrength(zc ZombieHunter, strength ZombieHunterTraitStrength, randomness int, rnd *Rand) int {
 	background := strength.Background[zc.Background].Strength
 	chain := strength.Chain[zc.Chain].Strength
 	clothing := strength.Clothing[zc.Clothing].Strength
@@ -362,12 +367,12 @@ func determineZhStrength(zc ZombieHunter, strength ZombieHunterTraitStrength, ra
 	//logrus.Infof("B: %d, C: %d, C: %d, E: %d, E: %d, H: %d, M: %d, LW: %d, S: %d, RW: %d, L: %d", background, chain, clothing, earring, eyes, hat, mouth, leftWeapon, skin, rightWeapon, loot)
 
 	power := background + chain + clothing + earring + eyes + hat + mouth + leftWeapon + skin + rightWeapon + loot
-	return alterRandomly(power, randomness)
+	return alterRandomly(power, randomness, rnd)
 }
 
-func fightZombieAndHunter(zombieName string, hunterName string, zombieMeta map[string]ZombieChain, hunterMeta map[string]ZombieHunter, zcStrengthCalc ZombieChainTraitStrength, zhStrengthCalc ZombieHunterTraitStrength, randomness int) (bool, bool, int) {
-	zcStrength := determineZcStrenth(zombieMeta[zombieName], zcStrengthCalc, randomness)
-	zhStrength := determineZhStrength(hunterMeta[hunterName], zhStrengthCalc, randomness)
+func fightZombieAndHunter(zombieName string, hunterName string, zombieMeta map[string]ZombieChain, hunterMeta map[string]ZombieHunter, zcStrengthCalc ZombieChainTraitStrength, zhStrengthCalc ZombieHunterTraitStrength, randomness int, rnd *Rand) (bool, bool, int) {
+	zcStrength := determineZcStrenth(zombieMeta[zombieName], zcStrengthCalc, randomness, rnd)
+	zhStrength := determineZhStrength(hunterMeta[hunterName], zhStrengthCalc, randomness, rnd)
 	logrus.Infof("Fighting %s with strength %d vs %s with strenth %d", zombieName, zcStrength, hunterName, zhStrength)
 
 	if zcStrength >= zhStrength {
@@ -377,21 +382,13 @@ func fightZombieAndHunter(zombieName string, hunterName string, zombieMeta map[s
 	}
 }
 
-func FightZombieAndHunterReturnStrength(zombieName string, hunterName string, zombieMeta map[string]ZombieChain, hunterMeta map[string]ZombieHunter, zcStrengthCalc ZombieChainTraitStrength, zhStrengthCalc ZombieHunterTraitStrength, randomness int) (int, int) {
-	zcStrength := determineZcStrenth(zombieMeta[zombieName], zcStrengthCalc, randomness)
-	zhStrength := determineZhStrength(hunterMeta[hunterName], zhStrengthCalc, randomness)
-	logrus.Infof("Fighting %s with strength %d vs %s with strenth %d", zombieName, zcStrength, hunterName, zhStrength)
-
-	return zcStrength, zhStrength
-}
-
-func alterRandomly(value int, randomness int) int {
+func alterRandomly(value int, randomness int, rnd *Rand) int {
 	// nothing to do if no randomness
 	if randomness == 0 {
 		return value
 	}
 
-	randValue := rand.Intn(randomness)
+	randValue := rnd.Intn(randomness)
 	randValue = randValue - (randomness / 2)
 	updatedValue := value + randValue
 
@@ -399,35 +396,3 @@ func alterRandomly(value int, randomness int) int {
 
 	return updatedValue
 }
-
-func DetermineLifeBar(pointDifference int) (int, int, bool) {
-	//some randonness
-	if pointDifference > 140 {
-		return 100, 0, true
-	} else if pointDifference > 120 {
-		return pickRandom(9, 7), pickRandom(2, 1), true
-	} else if pointDifference > 100 {
-		return pickRandom(9, 7), pickRandom(2, 1), false
-	} else if pointDifference > 90 {
-		return pickRandom(8, 6), pickRandom(3, 1), false
-	} else if pointDifference > 80 {
-		return pickRandom(8, 6), pickRandom(4, 2), false
-	} else if pointDifference > 70 {
-		return pickRandom(7, 4), pickRandom(3, 2), false
-	} else if pointDifference > 55 {
-		return pickRandom(7, 4), pickRandom(3, 2), false
-	} else if pointDifference > 44 {
-		return pickRandom(7, 4), pickRandom(3, 2), false
-	} else if pointDifference > 25 {
-		return pickRandom(6, 5), pickRandom(4, 3), false
-	} else if pointDifference > 13 {
-		return 50, 40, false
-	} else {
-		return 60, 50, false
-	}
-}
-
-func pickRandom(max int, min int) int {
-	randomNumber := rand.Intn(max-min) + min
-	return randomNumber * 10
-}
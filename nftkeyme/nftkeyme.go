@@ -65,7 +65,7 @@ func (client NftkeymeClient) GetAssetsForUser(token string, policyID string) ([]
 
 	resp, err := client.HttpClient.Do(req)
 	if err != nil {
-		logrus.Errorf("Error posting request", err)
+		logrus.WithError(err).Error("Error posting request")
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -102,7 +102,7 @@ func (client NftkeymeClient) GetUserInfo(token string) (*UserInfo, error) {
 
 	resp, err := client.HttpClient.Do(req)
 	if err != nil {
-		logrus.Errorf("Error posting request", err)
+		logrus.WithError(err).Error("Error posting request")
 		return nil, err
 	}
 	defer resp.Body.Close()
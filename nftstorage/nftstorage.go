@@ -62,7 +62,7 @@ func (client NftstorageClient) IpfsAdd(filePath string) (*StoreResponse, error)
 
 	resp, err := client.HttpClient.Do(req)
 	if err != nil {
-		logrus.Errorf("Error posting request", err)
+		logrus.WithError(err).Error("Error posting request")
 		return nil, err
 	}
 	defer resp.Body.Close()
@@ -0,0 +1,795 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/imagebuilder"
+	"github.com/reliablestaking/zombie-fight-club-server/metadata"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// the minter's name in the engine heartbeats
+	mintingEngine = "minter"
+	// an engine that hasn't had a good pass in this long isn't healthy
+	engineStaleAfter = 5 * time.Minute
+
+	// handlers put what they did here for the audit log
+	auditDetailKey = "auditDetail"
+
+	defaultAdminLimit = 50
+	maxAdminLimit     = 500
+)
+
+type (
+	// AdminFight a fight as operators see it, with the status the engine has it in
+	AdminFight struct {
+		ID                    int        `json:"id"`
+		Status                string     `json:"status"`
+		ZombieName            string     `json:"zombieName"`
+		HunterName            string     `json:"hunterName"`
+		CreatedDate           time.Time  `json:"createdDate"`
+		MintedDate            *time.Time `json:"mintedDate,omitempty"`
+		PaymentAddress        string     `json:"paymentAddress"`
+		PaymentAmountLovelace int64      `json:"paymentAmountLovelace"`
+		IncomingUtxo          string     `json:"incomingUtxo,omitempty"`
+		ZombieSendAddress     string     `json:"zombieSendAddress,omitempty"`
+		HunterSendAddress     string     `json:"hunterSendAddress,omitempty"`
+		SignedTxHash          string     `json:"signedTxHash,omitempty"`
+		TxID                  string     `json:"txId,omitempty"`
+		TournamentID          *int64     `json:"tournamentId,omitempty"`
+		RegenerateImages      bool       `json:"regenerateImages"`
+	}
+
+	// AdminFightAction why an operator is acting on a fight
+	AdminFightAction struct {
+		Reason string `json:"reason"`
+	}
+
+	// AdminRefund a utxo to send back to whoever sent it, the address index is needed for a fight's own address
+	AdminRefund struct {
+		TxHash              string `json:"txHash"`
+		OutputIndex         int    `json:"outputIndex"`
+		PaymentAddress      string `json:"paymentAddress"`
+		PaymentAddressIndex *int64 `json:"paymentAddressIndex,omitempty"`
+	}
+
+	// AdminFightMetadata the metadata the mint tx has, or will have, for the fight and its alien
+	AdminFightMetadata struct {
+		Fight json.RawMessage `json:"fight"`
+		Alien json.RawMessage `json:"alien"`
+	}
+
+	// AdminUserRole a user's role on the admin api, empty for players
+	AdminUserRole struct {
+		UserID int         `json:"userId"`
+		Role   db.UserRole `json:"role"`
+	}
+
+	// AdminAudit one change made through the admin api
+	AdminAudit struct {
+		ID          int       `json:"id"`
+		UserID      int       `json:"userId"`
+		Action      string    `json:"action"`
+		Target      string    `json:"target,omitempty"`
+		Detail      string    `json:"detail,omitempty"`
+		StatusCode  int       `json:"statusCode"`
+		CreatedDate time.Time `json:"createdDate"`
+	}
+
+	// EngineHealth whether the engines are running and how much work is waiting on them
+	EngineHealth struct {
+		Healthy          bool           `json:"healthy"`
+		Engines          []EngineStatus `json:"engines"`
+		Fights           map[string]int `json:"fights"`
+		Refunds          map[string]int `json:"refunds"`
+		OldestQueuedDate *time.Time     `json:"oldestQueuedDate,omitempty"`
+		OldestStagedDate *time.Time     `json:"oldestStagedDate,omitempty"`
+		OldestMintedDate *time.Time     `json:"oldestMintedDate,omitempty"`
+	}
+
+	// EngineStatus an engine's latest pass, it's healthy if it had a good pass within engineStaleAfter
+	EngineStatus struct {
+		Engine              string     `json:"engine"`
+		Healthy             bool       `json:"healthy"`
+		LastPassDate        time.Time  `json:"lastPassDate"`
+		LastSuccessDate     *time.Time `json:"lastSuccessDate,omitempty"`
+		ConsecutiveFailures int        `json:"consecutiveFailures"`
+	}
+)
+
+// RequireRole turns away users without at least the role, it goes after CheckCookie
+func (s Server) RequireRole(role db.UserRole) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			dbUser, _ := c.Get("user").(*db.User)
+			if dbUser == nil || !dbUser.Role.Allows(role) {
+				return echo.NewHTTPError(http.StatusForbidden, fmt.Sprintf("Needs the %s role", role))
+			}
+			return next(c)
+		}
+	}
+}
+
+// AuditAdmin writes every change made through the admin api to the audit log, the ones turned away too. Reads
+// aren't logged. The audit is written before the change is made so nothing changes that isn't in the log, a change
+// that can't be audited isn't made
+func (s Server) AuditAdmin(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		if c.Request().Method == http.MethodGet {
+			return next(c)
+		}
+
+		dbUser, _ := c.Get("user").(*db.User)
+		if dbUser == nil {
+			return next(c)
+		}
+		log := logrus.WithContext(c.Request().Context())
+
+		audit := db.AdminAudit{
+			UserID: dbUser.ID,
+			Action: c.Request().Method + " " + c.Path(),
+		}
+		targets := make([]string, 0)
+		for i, name := range c.ParamNames() {
+			targets = append(targets, fmt.Sprintf("%s=%s", name, c.ParamValues()[i]))
+		}
+		audit.Target = strings.Join(targets, ",")
+
+		auditID, err := s.Store.InsertAdminAudit(c.Request().Context(), audit)
+		if err != nil {
+			log.WithError(err).Errorf("Error writing audit of %s by user %d", audit.Action, dbUser.ID)
+			return s.RenderError("Error writing audit log", c)
+		}
+
+		err = next(c)
+
+		statusCode := c.Response().Status
+		detail, _ := c.Get(auditDetailKey).(string)
+		var httpErr *echo.HTTPError
+		if errors.As(err, &httpErr) {
+			statusCode = httpErr.Code
+			if detail == "" {
+				detail = fmt.Sprint(httpErr.Message)
+			}
+		} else if err != nil {
+			statusCode = http.StatusInternalServerError
+		}
+
+		// the answer has gone out by now, the change is already in the log without it
+		if auditErr := s.Store.FinishAdminAudit(c.Request().Context(), auditID, statusCode, detail); auditErr != nil {
+			log.WithError(auditErr).Errorf("Error recording the answer to audit %d", auditID)
+		}
+
+		return err
+	}
+}
+
+// GetAdminFights list fights in a status, oldest first
+func (s Server) GetAdminFights(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	status := db.FightStatus(strings.ToUpper(c.QueryParam("status")))
+	if !validFightStatus(status) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown fight status")
+	}
+	limit, err := adminLimit(c)
+	if err != nil {
+		return err
+	}
+
+	fights, err := s.Store.GetFightsByStatus(status, limit)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting %s fights", status)
+		return s.RenderError("Error getting fights", c)
+	}
+
+	adminFights := make([]AdminFight, 0)
+	for _, fight := range fights {
+		adminFights = append(adminFights, adminFight(fight))
+	}
+
+	return c.JSON(http.StatusOK, adminFights)
+}
+
+// RetryFight gets a fight the minter is stuck on going again. A staged fight's signed mint tx is dropped once it's past its
+// ttl so a new one is built, unless it's on chain already, and a minted fight is confirmed once its tx is on chain
+func (s Server) RetryFight(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+	ctx := c.Request().Context()
+
+	fight, err := s.adminFightParam(c)
+	if err != nil {
+		return err
+	}
+
+	switch fight.Status {
+	case db.FightStatusStaged:
+		if !fight.SignedTxHash.Valid {
+			return echo.NewHTTPError(http.StatusConflict, "The minter builds a mint tx for the fight every pass")
+		}
+		txHash := fight.SignedTxHash.String
+		confirmed, err := s.Chain.TransactionConfirmed(ctx, txHash)
+		if err != nil {
+			log.WithError(err).Errorf("Error checking tx %s", txHash)
+			return s.RenderError("Error checking mint tx", c)
+		}
+		if confirmed {
+			c.Set(auditDetailKey, fmt.Sprintf("Signed tx %s is on chain, moved to minted", txHash))
+			err = s.Store.MoveFightFromStagedToMinted(ctx, fight.ID, txHash)
+		} else {
+			// like the minter, only a tx past its ttl can't land anymore, dropping a live one could mint twice
+			tip, tipErr := s.Chain.Tip(ctx)
+			if tipErr != nil {
+				log.WithError(tipErr).Errorf("Error getting tip")
+				return s.RenderError("Error checking mint tx", c)
+			}
+			if tip.Slot <= int(fight.SignedTxTTL.Int64)+signedTxExpiryMargin {
+				return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Signed tx %s can still land until slot %d", txHash, fight.SignedTxTTL.Int64+signedTxExpiryMargin))
+			}
+			c.Set(auditDetailKey, fmt.Sprintf("Dropped expired signed tx %s", txHash))
+			err = s.Store.ClearSignedMintTx(ctx, fight.ID, txHash)
+		}
+		if err != nil {
+			log.WithError(err).Errorf("Error retrying fight %d", fight.ID)
+			return s.RenderError("Error retrying fight", c)
+		}
+	case db.FightStatusMinted:
+		txHash := strings.ReplaceAll(fight.TxID.String, "\"", "")
+		confirmed, err := s.Chain.TransactionConfirmed(ctx, txHash)
+		if err != nil {
+			log.WithError(err).Errorf("Error checking tx %s", txHash)
+			return s.RenderError("Error checking mint tx", c)
+		}
+		if !confirmed {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Mint tx %s isn't on chain", txHash))
+		}
+		c.Set(auditDetailKey, fmt.Sprintf("Mint tx %s is on chain, moved to confirmed", txHash))
+		err = s.Store.MoveFightFromMintedToConfirmed(ctx, fight.ID)
+		if err != nil {
+			log.WithError(err).Errorf("Error confirming fight %d", fight.ID)
+			return s.RenderError("Error retrying fight", c)
+		}
+	default:
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("Nothing to retry for a %s fight", strings.ToLower(string(fight.Status))))
+	}
+
+	return s.renderAdminFight(c, fight.ID)
+}
+
+// CancelFight voids a pending or queued fight, its alien goes back to the pool and whatever paid for it is refunded
+func (s Server) CancelFight(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	fight, err := s.adminFightParam(c)
+	if err != nil {
+		return err
+	}
+	action := new(AdminFightAction)
+	if err = c.Bind(action); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if strings.TrimSpace(action.Reason) == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "A reason is needed to cancel a fight")
+	}
+	if fight.TournamentID.Valid {
+		return echo.NewHTTPError(http.StatusConflict, "Tournament fights are played out by the bracket")
+	}
+
+	dbUser := c.Get("user").(*db.User)
+	c.Set(auditDetailKey, action.Reason)
+	err = s.Store.VoidFight(c.Request().Context(), fight.ID, db.FightActorAPI, fmt.Sprintf("Cancelled by user %d: %s", dbUser.ID, action.Reason))
+	if err != nil {
+		var illegal db.IllegalTransitionError
+		if errors.As(err, &illegal) {
+			return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("A %s fight can't be cancelled", strings.ToLower(string(illegal.From))))
+		}
+		log.WithError(err).Errorf("Error cancelling fight %d", fight.ID)
+		return s.RenderError("Error cancelling fight", c)
+	}
+
+	return s.renderAdminFight(c, fight.ID)
+}
+
+// RegenerateFightImages asks the minter to rebuild a staged fight's images with the recorded outcome before it's
+// minted
+func (s Server) RegenerateFightImages(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	fight, err := s.adminFightParam(c)
+	if err != nil {
+		return err
+	}
+
+	requested, err := s.Store.RequestImageRegeneration(c.Request().Context(), fight.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Error requesting images for fight %d", fight.ID)
+		return s.RenderError("Error requesting images", c)
+	}
+	if !requested {
+		return echo.NewHTTPError(http.StatusConflict, "Only staged fights without a signed mint tx get new images")
+	}
+
+	return s.renderAdminFightStatus(c, http.StatusAccepted, fight.ID)
+}
+
+// GetFightMetadata rebuilds the metadata of the fight and its alien the way the mint tx has it
+func (s Server) GetFightMetadata(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	fight, err := s.adminFightParam(c)
+	if err != nil {
+		return err
+	}
+	if !fight.IPFS.Valid {
+		return echo.NewHTTPError(http.StatusConflict, "The fight hasn't been fought")
+	}
+
+	alien, err := s.Store.GetAlienByFightId(fight.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting alien of fight %d", fight.ID)
+		return s.RenderError("Error getting alien", c)
+	}
+	if alien == nil {
+		return echo.NewHTTPError(http.StatusConflict, "The fight has no alien")
+	}
+	number, err := alienNumber(*alien)
+	if err != nil {
+		log.WithError(err).Errorf("Invalid alien name %s", alien.Name)
+		return s.RenderError("Error building metadata", c)
+	}
+
+	fightMeta, err := buildFightMetaString(*fight, number)
+	if err != nil {
+		return s.RenderError("Error building metadata", c)
+	}
+	alienMeta, err := buildAlienMetaString(*alien)
+	if err != nil {
+		return s.RenderError("Error building metadata", c)
+	}
+
+	return c.JSON(http.StatusOK, AdminFightMetadata{
+		Fight: json.RawMessage("{" + fightMeta + "}"),
+		Alien: json.RawMessage("{" + alienMeta + "}"),
+	})
+}
+
+// ForceRefund owes a utxo at a payment address back to its sender. A utxo that paid for a fight can only be refunded
+// once the fight is void, tournament entries and wager deposits never
+func (s Server) ForceRefund(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+	ctx := c.Request().Context()
+
+	request := new(AdminRefund)
+	if err = c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if request.TxHash == "" || request.PaymentAddress == "" {
+		return echo.NewHTTPError(http.StatusBadRequest, "A tx hash and payment address are needed")
+	}
+
+	addressIndex, err := s.refundAddressIndex(*request)
+	if err != nil {
+		return err
+	}
+
+	// only what's still sitting at the address can go back
+	utxos, err := s.Chain.AddressUTXOs(ctx, request.PaymentAddress)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting utxos at %s", request.PaymentAddress)
+		return s.RenderError("Error getting utxos", c)
+	}
+	found := -1
+	for i, utxo := range utxos {
+		if utxo.TxHash == request.TxHash && utxo.OutputIndex == request.OutputIndex {
+			found = i
+		}
+	}
+	if found < 0 {
+		return echo.NewHTTPError(http.StatusNotFound, "The utxo isn't at the payment address")
+	}
+
+	paid, err := s.utxoInUse(request.TxHash, request.OutputIndex)
+	if err != nil {
+		log.WithError(err).Errorf("Error checking utxo %s#%d", request.TxHash, request.OutputIndex)
+		return s.RenderError("Error checking utxo", c)
+	}
+	if paid != "" {
+		return echo.NewHTTPError(http.StatusConflict, fmt.Sprintf("The utxo pays for %s", paid))
+	}
+
+	payment, err := s.paymentFromUtxo(ctx, utxos[found], make(map[string]string))
+	if err != nil {
+		log.WithError(err).Errorf("Error reading utxo %s#%d", request.TxHash, request.OutputIndex)
+		return echo.NewHTTPError(http.StatusConflict, "The utxo can't be returned")
+	}
+
+	refund, err := s.Store.ForceRefund(ctx, refundForPayment(*payment, request.PaymentAddress, addressIndex))
+	if err != nil {
+		log.WithError(err).Errorf("Error forcing refund of %s#%d", request.TxHash, request.OutputIndex)
+		return s.RenderError("Error recording refund", c)
+	}
+	c.Set(auditDetailKey, fmt.Sprintf("%s#%d to %s, refund %d is %s", refund.TxHash, refund.OutputIndex, refund.SenderAddress, refund.ID, refund.Status))
+
+	return c.JSON(http.StatusOK, Refund{
+		TxHash:       refund.TxHash,
+		OutputIndex:  refund.OutputIndex,
+		Address:      refund.SenderAddress,
+		Lovelace:     refund.Lovelace,
+		Assets:       refund.Assets,
+		Status:       refund.Status,
+		Attempts:     refund.Attempts,
+		RefundTxHash: refund.RefundTxHash.String,
+		CreatedDate:  refund.CreatedDate,
+	})
+}
+
+// GetEngineHealth whether the minter is passing and how much work is waiting on it
+func (s Server) GetEngineHealth(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	heartbeats, err := s.Store.GetEngineHeartbeats()
+	if err != nil {
+		log.WithError(err).Error("Error getting engine heartbeats")
+		return s.RenderError("Error getting engine health", c)
+	}
+	fightCounts, err := s.Store.CountFightsByStatus()
+	if err != nil {
+		log.WithError(err).Error("Error counting fights")
+		return s.RenderError("Error getting engine health", c)
+	}
+	refundCounts, err := s.Store.CountRefundsByStatus()
+	if err != nil {
+		log.WithError(err).Error("Error counting refunds")
+		return s.RenderError("Error getting engine health", c)
+	}
+
+	health := EngineHealth{
+		Engines: make([]EngineStatus, 0),
+		Fights:  make(map[string]int),
+		Refunds: make(map[string]int),
+	}
+	minterHealthy := false
+	for _, heartbeat := range heartbeats {
+		status := EngineStatus{
+			Engine:              heartbeat.Engine,
+			LastPassDate:        heartbeat.LastPassDate,
+			ConsecutiveFailures: heartbeat.ConsecutiveFailures,
+		}
+		if heartbeat.LastSuccessDate.Valid {
+			status.LastSuccessDate = &heartbeat.LastSuccessDate.Time
+			status.Healthy = time.Since(heartbeat.LastSuccessDate.Time) < engineStaleAfter
+		}
+		if heartbeat.Engine == mintingEngine {
+			minterHealthy = status.Healthy
+		}
+		health.Engines = append(health.Engines, status)
+	}
+	for status, count := range fightCounts {
+		health.Fights[string(status)] = count
+	}
+	for status, count := range refundCounts {
+		health.Refunds[string(status)] = count
+	}
+
+	// how long the oldest fight has been waiting at each step the minter takes it through
+	for status, oldest := range map[db.FightStatus]**time.Time{
+		db.FightStatusQueued: &health.OldestQueuedDate,
+		db.FightStatusStaged: &health.OldestStagedDate,
+		db.FightStatusMinted: &health.OldestMintedDate,
+	} {
+		fights, err := s.Store.GetFightsByStatus(status, 1)
+		if err != nil {
+			log.WithError(err).Errorf("Error getting oldest %s fight", status)
+			return s.RenderError("Error getting engine health", c)
+		}
+		if len(fights) > 0 {
+			*oldest = &fights[0].CreatedDate
+		}
+	}
+
+	health.Healthy = minterHealthy && refundCounts[db.RefundStatusFailed] == 0
+
+	return c.JSON(http.StatusOK, health)
+}
+
+// GetAdminAudit list the latest changes made through the admin api
+func (s Server) GetAdminAudit(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	limit, err := adminLimit(c)
+	if err != nil {
+		return err
+	}
+
+	audits, err := s.Store.GetAdminAudits(limit)
+	if err != nil {
+		log.WithError(err).Error("Error getting admin audits")
+		return s.RenderError("Error getting audit log", c)
+	}
+
+	auditDtos := make([]AdminAudit, 0)
+	for _, audit := range audits {
+		auditDtos = append(auditDtos, AdminAudit{
+			ID:          audit.ID,
+			UserID:      audit.UserID,
+			Action:      audit.Action,
+			Target:      audit.Target,
+			Detail:      audit.Detail,
+			StatusCode:  audit.StatusCode,
+			CreatedDate: audit.CreatedDate,
+		})
+	}
+
+	return c.JSON(http.StatusOK, auditDtos)
+}
+
+// SetUserRole give a user a role on the admin api, or take it away with an empty one
+func (s Server) SetUserRole(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	userID, err := strconv.Atoi(c.Param("userId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "User id must be a number")
+	}
+	request := new(AdminUserRole)
+	if err = c.Bind(request); err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if request.Role != "" && !request.Role.Valid() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown role")
+	}
+
+	// someone else has to take an admin's role away, so there's always one left
+	dbUser := c.Get("user").(*db.User)
+	if dbUser.ID == userID {
+		return echo.NewHTTPError(http.StatusBadRequest, "You can't change your own role")
+	}
+
+	user, err := s.Store.GetUserByID(userID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting user %d", userID)
+		return s.RenderError("Error getting user", c)
+	}
+	if user == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "User not found")
+	}
+
+	c.Set(auditDetailKey, fmt.Sprintf("%q to %q", user.Role, request.Role))
+	err = s.Store.SetUserRole(c.Request().Context(), userID, request.Role)
+	if err != nil {
+		log.WithError(err).Errorf("Error setting role of user %d", userID)
+		return s.RenderError("Error setting role", c)
+	}
+
+	return c.JSON(http.StatusOK, AdminUserRole{UserID: userID, Role: request.Role})
+}
+
+// regenerateFightImages rebuilds the images of a staged fight from its recorded outcome and uploads them
+func (s Server) regenerateFightImages(fight db.FightDb) error {
+	ctx := context.Background()
+	logrus.Infof("Regenerating images of fight %d", fight.ID)
+
+	alien, err := s.Store.GetAlienByFightId(fight.ID)
+	if err != nil {
+		return err
+	}
+	if alien == nil {
+		return fmt.Errorf("No alien for fight %d", fight.ID)
+	}
+
+	// the combat log has how beaten up each side ended up, fights from before it was kept look fresh
+	combatLog := metadata.CombatLog{}
+	combatLogJSON, err := s.Store.GetFightCombatLog(ctx, fight.ID)
+	if err != nil {
+		return err
+	}
+	if combatLogJSON != "" {
+		if err = json.Unmarshal([]byte(combatLogJSON), &combatLog); err != nil {
+			return err
+		}
+	}
+
+	dirName := "work/" + uuid.New().String()
+	err = os.MkdirAll(dirName, 0755)
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dirName)
+
+	alienFile := dirName + "/alien.jpg"
+	err = s.buildAlienImage(*alien, alienFile)
+	if err != nil {
+		return err
+	}
+	alienIpfsResponse, err := s.NftStorageClient.IpfsAdd(alienFile)
+	if err != nil {
+		return err
+	}
+
+	fightBytes, background, err := s.ImageBuilderClient.Buildfight(imagebuilder.ZombieFightImage{
+		Background:          fight.Background.String,
+		ZombieChain:         fight.ZombieName,
+		ZombieHunter:        fight.HunterName,
+		Vs:                  "VS",
+		ZombieChainLifeBar:  int(fight.ZombieLifeBar.Int64),
+		ZombieHunterLifeBar: int(fight.HunterLifeBar.Int64),
+		ZombieRecord:        fight.ZombieRecord.String,
+		HunterRecord:        fight.HunterRecord.String,
+		ZombieKO:            fight.ZombieKo.Bool,
+		HunterKO:            fight.HunterKo.Bool,
+		ZombieBeatup:        combatLog.ZombieBeatup,
+		HunterBeatup:        combatLog.HunterBeatup,
+	})
+	if err != nil {
+		return err
+	}
+	fightFile := dirName + "/fight.jpg"
+	err = os.WriteFile(fightFile, fightBytes, 0644)
+	if err != nil {
+		return err
+	}
+	fightIpfsResponse, err := s.NftStorageClient.IpfsAdd(fightFile)
+	if err != nil {
+		return err
+	}
+	if background == "" {
+		background = fight.Background.String
+	}
+
+	logrus.Infof("Fight %d has images %s and %s now", fight.ID, fightIpfsResponse.Value.Pin.CID, alienIpfsResponse.Value.Pin.CID)
+	return s.Store.SetFightImages(ctx, fight.ID, alien.ID, alienIpfsResponse.Value.Pin.CID, fightIpfsResponse.Value.Pin.CID, background)
+}
+
+// recordEnginePass records the engine's pass, a heartbeat that can't be written doesn't stop the engine
+func (s Server) recordEnginePass(engine string, failed bool) {
+	err := s.Store.RecordEnginePass(context.Background(), engine, time.Now(), failed)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error recording %s pass", engine)
+	}
+}
+
+// adminFightParam the fight in the path. Errors are http errors to return as is
+func (s Server) adminFightParam(c echo.Context) (*db.FightDb, error) {
+	fightID, err := strconv.Atoi(c.Param("fightId"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Fight id must be a number")
+	}
+
+	fight, err := s.Store.GetFight(fightID)
+	if err != nil {
+		logrus.WithContext(c.Request().Context()).WithError(err).Errorf("Error getting fight %d", fightID)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Error getting fight")
+	}
+	if fight == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Fight not found")
+	}
+
+	return fight, nil
+}
+
+func (s Server) renderAdminFight(c echo.Context, fightID int) error {
+	return s.renderAdminFightStatus(c, http.StatusOK, fightID)
+}
+
+func (s Server) renderAdminFightStatus(c echo.Context, code int, fightID int) error {
+	fight, err := s.Store.GetFight(fightID)
+	if err != nil || fight == nil {
+		logrus.WithContext(c.Request().Context()).WithError(err).Errorf("Error getting fight %d", fightID)
+		return s.RenderError("Error getting fight", c)
+	}
+
+	return c.JSON(code, adminFight(*fight))
+}
+
+// refundAddressIndex the hd wallet index of the refund's payment address, none for the shared one. Errors are http
+// errors to return as is
+func (s Server) refundAddressIndex(request AdminRefund) (sql.NullInt64, error) {
+	if request.PaymentAddress == s.PaymentAddress {
+		return sql.NullInt64{}, nil
+	}
+	if request.PaymentAddressIndex == nil || s.PaymentWallet == nil {
+		return sql.NullInt64{}, echo.NewHTTPError(http.StatusBadRequest, "Not the payment address, and no address index to derive it from")
+	}
+
+	derived, err := s.PaymentWallet.PaymentAddress(uint32(*request.PaymentAddressIndex))
+	if err != nil || derived != request.PaymentAddress {
+		return sql.NullInt64{}, echo.NewHTTPError(http.StatusBadRequest, "The address index doesn't derive the payment address")
+	}
+
+	return sql.NullInt64{Int64: *request.PaymentAddressIndex, Valid: true}, nil
+}
+
+// utxoInUse what the utxo pays for that keeps it from being refunded, empty if nothing does
+func (s Server) utxoInUse(txHash string, outputIndex int) (string, error) {
+	fights, err := s.Store.GetFightForUtxo(txHash, outputIndex)
+	if err != nil {
+		return "", err
+	}
+	for _, fight := range fights {
+		if fight.Status != db.FightStatusVoid {
+			return fmt.Sprintf("fight %d", fight.ID), nil
+		}
+	}
+
+	entry, err := s.Store.GetTournamentEntryForUtxo(txHash, outputIndex)
+	if err != nil {
+		return "", err
+	}
+	if entry != nil {
+		return fmt.Sprintf("tournament entry %d", entry.ID), nil
+	}
+
+	deposit, err := s.Store.GetWagerDepositForUtxo(txHash, outputIndex)
+	if err != nil {
+		return "", err
+	}
+	if deposit != nil {
+		return fmt.Sprintf("a deposit of wager %d", deposit.WagerID), nil
+	}
+
+	return "", nil
+}
+
+func adminFight(fight db.FightDb) AdminFight {
+	adminFight := AdminFight{
+		ID:                    fight.ID,
+		Status:                string(fight.Status),
+		ZombieName:            fight.ZombieName,
+		HunterName:            fight.HunterName,
+		CreatedDate:           fight.CreatedDate,
+		PaymentAddress:        fight.PaymentAddress,
+		PaymentAmountLovelace: fight.PaymentAmountLovelace,
+		ZombieSendAddress:     fight.ZombieSendAddress.String,
+		HunterSendAddress:     fight.HunterSendAddress.String,
+		SignedTxHash:          fight.SignedTxHash.String,
+		TxID:                  strings.ReplaceAll(fight.TxID.String, "\"", ""),
+		RegenerateImages:      fight.RegenerateImages,
+	}
+	if fight.MintedDate.Valid {
+		adminFight.MintedDate = &fight.MintedDate.Time
+	}
+	if fight.IncomingUtxo.Valid {
+		adminFight.IncomingUtxo = fmt.Sprintf("%s#%d", fight.IncomingUtxo.String, fight.IncomingUtxoInt.Int64)
+	}
+	if fight.TournamentID.Valid {
+		adminFight.TournamentID = &fight.TournamentID.Int64
+	}
+
+	return adminFight
+}
+
+// adminLimit the limit query param, 50 by default. Errors are http errors to return as is
+func adminLimit(c echo.Context) (int, error) {
+	limitParam := c.QueryParam("limit")
+	if limitParam == "" {
+		return defaultAdminLimit, nil
+	}
+
+	limit, err := strconv.Atoi(limitParam)
+	if err != nil || limit < 1 || limit > maxAdminLimit {
+		return 0, echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("Limit must be between 1 and %d", maxAdminLimit))
+	}
+
+	return limit, nil
+}
+
+func validFightStatus(status db.FightStatus) bool {
+	switch status {
+	case db.FightStatusPending, db.FightStatusQueued, db.FightStatusStaged, db.FightStatusMinted, db.FightStatusConfirmed, db.FightStatusVoid:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,275 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+)
+
+// newTestAdmin makes a user with the role, an empty role makes a player
+func newTestAdmin(t *testing.T, memoryStore *db.MemoryStore, nftkeyID string, role db.UserRole) db.User {
+	if err := memoryStore.InsertUser(nftkeyID, "access", "refresh"); err != nil {
+		t.Fatalf("Error inserting user %v", err)
+	}
+	user, _ := memoryStore.GetUserByNftkeyID(nftkeyID)
+	if err := memoryStore.SetUserRole(context.Background(), user.ID, role); err != nil {
+		t.Fatalf("Error setting role %v", err)
+	}
+	user, _ = memoryStore.GetUserByID(user.ID)
+	return *user
+}
+
+// serveAdmin runs the handler behind the admin middleware the way the /admin group does
+func serveAdmin(s Server, handler echo.HandlerFunc, role db.UserRole, c echo.Context) error {
+	return s.AuditAdmin(s.RequireRole(role)(handler))(c)
+}
+
+func TestRequireRole(t *testing.T) {
+	s, memoryStore, _ := newTestMintingServer(t)
+	player, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	viewer := newTestAdmin(t, memoryStore, "viewer", db.UserRoleViewer)
+
+	c, _ := newTestContext(http.MethodGet, "/admin/fights?status=PENDING", "", *player)
+	err := serveAdmin(s, s.GetAdminFights, db.UserRoleViewer, c)
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusForbidden {
+		t.Fatalf("Expected a player turned away but got %v", err)
+	}
+
+	c, rec := newTestContext(http.MethodGet, "/admin/fights?status=PENDING", "", viewer)
+	if err := serveAdmin(s, s.GetAdminFights, db.UserRoleViewer, c); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("Expected a viewer to list fights but got %v %d", err, rec.Code)
+	}
+
+	// a viewer can't change anything, and trying is in the audit log
+	c, _ = newTestContext(http.MethodPost, "/admin/fights/1/cancel", `{"reason":"test"}`, viewer)
+	c.SetPath("/admin/fights/:fightId/cancel")
+	c.SetParamNames("fightId")
+	c.SetParamValues("1")
+	err = serveAdmin(s, s.CancelFight, db.UserRoleOperator, c)
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusForbidden {
+		t.Fatalf("Expected a viewer turned away but got %v", err)
+	}
+	audits, _ := memoryStore.GetAdminAudits(10)
+	if len(audits) != 1 || audits[0].StatusCode != http.StatusForbidden || audits[0].Target != "fightId=1" {
+		t.Errorf("Expected the refused cancel audited but got %v", audits)
+	}
+}
+
+func TestCancelFightVoidsAndAudits(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	operator := newTestAdmin(t, memoryStore, "operator", db.UserRoleOperator)
+
+	fightID := newTestPaidFight(t, memoryStore, fakeChain, "addr_ZombieChains00001", "addr_ZombieHunter00001")
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+
+	c, rec := newTestContext(http.MethodPost, fmt.Sprintf("/admin/fights/%d/cancel", fightID), `{"reason":"stuck in the queue"}`, operator)
+	c.SetPath("/admin/fights/:fightId/cancel")
+	c.SetParamNames("fightId")
+	c.SetParamValues(fmt.Sprint(fightID))
+	if err := serveAdmin(s, s.CancelFight, db.UserRoleOperator, c); err != nil {
+		t.Fatalf("Error cancelling fight %v", err)
+	}
+	fight := AdminFight{}
+	json.Unmarshal(rec.Body.Bytes(), &fight)
+	if fight.Status != string(db.FightStatusVoid) {
+		t.Fatalf("Expected the fight voided but got %s", rec.Body.String())
+	}
+
+	refunds, _ := memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10)
+	if len(refunds) != 1 || refunds[0].SenderAddress != "addr_buyer" {
+		t.Errorf("Expected the payment owed back but got %v", refunds)
+	}
+	audits, _ := memoryStore.GetAdminAudits(10)
+	if len(audits) != 1 || audits[0].UserID != operator.ID || audits[0].Action != "POST /admin/fights/:fightId/cancel" ||
+		audits[0].StatusCode != http.StatusOK || audits[0].Detail != "stuck in the queue" {
+		t.Errorf("Expected the cancel audited but got %v", audits)
+	}
+
+	// it's void now, there's nothing left to cancel
+	c, _ = newTestContext(http.MethodPost, fmt.Sprintf("/admin/fights/%d/cancel", fightID), `{"reason":"again"}`, operator)
+	c.SetParamNames("fightId")
+	c.SetParamValues(fmt.Sprint(fightID))
+	err := serveAdmin(s, s.CancelFight, db.UserRoleOperator, c)
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusConflict {
+		t.Errorf("Expected a void fight not cancelled again but got %v", err)
+	}
+}
+
+// auditDownStore a store that can't write the audit log
+type auditDownStore struct {
+	db.Store
+}
+
+func (s auditDownStore) InsertAdminAudit(ctx context.Context, audit db.AdminAudit) (int, error) {
+	return 0, errors.New("audit log is down")
+}
+
+func TestCancelFightNotMadeUnaudited(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	operator := newTestAdmin(t, memoryStore, "operator", db.UserRoleOperator)
+
+	fightID := newTestPaidFight(t, memoryStore, fakeChain, "addr_ZombieChains00001", "addr_ZombieHunter00001")
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+
+	s.Store = auditDownStore{Store: memoryStore}
+	c, rec := newTestContext(http.MethodPost, fmt.Sprintf("/admin/fights/%d/cancel", fightID), `{"reason":"stuck in the queue"}`, operator)
+	c.SetPath("/admin/fights/:fightId/cancel")
+	c.SetParamNames("fightId")
+	c.SetParamValues(fmt.Sprint(fightID))
+	if err := serveAdmin(s, s.CancelFight, db.UserRoleOperator, c); err != nil || rec.Code != http.StatusInternalServerError {
+		t.Fatalf("Expected the cancel turned away without an audit but got %v %d", err, rec.Code)
+	}
+
+	fight, _ := memoryStore.GetFight(fightID)
+	if fight.Status == db.FightStatusVoid {
+		t.Error("Expected the fight left alone")
+	}
+}
+
+func TestRetryFightKeepsLiveMintTx(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	operator := newTestAdmin(t, memoryStore, "operator", db.UserRoleOperator)
+
+	fightID := newTestPaidFight(t, memoryStore, fakeChain, "addr_ZombieChains00001", "addr_ZombieHunter00001")
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	// a mint tx was signed but its submit never made it
+	tip, _ := fakeChain.Tip(context.Background())
+	if err := memoryStore.SetSignedMintTx(context.Background(), fightID, "mint-tx", "84a0", tip.Slot+1000); err != nil {
+		t.Fatalf("Error setting signed tx %v", err)
+	}
+
+	retry := func() error {
+		c, _ := newTestContext(http.MethodPost, fmt.Sprintf("/admin/fights/%d/retry", fightID), "", operator)
+		c.SetParamNames("fightId")
+		c.SetParamValues(fmt.Sprint(fightID))
+		return serveAdmin(s, s.RetryFight, db.UserRoleOperator, c)
+	}
+
+	// the submitted tx can still land, dropping it could mint the fight twice
+	err := retry()
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusConflict {
+		t.Fatalf("Expected a live mint tx kept but got %v", err)
+	}
+
+	fakeChain.AdvanceSlots(1000 + signedTxExpiryMargin + 1)
+	if err := retry(); err != nil {
+		t.Fatalf("Error retrying fight %v", err)
+	}
+	if fight, _ := memoryStore.GetFight(fightID); fight.SignedTxHash.Valid {
+		t.Errorf("Expected the expired mint tx dropped but got %v", fight)
+	}
+}
+
+func TestForceRefund(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	operator := newTestAdmin(t, memoryStore, "operator", db.UserRoleOperator)
+
+	newTestPaidFight(t, memoryStore, fakeChain, "addr_ZombieChains00001", "addr_ZombieHunter00001")
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	fightPayment, _ := fakeChain.AddressUTXOs(context.Background(), "addr_payment")
+	stray := fakeChain.Pay("addr_stranger", "addr_payment", 3000000)
+
+	// the payment of a live fight stays where it is
+	c, _ := newTestContext(http.MethodPost, "/admin/refunds", fmt.Sprintf(`{"txHash":"%s","outputIndex":%d,"paymentAddress":"addr_payment"}`, fightPayment[0].TxHash, fightPayment[0].OutputIndex), operator)
+	err := serveAdmin(s, s.ForceRefund, db.UserRoleOperator, c)
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusConflict {
+		t.Fatalf("Expected a fight's payment not refunded but got %v", err)
+	}
+
+	c, rec := newTestContext(http.MethodPost, "/admin/refunds", fmt.Sprintf(`{"txHash":"%s","outputIndex":%d,"paymentAddress":"addr_payment"}`, stray.TxHash, stray.OutputIndex), operator)
+	if err := serveAdmin(s, s.ForceRefund, db.UserRoleOperator, c); err != nil {
+		t.Fatalf("Error forcing refund %v", err)
+	}
+	refund := Refund{}
+	json.Unmarshal(rec.Body.Bytes(), &refund)
+	if refund.Address != "addr_stranger" || refund.Lovelace != 3000000 || refund.Status != db.RefundStatusPending {
+		t.Errorf("Expected the stray payment owed back but got %s", rec.Body.String())
+	}
+
+	// a utxo that isn't there can't be sent back
+	c, _ = newTestContext(http.MethodPost, "/admin/refunds", `{"txHash":"missing","outputIndex":0,"paymentAddress":"addr_payment"}`, operator)
+	err = serveAdmin(s, s.ForceRefund, db.UserRoleOperator, c)
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusNotFound {
+		t.Errorf("Expected a missing utxo not found but got %v", err)
+	}
+}
+
+func TestRegenerateFightImages(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	operator := newTestAdmin(t, memoryStore, "operator", db.UserRoleOperator)
+
+	fightID := newTestPaidFight(t, memoryStore, fakeChain, "addr_ZombieChains00001", "addr_ZombieHunter00001")
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	staged, _ := memoryStore.GetFight(fightID)
+
+	c, rec := newTestContext(http.MethodPost, fmt.Sprintf("/admin/fights/%d/images", fightID), "", operator)
+	c.SetParamNames("fightId")
+	c.SetParamValues(fmt.Sprint(fightID))
+	if err := serveAdmin(s, s.RegenerateFightImages, db.UserRoleOperator, c); err != nil || rec.Code != http.StatusAccepted {
+		t.Fatalf("Expected new images requested but got %v %d", err, rec.Code)
+	}
+
+	// the minter rebuilds them instead of minting this pass
+	if err := s.processStagedFights(); err != nil {
+		t.Fatalf("Error processing staged fights %v", err)
+	}
+	fight, _ := memoryStore.GetFight(fightID)
+	if fight.Status != db.FightStatusStaged || fight.RegenerateImages || fight.IPFS.String == staged.IPFS.String {
+		t.Fatalf("Expected the fight staged with new images but got %v", fight)
+	}
+
+	if err := s.processStagedFights(); err != nil {
+		t.Fatalf("Error processing staged fights %v", err)
+	}
+	if minted, _ := memoryStore.GetMintedFights(); len(minted) != 1 {
+		t.Errorf("Expected the fight minted with its new images but got %v", minted)
+	}
+}
+
+func TestEngineHealth(t *testing.T) {
+	s, memoryStore, _ := newTestMintingServer(t)
+	viewer := newTestAdmin(t, memoryStore, "viewer", db.UserRoleViewer)
+
+	getHealth := func() EngineHealth {
+		c, rec := newTestContext(http.MethodGet, "/admin/health", "", viewer)
+		if err := serveAdmin(s, s.GetEngineHealth, db.UserRoleViewer, c); err != nil {
+			t.Fatalf("Error getting health %v", err)
+		}
+		health := EngineHealth{}
+		json.Unmarshal(rec.Body.Bytes(), &health)
+		return health
+	}
+
+	if health := getHealth(); health.Healthy {
+		t.Errorf("Expected unhealthy before the minter ran but got %v", health)
+	}
+
+	s.recordEnginePass(mintingEngine, false)
+	s.recordEnginePass(mintingEngine, true)
+	health := getHealth()
+	if !health.Healthy || len(health.Engines) != 1 || health.Engines[0].ConsecutiveFailures != 1 {
+		t.Errorf("Expected a healthy minter with one failed pass but got %v", health)
+	}
+}
@@ -0,0 +1,430 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	defaultChallengeTTL = 24 * time.Hour
+	challengeLimit      = 50
+)
+
+type (
+	// Challenge what a user sees of a challenge they sent or received
+	Challenge struct {
+		ID                int                `json:"id"`
+		Direction         string             `json:"direction"`
+		ChallengerNftName string             `json:"challengerNftName"`
+		OpponentNftName   string             `json:"opponentNftName"`
+		StakeLovelace     int64              `json:"stakeLovelace"`
+		StakeAda          string             `json:"stakeAda"`
+		Status            db.ChallengeStatus `json:"status"`
+		AwaitingResponse  bool               `json:"awaitingResponse"`
+		CreatedDate       time.Time          `json:"createdDate"`
+		ExpiresDate       time.Time          `json:"expiresDate"`
+		FightID           int                `json:"fightId,omitempty"`
+		Fight             *db.FightDto       `json:"fight,omitempty"`
+		Wager             *Wager             `json:"wager,omitempty"`
+	}
+
+	// ChallengeRequest my nft, the nft it challenges and the stake offered
+	ChallengeRequest struct {
+		NftName         string `json:"nftName"`
+		OpponentNftName string `json:"opponentNftName"`
+		StakeAda        int    `json:"stakeAda"`
+	}
+
+	// ChallengeCounterRequest the stake offered instead
+	ChallengeCounterRequest struct {
+		StakeAda int `json:"stakeAda"`
+	}
+)
+
+func (s Server) challengeTTL() time.Duration {
+	if s.ChallengeTTL == 0 {
+		return defaultChallengeTTL
+	}
+	return s.ChallengeTTL
+}
+
+// GetMyChallenges my challenges inbox, the latest challenges I sent or received with the ones waiting on me flagged
+func (s Server) GetMyChallenges(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	dbUser := c.Get("user").(*db.User)
+
+	if _, err = s.Store.ExpireChallenges(c.Request().Context(), time.Now()); err != nil {
+		log.WithError(err).Error("Error expiring challenges")
+		return s.RenderError("Error getting challenges", c)
+	}
+
+	challenges, err := s.Store.GetChallengesForUser(dbUser.ID, challengeLimit)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting challenges for user %d", dbUser.ID)
+		return s.RenderError("Error getting challenges", c)
+	}
+
+	challengeDtos := make([]Challenge, 0)
+	for _, challenge := range challenges {
+		challengeDto, err := s.convertChallengeToDto(challenge, *dbUser)
+		if err != nil {
+			log.WithError(err).Errorf("Error getting fight for challenge %d", challenge.ID)
+			return s.RenderError("Error getting challenges", c)
+		}
+		challengeDtos = append(challengeDtos, challengeDto)
+	}
+
+	return c.JSON(http.StatusOK, challengeDtos)
+}
+
+// CreateChallenge challenge another owner's zombie or hunter with one of my own, listed or not. The owner has until
+// the challenge expires to respond
+func (s Server) CreateChallenge(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	request := new(ChallengeRequest)
+	if err = c.Bind(request); err != nil {
+		log.WithError(err).Errorf("Error binding")
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if request.StakeAda < 0 {
+		return echo.NewHTTPError(http.StatusBadRequest, "Stake can't be negative")
+	}
+	if request.StakeAda > 0 && s.PaymentWallet == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Staked challenges aren't enabled")
+	}
+
+	dbUser := c.Get("user").(*db.User)
+	log.Infof("Challenging %s with %s for user %d", request.OpponentNftName, request.NftName, dbUser.ID)
+
+	nft, err := s.challengeNft(c, request.NftName)
+	if err != nil {
+		return err
+	}
+	if nft.UserID != dbUser.ID {
+		log.Warnf("Nft %s not owned by user %d", request.NftName, dbUser.ID)
+		return echo.NewHTTPError(http.StatusBadRequest, "Nft not owned by user")
+	}
+	opponent, err := s.challengeNft(c, request.OpponentNftName)
+	if err != nil {
+		return err
+	}
+	if opponent.UserID == dbUser.ID {
+		return echo.NewHTTPError(http.StatusBadRequest, "Can't challenge your own nft")
+	}
+	if opponent.NftType == nft.NftType {
+		return echo.NewHTTPError(http.StatusBadRequest, "A zombie can only challenge a hunter and a hunter a zombie")
+	}
+
+	now := time.Now()
+	if _, err = s.Store.ExpireChallenges(c.Request().Context(), now); err != nil {
+		log.WithError(err).Error("Error expiring challenges")
+		return s.RenderError("Error creating challenge", c)
+	}
+	challenges, err := s.Store.GetChallengesForUser(dbUser.ID, challengeLimit)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting challenges for user %d", dbUser.ID)
+		return s.RenderError("Error creating challenge", c)
+	}
+	for _, existing := range challenges {
+		if existing.Open() && existing.ChallengerNftID == nft.NftID && existing.OpponentNftID == opponent.NftID {
+			return echo.NewHTTPError(http.StatusBadRequest, "Already challenged")
+		}
+	}
+
+	challenge := db.Challenge{
+		ChallengerUserID:  dbUser.ID,
+		ChallengerNftID:   nft.NftID,
+		ChallengerNftName: nft.NftName,
+		ChallengerNftType: nft.NftType,
+		OpponentUserID:    opponent.UserID,
+		OpponentNftID:     opponent.NftID,
+		OpponentNftName:   opponent.NftName,
+		OpponentNftType:   opponent.NftType,
+		StakeLovelace:     int64(request.StakeAda) * 1000000,
+		Status:            db.ChallengeStatusPending,
+		CreatedDate:       now,
+		ExpiresDate:       now.Add(s.challengeTTL()),
+	}
+	challenge.ID, err = s.Store.CreateChallenge(c.Request().Context(), challenge)
+	if err != nil {
+		log.WithError(err).Errorf("Error persisting challenge")
+		return s.RenderError("Error persisting challenge", c)
+	}
+
+	challengeDto, err := s.convertChallengeToDto(challenge, *dbUser)
+	if err != nil {
+		return s.RenderError("Error getting challenge", c)
+	}
+
+	return c.JSON(http.StatusOK, challengeDto)
+}
+
+// AcceptChallenge accept a challenge waiting on me, the fight is created for the challenger to pay. Neither side pays
+// the other's list price, both owners agreed to the fight. A staked challenge also gets a wager both sides deposit to
+func (s Server) AcceptChallenge(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	dbUser := c.Get("user").(*db.User)
+	challenge, err := s.awaitingChallenge(c, *dbUser)
+	if err != nil {
+		return err
+	}
+
+	// both still have to be held by the owners that agreed to the fight
+	challengerNft, err := s.challengeNft(c, challenge.ChallengerNftName)
+	if err != nil {
+		return err
+	}
+	opponentNft, err := s.challengeNft(c, challenge.OpponentNftName)
+	if err != nil {
+		return err
+	}
+	if challengerNft.UserID != challenge.ChallengerUserID || opponentNft.UserID != challenge.OpponentUserID {
+		log.Warnf("Challenge %d nfts changed hands", challenge.ID)
+		return echo.NewHTTPError(http.StatusBadRequest, "Nft not owned by user anymore")
+	}
+
+	challenger, err := s.Store.GetUserByID(challenge.ChallengerUserID)
+	if err != nil || challenger == nil {
+		log.WithError(err).Errorf("Error getting challenger %d", challenge.ChallengerUserID)
+		return s.RenderError("Error accepting challenge", c)
+	}
+
+	zombie, hunter := *challengerNft, *opponentNft
+	if challengerNft.NftType == "Hunter" {
+		zombie, hunter = *opponentNft, *challengerNft
+	}
+	zombie.ListAmount.Int16, zombie.ListAmount.Valid = 0, true
+	hunter.ListAmount.Int16, hunter.ListAmount.Valid = 0, true
+
+	fight := &db.FightDto{
+		ZombieName: zombie.NftName,
+		HunterName: hunter.NftName,
+	}
+	if err = s.prepareFight(c.Request().Context(), fight, &zombie, &hunter, *challenger); err != nil {
+		return s.RenderError(err.Error(), c)
+	}
+
+	var wager *db.Wager
+	if challenge.StakeLovelace > 0 {
+		wager, err = s.newWager(c.Request().Context(), *challenge, zombie, hunter)
+		if err != nil {
+			log.WithError(err).Errorf("Error creating wager for challenge %d", challenge.ID)
+			return s.RenderError("Error creating wager", c)
+		}
+	}
+
+	fightID, err := s.Store.AcceptChallenge(c.Request().Context(), challenge.ID, challenge.Status, *fight, hunter, zombie, *challenger, wager)
+	if err != nil {
+		log.WithError(err).Warnf("Error accepting challenge %d", challenge.ID)
+		return echo.NewHTTPError(http.StatusBadRequest, "Challenge isn't open anymore")
+	}
+	log.Infof("Challenge %d accepted by user %d, fight %d", challenge.ID, dbUser.ID, fightID)
+
+	return s.renderChallenge(c, challenge.ID, *dbUser)
+}
+
+// DeclineChallenge decline a challenge waiting on me
+func (s Server) DeclineChallenge(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	dbUser := c.Get("user").(*db.User)
+	challenge, err := s.awaitingChallenge(c, *dbUser)
+	if err != nil {
+		return err
+	}
+
+	err = s.Store.CloseChallenge(c.Request().Context(), challenge.ID, challenge.Status, db.ChallengeStatusDeclined)
+	if err != nil {
+		log.WithError(err).Warnf("Error declining challenge %d", challenge.ID)
+		return echo.NewHTTPError(http.StatusBadRequest, "Challenge isn't open anymore")
+	}
+
+	return s.renderChallenge(c, challenge.ID, *dbUser)
+}
+
+// CounterChallenge answer a challenge waiting on me with a different stake, it's then the other side's turn
+func (s Server) CounterChallenge(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	dbUser := c.Get("user").(*db.User)
+	challenge, err := s.awaitingChallenge(c, *dbUser)
+	if err != nil {
+		return err
+	}
+
+	request := new(ChallengeCounterRequest)
+	if err = c.Bind(request); err != nil {
+		log.WithError(err).Errorf("Error binding")
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	stakeLovelace := int64(request.StakeAda) * 1000000
+	if request.StakeAda < 0 || stakeLovelace == challenge.StakeLovelace {
+		return echo.NewHTTPError(http.StatusBadRequest, "Counter with a different stake")
+	}
+	if request.StakeAda > 0 && s.PaymentWallet == nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Staked challenges aren't enabled")
+	}
+
+	err = s.Store.CounterChallenge(c.Request().Context(), challenge.ID, challenge.Status, stakeLovelace, time.Now().Add(s.challengeTTL()))
+	if err != nil {
+		log.WithError(err).Warnf("Error countering challenge %d", challenge.ID)
+		return echo.NewHTTPError(http.StatusBadRequest, "Challenge isn't open anymore")
+	}
+
+	return s.renderChallenge(c, challenge.ID, *dbUser)
+}
+
+// CancelChallenge take back a challenge I sent that's still open
+func (s Server) CancelChallenge(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	dbUser := c.Get("user").(*db.User)
+	challenge, err := s.challengeParam(c, *dbUser)
+	if err != nil {
+		return err
+	}
+	if challenge.ChallengerUserID != dbUser.ID || !challenge.Open() {
+		return echo.NewHTTPError(http.StatusBadRequest, "Challenge can't be cancelled")
+	}
+
+	err = s.Store.CloseChallenge(c.Request().Context(), challenge.ID, challenge.Status, db.ChallengeStatusCancelled)
+	if err != nil {
+		log.WithError(err).Warnf("Error cancelling challenge %d", challenge.ID)
+		return echo.NewHTTPError(http.StatusBadRequest, "Challenge isn't open anymore")
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// challengeParam the challenge in the path, only the two sides can see it. Errors are http errors to return as is
+func (s Server) challengeParam(c echo.Context, dbUser db.User) (*db.Challenge, error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	challengeID, err := strconv.Atoi(c.Param("challengeId"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Challenge id must be a number")
+	}
+
+	challenge, err := s.Store.GetChallenge(challengeID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting challenge %d", challengeID)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Error getting challenge")
+	}
+	if challenge == nil || (challenge.ChallengerUserID != dbUser.ID && challenge.OpponentUserID != dbUser.ID) {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Challenge not found")
+	}
+
+	return challenge, nil
+}
+
+// awaitingChallenge the challenge in the path when it's the user's turn to respond to it
+func (s Server) awaitingChallenge(c echo.Context, dbUser db.User) (*db.Challenge, error) {
+	challenge, err := s.challengeParam(c, dbUser)
+	if err != nil {
+		return nil, err
+	}
+	if challenge.AwaitingUserID() != dbUser.ID || !challenge.ExpiresDate.After(time.Now()) {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Challenge isn't waiting on you")
+	}
+
+	return challenge, nil
+}
+
+// challengeNft an nft with the user that owns it, checked to still be held by them on chain. Errors are http errors
+// to return as is
+func (s Server) challengeNft(c echo.Context, name string) (*db.UserNfts, error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	nfts, err := s.Store.GetListedNftByName(name)
+	if err != nil {
+		log.WithError(err).Errorf("Error checking who owns %s", name)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Error checking who owns "+name)
+	}
+	if len(nfts) == 0 {
+		log.Warnf("Nft %s isn't owned by anyone", name)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Nft not found")
+	}
+
+	owns, err := s.doesUserOwnNft(c.Request().Context(), name, nfts[0].UserID)
+	if err != nil {
+		log.WithError(err).Errorf("Error checking user owns %s", name)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Error checking who owns "+name)
+	}
+	if !owns {
+		log.Warnf("Nft %s not owned by user %d", name, nfts[0].UserID)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Nft not owned by user anymore")
+	}
+
+	return &nfts[0], nil
+}
+
+// renderChallenge the challenge as it is after a response
+func (s Server) renderChallenge(c echo.Context, challengeID int, dbUser db.User) error {
+	challenge, err := s.Store.GetChallenge(challengeID)
+	if err != nil || challenge == nil {
+		logrus.WithContext(c.Request().Context()).WithError(err).Errorf("Error getting challenge %d", challengeID)
+		return s.RenderError("Error getting challenge", c)
+	}
+
+	challengeDto, err := s.convertChallengeToDto(*challenge, dbUser)
+	if err != nil {
+		logrus.WithContext(c.Request().Context()).WithError(err).Errorf("Error getting fight for challenge %d", challengeID)
+		return s.RenderError("Error getting challenge", c)
+	}
+
+	return c.JSON(http.StatusOK, challengeDto)
+}
+
+// convertChallengeToDto the challenge as the user sees it, the fight's payment details only go to the challenger
+// who pays for it and each side only sees their own deposit address
+func (s Server) convertChallengeToDto(challenge db.Challenge, dbUser db.User) (Challenge, error) {
+	challengeDto := Challenge{
+		ID:                challenge.ID,
+		Direction:         "received",
+		ChallengerNftName: challenge.ChallengerNftName,
+		OpponentNftName:   challenge.OpponentNftName,
+		StakeLovelace:     challenge.StakeLovelace,
+		StakeAda:          lovelaceToString(int(challenge.StakeLovelace)),
+		Status:            challenge.Status,
+		AwaitingResponse:  challenge.AwaitingUserID() == dbUser.ID,
+		CreatedDate:       challenge.CreatedDate,
+		ExpiresDate:       challenge.ExpiresDate,
+		FightID:           int(challenge.FightID.Int64),
+	}
+	if challenge.ChallengerUserID == dbUser.ID {
+		challengeDto.Direction = "sent"
+	}
+
+	if challenge.FightID.Valid && challenge.ChallengerUserID == dbUser.ID {
+		fights, err := s.Store.GetFightsForUserAndId(dbUser, int(challenge.FightID.Int64))
+		if err != nil {
+			return challengeDto, err
+		}
+		if len(fights) > 0 {
+			challengeDto.Fight = &s.convertFightToDto(fights)[0]
+		}
+	}
+
+	if challenge.FightID.Valid && challenge.StakeLovelace > 0 {
+		wager, err := s.Store.GetWagerForFight(int(challenge.FightID.Int64))
+		if err != nil || wager == nil {
+			return challengeDto, err
+		}
+		deposits, err := s.Store.GetWagerDeposits(wager.ID)
+		if err != nil {
+			return challengeDto, err
+		}
+		wagerDto := convertWagerToDto(*wager, deposits, dbUser.ID)
+		challengeDto.Wager = &wagerDto
+	}
+
+	return challengeDto, nil
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+)
+
+func TestChallengeCounterAndAccept(t *testing.T) {
+	memoryStore, zombieOwner, hunterOwner := newTestStore(t)
+	s := Server{
+		Store:          memoryStore,
+		Chain:          newTestChain(),
+		ZombiePolicyId: testZombiePolicyID,
+		HunterPolicyId: testHunterPolicyID,
+		BaseCostAda:    12,
+		PaymentAddress: "addr_payment",
+		PaymentWallet:  newTestWallet(),
+	}
+
+	respond := func(handler echo.HandlerFunc, user db.User, challengeID int, body string) (*Challenge, error) {
+		c, rec := newTestContext(http.MethodPost, "/user/challenges", body, user)
+		if challengeID != 0 {
+			c.SetParamNames("challengeId")
+			c.SetParamValues(strconv.Itoa(challengeID))
+		}
+		if err := handler(c); err != nil {
+			return nil, err
+		}
+		challenge := Challenge{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &challenge); err != nil {
+			t.Fatalf("Error decoding challenge %v", err)
+		}
+		return &challenge, nil
+	}
+
+	challenge, err := respond(s.CreateChallenge, zombieOwner, 0, `{"nftName":"ZombieChains00001","opponentNftName":"ZombieHunter00001","stakeAda":10}`)
+	if err != nil {
+		t.Fatalf("Error creating challenge %v", err)
+	}
+	if challenge.Status != db.ChallengeStatusPending || challenge.Direction != "sent" || challenge.AwaitingResponse || challenge.StakeLovelace != 10000000 {
+		t.Fatalf("Expected a pending challenge sent at 10 ada but got %v", challenge)
+	}
+
+	// it's the hunter owner's turn, the challenger can't accept their own challenge
+	if _, err = respond(s.AcceptChallenge, zombieOwner, challenge.ID, ""); err == nil {
+		t.Errorf("Expected the challenger to be turned away")
+	}
+
+	countered, err := respond(s.CounterChallenge, hunterOwner, challenge.ID, `{"stakeAda":20}`)
+	if err != nil {
+		t.Fatalf("Error countering %v", err)
+	}
+	if countered.Status != db.ChallengeStatusCountered || countered.Direction != "received" || countered.StakeLovelace != 20000000 {
+		t.Fatalf("Expected a countered challenge at 20 ada but got %v", countered)
+	}
+
+	accepted, err := respond(s.AcceptChallenge, zombieOwner, challenge.ID, "")
+	if err != nil {
+		t.Fatalf("Error accepting %v", err)
+	}
+	if accepted.Status != db.ChallengeStatusAccepted || accepted.Fight == nil {
+		t.Fatalf("Expected an accepted challenge with a fight but got %v", accepted)
+	}
+	// both agreed to the fight so neither list price is paid, just the base cost
+	if accepted.Fight.PaymentAmountLovelace != 12000000 {
+		t.Errorf("Expected a payment of the base cost but got %d", accepted.Fight.PaymentAmountLovelace)
+	}
+	// the stake is wagered, each side depositing to its own address
+	if accepted.Wager == nil || accepted.Wager.Status != db.WagerStatusAwaitingDeposits || accepted.Wager.StakeLovelace != 20000000 ||
+		accepted.Wager.DepositAddress == "" || accepted.Wager.DepositAddress == accepted.Fight.PaymentAddress {
+		t.Fatalf("Expected a wager awaiting a 20 ada deposit but got %v", accepted.Wager)
+	}
+
+	// the opponent sees the fight in their inbox without the challenger's payment details
+	c, rec := newTestContext(http.MethodGet, "/user/challenges", "", hunterOwner)
+	if err = s.GetMyChallenges(c); err != nil {
+		t.Fatalf("Error getting challenges %v", err)
+	}
+	inbox := make([]Challenge, 0)
+	json.Unmarshal(rec.Body.Bytes(), &inbox)
+	if len(inbox) != 1 || inbox[0].FightID != accepted.Fight.ID || inbox[0].Fight != nil {
+		t.Fatalf("Expected the accepted challenge with only its fight id but got %v", inbox)
+	}
+	if inbox[0].Wager == nil || inbox[0].Wager.DepositAddress == accepted.Wager.DepositAddress {
+		t.Errorf("Expected the opponent to get their own deposit address but got %v", inbox[0].Wager)
+	}
+}
+
+func TestChallengeRejected(t *testing.T) {
+	memoryStore, zombieOwner, hunterOwner := newTestStore(t)
+	s := Server{
+		Store:        memoryStore,
+		Chain:        newTestChain(),
+		ChallengeTTL: time.Hour,
+	}
+	addTestNft(t, memoryStore, zombieOwner, "ZombieHunter00002", "Hunter")
+
+	create := func(body string) error {
+		c, _ := newTestContext(http.MethodPost, "/user/challenges", body, zombieOwner)
+		return s.CreateChallenge(c)
+	}
+
+	if err := create(`{"nftName":"ZombieChains00001","opponentNftName":"ZombieHunter00001"}`); err != nil {
+		t.Fatalf("Error creating challenge %v", err)
+	}
+
+	// challenged again, own nft, a zombie against a zombie and someone else's nft to challenge with
+	for _, body := range []string{
+		`{"nftName":"ZombieChains00001","opponentNftName":"ZombieHunter00001"}`,
+		`{"nftName":"ZombieChains00001","opponentNftName":"ZombieHunter00002"}`,
+		`{"nftName":"ZombieHunter00002","opponentNftName":"ZombieHunter00001"}`,
+		`{"nftName":"ZombieHunter00001","opponentNftName":"ZombieChains00001"}`,
+	} {
+		err := create(body)
+		if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusBadRequest {
+			t.Errorf("Expected bad request for %s but got %v", body, err)
+		}
+	}
+
+	// declined challenges are closed, and open ones expire after the ttl
+	c, _ := newTestContext(http.MethodPost, "/user/challenges/1/decline", "", hunterOwner)
+	c.SetParamNames("challengeId")
+	c.SetParamValues("1")
+	if err := s.DeclineChallenge(c); err != nil {
+		t.Fatalf("Error declining %v", err)
+	}
+	if err := create(`{"nftName":"ZombieChains00001","opponentNftName":"ZombieHunter00001"}`); err != nil {
+		t.Fatalf("Error challenging again %v", err)
+	}
+	expired, _ := memoryStore.ExpireChallenges(c.Request().Context(), time.Now().Add(time.Hour))
+	if expired != 1 {
+		t.Errorf("Expected one expired challenge but got %d", expired)
+	}
+
+	challenges, _ := memoryStore.GetChallengesForUser(hunterOwner.ID, 10)
+	if len(challenges) != 2 || challenges[0].Status != db.ChallengeStatusExpired || challenges[1].Status != db.ChallengeStatusDeclined {
+		t.Errorf("Expected an expired and a declined challenge but got %v", challenges)
+	}
+}
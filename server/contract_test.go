@@ -0,0 +1,368 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/cookiejar"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/patrickmn/go-cache"
+	"github.com/reliablestaking/zombie-fight-club-server/api"
+	"github.com/reliablestaking/zombie-fight-club-server/cip8"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/metadata"
+	"github.com/reliablestaking/zombie-fight-club-server/zfcclient"
+)
+
+// contractType the go type a schema in api/openapi.yaml is rendered from, or bound into if it's a request
+type contractType struct {
+	goType  reflect.Type
+	request bool
+}
+
+// contractTypes every schema in the spec, a schema that isn't here fails TestSchemasMatchDtos
+var contractTypes = map[string]contractType{
+	"HTTPError":               {goType: reflect.TypeOf(echo.HTTPError{})},
+	"Error":                   {goType: reflect.TypeOf(ErrorResponse{})},
+	"FightLimitError":         {goType: reflect.TypeOf(FightLimitError{})},
+	"Version":                 {goType: reflect.TypeOf(Version{})},
+	"WalletLoginNonceRequest": {goType: reflect.TypeOf(WalletLoginNonce{}), request: true},
+	"WalletLoginNonce":        {goType: reflect.TypeOf(WalletLoginNonce{})},
+	"WalletLogin":             {goType: reflect.TypeOf(WalletLogin{}), request: true},
+	"Nft":                     {goType: reflect.TypeOf(Nft{})},
+	"ListNftRequest":          {goType: reflect.TypeOf(Nft{}), request: true},
+	"CreateFightRequest":      {goType: reflect.TypeOf(db.FightDto{}), request: true},
+	"Fight":                   {goType: reflect.TypeOf(db.FightDto{})},
+	"FightSeed":               {goType: reflect.TypeOf(db.FightSeed{})},
+	"CombatLog":               {goType: reflect.TypeOf(metadata.CombatLog{})},
+	"Breakdown":               {goType: reflect.TypeOf(metadata.Breakdown{})},
+	"Modifier":                {goType: reflect.TypeOf(metadata.Modifier{})},
+	"Round":                   {goType: reflect.TypeOf(metadata.Round{})},
+	"Attack":                  {goType: reflect.TypeOf(metadata.Attack{})},
+	"Refund":                  {goType: reflect.TypeOf(Refund{})},
+	"PaymentAsset":            {goType: reflect.TypeOf(db.PaymentAsset{})},
+	"MatchmakingRequest":      {goType: reflect.TypeOf(MatchmakingRequest{}), request: true},
+	"MatchmakingEntry":        {goType: reflect.TypeOf(MatchmakingEntry{})},
+	"ChallengeRequest":        {goType: reflect.TypeOf(ChallengeRequest{}), request: true},
+	"ChallengeCounterRequest": {goType: reflect.TypeOf(ChallengeCounterRequest{}), request: true},
+	"Challenge":               {goType: reflect.TypeOf(Challenge{})},
+	"Wager":                   {goType: reflect.TypeOf(Wager{})},
+	"Tournament":              {goType: reflect.TypeOf(Tournament{})},
+	"TournamentDetail":        {goType: reflect.TypeOf(TournamentDetail{})},
+	"TournamentEntry":         {goType: reflect.TypeOf(TournamentEntry{})},
+	"TournamentMatch":         {goType: reflect.TypeOf(TournamentMatch{})},
+	"TournamentEntryRequest":  {goType: reflect.TypeOf(TournamentEntryRequest{}), request: true},
+	"TournamentEntryPayment":  {goType: reflect.TypeOf(TournamentEntryPayment{})},
+	"Leaders":                 {goType: reflect.TypeOf(Leaders{})},
+	"RatingLeaders":           {goType: reflect.TypeOf(RatingLeaders{})},
+	"SeasonLeaders":           {goType: reflect.TypeOf(SeasonLeaders{})},
+	"Season":                  {goType: reflect.TypeOf(Season{})},
+	"SeasonStanding":          {goType: reflect.TypeOf(SeasonStanding{})},
+	"AdminFight":              {goType: reflect.TypeOf(AdminFight{})},
+	"AdminFightAction":        {goType: reflect.TypeOf(AdminFightAction{}), request: true},
+	"AdminRefund":             {goType: reflect.TypeOf(AdminRefund{}), request: true},
+	"AdminFightMetadata":      {goType: reflect.TypeOf(AdminFightMetadata{})},
+	"AdminUserRoleRequest":    {goType: reflect.TypeOf(AdminUserRole{}), request: true},
+	"AdminUserRole":           {goType: reflect.TypeOf(AdminUserRole{})},
+	"AdminAudit":              {goType: reflect.TypeOf(AdminAudit{})},
+	"EngineHealth":            {goType: reflect.TypeOf(EngineHealth{})},
+	"EngineStatus":            {goType: reflect.TypeOf(EngineStatus{})},
+}
+
+// jsonField a field of a go struct as encoding/json sees it
+type jsonField struct {
+	goType    reflect.Type
+	omitempty bool
+}
+
+// specField a property of a schema, with the allOf parts merged
+type specField struct {
+	schema   *api.Schema
+	required bool
+}
+
+func loadTestSpec(t *testing.T) *api.Document {
+	doc, err := api.Load()
+	if err != nil {
+		t.Fatalf("Error loading api spec %v", err)
+	}
+	return doc
+}
+
+func TestRoutesMatchSpec(t *testing.T) {
+	doc := loadTestSpec(t)
+
+	// the operationId of every route in the spec
+	specRoutes := map[string]string{}
+	for _, path := range doc.Paths {
+		for _, op := range path.Operations {
+			specRoutes[op.Method+" "+path.Path] = op.OperationID
+		}
+	}
+
+	e := Server{}.newRouter()
+	served := map[string]bool{}
+	for _, route := range e.Routes() {
+		// the group adds not found routes of echo's own
+		if strings.HasPrefix(route.Name, "github.com/labstack/echo") {
+			continue
+		}
+		// the name is the handler's, github.com/.../server.Server.GetFightById-fm
+		handler := strings.TrimSuffix(route.Name, "-fm")
+		handler = handler[strings.LastIndex(handler, ".")+1:]
+		route := route.Method + " " + specPath(route.Path)
+		served[route] = true
+
+		operationID, ok := specRoutes[route]
+		if !ok {
+			t.Errorf("%s is served by %s but isn't in api/openapi.yaml", route, handler)
+			continue
+		}
+		if operationID != handler {
+			t.Errorf("%s has operationId %s in api/openapi.yaml but is served by %s", route, operationID, handler)
+		}
+	}
+
+	for route := range specRoutes {
+		if !served[route] {
+			t.Errorf("%s is in api/openapi.yaml but isn't served", route)
+		}
+	}
+}
+
+// specPath an echo path the way the spec writes it, /fights/:fightId is /fights/{fightId}
+func specPath(path string) string {
+	parts := strings.Split(path, "/")
+	for i, part := range parts {
+		if strings.HasPrefix(part, ":") {
+			parts[i] = "{" + part[1:] + "}"
+		}
+	}
+	return strings.Join(parts, "/")
+}
+
+func TestSchemasMatchDtos(t *testing.T) {
+	doc := loadTestSpec(t)
+
+	for _, schema := range doc.Components.Schemas {
+		contract, ok := contractTypes[schema.Name]
+		if !ok {
+			t.Errorf("%s isn't mapped to a go type in contractTypes", schema.Name)
+			continue
+		}
+
+		goFields := jsonFields(contract.goType)
+		specFields := schemaFields(doc, schema.Schema)
+		for name, property := range specFields {
+			field, ok := goFields[name]
+			if !ok {
+				t.Errorf("%s.%s isn't a json field of %s", schema.Name, name, contract.goType)
+				continue
+			}
+			if err := matchKind(doc, property.schema, field.goType); err != nil {
+				t.Errorf("%s.%s doesn't match %s: %v", schema.Name, name, contract.goType, err)
+			}
+			// a response field is always rendered unless it's omitempty
+			if !contract.request && property.required == field.omitempty {
+				t.Errorf("%s.%s is required %t but omitempty %t in %s", schema.Name, name, property.required, field.omitempty, contract.goType)
+			}
+		}
+
+		// a request only has to document what can be sent, a response everything that's rendered
+		if contract.request {
+			continue
+		}
+		for name := range goFields {
+			if _, ok := specFields[name]; !ok {
+				t.Errorf("%s renders %s but it isn't in schema %s", contract.goType, name, schema.Name)
+			}
+		}
+	}
+}
+
+// jsonFields the fields encoding/json reads and writes, embedded structs flattened
+func jsonFields(goType reflect.Type) map[string]jsonField {
+	fields := map[string]jsonField{}
+	for goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+
+	for i := 0; i < goType.NumField(); i++ {
+		field := goType.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" || (!field.IsExported() && !field.Anonymous) {
+			continue
+		}
+		name, options, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			for embeddedName, embedded := range jsonFields(field.Type) {
+				fields[embeddedName] = embedded
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = jsonField{goType: field.Type, omitempty: strings.Contains(options, "omitempty")}
+	}
+
+	return fields
+}
+
+// schemaFields the properties of an object schema, allOf parts and the schemas they refer to merged
+func schemaFields(doc *api.Document, schema *api.Schema) map[string]specField {
+	fields := map[string]specField{}
+	if schema.Ref != "" {
+		return schemaFields(doc, doc.Schema(schema.Ref))
+	}
+	for _, part := range schema.AllOf {
+		for name, field := range schemaFields(doc, part) {
+			fields[name] = field
+		}
+	}
+	for _, property := range schema.Properties {
+		fields[property.Name] = specField{schema: property.Schema, required: schema.IsRequired(property.Name)}
+	}
+	return fields
+}
+
+// matchKind checks the go type encodes to json of the schema's type
+func matchKind(doc *api.Document, schema *api.Schema, goType reflect.Type) error {
+	for goType.Kind() == reflect.Ptr {
+		goType = goType.Elem()
+	}
+	rawMessage := reflect.TypeOf(json.RawMessage{})
+	if goType.Kind() == reflect.Interface || goType == rawMessage {
+		// any json, whatever the schema says
+		return nil
+	}
+
+	switch {
+	case schema.Ref != "":
+		contract, ok := contractTypes[api.RefName(schema.Ref)]
+		if !ok || contract.goType != goType {
+			return fmt.Errorf("%s isn't %s", schema.Ref, goType)
+		}
+		return nil
+	case len(schema.OneOf) > 0:
+		return fmt.Errorf("oneOf has to be json.RawMessage or an interface")
+	}
+
+	kinds := map[string][]reflect.Kind{
+		"string":  {reflect.String},
+		"integer": {reflect.Int, reflect.Int16, reflect.Int32, reflect.Int64},
+		"number":  {reflect.Float32, reflect.Float64},
+		"boolean": {reflect.Bool},
+		"array":   {reflect.Slice},
+		"object":  {reflect.Map},
+	}
+	if schema.Format == "date-time" {
+		if goType != reflect.TypeOf(time.Time{}) {
+			return fmt.Errorf("date-time isn't %s", goType)
+		}
+		return nil
+	}
+	if schema.Format == "int64" && goType.Kind() != reflect.Int64 {
+		return fmt.Errorf("int64 isn't %s", goType)
+	}
+	ok := false
+	for _, kind := range kinds[schema.Type] {
+		ok = ok || goType.Kind() == kind
+	}
+	if !ok {
+		return fmt.Errorf("%s isn't %s", schema.Type, goType)
+	}
+
+	switch {
+	case schema.Type == "array":
+		return matchKind(doc, schema.Items, goType.Elem())
+	case schema.Type == "object" && schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil:
+		return matchKind(doc, schema.AdditionalProperties.Schema, goType.Elem())
+	}
+	return nil
+}
+
+func TestGeneratedClientAgainstServer(t *testing.T) {
+	s, memoryStore, _ := newTestMintingServer(t)
+	s.LeaderCache = cache.New(time.Minute, time.Minute)
+	t.Setenv("SESSION_KEY", "secret")
+
+	server := httptest.NewServer(s.newRouter())
+	t.Cleanup(server.Close)
+	jar, _ := cookiejar.New(nil)
+	client := zfcclient.ZfcClient{HttpClient: http.Client{Jar: jar}, BaseUrl: server.URL}
+	ctx := context.Background()
+
+	// logged out the user routes are turned away
+	_, err := client.GetMyNfts(ctx)
+	responseErr := &zfcclient.ResponseError{}
+	if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("Expected unauthorized before logging in but got %v", err)
+	}
+
+	// log in with a wallet that holds the zombie
+	key, address, stakeAddress := newTestStakeKey("contract wallet")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	memoryStore.SetNftHolder(ctx, zombie.ID, &db.NftHolder{Address: "addr_ZombieChains00001", Account: stakeAddress}, time.Now())
+	nonce, err := client.CreateWalletLoginNonce(ctx, zfcclient.WalletLoginNonceRequest{StakeAddress: stakeAddress})
+	if err != nil {
+		t.Fatalf("Error getting nonce %v", err)
+	}
+	coseSign1, coseKey := cip8.Sign(key, address, []byte(nonce.Message))
+	err = client.LoginWithWallet(ctx, zfcclient.WalletLogin{StakeAddress: stakeAddress, Nonce: nonce.Nonce, Signature: fmt.Sprintf("%x", coseSign1), Key: fmt.Sprintf("%x", coseKey)})
+	if err != nil {
+		t.Fatalf("Error logging in %v", err)
+	}
+
+	nfts, err := client.GetMyNfts(ctx)
+	if err != nil || len(nfts) != 1 || nfts[0].Name != "ZombieChains00001" || nfts[0].ID != zombie.ID {
+		t.Fatalf("Expected the wallet's zombie but got %v %v", nfts, err)
+	}
+	listed, err := client.ListNftForFight(ctx, "ZombieChains00001", zfcclient.ListNftRequest{Name: "ZombieChains00001", ListedPriceAda: 7})
+	if err != nil || listed.ListedPriceAda == nil || *listed.ListedPriceAda != 7 {
+		t.Errorf("Expected the zombie listed for 7 ada but got %v %v", listed, err)
+	}
+	toFight, err := client.GetNftsToFight(ctx, zfcclient.GetNftsToFightParams{Type: "Hunter"})
+	if err != nil || len(toFight) == 0 {
+		t.Errorf("Expected nfts to fight but got %v %v", toFight, err)
+	}
+
+	fight, err := client.CreateFight(ctx, zfcclient.CreateFightRequest{ZombieName: "ZombieChains00001", HunterName: "ZombieHunter00001"})
+	if err != nil {
+		t.Fatalf("Error creating fight %v", err)
+	}
+	if fight.PaymentAddress != "addr_payment" || fight.ZombieSendAddress != "addr_ZombieChains00001" || fight.HunterSendAddress != "addr_ZombieHunter00001" {
+		t.Errorf("Unexpected fight %v", fight)
+	}
+	got, err := client.GetFightById(ctx, fight.ID)
+	if err != nil || got.ID != fight.ID || got.Status != "AWAITING_PAYMENT" {
+		t.Errorf("Expected fight %d but got %v %v", fight.ID, got, err)
+	}
+	if myFights, err := client.GetMyFights(ctx); err != nil || len(myFights) != 1 {
+		t.Errorf("Expected my one fight but got %v %v", myFights, err)
+	}
+
+	_, err = client.GetFightById(ctx, fight.ID+100)
+	if !errors.As(err, &responseErr) || responseErr.StatusCode != http.StatusNotFound {
+		t.Errorf("Expected a missing fight not found but got %v", err)
+	}
+
+	if _, err := client.GetTournaments(ctx); err != nil {
+		t.Errorf("Error getting tournaments %v", err)
+	}
+	leaders, err := client.GetLeaders(ctx, zfcclient.GetLeadersParams{})
+	if err != nil || json.Unmarshal(leaders, &zfcclient.Leaders{}) != nil {
+		t.Errorf("Expected leaders but got %s %v", leaders, err)
+	}
+	if _, err := client.GetVersion(ctx); err != nil {
+		t.Errorf("Error getting version %v", err)
+	}
+}
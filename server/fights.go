@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"database/sql"
 	"encoding/hex"
 	"fmt"
@@ -14,7 +15,7 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
-//GetMyNfts get all nfts I own
+// GetMyNfts get all nfts I own
 func (s Server) GetNftsToFight(c echo.Context) (err error) {
 	log := logrus.WithContext(c.Request().Context())
 
@@ -88,7 +89,7 @@ func (s Server) GetNftsToFight(c echo.Context) (err error) {
 	return c.JSON(http.StatusOK, nftDtos)
 }
 
-//CreateFight create new fight
+// CreateFight create new fight
 func (s Server) CreateFight(c echo.Context) (err error) {
 	log := logrus.WithContext(c.Request().Context())
 
@@ -168,113 +169,149 @@ func (s Server) CreateFight(c echo.Context) (err error) {
 	log.Infof("Hunter %s is valid", fight.HunterName)
 
 	// check that user owns at least one or the other (unless they are service account)
-	if dbUser.NftkeymeAccessToken != "" && zombies[0].UserID != dbUser.ID && hunters[0].UserID != dbUser.ID {
+	if !dbUser.ServiceAccount() && zombies[0].UserID != dbUser.ID && hunters[0].UserID != dbUser.ID {
 		log.Warn("User doesn't own zombie or hunter, can't create fight")
 		return echo.NewHTTPError(http.StatusBadRequest, "User doesn't own zombie or hunter, can't create fight")
 	}
 
-	// calculate cost
-	//zombieCost := int64(zombies[0].ListAmount.Int16)
-	// if zombies[0].UserID == dbUser.ID {
-	// 	log.Infof("User %d owns zombie %s so not cost", dbUser.ID, fight.ZombieName)
-	// 	//zombieCost = 0
-	// } else {
+	if err = s.checkFightLimits(c, *dbUser, zombies[0], hunters[0]); err != nil {
+		return err
+	}
+
+	err = s.prepareFight(c.Request().Context(), fight, &zombies[0], &hunters[0], *dbUser)
+	if err != nil {
+		return s.RenderError(err.Error(), c)
+	}
+
+	// persist
+	fightId, err := s.Store.CreateFight(*fight, hunters[0], zombies[0], *dbUser)
+	if err != nil {
+		log.WithError(err).Errorf("Error persisting fight")
+		return s.RenderError("Error persisting fight", c)
+	}
+	fight.ID = fightId
+
+	// set minutes til expired
+	fight.MinutesUntilExpired = int(s.paymentWindow().Minutes())
+
+	// set amount in ada
+	fight.PaymentAmountAda = lovelaceToString(int(fight.PaymentAmountLovelace))
+
+	return c.JSON(http.StatusOK, fight)
+}
+
+// prepareFight fills in what a new fight is paid to and how much: the owners' send addresses, the price less the
+// list price of the user's own nfts, the payment address and amount and the seed commitment. Errors are messages for
+// the user, the details are logged.
+func (s Server) prepareFight(ctx context.Context, fight *db.FightDto, zombie *db.UserNfts, hunter *db.UserNfts, dbUser db.User) error {
+	log := logrus.WithContext(ctx)
+
+	// calculate cost, the owner is paid their list price unless it's the user's own zombie
+	if zombie.UserID == dbUser.ID {
+		log.Infof("User %d owns zombie %s so no cost", dbUser.ID, fight.ZombieName)
+		zombie.ListAmount = sql.NullInt16{Int16: 0, Valid: true}
+	}
+
 	// figure out what address asset lives at
 	assetName := s.ZombiePolicyId + hex.EncodeToString([]byte(fight.ZombieName))
 	logrus.Infof("Finding address for asset %s", assetName)
-	addresses, err := s.BlockforstIpfsClient.GetAddressesForAsset(assetName)
+	addresses, err := s.Chain.AssetHolders(ctx, assetName)
 	if err != nil {
 		log.WithError(err).Errorf("Error getting asset address %s", fight.ZombieName)
-		return s.RenderError("Error getting asset address", c)
+		return fmt.Errorf("Error getting asset address")
 	} else if len(addresses) == 0 || addresses[0].Address == "" {
 		log.WithError(err).Errorf("No asset address %s", fight.HunterName)
-		return s.RenderError("No asset address", c)
+		return fmt.Errorf("No asset address")
 	}
 	logrus.Infof("Setting zombie send address to %d / %s / %s", len(addresses), addresses[0].Address, addresses[0].Quantity)
 	fight.ZombieSendAddress = addresses[0].Address
-	//}
 
-	//hunterCost := int64(hunters[0].ListAmount.Int16)
-	// if hunters[0].UserID == dbUser.ID {
-	// 	log.Infof("User %d owns hunter %s so not cost", dbUser.ID, fight.HunterName)
-	// 	//hunterCost = 0
-	// } else {
+	if hunter.UserID == dbUser.ID {
+		log.Infof("User %d owns hunter %s so no cost", dbUser.ID, fight.HunterName)
+		hunter.ListAmount = sql.NullInt16{Int16: 0, Valid: true}
+	}
+
 	// figure out what address asset lives at
 	assetName = s.HunterPolicyId + hex.EncodeToString([]byte(fight.HunterName))
-	addresses, err = s.BlockforstIpfsClient.GetAddressesForAsset(assetName)
+	addresses, err = s.Chain.AssetHolders(ctx, assetName)
 	if err != nil {
 		log.WithError(err).Errorf("Error getting asset address %s", fight.HunterName)
-		return s.RenderError("Error getting asset address", c)
+		return fmt.Errorf("Error getting asset address")
 	} else if len(addresses) == 0 {
 		log.WithError(err).Errorf("No asset address %s", fight.HunterName)
-		return s.RenderError("No asset address", c)
+		return fmt.Errorf("No asset address")
 	}
 	logrus.Infof("Setting hunter send address to %s", addresses[0].Address)
 	fight.HunterSendAddress = addresses[0].Address
-	// }
 
-	paymentAmountAda := int64(s.BaseCostAda)
-	logrus.Infof("Calculated a payment amoutn of %d", paymentAmountAda)
+	price := s.fightPrice(int(zombie.ListAmount.Int16), int(hunter.ListAmount.Int16))
+	logrus.Infof("Calculated a payment amoutn of %d", price)
 
-	// calculate dust
-	dustTry := 0
-	for {
-		if dustTry == 5 {
-			log.WithError(err).Errorf("Error getting unique payment amount")
-			return s.RenderError("Error getting unique payment amount", c)
+	if s.PaymentWallet != nil {
+		// own address per fight, so the amount doesn't have to tell payments apart
+		index, err := s.Store.NextPaymentAddressIndex()
+		if err != nil {
+			log.WithError(err).Errorf("Error reserving payment address index")
+			return fmt.Errorf("Error getting payment address")
 		}
-
-		cost := (paymentAmountAda * 1000000) + int64(rand.Intn(500000))
-
-		fightCheck, err := s.Store.GetFightForPaymentLastFifteen(cost)
+		address, err := s.PaymentWallet.PaymentAddress(uint32(index))
 		if err != nil {
-			log.WithError(err).Errorf("Error checking fights")
-			return s.RenderError("Error checking fights", c)
+			log.WithError(err).Errorf("Error deriving payment address %d", index)
+			return fmt.Errorf("Error getting payment address")
 		}
 
-		if fightCheck == nil {
-			fight.PaymentAmountLovelace = cost
-			break
-		}
+		fight.PaymentAmountLovelace = price
+		fight.PaymentAddress = address
+		fight.PaymentAddressIndex = sql.NullInt64{Int64: int64(index), Valid: true}
+	} else {
+		// calculate dust
+		dustTry := 0
+		for {
+			if dustTry == 5 {
+				log.WithError(err).Errorf("Error getting unique payment amount")
+				return fmt.Errorf("Error getting unique payment amount")
+			}
 
-		dustTry++
-	}
+			cost := price + int64(rand.Intn(500000))
 
-	// set payment address
-	fight.PaymentAddress = s.PaymentAddress
+			fightCheck, err := s.Store.GetUnpaidFightForAmount(cost, s.paymentWindow()+paymentGracePeriod)
+			if err != nil {
+				log.WithError(err).Errorf("Error checking fights")
+				return fmt.Errorf("Error checking fights")
+			}
 
-	// set initial status
-	fight.Status = "PENDING"
+			if fightCheck == nil {
+				fight.PaymentAmountLovelace = cost
+				break
+			}
 
-	// persist
-	fightId, err := s.Store.CreateFight(*fight, hunters[0], zombies[0], *dbUser)
-	if err != nil {
-		log.WithError(err).Errorf("Error persisting fight")
-		return s.RenderError("Error persisting fight", c)
+			dustTry++
+		}
+
+		// set payment address
+		fight.PaymentAddress = s.PaymentAddress
 	}
-	fight.ID = fightId
 
-	// set minutes til expired
-	fight.MinutesUntilExpired = 15
+	// commit to the secret the outcome is seeded from before anyone pays
+	secret, commitment, err := newFightSecret()
+	if err != nil {
+		log.WithError(err).Errorf("Error creating fight secret")
+		return fmt.Errorf("Error creating fight")
+	}
+	fight.SeedSecret = secret
+	fight.Seed = &db.FightSeed{Commitment: commitment}
 
-	// set amount in ada
-	fight.PaymentAmountAda = lovelaceToString(int(fight.PaymentAmountLovelace))
+	// set initial status
+	fight.Status = string(db.FightStatusPending)
 
-	return c.JSON(http.StatusOK, fight)
+	return nil
 }
 
 func lovelaceToString(lovelace int) string {
-	lovelaceString := strconv.Itoa(lovelace)
-
-	lovelaceLen := len(lovelaceString)
-
-	ada := lovelaceString[0 : lovelaceLen-6]
-	lace := lovelaceString[lovelaceLen-6 : lovelaceLen]
-
-	return ada + "." + lace
+	return fmt.Sprintf("%d.%06d", lovelace/1000000, lovelace%1000000)
 }
 
-//GetFightById gets fight by id
+// GetFightById gets fight by id
 func (s Server) GetFightById(c echo.Context) (err error) {
 	log := logrus.WithContext(c.Request().Context())
 
@@ -309,6 +346,37 @@ func (s Server) GetFightById(c echo.Context) (err error) {
 	return c.JSON(http.StatusOK, fightDtos[0])
 }
 
+// GetFightLog the combat log of a minted fight, the outcome is public on chain by then so anyone can replay it. Before
+// that the fight can still be voided or regenerated so its log isn't shown
+func (s Server) GetFightLog(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	fightId, err := strconv.Atoi(c.Param("fightId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid fight id")
+	}
+
+	fight, err := s.Store.GetFight(fightId)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting fight %d", fightId)
+		return s.RenderError("Error getting fight", c)
+	}
+	if fight == nil || (fight.Status != db.FightStatusMinted && fight.Status != db.FightStatusConfirmed) {
+		return c.String(http.StatusNotFound, "No combat log for fight")
+	}
+
+	combatLog, err := s.Store.GetFightCombatLog(c.Request().Context(), fightId)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting combat log for fight %d", fightId)
+		return s.RenderError("Error getting combat log", c)
+	}
+	if combatLog == "" {
+		return c.String(http.StatusNotFound, "No combat log for fight")
+	}
+
+	return c.JSONBlob(http.StatusOK, []byte(combatLog))
+}
+
 func (s Server) convertFightToDto(fights []db.FightDb) []db.FightDto {
 	dto := make([]db.FightDto, 0)
 
@@ -317,7 +385,7 @@ func (s Server) convertFightToDto(fights []db.FightDb) []db.FightDto {
 			ID:                    f.ID,
 			ZombieName:            f.ZombieName,
 			HunterName:            f.HunterName,
-			Status:                f.Status,
+			Status:                string(f.Status),
 			CreatedDate:           &f.CreatedDate,
 			PaymentAddress:        f.PaymentAddress,
 			PaymentAmountLovelace: f.PaymentAmountLovelace,
@@ -332,7 +400,7 @@ func (s Server) convertFightToDto(fights []db.FightDb) []db.FightDto {
 		}
 
 		fight.PaymentAmountAda = lovelaceToString(int(fight.PaymentAmountLovelace))
-		minutes := int(fight.CreatedDate.Add(15 * time.Minute).Sub(time.Now()).Minutes())
+		minutes := int(fight.CreatedDate.Add(s.paymentWindow()).Sub(time.Now()).Minutes())
 
 		//logrus.Infof("Comparing %s to %s with a diff of minutes %d", fight.CreatedDate, time.Now(), minutes)
 
@@ -342,17 +410,25 @@ func (s Server) convertFightToDto(fights []db.FightDb) []db.FightDto {
 			fight.MinutesUntilExpired = 0
 		}
 
-		// PENDING > QUEUED > STAGED > MINTED > CONFIRMED > MINTED
-		if fight.Status == "PENDING" {
-			if time.Now().Sub(*fight.CreatedDate).Minutes() > 15 {
+		// map fight status to what the user sees
+		if f.Status == db.FightStatusPending && f.TournamentID.Valid {
+			// waiting on its turn to be paid from the prize pool, it can't expire
+			fight.Status = "SCHEDULED"
+			fight.MinutesUntilExpired = 0
+		} else if f.Status == db.FightStatusPending {
+			if time.Now().Sub(*fight.CreatedDate) > s.paymentWindow() {
 				fight.Status = "EXPIRED"
 			} else {
 				fight.Status = "AWAITING_PAYMENT"
 			}
-		} else if fight.Status == "QUEUED" || fight.Status == "STAGED" || fight.Status == "MINTED" {
+		} else if f.Status == db.FightStatusQueued || f.Status == db.FightStatusStaged || f.Status == db.FightStatusMinted {
 			fight.Status = "PAYMENT_RECEIVED"
-		} else if fight.Status == "CONFIRMED" {
+		} else if f.Status == db.FightStatusConfirmed {
 			fight.Status = "MINTED"
+		} else if f.Status == db.FightStatusVoid {
+			// a fighter changed hands, anything paid is on its way back
+			fight.Status = "VOID"
+			fight.MinutesUntilExpired = 0
 		}
 
 		// set winner text
@@ -371,6 +447,8 @@ func (s Server) convertFightToDto(fights []db.FightDb) []db.FightDto {
 			fight.TweetLink = fmt.Sprintf("https://twitter.com/ZFCBot/status/%s", f.TweetID.String)
 		}
 
+		fight.Seed = fightSeedProof(f)
+
 		dto = append(dto, fight)
 	}
 
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/reliablestaking/zombie-fight-club-server/chain"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/metadata"
+)
+
+const (
+	testZombiePolicyID = "aa11"
+	testHunterPolicyID = "bb22"
+)
+
+// newTestStore seeds a memory store with a zombie owner, a hunter owner and a listed nft for each
+func newTestStore(t *testing.T) (*db.MemoryStore, db.User, db.User) {
+	memoryStore := db.NewMemoryStore()
+
+	zombie := memoryStore.AddNft("ZombieChains00001", "Zombie")
+	hunter := memoryStore.AddNft("ZombieHunter00001", "Hunter")
+	memoryStore.AddAlien(db.Alien{Name: "Alien00001", ReadableName: "Alien #00001", Background: "Space", Skin: "Green", Clothes: "Suit", Eyes: "Big", Mouth: "Smile", Hand: "Gun", Hat: "None"})
+
+	users := make([]db.User, 0)
+	for i, nft := range []*db.Nft{zombie, hunter} {
+		nftkeyID := []string{"zombie-owner", "hunter-owner"}[i]
+		if err := memoryStore.InsertUser(nftkeyID, "access", "refresh"); err != nil {
+			t.Fatalf("Error inserting user %v", err)
+		}
+		user, err := memoryStore.GetUserByNftkeyID(nftkeyID)
+		if err != nil || user == nil {
+			t.Fatalf("Error getting user %v", err)
+		}
+		if err := memoryStore.SetLastAssetCheckTime(user.ID, time.Now()); err != nil {
+			t.Fatalf("Error setting asset check time %v", err)
+		}
+		user, _ = memoryStore.GetUserByID(user.ID)
+		if err := memoryStore.InsertZcNftOwnedByUser(user.ID, nft.ID); err != nil {
+			t.Fatalf("Error inserting owned nft %v", err)
+		}
+		listPrice := int16(5)
+		if err := memoryStore.UpdateNftListPrice(&listPrice, user.ID, nft.ID); err != nil {
+			t.Fatalf("Error listing nft %v", err)
+		}
+		users = append(users, *user)
+	}
+
+	return memoryStore, users[0], users[1]
+}
+
+// newTestChain puts each listed nft at addr_<nft name>
+func newTestChain() *chain.FakeChain {
+	fakeChain := chain.NewFakeChain()
+	fakeChain.SetAssetHolder(testZombiePolicyID+hex.EncodeToString([]byte("ZombieChains00001")), "addr_ZombieChains00001")
+	fakeChain.SetAssetHolder(testHunterPolicyID+hex.EncodeToString([]byte("ZombieHunter00001")), "addr_ZombieHunter00001")
+
+	return fakeChain
+}
+
+func newTestContext(method string, path string, body string, user db.User) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.Set("user", &user)
+
+	return c, rec
+}
+
+func TestCreateFight(t *testing.T) {
+	memoryStore, zombieOwner, _ := newTestStore(t)
+	s := Server{
+		Store:          memoryStore,
+		Chain:          newTestChain(),
+		ZombiePolicyId: testZombiePolicyID,
+		HunterPolicyId: testHunterPolicyID,
+		BaseCostAda:    12,
+		PaymentAddress: "addr_payment",
+	}
+
+	c, rec := newTestContext(http.MethodPost, "/fights", `{"zombieName":"ZombieChains00001","hunterName":"ZombieHunter00001"}`, zombieOwner)
+	if err := s.CreateFight(c); err != nil {
+		t.Fatalf("Error creating fight %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	fight := db.FightDto{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &fight); err != nil {
+		t.Fatalf("Error decoding fight %v", err)
+	}
+	// base cost plus the hunter's 5 ada list price, the zombie is the user's own
+	if fight.PaymentAmountLovelace < 17000000 || fight.PaymentAmountLovelace >= 17500000 {
+		t.Errorf("Payment amount %d outside of base cost plus list price plus dust", fight.PaymentAmountLovelace)
+	}
+	if fight.PaymentAddress != "addr_payment" {
+		t.Errorf("Expected payment address addr_payment but got %s", fight.PaymentAddress)
+	}
+	if fight.ZombieSendAddress != "addr_ZombieChains00001" || fight.HunterSendAddress != "addr_ZombieHunter00001" {
+		t.Errorf("Unexpected send addresses %s / %s", fight.ZombieSendAddress, fight.HunterSendAddress)
+	}
+
+	// the new fight is waiting for payment
+	c, rec = newTestContext(http.MethodGet, "/fights/1", "", zombieOwner)
+	c.SetParamNames("fightId")
+	c.SetParamValues("1")
+	if err := s.GetFightById(c); err != nil {
+		t.Fatalf("Error getting fight %v", err)
+	}
+	created := db.FightDto{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("Error decoding fight %v", err)
+	}
+	if created.Status != "AWAITING_PAYMENT" {
+		t.Errorf("Expected status AWAITING_PAYMENT but got %s", created.Status)
+	}
+
+	// the commitment is published, the secret stays hidden until the fight is paid
+	if fight.Seed == nil || len(fight.Seed.Commitment) != 64 || fight.Seed.Secret != "" {
+		t.Errorf("Expected only a seed commitment but got %v", fight.Seed)
+	}
+	if created.Seed == nil || created.Seed.Commitment != fight.Seed.Commitment || created.Seed.Secret != "" {
+		t.Errorf("Expected the same commitment without the secret but got %v", created.Seed)
+	}
+}
+
+func TestCreateFightUnlistedNotOwned(t *testing.T) {
+	memoryStore, zombieOwner, hunterOwner := newTestStore(t)
+	s := Server{
+		Store:          memoryStore,
+		Chain:          newTestChain(),
+		ZombiePolicyId: testZombiePolicyID,
+		HunterPolicyId: testHunterPolicyID,
+		BaseCostAda:    12,
+	}
+
+	// hunter owner delists, so the zombie owner can't pick it anymore
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	if err := memoryStore.UpdateNftListPrice(nil, hunterOwner.ID, hunter.ID); err != nil {
+		t.Fatalf("Error delisting %v", err)
+	}
+
+	c, _ := newTestContext(http.MethodPost, "/fights", `{"zombieName":"ZombieChains00001","hunterName":"ZombieHunter00001"}`, zombieOwner)
+	err := s.CreateFight(c)
+	httpErr, ok := err.(*echo.HTTPError)
+	if !ok || httpErr.Code != http.StatusBadRequest {
+		t.Fatalf("Expected bad request but got %v", err)
+	}
+}
+
+func TestGetFightLog(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	fightID, _ := memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment"},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+
+	getLog := func() *httptest.ResponseRecorder {
+		c, rec := newTestContext(http.MethodGet, "/", "", db.User{})
+		c.SetParamNames("fightId")
+		c.SetParamValues(strconv.Itoa(fightID))
+		if err := s.GetFightLog(c); err != nil {
+			t.Fatalf("Error getting log %v", err)
+		}
+		return rec
+	}
+
+	// nothing to show until the fight is fought
+	if rec := getLog(); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 but got %d", rec.Code)
+	}
+
+	fakeChain.Pay("addr_buyer", "addr_payment", 12000123)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+
+	// fought but not minted, it can still be voided
+	if rec := getLog(); rec.Code != http.StatusNotFound {
+		t.Errorf("Expected 404 for a staged fight but got %d", rec.Code)
+	}
+	staged, _ := memoryStore.GetStagedFights()
+	if err := s.processStagedFights(); err != nil {
+		t.Fatalf("Error minting fights %v", err)
+	}
+
+	rec := getLog()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected 200 but got %d", rec.Code)
+	}
+	combatLog := metadata.CombatLog{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &combatLog); err != nil {
+		t.Fatalf("Error decoding log %v", err)
+	}
+
+	// the minted fight is what the log says happened
+	if len(combatLog.Rounds) == 0 || combatLog.Breakdown == nil || int64(combatLog.ZombieLifeBar) != staged[0].ZombieLifeBar.Int64 ||
+		int64(combatLog.HunterLifeBar) != staged[0].HunterLifeBar.Int64 || combatLog.HunterKO != staged[0].HunterKo.Bool {
+		t.Errorf("Log doesn't match the minted fight %s", rec.Body.String())
+	}
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"time"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/hdwallet"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// NftIndex how the minter keeps track of where every zombie and hunter is held
+	NftIndex struct {
+		// how many nfts are checked each pass, longest unchecked first, zero turns the indexer off
+		BatchSize int
+	}
+)
+
+// processNftHolders checks where the nfts checked longest ago are held now and records it in the index
+func (s Server) processNftHolders() error {
+	if s.NftIndex.BatchSize <= 0 {
+		return nil
+	}
+
+	ctx := context.Background()
+	nfts, err := s.Store.GetNftsToIndex(s.NftIndex.BatchSize)
+	if err != nil {
+		return err
+	}
+
+	for _, nft := range nfts {
+		holder, err := s.nftHolder(ctx, nft)
+		if err != nil {
+			return err
+		}
+
+		previous, err := s.Store.SetNftHolder(ctx, nft.ID, holder, time.Now())
+		if err != nil {
+			return err
+		}
+		if !previous.Valid || (holder != nil && previous.String == holder.Account) {
+			continue
+		}
+		if holder != nil {
+			logrus.Infof("Nft %s moved from %s to %s", nft.NftName, previous.String, holder.Account)
+		} else {
+			logrus.Infof("Nft %s left %s and isn't held anymore", nft.NftName, previous.String)
+		}
+		err = s.nftMoved(ctx, nft, holder)
+		if err != nil {
+			return err
+		}
+	}
+
+	logrus.Infof("Indexed holders of %d nfts", len(nfts))
+	return nil
+}
+
+// nftHolder where the nft is held on chain, nil when nothing holds it
+func (s Server) nftHolder(ctx context.Context, nft db.Nft) (*db.NftHolder, error) {
+	policyID := ""
+	switch nft.NftType {
+	case "Zombie":
+		policyID = s.ZombiePolicyId
+	case "Hunter":
+		policyID = s.HunterPolicyId
+	default:
+		return nil, nil
+	}
+
+	holders, err := s.Chain.AssetHolders(ctx, policyID+hex.EncodeToString([]byte(nft.NftName)))
+	if err != nil {
+		return nil, err
+	}
+	for _, holder := range holders {
+		if holder.Address != "" && holder.Quantity != "0" {
+			return &db.NftHolder{Address: holder.Address, Account: holderAccount(holder.Address)}, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// holderAccount the stake address of the address, or the address itself when it has no stake part
+func holderAccount(address string) string {
+	if stakeAddress := hdwallet.StakeAddressOf(address); stakeAddress != "" {
+		return stakeAddress
+	}
+	return address
+}
+
+// nftHeldByUser whether the index has the nft in one of the user's accounts, indexed is false until the indexer has
+// checked the nft
+func (s Server) nftHeldByUser(nft db.Nft, userID int) (bool, bool, error) {
+	if !nft.HolderCheckedDate.Valid {
+		return false, false, nil
+	}
+	if !nft.HolderAccount.Valid {
+		return false, true, nil
+	}
+
+	accounts, err := s.Store.GetUserAccounts(userID)
+	if err != nil {
+		return false, true, err
+	}
+
+	return containsString(accounts, nft.HolderAccount.String), true, nil
+}
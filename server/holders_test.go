@@ -0,0 +1,121 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/nftkeyme"
+	"golang.org/x/oauth2"
+)
+
+func TestProcessNftHolders(t *testing.T) {
+	memoryStore, zombieOwner, _ := newTestStore(t)
+	fakeChain := newTestChain()
+	s := Server{
+		Store:          memoryStore,
+		Chain:          fakeChain,
+		ZombiePolicyId: testZombiePolicyID,
+		HunterPolicyId: testHunterPolicyID,
+		NftIndex:       NftIndex{BatchSize: 1},
+	}
+
+	// one nft a pass, the one checked longest ago
+	for i := 0; i < 2; i++ {
+		if err := s.processNftHolders(); err != nil {
+			t.Fatalf("Error indexing holders %v", err)
+		}
+	}
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	if zombie.HolderAccount.String != "addr_ZombieChains00001" || hunter.HolderAccount.String != "addr_ZombieHunter00001" {
+		t.Fatalf("Expected both nfts indexed at their addresses but got %v and %v", zombie.HolderAccount, hunter.HolderAccount)
+	}
+
+	// the owner's account isn't known until their assets are checked
+	if owns, _ := s.doesUserOwnNft(context.Background(), "ZombieChains00001", zombieOwner.ID); owns {
+		t.Errorf("Expected the zombie not to be held in any of the owner's accounts")
+	}
+	memoryStore.AddUserAccount(zombieOwner.ID, "addr_ZombieChains00001")
+	if owns, _ := s.doesUserOwnNft(context.Background(), "ZombieChains00001", zombieOwner.ID); !owns {
+		t.Errorf("Expected the zombie to be held in the owner's account")
+	}
+
+	// a base address is indexed under its stake address
+	fakeChain.SetAssetHolder(testZombiePolicyID+hex.EncodeToString([]byte("ZombieChains00001")),
+		"addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x")
+	for i := 0; i < 2; i++ {
+		if err := s.processNftHolders(); err != nil {
+			t.Fatalf("Error indexing holders %v", err)
+		}
+	}
+	zombie, _ = memoryStore.GetNftByName("ZombieChains00001")
+	if zombie.HolderAccount.String != "stake1uyehkck0lajq8gr28t9uxnuvgcqrc6070x3k9r8048z8y5gh6ffgw" {
+		t.Fatalf("Expected the zombie indexed at its stake address but got %v", zombie.HolderAccount)
+	}
+	if owns, _ := s.doesUserOwnNft(context.Background(), "ZombieChains00001", zombieOwner.ID); owns {
+		t.Errorf("Expected the zombie not to be the owner's once it moved")
+	}
+}
+
+func TestNftkeymeAccountsGiveWayToProvenOnes(t *testing.T) {
+	memoryStore, zombieOwner, hunterOwner := newTestStore(t)
+
+	// nftkey.me says the zombie owner holds the zombie
+	nftkeymeServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/token":
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `{"access_token":"access","refresh_token":"refresh","token_type":"bearer"}`)
+		case r.URL.Query().Get("policyId") == testZombiePolicyID:
+			fmt.Fprintf(w, `[{"policy_id":"%s","asset_name":"%s","quantity":"1"}]`, testZombiePolicyID, hex.EncodeToString([]byte("ZombieChains00001")))
+		default:
+			fmt.Fprint(w, `[]`)
+		}
+	}))
+	defer nftkeymeServer.Close()
+	s := Server{
+		Store:               memoryStore,
+		Chain:               newTestChain(),
+		ZombiePolicyId:      testZombiePolicyID,
+		HunterPolicyId:      testHunterPolicyID,
+		NftkeymeOauthConfig: &oauth2.Config{Endpoint: oauth2.Endpoint{TokenURL: nftkeymeServer.URL + "/token"}},
+		NftkeymeClient:      nftkeyme.NftkeymeClient{BaseUrl: nftkeymeServer.URL},
+		NftIndex:            NftIndex{BatchSize: 2},
+	}
+	if err := s.processNftHolders(); err != nil {
+		t.Fatalf("Error indexing holders %v", err)
+	}
+
+	getAssets := func(user db.User) []Nft {
+		memoryStore.SetLastAssetCheckTime(user.ID, time.Now().Add(-2*time.Hour))
+		dbUser, _ := memoryStore.GetUserByID(user.ID)
+		nfts, err := s.GetAssetsForUser(context.Background(), *dbUser)
+		if err != nil {
+			t.Fatalf("Error getting assets %v", err)
+		}
+		return nfts
+	}
+
+	// the indexed zombie is the owner's through the account nftkey.me says they hold it in, and stays listed
+	if nfts := getAssets(zombieOwner); len(nfts) != 1 || nfts[0].Name != "ZombieChains00001" || nfts[0].ListedPriceAda == nil {
+		t.Fatalf("Expected the zombie still owned and listed but got %v", nfts)
+	}
+	if owns, _ := s.doesUserOwnNft(context.Background(), "ZombieChains00001", zombieOwner.ID); !owns {
+		t.Errorf("Expected the zombie held in the owner's account")
+	}
+
+	// someone else proving the account takes it over
+	memoryStore.AddUserAccount(hunterOwner.ID, "addr_ZombieChains00001")
+	if nfts := getAssets(zombieOwner); len(nfts) != 0 {
+		t.Errorf("Expected the zombie no longer owned but got %v", nfts)
+	}
+	if owns, _ := s.doesUserOwnNft(context.Background(), "ZombieChains00001", hunterOwner.ID); !owns {
+		t.Errorf("Expected the zombie held in the account that was proven")
+	}
+}
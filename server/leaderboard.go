@@ -2,6 +2,8 @@ package server
 
 import (
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	db "github.com/reliablestaking/zombie-fight-club-server/db"
@@ -14,17 +16,84 @@ type (
 		MostLosses  []Nft `json:"mostLoses"`
 		BestPercent []Nft `json:"bestPercent"`
 	}
+
+	// RatingLeaders nfts with the best glicko-2 ratings, ranked by rating less twice the deviation
+	RatingLeaders struct {
+		Rating []Nft `json:"rating"`
+	}
+
+	// SeasonLeaders leaderboards from records in a season, with the final standings once the season is closed
+	SeasonLeaders struct {
+		Season Season `json:"season"`
+		Leaders
+		Standings []SeasonStanding `json:"standings,omitempty"`
+	}
+
+	// Season a stretch of time with its own records and leaderboards
+	Season struct {
+		ID         int        `json:"id"`
+		Name       string     `json:"name"`
+		StartDate  time.Time  `json:"startDate"`
+		EndDate    time.Time  `json:"endDate"`
+		ClosedDate *time.Time `json:"closedDate,omitempty"`
+	}
+
+	// SeasonStanding an nft's final place in a closed season, rated as it was when the season closed
+	SeasonStanding struct {
+		Rank int `json:"rank"`
+		Nft
+	}
 )
 
-//GetLeaders get all leaders
+const (
+	boardRating = "rating"
+
+	// seasonCurrent season parameter for the season running now
+	seasonCurrent = "current"
+)
+
+//GetLeaders get all leaders, ?season= for a season's leaders by id or the current one
 func (s Server) GetLeaders(c echo.Context) (err error) {
 	log := logrus.WithContext(c.Request().Context())
 
-	leaderCache, found := s.LeaderCache.Get("leaders")
+	board := c.QueryParam("board")
+	if board != "" && board != boardRating {
+		return echo.NewHTTPError(http.StatusBadRequest, "Unknown board")
+	}
+
+	seasonParam := c.QueryParam("season")
+	if seasonParam != "" {
+		if board == boardRating {
+			return echo.NewHTTPError(http.StatusBadRequest, "Ratings aren't kept per season")
+		}
+		return s.getSeasonLeaders(c, seasonParam)
+	}
+
+	leaderCache, found := s.LeaderCache.Get("leaders" + board)
 	if found {
 		return c.JSON(http.StatusOK, leaderCache)
 	}
 
+	if board == boardRating {
+		rated, err := s.Store.GetNftHighestRating(10)
+		if err != nil {
+			log.WithError(err).Error("Error getting nfts")
+			return s.RenderError("Error getting nfts", c)
+		}
+
+		leaders := RatingLeaders{
+			Rating: s.convertDbNftToNftDto(rated),
+		}
+		for i := range rated {
+			leaders.Rating[i].Rating = &rated[i].Rating
+			leaders.Rating[i].RatingDeviation = &rated[i].RatingDeviation
+		}
+
+		s.LeaderCache.SetDefault("leaders"+board, leaders)
+
+		return c.JSON(http.StatusOK, leaders)
+	}
+
 	wins, err := s.Store.GetNftMostWins(10)
 	if err != nil {
 		log.WithError(err).Error("Error getting nfts")
@@ -47,11 +116,103 @@ func (s Server) GetLeaders(c echo.Context) (err error) {
 		BestPercent: s.convertDbNftToNftDto(percent),
 	}
 
-	c.Set("leaders", leaders)
+	s.LeaderCache.SetDefault("leaders", leaders)
+
+	return c.JSON(http.StatusOK, leaders)
+}
+
+// getSeasonLeaders leaderboards for a season by id, or the season running now
+func (s Server) getSeasonLeaders(c echo.Context, seasonParam string) error {
+	log := logrus.WithContext(c.Request().Context())
+
+	if seasonParam == seasonCurrent {
+		current, err := s.Store.GetCurrentSeason(time.Now())
+		if err != nil {
+			log.WithError(err).Error("Error getting current season")
+			return s.RenderError("Error getting season", c)
+		}
+		if current == nil {
+			return echo.NewHTTPError(http.StatusNotFound, "No season is running")
+		}
+		seasonParam = strconv.Itoa(current.ID)
+	}
+
+	cacheKey := "leadersseason" + seasonParam
+	leaderCache, found := s.LeaderCache.Get(cacheKey)
+	if found {
+		return c.JSON(http.StatusOK, leaderCache)
+	}
+
+	seasonID, err := strconv.Atoi(seasonParam)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Season must be an id or current")
+	}
+	season, err := s.Store.GetSeason(seasonID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting season %d", seasonID)
+		return s.RenderError("Error getting season", c)
+	}
+	if season == nil {
+		return echo.NewHTTPError(http.StatusNotFound, "Season not found")
+	}
+
+	wins, err := s.Store.GetSeasonMostWins(season.ID, 10)
+	if err != nil {
+		log.WithError(err).Error("Error getting nfts")
+		return s.RenderError("Error getting nfts", c)
+	}
+	loses, err := s.Store.GetSeasonMostLoses(season.ID, 10)
+	if err != nil {
+		log.WithError(err).Error("Error getting nfts")
+		return s.RenderError("Error getting nfts", c)
+	}
+	percent, err := s.Store.GetSeasonHighestPercentMinimum(season.ID, 10, 10)
+	if err != nil {
+		log.WithError(err).Error("Error getting nfts")
+		return s.RenderError("Error getting nfts", c)
+	}
+
+	leaders := SeasonLeaders{
+		Season: convertDbSeasonToSeasonDto(*season),
+		Leaders: Leaders{
+			MostWins:    s.convertDbNftToNftDto(wins),
+			MostLosses:  s.convertDbNftToNftDto(loses),
+			BestPercent: s.convertDbNftToNftDto(percent),
+		},
+	}
+
+	if season.ClosedDate.Valid {
+		standings, err := s.Store.GetSeasonStandings(season.ID)
+		if err != nil {
+			log.WithError(err).Errorf("Error getting standings for season %d", season.ID)
+			return s.RenderError("Error getting standings", c)
+		}
+		for i, standing := range standings {
+			nft := s.convertDbNftToNftDto([]db.Nft{{NftName: standing.NftName, NftType: standing.NftType, Wins: standing.Wins, Loses: standing.Loses}})[0]
+			nft.Rating = &standings[i].Rating
+			nft.RatingDeviation = &standings[i].RatingDeviation
+			leaders.Standings = append(leaders.Standings, SeasonStanding{Rank: standing.Rank, Nft: nft})
+		}
+	}
+
+	s.LeaderCache.SetDefault(cacheKey, leaders)
 
 	return c.JSON(http.StatusOK, leaders)
 }
 
+func convertDbSeasonToSeasonDto(season db.Season) Season {
+	dto := Season{
+		ID:        season.ID,
+		Name:      season.Name,
+		StartDate: season.StartDate,
+		EndDate:   season.EndDate,
+	}
+	if season.ClosedDate.Valid {
+		dto.ClosedDate = &season.ClosedDate.Time
+	}
+	return dto
+}
+
 func (s Server) convertDbNftToNftDto(nfts []db.Nft) []Nft {
 	dto := make([]Nft, 0)
 
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/patrickmn/go-cache"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+)
+
+func TestGetLeadersRating(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	s.LeaderCache = cache.New(cache.NoExpiration, cache.NoExpiration)
+
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment"},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+	fakeChain.Pay("addr_buyer", "addr_payment", 12000123)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+
+	c, rec := newTestContext(http.MethodGet, "/leaders?board=rating", "", db.User{})
+	if err := s.GetLeaders(c); err != nil {
+		t.Fatalf("Error getting leaders %v", err)
+	}
+	leaders := RatingLeaders{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &leaders); err != nil {
+		t.Fatalf("Error decoding leaders %v", err)
+	}
+
+	// the fight's winner tops the board
+	staged, _ := memoryStore.GetStagedFights()
+	winner := staged[0].HunterName
+	if staged[0].ZombieLifeBar.Int64 > staged[0].HunterLifeBar.Int64 {
+		winner = staged[0].ZombieName
+	}
+	if len(leaders.Rating) != 2 || leaders.Rating[0].Name != winner || *leaders.Rating[0].Rating <= 1500 || leaders.Rating[0].RatingDeviation == nil {
+		t.Errorf("Expected %s rated first but got %s", winner, rec.Body.String())
+	}
+
+	c, rec = newTestContext(http.MethodGet, "/leaders?board=elo", "", db.User{})
+	if err := s.GetLeaders(c); err == nil || err.(*echo.HTTPError).Code != http.StatusBadRequest {
+		t.Errorf("Expected 400 for an unknown board but got %v / %d", err, rec.Code)
+	}
+}
+
+func TestGetLeadersSeason(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	s.LeaderCache = cache.New(cache.NoExpiration, cache.NoExpiration)
+
+	c, _ := newTestContext(http.MethodGet, "/leaders?season=current", "", db.User{})
+	if err := s.GetLeaders(c); err == nil || err.(*echo.HTTPError).Code != http.StatusNotFound {
+		t.Errorf("Expected 404 outside of a season but got %v", err)
+	}
+
+	now := time.Now()
+	seasonID, _ := memoryStore.CreateSeason(context.Background(), "Season 1", now.Add(-time.Hour), now.Add(time.Hour))
+
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment"},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+	fakeChain.Pay("addr_buyer", "addr_payment", 12000123)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	memoryStore.CloseSeason(context.Background(), seasonID)
+
+	c, rec := newTestContext(http.MethodGet, fmt.Sprintf("/leaders?season=%d", seasonID), "", db.User{})
+	if err := s.GetLeaders(c); err != nil {
+		t.Fatalf("Error getting leaders %v", err)
+	}
+	leaders := SeasonLeaders{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &leaders); err != nil {
+		t.Fatalf("Error decoding leaders %v", err)
+	}
+	if leaders.Season.ID != seasonID || leaders.Season.ClosedDate == nil || len(leaders.MostWins) != 2 || leaders.MostWins[0].Wins != 1 {
+		t.Errorf("Unexpected season leaders %s", rec.Body.String())
+	}
+	if len(leaders.Standings) != 2 || leaders.Standings[0].Rank != 1 || leaders.Standings[0].Name != leaders.MostWins[0].Name || leaders.Standings[0].Rating == nil {
+		t.Errorf("Unexpected standings %s", rec.Body.String())
+	}
+
+	for _, query := range []string{"season=spring", "season=1&board=rating"} {
+		c, _ = newTestContext(http.MethodGet, "/leaders?"+query, "", db.User{})
+		if err := s.GetLeaders(c); err == nil || err.(*echo.HTTPError).Code != http.StatusBadRequest {
+			t.Errorf("Expected 400 for %s but got %v", query, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package server
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/metadata"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// FightLimitNftCooldown one of the nfts fought too recently
+	FightLimitNftCooldown = "NFT_COOLDOWN"
+	// FightLimitDaily the user created as many fights as they can in a day
+	FightLimitDaily = "DAILY_FIGHT_LIMIT"
+)
+
+type (
+	// FightLimits how often fights can be created, zero turns a limit off
+	FightLimits struct {
+		// how long an nft rests after a paid fight before it can be in a new one
+		NftCooldown time.Duration
+		// how many fights a user can create in 24 hours
+		DailyLimit int
+	}
+
+	// FightLimitError why a fight can't be created yet and when to try again
+	FightLimitError struct {
+		Code              string    `json:"code"`
+		Message           string    `json:"message"`
+		RetryAfterSeconds int       `json:"retryAfterSeconds"`
+		RetryAfter        time.Time `json:"retryAfter"`
+	}
+)
+
+// checkFightLimits turns away a fight when either nft is cooling down or the user hit their daily limit, service
+// accounts have no daily limit. Errors are http errors to return as is
+func (s Server) checkFightLimits(c echo.Context, dbUser db.User, zombie db.UserNfts, hunter db.UserNfts) error {
+	log := logrus.WithContext(c.Request().Context())
+	now := time.Now()
+
+	if s.FightLimits.NftCooldown > 0 {
+		for _, nft := range []db.UserNfts{zombie, hunter} {
+			last, err := s.Store.GetNftLastFightDate(nft.NftID)
+			if err != nil {
+				log.WithError(err).Errorf("Error getting last fight of %s", nft.NftName)
+				return echo.NewHTTPError(http.StatusInternalServerError, "Error checking fight limits")
+			}
+			if last != nil && now.Before(last.Add(s.FightLimits.NftCooldown)) {
+				log.Warnf("Nft %s is cooling down from a fight at %v", nft.NftName, *last)
+				return fightLimitError(c, FightLimitNftCooldown, fmt.Sprintf("%s is resting after its last fight", nft.NftName), last.Add(s.FightLimits.NftCooldown))
+			}
+		}
+	}
+
+	if s.FightLimits.DailyLimit > 0 && !dbUser.ServiceAccount() {
+		since := now.Add(-24 * time.Hour)
+		dates, err := s.Store.GetUserFightDates(dbUser.ID, since)
+		if err != nil {
+			log.WithError(err).Errorf("Error counting fights of user %d", dbUser.ID)
+			return echo.NewHTTPError(http.StatusInternalServerError, "Error checking fight limits")
+		}
+		if len(dates) >= s.FightLimits.DailyLimit {
+			log.Warnf("User %d created %d fights in the last day", dbUser.ID, len(dates))
+			// a fight can be created again once enough of them are a day old
+			return fightLimitError(c, FightLimitDaily, fmt.Sprintf("Limit of %d fights a day reached", s.FightLimits.DailyLimit), dates[len(dates)-s.FightLimits.DailyLimit].Add(24*time.Hour))
+		}
+	}
+
+	return nil
+}
+
+func fightLimitError(c echo.Context, code string, message string, retryAfter time.Time) error {
+	seconds := int(math.Ceil(time.Until(retryAfter).Seconds()))
+	c.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+
+	return echo.NewHTTPError(http.StatusTooManyRequests, FightLimitError{
+		Code:              code,
+		Message:           message,
+		RetryAfterSeconds: seconds,
+		RetryAfter:        retryAfter,
+	})
+}
+
+// recentFights how many paid fights each side was in within the fatigue window before this fight was created, none
+// when the fight's rule set has no fatigue
+func (s Server) recentFights(fight db.FightDb, season string) (map[string]int, error) {
+	_, ruleSet := s.fightRules().RuleSet(season)
+	if ruleSet.Fatigue == nil {
+		return nil, nil
+	}
+
+	recent := make(map[string]int)
+	for side, name := range map[string]string{metadata.SideZombie: fight.ZombieName, metadata.SideHunter: fight.HunterName} {
+		nft, err := s.Store.GetNftByName(name)
+		if err != nil {
+			return nil, err
+		}
+		if nft == nil {
+			return nil, fmt.Errorf("No nft %s for fight %d", name, fight.ID)
+		}
+		recent[side], err = s.Store.CountNftFights(nft.ID, fight.CreatedDate.Add(-ruleSet.Fatigue.Window()), fight.CreatedDate)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return recent, nil
+}
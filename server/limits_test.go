@@ -0,0 +1,88 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/metadata"
+)
+
+func TestFightLimits(t *testing.T) {
+	memoryStore, zombieOwner, _ := newTestStore(t)
+	s := Server{
+		Store:          memoryStore,
+		Chain:          newTestChain(),
+		ZombiePolicyId: testZombiePolicyID,
+		HunterPolicyId: testHunterPolicyID,
+		BaseCostAda:    12,
+		PaymentAddress: "addr_payment",
+		FightLimits:    FightLimits{NftCooldown: time.Hour, DailyLimit: 2},
+	}
+
+	create := func() (error, *FightLimitError) {
+		c, _ := newTestContext(http.MethodPost, "/fights", `{"zombieName":"ZombieChains00001","hunterName":"ZombieHunter00001"}`, zombieOwner)
+		err := s.CreateFight(c)
+		if httpErr, ok := err.(*echo.HTTPError); ok && httpErr.Code == http.StatusTooManyRequests {
+			limitErr := httpErr.Message.(FightLimitError)
+			if c.Response().Header().Get("Retry-After") == "" {
+				t.Errorf("Expected a Retry-After header")
+			}
+			return err, &limitErr
+		}
+		return err, nil
+	}
+
+	// unpaid fights don't put the nfts to rest but count toward the daily limit
+	for i := 0; i < 2; i++ {
+		if err, _ := create(); err != nil {
+			t.Fatalf("Error creating fight %v", err)
+		}
+	}
+	_, limitErr := create()
+	if limitErr == nil || limitErr.Code != FightLimitDaily || limitErr.RetryAfterSeconds <= 23*60*60 {
+		t.Fatalf("Expected the daily limit for about a day but got %v", limitErr)
+	}
+
+	// once a fight is paid both nfts rest
+	s.FightLimits.DailyLimit = 0
+	alien, _ := memoryStore.GetNextAvailableAlien()
+	if err := memoryStore.MoveFightFromPendingToQueued(context.Background(), 1, alien.ID, []db.FightPayment{{TxHash: "abc", SenderAddress: "addr_buyer", Lovelace: 17000000}}); err != nil {
+		t.Fatalf("Error paying fight %v", err)
+	}
+	_, limitErr = create()
+	if limitErr == nil || limitErr.Code != FightLimitNftCooldown || limitErr.RetryAfterSeconds > 60*60 {
+		t.Fatalf("Expected the nft cooldown for up to an hour but got %v", limitErr)
+	}
+}
+
+func TestRecentFights(t *testing.T) {
+	memoryStore, zombieOwner, _ := newTestStore(t)
+	s := Server{Store: memoryStore}
+
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	for i := 0; i < 2; i++ {
+		memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000000, PaymentAddress: "addr_payment"},
+			db.UserNfts{UserID: zombieOwner.ID, NftID: hunter.ID}, db.UserNfts{UserID: zombieOwner.ID, NftID: zombie.ID}, zombieOwner)
+	}
+	alien, _ := memoryStore.GetNextAvailableAlien()
+	memoryStore.MoveFightFromPendingToQueued(context.Background(), 1, alien.ID, []db.FightPayment{{TxHash: "abc", SenderAddress: "addr_buyer", Lovelace: 12000000}})
+	fights, _ := memoryStore.GetFightsForUserAndId(zombieOwner, 2)
+
+	// no fatigue without it in the rule set
+	if recent, err := s.recentFights(fights[0], ""); err != nil || recent != nil {
+		t.Fatalf("Expected no recent fights without fatigue but got %v %v", recent, err)
+	}
+
+	rules := metadata.DefaultRules()
+	rules.RuleSets["base"] = metadata.RuleSet{Fatigue: &metadata.Fatigue{WindowMinutes: 60, PerFight: -5}}
+	s.FightRules = &rules
+	recent, err := s.recentFights(fights[0], "")
+	if err != nil || recent[metadata.SideZombie] != 1 || recent[metadata.SideHunter] != 1 {
+		t.Errorf("Expected the paid fight before to count for both sides but got %v %v", recent, err)
+	}
+}
@@ -61,6 +61,17 @@ func (s Server) Login(c echo.Context) (err error) {
 		}
 	}
 
+	return s.saveSession(c, sessionNftkeyID, nftkeyUser.NftkeymeID)
+}
+
+const (
+	// session values, nftkey.me users are known by their nftkey.me id and wallet users by their stake address
+	sessionNftkeyID     = "nftId"
+	sessionStakeAddress = "stakeAddress"
+)
+
+// saveSession start a new session for the logged in user
+func (s Server) saveSession(c echo.Context, key string, value string) error {
 	sess, err := session.Get("session", c)
 	if err != nil {
 		logrus.WithError(err).Error("Error getting session")
@@ -77,8 +88,7 @@ func (s Server) Login(c echo.Context) (err error) {
 	}
 
 	sess.Values = make(map[interface{}]interface{})
-	//TODO: fix panic
-	sess.Values["nftId"] = nftkeyUser.NftkeymeID
+	sess.Values[key] = value
 	sess.Save(c.Request(), c.Response())
 
 	return c.JSON(http.StatusOK, nil)
@@ -0,0 +1,337 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+)
+
+const (
+	// MatchmakingByRating pairs nfts whose glicko-2 ratings are within the window
+	MatchmakingByRating = "rating"
+	// MatchmakingByRecord pairs nfts whose win percents are within the window, unfought nfts count as 50
+	MatchmakingByRecord = "record"
+
+	defaultMatchmakingRatingWindow = 150
+	defaultMatchmakingRecordWindow = 10
+	defaultMatchmakingQueueTime    = 30 * time.Minute
+
+	// matchmakingCandidates how many of the closest opponents are checked on chain before giving up for now
+	matchmakingCandidates = 3
+	matchmakingEntryLimit = 20
+)
+
+type (
+	// Matchmaking how queued nfts are paired with listed opponents
+	Matchmaking struct {
+		By        string
+		Window    float64
+		QueueTime time.Duration
+	}
+
+	// MatchmakingEntry what the user sees of a queued nft, with its fight once matched
+	MatchmakingEntry struct {
+		ID          int                  `json:"id"`
+		NftName     string               `json:"nftName"`
+		NftType     string               `json:"nftType"`
+		Status      db.MatchmakingStatus `json:"status"`
+		CreatedDate time.Time            `json:"createdDate"`
+		ExpiresDate time.Time            `json:"expiresDate"`
+		Fight       *db.FightDto         `json:"fight,omitempty"`
+	}
+
+	// MatchmakingRequest the nft a user queues
+	MatchmakingRequest struct {
+		NftName string `json:"nftName"`
+	}
+)
+
+func (s Server) matchmaking() Matchmaking {
+	m := s.Matchmaking
+	if m.By == "" {
+		m.By = MatchmakingByRating
+	}
+	if m.Window == 0 {
+		m.Window = defaultMatchmakingRatingWindow
+		if m.By == MatchmakingByRecord {
+			m.Window = defaultMatchmakingRecordWindow
+		}
+	}
+	if m.QueueTime == 0 {
+		m.QueueTime = defaultMatchmakingQueueTime
+	}
+	return m
+}
+
+// score where an nft sits on the scale opponents are matched by
+func (m Matchmaking) score(rating float64, wins int, loses int) float64 {
+	if m.By == MatchmakingByRecord {
+		if wins+loses == 0 {
+			return 50
+		}
+		return float64(wins) * 100 / float64(wins+loses)
+	}
+	return rating
+}
+
+// rank the opponents within the window of an nft, closest first, ties keep the order they were listed in
+func (m Matchmaking) rank(nft db.Nft, opponents []db.MatchmakingOpponent) []db.MatchmakingOpponent {
+	score := m.score(nft.Rating, nft.Wins, nft.Loses)
+	distance := func(opponent db.MatchmakingOpponent) float64 {
+		return math.Abs(m.score(opponent.Rating, opponent.Wins, opponent.Loses) - score)
+	}
+
+	ranked := make([]db.MatchmakingOpponent, 0)
+	for _, opponent := range opponents {
+		if distance(opponent) <= m.Window {
+			ranked = append(ranked, opponent)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return distance(ranked[i]) < distance(ranked[j])
+	})
+
+	return ranked
+}
+
+// GetMyMatchmaking get my latest matchmaking entries, queued ones are matched again first in case a new opponent
+// was listed
+func (s Server) GetMyMatchmaking(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	dbUser := c.Get("user").(*db.User)
+
+	if _, err = s.Store.ExpireMatchmakingEntries(c.Request().Context(), time.Now()); err != nil {
+		log.WithError(err).Error("Error expiring matchmaking entries")
+		return s.RenderError("Error getting matchmaking entries", c)
+	}
+
+	entries, err := s.Store.GetMatchmakingEntriesForUser(dbUser.ID, matchmakingEntryLimit)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting matchmaking entries for user %d", dbUser.ID)
+		return s.RenderError("Error getting matchmaking entries", c)
+	}
+
+	entryDtos := make([]MatchmakingEntry, 0)
+	for _, entry := range entries {
+		if entry.Status == db.MatchmakingStatusQueued {
+			s.tryMatch(c.Request().Context(), &entry, *dbUser)
+		}
+		entryDto, err := s.convertMatchmakingEntryToDto(entry, *dbUser)
+		if err != nil {
+			log.WithError(err).Errorf("Error getting fight for matchmaking entry %d", entry.ID)
+			return s.RenderError("Error getting matchmaking entries", c)
+		}
+		entryDtos = append(entryDtos, entryDto)
+	}
+
+	return c.JSON(http.StatusOK, entryDtos)
+}
+
+// QueueForMatchmaking queue one of my zombies or hunters to be paired with a listed opponent, it's matched right away
+// when there's one and otherwise waits until the queue time runs out
+func (s Server) QueueForMatchmaking(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	request := new(MatchmakingRequest)
+	if err = c.Bind(request); err != nil {
+		log.WithError(err).Errorf("Error binding")
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	dbUser := c.Get("user").(*db.User)
+	log.Infof("Queueing %s for matchmaking for user %d", request.NftName, dbUser.ID)
+
+	// has to be the user's own, listed or not
+	owned, err := s.Store.GetListedNftByName(request.NftName)
+	if err != nil {
+		log.WithError(err).Errorf("Error checking if user owns %s", request.NftName)
+		return s.RenderError("Error checking if user owns nft", c)
+	}
+	if len(owned) == 0 || owned[0].UserID != dbUser.ID {
+		log.Warnf("Nft %s not owned by user %d", request.NftName, dbUser.ID)
+		return echo.NewHTTPError(http.StatusBadRequest, "Nft not owned by user")
+	}
+	ownsNft, err := s.doesUserOwnNft(c.Request().Context(), request.NftName, dbUser.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Error checking user owns %s", request.NftName)
+		return s.RenderError("Error checking if user owns nft", c)
+	}
+	if !ownsNft {
+		log.Warnf("Nft %s not owned by user %d anymore", request.NftName, dbUser.ID)
+		return echo.NewHTTPError(http.StatusBadRequest, "Nft not owned by user anymore")
+	}
+
+	// entries past their queue time are expired first so they don't hold the nft
+	now := time.Now()
+	if _, err = s.Store.ExpireMatchmakingEntries(c.Request().Context(), now); err != nil {
+		log.WithError(err).Error("Error expiring matchmaking entries")
+		return s.RenderError("Error queueing nft", c)
+	}
+	entries, err := s.Store.GetMatchmakingEntriesForUser(dbUser.ID, matchmakingEntryLimit)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting matchmaking entries for user %d", dbUser.ID)
+		return s.RenderError("Error queueing nft", c)
+	}
+	for _, entry := range entries {
+		if entry.NftID == owned[0].NftID && entry.Status == db.MatchmakingStatusQueued {
+			return echo.NewHTTPError(http.StatusBadRequest, "Nft already queued")
+		}
+	}
+
+	entry := db.MatchmakingEntry{
+		UserID:      dbUser.ID,
+		NftID:       owned[0].NftID,
+		NftName:     owned[0].NftName,
+		NftType:     owned[0].NftType,
+		Status:      db.MatchmakingStatusQueued,
+		CreatedDate: now,
+		ExpiresDate: now.Add(s.matchmaking().QueueTime),
+	}
+	entry.ID, err = s.Store.QueueMatchmakingEntry(c.Request().Context(), entry)
+	if err != nil {
+		log.WithError(err).Errorf("Error queueing %s", request.NftName)
+		return s.RenderError("Error queueing nft", c)
+	}
+
+	s.tryMatch(c.Request().Context(), &entry, *dbUser)
+
+	entryDto, err := s.convertMatchmakingEntryToDto(entry, *dbUser)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting fight for matchmaking entry %d", entry.ID)
+		return s.RenderError("Error getting matchmaking entry", c)
+	}
+
+	return c.JSON(http.StatusOK, entryDto)
+}
+
+// DeleteMatchmakingEntry take one of my queued nfts out of the queue
+func (s Server) DeleteMatchmakingEntry(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	entryID, err := strconv.Atoi(c.Param("entryId"))
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Invalid entry id")
+	}
+
+	dbUser := c.Get("user").(*db.User)
+	err = s.Store.CancelMatchmakingEntry(c.Request().Context(), dbUser.ID, entryID)
+	if err != nil {
+		log.WithError(err).Warnf("Error cancelling matchmaking entry %d for user %d", entryID, dbUser.ID)
+		return echo.NewHTTPError(http.StatusBadRequest, "Entry isn't queued")
+	}
+
+	return c.NoContent(http.StatusOK)
+}
+
+// tryMatch matches a queued entry when there's an opponent for it, errors are logged and the entry stays queued
+func (s Server) tryMatch(ctx context.Context, entry *db.MatchmakingEntry, dbUser db.User) {
+	fightID, err := s.matchEntry(ctx, *entry, dbUser)
+	if err != nil {
+		logrus.WithContext(ctx).WithError(err).Errorf("Error matching matchmaking entry %d", entry.ID)
+		return
+	}
+	if fightID != 0 {
+		entry.Status = db.MatchmakingStatusMatched
+		entry.FightID = sql.NullInt64{Int64: int64(fightID), Valid: true}
+	}
+}
+
+// matchEntry pairs a queued entry with the closest listed opponent that's still owned by its owner and creates the
+// fight waiting for the user's payment, returns the fight's id or 0 when there's no opponent yet
+func (s Server) matchEntry(ctx context.Context, entry db.MatchmakingEntry, dbUser db.User) (int, error) {
+	log := logrus.WithContext(ctx)
+
+	nft, err := s.Store.GetNftByName(entry.NftName)
+	if err != nil {
+		return 0, err
+	}
+
+	opponentType := "Hunter"
+	if entry.NftType == "Hunter" {
+		opponentType = "Zombie"
+	}
+	opponents, err := s.Store.GetMatchmakingOpponents(opponentType, entry.UserID)
+	if err != nil {
+		return 0, err
+	}
+
+	ranked := s.matchmaking().rank(*nft, opponents)
+	for i, opponent := range ranked {
+		if i == matchmakingCandidates {
+			break
+		}
+
+		owned, err := s.doesUserOwnNft(ctx, opponent.NftName, opponent.UserID)
+		if err != nil {
+			return 0, err
+		}
+		if !owned {
+			log.Warnf("Skipping opponent %s not owned by user %d anymore", opponent.NftName, opponent.UserID)
+			continue
+		}
+
+		// the user's own side costs nothing, the opponent's owner is paid their list price
+		own := db.UserNfts{
+			UserID:     entry.UserID,
+			NftID:      entry.NftID,
+			NftName:    entry.NftName,
+			NftType:    entry.NftType,
+			ListAmount: sql.NullInt16{Int16: 0, Valid: true},
+		}
+		zombie, hunter := own, opponent.UserNfts
+		if entry.NftType == "Hunter" {
+			zombie, hunter = opponent.UserNfts, own
+		}
+
+		fight := &db.FightDto{
+			ZombieName: zombie.NftName,
+			HunterName: hunter.NftName,
+		}
+		if err = s.prepareFight(ctx, fight, &zombie, &hunter, dbUser); err != nil {
+			return 0, err
+		}
+
+		fightID, err := s.Store.MatchMatchmakingEntry(ctx, entry.ID, *fight, hunter, zombie, dbUser)
+		if err != nil {
+			return 0, err
+		}
+		log.Infof("Matched %s against %s in fight %d", entry.NftName, opponent.NftName, fightID)
+
+		return fightID, nil
+	}
+
+	log.Infof("No opponent for %s out of %d within the window", entry.NftName, len(ranked))
+	return 0, nil
+}
+
+func (s Server) convertMatchmakingEntryToDto(entry db.MatchmakingEntry, dbUser db.User) (MatchmakingEntry, error) {
+	entryDto := MatchmakingEntry{
+		ID:          entry.ID,
+		NftName:     entry.NftName,
+		NftType:     entry.NftType,
+		Status:      entry.Status,
+		CreatedDate: entry.CreatedDate,
+		ExpiresDate: entry.ExpiresDate,
+	}
+
+	if entry.FightID.Valid {
+		fights, err := s.Store.GetFightsForUserAndId(dbUser, int(entry.FightID.Int64))
+		if err != nil {
+			return entryDto, err
+		}
+		if len(fights) > 0 {
+			entryDto.Fight = &s.convertFightToDto(fights)[0]
+		}
+	}
+
+	return entryDto, nil
+}
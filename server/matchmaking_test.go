@@ -0,0 +1,167 @@
+package server
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+)
+
+func TestMatchmakingRank(t *testing.T) {
+	opponents := []db.MatchmakingOpponent{
+		{UserNfts: db.UserNfts{NftName: "far"}, Rating: 1800},
+		{UserNfts: db.UserNfts{NftName: "near", Wins: 3, Loses: 1}, Rating: 1550},
+		{UserNfts: db.UserNfts{NftName: "nearest"}, Rating: 1480},
+		{UserNfts: db.UserNfts{NftName: "unfought"}, Rating: 1500},
+	}
+	nft := db.Nft{Rating: 1490, Wins: 1, Loses: 1}
+
+	names := func(ranked []db.MatchmakingOpponent) []string {
+		n := make([]string, 0)
+		for _, opponent := range ranked {
+			n = append(n, opponent.NftName)
+		}
+		return n
+	}
+
+	byRating := names(Server{}.matchmaking().rank(nft, opponents))
+	if len(byRating) != 3 || byRating[0] != "nearest" || byRating[1] != "unfought" || byRating[2] != "near" {
+		t.Errorf("Expected nearest, unfought and near by rating but got %v", byRating)
+	}
+
+	// 50 percent, unfought counts as 50 and 75 percent is outside the window
+	byRecord := names(Server{Matchmaking: Matchmaking{By: MatchmakingByRecord}}.matchmaking().rank(nft, opponents))
+	if len(byRecord) != 3 || byRecord[0] != "far" || byRecord[1] != "nearest" || byRecord[2] != "unfought" {
+		t.Errorf("Expected the unfought opponents in listing order by record but got %v", byRecord)
+	}
+}
+
+func TestQueueForMatchmaking(t *testing.T) {
+	memoryStore, zombieOwner, _ := newTestStore(t)
+	fakeChain := newTestChain()
+	s := Server{
+		Store:          memoryStore,
+		Chain:          fakeChain,
+		ZombiePolicyId: testZombiePolicyID,
+		HunterPolicyId: testHunterPolicyID,
+		BaseCostAda:    12,
+		PaymentAddress: "addr_payment",
+	}
+
+	queue := func(body string) (*MatchmakingEntry, error) {
+		c, rec := newTestContext(http.MethodPost, "/user/matchmaking", body, zombieOwner)
+		if err := s.QueueForMatchmaking(c); err != nil {
+			return nil, err
+		}
+		entry := MatchmakingEntry{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil {
+			t.Fatalf("Error decoding entry %v", err)
+		}
+		return &entry, nil
+	}
+
+	entry, err := queue(`{"nftName":"ZombieChains00001"}`)
+	if err != nil {
+		t.Fatalf("Error queueing %v", err)
+	}
+	if entry.Status != db.MatchmakingStatusMatched || entry.Fight == nil {
+		t.Fatalf("Expected a match against the listed hunter but got %v", entry)
+	}
+	if entry.Fight.HunterName != "ZombieHunter00001" || entry.Fight.Status != "AWAITING_PAYMENT" {
+		t.Errorf("Expected a fight awaiting payment against ZombieHunter00001 but got %v", entry.Fight)
+	}
+	// base cost plus the hunter's 5 ada list price, the zombie is the user's own
+	if entry.Fight.PaymentAmountLovelace < 17000000 || entry.Fight.PaymentAmountLovelace >= 17500000 {
+		t.Errorf("Payment amount %d outside of base cost plus list price plus dust", entry.Fight.PaymentAmountLovelace)
+	}
+
+	// the only hunter is rated well above the zombie, so the next one waits in the queue
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	memoryStore.SetNftRating(hunter.ID, 1800, 100)
+	zombie := addTestNft(t, memoryStore, zombieOwner, "ZombieChains00002", "Zombie")
+	fakeChain.SetAssetHolder(testZombiePolicyID+hex.EncodeToString([]byte(zombie.NftName)), "addr_ZombieChains00002")
+
+	entry, err = queue(`{"nftName":"ZombieChains00002"}`)
+	if err != nil {
+		t.Fatalf("Error queueing %v", err)
+	}
+	if entry.Status != db.MatchmakingStatusQueued || entry.Fight != nil {
+		t.Fatalf("Expected the zombie to stay queued but got %v", entry)
+	}
+
+	// queued twice, someone else's nft and one that doesn't exist are rejected
+	for _, body := range []string{`{"nftName":"ZombieChains00002"}`, `{"nftName":"ZombieHunter00001"}`, `{"nftName":"ZombieChains09999"}`} {
+		_, err = queue(body)
+		httpErr, ok := err.(*echo.HTTPError)
+		if !ok || httpErr.Code != http.StatusBadRequest {
+			t.Errorf("Expected bad request for %s but got %v", body, err)
+		}
+	}
+
+	// once the hunter is rated closer the queued zombie is matched when the user checks
+	memoryStore.SetNftRating(hunter.ID, 1550, 100)
+	c, rec := newTestContext(http.MethodGet, "/user/matchmaking", "", zombieOwner)
+	if err = s.GetMyMatchmaking(c); err != nil {
+		t.Fatalf("Error getting matchmaking %v", err)
+	}
+	entries := make([]MatchmakingEntry, 0)
+	json.Unmarshal(rec.Body.Bytes(), &entries)
+	if len(entries) != 2 || entries[0].Status != db.MatchmakingStatusMatched || entries[0].Fight.ZombieName != "ZombieChains00002" {
+		t.Errorf("Expected the second zombie to be matched but got %v", entries)
+	}
+}
+
+func TestMatchmakingEntryExpires(t *testing.T) {
+	memoryStore, zombieOwner, hunterOwner := newTestStore(t)
+	s := Server{
+		Store:       memoryStore,
+		Chain:       newTestChain(),
+		Matchmaking: Matchmaking{Window: 50},
+	}
+
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	memoryStore.SetNftRating(hunter.ID, 1800, 100)
+
+	c, _ := newTestContext(http.MethodPost, "/user/matchmaking", `{"nftName":"ZombieChains00001"}`, zombieOwner)
+	if err := s.QueueForMatchmaking(c); err != nil {
+		t.Fatalf("Error queueing %v", err)
+	}
+
+	// nothing to match against, the entry expires once its queue time has passed
+	expired, err := memoryStore.ExpireMatchmakingEntries(c.Request().Context(), time.Now().Add(defaultMatchmakingQueueTime))
+	if err != nil || expired != 1 {
+		t.Fatalf("Expected one expired entry but got %d %v", expired, err)
+	}
+
+	// and the zombie can be queued again, then taken out of the queue, only by its owner
+	c, rec := newTestContext(http.MethodPost, "/user/matchmaking", `{"nftName":"ZombieChains00001"}`, zombieOwner)
+	if err = s.QueueForMatchmaking(c); err != nil {
+		t.Fatalf("Error queueing again %v", err)
+	}
+	entry := MatchmakingEntry{}
+	json.Unmarshal(rec.Body.Bytes(), &entry)
+
+	for _, user := range []db.User{hunterOwner, zombieOwner} {
+		c, _ = newTestContext(http.MethodDelete, "/user/matchmaking/1", "", user)
+		c.SetParamNames("entryId")
+		c.SetParamValues(strconv.Itoa(entry.ID))
+		err = s.DeleteMatchmakingEntry(c)
+		if user.ID == hunterOwner.ID {
+			if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusBadRequest {
+				t.Errorf("Expected bad request cancelling someone else's entry but got %v", err)
+			}
+		} else if err != nil {
+			t.Errorf("Error cancelling entry %v", err)
+		}
+	}
+
+	entries, _ := memoryStore.GetMatchmakingEntriesForUser(zombieOwner.ID, 10)
+	if len(entries) != 2 || entries[0].Status != db.MatchmakingStatusCancelled || entries[1].Status != db.MatchmakingStatusExpired {
+		t.Errorf("Expected a cancelled and an expired entry but got %v", entries)
+	}
+}
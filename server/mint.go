@@ -2,19 +2,17 @@ package server
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"io"
 	"io/ioutil"
-	"net/http"
 	"os"
 	"strconv"
 	"strings"
 	"time"
 
-	bfg "github.com/blockfrost/blockfrost-go"
 	"github.com/google/uuid"
-	"github.com/labstack/echo/v4"
 	cli "github.com/reliablestaking/zombie-fight-club-server/cardanocli"
 	store "github.com/reliablestaking/zombie-fight-club-server/db"
 	"github.com/reliablestaking/zombie-fight-club-server/imagebuilder"
@@ -39,6 +37,7 @@ type (
 		Copyright    *string           `json:"copyright,omitempty"`
 		Type         *string           `json:"type,omitempty"`
 		FileMetadata []FileMetadata    `json:"files,omitempty"`
+		Seed         *store.FightSeed  `json:"seed,omitempty"`
 	}
 
 	FileMetadata struct {
@@ -46,12 +45,6 @@ type (
 		Name      string   `json:"name"`
 		Src       []string `json:"src"`
 	}
-
-	NFTReturn struct {
-		ReturnToAddress string `json:"ReturnToAddress"`
-		FromUtxo        string `json:"FromUtxo"`
-		FromUtxoAmount  int    `json:"FromUtxoAmount"`
-	}
 )
 
 func (s Server) RunMintingEngine() {
@@ -62,12 +55,16 @@ func (s Server) RunMintingEngine() {
 	}
 
 	errorOnCheck := false
-	refundCheck := 0
 
+	passes := 0
 	for {
-		logrus.Infof("Running minting check for address %s", s.PaymentAddress)
+		// the admin api tells from the heartbeat whether the minter is running
+		if passes > 0 {
+			s.recordEnginePass(mintingEngine, errorOnCheck)
+		}
+		passes++
 
-		returns := make([]NFTReturn, 0)
+		logrus.Infof("Running minting check for address %s", s.PaymentAddress)
 
 		// sleep if an error
 		if errorOnCheck {
@@ -76,436 +73,620 @@ func (s Server) RunMintingEngine() {
 			errorOnCheck = false
 		}
 
-		// get all utxos
-		utxos, err := getAllUtxos(s.PaymentAddress, s.BlockfrostClient)
+		// every step below checkpoints its side effects, so after a crash the next pass
+		// resumes each fight where it stopped instead of redoing the work
+		err := s.processIncomingPayments()
 		if err != nil {
-			logrus.WithError(err).Errorf("Error getting address utxos")
+			logrus.WithError(err).Errorf("Error processing incoming payments")
 			errorOnCheck = true
 			continue
 		}
-		logrus.Infof("Found %d utxos for address", len(utxos))
 
-		// loop through them
-		for _, utxo := range utxos {
-			// have we seen this before?
-			existingFight, err := s.Store.GetFightForUtxo(utxo.TxHash, utxo.OutputIndex)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error getting fight for utxo and index")
-				errorOnCheck = true
-				break
-			}
+		// tournaments only pay for their fights from the prize pool, a failed pass is retried on the next one
+		err = s.processTournaments()
+		if err != nil {
+			logrus.WithError(err).Errorf("Error processing tournaments")
+		}
 
-			if len(existingFight) == 1 {
-				logrus.Infof("Found fight with id %d, nothing to do", existingFight[0].ID)
-				continue
-			}
+		// wagers keep their deposits and payouts in the db, a failed pass is retried on the next one
+		err = s.processWagers()
+		if err != nil {
+			logrus.WithError(err).Errorf("Error processing wagers")
+		}
+
+		// the index is only ever refreshed, a failed pass is retried on the next one
+		err = s.processNftHolders()
+		if err != nil {
+			logrus.WithError(err).Errorf("Error indexing nft holders")
+		}
 
-			logrus.Infof("Utxo %s with index %d not seen before, check if valid for minting...", utxo.TxHash, utxo.OutputIndex)
+		err = s.processQueuedFights()
+		if err != nil {
+			logrus.WithError(err).Errorf("Error processing queued fights")
+			errorOnCheck = true
+			continue
+		}
 
-			// make sure amount is lovelace
-			// make sure valid
-			if len(utxo.Amount) > 1 {
-				logrus.Errorf("Utxo %s has more than 1 amount, ignoring...", utxo.TxHash)
-				continue
-			} else if utxo.Amount[0].Unit != "lovelace" { // make sure is lovelace
-				logrus.WithError(err).Errorf("Utxo %s isn't lovelace", utxo.TxHash)
-				continue
-			}
+		err = s.processFightTweets()
+		if err != nil {
+			logrus.WithError(err).Errorf("Error tweeting fights")
+			errorOnCheck = true
+			continue
+		}
 
-			// convert to int
-			utxoQuantity, err := strconv.Atoi(utxo.Amount[0].Quantity)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error converting quantity")
-				errorOnCheck = true
-				break
-			}
+		err = s.processStagedFights()
+		if err != nil {
+			logrus.WithError(err).Errorf("Error minting staged fights")
+			errorOnCheck = true
+		}
 
-			// does it match an existing mint
-			matchingFight, err := s.Store.GetFightForPaymentLastFifteen(int64(utxoQuantity))
+		// refunds are kept in the ledger, so a failed pass is retried on the next one
+		if processRefunds {
+			err = s.processRefunds()
 			if err != nil {
-				logrus.WithError(err).Errorf("Error finding payment for quantity %d", utxoQuantity)
-				errorOnCheck = true
-				break
+				logrus.WithError(err).Errorf("Error processing refunds")
 			}
+		}
 
-			if matchingFight == nil {
-				logrus.Warnf("No matching fight found for utxo %s and amount %d, returning...", utxo.TxHash, utxoQuantity)
-				if len(returns) < 10 {
-					logrus.Infof("Returning utxo %s with quantity %d", utxo.TxHash, utxoQuantity)
-					// find from address
-					txUtxos, err := s.BlockfrostClient.TransactionUTXOs(context.Background(), utxo.TxHash)
-					if err != nil {
-						logrus.WithError(err).Errorf("Error getting transaction utxo %s", utxo.TxHash)
-						errorOnCheck = true
-						break
-					}
-					returnAddress := txUtxos.Inputs[0].Address
-
-					returnNft := NFTReturn{
-						FromUtxo:        fmt.Sprintf("%s#%d", utxo.TxHash, utxo.OutputIndex),
-						FromUtxoAmount:  utxoQuantity,
-						ReturnToAddress: returnAddress,
-					}
-					returns = append(returns, returnNft)
-				}
-				continue
-			}
+		err = s.processMintedFights()
+		if err != nil {
+			logrus.WithError(err).Errorf("Error confirming minted fights")
+			errorOnCheck = true
+			continue
+		}
+
+		logrus.Info("Sleeping for 30 seconds")
+		time.Sleep(30 * time.Second)
+	}
+}
 
-			logrus.Infof("Utxo %s is valid and we should mint for fight %d", utxo.TxHash, matchingFight.ID)
+// processIncomingPayments matches new utxos at the payment addresses to pending fights, recording the ones to refund
+func (s Server) processIncomingPayments() error {
+	ctx := context.Background()
 
-			// update alien and fight with utxo and fight status from PENDING to QUEUED in atomic tx
-			err = s.moveFightFromPendingToQueued(*matchingFight, utxo.TxHash, utxo.OutputIndex)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error moving fight to queued %d", matchingFight.ID)
-				errorOnCheck = true
-				break
-			}
+	// fights with their own address are kept an extra window so late payments to it still get refunded, voided ones
+	// too so payments to them are returned
+	fights, err := s.Store.GetFightsAwaitingPayment(2*s.paymentWindow() + paymentGracePeriod)
+	if err != nil {
+		return err
+	}
+	cutoff := time.Now().Add(-(s.paymentWindow() + paymentGracePeriod))
+
+	sharedFights := make([]store.FightDb, 0)
+	derivedFights := make([]store.FightDb, 0)
+	for _, fight := range fights {
+		if fight.PaymentAddressIndex.Valid {
+			derivedFights = append(derivedFights, fight)
+		} else if fight.CreatedDate.After(cutoff) && fight.Status == store.FightStatusPending {
+			sharedFights = append(sharedFights, fight)
 		}
+	}
+
+	err = s.processSharedAddressPayments(ctx, sharedFights)
+	if err != nil {
+		return err
+	}
+
+	return s.processDerivedAddressPayments(ctx, derivedFights, cutoff)
+}
 
-		// loop over status of fights, mint a max of 10
-		queuedFights, err := s.Store.GetQueuedFight()
+// processSharedAddressPayments matches utxos at the shared payment address to fights by amount
+func (s Server) processSharedAddressPayments(ctx context.Context, fights []store.FightDb) error {
+	senders := make(map[string]string)
+	payments, err := s.unseenPayments(ctx, s.PaymentAddress, senders)
+	if err != nil {
+		return err
+	}
+
+	if len(payments) == 0 {
+		return nil
+	}
+
+	matches, held, unmatched := matchPayments(fights, payments, s.PaymentTolerance)
+	for _, match := range matches {
+		logrus.Infof("%d utxos are valid and we should mint for fight %d", len(match.Payments), match.Fight.ID)
+
+		// update alien and fight with utxo and fight status from PENDING to QUEUED in atomic tx
+		err = s.moveFightFromPendingToQueued(match.Fight, match.Payments)
 		if err != nil {
-			logrus.WithError(err).Errorf("Error getting queued fights")
-			errorOnCheck = true
-			break
+			return err
 		}
+	}
 
-		i := 0
-		logrus.Infof("Found %d queued fights", len(queuedFights))
+	// the rest of a payment comes within a fight's payment window, after that what's held goes back
+	holdCutoff := time.Now().Add(-(s.paymentWindow() + paymentGracePeriod))
+	for _, payment := range held {
+		firstSeen, err := s.Store.HoldPayment(ctx, payment.TxHash, payment.OutputIndex, time.Now())
+		if err != nil {
+			return err
+		}
+		if firstSeen.Before(holdCutoff) {
+			logrus.Warnf("Utxo %s#%d from %s was held since %v and the rest never arrived", payment.TxHash, payment.OutputIndex, payment.SenderAddress, firstSeen)
+			unmatched = append(unmatched, payment)
+			continue
+		}
+		logrus.Infof("Holding utxo %s#%d from %s until the rest of the payment arrives", payment.TxHash, payment.OutputIndex, payment.SenderAddress)
+	}
 
-		for _, fight := range queuedFights {
-			if i == 10 {
-				logrus.Info("Already minted 10")
-				break
-			}
+	for _, payment := range unmatched {
+		logrus.Warnf("No matching fight found for utxo %s and amount %d, recording refund...", payment.TxHash, payment.Lovelace)
+		err = s.Store.RecordRefund(ctx, refundForPayment(payment, s.PaymentAddress, sql.NullInt64{}))
+		if err != nil {
+			return err
+		}
+	}
 
-			logrus.Infof("Minting fight for id %d", fight.ID)
+	return nil
+}
 
-			// create working directory
-			dirName := "work/" + uuid.New().String()
-			err := os.Mkdir(dirName, 0755)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error creating directory")
-				errorOnCheck = true
-				break
-			}
+// processQueuedFights resolves up to 10 queued fights and stages them for minting
+func (s Server) processQueuedFights() error {
+	queuedFights, err := s.Store.GetQueuedFight()
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Found %d queued fights", len(queuedFights))
 
-			// build alien image and upload to ipfs
-			alien, err := s.Store.GetAlienByFightId(fight.ID)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error getting alient for fight id %d", fight.ID)
-				errorOnCheck = true
-				break
-			}
-			logrus.Infof("Fight has alien %s", alien.Name)
-
-			alienBytes, err := s.ImageBuilderClient.BuildAlien(imagebuilder.Alien{
-				Background: alien.Background,
-				Skin:       alien.Skin,
-				Clothes:    alien.Clothes,
-				Hat:        alien.Hat,
-				Hand:       alien.Hand,
-				Mouth:      alien.Mouth,
-				Eyes:       alien.Eyes,
-				Width:      640,
-				Height:     640,
-			})
-			if err != nil {
-				logrus.WithError(err).Errorf("Error building alien")
-				errorOnCheck = true
-				break
-			}
+	failed := 0
+	for i, fight := range queuedFights {
+		if i == 10 {
+			logrus.Info("Already minted 10")
+			break
+		}
 
-			// write file
-			err = os.WriteFile(dirName+"/alien.jpg", alienBytes, 0644)
+		// a fighter that changed hands since the fight was created calls it off, the rest follow their nft
+		moved, err := s.resolveSendAddresses(context.Background(), &fight)
+		if err != nil {
+			logrus.WithError(err).Errorf("Error resolving send addresses for fight %d", fight.ID)
+			failed++
+			continue
+		}
+		if len(moved) > 0 {
+			err = s.voidFight(context.Background(), fight, moved)
 			if err != nil {
-				logrus.WithError(err).Errorf("Error writing alien file")
-				errorOnCheck = true
-				break
+				logrus.WithError(err).Errorf("Error voiding fight %d", fight.ID)
+				failed++
 			}
+			continue
+		}
 
-			// add to ipfs
-			alienIpfsResponse, err := s.NftStorageClient.IpfsAdd(dirName + "/alien.jpg")
-			if err != nil {
-				logrus.WithError(err).Errorf("Error uploading alien to ipfs")
-				errorOnCheck = true
-				break
-			}
-			logrus.Infof("Alien ipfs %s", alienIpfsResponse.Value.Pin.CID)
-
-			// alienIpfsResponse, err := s.BlockforstIpfsClient.IpfsAdd(dirName + "/alien.jpg")
-			// if err != nil {
-			// 	logrus.WithError(err).Errorf("Error uploading alien to ipfs")
-			// 	errorOnCheck = true
-			// 	break
-			// }
-			// logrus.Infof("Updload alien to ipfs %s, now pinnings", alienIpfsResponse.Hash)
-			// err = s.BlockforstIpfsClient.IpfsPin(alienIpfsResponse.Hash)
-			// if err != nil {
-			// 	logrus.WithError(err).Errorf("Error pinning alien to ipfs")
-			// 	errorOnCheck = true
-			// 	break
-			// }
-
-			// build fight image (random background, message) and upload to ipfs
-			w, l, err := s.determineFightWinner(dirName, fight.ZombieName, fight.HunterName, fight.ID, alien.ID, alienIpfsResponse.Value.Pin.CID)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error determining fight winner")
-				errorOnCheck = true
-				break
-			}
+		// a staked fight isn't fought until both stakes are in, or the wager is called off
+		waiting, err := s.awaitingWager(fight.ID)
+		if err != nil {
+			logrus.WithError(err).Errorf("Error getting wager for fight %d", fight.ID)
+			failed++
+			continue
+		}
+		if waiting {
+			logrus.Infof("Fight %d is waiting on wager deposits", fight.ID)
+			continue
+		}
 
-			tweetId, err := twitter.TweetFight(dirName+"/alien.jpg", dirName+"/fight.jpg", fmt.Sprintf("%s defeated %s and revealed %s!", w, l, alien.ReadableName))
-			if err != nil {
-				//don't make this a real error, just fail silently
-				logrus.WithError(err).Errorf("Error tweeting, failing silently...")
-			} else {
-				err = s.Store.UpdateTweetID(context.Background(), fight.ID, tweetId)
-				if err != nil {
-					logrus.WithError(err).Errorf("Error updating tweet id")
-					errorOnCheck = true
-					break
-				}
-			}
+		err = s.processQueuedFight(fight)
+		if err != nil {
+			logrus.WithError(err).Errorf("Error processing queued fight %d", fight.ID)
+			failed++
+		}
+	}
 
-			// move images to backup dir
-			err = moveImagesToBackup(dirName, alien.Name)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error moving images")
-				errorOnCheck = true
-				break
-			}
+	if failed > 0 {
+		return fmt.Errorf("%d queued fights failed", failed)
+	}
+	return nil
+}
 
-			//remove folder
-			err = os.RemoveAll(dirName)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error removing directory")
-				errorOnCheck = true
-				break
-			}
+func (s Server) processQueuedFight(fight store.FightDb) error {
+	logrus.Infof("Minting fight for id %d", fight.ID)
+
+	// working directory is per fight so a restart finds the images again
+	dirName := fightWorkDir(fight.ID)
+	err := os.MkdirAll(dirName, 0755)
+	if err != nil {
+		return err
+	}
+
+	// build alien image and upload to ipfs
+	alien, err := s.Store.GetAlienByFightId(fight.ID)
+	if err != nil {
+		return err
+	}
+	if alien == nil {
+		return fmt.Errorf("No alien for fight %d", fight.ID)
+	}
+	logrus.Infof("Fight has alien %s", alien.Name)
 
-			i++
+	// the image only depends on the alien traits so rebuilding it is safe
+	alienFile := dirName + "/alien.jpg"
+	if _, err := os.Stat(alienFile); err != nil {
+		err = s.buildAlienImage(*alien, alienFile)
+		if err != nil {
+			return err
 		}
+	}
 
-		// don't continue
-		if errorOnCheck {
-			continue
+	// upload once, the stored hash is the checkpoint
+	alienIpfs := alien.Ipfs.String
+	if !alien.Ipfs.Valid || alienIpfs == "" {
+		alienIpfsResponse, err := s.NftStorageClient.IpfsAdd(alienFile)
+		if err != nil {
+			return err
 		}
+		alienIpfs = alienIpfsResponse.Value.Pin.CID
+		logrus.Infof("Alien ipfs %s", alienIpfs)
 
-		//find staged fights
-		stagedFights, err := s.Store.GetStagedFights()
+		err = s.Store.SetAlienIpfs(context.Background(), alien.ID, alienIpfs)
 		if err != nil {
-			logrus.WithError(err).Errorf("Error getting staged fights")
-			errorOnCheck = true
+			return err
+		}
+	} else {
+		logrus.Infof("Alien %s already uploaded to ipfs %s", alien.Name, alienIpfs)
+	}
+
+	// the outcome is drawn from the fight's committed seed so it's the same on every retry
+	rnd, err := s.fightRand(context.Background(), fight)
+	if err != nil {
+		return err
+	}
+
+	// fights in a season are decided by the season's rule set
+	season := ""
+	if fight.SeasonID.Valid {
+		season = strconv.FormatInt(fight.SeasonID.Int64, 10)
+	}
+
+	// fatigue counts the fights before this one, so it's the same on every retry
+	recent, err := s.recentFights(fight, season)
+	if err != nil {
+		return err
+	}
+
+	// build fight image (random background, message), upload to ipfs and stage
+	_, _, err = s.determineFightWinner(dirName, fight.ZombieName, fight.HunterName, fight.ID, alien.ID, alienIpfs, season, recent, rnd)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// buildAlienImage builds the image of the alien's traits and writes it to the file
+func (s Server) buildAlienImage(alien store.Alien, fileName string) error {
+	alienBytes, err := s.ImageBuilderClient.BuildAlien(imagebuilder.Alien{
+		Background: alien.Background,
+		Skin:       alien.Skin,
+		Clothes:    alien.Clothes,
+		Hat:        alien.Hat,
+		Hand:       alien.Hand,
+		Mouth:      alien.Mouth,
+		Eyes:       alien.Eyes,
+		Width:      640,
+		Height:     640,
+	})
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(fileName, alienBytes, 0644)
+}
+
+// alienNumber the number in the alien's name, which is also the number of the fight it's revealed in
+func alienNumber(alien store.Alien) (int, error) {
+	return strconv.Atoi(strings.Replace(alien.Name, "Alien", "", 1))
+}
+
+// processFightTweets tweets staged fights that haven't been tweeted yet
+func (s Server) processFightTweets() error {
+	stagedFights, err := s.Store.GetStagedFights()
+	if err != nil {
+		return err
+	}
+
+	for _, fight := range stagedFights {
+		if fight.TweetAttempted {
 			continue
 		}
 
-		i = 0
-		logrus.Infof("Found %d staged fights", len(stagedFights))
-		for _, fight := range stagedFights {
-			if i == 10 {
-				logrus.Info("Already minted 10")
-				break
-			}
+		err = s.tweetFight(fight)
+		if err != nil {
+			return err
+		}
+	}
 
-			logrus.Infof("Minting fight for id %d", fight.ID)
-			// call method to mint both fight and alien
-			// build new dir
-			dirName := "work/" + uuid.New().String()
+	return nil
+}
 
-			// find return address
-			txUtxos, err := s.BlockfrostClient.TransactionUTXOs(context.Background(), fight.IncomingUtxo.String)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error getting transaction utxo %s", fight.IncomingUtxo.String)
-				errorOnCheck = true
-				break
-			}
-			returnAddress := txUtxos.Inputs[0].Address
+func (s Server) tweetFight(fight store.FightDb) error {
+	alien, err := s.Store.GetAlienByFightId(fight.ID)
+	if err != nil {
+		return err
+	}
+	if alien == nil {
+		return fmt.Errorf("No alien for fight %d", fight.ID)
+	}
 
-			//cando: should probably store this on first loop
-			// utxoQuantity, err := strconv.Atoi(txUtxos.Outputs[0].Amount[0].Quantity)
-			// if err != nil {
-			// 	logrus.WithError(err).Errorf("Error converting quantity to int %s", txUtxos.Outputs[0].Amount[0].Quantity)
-			// 	errorOnCheck = true
-			// 	break
-			// }
+	// mark first, a crash while tweeting means no tweet rather than two
+	err = s.Store.MarkTweetAttempted(context.Background(), fight.ID)
+	if err != nil {
+		return err
+	}
 
-			// just use amount requested since already matches
-			utxoQuantity := int(fight.PaymentAmountLovelace)
+	dirName := fightWorkDir(fight.ID)
+	if _, err := os.Stat(dirName + "/fight.jpg"); err != nil {
+		logrus.Warnf("Images for fight %d are gone, not tweeting", fight.ID)
+		return nil
+	}
 
-			//build metadata
-			alien, err := s.Store.GetAlienByFightId(fight.ID)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error getting alient for fight id %d", fight.ID)
-				errorOnCheck = true
-				break
-			}
-			alienMeta, err := buildAlienMetaString(*alien)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error building alien meta for id %d", alien.ID)
-				errorOnCheck = true
-				break
-			}
+	w, l := fight.HunterName, fight.ZombieName
+	if fight.ZombieLifeBar.Int64 > fight.HunterLifeBar.Int64 {
+		w, l = fight.ZombieName, fight.HunterName
+	}
 
-			alienNumberString := strings.Replace(alien.Name, "Alien", "", 1)
-			fightNumber, err := strconv.Atoi(alienNumberString)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error converting fight number %s", alienNumberString)
-				errorOnCheck = true
-				break
-			}
+	tweetId, err := twitter.TweetFight(dirName+"/alien.jpg", dirName+"/fight.jpg", fmt.Sprintf("%s defeated %s and revealed %s!", w, l, alien.ReadableName))
+	if err != nil {
+		//don't make this a real error, just fail silently
+		logrus.WithError(err).Errorf("Error tweeting, failing silently...")
+	} else {
+		err = s.Store.UpdateTweetID(context.Background(), fight.ID, tweetId)
+		if err != nil {
+			return err
+		}
+	}
 
-			fightMeta, err := buildFightMetaString(fight, fightNumber)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error building fight meta for id %d", fight.ID)
-				errorOnCheck = true
-				break
-			}
+	// move images to backup dir
+	err = moveImagesToBackup(dirName, alien.Name)
+	if err != nil {
+		return err
+	}
 
-			// determine splits
-			txsOut := make([]string, 0)
-			// if fight.ZombieSendAddress.Valid && fight.ZombieSendAddress.String != "" {
-			// 	zombiePaymentAmount := fight.ZombieAmountAda * 1000000
-			// 	txsOut = append(txsOut, fmt.Sprintf("%s+%d", fight.ZombieSendAddress.String, zombiePaymentAmount))
-			// }
-			// if fight.HunterSendAddress.Valid && fight.HunterSendAddress.String != "" {
-			// 	hunterPaymentAmount := fight.HunterAmountAda * 1000000
-			// 	txsOut = append(txsOut, fmt.Sprintf("%s+%d", fight.HunterSendAddress.String, hunterPaymentAmount))
-			// }
-			// split brian/royalty
-			//TODO: determine if 12 is correct amount
-			txsOut = append(txsOut, fmt.Sprintf("%s+%d", s.BrianSplitAddress, 5000000))
-			//txsOut = append(txsOut, fmt.Sprintf("%s+%d", s.RoyaltySplitAddress, 4500000))
-
-			alienSendAddress := ""
-			if fight.ZombieLifeBar.Int64 > fight.HunterLifeBar.Int64 {
-				alienSendAddress = fight.ZombieSendAddress.String
-			} else {
-				alienSendAddress = fight.HunterSendAddress.String
-			}
+	//remove folder
+	return os.RemoveAll(dirName)
+}
 
-			txHash, err := s.mintAlienAndZfcNfts(dirName, txsOut, s.RoyaltySplitAddress, returnAddress, fight.IncomingUtxo.String, int(fight.IncomingUtxoInt.Int64), utxoQuantity, s.ZfcPolicyID, s.AlienPolicyID, fightMeta, alienMeta, fmt.Sprintf("Fight%d", fightNumber), alien.Name, alienSendAddress)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error minting, continueing...")
-				errorOnCheck = true
-				continue
-			}
+// processStagedFights builds, checkpoints and submits the mint tx for up to 10 staged fights
+func (s Server) processStagedFights() error {
+	stagedFights, err := s.Store.GetStagedFights()
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Found %d staged fights", len(stagedFights))
 
-			// update tx
-			logrus.Infof("Moving fight %d to minted for hash %s", fight.ID, txHash)
-			err = s.Store.MoveFightFromStagedToMinted(context.Background(), fight.ID, txHash)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error moving to minted")
-				errorOnCheck = true
-				break
-			}
+	failed := 0
+	for i, fight := range stagedFights {
+		if i == 10 {
+			logrus.Info("Already minted 10")
+			break
+		}
 
-			//remove folder
-			err = os.RemoveAll(dirName)
+		// an operator asked for new images, the mint tx is built with them on the next pass
+		if fight.RegenerateImages && !fight.SignedTxHash.Valid {
+			err = s.regenerateFightImages(fight)
 			if err != nil {
-				logrus.WithError(err).Errorf("Error removing directory")
-				errorOnCheck = true
-				break
+				logrus.WithError(err).Errorf("Error regenerating images of fight %d, continuing...", fight.ID)
+				failed++
 			}
+			continue
+		}
 
+		err = s.processStagedFight(fight)
+		if err != nil {
+			logrus.WithError(err).Errorf("Error minting fight %d, continuing...", fight.ID)
+			failed++
 		}
+	}
 
-		// handle any returns
-		refundCheck++
-		if processRefunds && refundCheck == 10 {
-			// check if any returns
-			if len(returns) > 0 {
-				logrus.Infof("Returning %d utxos...", len(returns))
-				err = s.ReturnStuff(returns)
-				if err != nil {
-					logrus.WithError(err).Errorf("Error returning utxos")
-				}
-			}
+	if failed > 0 {
+		return fmt.Errorf("%d staged fights failed", failed)
+	}
+	return nil
+}
 
-			refundCheck = 0
-		}
+func (s Server) processStagedFight(fight store.FightDb) error {
+	ctx := context.Background()
 
-		// check and validate submitted txes
-		unconfirmedFights, err := s.Store.GetMintedFights()
+	// a signed tx is checkpointed, reconcile it against the chain before ever building a different one
+	if fight.SignedTxHash.Valid {
+		txHash := fight.SignedTxHash.String
+		confirmed, err := s.Chain.TransactionConfirmed(ctx, txHash)
 		if err != nil {
-			logrus.WithError(err).Errorf("Error getting unconfirmed fights")
-			errorOnCheck = true
-			continue
+			return err
+		}
+		if confirmed {
+			logrus.Infof("Signed tx %s for fight %d is already on chain", txHash, fight.ID)
+			return s.Store.MoveFightFromStagedToMinted(ctx, fight.ID, txHash)
 		}
-		logrus.Infof("Found %d unconfirmed fights", len(unconfirmedFights))
 
-		for _, fight := range unconfirmedFights {
-			txID := strings.ReplaceAll(fight.TxID.String, "\"", "")
+		tip, err := s.Chain.Tip(ctx)
+		if err != nil {
+			return err
+		}
 
-			logrus.Infof("Getting tx hash %s", txID)
-			transaction, err := s.BlockforstIpfsClient.GetTransaction(txID)
-			if err != nil {
-				logrus.WithError(err).Errorf("Error verifying tx...")
-			}
+		// still valid, sending the same signed tx again can only ever mint once
+		if tip.Slot <= int(fight.SignedTxTTL.Int64)+signedTxExpiryMargin {
+			logrus.Infof("Resubmitting signed tx %s for fight %d", txHash, fight.ID)
+			return s.submitMintTx(ctx, fight.ID, txHash, fight.SignedTxCbor.String)
+		}
 
-			if transaction != nil {
-				logrus.Infof("Transaction %s found", txID)
-				err = s.Store.MoveFightFromMintedToConfirmed(context.Background(), fight.ID)
-				if err != nil {
-					logrus.WithError(err).Errorf("Error updating fight to confirmed")
-					errorOnCheck = true
-					continue
-				}
-			} else {
-				logrus.Info("Tx not found")
-			}
+		// past its ttl the tx can't land anymore, so the payment utxo is unspent and a new tx is safe
+		logrus.Warnf("Signed tx %s for fight %d expired at slot %d, building a new one", txHash, fight.ID, fight.SignedTxTTL.Int64)
+		err = s.Store.ClearSignedMintTx(ctx, fight.ID, txHash)
+		if err != nil {
+			return err
 		}
+	}
 
-		logrus.Info("Sleeping for 30 seconds")
-		time.Sleep(30 * time.Second)
+	// payouts follow the fighters within their owner's account, one sold since the fight pays the owner it fought for
+	moved, err := s.resolveSendAddresses(ctx, &fight)
+	if err != nil {
+		return err
+	}
+	if len(moved) > 0 {
+		logrus.Infof("%s changed hands after fight %d, paying out to the address it fought for", strings.Join(moved, " and "), fight.ID)
 	}
-}
 
-func (s Server) moveFightFromPendingToQueued(fight store.FightDb, utxo string, utxoIndex int) error {
-	// get next alien
-	alien, err := s.Store.GetNextAvailableAlien()
+	logrus.Infof("Minting fight for id %d", fight.ID)
+	// call method to mint both fight and alien
+	// build new dir
+	dirName := "work/" + uuid.New().String()
+	defer os.RemoveAll(dirName)
+
+	// every utxo that paid goes in, over payment and tokens go back to the payer
+	txsIn, returnAddress, changeTxOut, utxoQuantity, err := s.fightPaymentInputs(ctx, fight)
 	if err != nil {
 		return err
 	}
 
-	logrus.Infof("Found alien %s for fight %d", alien.Name, fight.ID)
+	//build metadata
+	alien, err := s.Store.GetAlienByFightId(fight.ID)
+	if err != nil {
+		return err
+	}
+	if alien == nil {
+		return fmt.Errorf("No alien for fight %d", fight.ID)
+	}
+	alienMeta, err := buildAlienMetaString(*alien)
+	if err != nil {
+		return err
+	}
 
-	// update alien fk and fight status
-	logrus.Infof("Moving fight %d to alien %d", alien.ID, fight.ID)
-	err = s.Store.MoveFightFromPendingToQueued(context.Background(), fight.ID, alien.ID, utxo, utxoIndex)
+	fightNumber, err := alienNumber(*alien)
 	if err != nil {
 		return err
 	}
 
-	logrus.Infof("Fight %d is now QUEUED", fight.ID)
+	fightMeta, err := buildFightMetaString(fight, fightNumber)
+	if err != nil {
+		return err
+	}
 
-	return nil
+	// over payment and tokens go back as is, the rest is split
+	changeTxsOut := make([]string, 0)
+	if changeTxOut != "" {
+		changeTxsOut = append(changeTxsOut, changeTxOut)
+	}
+
+	alienSendAddress := ""
+	if fight.ZombieLifeBar.Int64 > fight.HunterLifeBar.Int64 {
+		alienSendAddress = fight.ZombieSendAddress.String
+	} else {
+		alienSendAddress = fight.HunterSendAddress.String
+	}
+
+	// a tournament fight is paid from the prize pool, change goes back to the pool but the fight nft to the zombie entry
+	fightSendAddress := returnAddress
+	if fight.TournamentID.Valid {
+		fightSendAddress = fight.ZombieSendAddress.String
+	}
+
+	signedTx, txHash, ttl, err := s.buildMintTransaction(dirName, changeTxsOut, s.Pricing.fightPayouts(fight), fightSendAddress, txsIn, utxoQuantity, s.ZfcPolicyID, s.AlienPolicyID, fightMeta, alienMeta, fmt.Sprintf("Fight%d", fightNumber), alien.Name, alienSendAddress, fight.PaymentAddressIndex)
+	if err != nil {
+		return err
+	}
+
+	// checkpoint before submitting so a crash can't lead to a second, different mint tx
+	err = s.Store.SetSignedMintTx(ctx, fight.ID, txHash, signedTx.Hex, ttl)
+	if err != nil {
+		return err
+	}
+
+	return s.submitMintTx(ctx, fight.ID, txHash, signedTx.Hex)
+}
+
+func (s Server) submitMintTx(ctx context.Context, fightID int, txHash string, cborHex string) error {
+	submitted, err := s.Chain.SubmitTransaction(ctx, cborHex)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Submitted tx: %s", submitted)
+
+	// update tx
+	logrus.Infof("Moving fight %d to minted for hash %s", fightID, txHash)
+	return s.Store.MoveFightFromStagedToMinted(ctx, fightID, txHash)
 }
 
-func getAllUtxos(address string, client bfg.APIClient) ([]bfg.AddressUTXO, error) {
-	allUtxos := make([]bfg.AddressUTXO, 0)
+// processMintedFights confirms minted fights once their tx is on chain
+func (s Server) processMintedFights() error {
+	unconfirmedFights, err := s.Store.GetMintedFights()
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Found %d unconfirmed fights", len(unconfirmedFights))
+
+	for _, fight := range unconfirmedFights {
+		txID := strings.ReplaceAll(fight.TxID.String, "\"", "")
 
-	page := 1
-	for true {
-		utxos, err := client.AddressUTXOs(context.Background(), address, bfg.APIQueryParams{Count: 100, Page: page})
-		page++
+		logrus.Infof("Getting tx hash %s", txID)
+		confirmed, err := s.Chain.TransactionConfirmed(context.Background(), txID)
 		if err != nil {
-			return nil, err
+			logrus.WithError(err).Errorf("Error verifying tx...")
 		}
 
-		if len(utxos) == 0 {
-			return allUtxos, nil
+		if confirmed {
+			logrus.Infof("Transaction %s found", txID)
+			err = s.Store.MoveFightFromMintedToConfirmed(context.Background(), fight.ID)
+			if err != nil {
+				return err
+			}
+		} else {
+			logrus.Info("Tx not found")
 		}
+	}
+
+	return nil
+}
 
-		allUtxos = append(allUtxos, utxos...)
+// signedTxExpiryMargin slots past the ttl before a checkpointed tx is treated as never landing
+const signedTxExpiryMargin = 300
+
+// fightRules rules fights are decided by, plain trait strengths unless a rules file was loaded
+func (s Server) fightRules() metadata.Rules {
+	if s.FightRules == nil {
+		return metadata.DefaultRules()
+	}
+	return *s.FightRules
+}
+
+// txBuilder builder for mint and refund txs, cardano-cli unless one was configured
+func (s Server) txBuilder() cli.Builder {
+	if s.TxBuilder == nil {
+		return cli.CliBuilder{}
+	}
+	return s.TxBuilder
+}
+
+func fightWorkDir(fightID int) string {
+	return fmt.Sprintf("work/fight-%d", fightID)
+}
+
+func (s Server) moveFightFromPendingToQueued(fight store.FightDb, payments []store.FightPayment) error {
+	// get next alien
+	alien, err := s.Store.GetNextAvailableAlien()
+	if err != nil {
+		return err
 	}
 
-	return allUtxos, nil
+	if alien == nil {
+		return fmt.Errorf("No alien left for fight %d", fight.ID)
+	}
+	logrus.Infof("Found alien %s for fight %d", alien.Name, fight.ID)
+
+	// update alien fk and fight status
+	logrus.Infof("Moving fight %d to alien %d", alien.ID, fight.ID)
+	err = s.Store.MoveFightFromPendingToQueued(context.Background(), fight.ID, alien.ID, payments)
+	if err != nil {
+		return err
+	}
+
+	logrus.Infof("Fight %d is now QUEUED", fight.ID)
+
+	return nil
 }
 
-func (s Server) determineFightWinner(dirName string, zombieName string, hunterName string, fightId int, alienId int, alienIpfs string) (string, string, error) {
+func (s Server) determineFightWinner(dirName string, zombieName string, hunterName string, fightId int, alienId int, alienIpfs string, season string, recentFights map[string]int, rnd *metadata.Rand) (string, string, error) {
 	// determine if this is right randomness
-	zcStrength, zhStrength := metadata.FightZombieAndHunterReturnStrength(zombieName, hunterName, s.ZombieMetaStruct, s.HunterMetaStruct, s.ZombieChainTraitStrength, s.ZombieHunterTraitStrength, 120)
+	zcStrength, zhStrength, breakdown := metadata.FightZombieAndHunterReturnStrength(zombieName, hunterName, s.ZombieMetaStruct, s.HunterMetaStruct, s.ZombieChainTraitStrength, s.ZombieHunterTraitStrength, s.fightRules(), season, recentFights, rnd)
+	for _, modifier := range breakdown.Modifiers {
+		logrus.Infof("Fight %d %s %s %+d: %s", fightId, modifier.Side, modifier.Source, modifier.Value, modifier.Description)
+	}
 
 	// build fight image
 	// {
@@ -530,29 +711,19 @@ func (s Server) determineFightWinner(dirName string, zombieName string, hunterNa
 		Vs:           "VS",
 	}
 
-	// zombie winner
-	if zcStrength >= zhStrength {
-		pointDifference := zcStrength - zhStrength
-		winnerLb, loserLb, knockOut := metadata.DetermineLifeBar(pointDifference)
-		zombieFightImage.ZombieChainLifeBar = winnerLb
-		zombieFightImage.ZombieHunterLifeBar = loserLb
-		if knockOut {
-			zombieFightImage.HunterKO = true
-		}
-		if pointDifference > 20 {
-			zombieFightImage.HunterBeatup = true
-		}
-	} else {
-		pointDifference := zhStrength - zcStrength
-		winnerLb, loserLb, knockOut := metadata.DetermineLifeBar(pointDifference)
-		zombieFightImage.ZombieHunterLifeBar = winnerLb
-		zombieFightImage.ZombieChainLifeBar = loserLb
-		if knockOut {
-			zombieFightImage.ZombieKO = true
-		}
-		if pointDifference > 20 {
-			zombieFightImage.ZombieBeatup = true
-		}
+	// play out the fight, the image shows how it ended
+	combatLog := metadata.SimulateFight(zcStrength, zhStrength, rnd)
+	combatLog.Breakdown = &breakdown
+	logrus.Infof("Fight %d won by the %s after %d rounds", fightId, combatLog.Winner, len(combatLog.Rounds))
+	zombieFightImage.ZombieChainLifeBar = combatLog.ZombieLifeBar
+	zombieFightImage.ZombieHunterLifeBar = combatLog.HunterLifeBar
+	zombieFightImage.ZombieKO = combatLog.ZombieKO
+	zombieFightImage.HunterKO = combatLog.HunterKO
+	zombieFightImage.ZombieBeatup = combatLog.ZombieBeatup
+	zombieFightImage.HunterBeatup = combatLog.HunterBeatup
+	combatLogJSON, err := json.Marshal(combatLog)
+	if err != nil {
+		return "", "", err
 	}
 
 	// determine record
@@ -569,8 +740,7 @@ func (s Server) determineFightWinner(dirName string, zombieName string, hunterNa
 	winningNft := ""
 	losingNft := ""
 
-	//verify beatup works
-	if zcStrength >= zhStrength {
+	if combatLog.Winner == metadata.SideZombie {
 		zombieFightImage.ZombieRecord = fmt.Sprintf("%03d-%03d", zombieNft.Wins+1, zombieNft.Loses)
 		zombieFightImage.HunterRecord = fmt.Sprintf("%03d-%03d", hunterNft.Wins, hunterNft.Loses+1)
 		winningNft = zombieName
@@ -615,7 +785,7 @@ func (s Server) determineFightWinner(dirName string, zombieName string, hunterNa
 
 	// update fight and record in one tx
 	logrus.Infof("Moving fight %d form queued to staged", fightId)
-	err = s.Store.MoveFightFromQueuedToStaged(context.Background(), alienId, alienIpfs, fightId, fightIpfsResponse.Value.Pin.CID, zfcBackground, zombieFightImage.ZombieRecord, zombieFightImage.HunterRecord, zombieFightImage.ZombieChainLifeBar, zombieFightImage.ZombieHunterLifeBar, zombieFightImage.ZombieKO, zombieFightImage.HunterKO, zombieFightImage.ZombieBeatup, zombieFightImage.HunterBeatup, winningNft, losingNft)
+	err = s.Store.MoveFightFromQueuedToStaged(context.Background(), alienId, alienIpfs, fightId, fightIpfsResponse.Value.Pin.CID, zfcBackground, zombieFightImage.ZombieRecord, zombieFightImage.HunterRecord, zombieFightImage.ZombieChainLifeBar, zombieFightImage.ZombieHunterLifeBar, zombieFightImage.ZombieKO, zombieFightImage.HunterKO, zombieFightImage.ZombieBeatup, zombieFightImage.HunterBeatup, string(combatLogJSON), winningNft, losingNft)
 	if err != nil {
 		return "", "", err
 	}
@@ -623,8 +793,9 @@ func (s Server) determineFightWinner(dirName string, zombieName string, hunterNa
 	return winningNft, losingNft, nil
 }
 
-func (s Server) mintAlienAndZfcNfts(dirName string, baseTxsOut []string, royaltyAddress, toAddress string, fromUtxo string, fromUtxoIndex int, fromUtxoAmount int, zfcPolicyId string, alienPolicyId string, zfcMetaString string, alienMetaString string, fightName string, alienName string, alienSendAddress string) (string, error) {
-	logrus.Infof("Minting nft to address %s from %s#%d with amount %d and alien to %s", toAddress, fromUtxo, fromUtxoIndex, fromUtxoAmount, alienSendAddress)
+// buildMintTransaction builds and signs the mint tx, returning it with its hash and ttl slot without submitting
+func (s Server) buildMintTransaction(dirName string, changeTxsOut []string, payouts []payout, toAddress string, txsIn []string, fromUtxoAmount int, zfcPolicyId string, alienPolicyId string, zfcMetaString string, alienMetaString string, fightName string, alienName string, alienSendAddress string, paymentAddressIndex sql.NullInt64) (*SignedTx, string, int, error) {
+	logrus.Infof("Minting nft to address %s from %s with amount %d and alien to %s", toAddress, strings.Join(txsIn, ", "), fromUtxoAmount, alienSendAddress)
 
 	//TODO: verify that this utxo is still valid
 
@@ -632,7 +803,12 @@ func (s Server) mintAlienAndZfcNfts(dirName string, baseTxsOut []string, royalty
 	err := os.Mkdir(dirName, 0755)
 	if err != nil {
 		logrus.WithError(err).Errorf("Error creating directory")
-		return "", err
+		return nil, "", 0, err
+	}
+
+	paymentKeyFile, err := s.paymentSigningKeyFile(dirName, paymentAddressIndex)
+	if err != nil {
+		return nil, "", 0, err
 	}
 
 	// build metadata file
@@ -640,7 +816,7 @@ func (s Server) mintAlienAndZfcNfts(dirName string, baseTxsOut []string, royalty
 	f, err := os.Create(metadataFile)
 	if err != nil {
 		logrus.WithError(err).Errorf("Error creating meatdata file")
-		return "", err
+		return nil, "", 0, err
 	}
 
 	_, err = f.WriteString(fmt.Sprintf("{\"721\":{\"%s\":{%s},\"%s\":{%s}}}", zfcPolicyId, zfcMetaString, alienPolicyId, alienMetaString))
@@ -656,213 +832,98 @@ func (s Server) mintAlienAndZfcNfts(dirName string, baseTxsOut []string, royalty
 	alienMint := fmt.Sprintf("1 %s.%s", alienPolicyId, alienName)
 	mints = append(mints, alienMint)
 
-	// build tx in
-	txsIn := make([]string, 0)
-	txsIn = append(txsIn, fmt.Sprintf("%s#%d", fromUtxo, fromUtxoIndex))
-
-	// build draft tx out and mints
-	txsOut := make([]string, 0)
-	txsOut = append(txsOut, baseTxsOut...)
-	txsOut = append(txsOut, fmt.Sprintf("%s+%d", royaltyAddress, 4500000))
-
 	//build send mint i.e. --tx-out addr_test1qqf3x5gu3g3c7p6dnw3qaqarhzs5rryp0t2qlf09mlvx2ugkjsr90u8d0qpqagp4lts2y7jq8s0c2z6dep2tmgr6s0wqnejpag+2000000+1 056cd1282696748708db782c5af5f3ff1834a96b1cb198ab1e937af3.TestNFTName3
+	mintOutTx := fmt.Sprintf("%s+%d+%s", toAddress, s.Pricing.MintOutputLovelace, zfcMint)
+	alienMintOutTx := fmt.Sprintf("%s+%d+%s", alienSendAddress, s.Pricing.MintOutputLovelace, alienMint)
 
-	mintOutTx := fmt.Sprintf("%s+%d+%s", toAddress, 1250000, zfcMint)
-	alienMintOutTx := fmt.Sprintf("%s+%d+%s", alienSendAddress, 1250000, alienMint)
-
-	txsOut = append(txsOut, mintOutTx)
-	txsOut = append(txsOut, alienMintOutTx)
-
-	draftTxFile := fmt.Sprintf("%s/%s", dirName, "tx.draft")
-	err = cli.BuildTransaction(draftTxFile, txsIn, txsOut, 0, 0, metadataFile, mints, "keys/zfc-policy.txt", "keys/alien-policy.txt")
-	if err != nil {
-		logrus.WithError(err).Errorf("Error building draft transaction")
-		return "", err
-	}
-	fee, err := cli.CalculateFee(draftTxFile, len(txsIn), len(txsOut), 3)
-	if err != nil {
-		logrus.WithError(err).Errorf("Error calculating fee")
-		return "", err
-	}
-	logrus.Infof("Calculated a fee of %d", fee)
-
-	// build tx out again with fee
-	txsOut = make([]string, 0)
-	txsOut = append(txsOut, baseTxsOut...)
-	txsOut = append(txsOut, fmt.Sprintf("%s+%d", royaltyAddress, fromUtxoAmount-7500000-fee))
-
-	mintOutTx = fmt.Sprintf("%s+%d+%s", toAddress, 1250000, zfcMint)
-	alienMintOutTx = fmt.Sprintf("%s+%d+%s", alienSendAddress, 1250000, alienMint)
-
-	txsOut = append(txsOut, mintOutTx)
-	txsOut = append(txsOut, alienMintOutTx)
-
-	// old code
-	//mintOutTx = fmt.Sprintf("%s+%d+%s+%s", toAddress, fromUtxoAmount-8000000-fee, zfcMint, alienMint) //TODO: need to change this if change min amount
-	//txsOut = append(txsOut, mintOutTx)
-
-	// get ttl
-	block, err := s.BlockfrostClient.BlockLatest(context.Background())
-	if err != nil {
-		logrus.WithError(err).Errorf("Error getting latet block")
-		return "", err
-	}
-	logrus.Infof("Found slot of %d", block.Slot)
-
-	// build actual transaction
-	actualTxFile := fmt.Sprintf("%s/%s", dirName, "mint.tx")
-
-	err = cli.BuildTransaction(actualTxFile, txsIn, txsOut, block.Slot+1000, fee, metadataFile, mints, "keys/zfc-policy.txt", "keys/alien-policy.txt")
-	if err != nil {
-		logrus.WithError(err).Errorf("Error building transaction")
-		return "", err
-	}
-
-	// sign file
-	signedTxFile := fmt.Sprintf("%s/%s", dirName, "mint.signed")
-
-	err = cli.SignTransaction(actualTxFile, "keys/payment.skey", "keys/zfc-mint.skey", "keys/alien-mint.skey", signedTxFile)
-	if err != nil {
-		logrus.WithError(err).Errorf("Error signing transaction")
-		return "", err
-	}
-
-	// get cbor hex
-	jsonFile, err := os.Open(signedTxFile)
-	if err != nil {
-		logrus.WithError(err).Errorf("Error opening file")
-		return "", err
-	}
-	defer jsonFile.Close()
-	byteValue, _ := ioutil.ReadAll(jsonFile)
-	var signedTx SignedTx
-	json.Unmarshal(byteValue, &signedTx)
-
-	//txHash := ""
-	//txHex := ""
+	buildTxsOut := func(fee int) ([]string, error) {
+		payoutTxsOut, err := s.Pricing.payoutTxsOut(payouts, int64(fromUtxoAmount), int64(fee))
+		if err != nil {
+			return nil, err
+		}
 
-	txHex, err := s.BlockforstIpfsClient.SubmitTransaction(signedTx.Hex)
-	logrus.Infof("Submitted tx: %s", txHex)
-	if err != nil {
-		logrus.WithError(err).Errorf("Error submitting tx")
-		return "", echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		txsOut := make([]string, 0)
+		txsOut = append(txsOut, changeTxsOut...)
+		txsOut = append(txsOut, payoutTxsOut...)
+		txsOut = append(txsOut, mintOutTx)
+		txsOut = append(txsOut, alienMintOutTx)
+		return txsOut, nil
 	}
 
-	return txHex, nil
-}
-
-func (s Server) ReturnStuff(returns []NFTReturn) error {
-	logrus.Infof("Processing %d returns", len(returns))
-
-	//build and submit tx
-	dirName := "work/" + uuid.New().String()
-
-	// makedir for tx files
-	err := os.Mkdir(dirName, 0755)
+	// build draft tx out and mints
+	txsOut, err := buildTxsOut(0)
 	if err != nil {
-		logrus.WithError(err).Errorf("Error creating directory")
-		return err
-	}
-
-	txsIn := make([]string, 0)
-	txsOut := make([]string, 0)
-	for _, returnTx := range returns {
-		//build txIn i.e. de44bc164500a05fa49d095e299d1a8b4d706fd971b22995f8cba60737ee5552#0
-		txIn := returnTx.FromUtxo
-		txsIn = append(txsIn, txIn)
-
-		//build send mint i.e. --tx-out addr_test1qqf3x5gu3g3c7p6dnw3qaqarhzs5rryp0t2qlf09mlvx2ugkjsr90u8d0qpqagp4lts2y7jq8s0c2z6dep2tmgr6s0wqnejpag+2000000+1 056cd1282696748708db782c5af5f3ff1834a96b1cb198ab1e937af3.TestNFTName3
-		returnTxOut := fmt.Sprintf("%s+%d", returnTx.ReturnToAddress, returnTx.FromUtxoAmount)
-		txsOut = append(txsOut, returnTxOut)
+		return nil, "", 0, err
 	}
 
 	draftTxFile := fmt.Sprintf("%s/%s", dirName, "tx.draft")
-	err = cli.BuildTransaction(draftTxFile, txsIn, txsOut, 0, 0, "", nil, "", "")
+	err = s.txBuilder().BuildTransaction(draftTxFile, txsIn, txsOut, 0, 0, metadataFile, mints, "keys/zfc-policy.txt", "keys/alien-policy.txt")
 	if err != nil {
 		logrus.WithError(err).Errorf("Error building draft transaction")
-		return err
+		return nil, "", 0, err
 	}
-	fee, err := cli.CalculateFee(draftTxFile, len(txsIn), len(txsOut), 1)
+	fee, err := s.txBuilder().CalculateFee(draftTxFile, len(txsIn), len(txsOut), 3)
 	if err != nil {
 		logrus.WithError(err).Errorf("Error calculating fee")
-		return err
+		return nil, "", 0, err
 	}
 	logrus.Infof("Calculated a fee of %d", fee)
 
-	//incorporate fee
-	txsIn = make([]string, 0)
-	txsOut = make([]string, 0)
-	for i, returnTx := range returns {
-		//build txIn i.e. de44bc164500a05fa49d095e299d1a8b4d706fd971b22995f8cba60737ee5552#0
-		txIn := returnTx.FromUtxo
-		txsIn = append(txsIn, txIn)
-
-		//build send mint i.e. --tx-out addr_test1qqf3x5gu3g3c7p6dnw3qaqarhzs5rryp0t2qlf09mlvx2ugkjsr90u8d0qpqagp4lts2y7jq8s0c2z6dep2tmgr6s0wqnejpag+2000000+1 056cd1282696748708db782c5af5f3ff1834a96b1cb198ab1e937af3.TestNFTName3
-		returnAmount := returnTx.FromUtxoAmount
-		if i == 0 {
-			returnAmount = returnAmount - fee
-		}
-
-		returnTxOut := fmt.Sprintf("%s+%d", returnTx.ReturnToAddress, returnAmount)
-		txsOut = append(txsOut, returnTxOut)
+	// build tx out again with fee
+	txsOut, err = buildTxsOut(fee)
+	if err != nil {
+		return nil, "", 0, err
 	}
 
 	// get ttl
-	block, err := s.BlockfrostClient.BlockLatest(context.Background())
+	tip, err := s.Chain.Tip(context.Background())
 	if err != nil {
 		logrus.WithError(err).Errorf("Error getting latet block")
-		return err
+		return nil, "", 0, err
 	}
-	logrus.Infof("Found slot of %d", block.Slot)
+	logrus.Infof("Found slot of %d", tip.Slot)
 
 	// build actual transaction
 	actualTxFile := fmt.Sprintf("%s/%s", dirName, "mint.tx")
 
-	//vaid for 1 hour
-	err = cli.BuildTransaction(actualTxFile, txsIn, txsOut, block.Slot+10800, fee, "", nil, "", "")
+	ttl := tip.Slot + 1000
+	err = s.txBuilder().BuildTransaction(actualTxFile, txsIn, txsOut, ttl, fee, metadataFile, mints, "keys/zfc-policy.txt", "keys/alien-policy.txt")
 	if err != nil {
 		logrus.WithError(err).Errorf("Error building transaction")
-		return err
+		return nil, "", 0, err
 	}
 
 	// sign file
-	signedTxFile := fmt.Sprintf("%s/%s", dirName, "return.signed")
+	signedTxFile := fmt.Sprintf("%s/%s", dirName, "mint.signed")
 
-	err = cli.SignTransaction(actualTxFile, "keys/payment.skey", "", "", signedTxFile)
+	err = s.txBuilder().SignTransaction(actualTxFile, paymentKeyFile, "keys/zfc-mint.skey", "keys/alien-mint.skey", signedTxFile)
 	if err != nil {
 		logrus.WithError(err).Errorf("Error signing transaction")
-		return err
+		return nil, "", 0, err
 	}
 
 	// get cbor hex
 	jsonFile, err := os.Open(signedTxFile)
 	if err != nil {
 		logrus.WithError(err).Errorf("Error opening file")
-		return err
+		return nil, "", 0, err
 	}
-
+	defer jsonFile.Close()
 	byteValue, _ := ioutil.ReadAll(jsonFile)
 	var signedTx SignedTx
-	json.Unmarshal(byteValue, &signedTx)
-
-	//submit transaction
-	txHex, err := s.BlockforstIpfsClient.SubmitTransaction(signedTx.Hex)
-	logrus.Infof("Submitted tx: %s", txHex)
+	err = json.Unmarshal(byteValue, &signedTx)
 	if err != nil {
-		logrus.WithError(err).Errorf("Error submitting tx")
-		return err
+		logrus.WithError(err).Errorf("Error reading signed tx")
+		return nil, "", 0, err
 	}
-	logrus.Infof("Submittted refunds with tx %s", txHex)
 
-	jsonFile.Close()
-	//remove folder
-	err = os.RemoveAll(dirName)
+	txHash, err := s.txBuilder().GetTransactionId(signedTxFile)
 	if err != nil {
-		logrus.WithError(err).Errorf("Error removing directory")
-		return echo.NewHTTPError(http.StatusInternalServerError, err.Error())
+		logrus.WithError(err).Errorf("Error getting tx id")
+		return nil, "", 0, err
 	}
 
-	return nil
+	return &signedTx, strings.TrimSpace(txHash), ttl, nil
 }
 
 func buildAlienMetaString(alien store.Alien) (string, error) {
@@ -958,6 +1019,9 @@ func buildFightMetaString(fight store.FightDb, number int) (string, error) {
 
 	metaDto.Traits = traits
 
+	// anyone can check the commitment and replay the fight from the seed
+	metaDto.Seed = fightSeedProof(fight)
+
 	metaDto.FileMetadata = make([]FileMetadata, 0)
 
 	front := FileMetadata{
@@ -1022,5 +1086,3 @@ func moveFile(sourcePath, destPath string) error {
 	}
 	return nil
 }
-
-// PENDING > QUEUED > STAGED > MINTED > CONFIRMED > MINTED
@@ -1,18 +1,186 @@
 package server
 
 import (
-	"math/rand"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
 	"testing"
 
-	"github.com/reliablestaking/zombie-fight-club-server/metadata"
+	"github.com/reliablestaking/zombie-fight-club-server/chain"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/imagebuilder"
+	"github.com/reliablestaking/zombie-fight-club-server/nftstorage"
 )
 
-func TestLifeBar(t *testing.T) {
-	for i := 1; i < 500; i++ {
-		diff := rand.Intn(200-0) + 0
-		winner, loser, _ := metadata.DetermineLifeBar(diff)
-		if winner > 100 || loser > 100 || winner < 0 || loser < 0 {
-			t.Errorf("More than 100 %d/%d for value %d", winner, loser, diff)
-		}
+// fakeTxBuilder writes text envelopes instead of calling cardano-cli, the tx id is a hash of the file
+type fakeTxBuilder struct{}
+
+func (fakeTxBuilder) BuildTransaction(fileName string, txsIn []string, txsOut []string, ttl int, fee int, metadataFile string, mints []string, scriptFile string, scriptFile2 string) error {
+	body := hex.EncodeToString([]byte(fmt.Sprintf("%v%v%d%d%v", txsIn, txsOut, ttl, fee, mints)))
+	return os.WriteFile(fileName, []byte(fmt.Sprintf(`{"type":"TxBodyAlonzo","cborHex":"%s"}`, body)), 0644)
+}
+
+func (fakeTxBuilder) CalculateFee(txDraftFile string, txInCount int, txOutCount int, witnessCount int) (int, error) {
+	return 200000, nil
+}
+
+func (fakeTxBuilder) SignTransaction(txFile string, signingKey1 string, signingKey2 string, signingKey3 string, outFile string) error {
+	body, err := os.ReadFile(txFile)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(outFile, body, 0644)
+}
+
+func (fakeTxBuilder) GetTransactionId(txFile string) (string, error) {
+	body, err := os.ReadFile(txFile)
+	if err != nil {
+		return "", err
+	}
+	hash := sha256.Sum256(body)
+	return hex.EncodeToString(hash[:]) + "\n", nil
+}
+
+// newTestMintingServer runs in a temp dir with stub image builder and nft storage services
+func newTestMintingServer(t *testing.T) (Server, *db.MemoryStore, *chain.FakeChain) {
+	wd, _ := os.Getwd()
+	dir := t.TempDir()
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("Error changing dir %v", err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	for _, backupDir := range []string{"work", "backup/aliens", "backup/fights"} {
+		os.MkdirAll(backupDir, 0755)
+	}
+	t.Setenv("BACKUP_IMAGE_PATH", dir+"/backup")
+
+	images := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Background", "Ring")
+		w.Write([]byte("jpg"))
+	}))
+	t.Cleanup(images.Close)
+
+	uploads := 0
+	storage := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		uploads++
+		fmt.Fprintf(w, `{"value":{"pin":{"cid":"cid%d"}}}`, uploads)
+	}))
+	t.Cleanup(storage.Close)
+
+	memoryStore, _, _ := newTestStore(t)
+	fakeChain := newTestChain()
+
+	s := Server{
+		Store:              memoryStore,
+		Chain:              fakeChain,
+		TxBuilder:          fakeTxBuilder{},
+		ImageBuilderClient: imagebuilder.ImageBuilderClient{HttpClient: *images.Client(), BaseUrl: images.URL},
+		NftStorageClient:   nftstorage.NftstorageClient{HttpClient: *storage.Client(), BaseUrl: storage.URL},
+		PaymentAddress:     "addr_payment",
+		ZombiePolicyId:     testZombiePolicyID,
+		HunterPolicyId:     testHunterPolicyID,
+		ZfcPolicyID:        "cc33",
+		AlienPolicyID:      "dd44",
+		Pricing:            DefaultPricing("addr_brian", "addr_royalty"),
+	}
+
+	return s, memoryStore, fakeChain
+}
+
+func TestMintingEnginePayMintConfirm(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	fightID, err := memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment", ZombieSendAddress: "addr_ZombieChains00001", HunterSendAddress: "addr_ZombieHunter00001"},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+	if err != nil {
+		t.Fatalf("Error creating fight %v", err)
+	}
+
+	// one payment for the fight and one nobody asked for
+	fakeChain.Pay("addr_buyer", "addr_payment", 12000123)
+	fakeChain.Pay("addr_stranger", "addr_payment", 5000000)
+
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	refunds, _ := memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10)
+	if len(refunds) != 1 || refunds[0].SenderAddress != "addr_stranger" {
+		t.Errorf("Expected the unmatched payment to be owed back but got %v", refunds)
+	}
+
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	if err := s.processFightTweets(); err != nil {
+		t.Fatalf("Error tweeting fights %v", err)
+	}
+	if _, err := os.Stat("backup/fights/fight00001.jpg"); err != nil {
+		t.Errorf("Expected fight image in backup %v", err)
+	}
+	if err := s.processStagedFights(); err != nil {
+		t.Fatalf("Error minting fights %v", err)
+	}
+
+	minted, _ := memoryStore.GetMintedFights()
+	if len(minted) != 1 || len(fakeChain.Submitted()) != 1 {
+		t.Fatalf("Expected one minted fight and one submitted tx but got %d / %d", len(minted), len(fakeChain.Submitted()))
+	}
+
+	// nothing happens until the tx is in a block
+	if err := s.processMintedFights(); err != nil {
+		t.Fatalf("Error confirming fights %v", err)
+	}
+	if still, _ := memoryStore.GetMintedFights(); len(still) != 1 {
+		t.Fatal("Fight shouldn't be confirmed before the tx is")
+	}
+
+	fakeChain.Confirm(minted[0].TxID.String)
+	if err := s.processMintedFights(); err != nil {
+		t.Fatalf("Error confirming fights %v", err)
+	}
+
+	events, _ := memoryStore.GetFightEvents(fightID)
+	if len(events) != 5 || events[4].ToStatus != db.FightStatusConfirmed {
+		t.Fatalf("Expected fight to be confirmed after 5 events but got %v", events)
+	}
+}
+
+func TestMintingEngineResubmitsCheckpointedTx(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	fightID, _ := memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment"},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+	fakeChain.Pay("addr_buyer", "addr_payment", 12000123)
+
+	s.processIncomingPayments()
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+
+	// crash between checkpoint and submit
+	ctx := context.Background()
+	memoryStore.SetSignedMintTx(ctx, fightID, "signedhash", "signedcbor", 1500)
+
+	if err := s.processStagedFights(); err != nil {
+		t.Fatalf("Error minting fights %v", err)
+	}
+	submitted := fakeChain.Submitted()
+	if len(submitted) != 1 || submitted[0] != "signedcbor" {
+		t.Fatalf("Expected the checkpointed tx to be resubmitted but got %v", submitted)
+	}
+	minted, _ := memoryStore.GetMintedFights()
+	if len(minted) != 1 || minted[0].TxID.String != "signedhash" {
+		t.Fatalf("Expected fight minted with checkpointed hash but got %v", minted)
 	}
 }
@@ -32,58 +32,64 @@ func (s Server) GetAssetsForUser(ctx context.Context, zfcUser db.User) ([]Nft, e
 		log.Infof("Need to check for assets again %v", updateDuration.Minutes())
 	}
 
-	//get new token
-	//TODO: add this to func
-	t := oauth2.Token{
-		RefreshToken: zfcUser.NftkeymeRefreshToken,
-	}
-	tokenSource := s.NftkeymeOauthConfig.TokenSource(oauth2.NoContext, &t)
-	newToken, err := tokenSource.Token()
-	if err != nil {
-		logrus.WithError(err).Error("Error getting token")
-		return nil, err
-	}
-	if newToken.AccessToken != zfcUser.NftkeymeAccessToken {
-		logrus.Infof("Updating zfc user %s with new token", zfcUser.NftkeymeID)
-		err = s.Store.UpdatedUser(zfcUser.NftkeymeID, newToken.AccessToken, newToken.RefreshToken)
+	// what a user holds comes from the index at their accounts. nftkey.me users also get the accounts nftkey.me says
+	// they hold nfts in, until someone proves one of them is theirs
+	ownedAssetNames := make([]string, 0)
+	if !zfcUser.StakeAddress.Valid {
+		zcs, err := s.getNftkeymeAssetsForUser(ctx, zfcUser)
 		if err != nil {
-			logrus.WithError(err).Error("Error updating discord user")
 			return nil, err
 		}
-	}
 
-	// lookup zombies from nft key me
-	zombies, err := s.NftkeymeClient.GetAssetsForUser(newToken.AccessToken, s.ZombiePolicyId)
-	if err != nil {
-		log.WithError(err).Error("Error getting assets from nft key for user")
-		return nil, err
-	}
+		accounts := make([]string, 0)
+		for _, zc := range zcs {
+			//convert asset name to string
+			nftName, err := hex.DecodeString(zc.AssetName)
+			if err != nil {
+				return nil, err
+			}
 
-	hunters, err := s.NftkeymeClient.GetAssetsForUser(newToken.AccessToken, s.HunterPolicyId)
-	if err != nil {
-		log.WithError(err).Error("Error getting assets from nft key for user")
-		return nil, err
-	}
+			// get nft from DB
+			nft, err := s.Store.GetNftByName(string(nftName))
+			if err != nil {
+				log.WithError(err).Errorf("Error getting nft with name %s", nftName)
+				return nil, err
+			}
+			if nft == nil {
+				log.WithError(err).Errorf("No nft with name %s", nftName)
+				return nil, fmt.Errorf("No asset with name %s", nftName)
+			}
 
-	//combine into one slice
-	zcs := make([]nftkeyme.Asset, 0)
-	zcs = append(zcs, zombies...)
-	zcs = append(zcs, hunters...)
+			if nft.HolderAccount.Valid {
+				accounts = append(accounts, nft.HolderAccount.String)
+			} else if !nft.HolderCheckedDate.Valid {
+				// not indexed yet, take nftkey.me's word for it
+				ownedAssetNames = append(ownedAssetNames, string(nftName))
+			}
+		}
 
-	//persist owned zombie
-	ownedAssetNames := make([]string, 0)
-	for _, zc := range zcs {
-		//convert asset name to string
-		nftName, err := hex.DecodeString(zc.AssetName)
+		err = s.Store.SetNftkeymeAccounts(zfcUser.ID, accounts)
 		if err != nil {
+			log.WithError(err).Errorf("Error setting nftkey.me accounts of user %d", zfcUser.ID)
 			return nil, err
 		}
+	}
+
+	held, err := s.Store.GetNftsHeldByUser(zfcUser.ID)
+	if err != nil {
+		log.WithError(err).Error("Error getting nfts held by user")
+		return nil, err
+	}
+	for _, nft := range held {
+		ownedAssetNames = append(ownedAssetNames, nft.NftName)
+	}
 
+	//persist owned zombie
+	for _, nftName := range ownedAssetNames {
 		logrus.Infof("User %d owns zombie %s", zfcUser.ID, nftName)
-		ownedAssetNames = append(ownedAssetNames, string(nftName))
 
 		// get nft from DB
-		nft, err := s.Store.GetNftByName(string(nftName))
+		nft, err := s.Store.GetNftByName(nftName)
 		if err != nil {
 			log.WithError(err).Errorf("Error getting nft with name %s", nftName)
 			return nil, err
@@ -94,7 +100,7 @@ func (s Server) GetAssetsForUser(ctx context.Context, zfcUser db.User) ([]Nft, e
 		}
 
 		// if not already in db, then persist
-		if !containsNft(nfts, string(nftName)) {
+		if !containsNft(nfts, nftName) {
 			err = s.Store.InsertZcNftOwnedByUser(zfcUser.ID, nft.ID)
 			if err != nil {
 				log.WithError(err).Error("Error inserting zfc nft owned")
@@ -110,8 +116,11 @@ func (s Server) GetAssetsForUser(ctx context.Context, zfcUser db.User) ([]Nft, e
 		}
 	}
 
-	// delete any not owned anymore
+	// delete any not owned anymore, not on a user's first check though, that's when their accounts are learned
 	for _, nft := range nfts {
+		if !zfcUser.LastAssetCheckTime.Valid {
+			break
+		}
 		//log.Infof("Checking if user still owns %s", nft.NftName)
 		if !containsString(ownedAssetNames, nft.NftName) {
 			log.Infof("User no longer owns %s, remove from db", nft.NftName)
@@ -130,7 +139,7 @@ func (s Server) GetAssetsForUser(ctx context.Context, zfcUser db.User) ([]Nft, e
 	}
 
 	// set update date
-	err = s.Store.SetLastAssetCheckTime(zfcUser.NftkeymeID, time.Now())
+	err = s.Store.SetLastAssetCheckTime(zfcUser.ID, time.Now())
 	if err != nil {
 		log.WithError(err).Error("Error updating asset check time")
 		return nil, err
@@ -146,12 +155,75 @@ func (s Server) GetAssetsForUser(ctx context.Context, zfcUser db.User) ([]Nft, e
 
 }
 
+// getNftkeymeAssetsForUser get the user's zombies and hunters from nftkey.me, refreshing their token
+func (s Server) getNftkeymeAssetsForUser(ctx context.Context, zfcUser db.User) ([]nftkeyme.Asset, error) {
+	log := logrus.WithContext(ctx)
+
+	//get new token
+	//TODO: add this to func
+	t := oauth2.Token{
+		RefreshToken: zfcUser.NftkeymeRefreshToken,
+	}
+	tokenSource := s.NftkeymeOauthConfig.TokenSource(oauth2.NoContext, &t)
+	newToken, err := tokenSource.Token()
+	if err != nil {
+		logrus.WithError(err).Error("Error getting token")
+		return nil, err
+	}
+	if newToken.AccessToken != zfcUser.NftkeymeAccessToken {
+		logrus.Infof("Updating zfc user %s with new token", zfcUser.NftkeymeID)
+		err = s.Store.UpdatedUser(zfcUser.NftkeymeID, newToken.AccessToken, newToken.RefreshToken)
+		if err != nil {
+			logrus.WithError(err).Error("Error updating discord user")
+			return nil, err
+		}
+	}
+
+	// lookup zombies from nft key me
+	zombies, err := s.NftkeymeClient.GetAssetsForUser(newToken.AccessToken, s.ZombiePolicyId)
+	if err != nil {
+		log.WithError(err).Error("Error getting assets from nft key for user")
+		return nil, err
+	}
+
+	hunters, err := s.NftkeymeClient.GetAssetsForUser(newToken.AccessToken, s.HunterPolicyId)
+	if err != nil {
+		log.WithError(err).Error("Error getting assets from nft key for user")
+		return nil, err
+	}
+
+	//combine into one slice
+	zcs := make([]nftkeyme.Asset, 0)
+	zcs = append(zcs, zombies...)
+	zcs = append(zcs, hunters...)
+
+	return zcs, nil
+}
+
 func (s Server) doesUserOwnNft(ctx context.Context, nftName string, userID int) (bool, error) {
+	// the index knows once it has checked the nft
+	nft, err := s.Store.GetNftByName(nftName)
+	if err != nil {
+		return false, err
+	}
+	if nft == nil {
+		return false, nil
+	}
+	held, indexed, err := s.nftHeldByUser(*nft, userID)
+	if err != nil || held {
+		return held, err
+	}
+
 	dbUser, err := s.Store.GetUserByID(userID)
 	if err != nil {
 		return false, err
 	}
 
+	// until an nftkey.me user's assets are checked their accounts aren't known
+	if indexed && (dbUser.StakeAddress.Valid || dbUser.LastAssetCheckTime.Valid) {
+		return false, nil
+	}
+
 	assets, err := s.GetAssetsForUser(ctx, *dbUser)
 	if err != nil {
 		return false, err
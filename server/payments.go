@@ -0,0 +1,446 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/reliablestaking/zombie-fight-club-server/chain"
+	store "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// PaymentTolerance how far a payment may be off the requested amount and still pay for the fight
+	PaymentTolerance struct {
+		UnderLovelace int64
+		OverLovelace  int64
+	}
+
+	// paymentMatch payments that together pay for one fight
+	paymentMatch struct {
+		Fight    store.FightDb
+		Payments []store.FightPayment
+	}
+)
+
+const (
+	// default for how long a fight waits for payment
+	defaultPaymentWindow = 15 * time.Minute
+	// payments are still accepted this long after the window the user was shown
+	paymentGracePeriod = 5 * time.Minute
+
+	// overpayment below this stays with the royalty output, it couldn't be its own output anyway
+	minChangeLovelace = 1000000
+	// lovelace that goes along with returned tokens
+	minAssetChangeLovelace = 1500000
+)
+
+func (s Server) paymentWindow() time.Duration {
+	if s.PaymentWindow == 0 {
+		return defaultPaymentWindow
+	}
+	return s.PaymentWindow
+}
+
+func (t PaymentTolerance) accepts(expected int64, paid int64) bool {
+	return paid >= expected-t.UnderLovelace && paid <= expected+t.OverLovelace
+}
+
+// matchPayments matches unseen payments to fights awaiting payment. Single utxos are matched first, exact amounts
+// before ones within tolerance, then the utxos of each sender are added up. Payments a fight could still be topped up
+// to are held, the rest are unmatched and should be returned.
+func matchPayments(fights []store.FightDb, payments []store.FightPayment, tolerance PaymentTolerance) ([]paymentMatch, []store.FightPayment, []store.FightPayment) {
+	available := append([]store.FightDb{}, fights...)
+	matches := make([]paymentMatch, 0)
+	remaining := append([]store.FightPayment{}, payments...)
+
+	takeFight := func(amount int64, exact bool) *store.FightDb {
+		index := closestFight(available, amount, tolerance, exact)
+		if index < 0 {
+			return nil
+		}
+		fight := available[index]
+		available = append(available[:index], available[index+1:]...)
+		return &fight
+	}
+
+	for _, exact := range []bool{true, false} {
+		unmatched := make([]store.FightPayment, 0)
+		for _, payment := range remaining {
+			if fight := takeFight(payableLovelace(payment), exact); fight != nil {
+				matches = append(matches, paymentMatch{Fight: *fight, Payments: []store.FightPayment{payment}})
+				continue
+			}
+			unmatched = append(unmatched, payment)
+		}
+		remaining = unmatched
+	}
+
+	// several utxos from one sender for one fight
+	bySender := make(map[string][]store.FightPayment)
+	senders := make([]string, 0)
+	for _, payment := range remaining {
+		if _, found := bySender[payment.SenderAddress]; !found {
+			senders = append(senders, payment.SenderAddress)
+		}
+		bySender[payment.SenderAddress] = append(bySender[payment.SenderAddress], payment)
+	}
+
+	held := make([]store.FightPayment, 0)
+	unmatched := make([]store.FightPayment, 0)
+	for _, sender := range senders {
+		senderPayments := bySender[sender]
+		total := int64(0)
+		for _, payment := range senderPayments {
+			total += payableLovelace(payment)
+		}
+
+		if len(senderPayments) > 1 && sender != "" {
+			if fight := takeFight(total, false); fight != nil {
+				matches = append(matches, paymentMatch{Fight: *fight, Payments: senderPayments})
+				continue
+			}
+		}
+
+		if sender != "" && couldTopUp(available, total, tolerance) {
+			held = append(held, senderPayments...)
+		} else {
+			unmatched = append(unmatched, senderPayments...)
+		}
+	}
+
+	return matches, held, unmatched
+}
+
+// payableLovelace what a payment pays towards a fight, tokens that came along are sent back with lovelace the
+// payment has to cover itself or the mint outputs would come up short
+func payableLovelace(payment store.FightPayment) int64 {
+	if len(payment.Assets) > 0 {
+		return payment.Lovelace - minAssetChangeLovelace
+	}
+	return payment.Lovelace
+}
+
+// closestFight index of the fight the amount pays for, -1 if none or if two are equally close
+func closestFight(fights []store.FightDb, amount int64, tolerance PaymentTolerance, exact bool) int {
+	closest := -1
+	closestDiff := int64(0)
+	tie := false
+	for i, fight := range fights {
+		if exact && fight.PaymentAmountLovelace != amount {
+			continue
+		}
+		if !tolerance.accepts(fight.PaymentAmountLovelace, amount) {
+			continue
+		}
+
+		diff := fight.PaymentAmountLovelace - amount
+		if diff < 0 {
+			diff = -diff
+		}
+		if closest < 0 || diff < closestDiff {
+			closest, closestDiff, tie = i, diff, false
+		} else if diff == closestDiff {
+			tie = true
+		}
+	}
+
+	if tie {
+		return -1
+	}
+	return closest
+}
+
+// couldTopUp true if another payment from the sender could still complete a fight
+func couldTopUp(fights []store.FightDb, total int64, tolerance PaymentTolerance) bool {
+	for _, fight := range fights {
+		if total < fight.PaymentAmountLovelace-tolerance.UnderLovelace {
+			return true
+		}
+	}
+	return false
+}
+
+// fightPaymentInputs gets the tx ins for every utxo that paid for the fight, the payer's address, the change tx out
+// sending over payment and tokens back (empty if there is none) and the lovelace left for the mint outputs
+func (s Server) fightPaymentInputs(ctx context.Context, fight store.FightDb) ([]string, string, string, int, error) {
+	payments, err := s.Store.GetFightPayments(fight.ID)
+	if err != nil {
+		return nil, "", "", 0, err
+	}
+
+	// paid before payments were recorded, always one exact utxo
+	if len(payments) == 0 {
+		payments = []store.FightPayment{{TxHash: fight.IncomingUtxo.String, OutputIndex: int(fight.IncomingUtxoInt.Int64), Lovelace: fight.PaymentAmountLovelace}}
+	}
+
+	returnAddress := payments[0].SenderAddress
+	if returnAddress == "" {
+		inputs, err := s.Chain.TransactionInputs(ctx, payments[0].TxHash)
+		if err != nil {
+			return nil, "", "", 0, err
+		}
+		if len(inputs) == 0 {
+			return nil, "", "", 0, fmt.Errorf("No inputs for tx %s", payments[0].TxHash)
+		}
+		returnAddress = inputs[0].Address
+	}
+
+	txsIn := make([]string, 0)
+	assets := make(store.PaymentAssets, 0)
+	paid := int64(0)
+	for _, payment := range payments {
+		txsIn = append(txsIn, fmt.Sprintf("%s#%d", payment.TxHash, payment.OutputIndex))
+		assets = append(assets, payment.Assets...)
+		paid += payment.Lovelace
+	}
+
+	change := paid - fight.PaymentAmountLovelace
+	if change < 0 {
+		change = 0
+	}
+	// matching made the payment cover this, less at most the under payment tolerance
+	if len(assets) > 0 && change < minAssetChangeLovelace {
+		change = minAssetChangeLovelace
+	} else if len(assets) == 0 && change < minChangeLovelace {
+		change = 0
+	}
+
+	changeTxOut := ""
+	if change > 0 {
+		assetsOut, err := assetsTxOut(assets)
+		if err != nil {
+			return nil, "", "", 0, err
+		}
+		changeTxOut = fmt.Sprintf("%s+%d%s", returnAddress, change, assetsOut)
+		logrus.Infof("Returning %d lovelace and %d assets to %s for fight %d", change, len(assets), returnAddress, fight.ID)
+	}
+
+	return txsIn, returnAddress, changeTxOut, int(paid - change), nil
+}
+
+// processDerivedAddressPayments checks each fight's own address, everything paid to it pays for that fight
+func (s Server) processDerivedAddressPayments(ctx context.Context, fights []store.FightDb, cutoff time.Time) error {
+	senders := make(map[string]string)
+	for _, fight := range fights {
+		payments, err := s.unseenPayments(ctx, fight.PaymentAddress, senders)
+		if err != nil {
+			return err
+		}
+		if len(payments) == 0 {
+			continue
+		}
+
+		// too late, already paid for or called off, send everything back
+		if fight.CreatedDate.Before(cutoff) || fight.Status != store.FightStatusPending {
+			for _, payment := range payments {
+				logrus.Warnf("Utxo %s#%d paid fight %d after it expired, was paid or was voided, recording refund...", payment.TxHash, payment.OutputIndex, fight.ID)
+				err = s.Store.RecordRefund(ctx, refundForPayment(payment, fight.PaymentAddress, fight.PaymentAddressIndex))
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		paid := int64(0)
+		for _, payment := range payments {
+			paid += payableLovelace(payment)
+		}
+		if paid < fight.PaymentAmountLovelace-s.PaymentTolerance.UnderLovelace {
+			logrus.Infof("Fight %d has %d of %d lovelace, waiting for the rest", fight.ID, paid, fight.PaymentAmountLovelace)
+			continue
+		}
+
+		// over payment goes back as change when minting
+		logrus.Infof("%d utxos at %s pay for fight %d", len(payments), fight.PaymentAddress, fight.ID)
+		err = s.moveFightFromPendingToQueued(fight, payments)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// unseenPayments converts the utxos at an address that didn't pay for a fight, a tournament entry or a wager deposit
+// and aren't owed back
+func (s Server) unseenPayments(ctx context.Context, address string, senders map[string]string) ([]store.FightPayment, error) {
+	utxos, err := s.Chain.AddressUTXOs(ctx, address)
+	if err != nil {
+		return nil, err
+	}
+	logrus.Infof("Found %d utxos for address %s", len(utxos), address)
+
+	payments := make([]store.FightPayment, 0)
+	for _, utxo := range utxos {
+		// have we seen this before?
+		existingFight, err := s.Store.GetFightForUtxo(utxo.TxHash, utxo.OutputIndex)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(existingFight) == 1 {
+			logrus.Infof("Found fight with id %d, nothing to do", existingFight[0].ID)
+			continue
+		}
+
+		// already owed back, the refund step takes it from here
+		refund, err := s.Store.GetRefundForUtxo(utxo.TxHash, utxo.OutputIndex)
+		if err != nil {
+			return nil, err
+		}
+		if refund != nil {
+			continue
+		}
+
+		// paid a tournament entry, it stays in the prize pool
+		entry, err := s.Store.GetTournamentEntryForUtxo(utxo.TxHash, utxo.OutputIndex)
+		if err != nil {
+			return nil, err
+		}
+		if entry != nil {
+			continue
+		}
+
+		// paid a wager deposit, it stays put until the wager is paid out or called off
+		deposit, err := s.Store.GetWagerDepositForUtxo(utxo.TxHash, utxo.OutputIndex)
+		if err != nil {
+			return nil, err
+		}
+		if deposit != nil {
+			continue
+		}
+
+		logrus.Infof("Utxo %s with index %d not seen before, check if valid for minting...", utxo.TxHash, utxo.OutputIndex)
+		payment, err := s.paymentFromUtxo(ctx, utxo, senders)
+		if err != nil {
+			logrus.WithError(err).Errorf("Utxo %s#%d can't be matched or returned, ignoring...", utxo.TxHash, utxo.OutputIndex)
+			continue
+		}
+		payments = append(payments, *payment)
+	}
+
+	return payments, nil
+}
+
+// paymentSigningKeyFile key for spending from a payment address, the shared payment key unless the address is derived
+func (s Server) paymentSigningKeyFile(dirName string, addressIndex sql.NullInt64) (string, error) {
+	if !addressIndex.Valid {
+		return "keys/payment.skey", nil
+	}
+	if s.PaymentWallet == nil {
+		return "", fmt.Errorf("No hd wallet configured to spend from derived address %d", addressIndex.Int64)
+	}
+
+	keyFile := fmt.Sprintf("%s/payment-%d.skey", dirName, addressIndex.Int64)
+	return keyFile, s.PaymentWallet.WritePaymentSigningKey(uint32(addressIndex.Int64), keyFile)
+}
+
+func refundForPayment(payment store.FightPayment, address string, addressIndex sql.NullInt64) store.Refund {
+	return store.Refund{
+		TxHash:              payment.TxHash,
+		OutputIndex:         payment.OutputIndex,
+		SenderAddress:       payment.SenderAddress,
+		SenderAccount:       holderAccount(payment.SenderAddress),
+		Lovelace:            payment.Lovelace,
+		Assets:              payment.Assets,
+		PaymentAddress:      address,
+		PaymentAddressIndex: addressIndex,
+	}
+}
+
+// paymentFromUtxo converts a utxo at the payment address, looking up who sent it
+func (s Server) paymentFromUtxo(ctx context.Context, utxo chain.Utxo, senders map[string]string) (*store.FightPayment, error) {
+	payment := store.FightPayment{
+		TxHash:      utxo.TxHash,
+		OutputIndex: utxo.OutputIndex,
+		Assets:      make(store.PaymentAssets, 0),
+	}
+
+	for _, amount := range utxo.Amount {
+		if amount.Unit == chain.Lovelace {
+			lovelace, err := strconv.ParseInt(amount.Quantity, 10, 64)
+			if err != nil {
+				return nil, err
+			}
+			payment.Lovelace += lovelace
+			continue
+		}
+
+		asset := store.PaymentAsset{Unit: amount.Unit, Quantity: amount.Quantity}
+		if _, err := assetTxOut(asset); err != nil {
+			return nil, err
+		}
+		payment.Assets = append(payment.Assets, asset)
+	}
+
+	sender, found := senders[utxo.TxHash]
+	if !found {
+		inputs, err := s.Chain.TransactionInputs(ctx, utxo.TxHash)
+		if err != nil {
+			return nil, err
+		}
+		if len(inputs) == 0 {
+			return nil, fmt.Errorf("No inputs for tx %s", utxo.TxHash)
+		}
+		sender = inputs[0].Address
+		senders[utxo.TxHash] = sender
+	}
+	payment.SenderAddress = sender
+
+	return &payment, nil
+}
+
+// assetTxOut formats an asset the way tx outs take it, "quantity policy.name" with an ascii name
+func assetTxOut(asset store.PaymentAsset) (string, error) {
+	if len(asset.Unit) < 56 {
+		return "", fmt.Errorf("Invalid asset unit %s", asset.Unit)
+	}
+
+	name, err := hex.DecodeString(asset.Unit[56:])
+	if err != nil {
+		return "", err
+	}
+	for _, c := range name {
+		if c < 0x21 || c > 0x7e || c == '+' || c == '.' {
+			return "", fmt.Errorf("Asset %s doesn't have a printable name and has to be returned by hand", asset.Unit)
+		}
+	}
+
+	return fmt.Sprintf("%s %s.%s", asset.Quantity, asset.Unit[:56], string(name)), nil
+}
+
+// assetsTxOut formats every asset as a tx out suffix, i.e. +1 policy.name+2 policy.other, adding up repeated units
+func assetsTxOut(assets store.PaymentAssets) (string, error) {
+	quantities := make(map[string]int64)
+	units := make([]string, 0)
+	for _, asset := range assets {
+		quantity, err := strconv.ParseInt(asset.Quantity, 10, 64)
+		if err != nil {
+			return "", err
+		}
+		if _, found := quantities[asset.Unit]; !found {
+			units = append(units, asset.Unit)
+		}
+		quantities[asset.Unit] += quantity
+	}
+	sort.Strings(units)
+
+	var b strings.Builder
+	for _, unit := range units {
+		out, err := assetTxOut(store.PaymentAsset{Unit: unit, Quantity: strconv.FormatInt(quantities[unit], 10)})
+		if err != nil {
+			return "", err
+		}
+		b.WriteString("+" + out)
+	}
+
+	return b.String(), nil
+}
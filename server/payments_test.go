@@ -0,0 +1,249 @@
+package server
+
+import (
+	"context"
+	"crypto/sha512"
+	"database/sql"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/reliablestaking/zombie-fight-club-server/chain"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/hdwallet"
+)
+
+func TestMatchPayments(t *testing.T) {
+	fights := []db.FightDb{
+		{ID: 1, PaymentAmountLovelace: 12000100},
+		{ID: 2, PaymentAmountLovelace: 12000200},
+		{ID: 3, PaymentAmountLovelace: 12400000},
+	}
+	payments := []db.FightPayment{
+		// within tolerance of fight 1, but fight 2 is exact and must win it
+		{TxHash: "a", SenderAddress: "addr_a", Lovelace: 12000200},
+		{TxHash: "b", SenderAddress: "addr_b", Lovelace: 12000150},
+		// two halves for fight 3
+		{TxHash: "c", SenderAddress: "addr_c", Lovelace: 6000000},
+		{TxHash: "c", OutputIndex: 1, SenderAddress: "addr_c", Lovelace: 6400000},
+		// first half of nothing yet, could still top up
+		{TxHash: "d", SenderAddress: "addr_d", Lovelace: 2000000},
+		// way too much for anything
+		{TxHash: "e", SenderAddress: "addr_e", Lovelace: 50000000},
+	}
+
+	matches, held, unmatched := matchPayments(fights, payments, PaymentTolerance{UnderLovelace: 100000, OverLovelace: 100000})
+
+	matched := make(map[int][]string)
+	for _, match := range matches {
+		for _, payment := range match.Payments {
+			matched[match.Fight.ID] = append(matched[match.Fight.ID], payment.TxHash)
+		}
+	}
+	if strings.Join(matched[2], ",") != "a" || strings.Join(matched[1], ",") != "b" || strings.Join(matched[3], ",") != "c,c" {
+		t.Errorf("Unexpected matches %v", matched)
+	}
+	// every fight is taken so there is nothing left to top up
+	if len(held) != 0 {
+		t.Errorf("Expected nothing held but got %v", held)
+	}
+	if len(unmatched) != 2 || unmatched[0].TxHash != "d" || unmatched[1].TxHash != "e" {
+		t.Errorf("Expected d and e unmatched but got %v", unmatched)
+	}
+
+	// with fight 3 still open the small payment waits for the rest
+	_, held, _ = matchPayments(fights[2:], payments[4:5], PaymentTolerance{})
+	if len(held) != 1 {
+		t.Errorf("Expected partial payment to be held but got %v", held)
+	}
+}
+
+func TestMatchPaymentsAmbiguous(t *testing.T) {
+	fights := []db.FightDb{{ID: 1, PaymentAmountLovelace: 12000000}, {ID: 2, PaymentAmountLovelace: 12000200}}
+	payments := []db.FightPayment{{TxHash: "a", SenderAddress: "addr_a", Lovelace: 12000100}}
+
+	matches, _, _ := matchPayments(fights, payments, PaymentTolerance{UnderLovelace: 1000, OverLovelace: 1000})
+	if len(matches) != 0 {
+		t.Errorf("Expected no match when two fights are equally close but got %v", matches)
+	}
+}
+
+func TestMatchPaymentsWithTokens(t *testing.T) {
+	fights := []db.FightDb{{ID: 1, PaymentAmountLovelace: 12000000}}
+	token := db.PaymentAssets{{Unit: strings.Repeat("ab", 28) + hex.EncodeToString([]byte("Token")), Quantity: "1"}}
+
+	// the token goes back with lovelace the fight's price can't pay for, so it waits for the rest
+	payments := []db.FightPayment{{TxHash: "a", SenderAddress: "addr_a", Lovelace: 12000000, Assets: token}}
+	matches, held, _ := matchPayments(fights, payments, PaymentTolerance{})
+	if len(matches) != 0 || len(held) != 1 {
+		t.Errorf("Expected a payment with a token and nothing for its return held but got %v", matches)
+	}
+
+	payments = []db.FightPayment{{TxHash: "b", SenderAddress: "addr_b", Lovelace: 12000000 + minAssetChangeLovelace, Assets: token}}
+	matches, _, _ = matchPayments(fights, payments, PaymentTolerance{})
+	if len(matches) != 1 {
+		t.Errorf("Expected a payment covering its token's return matched but got %v", matches)
+	}
+}
+
+func TestAssetsTxOut(t *testing.T) {
+	policy := strings.Repeat("ab", 28)
+	out, err := assetsTxOut(db.PaymentAssets{
+		{Unit: policy + hex.EncodeToString([]byte("Token")), Quantity: "2"},
+		{Unit: policy + hex.EncodeToString([]byte("Token")), Quantity: "3"},
+	})
+	if err != nil || out != "+5 "+policy+".Token" {
+		t.Errorf("Unexpected assets tx out %s / %v", out, err)
+	}
+
+	if _, err := assetTxOut(db.PaymentAsset{Unit: policy + "00ff", Quantity: "1"}); err == nil {
+		t.Error("Expected error for an asset name that isn't printable")
+	}
+}
+
+func TestMintingEngineSplitPaymentWithToken(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	s.PaymentTolerance = PaymentTolerance{UnderLovelace: 0, OverLovelace: 3000000}
+
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	fightID, _ := memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment"},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+
+	// pays in two goes, overpays by 2 ada and the wallet attaches a token to the first one
+	first := fakeChain.Pay("addr_buyer", "addr_payment", 7000000)
+	fakeChain.Pay("addr_buyer", "addr_payment", 7000123)
+	token := strings.Repeat("ab", 28) + hex.EncodeToString([]byte("Token"))
+	fakeChain.AddAsset(first.TxHash, first.OutputIndex, chain.Amount{Unit: token, Quantity: "1"})
+
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	if refunds, _ := memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10); len(refunds) != 0 {
+		t.Errorf("Expected nothing to refund but got %v", refunds)
+	}
+	payments, _ := memoryStore.GetFightPayments(fightID)
+	if len(payments) != 2 || len(payments[0].Assets) != 1 || payments[0].SenderAddress != "addr_buyer" {
+		t.Fatalf("Expected both payments recorded but got %v", payments)
+	}
+
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	if err := s.processStagedFights(); err != nil {
+		t.Fatalf("Error minting fights %v", err)
+	}
+
+	submitted := fakeChain.Submitted()
+	if len(submitted) != 1 {
+		t.Fatalf("Expected one submitted tx but got %d", len(submitted))
+	}
+	tx, _ := hex.DecodeString(submitted[0])
+	if !strings.Contains(string(tx), "addr_buyer+2000000+1 "+strings.Repeat("ab", 28)+".Token") {
+		t.Errorf("Expected over payment and token returned in %s", tx)
+	}
+	if strings.Count(string(tx), "#0") != 2 {
+		t.Errorf("Expected both payment utxos spent in %s", tx)
+	}
+}
+
+func TestHeldPaymentReturnedOnceTheWindowPasses(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment"},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+
+	// both could still be topped up to the fight, one of them was first held longer ago than a fight waits for payment
+	fresh := fakeChain.Pay("addr_buyer", "addr_payment", 5000000)
+	stale := fakeChain.Pay("addr_stale", "addr_payment", 6000000)
+	memoryStore.HoldPayment(context.Background(), stale.TxHash, stale.OutputIndex, time.Now().Add(-(s.paymentWindow() + paymentGracePeriod + time.Minute)))
+
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	if refund, _ := memoryStore.GetRefundForUtxo(fresh.TxHash, fresh.OutputIndex); refund != nil {
+		t.Errorf("Expected the fresh payment held but got refund %+v", refund)
+	}
+	if refund, _ := memoryStore.GetRefundForUtxo(stale.TxHash, stale.OutputIndex); refund == nil || refund.SenderAddress != "addr_stale" {
+		t.Errorf("Expected the stale payment refunded but got %+v", refund)
+	}
+}
+
+// newTestWallet hd wallet with a fixed account key that can sign for its derived addresses
+func newTestWallet() *hdwallet.Wallet {
+	seed := sha512.Sum512([]byte("zombie fight club"))
+	key := append([]byte{}, seed[:]...)
+	key[0] &= 0xf8
+	key[31] &= 0x1f
+	key[31] |= 0x40
+	accountKey := &hdwallet.ExtendedPrivateKey{Key: key, ChainCode: seed[32:]}
+	public, _ := accountKey.PublicKey()
+
+	return &hdwallet.Wallet{Account: hdwallet.ExtendedPublicKey{Key: public, ChainCode: accountKey.ChainCode}, AccountKey: accountKey}
+}
+
+func TestMintingEngineDerivedPaymentAddress(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	s.PaymentWallet = newTestWallet()
+
+	index, _ := memoryStore.NextPaymentAddressIndex()
+	address, err := s.PaymentWallet.PaymentAddress(uint32(index))
+	if err != nil {
+		t.Fatalf("Error deriving address %v", err)
+	}
+
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	fightID, _ := memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000000, PaymentAddress: address, PaymentAddressIndex: sql.NullInt64{Int64: int64(index), Valid: true}},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+
+	// half paid, the fight waits for the rest
+	fakeChain.Pay("addr_buyer", address, 6000000)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	if payments, _ := memoryStore.GetFightPayments(fightID); len(payments) != 0 {
+		t.Fatalf("Expected fight to wait for the rest but got %v", payments)
+	}
+
+	// the same amount at the shared address can't pay for it
+	fakeChain.Pay("addr_stranger", "addr_payment", 12000000)
+	fakeChain.Pay("addr_buyer", address, 6000000)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	payments, _ := memoryStore.GetFightPayments(fightID)
+	if len(payments) != 2 || payments[0].SenderAddress != "addr_buyer" || payments[1].SenderAddress != "addr_buyer" {
+		t.Fatalf("Expected both payments to the derived address recorded but got %v", payments)
+	}
+	refunds, _ := memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10)
+	if len(refunds) != 1 || refunds[0].SenderAddress != "addr_stranger" || refunds[0].PaymentAddress != "addr_payment" {
+		t.Errorf("Expected the shared address payment to be owed back but got %v", refunds)
+	}
+
+	// the fight is paid for, anything else sent to its address goes back
+	fakeChain.Pay("addr_late", address, 12000000)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	refunds, _ = memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10)
+	if len(refunds) != 2 || refunds[1].SenderAddress != "addr_late" || refunds[1].PaymentAddress != address {
+		t.Errorf("Expected the late payment to the paid fight's address owed back but got %v", refunds)
+	}
+
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	if err := s.processStagedFights(); err != nil {
+		t.Fatalf("Error minting fights %v", err)
+	}
+	if len(fakeChain.Submitted()) != 1 {
+		t.Fatalf("Expected one submitted tx but got %d", len(fakeChain.Submitted()))
+	}
+}
@@ -0,0 +1,127 @@
+package server
+
+import (
+	"fmt"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+)
+
+type (
+	// Pricing split table for what a fight pays, the owners get their list prices, the artist and treasury fixed
+	// amounts and the royalty address whatever is left after the minted outputs and the tx fee
+	Pricing struct {
+		ArtistAddress      string
+		ArtistLovelace     int64
+		TreasuryAddress    string
+		TreasuryLovelace   int64
+		RoyaltyAddress     string
+		MintOutputLovelace int64
+	}
+
+	// payout one fixed output of a fight's split
+	payout struct {
+		Name     string
+		Address  string
+		Lovelace int64
+	}
+)
+
+const (
+	// defaults match the split from before it was configurable
+	defaultArtistLovelace     = 5000000
+	defaultMintOutputLovelace = 1250000
+
+	// list prices above this are a typo
+	maxListPriceAda = 10000
+	// what the base cost has to leave for the mint tx fee, a mint tx with its metadata comes in well under it
+	mintFeeAllowanceLovelace = 1000000
+)
+
+// DefaultPricing pricing with the default artist and mint output amounts and no treasury
+func DefaultPricing(artistAddress string, royaltyAddress string) Pricing {
+	return Pricing{
+		ArtistAddress:      artistAddress,
+		ArtistLovelace:     defaultArtistLovelace,
+		RoyaltyAddress:     royaltyAddress,
+		MintOutputLovelace: defaultMintOutputLovelace,
+	}
+}
+
+// Validate checks every output the split can produce is above the min utxo, and that a fight paid at the base cost,
+// short by as much as the tolerance allows, covers the minted outputs, the artist and treasury, the fee and the royalty
+func (p Pricing) Validate(baseCostAda int, tolerance PaymentTolerance) error {
+	if p.RoyaltyAddress == "" {
+		return fmt.Errorf("No royalty address")
+	}
+	if p.MintOutputLovelace < minChangeLovelace {
+		return fmt.Errorf("Mint output of %d lovelace is below the min utxo of %d", p.MintOutputLovelace, minChangeLovelace)
+	}
+
+	for _, split := range []payout{{"artist", p.ArtistAddress, p.ArtistLovelace}, {"treasury", p.TreasuryAddress, p.TreasuryLovelace}} {
+		if split.Lovelace == 0 {
+			continue
+		}
+		if split.Address == "" {
+			return fmt.Errorf("No %s address for a split of %d lovelace", split.Name, split.Lovelace)
+		}
+		if split.Lovelace < minChangeLovelace {
+			return fmt.Errorf("The %s split of %d lovelace is below the min utxo of %d", split.Name, split.Lovelace, minChangeLovelace)
+		}
+	}
+
+	// the owners' list prices are paid on top and go to them in full
+	needed := 2*p.MintOutputLovelace + p.ArtistLovelace + p.TreasuryLovelace + mintFeeAllowanceLovelace + minChangeLovelace
+	paid := int64(baseCostAda)*1000000 - tolerance.UnderLovelace
+	if paid < needed {
+		return fmt.Errorf("A base cost of %d ada paid %d lovelace short leaves %d lovelace, the split needs %d", baseCostAda, tolerance.UnderLovelace, paid, needed)
+	}
+
+	return nil
+}
+
+// fightPrice lovelace a fight costs, the base cost plus what the owners listed their nfts for
+func (s Server) fightPrice(zombieAmountAda int, hunterAmountAda int) int64 {
+	return int64(s.BaseCostAda+zombieAmountAda+hunterAmountAda) * 1000000
+}
+
+// fightPayouts fixed outputs for a fight, owners are paid at the address holding their nft
+func (p Pricing) fightPayouts(fight db.FightDb) []payout {
+	payouts := []payout{
+		{"zombie owner", fight.ZombieSendAddress.String, int64(fight.ZombieAmountAda) * 1000000},
+		{"hunter owner", fight.HunterSendAddress.String, int64(fight.HunterAmountAda) * 1000000},
+		{"artist", p.ArtistAddress, p.ArtistLovelace},
+		{"treasury", p.TreasuryAddress, p.TreasuryLovelace},
+	}
+
+	fixed := make([]payout, 0)
+	for _, payout := range payouts {
+		if payout.Lovelace > 0 {
+			fixed = append(fixed, payout)
+		}
+	}
+	return fixed
+}
+
+// payoutTxsOut tx outs for the payouts plus the royalty, which gets what's left of paid after the two minted
+// outputs and the fee
+func (p Pricing) payoutTxsOut(payouts []payout, paid int64, fee int64) ([]string, error) {
+	txsOut := make([]string, 0)
+	royalty := paid - 2*p.MintOutputLovelace - fee
+	for _, payout := range payouts {
+		if payout.Address == "" {
+			return nil, fmt.Errorf("No address to pay the %s %d lovelace", payout.Name, payout.Lovelace)
+		}
+		if payout.Lovelace < minChangeLovelace {
+			return nil, fmt.Errorf("Paying the %s %d lovelace is below the min utxo of %d", payout.Name, payout.Lovelace, minChangeLovelace)
+		}
+		txsOut = append(txsOut, fmt.Sprintf("%s+%d", payout.Address, payout.Lovelace))
+		royalty -= payout.Lovelace
+	}
+
+	if royalty < minChangeLovelace {
+		return nil, fmt.Errorf("Payment of %d lovelace leaves %d for royalty", paid, royalty)
+	}
+	txsOut = append(txsOut, fmt.Sprintf("%s+%d", p.RoyaltyAddress, royalty))
+
+	return txsOut, nil
+}
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"database/sql"
+	"reflect"
+	"testing"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+)
+
+func TestPayoutTxsOut(t *testing.T) {
+	pricing := DefaultPricing("addr_artist", "addr_royalty")
+	pricing.TreasuryAddress = "addr_treasury"
+	pricing.TreasuryLovelace = 2000000
+
+	fight := db.FightDb{
+		ZombieSendAddress: sql.NullString{String: "addr_zombie", Valid: true},
+		ZombieAmountAda:   10,
+		HunterSendAddress: sql.NullString{String: "addr_hunter", Valid: true},
+	}
+
+	// the hunter's owner fought with their own so isn't paid
+	txsOut, err := pricing.payoutTxsOut(pricing.fightPayouts(fight), 22000000, 200000)
+	if err != nil {
+		t.Fatalf("Error building payouts %v", err)
+	}
+	expected := []string{"addr_zombie+10000000", "addr_artist+5000000", "addr_treasury+2000000", "addr_royalty+2300000"}
+	if !reflect.DeepEqual(txsOut, expected) {
+		t.Errorf("Expected %v but got %v", expected, txsOut)
+	}
+
+	if _, err := pricing.payoutTxsOut(pricing.fightPayouts(fight), 20000000, 200000); err == nil {
+		t.Error("Expected error when the royalty is below the min utxo")
+	}
+}
+
+func TestPricingValidate(t *testing.T) {
+	pricing := DefaultPricing("addr_artist", "addr_royalty")
+	if err := pricing.Validate(12, PaymentTolerance{}); err != nil {
+		t.Errorf("Expected default pricing to be valid %v", err)
+	}
+
+	pricing.TreasuryLovelace = 500000
+	if err := pricing.Validate(12, PaymentTolerance{}); err == nil {
+		t.Error("Expected error for a treasury split without an address")
+	}
+
+	pricing.TreasuryAddress = "addr_treasury"
+	if err := pricing.Validate(12, PaymentTolerance{}); err == nil {
+		t.Error("Expected error for a treasury split below the min utxo")
+	}
+}
+
+func TestPricingValidateBaseCost(t *testing.T) {
+	// 2.5 ada of minted outputs, 5 to the artist, 2 to the treasury, 1 for the fee and 1 of royalty
+	pricing := DefaultPricing("addr_artist", "addr_royalty")
+	pricing.TreasuryAddress = "addr_treasury"
+	pricing.TreasuryLovelace = 2000000
+
+	if err := pricing.Validate(12, PaymentTolerance{}); err != nil {
+		t.Errorf("Expected a base cost of 12 ada to cover the split %v", err)
+	}
+	if err := pricing.Validate(11, PaymentTolerance{}); err == nil {
+		t.Error("Expected error for a base cost that doesn't cover the split")
+	}
+	if err := pricing.Validate(12, PaymentTolerance{UnderLovelace: 600000}); err == nil {
+		t.Error("Expected error for a base cost that doesn't cover the split when paid short")
+	}
+}
@@ -0,0 +1,365 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	store "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// Refund what the user sees of a payment owed back to them
+	Refund struct {
+		TxHash        string              `json:"txHash"`
+		OutputIndex   int                 `json:"outputIndex"`
+		Address       string              `json:"address"`
+		Lovelace      int64               `json:"lovelace"`
+		Assets        store.PaymentAssets `json:"assets,omitempty"`
+		Status        store.RefundStatus  `json:"status"`
+		Attempts      int                 `json:"attempts"`
+		RefundTxHash  string              `json:"refundTxHash,omitempty"`
+		CreatedDate   time.Time           `json:"createdDate"`
+		ConfirmedDate *time.Time          `json:"confirmedDate,omitempty"`
+	}
+)
+
+// refunds returned in one tx
+const refundBatchSize = 10
+
+// GetMyRefunds get refunds owed to addresses I've paid for fights from and to my accounts
+func (s Server) GetMyRefunds(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	dbUser := c.Get("user").(*store.User)
+	log.Infof("Getting refunds for user %d", dbUser.ID)
+
+	refunds, err := s.Store.GetRefundsForUser(dbUser.ID)
+	if err != nil {
+		log.WithError(err).Error("Error getting refunds for user")
+		return s.RenderError("Error getting refunds", c)
+	}
+
+	refundDtos := make([]Refund, 0)
+	for _, refund := range refunds {
+		refundDto := Refund{
+			TxHash:       refund.TxHash,
+			OutputIndex:  refund.OutputIndex,
+			Address:      refund.SenderAddress,
+			Lovelace:     refund.Lovelace,
+			Assets:       refund.Assets,
+			Status:       refund.Status,
+			Attempts:     refund.Attempts,
+			RefundTxHash: refund.RefundTxHash.String,
+			CreatedDate:  refund.CreatedDate,
+		}
+		if refund.ConfirmedDate.Valid {
+			refundDto.ConfirmedDate = &refund.ConfirmedDate.Time
+		}
+		refundDtos = append(refundDtos, refundDto)
+	}
+
+	return c.JSON(http.StatusOK, refundDtos)
+}
+
+// processRefunds settles submitted refund txs, then returns the oldest pending refunds in one tx
+func (s Server) processRefunds() error {
+	ctx := context.Background()
+
+	err := s.processSubmittedRefunds(ctx)
+	if err != nil {
+		return err
+	}
+
+	pending, err := s.Store.GetRefundsByStatus(store.RefundStatusPending, refundBatchSize)
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+
+	// a tx spends from one address, the rest wait for the next pass
+	fromAddress := refundAddress(pending[0], s.PaymentAddress)
+	addressIndex := pending[0].PaymentAddressIndex
+
+	// a refund whose utxo is gone or that's too small to be an output on its own can never go in a tx, fail it so it
+	// doesn't hold up the rest
+	utxos, err := s.Chain.AddressUTXOs(ctx, fromAddress)
+	if err != nil {
+		return err
+	}
+	unspent := make(map[string]bool)
+	for _, utxo := range utxos {
+		unspent[fmt.Sprintf("%s#%d", utxo.TxHash, utxo.OutputIndex)] = true
+	}
+	refunds := make([]store.Refund, 0)
+	for _, refund := range pending {
+		if refundAddress(refund, s.PaymentAddress) != fromAddress {
+			continue
+		}
+
+		reason := ""
+		if !unspent[fmt.Sprintf("%s#%d", refund.TxHash, refund.OutputIndex)] {
+			reason = fmt.Sprintf("Utxo %s#%d is no longer at %s", refund.TxHash, refund.OutputIndex, fromAddress)
+		} else if refund.Lovelace < minChangeLovelace {
+			reason = fmt.Sprintf("%d lovelace is too little to return", refund.Lovelace)
+		}
+		if reason != "" {
+			logrus.Warnf("Refund %d can't be returned, failing it: %s", refund.ID, reason)
+			err = s.Store.FailRefunds(ctx, []int{refund.ID}, reason, true)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		refunds = append(refunds, refund)
+	}
+	if len(refunds) == 0 {
+		return nil
+	}
+	logrus.Infof("Returning %d utxos...", len(refunds))
+
+	refundIDs := make([]int, 0)
+	for _, refund := range refunds {
+		refundIDs = append(refundIDs, refund.ID)
+	}
+
+	dirName := "work/" + uuid.New().String()
+	err = os.Mkdir(dirName, 0755)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error creating directory")
+		return err
+	}
+	defer os.RemoveAll(dirName)
+
+	// a tx that can't be built counts as an attempt, and the refunds go to the back of the queue
+	signedTx, txHash, ttl, err := s.buildRefundTransaction(dirName, refunds, addressIndex)
+	if err != nil {
+		failErr := s.Store.FailRefunds(ctx, refundIDs, err.Error(), false)
+		if failErr != nil {
+			return failErr
+		}
+		return err
+	}
+
+	// checkpoint before submitting so a crash can't return the same utxos twice
+	err = s.Store.MarkRefundsSubmitted(ctx, refundIDs, txHash, ttl)
+	if err != nil {
+		return err
+	}
+
+	submitted, err := s.Chain.SubmitTransaction(ctx, signedTx.Hex)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error submitting refund tx %s", txHash)
+		releaseErr := s.Store.ReleaseRefunds(ctx, txHash, err.Error())
+		if releaseErr != nil {
+			return releaseErr
+		}
+		return err
+	}
+	logrus.Infof("Submittted refunds with tx %s", submitted)
+
+	return nil
+}
+
+// refundAddress address the refund's utxo sits at, refunds recorded before derived addresses have none
+func refundAddress(refund store.Refund, sharedAddress string) string {
+	if refund.PaymentAddress == "" {
+		return sharedAddress
+	}
+	return refund.PaymentAddress
+}
+
+// processSubmittedRefunds confirms refund txs that made it on chain and releases the ones that expired
+func (s Server) processSubmittedRefunds(ctx context.Context) error {
+	submitted, err := s.Store.GetRefundsByStatus(store.RefundStatusSubmitted, 1000)
+	if err != nil {
+		return err
+	}
+	if len(submitted) == 0 {
+		return nil
+	}
+
+	tip, err := s.Chain.Tip(ctx)
+	if err != nil {
+		return err
+	}
+
+	checked := make(map[string]bool)
+	for _, refund := range submitted {
+		txHash := refund.RefundTxHash.String
+		if checked[txHash] {
+			continue
+		}
+		checked[txHash] = true
+
+		confirmed, err := s.Chain.TransactionConfirmed(ctx, txHash)
+		if err != nil {
+			return err
+		}
+
+		if confirmed {
+			logrus.Infof("Refund tx %s confirmed", txHash)
+			err = s.Store.ConfirmRefunds(ctx, txHash)
+		} else if tip.Slot > int(refund.RefundTxTTL.Int64)+signedTxExpiryMargin {
+			logrus.Warnf("Refund tx %s expired without being confirmed, releasing its refunds", txHash)
+			err = s.Store.ReleaseRefunds(ctx, txHash, fmt.Sprintf("Refund tx %s expired", txHash))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// buildRefundTransaction builds and signs a tx sending each refund back to its sender, the largest one pays the fee
+func (s Server) buildRefundTransaction(dirName string, refunds []store.Refund, paymentAddressIndex sql.NullInt64) (*SignedTx, string, int, error) {
+	refunds = append([]store.Refund{}, refunds...)
+	sort.SliceStable(refunds, func(i, j int) bool {
+		return refunds[i].Lovelace > refunds[j].Lovelace
+	})
+
+	buildTxs := func(fee int) ([]string, []string, error) {
+		txsIn := make([]string, 0)
+		txsOut := make([]string, 0)
+		for i, refund := range refunds {
+			//build txIn i.e. de44bc164500a05fa49d095e299d1a8b4d706fd971b22995f8cba60737ee5552#0
+			txsIn = append(txsIn, fmt.Sprintf("%s#%d", refund.TxHash, refund.OutputIndex))
+
+			returnAmount := refund.Lovelace
+			if i == 0 {
+				returnAmount = returnAmount - int64(fee)
+			}
+
+			//build send i.e. --tx-out addr_test1qqf3x5gu3g3c7p6dnw3qaqarhzs5rryp0t2qlf09mlvx2ugkjsr90u8d0qpqagp4lts2y7jq8s0c2z6dep2tmgr6s0wqnejpag+2000000+1 056cd1282696748708db782c5af5f3ff1834a96b1cb198ab1e937af3.TestNFTName3
+			assetsOut, err := assetsTxOut(refund.Assets)
+			if err != nil {
+				return nil, nil, err
+			}
+			txsOut = append(txsOut, fmt.Sprintf("%s+%d%s", refund.SenderAddress, returnAmount, assetsOut))
+		}
+		return txsIn, txsOut, nil
+	}
+
+	txsIn, txsOut, err := buildTxs(0)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	fee, err := s.transactionFee(dirName, txsIn, txsOut)
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if refunds[0].Lovelace-int64(fee) < minChangeLovelace {
+		return nil, "", 0, fmt.Errorf("Refund %d of %d lovelace can't cover the fee", refunds[0].ID, refunds[0].Lovelace)
+	}
+
+	//incorporate fee
+	txsIn, txsOut, err = buildTxs(fee)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	return s.signTransaction(dirName, "return", txsIn, txsOut, fee, paymentAddressIndex)
+}
+
+// transactionFee fee for a plain tx spending txsIn to txsOut from a single address, worked out from a draft
+func (s Server) transactionFee(dirName string, txsIn []string, txsOut []string) (int, error) {
+	return s.witnessedTransactionFee(dirName, txsIn, txsOut, 1)
+}
+
+// witnessedTransactionFee fee for a plain tx spending txsIn to txsOut signed by witnesses keys
+func (s Server) witnessedTransactionFee(dirName string, txsIn []string, txsOut []string, witnesses int) (int, error) {
+	draftTxFile := fmt.Sprintf("%s/%s", dirName, "tx.draft")
+	err := s.txBuilder().BuildTransaction(draftTxFile, txsIn, txsOut, 0, 0, "", nil, "", "")
+	if err != nil {
+		logrus.WithError(err).Errorf("Error building draft transaction")
+		return 0, err
+	}
+	fee, err := s.txBuilder().CalculateFee(draftTxFile, len(txsIn), len(txsOut), witnesses)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error calculating fee")
+		return 0, err
+	}
+	logrus.Infof("Calculated a fee of %d", fee)
+
+	return fee, nil
+}
+
+// signTransaction builds a plain tx valid for 3 hours and signs it with the key for the payment address, returns the
+// signed tx, its hash and ttl
+func (s Server) signTransaction(dirName string, name string, txsIn []string, txsOut []string, fee int, paymentAddressIndex sql.NullInt64) (*SignedTx, string, int, error) {
+	return s.multiSignTransaction(dirName, name, txsIn, txsOut, fee, []sql.NullInt64{paymentAddressIndex})
+}
+
+// multiSignTransaction builds a plain tx valid for 3 hours spending from up to 3 payment addresses and signs it with
+// the key for each, returns the signed tx, its hash and ttl
+func (s Server) multiSignTransaction(dirName string, name string, txsIn []string, txsOut []string, fee int, paymentAddressIndexes []sql.NullInt64) (*SignedTx, string, int, error) {
+	if len(paymentAddressIndexes) == 0 || len(paymentAddressIndexes) > 3 {
+		return nil, "", 0, fmt.Errorf("Can't sign with %d keys", len(paymentAddressIndexes))
+	}
+
+	// get ttl
+	tip, err := s.Chain.Tip(context.Background())
+	if err != nil {
+		logrus.WithError(err).Errorf("Error getting latet block")
+		return nil, "", 0, err
+	}
+	logrus.Infof("Found slot of %d", tip.Slot)
+
+	//vaid for 3 hours
+	ttl := tip.Slot + 10800
+	actualTxFile := fmt.Sprintf("%s/%s.tx", dirName, name)
+	err = s.txBuilder().BuildTransaction(actualTxFile, txsIn, txsOut, ttl, fee, "", nil, "", "")
+	if err != nil {
+		logrus.WithError(err).Errorf("Error building transaction")
+		return nil, "", 0, err
+	}
+
+	// sign file
+	keyFiles := make([]string, 3)
+	for i, paymentAddressIndex := range paymentAddressIndexes {
+		keyFiles[i], err = s.paymentSigningKeyFile(dirName, paymentAddressIndex)
+		if err != nil {
+			return nil, "", 0, err
+		}
+	}
+	signedTxFile := fmt.Sprintf("%s/%s.signed", dirName, name)
+	err = s.txBuilder().SignTransaction(actualTxFile, keyFiles[0], keyFiles[1], keyFiles[2], signedTxFile)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error signing transaction")
+		return nil, "", 0, err
+	}
+
+	// get cbor hex
+	byteValue, err := os.ReadFile(signedTxFile)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error opening file")
+		return nil, "", 0, err
+	}
+	var signedTx SignedTx
+	err = json.Unmarshal(byteValue, &signedTx)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error reading signed tx")
+		return nil, "", 0, err
+	}
+
+	txHash, err := s.txBuilder().GetTransactionId(signedTxFile)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error getting tx id")
+		return nil, "", 0, err
+	}
+
+	return &signedTx, strings.TrimSpace(txHash), ttl, nil
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+)
+
+func TestRefundLedger(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+
+	stray := fakeChain.Pay("addr_stranger", "addr_payment", 5000000)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	// seeing the utxo again doesn't owe it twice
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+
+	// a failed submission is kept, with the attempt counted
+	fakeChain.SubmitError = errors.New("node rejected tx")
+	if err := s.processRefunds(); err == nil {
+		t.Fatal("Expected submit error")
+	}
+	refund, _ := memoryStore.GetRefundForUtxo(stray.TxHash, 0)
+	if refund.Status != db.RefundStatusPending || refund.Attempts != 1 || refund.LastError.String != "node rejected tx" {
+		t.Fatalf("Expected refund back to pending after a failed submit but got %+v", refund)
+	}
+
+	fakeChain.SubmitError = nil
+	if err := s.processRefunds(); err != nil {
+		t.Fatalf("Error processing refunds %v", err)
+	}
+	// still waiting on the tx, nothing is submitted twice
+	if err := s.processRefunds(); err != nil {
+		t.Fatalf("Error processing refunds %v", err)
+	}
+	if len(fakeChain.Submitted()) != 1 {
+		t.Fatalf("Expected one submitted refund tx but got %d", len(fakeChain.Submitted()))
+	}
+
+	refund, _ = memoryStore.GetRefundForUtxo(stray.TxHash, 0)
+	if refund.Status != db.RefundStatusSubmitted || refund.Attempts != 2 {
+		t.Fatalf("Expected refund submitted on the second attempt but got %+v", refund)
+	}
+
+	fakeChain.Confirm(refund.RefundTxHash.String)
+	if err := s.processRefunds(); err != nil {
+		t.Fatalf("Error processing refunds %v", err)
+	}
+	refund, _ = memoryStore.GetRefundForUtxo(stray.TxHash, 0)
+	if refund.Status != db.RefundStatusConfirmed || !refund.ConfirmedDate.Valid {
+		t.Fatalf("Expected refund confirmed but got %+v", refund)
+	}
+}
+
+func TestRefundExpiresAndIsRetried(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+
+	stray := fakeChain.Pay("addr_stranger", "addr_payment", 5000000)
+	s.processIncomingPayments()
+	if err := s.processRefunds(); err != nil {
+		t.Fatalf("Error processing refunds %v", err)
+	}
+
+	// never makes it into a block
+	fakeChain.AdvanceSlots(10800 + signedTxExpiryMargin + 1)
+	if err := s.processRefunds(); err != nil {
+		t.Fatalf("Error processing refunds %v", err)
+	}
+
+	refund, _ := memoryStore.GetRefundForUtxo(stray.TxHash, 0)
+	if refund.Status != db.RefundStatusSubmitted || refund.Attempts != 2 || len(fakeChain.Submitted()) != 2 {
+		t.Fatalf("Expected expired refund to be resubmitted but got %+v", refund)
+	}
+}
+
+func TestRefundThatCantBeReturnedDoesntHoldUpTheRest(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	s.PaymentWallet = newTestWallet()
+	derived, _ := s.PaymentWallet.PaymentAddress(7)
+
+	// ahead of a normal refund, one that can't pay its own fee at a derived address and dust at the shared one
+	small := fakeChain.Pay("addr_small", derived, 1100000)
+	memoryStore.RecordRefund(context.Background(), db.Refund{TxHash: small.TxHash, OutputIndex: small.OutputIndex, SenderAddress: "addr_small",
+		Lovelace: 1100000, PaymentAddress: derived, PaymentAddressIndex: sql.NullInt64{Int64: 7, Valid: true}})
+	dust := fakeChain.Pay("addr_dust", "addr_payment", 500000)
+	stray := fakeChain.Pay("addr_stranger", "addr_payment", 5000000)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+
+	// the tx can't be built, that's an attempt and the refund goes to the back of the queue
+	if err := s.processRefunds(); err == nil {
+		t.Fatal("Expected the small refund to fail")
+	}
+	refund, _ := memoryStore.GetRefundForUtxo(small.TxHash, small.OutputIndex)
+	if refund.Status != db.RefundStatusPending || refund.Attempts != 1 || !refund.LastError.Valid {
+		t.Fatalf("Expected the failed build counted but got %+v", refund)
+	}
+
+	// dust can never be returned, the normal refund goes out
+	if err := s.processRefunds(); err != nil {
+		t.Fatalf("Error processing refunds %v", err)
+	}
+	refund, _ = memoryStore.GetRefundForUtxo(dust.TxHash, dust.OutputIndex)
+	if refund.Status != db.RefundStatusFailed {
+		t.Errorf("Expected the dust refund failed but got %+v", refund)
+	}
+	refund, _ = memoryStore.GetRefundForUtxo(stray.TxHash, stray.OutputIndex)
+	if refund.Status != db.RefundStatusSubmitted || len(fakeChain.Submitted()) != 1 {
+		t.Fatalf("Expected the normal refund submitted but got %+v", refund)
+	}
+
+	// the small one is failed once out of attempts
+	for i := 1; i < db.MaxRefundAttempts; i++ {
+		s.processRefunds()
+	}
+	refund, _ = memoryStore.GetRefundForUtxo(small.TxHash, small.OutputIndex)
+	if refund.Status != db.RefundStatusFailed || refund.Attempts != db.MaxRefundAttempts {
+		t.Errorf("Expected the small refund failed after %d attempts but got %+v", db.MaxRefundAttempts, refund)
+	}
+}
+
+func TestGetMyRefunds(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment"},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+
+	// the user pays for their fight, then pays again by mistake
+	fakeChain.Pay("addr_buyer", "addr_payment", 12000123)
+	s.processIncomingPayments()
+	fakeChain.Pay("addr_buyer", "addr_payment", 3000000)
+	fakeChain.Pay("addr_stranger", "addr_payment", 5000000)
+	// and sends a payment that matches nothing from another address of an account they hold
+	wallet, _ := newTestWallet().PaymentAddress(3)
+	if err := memoryStore.AddUserAccount(user.ID, holderAccount(wallet)); err != nil {
+		t.Fatalf("Error adding account %v", err)
+	}
+	fakeChain.Pay(wallet, "addr_payment", 4000000)
+	s.processIncomingPayments()
+
+	c, rec := newTestContext(http.MethodGet, "/user/refunds", "", *user)
+	if err := s.GetMyRefunds(c); err != nil {
+		t.Fatalf("Error getting refunds %v", err)
+	}
+
+	refunds := make([]Refund, 0)
+	json.Unmarshal(rec.Body.Bytes(), &refunds)
+	if len(refunds) != 2 || refunds[0].Address != wallet || refunds[1].Address != "addr_buyer" || refunds[1].Lovelace != 3000000 ||
+		refunds[1].Status != db.RefundStatusPending {
+		t.Fatalf("Expected only the user's refund but got %s", rec.Body.String())
+	}
+}
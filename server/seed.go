@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+
+	store "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/metadata"
+	"github.com/sirupsen/logrus"
+)
+
+// newFightSecret random secret for a new fight and the commitment to it that's published right away
+func newFightSecret() (string, string, error) {
+	secret := make([]byte, 32)
+	_, err := rand.Read(secret)
+	if err != nil {
+		return "", "", err
+	}
+
+	commitment := sha256.Sum256(secret)
+	return hex.EncodeToString(secret), hex.EncodeToString(commitment[:]), nil
+}
+
+// fightSeed sha256(secret || payment tx hash), the payment isn't known when the secret is committed to so neither
+// side can pick the outcome
+func fightSeed(secret string, paymentTxHash string) ([]byte, error) {
+	secretBytes, err := hex.DecodeString(secret)
+	if err != nil {
+		return nil, err
+	}
+	txHashBytes, err := hex.DecodeString(paymentTxHash)
+	if err != nil {
+		return nil, err
+	}
+
+	seed := sha256.Sum256(append(secretBytes, txHashBytes...))
+	return seed[:], nil
+}
+
+// fightRand random numbers for a fight's outcome, the seed is recorded from the fight's first payment the first time
+// so retrying a fight can't change it
+func (s Server) fightRand(ctx context.Context, fight store.FightDb) (*metadata.Rand, error) {
+	if fight.Seed.Valid {
+		seed, err := hex.DecodeString(fight.Seed.String)
+		if err != nil {
+			return nil, err
+		}
+		return metadata.NewRand(seed), nil
+	}
+
+	payments, err := s.Store.GetFightPayments(fight.ID)
+	if err != nil {
+		return nil, err
+	}
+	paymentTxHash := fight.IncomingUtxo.String
+	if len(payments) > 0 {
+		paymentTxHash = payments[0].TxHash
+	}
+	if paymentTxHash == "" {
+		return nil, fmt.Errorf("No payment to seed fight %d from", fight.ID)
+	}
+
+	// fights created before seeds were committed to only have the payment
+	if !fight.SeedSecret.Valid {
+		logrus.Warnf("Fight %d has no committed secret, seeding from payment %s only", fight.ID, paymentTxHash)
+	}
+	seed, err := fightSeed(fight.SeedSecret.String, paymentTxHash)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Store.SetFightSeed(ctx, fight.ID, paymentTxHash, hex.EncodeToString(seed))
+	if err != nil {
+		return nil, err
+	}
+
+	// a retry or another minter may have set it first, the stored seed is the one the fight is replayed from
+	seeded, err := s.Store.GetFight(fight.ID)
+	if err != nil {
+		return nil, err
+	}
+	if seeded == nil || !seeded.Seed.Valid {
+		return nil, fmt.Errorf("Fight %d has no seed after setting it", fight.ID)
+	}
+	if seeded.Seed.String != hex.EncodeToString(seed) {
+		logrus.Warnf("Fight %d was already seeded with %s from payment %s", fight.ID, seeded.Seed.String, seeded.SeedTxHash.String)
+	} else {
+		logrus.Infof("Fight %d seeded with %x from payment %s", fight.ID, seed, paymentTxHash)
+	}
+
+	seed, err = hex.DecodeString(seeded.Seed.String)
+	if err != nil {
+		return nil, err
+	}
+	return metadata.NewRand(seed), nil
+}
+
+// fightSeedProof the fight's commitment, with the secret and seed once the fight is paid and its outcome fixed
+func fightSeedProof(fight store.FightDb) *store.FightSeed {
+	if !fight.SeedCommitment.Valid && !fight.Seed.Valid {
+		return nil
+	}
+
+	proof := &store.FightSeed{Commitment: fight.SeedCommitment.String}
+	if fight.Seed.Valid {
+		proof.Secret = fight.SeedSecret.String
+		proof.PaymentTxHash = fight.SeedTxHash.String
+		proof.Seed = fight.Seed.String
+	}
+
+	return proof
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+)
+
+func TestFightSeedCommitReveal(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+
+	secret, commitment, err := newFightSecret()
+	if err != nil {
+		t.Fatalf("Error creating secret %v", err)
+	}
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	fightID, _ := memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment", SeedSecret: secret, Seed: &db.FightSeed{Commitment: commitment}},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+
+	payment := fakeChain.Pay("addr_buyer", "addr_payment", 12000123)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+
+	staged, _ := memoryStore.GetStagedFights()
+	if len(staged) != 1 || staged[0].ID != fightID {
+		t.Fatalf("Expected the fight to be staged but got %v", staged)
+	}
+	proof := fightSeedProof(staged[0])
+
+	// anyone can check the secret against the commitment and the seed against the secret and payment
+	secretBytes, _ := hex.DecodeString(proof.Secret)
+	committed := sha256.Sum256(secretBytes)
+	txHashBytes, _ := hex.DecodeString(payment.TxHash)
+	seed := sha256.Sum256(append(secretBytes, txHashBytes...))
+	if hex.EncodeToString(committed[:]) != commitment || proof.PaymentTxHash != payment.TxHash || proof.Seed != hex.EncodeToString(seed[:]) {
+		t.Errorf("Proof doesn't check out %v", proof)
+	}
+
+	// the seed is recorded once, a retry draws the same numbers
+	if err := memoryStore.SetFightSeed(nil, fightID, "other", "other"); err != nil {
+		t.Fatalf("Error setting seed %v", err)
+	}
+	first, _ := s.fightRand(nil, staged[0])
+	staged, _ = memoryStore.GetStagedFights()
+	second, _ := s.fightRand(nil, staged[0])
+	if first.Intn(1000000) != second.Intn(1000000) {
+		t.Error("Expected the same draws from the recorded seed")
+	}
+
+	// a copy of the fight read before it was seeded, by a retry that would work out another seed, still draws from
+	// the recorded one
+	otherSecret, _, _ := newFightSecret()
+	unseeded := staged[0]
+	unseeded.Seed.Valid = false
+	unseeded.SeedSecret.String = otherSecret
+	third, err := s.fightRand(nil, unseeded)
+	if err != nil {
+		t.Fatalf("Error seeding fight %v", err)
+	}
+	recorded, _ := s.fightRand(nil, staged[0])
+	if third.Intn(1000000) != recorded.Intn(1000000) {
+		t.Error("Expected the draws from the recorded seed, not a new one")
+	}
+
+	meta, err := buildFightMetaString(staged[0], 1)
+	if err != nil {
+		t.Fatalf("Error building metadata %v", err)
+	}
+	if !strings.Contains(meta, `"seed":{"commitment":"`+commitment+`","secret":"`+secret) {
+		t.Errorf("Expected the proof in the fight metadata %s", meta)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/labstack/echo-contrib/session"
 	"github.com/labstack/echo/v4"
@@ -11,8 +12,9 @@ import (
 	"github.com/ory/hydra-client-go/client/admin"
 	db "github.com/reliablestaking/zombie-fight-club-server/db"
 
-	bfg "github.com/blockfrost/blockfrost-go"
-	"github.com/reliablestaking/zombie-fight-club-server/blockfrost"
+	"github.com/reliablestaking/zombie-fight-club-server/cardanocli"
+	"github.com/reliablestaking/zombie-fight-club-server/chain"
+	"github.com/reliablestaking/zombie-fight-club-server/hdwallet"
 
 	"github.com/reliablestaking/zombie-fight-club-server/metadata"
 	"github.com/reliablestaking/zombie-fight-club-server/nftstorage"
@@ -43,16 +45,24 @@ type (
 		HunterMeta                map[string]string
 		HunterMetaStruct          map[string]metadata.ZombieHunter
 		ZombieHunterTraitStrength metadata.ZombieHunterTraitStrength
+		FightRules                *metadata.Rules
 		BaseCostAda               int
 		PaymentAddress            string
-		BlockfrostClient          bfg.APIClient
+		PaymentTolerance          PaymentTolerance
+		PaymentWindow             time.Duration
+		PaymentWallet             *hdwallet.Wallet
+		Matchmaking               Matchmaking
+		ChallengeTTL              time.Duration
+		FightLimits               FightLimits
+		Wagers                    Wagers
+		NftIndex                  NftIndex
+		Chain                     chain.ChainProvider
+		TxBuilder                 cardanocli.Builder
 		ImageBuilderClient        imagebuilder.ImageBuilderClient
-		BlockforstIpfsClient      blockfrost.BlockfrostClient
 		NftStorageClient          nftstorage.NftstorageClient
 		ZfcPolicyID               string
 		AlienPolicyID             string
-		BrianSplitAddress         string
-		RoyaltySplitAddress       string
+		Pricing                   Pricing
 		LeaderCache               *cache.Cache
 		HydraClient               HydraClient
 	}
@@ -62,11 +72,27 @@ type (
 		Sha       string `json:"sha"`
 		BuildTime string `json:"buildTime"`
 	}
+
+	// ErrorResponse what RenderError renders
+	ErrorResponse struct {
+		Error string `json:"Error"`
+	}
 )
 
 // Start the server
 func (s Server) Start() {
 	logrus.Info("Starting server...")
+	e := s.newRouter()
+
+	port := os.Getenv("NFTKEYME_SERVICE_PORT")
+	if port == "" {
+		port = "8080"
+	}
+	e.Logger.Fatal(e.Start(":" + port))
+}
+
+// newRouter sets up the middleware and routes, api/openapi.yaml documents every route
+func (s Server) newRouter() *echo.Echo {
 	e := echo.New()
 
 	allowedOriginsCsv := make([]string, 0)
@@ -88,28 +114,61 @@ func (s Server) Start() {
 	e.POST("/login", s.Login) //accept auth code from server, exchange for access/refresh token, create session using gorilla session
 	e.POST("/login/check", s.LoginCheck, s.CheckCookie)
 
+	// wallet login
+	e.POST("/login/wallet/nonce", s.CreateWalletLoginNonce) // get a nonce for a stake address to sign with the wallet
+	e.POST("/login/wallet", s.LoginWithWallet)              // verify the signed nonce (CIP-30 signData), create session like /login
+
 	// general fight endpoints
 	e.GET("/fightnfts", s.GetNftsToFight, s.CheckCookie)     // find nfts available to fight
 	e.POST("/fights", s.CreateFight, s.CheckCookie)          // create a new fight
 	e.GET("/fights/:fightId", s.GetFightById, s.CheckCookie) // get fight status
+	e.GET("/fights/:fightId/log", s.GetFightLog)             // get how a fought fight played out, round by round
 
 	// fights
 	e.GET("/user/nfts", s.GetMyNfts, s.CheckCookie)                // get all of my zombies
 	e.PUT("/user/nfts/:name", s.ListNftForFight, s.CheckCookie)    // list a new zombie
 	e.DELETE("/user/nfts/:name", s.DeleteListedNft, s.CheckCookie) // delist a zombie
 	e.GET("/user/fights", s.GetMyFights, s.CheckCookie)            // get my fights
+	e.GET("/user/refunds", s.GetMyRefunds, s.CheckCookie)          // get refunds owed to me
+	e.POST("/user/accounts", s.LinkWalletAccount, s.CheckCookie)   // link a stake address signed for like /login/wallet, what's held there is mine
+
+	// matchmaking
+	e.GET("/user/matchmaking", s.GetMyMatchmaking, s.CheckCookie)                   // get my queued and matched nfts
+	e.POST("/user/matchmaking", s.QueueForMatchmaking, s.CheckCookie)               // queue a zombie or hunter to be paired with a listed opponent
+	e.DELETE("/user/matchmaking/:entryId", s.DeleteMatchmakingEntry, s.CheckCookie) // take a queued nft out of the queue
+
+	// challenges
+	e.GET("/user/challenges", s.GetMyChallenges, s.CheckCookie)                        // get challenges I sent or received
+	e.POST("/user/challenges", s.CreateChallenge, s.CheckCookie)                       // challenge another owner's nft
+	e.POST("/user/challenges/:challengeId/accept", s.AcceptChallenge, s.CheckCookie)   // accept, creates the fight for the challenger to pay
+	e.POST("/user/challenges/:challengeId/decline", s.DeclineChallenge, s.CheckCookie) // decline
+	e.POST("/user/challenges/:challengeId/counter", s.CounterChallenge, s.CheckCookie) // counter with a different stake
+	e.DELETE("/user/challenges/:challengeId", s.CancelChallenge, s.CheckCookie)        // take back a challenge I sent
+
+	// tournaments
+	e.GET("/tournaments", s.GetTournaments)                                              // get every tournament
+	e.GET("/tournaments/:tournamentId", s.GetTournamentById)                             // get a tournament with its entries and bracket
+	e.POST("/tournaments/:tournamentId/entries", s.CreateTournamentEntry, s.CheckCookie) // enter a zombie and hunter, paid to the tournament's address
 
 	// leaderboard
-	e.GET("/leaders", s.GetLeaders) // get leaderboards (most wins, most loses, etc...)
+	e.GET("/leaders", s.GetLeaders) // get leaderboards (most wins, most loses, etc...), ?board=rating for glicko-2 ratings, ?season=id|current for a season
+
+	// admin, every change is written to the audit log
+	adminRoutes := e.Group("/admin", s.CheckCookie, s.AuditAdmin)
+	adminRoutes.GET("/health", s.GetEngineHealth, s.RequireRole(db.UserRoleViewer))                          // engine heartbeats and work waiting on them
+	adminRoutes.GET("/fights", s.GetAdminFights, s.RequireRole(db.UserRoleViewer))                           // list fights, ?status=QUEUED&limit=50
+	adminRoutes.GET("/fights/:fightId/metadata", s.GetFightMetadata, s.RequireRole(db.UserRoleViewer))       // rebuild the fight's mint metadata
+	adminRoutes.POST("/fights/:fightId/retry", s.RetryFight, s.RequireRole(db.UserRoleOperator))             // drop a stuck signed mint tx, or confirm a minted fight
+	adminRoutes.POST("/fights/:fightId/cancel", s.CancelFight, s.RequireRole(db.UserRoleOperator))           // void a pending or queued fight and refund it
+	adminRoutes.POST("/fights/:fightId/images", s.RegenerateFightImages, s.RequireRole(db.UserRoleOperator)) // have the minter rebuild a staged fight's images
+	adminRoutes.POST("/refunds", s.ForceRefund, s.RequireRole(db.UserRoleOperator))                          // owe a utxo at a payment address back to its sender
+	adminRoutes.GET("/audit", s.GetAdminAudit, s.RequireRole(db.UserRoleAdmin))                              // latest changes made through the admin api
+	adminRoutes.PUT("/users/:userId/role", s.SetUserRole, s.RequireRole(db.UserRoleAdmin))                   // give or take away a role
 
 	// version endpoint
 	e.GET("/version", s.GetVersion)
 
-	port := os.Getenv("NFTKEYME_SERVICE_PORT")
-	if port == "" {
-		port = "8080"
-	}
-	e.Logger.Fatal(e.Start(":" + port))
+	return e
 }
 
 // GetVersion return build version info
@@ -124,9 +183,7 @@ func (s Server) GetVersion(c echo.Context) (err error) {
 
 // RenderError renders an error page
 func (s Server) RenderError(errorMsg string, c echo.Context) error {
-	errorEnd := struct {
-		Error string
-	}{
+	errorEnd := ErrorResponse{
 		Error: errorMsg,
 	}
 	return c.JSON(http.StatusInternalServerError, errorEnd)
@@ -203,17 +260,26 @@ func (s Server) CheckCookie(next echo.HandlerFunc) echo.HandlerFunc {
 				return echo.NewHTTPError(http.StatusUnauthorized)
 			}
 
-			nftkeyUserID := sess.Values["nftId"].(string)
-			if nftkeyUserID == "" {
-				logrus.Error("No user id found")
-				return echo.NewHTTPError(http.StatusUnauthorized)
-			}
+			var user *db.User
+			if stakeAddress, _ := sess.Values[sessionStakeAddress].(string); stakeAddress != "" {
+				user, err = s.Store.GetUserByStakeAddress(stakeAddress)
+			} else {
+				nftkeyUserID, _ := sess.Values[sessionNftkeyID].(string)
+				if nftkeyUserID == "" {
+					logrus.Error("No user id found")
+					return echo.NewHTTPError(http.StatusUnauthorized)
+				}
 
-			user, err := s.Store.GetUserByNftkeyID(nftkeyUserID)
+				user, err = s.Store.GetUserByNftkeyID(nftkeyUserID)
+			}
 			if err != nil {
 				logrus.WithError(err).Error("Error getting user")
 				return echo.NewHTTPError(http.StatusInternalServerError)
 			}
+			if user == nil {
+				logrus.Error("No user found for session")
+				return echo.NewHTTPError(http.StatusUnauthorized)
+			}
 
 			c.Set("user", user)
 
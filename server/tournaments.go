@@ -0,0 +1,909 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+	store "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/tournament"
+	"github.com/sirupsen/logrus"
+)
+
+type (
+	// Tournament what the user sees of a tournament
+	Tournament struct {
+		ID                int                    `json:"id"`
+		Name              string                 `json:"name"`
+		Format            string                 `json:"format"`
+		Status            store.TournamentStatus `json:"status"`
+		BracketSize       int                    `json:"bracketSize"`
+		EntryFeeLovelace  int64                  `json:"entryFeeLovelace"`
+		EntryFeeAda       string                 `json:"entryFeeAda"`
+		PrizeSplit        []int                  `json:"prizeSplit"`
+		RegistrationStart time.Time              `json:"registrationStart"`
+		RegistrationEnd   time.Time              `json:"registrationEnd"`
+		PaidEntries       int                    `json:"paidEntries"`
+		CollectedLovelace int64                  `json:"collectedLovelace"`
+		PayoutTxHash      string                 `json:"payoutTxHash,omitempty"`
+		StartedDate       *time.Time             `json:"startedDate,omitempty"`
+		FinishedDate      *time.Time             `json:"finishedDate,omitempty"`
+	}
+
+	// TournamentDetail a tournament with its paid entries and bracket
+	TournamentDetail struct {
+		Tournament
+		Entries []TournamentEntry `json:"entries"`
+		Matches []TournamentMatch `json:"matches"`
+	}
+
+	// TournamentEntry a paid entry, seeded once the tournament starts and placed once it finishes
+	TournamentEntry struct {
+		ID         int    `json:"id"`
+		ZombieName string `json:"zombieName"`
+		HunterName string `json:"hunterName"`
+		Seed       int    `json:"seed,omitempty"`
+		Place      int    `json:"place,omitempty"`
+	}
+
+	// TournamentMatch one match of the bracket, entries are entry ids
+	TournamentMatch struct {
+		Number      int    `json:"number"`
+		Bracket     string `json:"bracket"`
+		Round       int    `json:"round"`
+		TopEntry    int    `json:"topEntry,omitempty"`
+		BottomEntry int    `json:"bottomEntry,omitempty"`
+		ZombieEntry int    `json:"zombieEntry,omitempty"`
+		Winner      int    `json:"winner,omitempty"`
+		Decided     bool   `json:"decided"`
+		FightID     int    `json:"fightId,omitempty"`
+		FightStatus string `json:"fightStatus,omitempty"`
+	}
+
+	// TournamentEntryRequest the zombie and hunter a user enters with
+	TournamentEntryRequest struct {
+		ZombieName string `json:"zombieName"`
+		HunterName string `json:"hunterName"`
+	}
+
+	// TournamentEntryPayment where and how much to pay for a new entry
+	TournamentEntryPayment struct {
+		ID                    int    `json:"id"`
+		TournamentID          int    `json:"tournamentId"`
+		ZombieName            string `json:"zombieName"`
+		HunterName            string `json:"hunterName"`
+		PaymentAddress        string `json:"paymentAddress"`
+		PaymentAmountLovelace int64  `json:"paymentAmountLovelace"`
+		PaymentAmountAda      string `json:"paymentAmountAda"`
+		MinutesUntilExpired   int    `json:"minutesUntilExpired"`
+	}
+)
+
+// GetTournaments get every tournament, latest first
+func (s Server) GetTournaments(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	tournaments, err := s.Store.GetTournaments()
+	if err != nil {
+		log.WithError(err).Error("Error getting tournaments")
+		return s.RenderError("Error getting tournaments", c)
+	}
+
+	tournamentDtos := make([]Tournament, 0)
+	for _, t := range tournaments {
+		entries, err := s.Store.GetTournamentEntries(t.ID)
+		if err != nil {
+			log.WithError(err).Errorf("Error getting entries for tournament %d", t.ID)
+			return s.RenderError("Error getting tournaments", c)
+		}
+		tournamentDtos = append(tournamentDtos, convertDbTournamentToDto(t, entries))
+	}
+
+	return c.JSON(http.StatusOK, tournamentDtos)
+}
+
+// GetTournamentById get a tournament with its entries and bracket
+func (s Server) GetTournamentById(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	t, err := s.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.Store.GetTournamentEntries(t.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting entries for tournament %d", t.ID)
+		return s.RenderError("Error getting tournament", c)
+	}
+	matches, err := s.Store.GetTournamentMatches(t.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting matches for tournament %d", t.ID)
+		return s.RenderError("Error getting tournament", c)
+	}
+
+	detail := TournamentDetail{
+		Tournament: convertDbTournamentToDto(*t, entries),
+		Entries:    make([]TournamentEntry, 0),
+		Matches:    make([]TournamentMatch, 0),
+	}
+	for _, entry := range entries {
+		if !entry.Paid() {
+			continue
+		}
+		detail.Entries = append(detail.Entries, TournamentEntry{
+			ID:         entry.ID,
+			ZombieName: entry.ZombieName,
+			HunterName: entry.HunterName,
+			Seed:       int(entry.Seed.Int64),
+			Place:      int(entry.Place.Int64),
+		})
+	}
+	for _, match := range matches {
+		detail.Matches = append(detail.Matches, TournamentMatch{
+			Number:      match.MatchNumber,
+			Bracket:     match.Bracket,
+			Round:       match.Round,
+			TopEntry:    int(match.TopEntryID.Int64),
+			BottomEntry: int(match.BottomEntryID.Int64),
+			ZombieEntry: int(match.ZombieEntryID.Int64),
+			Winner:      int(match.WinnerEntryID.Int64),
+			Decided:     match.Decided,
+			FightID:     int(match.FightID.Int64),
+			FightStatus: match.FightStatus.String,
+		})
+	}
+
+	return c.JSON(http.StatusOK, detail)
+}
+
+// CreateTournamentEntry enter one of my listed zombies and hunters in a tournament, the entry counts once its fee is
+// paid to the tournament's address
+func (s Server) CreateTournamentEntry(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	t, err := s.tournamentParam(c)
+	if err != nil {
+		return err
+	}
+
+	request := new(TournamentEntryRequest)
+	if err = c.Bind(request); err != nil {
+		log.WithError(err).Errorf("Error binding")
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	dbUser := c.Get("user").(*store.User)
+	log.Infof("Entering %s and %s in tournament %d for user %d", request.ZombieName, request.HunterName, t.ID, dbUser.ID)
+
+	now := time.Now()
+	if t.Status != store.TournamentStatusRegistration || now.Before(t.RegistrationStart) || !now.Before(t.RegistrationEnd) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Registration isn't open")
+	}
+
+	// both have to be the user's own and listed to fight
+	zombie, err := s.entryNft(c, request.ZombieName, "Zombie", dbUser.ID)
+	if err != nil {
+		return err
+	}
+	hunter, err := s.entryNft(c, request.HunterName, "Hunter", dbUser.ID)
+	if err != nil {
+		return err
+	}
+
+	entries, err := s.Store.GetTournamentEntries(t.ID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting entries for tournament %d", t.ID)
+		return s.RenderError("Error getting entries", c)
+	}
+
+	// unpaid entries hold their place until they expire
+	cutoff := now.Add(-(s.paymentWindow() + paymentGracePeriod))
+	taken := 0
+	pendingAmounts := make(map[int64]bool)
+	for _, entry := range entries {
+		if !entry.Paid() && entry.CreatedDate.Before(cutoff) {
+			continue
+		}
+		taken++
+		if !entry.Paid() {
+			pendingAmounts[entry.PaymentAmountLovelace] = true
+		}
+		if entry.ZombieNftID == zombie.NftID || entry.HunterNftID == hunter.NftID {
+			log.Warnf("%s or %s is already entered in tournament %d", request.ZombieName, request.HunterName, t.ID)
+			return echo.NewHTTPError(http.StatusBadRequest, "Already entered")
+		}
+	}
+	if taken >= t.BracketSize {
+		return echo.NewHTTPError(http.StatusBadRequest, "Tournament is full")
+	}
+
+	// every entry pays to the tournament's address, the dust tells them apart
+	entry := store.TournamentEntry{
+		TournamentID: t.ID,
+		UserID:       dbUser.ID,
+		ZombieNftID:  zombie.NftID,
+		HunterNftID:  hunter.NftID,
+	}
+	dustTry := 0
+	for {
+		if dustTry == 5 {
+			log.Errorf("Error getting unique payment amount")
+			return s.RenderError("Error getting unique payment amount", c)
+		}
+
+		cost := t.EntryFeeLovelace + int64(rand.Intn(500000))
+		if !pendingAmounts[cost] {
+			entry.PaymentAmountLovelace = cost
+			break
+		}
+
+		dustTry++
+	}
+
+	entryID, err := s.Store.CreateTournamentEntry(c.Request().Context(), entry)
+	if err != nil {
+		log.WithError(err).Errorf("Error persisting tournament entry")
+		return s.RenderError("Error persisting tournament entry", c)
+	}
+
+	return c.JSON(http.StatusOK, TournamentEntryPayment{
+		ID:                    entryID,
+		TournamentID:          t.ID,
+		ZombieName:            request.ZombieName,
+		HunterName:            request.HunterName,
+		PaymentAddress:        t.PaymentAddress,
+		PaymentAmountLovelace: entry.PaymentAmountLovelace,
+		PaymentAmountAda:      lovelaceToString(int(entry.PaymentAmountLovelace)),
+		MinutesUntilExpired:   int(s.paymentWindow().Minutes()),
+	})
+}
+
+// tournamentParam the tournament in the path, errors are http errors to return as is
+func (s Server) tournamentParam(c echo.Context) (*store.Tournament, error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	tournamentID, err := strconv.Atoi(c.Param("tournamentId"))
+	if err != nil {
+		return nil, echo.NewHTTPError(http.StatusBadRequest, "Tournament id must be a number")
+	}
+
+	t, err := s.Store.GetTournament(tournamentID)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting tournament %d", tournamentID)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Error getting tournament")
+	}
+	if t == nil {
+		return nil, echo.NewHTTPError(http.StatusNotFound, "Tournament not found")
+	}
+
+	return t, nil
+}
+
+// entryNft an nft the user lists and still holds on chain, errors are http errors to return as is
+func (s Server) entryNft(c echo.Context, name string, nftType string, userID int) (*store.UserNfts, error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	nfts, err := s.Store.GetListedNftByName(name)
+	if err != nil {
+		log.WithError(err).Errorf("Error checking if user owns %s", name)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Error checking if user owns "+name)
+	}
+	if len(nfts) == 0 || nfts[0].NftType != nftType || nfts[0].UserID != userID || !nfts[0].ListAmount.Valid {
+		log.Warnf("%s %s isn't listed by user %d", nftType, name, userID)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, nftType+" not listed by user")
+	}
+
+	owns, err := s.doesUserOwnNft(c.Request().Context(), name, userID)
+	if err != nil {
+		log.WithError(err).Errorf("Error checking user owns %s", name)
+		return nil, echo.NewHTTPError(http.StatusInternalServerError, "Error checking if user owns "+name)
+	}
+	if !owns {
+		log.Warnf("%s %s not owned by user %d", nftType, name, userID)
+		return nil, echo.NewHTTPError(http.StatusBadRequest, nftType+" not owned by user anymore")
+	}
+
+	return &nfts[0], nil
+}
+
+func convertDbTournamentToDto(t store.Tournament, entries []store.TournamentEntry) Tournament {
+	split, _ := tournament.ParseSplit(t.PrizeSplit)
+	dto := Tournament{
+		ID:                t.ID,
+		Name:              t.Name,
+		Format:            t.Format,
+		Status:            t.Status,
+		BracketSize:       t.BracketSize,
+		EntryFeeLovelace:  t.EntryFeeLovelace,
+		EntryFeeAda:       lovelaceToString(int(t.EntryFeeLovelace)),
+		PrizeSplit:        split,
+		RegistrationStart: t.RegistrationStart,
+		RegistrationEnd:   t.RegistrationEnd,
+		PayoutTxHash:      t.PayoutTxHash.String,
+	}
+	if t.StartedDate.Valid {
+		dto.StartedDate = &t.StartedDate.Time
+	}
+	if t.FinishedDate.Valid {
+		dto.FinishedDate = &t.FinishedDate.Time
+	}
+
+	for _, entry := range entries {
+		if entry.Paid() {
+			dto.PaidEntries++
+			dto.CollectedLovelace += entry.PaymentLovelace.Int64
+		}
+	}
+
+	return dto
+}
+
+// processTournaments moves every active tournament along, a tournament that fails is retried on the next pass
+// without holding up the others
+func (s Server) processTournaments() error {
+	ctx := context.Background()
+
+	tournaments, err := s.Store.GetActiveTournaments()
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, t := range tournaments {
+		switch t.Status {
+		case store.TournamentStatusRegistration:
+			err = s.processTournamentRegistration(ctx, t)
+		case store.TournamentStatusRunning:
+			err = s.processRunningTournament(ctx, t)
+		case store.TournamentStatusFinished:
+			err = s.processTournamentPayout(ctx, t)
+		}
+		if err != nil {
+			logrus.WithError(err).Errorf("Error processing tournament %d", t.ID)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d tournaments failed", failed, len(tournaments))
+	}
+
+	return nil
+}
+
+// processTournamentRegistration pays entries from new utxos at the tournament's address by exact amount, then starts
+// the tournament once the last entry could have paid
+func (s Server) processTournamentRegistration(ctx context.Context, t store.Tournament) error {
+	entries, err := s.Store.GetTournamentEntries(t.ID)
+	if err != nil {
+		return err
+	}
+
+	payments, err := s.unseenPayments(ctx, t.PaymentAddress, make(map[string]string))
+	if err != nil {
+		return err
+	}
+
+	cutoff := time.Now().Add(-(s.paymentWindow() + paymentGracePeriod))
+	paid := 0
+	for _, entry := range entries {
+		if entry.Paid() {
+			paid++
+		}
+	}
+	for _, payment := range payments {
+		var match *store.TournamentEntry
+		for i, entry := range entries {
+			if !entry.Paid() && entry.CreatedDate.After(cutoff) && entry.PaymentAmountLovelace == payment.Lovelace {
+				match = &entries[i]
+				break
+			}
+		}
+
+		if match == nil || len(payment.Assets) > 0 || paid >= t.BracketSize {
+			logrus.Warnf("Utxo %s#%d doesn't pay an entry in tournament %d, recording refund...", payment.TxHash, payment.OutputIndex, t.ID)
+			err = s.Store.RecordRefund(ctx, refundForPayment(payment, t.PaymentAddress, sql.NullInt64{Int64: int64(t.PaymentAddressIndex), Valid: true}))
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		logrus.Infof("Utxo %s#%d pays entry %d in tournament %d", payment.TxHash, payment.OutputIndex, match.ID, t.ID)
+		err = s.Store.PayTournamentEntry(ctx, match.ID, payment)
+		if err != nil {
+			return err
+		}
+		match.PaymentTxHash = sql.NullString{String: payment.TxHash, Valid: true}
+		match.PaymentOutputIndex = sql.NullInt64{Int64: int64(payment.OutputIndex), Valid: true}
+		match.PaymentLovelace = sql.NullInt64{Int64: payment.Lovelace, Valid: true}
+		match.SenderAddress = sql.NullString{String: payment.SenderAddress, Valid: true}
+		paid++
+	}
+
+	// entries made just before registration closed still get their payment window
+	if time.Now().Before(t.RegistrationEnd.Add(s.paymentWindow() + paymentGracePeriod)) {
+		return nil
+	}
+
+	return s.startTournament(ctx, t, entries)
+}
+
+// startTournament seeds the paid entries by the combined rating of their zombie and hunter and lays out the bracket,
+// with fewer than two entries the tournament is called off and every fee refunded
+func (s Server) startTournament(ctx context.Context, t store.Tournament, entries []store.TournamentEntry) error {
+	paid := make([]store.TournamentEntry, 0)
+	for _, entry := range entries {
+		if entry.Paid() {
+			paid = append(paid, entry)
+		}
+	}
+
+	if len(paid) < 2 {
+		logrus.Warnf("Tournament %d only has %d paid entries, cancelling", t.ID, len(paid))
+		for _, entry := range paid {
+			payment := store.FightPayment{
+				TxHash:        entry.PaymentTxHash.String,
+				OutputIndex:   int(entry.PaymentOutputIndex.Int64),
+				SenderAddress: entry.SenderAddress.String,
+				Lovelace:      entry.PaymentLovelace.Int64,
+			}
+			err := s.Store.RecordRefund(ctx, refundForPayment(payment, t.PaymentAddress, sql.NullInt64{Int64: int64(t.PaymentAddressIndex), Valid: true}))
+			if err != nil {
+				return err
+			}
+		}
+		return s.Store.CancelTournament(ctx, t.ID)
+	}
+
+	// conservative rating, same as the rating leaderboard
+	strength := make(map[int]float64)
+	for _, entry := range paid {
+		for _, name := range []string{entry.ZombieName, entry.HunterName} {
+			nft, err := s.Store.GetNftByName(name)
+			if err != nil {
+				return err
+			}
+			if nft == nil {
+				return fmt.Errorf("No nft %s for entry %d", name, entry.ID)
+			}
+			strength[entry.ID] += nft.Rating - 2*nft.RatingDeviation
+		}
+	}
+	sort.SliceStable(paid, func(i, j int) bool {
+		return strength[paid[i].ID] > strength[paid[j].ID]
+	})
+
+	format, err := tournament.ParseFormat(t.Format)
+	if err != nil {
+		return err
+	}
+	bracket, err := tournament.Generate(format, tournament.BracketSize(format, len(paid)))
+	if err != nil {
+		return err
+	}
+
+	seeds := make([]int, 0)
+	seedsByEntry := make(map[int]int)
+	for i, entry := range paid {
+		seeds = append(seeds, entry.ID)
+		seedsByEntry[entry.ID] = i + 1
+	}
+	err = tournament.Advance(format, bracket, seeds)
+	if err != nil {
+		return err
+	}
+
+	matches := make([]store.TournamentMatch, 0)
+	for _, m := range bracket {
+		match := store.TournamentMatch{
+			MatchNumber:  m.Number,
+			Bracket:      m.Bracket,
+			Round:        m.Round,
+			TopSource:    string(m.Top),
+			BottomSource: string(m.Bottom),
+		}
+		setMatchResult(&match, m)
+		matches = append(matches, match)
+	}
+
+	logrus.Infof("Starting tournament %d with %d entries and %d matches", t.ID, len(paid), len(matches))
+	return s.Store.StartTournament(ctx, t.ID, seedsByEntry, matches)
+}
+
+// processRunningTournament decides matches from their fought fights, creates fights for the matches now ready and
+// pays for the next one from the prize pool. Fights are paid one at a time so each one spends the change of the last.
+func (s Server) processRunningTournament(ctx context.Context, t store.Tournament) error {
+	format, err := tournament.ParseFormat(t.Format)
+	if err != nil {
+		return err
+	}
+	entries, err := s.Store.GetTournamentEntries(t.ID)
+	if err != nil {
+		return err
+	}
+	matches, err := s.Store.GetTournamentMatches(t.ID)
+	if err != nil {
+		return err
+	}
+
+	bracket := make([]tournament.Match, 0)
+	for _, match := range matches {
+		m := bracketMatch(match)
+
+		// the zombie entry wins if its zombie does, the same way the fight's winner is shown
+		if m.Ready() && match.FightID.Valid && match.ZombieLifeBar.Valid && match.HunterLifeBar.Valid {
+			winner := m.TopEntry + m.BottomEntry - int(match.ZombieEntryID.Int64)
+			if match.ZombieLifeBar.Int64 > match.HunterLifeBar.Int64 {
+				winner = int(match.ZombieEntryID.Int64)
+			}
+			err = m.Decide(winner)
+			if err != nil {
+				return err
+			}
+		}
+		bracket = append(bracket, m)
+	}
+
+	err = tournament.Advance(format, bracket, tournamentSeeds(entries))
+	if err != nil {
+		return err
+	}
+	for i := range matches {
+		setMatchResult(&matches[i], bracket[i])
+	}
+	err = s.Store.UpdateTournamentMatches(ctx, matches)
+	if err != nil {
+		return err
+	}
+
+	if places := tournament.Places(format, bracket, tournamentSeeds(entries)); places != nil {
+		placesByEntry := make(map[int]int)
+		for i, entryID := range places {
+			placesByEntry[entryID] = i + 1
+		}
+		logrus.Infof("Every match in tournament %d is decided, finishing", t.ID)
+		return s.Store.FinishTournament(ctx, t.ID, placesByEntry)
+	}
+
+	entriesByID := make(map[int]store.TournamentEntry)
+	for _, entry := range entries {
+		entriesByID[entry.ID] = entry
+	}
+	for i, m := range bracket {
+		if !m.Ready() || matches[i].FightID.Valid {
+			continue
+		}
+		fightID, err := s.createTournamentFight(ctx, t, matches[i], m, entriesByID)
+		if err != nil {
+			return err
+		}
+		matches[i].FightID = sql.NullInt64{Int64: int64(fightID), Valid: true}
+		matches[i].FightStatus = sql.NullString{String: string(store.FightStatusPending), Valid: true}
+	}
+
+	return s.fundTournamentFight(ctx, t, entries, matches)
+}
+
+// createTournamentFight creates the fight for a ready match, neither owner is paid and the fight nft goes to the
+// zombie entry, like it would to a user fighting with their own zombie
+func (s Server) createTournamentFight(ctx context.Context, t store.Tournament, match store.TournamentMatch, m tournament.Match, entries map[int]store.TournamentEntry) (int, error) {
+	zombieEntry := entries[m.ZombieEntry()]
+	hunterEntry := entries[m.TopEntry+m.BottomEntry-m.ZombieEntry()]
+
+	secret, commitment, err := newFightSecret()
+	if err != nil {
+		return 0, err
+	}
+
+	fight := store.FightDto{
+		PaymentAmountLovelace: t.FightCostLovelace,
+		PaymentAddress:        t.PaymentAddress,
+		PaymentAddressIndex:   sql.NullInt64{Int64: int64(t.PaymentAddressIndex), Valid: true},
+		TournamentID:          sql.NullInt64{Int64: int64(t.ID), Valid: true},
+		ZombieSendAddress:     zombieEntry.SenderAddress.String,
+		HunterSendAddress:     hunterEntry.SenderAddress.String,
+		SeedSecret:            secret,
+		Seed:                  &store.FightSeed{Commitment: commitment},
+	}
+	zombieUser := store.UserNfts{UserID: zombieEntry.UserID, NftID: zombieEntry.ZombieNftID, ListAmount: sql.NullInt16{Int16: 0, Valid: true}}
+	hunterUser := store.UserNfts{UserID: hunterEntry.UserID, NftID: hunterEntry.HunterNftID, ListAmount: sql.NullInt16{Int16: 0, Valid: true}}
+
+	logrus.Infof("Creating fight between %s and %s for match %d in tournament %d", zombieEntry.ZombieName, hunterEntry.HunterName, m.Number, t.ID)
+	return s.Store.CreateTournamentFight(ctx, match.ID, zombieEntry.ID, fight, hunterUser, zombieUser, store.User{ID: zombieEntry.UserID})
+}
+
+// fundTournamentFight pays for the first scheduled fight with everything in the prize pool, the change goes back to
+// the pool. Nothing is paid while another fight is still being minted.
+func (s Server) fundTournamentFight(ctx context.Context, t store.Tournament, entries []store.TournamentEntry, matches []store.TournamentMatch) error {
+	var next *store.TournamentMatch
+	for i, match := range matches {
+		switch store.FightStatus(match.FightStatus.String) {
+		case store.FightStatusQueued, store.FightStatusStaged, store.FightStatusMinted:
+			return nil
+		case store.FightStatusPending:
+			if next == nil {
+				next = &matches[i]
+			}
+		}
+	}
+	if next == nil {
+		return nil
+	}
+
+	funds, err := s.tournamentPool(ctx, t, entries, matches)
+	if err != nil {
+		return err
+	}
+	pool := int64(0)
+	for _, payment := range funds {
+		pool += payment.Lovelace
+	}
+	if pool < t.FightCostLovelace {
+		return fmt.Errorf("Prize pool of tournament %d has %d lovelace, not enough for fight %d", t.ID, pool, next.FightID.Int64)
+	}
+
+	logrus.Infof("Paying fight %d for match %d in tournament %d from %d utxos", next.FightID.Int64, next.MatchNumber, t.ID, len(funds))
+	return s.moveFightFromPendingToQueued(store.FightDb{ID: int(next.FightID.Int64)}, funds)
+}
+
+// tournamentPool the utxos at the tournament's address that are part of its prize pool, entry fees and the change of
+// its fights, as payments returning change to the pool. Anything else sent there is recorded as a refund.
+func (s Server) tournamentPool(ctx context.Context, t store.Tournament, entries []store.TournamentEntry, matches []store.TournamentMatch) ([]store.FightPayment, error) {
+	known := make(map[string]bool)
+	for _, entry := range entries {
+		if entry.Paid() {
+			known[fmt.Sprintf("%s#%d", entry.PaymentTxHash.String, entry.PaymentOutputIndex.Int64)] = true
+		}
+	}
+	mintTxs := make(map[string]bool)
+	for _, match := range matches {
+		if match.FightTxID.Valid {
+			mintTxs[match.FightTxID.String] = true
+		}
+	}
+
+	utxos, err := s.Chain.AddressUTXOs(ctx, t.PaymentAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	addressIndex := sql.NullInt64{Int64: int64(t.PaymentAddressIndex), Valid: true}
+	senders := make(map[string]string)
+	funds := make([]store.FightPayment, 0)
+	for _, utxo := range utxos {
+		// spent by a fight that hasn't reached the chain yet
+		fights, err := s.Store.GetFightForUtxo(utxo.TxHash, utxo.OutputIndex)
+		if err != nil {
+			return nil, err
+		}
+		if len(fights) > 0 {
+			continue
+		}
+		refund, err := s.Store.GetRefundForUtxo(utxo.TxHash, utxo.OutputIndex)
+		if err != nil {
+			return nil, err
+		}
+		if refund != nil {
+			continue
+		}
+
+		payment, err := s.paymentFromUtxo(ctx, utxo, senders)
+		if err != nil {
+			logrus.WithError(err).Errorf("Utxo %s#%d can't be used or returned, ignoring...", utxo.TxHash, utxo.OutputIndex)
+			continue
+		}
+
+		if (!known[fmt.Sprintf("%s#%d", utxo.TxHash, utxo.OutputIndex)] && !mintTxs[utxo.TxHash]) || len(payment.Assets) > 0 {
+			logrus.Warnf("Utxo %s#%d isn't part of the prize pool of tournament %d, recording refund...", utxo.TxHash, utxo.OutputIndex, t.ID)
+			err = s.Store.RecordRefund(ctx, refundForPayment(*payment, t.PaymentAddress, addressIndex))
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		payment.SenderAddress = t.PaymentAddress
+		funds = append(funds, *payment)
+	}
+
+	return funds, nil
+}
+
+// processTournamentPayout pays what's left of the prize pool to the placed entries once every fight is confirmed,
+// checkpointing the signed tx like a mint
+func (s Server) processTournamentPayout(ctx context.Context, t store.Tournament) error {
+	if t.PayoutTxHash.Valid {
+		txHash := t.PayoutTxHash.String
+		confirmed, err := s.Chain.TransactionConfirmed(ctx, txHash)
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			logrus.Infof("Payout tx %s for tournament %d is on chain", txHash, t.ID)
+			return s.Store.MarkTournamentPaid(ctx, t.ID)
+		}
+
+		tip, err := s.Chain.Tip(ctx)
+		if err != nil {
+			return err
+		}
+		if tip.Slot <= int(t.PayoutTxTTL.Int64)+signedTxExpiryMargin {
+			logrus.Infof("Resubmitting payout tx %s for tournament %d", txHash, t.ID)
+			_, err = s.Chain.SubmitTransaction(ctx, t.PayoutTxCbor.String)
+			return err
+		}
+
+		logrus.Warnf("Payout tx %s for tournament %d expired at slot %d, building a new one", txHash, t.ID, t.PayoutTxTTL.Int64)
+		err = s.Store.ClearTournamentPayoutTx(ctx, t.ID, txHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	matches, err := s.Store.GetTournamentMatches(t.ID)
+	if err != nil {
+		return err
+	}
+	for _, match := range matches {
+		if match.FightID.Valid && store.FightStatus(match.FightStatus.String) != store.FightStatusConfirmed {
+			logrus.Infof("Tournament %d is waiting on fight %d before paying out", t.ID, match.FightID.Int64)
+			return nil
+		}
+	}
+
+	entries, err := s.Store.GetTournamentEntries(t.ID)
+	if err != nil {
+		return err
+	}
+	funds, err := s.tournamentPool(ctx, t, entries, matches)
+	if err != nil {
+		return err
+	}
+	if len(funds) == 0 {
+		return fmt.Errorf("Nothing left in the prize pool of tournament %d", t.ID)
+	}
+
+	placed := make([]store.TournamentEntry, 0)
+	for _, entry := range entries {
+		if entry.Place.Valid {
+			placed = append(placed, entry)
+		}
+	}
+	sort.Slice(placed, func(i, j int) bool {
+		return placed[i].Place.Int64 < placed[j].Place.Int64
+	})
+	if len(placed) == 0 {
+		return fmt.Errorf("Tournament %d has nobody placed", t.ID)
+	}
+
+	dirName := "work/" + uuid.New().String()
+	err = os.Mkdir(dirName, 0755)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error creating directory")
+		return err
+	}
+	defer os.RemoveAll(dirName)
+
+	signedTx, txHash, ttl, err := s.buildPayoutTransaction(dirName, t, funds, placed)
+	if err != nil {
+		return err
+	}
+
+	// checkpoint before submitting so a crash can't pay out twice
+	err = s.Store.SetTournamentPayoutTx(ctx, t.ID, txHash, signedTx.Hex, ttl)
+	if err != nil {
+		return err
+	}
+
+	submitted, err := s.Chain.SubmitTransaction(ctx, signedTx.Hex)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Submitted payout for tournament %d with tx %s", t.ID, submitted)
+
+	return nil
+}
+
+// buildPayoutTransaction builds and signs a tx splitting the prize pool between the placed entries by the prize
+// split, paid to the address each entry paid from. First place pays the fee and takes any prize too small to send.
+func (s Server) buildPayoutTransaction(dirName string, t store.Tournament, funds []store.FightPayment, placed []store.TournamentEntry) (*SignedTx, string, int, error) {
+	split, err := tournament.ParseSplit(t.PrizeSplit)
+	if err != nil {
+		return nil, "", 0, err
+	}
+
+	txsIn := make([]string, 0)
+	pool := int64(0)
+	for _, payment := range funds {
+		txsIn = append(txsIn, fmt.Sprintf("%s#%d", payment.TxHash, payment.OutputIndex))
+		pool += payment.Lovelace
+	}
+
+	prizes := tournament.Prizes(pool, split, len(placed))
+	for i := 1; i < len(prizes); i++ {
+		if prizes[i] < minChangeLovelace {
+			prizes[0] += prizes[i]
+			prizes[i] = 0
+		}
+	}
+
+	buildTxsOut := func(fee int) []string {
+		txsOut := make([]string, 0)
+		for i, prize := range prizes {
+			if i == 0 {
+				prize -= int64(fee)
+			}
+			if prize > 0 {
+				txsOut = append(txsOut, fmt.Sprintf("%s+%d", placed[i].SenderAddress.String, prize))
+			}
+		}
+		return txsOut
+	}
+
+	fee, err := s.transactionFee(dirName, txsIn, buildTxsOut(0))
+	if err != nil {
+		return nil, "", 0, err
+	}
+	if prizes[0]-int64(fee) < minChangeLovelace {
+		return nil, "", 0, fmt.Errorf("Prize pool of %d lovelace for tournament %d can't cover the fee", pool, t.ID)
+	}
+
+	logrus.Infof("Paying %v lovelace to the top %d of tournament %d", prizes, len(prizes), t.ID)
+	return s.signTransaction(dirName, "payout", txsIn, buildTxsOut(fee), fee, sql.NullInt64{Int64: int64(t.PaymentAddressIndex), Valid: true})
+}
+
+// bracketMatch the bracket's view of a stored match
+func bracketMatch(match store.TournamentMatch) tournament.Match {
+	return tournament.Match{
+		Number:      match.MatchNumber,
+		Bracket:     match.Bracket,
+		Round:       match.Round,
+		Top:         tournament.Source(match.TopSource),
+		Bottom:      tournament.Source(match.BottomSource),
+		TopEntry:    int(match.TopEntryID.Int64),
+		BottomEntry: int(match.BottomEntryID.Int64),
+		Winner:      int(match.WinnerEntryID.Int64),
+		Loser:       int(match.LoserEntryID.Int64),
+		Decided:     match.Decided,
+	}
+}
+
+// setMatchResult copies the bracket's entries and result to the stored match
+func setMatchResult(match *store.TournamentMatch, m tournament.Match) {
+	entryID := func(id int) sql.NullInt64 {
+		return sql.NullInt64{Int64: int64(id), Valid: id != 0}
+	}
+	match.TopEntryID = entryID(m.TopEntry)
+	match.BottomEntryID = entryID(m.BottomEntry)
+	match.WinnerEntryID = entryID(m.Winner)
+	match.LoserEntryID = entryID(m.Loser)
+	match.Decided = m.Decided
+}
+
+// tournamentSeeds entry ids in seed order
+func tournamentSeeds(entries []store.TournamentEntry) []int {
+	seeded := make([]store.TournamentEntry, 0)
+	for _, entry := range entries {
+		if entry.Seed.Valid {
+			seeded = append(seeded, entry)
+		}
+	}
+	sort.Slice(seeded, func(i, j int) bool {
+		return seeded[i].Seed.Int64 < seeded[j].Seed.Int64
+	})
+
+	seeds := make([]int, 0)
+	for _, entry := range seeded {
+		seeds = append(seeds, entry.ID)
+	}
+	return seeds
+}
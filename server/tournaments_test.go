@@ -0,0 +1,228 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+)
+
+// newTestTournament a single elimination tournament for two, paid to its own derived address
+func newTestTournament(t *testing.T, s Server, memoryStore *db.MemoryStore, start time.Time, end time.Time) db.Tournament {
+	index, _ := memoryStore.NextPaymentAddressIndex()
+	address, err := s.PaymentWallet.PaymentAddress(uint32(index))
+	if err != nil {
+		t.Fatalf("Error deriving address %v", err)
+	}
+
+	tournamentID, err := memoryStore.CreateTournament(context.Background(), db.Tournament{
+		Name:                "Test Cup",
+		Format:              "SINGLE_ELIMINATION",
+		BracketSize:         2,
+		EntryFeeLovelace:    20000000,
+		FightCostLovelace:   15000000,
+		PrizeSplit:          "100",
+		RegistrationStart:   start,
+		RegistrationEnd:     end,
+		PaymentAddress:      address,
+		PaymentAddressIndex: index,
+	})
+	if err != nil {
+		t.Fatalf("Error creating tournament %v", err)
+	}
+
+	tournament, _ := memoryStore.GetTournament(tournamentID)
+	return *tournament
+}
+
+// addTestNft gives a user another listed nft
+func addTestNft(t *testing.T, memoryStore *db.MemoryStore, user db.User, name string, nftType string) *db.Nft {
+	nft := memoryStore.AddNft(name, nftType)
+	if err := memoryStore.InsertZcNftOwnedByUser(user.ID, nft.ID); err != nil {
+		t.Fatalf("Error inserting owned nft %v", err)
+	}
+	listPrice := int16(5)
+	if err := memoryStore.UpdateNftListPrice(&listPrice, user.ID, nft.ID); err != nil {
+		t.Fatalf("Error listing nft %v", err)
+	}
+	return nft
+}
+
+func TestCreateTournamentEntry(t *testing.T) {
+	memoryStore, zombieOwner, hunterOwner := newTestStore(t)
+	s := Server{
+		Store:         memoryStore,
+		Chain:         newTestChain(),
+		PaymentWallet: newTestWallet(),
+	}
+	addTestNft(t, memoryStore, zombieOwner, "ZombieHunter00002", "Hunter")
+	tournament := newTestTournament(t, s, memoryStore, time.Now().Add(-time.Hour), time.Now().Add(time.Hour))
+
+	enter := func(body string, user db.User) (*TournamentEntryPayment, error) {
+		c, rec := newTestContext(http.MethodPost, "/tournaments/1/entries", body, user)
+		c.SetParamNames("tournamentId")
+		c.SetParamValues("1")
+		if err := s.CreateTournamentEntry(c); err != nil {
+			return nil, err
+		}
+		payment := TournamentEntryPayment{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &payment); err != nil {
+			t.Fatalf("Error decoding entry %v", err)
+		}
+		return &payment, nil
+	}
+
+	payment, err := enter(`{"zombieName":"ZombieChains00001","hunterName":"ZombieHunter00002"}`, zombieOwner)
+	if err != nil {
+		t.Fatalf("Error entering %v", err)
+	}
+	if payment.PaymentAddress != tournament.PaymentAddress {
+		t.Errorf("Expected the tournament's address %s but got %s", tournament.PaymentAddress, payment.PaymentAddress)
+	}
+	if payment.PaymentAmountLovelace < 20000000 || payment.PaymentAmountLovelace >= 20500000 {
+		t.Errorf("Payment amount %d outside of entry fee plus dust", payment.PaymentAmountLovelace)
+	}
+
+	// the same zombie can't enter twice, and a hunter has to be the user's own
+	for _, rejected := range []struct {
+		body string
+		user db.User
+	}{
+		{`{"zombieName":"ZombieChains00001","hunterName":"ZombieHunter00002"}`, zombieOwner},
+		{`{"zombieName":"ZombieChains00001","hunterName":"ZombieHunter00001"}`, zombieOwner},
+		{`{"zombieName":"ZombieChains00001","hunterName":"ZombieHunter00001"}`, hunterOwner},
+	} {
+		_, err = enter(rejected.body, rejected.user)
+		httpErr, ok := err.(*echo.HTTPError)
+		if !ok || httpErr.Code != http.StatusBadRequest {
+			t.Errorf("Expected bad request for %s but got %v", rejected.body, err)
+		}
+	}
+}
+
+func TestTournamentEngine(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	s.PaymentWallet = newTestWallet()
+	ctx := context.Background()
+
+	zombieOwner, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	hunterOwner, _ := memoryStore.GetUserByNftkeyID("hunter-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	otherHunter := addTestNft(t, memoryStore, *zombieOwner, "ZombieHunter00002", "Hunter")
+	otherZombie := addTestNft(t, memoryStore, *hunterOwner, "ZombieChains00002", "Zombie")
+
+	// registration closed an hour ago, the entries were made just before
+	tournament := newTestTournament(t, s, memoryStore, time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	pool := tournament.PaymentAddress
+	memoryStore.CreateTournamentEntry(ctx, db.TournamentEntry{TournamentID: tournament.ID, UserID: zombieOwner.ID, ZombieNftID: zombie.ID, HunterNftID: otherHunter.ID, PaymentAmountLovelace: 20000001})
+	memoryStore.CreateTournamentEntry(ctx, db.TournamentEntry{TournamentID: tournament.ID, UserID: hunterOwner.ID, ZombieNftID: otherZombie.ID, HunterNftID: hunter.ID, PaymentAmountLovelace: 20000002})
+
+	fakeChain.Pay("addr_zombie_owner", pool, 20000001)
+	fakeChain.Pay("addr_hunter_owner", pool, 20000002)
+	fakeChain.Pay("addr_stranger", pool, 3000000)
+
+	// entries are paid and the bracket laid out, then the only match gets its fight paid from the pool
+	for i := 0; i < 2; i++ {
+		if err := s.processTournaments(); err != nil {
+			t.Fatalf("Error processing tournaments %v", err)
+		}
+	}
+	refunds, _ := memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10)
+	if len(refunds) != 1 || refunds[0].SenderAddress != "addr_stranger" || refunds[0].PaymentAddress != pool {
+		t.Errorf("Expected the stranger's payment to be owed back but got %v", refunds)
+	}
+	matches, _ := memoryStore.GetTournamentMatches(tournament.ID)
+	if len(matches) != 1 || matches[0].FightStatus.String != string(db.FightStatusQueued) {
+		t.Fatalf("Expected the final's fight to be paid but got %v", matches)
+	}
+	payments, _ := memoryStore.GetFightPayments(int(matches[0].FightID.Int64))
+	if len(payments) != 2 || payments[0].SenderAddress != pool {
+		t.Fatalf("Expected both entry fees to pay the fight with change to the pool but got %v", payments)
+	}
+
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	if err := s.processStagedFights(); err != nil {
+		t.Fatalf("Error minting fights %v", err)
+	}
+	submitted := fakeChain.Submitted()
+	if len(submitted) != 1 {
+		t.Fatalf("Expected one submitted tx but got %d", len(submitted))
+	}
+	mintTx, _ := hex.DecodeString(submitted[0])
+	if !strings.Contains(string(mintTx), "addr_zombie_owner+") || !strings.Contains(string(mintTx), pool+"+25000003") {
+		t.Errorf("Expected the fight nft to go to the zombie entry and the change to the pool but got %s", mintTx)
+	}
+
+	// the mint lands, spending the entry fees and leaving the change in the pool
+	minted, _ := memoryStore.GetMintedFights()
+	fakeChain.Confirm(minted[0].TxID.String)
+	if err := s.processMintedFights(); err != nil {
+		t.Fatalf("Error confirming fights %v", err)
+	}
+	for _, payment := range payments {
+		fakeChain.Spend(payment.TxHash, payment.OutputIndex)
+	}
+	fakeChain.AddOutput(minted[0].TxID.String, 3, pool, pool, 25000003)
+
+	// the final decides the tournament, then the pool is paid to the winner
+	for i := 0; i < 2; i++ {
+		if err := s.processTournaments(); err != nil {
+			t.Fatalf("Error processing tournaments %v", err)
+		}
+	}
+	finished, _ := memoryStore.GetTournament(tournament.ID)
+	if finished.Status != db.TournamentStatusFinished || !finished.PayoutTxHash.Valid {
+		t.Fatalf("Expected a finished tournament with a payout tx but got %v", finished)
+	}
+	entries, _ := memoryStore.GetTournamentEntries(tournament.ID)
+	winner := entries[0]
+	if entries[1].Place.Int64 == 1 {
+		winner = entries[1]
+	}
+	submitted = fakeChain.Submitted()
+	payoutTx, _ := hex.DecodeString(submitted[len(submitted)-1])
+	if !strings.Contains(string(payoutTx), winner.SenderAddress.String+"+24800003") {
+		t.Errorf("Expected the pool less the fee paid to %s but got %s", winner.SenderAddress.String, payoutTx)
+	}
+
+	fakeChain.Confirm(finished.PayoutTxHash.String)
+	if err := s.processTournaments(); err != nil {
+		t.Fatalf("Error processing tournaments %v", err)
+	}
+	if paid, _ := memoryStore.GetTournament(tournament.ID); paid.Status != db.TournamentStatusPaid {
+		t.Errorf("Expected the tournament to be paid but got %s", paid.Status)
+	}
+}
+
+func TestTournamentCancelledRefundsEntries(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	s.PaymentWallet = newTestWallet()
+
+	zombieOwner, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	otherHunter := addTestNft(t, memoryStore, *zombieOwner, "ZombieHunter00002", "Hunter")
+
+	tournament := newTestTournament(t, s, memoryStore, time.Now().Add(-2*time.Hour), time.Now().Add(-time.Hour))
+	memoryStore.CreateTournamentEntry(context.Background(), db.TournamentEntry{TournamentID: tournament.ID, UserID: zombieOwner.ID, ZombieNftID: zombie.ID, HunterNftID: otherHunter.ID, PaymentAmountLovelace: 20000001})
+	fakeChain.Pay("addr_zombie_owner", tournament.PaymentAddress, 20000001)
+
+	if err := s.processTournaments(); err != nil {
+		t.Fatalf("Error processing tournaments %v", err)
+	}
+	if cancelled, _ := memoryStore.GetTournament(tournament.ID); cancelled.Status != db.TournamentStatusCancelled {
+		t.Errorf("Expected a tournament with one entry to be cancelled but got %s", cancelled.Status)
+	}
+	refunds, _ := memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10)
+	if len(refunds) != 1 || refunds[0].SenderAddress != "addr_zombie_owner" || refunds[0].Lovelace != 20000001 {
+		t.Errorf("Expected the entry fee to be owed back but got %v", refunds)
+	}
+}
@@ -0,0 +1,112 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+)
+
+// nftMoved takes the nft off the listings of users who don't hold it anymore and voids the fights it was in for the
+// account it left, holder is nil when nothing holds it
+func (s Server) nftMoved(ctx context.Context, nft db.Nft, holder *db.NftHolder) error {
+	account := sql.NullString{}
+	if holder != nil {
+		account = sql.NullString{String: holder.Account, Valid: true}
+	}
+
+	removed, err := s.Store.RemoveNftListingsNotHeldBy(ctx, nft.ID, account)
+	if err != nil {
+		return err
+	}
+	if removed > 0 {
+		logrus.Infof("Delisted nft %s from %d users who don't hold it anymore", nft.NftName, removed)
+	}
+
+	fights, err := s.Store.GetOpenFightsForNft(nft.ID)
+	if err != nil {
+		return err
+	}
+	for _, fight := range fights {
+		// a fight the new owner started pays out to them already
+		sendAddress := fight.HunterSendAddress
+		if fight.ZombieName == nft.NftName {
+			sendAddress = fight.ZombieSendAddress
+		}
+		if account.Valid && holderAccount(sendAddress.String) == account.String {
+			continue
+		}
+
+		err = s.voidFight(ctx, fight, []string{nft.NftName})
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// resolveSendAddresses points each side's payout at where the nft is held now, as long as it's still in the account it
+// was in when the fight was created. Returns the fighters that are held in another account, or by nothing, whose send
+// address is left as it was
+func (s Server) resolveSendAddresses(ctx context.Context, fight *db.FightDb) ([]string, error) {
+	moved := make([]string, 0)
+
+	// tournament fights pay out to the entries, and without the policies there's nothing to look up
+	if fight.TournamentID.Valid || s.ZombiePolicyId == "" || s.HunterPolicyId == "" {
+		return moved, nil
+	}
+
+	zombieSendAddress, hunterSendAddress := fight.ZombieSendAddress.String, fight.HunterSendAddress.String
+	sides := []struct {
+		name    string
+		address *string
+	}{
+		{fight.ZombieName, &zombieSendAddress},
+		{fight.HunterName, &hunterSendAddress},
+	}
+	for _, side := range sides {
+		if *side.address == "" {
+			continue
+		}
+		nft, err := s.Store.GetNftByName(side.name)
+		if err != nil {
+			return nil, err
+		}
+		if nft == nil {
+			return nil, fmt.Errorf("No nft %s for fight %d", side.name, fight.ID)
+		}
+
+		holder, err := s.nftHolder(ctx, *nft)
+		if err != nil {
+			return nil, err
+		}
+		if holder == nil || holder.Account != holderAccount(*side.address) {
+			moved = append(moved, side.name)
+			continue
+		}
+		*side.address = holder.Address
+	}
+
+	if zombieSendAddress != fight.ZombieSendAddress.String || hunterSendAddress != fight.HunterSendAddress.String {
+		logrus.Infof("Fight %d pays out to %s and %s now", fight.ID, zombieSendAddress, hunterSendAddress)
+		err := s.Store.SetFightSendAddresses(ctx, fight.ID, zombieSendAddress, hunterSendAddress)
+		if err != nil {
+			return nil, err
+		}
+		fight.ZombieSendAddress = sql.NullString{String: zombieSendAddress, Valid: zombieSendAddress != ""}
+		fight.HunterSendAddress = sql.NullString{String: hunterSendAddress, Valid: hunterSendAddress != ""}
+	}
+
+	return moved, nil
+}
+
+// voidFight calls off a fight whose fighters changed hands, whatever paid for it is owed back
+func (s Server) voidFight(ctx context.Context, fight db.FightDb, moved []string) error {
+	logrus.Warnf("Voiding %s fight %d, %s changed hands", strings.ToLower(string(fight.Status)), fight.ID, strings.Join(moved, " and "))
+
+	return s.Store.VoidFight(ctx, fight.ID, db.FightActorEngine, fmt.Sprintf("%s changed hands", strings.Join(moved, " and ")))
+}
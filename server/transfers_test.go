@@ -0,0 +1,151 @@
+package server
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/reliablestaking/zombie-fight-club-server/chain"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/hdwallet"
+)
+
+// the CIP-19 base address
+const testBaseAddress = "addr1qx2fxv2umyhttkxyxp8x0dlpdt3k6cwng5pxj3jhsydzer3n0d3vllmyqwsx5wktcd8cc3sq835lu7drv2xwl2wywfgse35a3x"
+
+// otherTestBaseAddress another base address with the same stake part as the CIP-19 one
+func otherTestBaseAddress(t *testing.T) string {
+	hrp, data, err := hdwallet.Bech32Decode(testBaseAddress)
+	if err != nil {
+		t.Fatalf("Error decoding address %v", err)
+	}
+	data[1] ^= 0xff
+	address, err := hdwallet.Bech32Encode(hrp, data)
+	if err != nil {
+		t.Fatalf("Error encoding address %v", err)
+	}
+	return address
+}
+
+// newTestPaidFight creates a fight between the listed nfts paying out to the addresses and pays for it
+func newTestPaidFight(t *testing.T, memoryStore *db.MemoryStore, fakeChain *chain.FakeChain, zombieSendAddress string, hunterSendAddress string) int {
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	fightID, err := memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment", ZombieSendAddress: zombieSendAddress, HunterSendAddress: hunterSendAddress},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+	if err != nil {
+		t.Fatalf("Error creating fight %v", err)
+	}
+	fakeChain.Pay("addr_buyer", "addr_payment", 12000123)
+
+	return fightID
+}
+
+func lastFightStatus(memoryStore *db.MemoryStore, fightID int) db.FightStatus {
+	events, _ := memoryStore.GetFightEvents(fightID)
+	if len(events) == 0 {
+		return ""
+	}
+	return events[len(events)-1].ToStatus
+}
+
+func TestNftMovedDelistsAndVoids(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	s.NftIndex = NftIndex{BatchSize: 2}
+
+	zombieOwner, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	hunterOwner, _ := memoryStore.GetUserByNftkeyID("hunter-owner")
+	memoryStore.AddUserAccount(zombieOwner.ID, "addr_ZombieChains00001")
+	memoryStore.AddUserAccount(hunterOwner.ID, "addr_ZombieHunter00001")
+	if err := s.processNftHolders(); err != nil {
+		t.Fatalf("Error indexing holders %v", err)
+	}
+
+	user, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+	fightID, _ := memoryStore.CreateFight(db.FightDto{PaymentAmountLovelace: 12000123, PaymentAddress: "addr_payment", ZombieSendAddress: "addr_ZombieChains00001", HunterSendAddress: "addr_ZombieHunter00001"},
+		db.UserNfts{UserID: user.ID, NftID: hunter.ID}, db.UserNfts{UserID: user.ID, NftID: zombie.ID}, *user)
+
+	// the zombie is sold while its fight waits on payment
+	fakeChain.SetAssetHolder(testZombiePolicyID+hex.EncodeToString([]byte("ZombieChains00001")), "addr_new_owner")
+	if err := s.processNftHolders(); err != nil {
+		t.Fatalf("Error indexing holders %v", err)
+	}
+	if status := lastFightStatus(memoryStore, fightID); status != db.FightStatusVoid {
+		t.Fatalf("Expected the fight voided but it's %s", status)
+	}
+	if owned, _ := memoryStore.GetNftsOwnedByUser(zombieOwner.ID); len(owned) != 0 {
+		t.Errorf("Expected the zombie delisted from its old owner but got %v", owned)
+	}
+	if owned, _ := memoryStore.GetNftsOwnedByUser(hunterOwner.ID); len(owned) != 1 {
+		t.Errorf("Expected the hunter still listed but got %v", owned)
+	}
+
+	// paying for it now only gets the payment back
+	fakeChain.Pay("addr_buyer", "addr_payment", 12000123)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	refunds, _ := memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10)
+	if len(refunds) != 1 || refunds[0].SenderAddress != "addr_buyer" {
+		t.Errorf("Expected the payment owed back but got %v", refunds)
+	}
+}
+
+func TestQueuedFightFollowsItsFighters(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+
+	zombieAsset := testZombiePolicyID + hex.EncodeToString([]byte("ZombieChains00001"))
+	fakeChain.SetAssetHolder(zombieAsset, testBaseAddress)
+	fightID := newTestPaidFight(t, memoryStore, fakeChain, testBaseAddress, "addr_ZombieHunter00001")
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+
+	// moved to another address of the same wallet, the payout follows it
+	otherAddress := otherTestBaseAddress(t)
+	fakeChain.SetAssetHolder(zombieAsset, otherAddress)
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	staged, _ := memoryStore.GetStagedFights()
+	if len(staged) != 1 || staged[0].ID != fightID || staged[0].ZombieSendAddress.String != otherAddress {
+		t.Fatalf("Expected the fight staged paying out to %s but got %v", otherAddress, staged)
+	}
+
+	// sold after the fight, the owner it fought for is paid
+	fakeChain.SetAssetHolder(zombieAsset, "addr_new_owner")
+	if err := s.processStagedFights(); err != nil {
+		t.Fatalf("Error minting fights %v", err)
+	}
+	minted, _ := memoryStore.GetMintedFights()
+	if len(minted) != 1 || minted[0].ZombieSendAddress.String != otherAddress {
+		t.Errorf("Expected the fight minted paying out to %s but got %v", otherAddress, minted)
+	}
+}
+
+func TestQueuedFightVoidedWhenFighterSold(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+
+	fightID := newTestPaidFight(t, memoryStore, fakeChain, "addr_ZombieChains00001", "addr_ZombieHunter00001")
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+
+	fakeChain.SetAssetHolder(testHunterPolicyID+hex.EncodeToString([]byte("ZombieHunter00001")), "addr_new_owner")
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	if status := lastFightStatus(memoryStore, fightID); status != db.FightStatusVoid {
+		t.Fatalf("Expected the fight voided but it's %s", status)
+	}
+
+	refunds, _ := memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10)
+	if len(refunds) != 1 || refunds[0].SenderAddress != "addr_buyer" || refunds[0].Lovelace != 12000123 {
+		t.Errorf("Expected the payment owed back but got %v", refunds)
+	}
+	if alien, _ := memoryStore.GetNextAvailableAlien(); alien == nil {
+		t.Errorf("Expected the alien back in the pool")
+	}
+}
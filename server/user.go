@@ -1,6 +1,7 @@
 package server
 
 import (
+	"fmt"
 	"net/http"
 
 	"github.com/labstack/echo/v4"
@@ -11,7 +12,7 @@ import (
 type (
 	//Zombie store my zombie info
 	Nft struct {
-		ID             int
+		ID             int    `json:"ID"`
 		Name           string `json:"name"`
 		Type           string `json:"type"`
 		IPFS           string `json:"image"`
@@ -19,6 +20,9 @@ type (
 		UserOwns       bool   `json:"userOwns"`
 		Wins           int    `json:"wins"`
 		Loses          int    `json:"loses"`
+
+		Rating          *float64 `json:"rating,omitempty"`
+		RatingDeviation *float64 `json:"ratingDeviation,omitempty"`
 	}
 )
 
@@ -54,9 +58,14 @@ func (s Server) ListNftForFight(c echo.Context) (err error) {
 		return echo.NewHTTPError(http.StatusBadRequest, "Name in path doesn't match name in body")
 	}
 
+	// the owner is paid this out of every fight it's in, whole ada so it's always above the min utxo
+	if nft.ListedPriceAda == nil || *nft.ListedPriceAda < 0 || *nft.ListedPriceAda > maxListPriceAda {
+		return echo.NewHTTPError(http.StatusBadRequest, fmt.Sprintf("List price must be between 0 and %d ada", maxListPriceAda))
+	}
+
 	dbUser := c.Get("user").(*db.User)
 
-	log.Infof("Listing nft %s for user %d", nft.Name, dbUser.ID)
+	log.Infof("Listing nft %s for user %d at %d ada", nft.Name, dbUser.ID, *nft.ListedPriceAda)
 
 	//verify actually owns this
 	nfts, err := s.GetAssetsForUser(c.Request().Context(), *dbUser)
@@ -73,10 +82,7 @@ func (s Server) ListNftForFight(c echo.Context) (err error) {
 	}
 
 	//update in db
-	//err = s.Store.UpdateNftListPrice(nft.ListedPriceAda, dbUser.ID, existingNft.ID)
-
-	fakeListPrice := int16(5)
-	err = s.Store.UpdateNftListPrice(&fakeListPrice, dbUser.ID, existingNft.ID)
+	err = s.Store.UpdateNftListPrice(nft.ListedPriceAda, dbUser.ID, existingNft.ID)
 	if err != nil {
 		log.WithError(err).Error("Error persisting listing")
 		return s.RenderError("Error persisting listing", c)
@@ -0,0 +1,353 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/google/uuid"
+	store "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/sirupsen/logrus"
+)
+
+// wagerRefundWindow how long after a wager is created its deposit addresses are still watched for stray payments
+const wagerRefundWindow = 7 * 24 * time.Hour
+
+type (
+	// Wagers the house's cut of staked challenges and where it goes, the payment address by default
+	Wagers struct {
+		HouseFeePercent int
+		HouseAddress    string
+	}
+
+	// Wager what one side sees of the wager on their challenge's fight
+	Wager struct {
+		Status            store.WagerStatus `json:"status"`
+		StakeLovelace     int64             `json:"stakeLovelace"`
+		StakeAda          string            `json:"stakeAda"`
+		HouseFeePercent   int               `json:"houseFeePercent"`
+		DepositAddress    string            `json:"depositAddress"`
+		Deposited         bool              `json:"deposited"`
+		OpponentDeposited bool              `json:"opponentDeposited"`
+		ExpiresDate       time.Time         `json:"expiresDate"`
+		WinnerSide        string            `json:"winnerSide,omitempty"`
+		PayoutLovelace    int64             `json:"payoutLovelace,omitempty"`
+		PayoutTxHash      string            `json:"payoutTxHash,omitempty"`
+	}
+)
+
+func (s Server) wagerHouseAddress() string {
+	if s.Wagers.HouseAddress == "" {
+		return s.PaymentAddress
+	}
+	return s.Wagers.HouseAddress
+}
+
+// newWager the wager on a staked challenge's fight, each side deposits the stake to its own derived address within
+// the payment window
+func (s Server) newWager(ctx context.Context, challenge store.Challenge, zombie store.UserNfts, hunter store.UserNfts) (*store.Wager, error) {
+	if s.PaymentWallet == nil {
+		return nil, fmt.Errorf("No hd wallet configured for wager deposits")
+	}
+
+	wager := store.Wager{
+		StakeLovelace:   challenge.StakeLovelace,
+		HouseFeePercent: s.Wagers.HouseFeePercent,
+		ExpiresDate:     time.Now().Add(s.paymentWindow() + paymentGracePeriod),
+	}
+	sides := []struct {
+		side   store.WagerSide
+		userID int
+	}{{store.WagerSideZombie, zombie.UserID}, {store.WagerSideHunter, hunter.UserID}}
+	for _, side := range sides {
+		index, err := s.Store.NextPaymentAddressIndex()
+		if err != nil {
+			return nil, err
+		}
+		address, err := s.PaymentWallet.PaymentAddress(uint32(index))
+		if err != nil {
+			return nil, err
+		}
+		wager.Deposits = append(wager.Deposits, store.WagerDeposit{
+			Side:                side.side,
+			UserID:              side.userID,
+			PaymentAddress:      address,
+			PaymentAddressIndex: index,
+		})
+	}
+
+	return &wager, nil
+}
+
+// convertWagerToDto the wager as one side sees it
+func convertWagerToDto(wager store.Wager, deposits []store.WagerDeposit, userID int) Wager {
+	wagerDto := Wager{
+		Status:          wager.Status,
+		StakeLovelace:   wager.StakeLovelace,
+		StakeAda:        lovelaceToString(int(wager.StakeLovelace)),
+		HouseFeePercent: wager.HouseFeePercent,
+		ExpiresDate:     wager.ExpiresDate,
+		WinnerSide:      wager.WinnerSide.String,
+		PayoutLovelace:  wager.PayoutLovelace.Int64,
+		PayoutTxHash:    wager.PayoutTxHash.String,
+	}
+	for _, deposit := range deposits {
+		if deposit.UserID == userID {
+			wagerDto.DepositAddress = deposit.PaymentAddress
+			wagerDto.Deposited = deposit.Paid()
+		} else {
+			wagerDto.OpponentDeposited = deposit.Paid()
+		}
+	}
+
+	return wagerDto
+}
+
+// awaitingWager whether a fight's result has to wait for both stakes to be deposited
+func (s Server) awaitingWager(fightID int) (bool, error) {
+	wager, err := s.Store.GetWagerForFight(fightID)
+	if err != nil {
+		return false, err
+	}
+
+	return wager != nil && wager.Status == store.WagerStatusAwaitingDeposits, nil
+}
+
+// processWagers takes deposits, calls off wagers missing a deposit or an unpaid fight and pays out the ones whose fight
+// is fought. A wager that fails is retried on the next pass without holding up the others
+func (s Server) processWagers() error {
+	ctx := context.Background()
+
+	wagers, err := s.Store.GetWagersToProcess(time.Now().Add(-wagerRefundWindow))
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, wager := range wagers {
+		err = s.processWager(ctx, wager)
+		if err != nil {
+			logrus.WithError(err).Errorf("Error processing wager %d", wager.ID)
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d wagers failed", failed, len(wagers))
+	}
+
+	return nil
+}
+
+func (s Server) processWager(ctx context.Context, wager store.Wager) error {
+	deposits, err := s.Store.GetWagerDeposits(wager.ID)
+	if err != nil {
+		return err
+	}
+
+	// a new utxo at a deposit address pays that side's stake if it's still awaited, anything else is owed back
+	now := time.Now()
+	senders := make(map[string]string)
+	for i, deposit := range deposits {
+		payments, err := s.unseenPayments(ctx, deposit.PaymentAddress, senders)
+		if err != nil {
+			return err
+		}
+
+		addressIndex := sql.NullInt64{Int64: int64(deposit.PaymentAddressIndex), Valid: true}
+		for _, payment := range payments {
+			if wager.Status == store.WagerStatusAwaitingDeposits && !deposits[i].Paid() && now.Before(wager.ExpiresDate) &&
+				len(payment.Assets) == 0 && s.PaymentTolerance.accepts(wager.StakeLovelace, payment.Lovelace) {
+				logrus.Infof("Utxo %s#%d pays the %s side of wager %d", payment.TxHash, payment.OutputIndex, deposit.Side, wager.ID)
+				err = s.Store.PayWagerDeposit(ctx, deposit.ID, payment)
+				if err != nil {
+					return err
+				}
+				deposits[i].TxHash = sql.NullString{String: payment.TxHash, Valid: true}
+				deposits[i].OutputIndex = sql.NullInt64{Int64: int64(payment.OutputIndex), Valid: true}
+				deposits[i].SenderAddress = sql.NullString{String: payment.SenderAddress, Valid: true}
+				deposits[i].Lovelace = sql.NullInt64{Int64: payment.Lovelace, Valid: true}
+				continue
+			}
+
+			logrus.Warnf("Utxo %s#%d doesn't pay a deposit of wager %d, recording refund...", payment.TxHash, payment.OutputIndex, wager.ID)
+			err = s.Store.RecordWagerRefund(ctx, wager.ID, refundForPayment(payment, deposit.PaymentAddress, addressIndex))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// a fight called off because a fighter changed hands takes its wager with it
+	if store.FightStatus(wager.FightStatus.String) == store.FightStatusVoid {
+		return s.voidWager(ctx, wager, deposits, "Fight was called off")
+	}
+
+	switch wager.Status {
+	case store.WagerStatusAwaitingDeposits:
+		for _, deposit := range deposits {
+			if !deposit.Paid() && !now.Before(wager.ExpiresDate) {
+				return s.voidWager(ctx, wager, deposits, fmt.Sprintf("%s side never deposited", deposit.Side))
+			}
+		}
+	case store.WagerStatusFunded:
+		return s.processWagerPayout(ctx, wager, deposits)
+	}
+
+	return nil
+}
+
+// voidWager calls off the wager and refunds whatever was deposited, the fight goes ahead without stakes
+func (s Server) voidWager(ctx context.Context, wager store.Wager, deposits []store.WagerDeposit, reason string) error {
+	logrus.Warnf("Voiding wager %d on fight %d, %s", wager.ID, wager.FightID, reason)
+
+	for _, deposit := range deposits {
+		if !deposit.Paid() {
+			continue
+		}
+		payment := store.FightPayment{
+			TxHash:        deposit.TxHash.String,
+			OutputIndex:   int(deposit.OutputIndex.Int64),
+			SenderAddress: deposit.SenderAddress.String,
+			Lovelace:      deposit.Lovelace.Int64,
+		}
+		err := s.Store.RecordWagerRefund(ctx, wager.ID, refundForPayment(payment, deposit.PaymentAddress, sql.NullInt64{Int64: int64(deposit.PaymentAddressIndex), Valid: true}))
+		if err != nil {
+			return err
+		}
+	}
+
+	return s.Store.VoidWager(ctx, wager.ID, wager.Status, reason)
+}
+
+// processWagerPayout pays both stakes less the house fee to the winning side once the fight is fought, checkpointing
+// the signed tx like a mint. A fight that's never paid for calls the wager off
+func (s Server) processWagerPayout(ctx context.Context, wager store.Wager, deposits []store.WagerDeposit) error {
+	if wager.PayoutTxHash.Valid {
+		txHash := wager.PayoutTxHash.String
+		confirmed, err := s.Chain.TransactionConfirmed(ctx, txHash)
+		if err != nil {
+			return err
+		}
+		if confirmed {
+			logrus.Infof("Payout tx %s for wager %d is on chain", txHash, wager.ID)
+			return s.Store.MarkWagerPaid(ctx, wager.ID)
+		}
+
+		tip, err := s.Chain.Tip(ctx)
+		if err != nil {
+			return err
+		}
+		if tip.Slot <= int(wager.PayoutTxTTL.Int64)+signedTxExpiryMargin {
+			logrus.Infof("Resubmitting payout tx %s for wager %d", txHash, wager.ID)
+			_, err = s.Chain.SubmitTransaction(ctx, wager.PayoutTxCbor.String)
+			return err
+		}
+
+		logrus.Warnf("Payout tx %s for wager %d expired at slot %d, building a new one", txHash, wager.ID, wager.PayoutTxTTL.Int64)
+		err = s.Store.ClearWagerPayoutTx(ctx, wager.ID, txHash)
+		if err != nil {
+			return err
+		}
+	}
+
+	switch store.FightStatus(wager.FightStatus.String) {
+	case store.FightStatusPending:
+		if time.Now().Before(wager.ExpiresDate) {
+			return nil
+		}
+		return s.voidWager(ctx, wager, deposits, "Fight was never paid for")
+	case store.FightStatusQueued:
+		return nil
+	}
+	if !wager.ZombieLifeBar.Valid || !wager.HunterLifeBar.Valid {
+		return fmt.Errorf("Fight %d of wager %d has no result", wager.FightID, wager.ID)
+	}
+
+	// the zombie wins with the bigger life bar, the same way the fight's winner is shown
+	winner := store.WagerSideHunter
+	if wager.ZombieLifeBar.Int64 > wager.HunterLifeBar.Int64 {
+		winner = store.WagerSideZombie
+	}
+
+	dirName := "work/" + uuid.New().String()
+	err := os.Mkdir(dirName, 0755)
+	if err != nil {
+		logrus.WithError(err).Errorf("Error creating directory")
+		return err
+	}
+	defer os.RemoveAll(dirName)
+
+	signedTx, txHash, ttl, payout, err := s.buildWagerPayoutTransaction(dirName, wager, deposits, winner)
+	if err != nil {
+		return err
+	}
+
+	// checkpoint before submitting so a crash can't pay out twice
+	err = s.Store.SetWagerPayoutTx(ctx, wager.ID, *payout, txHash, signedTx.Hex, ttl)
+	if err != nil {
+		return err
+	}
+
+	submitted, err := s.Chain.SubmitTransaction(ctx, signedTx.Hex)
+	if err != nil {
+		return err
+	}
+	logrus.Infof("Submitted payout for wager %d with tx %s", wager.ID, submitted)
+
+	return nil
+}
+
+// buildWagerPayoutTransaction builds a tx spending both deposits, signed with both deposit keys. The winning side's
+// sender gets the pot less the house fee and the tx fee, a house fee too small to send goes to the winner too.
+func (s Server) buildWagerPayoutTransaction(dirName string, wager store.Wager, deposits []store.WagerDeposit, winner store.WagerSide) (*SignedTx, string, int, *store.WagerPayout, error) {
+	txsIn := make([]string, 0)
+	addressIndexes := make([]sql.NullInt64, 0)
+	pot := int64(0)
+	payout := store.WagerPayout{WinnerSide: winner}
+	for _, deposit := range deposits {
+		if !deposit.Paid() {
+			return nil, "", 0, nil, fmt.Errorf("Wager %d is missing the %s deposit", wager.ID, deposit.Side)
+		}
+		txsIn = append(txsIn, fmt.Sprintf("%s#%d", deposit.TxHash.String, deposit.OutputIndex.Int64))
+		addressIndexes = append(addressIndexes, sql.NullInt64{Int64: int64(deposit.PaymentAddressIndex), Valid: true})
+		pot += deposit.Lovelace.Int64
+		if deposit.Side == winner {
+			payout.Address = deposit.SenderAddress.String
+		}
+	}
+	if payout.Address == "" {
+		return nil, "", 0, nil, fmt.Errorf("Wager %d has no %s deposit", wager.ID, winner)
+	}
+
+	payout.HouseFeeLovelace = pot * int64(wager.HouseFeePercent) / 100
+	if payout.HouseFeeLovelace < minChangeLovelace {
+		payout.HouseFeeLovelace = 0
+	}
+
+	buildTxsOut := func(fee int) []string {
+		txsOut := []string{fmt.Sprintf("%s+%d", payout.Address, pot-payout.HouseFeeLovelace-int64(fee))}
+		if payout.HouseFeeLovelace > 0 {
+			txsOut = append(txsOut, fmt.Sprintf("%s+%d", s.wagerHouseAddress(), payout.HouseFeeLovelace))
+		}
+		return txsOut
+	}
+
+	fee, err := s.witnessedTransactionFee(dirName, txsIn, buildTxsOut(0), len(addressIndexes))
+	if err != nil {
+		return nil, "", 0, nil, err
+	}
+	payout.Lovelace = pot - payout.HouseFeeLovelace - int64(fee)
+	if payout.Lovelace < minChangeLovelace {
+		return nil, "", 0, nil, fmt.Errorf("Pot of %d lovelace for wager %d can't cover the fees", pot, wager.ID)
+	}
+
+	logrus.Infof("Paying %d lovelace to the %s side of wager %d with a house fee of %d", payout.Lovelace, winner, wager.ID, payout.HouseFeeLovelace)
+	signedTx, txHash, ttl, err := s.multiSignTransaction(dirName, "wager", txsIn, buildTxsOut(fee), fee, addressIndexes)
+	if err != nil {
+		return nil, "", 0, nil, err
+	}
+
+	return signedTx, txHash, ttl, &payout, nil
+}
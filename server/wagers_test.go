@@ -0,0 +1,161 @@
+package server
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+	"testing"
+	"time"
+
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+)
+
+// newTestWager accepts a challenge of the zombie owner's zombie against the hunter owner's hunter staked at 50 ada,
+// changing the wager before it's persisted
+func newTestWager(t *testing.T, s Server, memoryStore *db.MemoryStore, change func(*db.Wager)) (int, db.Wager) {
+	ctx := context.Background()
+	zombieOwner, _ := memoryStore.GetUserByNftkeyID("zombie-owner")
+	hunterOwner, _ := memoryStore.GetUserByNftkeyID("hunter-owner")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	hunter, _ := memoryStore.GetNftByName("ZombieHunter00001")
+
+	challenge := db.Challenge{
+		ChallengerUserID: zombieOwner.ID,
+		ChallengerNftID:  zombie.ID,
+		OpponentUserID:   hunterOwner.ID,
+		OpponentNftID:    hunter.ID,
+		StakeLovelace:    50000000,
+		ExpiresDate:      time.Now().Add(time.Hour),
+	}
+	challengeID, err := memoryStore.CreateChallenge(ctx, challenge)
+	if err != nil {
+		t.Fatalf("Error creating challenge %v", err)
+	}
+
+	zombieUser := db.UserNfts{UserID: zombieOwner.ID, NftID: zombie.ID}
+	hunterUser := db.UserNfts{UserID: hunterOwner.ID, NftID: hunter.ID}
+	wager, err := s.newWager(ctx, challenge, zombieUser, hunterUser)
+	if err != nil {
+		t.Fatalf("Error creating wager %v", err)
+	}
+	change(wager)
+
+	fightID, err := memoryStore.AcceptChallenge(ctx, challengeID, db.ChallengeStatusPending,
+		db.FightDto{PaymentAmountLovelace: 12000000, PaymentAddress: "addr_payment", ZombieSendAddress: "addr_ZombieChains00001", HunterSendAddress: "addr_ZombieHunter00001"},
+		hunterUser, zombieUser, *zombieOwner, wager)
+	if err != nil {
+		t.Fatalf("Error accepting challenge %v", err)
+	}
+
+	persisted, _ := memoryStore.GetWagerForFight(fightID)
+	return fightID, *persisted
+}
+
+func TestWagerFundedAndPaidOut(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	s.PaymentWallet = newTestWallet()
+	s.Wagers = Wagers{HouseFeePercent: 5, HouseAddress: "addr_house"}
+
+	fightID, wager := newTestWager(t, s, memoryStore, func(*db.Wager) {})
+	deposits, _ := memoryStore.GetWagerDeposits(wager.ID)
+	if len(deposits) != 2 || deposits[0].Side != db.WagerSideZombie || deposits[0].PaymentAddress == deposits[1].PaymentAddress {
+		t.Fatalf("Expected a deposit address for each side but got %v", deposits)
+	}
+
+	// the fight is paid for but isn't fought until both stakes are in
+	fakeChain.Pay("addr_buyer", "addr_payment", 12000000)
+	if err := s.processIncomingPayments(); err != nil {
+		t.Fatalf("Error processing payments %v", err)
+	}
+	fakeChain.Pay("addr_zombie_wallet", deposits[0].PaymentAddress, 50000000)
+	if err := s.processWagers(); err != nil {
+		t.Fatalf("Error processing wagers %v", err)
+	}
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	if queued, _ := memoryStore.GetQueuedFight(); len(queued) != 1 {
+		t.Fatalf("Expected the fight to wait on the hunter's deposit")
+	}
+
+	// a stray payment is owed back, the stake funds the wager
+	fakeChain.Pay("addr_stranger", deposits[1].PaymentAddress, 3000000)
+	fakeChain.Pay("addr_hunter_wallet", deposits[1].PaymentAddress, 50000000)
+	if err := s.processWagers(); err != nil {
+		t.Fatalf("Error processing wagers %v", err)
+	}
+	refunds, _ := memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10)
+	if len(refunds) != 1 || refunds[0].SenderAddress != "addr_stranger" {
+		t.Errorf("Expected the stray payment to be owed back but got %v", refunds)
+	}
+	funded, _ := memoryStore.GetWagerForFight(fightID)
+	if funded.Status != db.WagerStatusFunded {
+		t.Fatalf("Expected a funded wager but got %s", funded.Status)
+	}
+
+	if err := s.processQueuedFights(); err != nil {
+		t.Fatalf("Error processing queued fights %v", err)
+	}
+	if err := s.processWagers(); err != nil {
+		t.Fatalf("Error processing wagers %v", err)
+	}
+
+	// the winner's wallet gets both stakes less 5% and the tx fee
+	paying, _ := memoryStore.GetWagerForFight(fightID)
+	winnerAddress := "addr_hunter_wallet"
+	if paying.ZombieLifeBar.Int64 > paying.HunterLifeBar.Int64 {
+		winnerAddress = "addr_zombie_wallet"
+	}
+	if !paying.PayoutTxHash.Valid || paying.PayoutAddress.String != winnerAddress || paying.HouseFeeLovelace.Int64 != 5000000 || paying.PayoutLovelace.Int64 != 94800000 {
+		t.Fatalf("Expected 94.8 ada paid to %s but got %v", winnerAddress, paying)
+	}
+	submitted := fakeChain.Submitted()
+	body, _ := hex.DecodeString(submitted[len(submitted)-1])
+	if !strings.Contains(string(body), winnerAddress+"+94800000") || !strings.Contains(string(body), "addr_house+5000000") {
+		t.Errorf("Expected the payout tx to pay the winner and the house but got %s", body)
+	}
+
+	fakeChain.Confirm(paying.PayoutTxHash.String)
+	if err := s.processWagers(); err != nil {
+		t.Fatalf("Error processing wagers %v", err)
+	}
+	events, _ := memoryStore.GetWagerEvents(wager.ID)
+	if len(events) != 7 || events[6].Event != db.WagerEventPaid {
+		t.Errorf("Expected the wager to be paid after 7 events but got %v", events)
+	}
+}
+
+func TestWagerVoidedWithoutDeposit(t *testing.T) {
+	s, memoryStore, fakeChain := newTestMintingServer(t)
+	s.PaymentWallet = newTestWallet()
+
+	// the zombie side deposited in time but the hunter side never did
+	fightID, wager := newTestWager(t, s, memoryStore, func(wager *db.Wager) {
+		wager.ExpiresDate = time.Now().Add(-time.Minute)
+	})
+	deposits, _ := memoryStore.GetWagerDeposits(wager.ID)
+	zombieDeposit := fakeChain.Pay("addr_zombie_wallet", deposits[0].PaymentAddress, 50000000)
+	err := memoryStore.PayWagerDeposit(context.Background(), deposits[0].ID, db.FightPayment{TxHash: zombieDeposit.TxHash, SenderAddress: "addr_zombie_wallet", Lovelace: 50000000})
+	if err != nil {
+		t.Fatalf("Error paying deposit %v", err)
+	}
+	fakeChain.Pay("addr_hunter_wallet", deposits[1].PaymentAddress, 50000000)
+
+	if err := s.processWagers(); err != nil {
+		t.Fatalf("Error processing wagers %v", err)
+	}
+
+	voided, _ := memoryStore.GetWagerForFight(fightID)
+	if voided.Status != db.WagerStatusVoid {
+		t.Fatalf("Expected a void wager but got %s", voided.Status)
+	}
+	refunds, _ := memoryStore.GetRefundsByStatus(db.RefundStatusPending, 10)
+	if len(refunds) != 2 || refunds[0].PaymentAddressIndex.Int64 != int64(deposits[1].PaymentAddressIndex) || refunds[1].PaymentAddress != deposits[0].PaymentAddress {
+		t.Errorf("Expected the late deposit and the zombie side's stake to be owed back but got %v", refunds)
+	}
+
+	// the fight goes ahead without stakes
+	if waiting, _ := s.awaitingWager(fightID); waiting {
+		t.Errorf("Expected the fight not to wait on a void wager")
+	}
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/reliablestaking/zombie-fight-club-server/cip8"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"github.com/reliablestaking/zombie-fight-club-server/hdwallet"
+	"github.com/sirupsen/logrus"
+)
+
+// how long a wallet has to sign a nonce
+const walletLoginNonceTTL = 5 * time.Minute
+
+type (
+	// WalletLoginNonce a nonce for a stake address, the wallet signs the message with signData
+	WalletLoginNonce struct {
+		StakeAddress string    `json:"stakeAddress"`
+		Nonce        string    `json:"nonce"`
+		Message      string    `json:"message"`
+		ExpiresDate  time.Time `json:"expiresDate"`
+	}
+
+	// WalletLogin what signData returned for a nonce's message, signature is the COSE_Sign1 and key the COSE_Key
+	// both in hex
+	WalletLogin struct {
+		StakeAddress string `json:"stakeAddress"`
+		Nonce        string `json:"nonce"`
+		Signature    string `json:"signature"`
+		Key          string `json:"key"`
+	}
+)
+
+// CreateWalletLoginNonce hand out a nonce for a stake address to sign
+func (s Server) CreateWalletLoginNonce(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	request := new(WalletLoginNonce)
+	if err = c.Bind(request); err != nil {
+		log.WithError(err).Errorf("Error binding wallet login nonce")
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if !validStakeAddress(request.StakeAddress) {
+		return echo.NewHTTPError(http.StatusBadRequest, "Not a stake address")
+	}
+
+	nonceBytes := make([]byte, 32)
+	if _, err = rand.Read(nonceBytes); err != nil {
+		log.WithError(err).Error("Error generating nonce")
+		return s.RenderError("Error creating nonce", c)
+	}
+
+	nonce := db.LoginNonce{
+		Nonce:        hex.EncodeToString(nonceBytes),
+		StakeAddress: request.StakeAddress,
+		ExpiresDate:  time.Now().Add(walletLoginNonceTTL),
+	}
+	if err = s.Store.CreateLoginNonce(c.Request().Context(), nonce); err != nil {
+		log.WithError(err).Errorf("Error persisting nonce for %s", request.StakeAddress)
+		return s.RenderError("Error creating nonce", c)
+	}
+
+	return c.JSON(http.StatusOK, WalletLoginNonce{
+		StakeAddress: nonce.StakeAddress,
+		Nonce:        nonce.Nonce,
+		Message:      walletLoginMessage(nonce.StakeAddress, nonce.Nonce),
+		ExpiresDate:  nonce.ExpiresDate,
+	})
+}
+
+// LoginWithWallet verify a stake address signed its nonce, create a user tied to the stake address the first time and
+// start a session like nftkey.me login does
+func (s Server) LoginWithWallet(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	login := new(WalletLogin)
+	if err = c.Bind(login); err != nil {
+		log.WithError(err).Errorf("Error binding wallet login")
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+
+	if err = s.useWalletSignature(c, *login); err != nil {
+		return err
+	}
+
+	walletUser, err := s.Store.GetUserByStakeAddress(login.StakeAddress)
+	if err != nil {
+		log.WithError(err).Errorf("Error getting wallet user %s", login.StakeAddress)
+		return s.RenderError("Internal server error", c)
+	}
+	if walletUser == nil {
+		log.Infof("Wallet user not found, creating in db %s", login.StakeAddress)
+		if err = s.Store.InsertWalletUser(login.StakeAddress); err != nil {
+			log.WithError(err).Errorf("Error persisting wallet user %s", login.StakeAddress)
+			return s.RenderError("Internal server error", c)
+		}
+	}
+
+	return s.saveSession(c, sessionStakeAddress, login.StakeAddress)
+}
+
+// useWalletSignature checks the stake address signed its nonce's message and uses up the nonce
+func (s Server) useWalletSignature(c echo.Context, login WalletLogin) error {
+	log := logrus.WithContext(c.Request().Context())
+
+	coseSign1, err := hex.DecodeString(login.Signature)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Signature isn't hex")
+	}
+	coseKey, err := hex.DecodeString(login.Key)
+	if err != nil {
+		return echo.NewHTTPError(http.StatusBadRequest, "Key isn't hex")
+	}
+
+	signed, err := cip8.Verify(coseSign1, coseKey)
+	if err != nil {
+		log.WithError(err).Warnf("Invalid wallet login signature for %s", login.StakeAddress)
+		return echo.NewHTTPError(http.StatusUnauthorized, "Invalid signature")
+	}
+	stakeAddress, err := cip8.StakeAddress(signed.Address)
+	if err != nil || stakeAddress != login.StakeAddress {
+		log.Warnf("Wallet login for %s wasn't signed with that stake address", login.StakeAddress)
+		return echo.NewHTTPError(http.StatusUnauthorized, "Sign with your stake address")
+	}
+	if string(signed.Payload) != walletLoginMessage(login.StakeAddress, login.Nonce) {
+		log.Warnf("Wallet login for %s signed the wrong message", login.StakeAddress)
+		return echo.NewHTTPError(http.StatusUnauthorized, "Signed message doesn't match the nonce")
+	}
+
+	// only once the signature checks out, so the nonce can't be burned by someone else
+	used, err := s.Store.UseLoginNonce(c.Request().Context(), login.Nonce, login.StakeAddress, time.Now())
+	if err != nil {
+		log.WithError(err).Errorf("Error using nonce for %s", login.StakeAddress)
+		return s.RenderError("Internal server error", c)
+	}
+	if !used {
+		log.Warnf("Wallet login for %s with unknown, used or expired nonce", login.StakeAddress)
+		return echo.NewHTTPError(http.StatusUnauthorized, "Nonce is unknown, used or expired")
+	}
+
+	return nil
+}
+
+// LinkWalletAccount links a stake address the logged in user signed for to them, the nfts the index has there are
+// theirs from then on. It's taken off any other user it was linked to, proven or given by nftkey.me
+func (s Server) LinkWalletAccount(c echo.Context) (err error) {
+	log := logrus.WithContext(c.Request().Context())
+
+	dbUser := c.Get("user").(*db.User)
+
+	login := new(WalletLogin)
+	if err = c.Bind(login); err != nil {
+		log.WithError(err).Errorf("Error binding wallet account")
+		return echo.NewHTTPError(http.StatusBadRequest, err.Error())
+	}
+	if err = s.useWalletSignature(c, *login); err != nil {
+		return err
+	}
+
+	log.Infof("Linking account %s to user %d", login.StakeAddress, dbUser.ID)
+	if err = s.Store.AddUserAccount(dbUser.ID, login.StakeAddress); err != nil {
+		log.WithError(err).Errorf("Error linking account %s to user %d", login.StakeAddress, dbUser.ID)
+		return s.RenderError("Error linking account", c)
+	}
+
+	// their nfts are checked again on the next request rather than within the hour
+	if err = s.Store.SetLastAssetCheckTime(dbUser.ID, time.Time{}); err != nil {
+		log.WithError(err).Errorf("Error resetting asset check time of user %d", dbUser.ID)
+		return s.RenderError("Error linking account", c)
+	}
+
+	return c.JSON(http.StatusOK, nil)
+}
+
+// walletLoginMessage what the wallet signs, the stake address is in it so a signature can't be used for another one
+func walletLoginMessage(stakeAddress string, nonce string) string {
+	return fmt.Sprintf("Log in to Zombie Fight Club as %s\n\nNonce: %s", stakeAddress, nonce)
+}
+
+func validStakeAddress(stakeAddress string) bool {
+	_, data, err := hdwallet.Bech32Decode(stakeAddress)
+	if err != nil {
+		return false
+	}
+
+	// re-encoding also checks the human readable part matches the network
+	encoded, err := cip8.StakeAddress(data)
+	return err == nil && encoded == stakeAddress
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/sessions"
+	"github.com/labstack/echo-contrib/session"
+	"github.com/labstack/echo/v4"
+	"github.com/reliablestaking/zombie-fight-club-server/cip8"
+	db "github.com/reliablestaking/zombie-fight-club-server/db"
+	"golang.org/x/crypto/blake2b"
+)
+
+// newTestStakeKey a stake key and its mainnet reward address, raw and bech32
+func newTestStakeKey(seed string) (ed25519.PrivateKey, []byte, string) {
+	hash := sha256.Sum256([]byte(seed))
+	key := ed25519.NewKeyFromSeed(hash[:])
+	keyHash, _ := blake2b.New(28, nil)
+	keyHash.Write(key.Public().(ed25519.PublicKey))
+	address := append([]byte{0xe1}, keyHash.Sum(nil)...)
+	stakeAddress, _ := cip8.StakeAddress(address)
+	return key, address, stakeAddress
+}
+
+func TestLoginWithWallet(t *testing.T) {
+	memoryStore, _, _ := newTestStore(t)
+	s := Server{
+		Store:          memoryStore,
+		Chain:          newTestChain(),
+		ZombiePolicyId: testZombiePolicyID,
+		HunterPolicyId: testHunterPolicyID,
+	}
+
+	e := echo.New()
+	e.Use(session.Middleware(sessions.NewCookieStore([]byte("secret"))))
+	e.POST("/login/wallet/nonce", s.CreateWalletLoginNonce)
+	e.POST("/login/wallet", s.LoginWithWallet)
+	e.GET("/user/nfts", s.GetMyNfts, s.CheckCookie)
+	serve := func(method string, path string, body string, cookie string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		if cookie != "" {
+			req.Header.Set("Cookie", cookie)
+		}
+		rec := httptest.NewRecorder()
+		e.ServeHTTP(rec, req)
+		return rec
+	}
+
+	key, address, stakeAddress := newTestStakeKey("zombie wallet")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	memoryStore.SetNftHolder(context.Background(), zombie.ID, &db.NftHolder{Address: "addr_wallet", Account: stakeAddress}, time.Now())
+
+	if rec := serve(http.MethodPost, "/login/wallet/nonce", `{"stakeAddress":"addr1notastakeaddress"}`, ""); rec.Code != http.StatusBadRequest {
+		t.Errorf("Expected a bad request for a non stake address but got %d", rec.Code)
+	}
+	rec := serve(http.MethodPost, "/login/wallet/nonce", fmt.Sprintf(`{"stakeAddress":"%s"}`, stakeAddress), "")
+	nonce := WalletLoginNonce{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &nonce); err != nil || nonce.Nonce == "" || !strings.Contains(nonce.Message, nonce.Nonce) {
+		t.Fatalf("Expected a nonce to sign but got %d %s", rec.Code, rec.Body.String())
+	}
+
+	login := func(key ed25519.PrivateKey, address []byte, message string) *httptest.ResponseRecorder {
+		coseSign1, coseKey := cip8.Sign(key, address, []byte(message))
+		body := fmt.Sprintf(`{"stakeAddress":"%s","nonce":"%s","signature":"%x","key":"%x"}`, stakeAddress, nonce.Nonce, coseSign1, coseKey)
+		return serve(http.MethodPost, "/login/wallet", body, "")
+	}
+
+	// another wallet can't sign for the stake address and the message has to be the nonce's
+	otherKey, otherAddress, _ := newTestStakeKey("other wallet")
+	if rec := login(otherKey, otherAddress, nonce.Message); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected another stake address to be turned away but got %d", rec.Code)
+	}
+	if rec := login(key, address, "something else"); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected the wrong message to be turned away but got %d", rec.Code)
+	}
+
+	rec = login(key, address, nonce.Message)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("Expected to log in but got %d %s", rec.Code, rec.Body.String())
+	}
+	user, _ := memoryStore.GetUserByStakeAddress(stakeAddress)
+	if user == nil || user.ServiceAccount() {
+		t.Fatalf("Expected a wallet user for %s but got %v", stakeAddress, user)
+	}
+
+	// the nonce only works once
+	if rec := login(key, address, nonce.Message); rec.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a used nonce to be turned away but got %d", rec.Code)
+	}
+
+	// the session gets the nfts the index has at the wallet's stake address
+	rec = serve(http.MethodGet, "/user/nfts", "", rec.Header().Get("Set-Cookie"))
+	nfts := make([]Nft, 0)
+	if err := json.Unmarshal(rec.Body.Bytes(), &nfts); err != nil || len(nfts) != 1 || nfts[0].Name != "ZombieChains00001" {
+		t.Errorf("Expected the zombie held by the stake address but got %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestLinkWalletAccount(t *testing.T) {
+	memoryStore, zombieOwner, hunterOwner := newTestStore(t)
+	s := Server{
+		Store:          memoryStore,
+		Chain:          newTestChain(),
+		ZombiePolicyId: testZombiePolicyID,
+		HunterPolicyId: testHunterPolicyID,
+	}
+
+	key, address, stakeAddress := newTestStakeKey("linked wallet")
+	zombie, _ := memoryStore.GetNftByName("ZombieChains00001")
+	memoryStore.SetNftHolder(context.Background(), zombie.ID, &db.NftHolder{Address: "addr_wallet", Account: stakeAddress}, time.Now())
+
+	link := func(user db.User, signingKey ed25519.PrivateKey, signingAddress []byte) error {
+		nonce := db.LoginNonce{Nonce: fmt.Sprintf("nonce-%d", user.ID), StakeAddress: stakeAddress, ExpiresDate: time.Now().Add(time.Minute)}
+		memoryStore.CreateLoginNonce(context.Background(), nonce)
+		coseSign1, coseKey := cip8.Sign(signingKey, signingAddress, []byte(walletLoginMessage(stakeAddress, nonce.Nonce)))
+		body := fmt.Sprintf(`{"stakeAddress":"%s","nonce":"%s","signature":"%x","key":"%x"}`, stakeAddress, nonce.Nonce, coseSign1, coseKey)
+		c, _ := newTestContext(http.MethodPost, "/user/accounts", body, user)
+		return s.LinkWalletAccount(c)
+	}
+
+	// someone else's wallet doesn't link the account
+	otherKey, otherAddress, _ := newTestStakeKey("other wallet")
+	err := link(hunterOwner, otherKey, otherAddress)
+	if httpErr, ok := err.(*echo.HTTPError); !ok || httpErr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected a signature by another wallet turned away but got %v", err)
+	}
+	if owns, _ := s.doesUserOwnNft(context.Background(), "ZombieChains00001", hunterOwner.ID); owns {
+		t.Errorf("Expected the zombie not linked without a signature")
+	}
+
+	if err := link(hunterOwner, key, address); err != nil {
+		t.Fatalf("Error linking account %v", err)
+	}
+	if owns, _ := s.doesUserOwnNft(context.Background(), "ZombieChains00001", hunterOwner.ID); !owns {
+		t.Errorf("Expected the zombie held in the signed for account")
+	}
+
+	// the account goes to whoever signed for it last
+	if err := link(zombieOwner, key, address); err != nil {
+		t.Fatalf("Error linking account %v", err)
+	}
+	if owns, _ := s.doesUserOwnNft(context.Background(), "ZombieChains00001", hunterOwner.ID); owns {
+		t.Errorf("Expected the account taken off the user that signed for it before")
+	}
+	if owns, _ := s.doesUserOwnNft(context.Background(), "ZombieChains00001", zombieOwner.ID); !owns {
+		t.Errorf("Expected the zombie held by the user that signed for it last")
+	}
+}
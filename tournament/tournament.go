@@ -0,0 +1,423 @@
+// Package tournament brackets for single elimination, double elimination and round robin tournaments
+package tournament
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// Format how a tournament's matches are laid out
+	Format string
+
+	// Source where a match slot's entry comes from, a seed or the winner or loser of an earlier match
+	Source string
+
+	// Match one pairing in a bracket. Entries are ids, 0 is nobody, either because the slot isn't known yet or
+	// because it's a bye. A decided match without a winner had nobody to fight.
+	Match struct {
+		Number  int
+		Bracket string
+		Round   int
+		Top     Source
+		Bottom  Source
+
+		TopEntry    int
+		BottomEntry int
+		Winner      int
+		Loser       int
+		Decided     bool
+	}
+)
+
+const (
+	FormatSingleElimination Format = "SINGLE_ELIMINATION"
+	FormatDoubleElimination Format = "DOUBLE_ELIMINATION"
+	FormatRoundRobin        Format = "ROUND_ROBIN"
+
+	BracketWinners    = "WINNERS"
+	BracketLosers     = "LOSERS"
+	BracketFinal      = "FINAL"
+	BracketRoundRobin = "ROUND_ROBIN"
+
+	sourceSeed   = "seed"
+	sourceWinner = "winner"
+	sourceLoser  = "loser"
+
+	// biggest brackets, round robin grows with the square of its size
+	maxEliminationSize = 64
+	maxRoundRobinSize  = 16
+)
+
+// ParseFormat the format for its name
+func ParseFormat(name string) (Format, error) {
+	format := Format(strings.ToUpper(name))
+	switch format {
+	case FormatSingleElimination, FormatDoubleElimination, FormatRoundRobin:
+		return format, nil
+	}
+	return "", fmt.Errorf("Unknown tournament format %s", name)
+}
+
+// Seed source for the entry seeded n, 1 being the best
+func Seed(n int) Source {
+	return Source(fmt.Sprintf("%s:%d", sourceSeed, n))
+}
+
+// WinnerOf source for the winner of a match
+func WinnerOf(match int) Source {
+	return Source(fmt.Sprintf("%s:%d", sourceWinner, match))
+}
+
+// LoserOf source for the loser of a match
+func LoserOf(match int) Source {
+	return Source(fmt.Sprintf("%s:%d", sourceLoser, match))
+}
+
+func (s Source) parse() (string, int, error) {
+	parts := strings.SplitN(string(s), ":", 2)
+	if len(parts) != 2 {
+		return "", 0, fmt.Errorf("Invalid source %s", s)
+	}
+	n, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", 0, fmt.Errorf("Invalid source %s", s)
+	}
+	return parts[0], n, nil
+}
+
+// ValidateSize checks a bracket of size can be laid out in the format, elimination brackets are a power of two
+func ValidateSize(format Format, size int) error {
+	switch format {
+	case FormatSingleElimination, FormatDoubleElimination:
+		minimum := 2
+		if format == FormatDoubleElimination {
+			minimum = 4
+		}
+		if size < minimum || size > maxEliminationSize || size&(size-1) != 0 {
+			return fmt.Errorf("A %s bracket has to be a power of two from %d to %d, not %d", format, minimum, maxEliminationSize, size)
+		}
+	case FormatRoundRobin:
+		if size < 2 || size > maxRoundRobinSize {
+			return fmt.Errorf("A %s has to have 2 to %d entries, not %d", format, maxRoundRobinSize, size)
+		}
+	default:
+		return fmt.Errorf("Unknown tournament format %s", format)
+	}
+	return nil
+}
+
+// BracketSize smallest bracket that fits the entries, elimination brackets round up to a power of two and the rest of
+// the seeds are byes
+func BracketSize(format Format, entries int) int {
+	if format == FormatRoundRobin {
+		return entries
+	}
+
+	size := 2
+	if format == FormatDoubleElimination {
+		size = 4
+	}
+	for size < entries {
+		size *= 2
+	}
+	return size
+}
+
+// Generate lays out every match of a bracket of size, numbered from 1 in the order they can be played
+func Generate(format Format, size int) ([]Match, error) {
+	if err := ValidateSize(format, size); err != nil {
+		return nil, err
+	}
+
+	switch format {
+	case FormatSingleElimination:
+		matches, _ := winnersBracket(size)
+		return matches, nil
+	case FormatDoubleElimination:
+		return doubleElimination(size), nil
+	}
+	return roundRobin(size), nil
+}
+
+// seedOrder seeds in bracket order so the best seeds meet last, i.e. 1 8 4 5 2 7 3 6 for 8
+func seedOrder(size int) []int {
+	order := []int{1, 2}
+	for len(order) < size {
+		next := make([]int, 0, len(order)*2)
+		for _, seed := range order {
+			next = append(next, seed, len(order)*2+1-seed)
+		}
+		order = next
+	}
+	return order
+}
+
+// winnersBracket single elimination bracket, with the match numbers of each round
+func winnersBracket(size int) ([]Match, [][]int) {
+	matches := make([]Match, 0, size-1)
+	rounds := make([][]int, 0)
+
+	order := seedOrder(size)
+	round := make([]int, 0)
+	for i := 0; i < size; i += 2 {
+		matches = append(matches, Match{Number: len(matches) + 1, Bracket: BracketWinners, Round: 1, Top: Seed(order[i]), Bottom: Seed(order[i+1])})
+		round = append(round, len(matches))
+	}
+	rounds = append(rounds, round)
+
+	for len(round) > 1 {
+		next := make([]int, 0, len(round)/2)
+		for i := 0; i < len(round); i += 2 {
+			matches = append(matches, Match{Number: len(matches) + 1, Bracket: BracketWinners, Round: len(rounds) + 1, Top: WinnerOf(round[i]), Bottom: WinnerOf(round[i+1])})
+			next = append(next, len(matches))
+		}
+		round = next
+		rounds = append(rounds, round)
+	}
+
+	return matches, rounds
+}
+
+// doubleElimination winners bracket, then a losers bracket taking everyone who loses once, then a single grand final
+// between both bracket winners. Losers from the winners bracket drop in against the losers bracket in reverse order
+// to put off rematches.
+func doubleElimination(size int) []Match {
+	matches, winnerRounds := winnersBracket(size)
+
+	add := func(round int, top Source, bottom Source) int {
+		matches = append(matches, Match{Number: len(matches) + 1, Bracket: BracketLosers, Round: round, Top: top, Bottom: bottom})
+		return len(matches)
+	}
+
+	// losers of the first round play each other
+	round := make([]int, 0)
+	for i := 0; i < len(winnerRounds[0]); i += 2 {
+		round = append(round, add(1, LoserOf(winnerRounds[0][i]), LoserOf(winnerRounds[0][i+1])))
+	}
+
+	for i := 1; i < len(winnerRounds); i++ {
+		// survivors meet the losers of the next winners round
+		dropping := winnerRounds[i]
+		next := make([]int, 0, len(round))
+		for j, match := range round {
+			next = append(next, add(2*i, WinnerOf(match), LoserOf(dropping[len(dropping)-1-j])))
+		}
+		round = next
+
+		// then play each other down to one
+		if len(round) > 1 {
+			next = make([]int, 0, len(round)/2)
+			for j := 0; j < len(round); j += 2 {
+				next = append(next, add(2*i+1, WinnerOf(round[j]), WinnerOf(round[j+1])))
+			}
+			round = next
+		}
+	}
+
+	winnersFinal := winnerRounds[len(winnerRounds)-1][0]
+	matches = append(matches, Match{Number: len(matches) + 1, Bracket: BracketFinal, Round: 1, Top: WinnerOf(winnersFinal), Bottom: WinnerOf(round[0])})
+
+	return matches
+}
+
+// roundRobin every seed meets every other seed once, rounds by the circle method
+func roundRobin(size int) []Match {
+	seats := size
+	if seats%2 == 1 {
+		seats++
+	}
+
+	// seat 0 stays put, everyone else rotates, a seat past size sits the round out
+	rotation := make([]int, seats)
+	for i := range rotation {
+		rotation[i] = i + 1
+	}
+
+	matches := make([]Match, 0, size*(size-1)/2)
+	for round := 1; round < seats; round++ {
+		for i := 0; i < seats/2; i++ {
+			top, bottom := rotation[i], rotation[seats-1-i]
+			if top > size || bottom > size {
+				continue
+			}
+			if top > bottom {
+				top, bottom = bottom, top
+			}
+			matches = append(matches, Match{Number: len(matches) + 1, Bracket: BracketRoundRobin, Round: round, Top: Seed(top), Bottom: Seed(bottom)})
+		}
+		rotation = append([]int{rotation[0], rotation[seats-1]}, rotation[1:seats-1]...)
+	}
+
+	return matches
+}
+
+// Advance fills in entries from the seeds, seeds[0] being seed 1, and from decided matches, and decides matches
+// with nobody or one entry to fight. In elimination a lone entry goes through, in round robin nobody wins.
+func Advance(format Format, matches []Match, seeds []int) error {
+	byNumber := make(map[int]*Match)
+	for i := range matches {
+		byNumber[matches[i].Number] = &matches[i]
+	}
+
+	resolve := func(source Source) (int, bool, error) {
+		kind, n, err := source.parse()
+		if err != nil {
+			return 0, false, err
+		}
+		if kind == sourceSeed {
+			if n >= 1 && n <= len(seeds) {
+				return seeds[n-1], true, nil
+			}
+			return 0, true, nil
+		}
+
+		match, found := byNumber[n]
+		if !found {
+			return 0, false, fmt.Errorf("Source %s refers to a match that doesn't exist", source)
+		}
+		if !match.Decided {
+			return 0, false, nil
+		}
+		if kind == sourceWinner {
+			return match.Winner, true, nil
+		} else if kind == sourceLoser {
+			return match.Loser, true, nil
+		}
+		return 0, false, fmt.Errorf("Invalid source %s", source)
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for i := range matches {
+			match := &matches[i]
+			if match.Decided {
+				continue
+			}
+
+			top, topKnown, err := resolve(match.Top)
+			if err != nil {
+				return err
+			}
+			bottom, bottomKnown, err := resolve(match.Bottom)
+			if err != nil {
+				return err
+			}
+			match.TopEntry, match.BottomEntry = top, bottom
+			if !topKnown || !bottomKnown || (top != 0 && bottom != 0) {
+				continue
+			}
+
+			match.Decided = true
+			if format != FormatRoundRobin {
+				match.Winner = top + bottom
+			}
+			changed = true
+		}
+	}
+
+	return nil
+}
+
+// Ready whether the match has two entries and is waiting on a fight
+func (m Match) Ready() bool {
+	return !m.Decided && m.TopEntry != 0 && m.BottomEntry != 0
+}
+
+// ZombieEntry the entry fighting with its zombie, the other fights with its hunter. The top entry has the zombie in
+// odd numbered matches so everyone gets both sides over a tournament.
+func (m Match) ZombieEntry() int {
+	if m.Number%2 == 1 {
+		return m.TopEntry
+	}
+	return m.BottomEntry
+}
+
+// Decide records the winner of a fought match
+func (m *Match) Decide(winner int) error {
+	if !m.Ready() {
+		return fmt.Errorf("Match %d isn't waiting on a fight", m.Number)
+	}
+	if winner != m.TopEntry && winner != m.BottomEntry {
+		return fmt.Errorf("Entry %d isn't in match %d", winner, m.Number)
+	}
+
+	m.Winner, m.Loser, m.Decided = winner, m.TopEntry+m.BottomEntry-winner, true
+	return nil
+}
+
+// Places entries in finishing order once every match is decided, nil before. Elimination places the final's winner
+// and loser, round robin everyone by wins then seed.
+func Places(format Format, matches []Match, seeds []int) []int {
+	for _, match := range matches {
+		if !match.Decided {
+			return nil
+		}
+	}
+
+	places := make([]int, 0)
+	if format != FormatRoundRobin {
+		final := matches[len(matches)-1]
+		for _, entry := range []int{final.Winner, final.Loser} {
+			if entry != 0 {
+				places = append(places, entry)
+			}
+		}
+		return places
+	}
+
+	wins := make(map[int]int)
+	for _, match := range matches {
+		if match.Winner != 0 {
+			wins[match.Winner]++
+		}
+	}
+	for _, entry := range seeds {
+		if entry != 0 {
+			places = append(places, entry)
+		}
+	}
+	sort.SliceStable(places, func(i, j int) bool {
+		return wins[places[i]] > wins[places[j]]
+	})
+	return places
+}
+
+// Prizes splits a prize pool by percent per place, shares for places nobody took and rounding go to first place
+func Prizes(pool int64, split []int, placed int) []int64 {
+	if placed == 0 {
+		return nil
+	}
+	if placed > len(split) {
+		placed = len(split)
+	}
+
+	prizes := make([]int64, placed)
+	paid := int64(0)
+	for i := 1; i < placed; i++ {
+		prizes[i] = pool * int64(split[i]) / 100
+		paid += prizes[i]
+	}
+	prizes[0] = pool - paid
+	return prizes
+}
+
+// ParseSplit reads a prize split of comma separated percents per place, which have to add up to 100
+func ParseSplit(split string) ([]int, error) {
+	percents := make([]int, 0)
+	total := 0
+	for _, part := range strings.Split(split, ",") {
+		percent, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || percent <= 0 {
+			return nil, fmt.Errorf("Invalid prize split %s", split)
+		}
+		percents = append(percents, percent)
+		total += percent
+	}
+	if total != 100 {
+		return nil, fmt.Errorf("Prize split %s adds up to %d percent", split, total)
+	}
+	return percents, nil
+}
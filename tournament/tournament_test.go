@@ -0,0 +1,180 @@
+package tournament
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// play runs a bracket to the end with the better seed, the lower entry, always winning
+func play(t *testing.T, format Format, matches []Match, seeds []int) int {
+	fought := 0
+	for {
+		if err := Advance(format, matches, seeds); err != nil {
+			t.Fatalf("Error advancing %v", err)
+		}
+
+		ready := false
+		for i := range matches {
+			if !matches[i].Ready() {
+				continue
+			}
+			winner := matches[i].TopEntry
+			if matches[i].BottomEntry < winner {
+				winner = matches[i].BottomEntry
+			}
+			if err := matches[i].Decide(winner); err != nil {
+				t.Fatalf("Error deciding match %v", err)
+			}
+			ready = true
+			fought++
+		}
+		if !ready {
+			return fought
+		}
+	}
+}
+
+func entries(n int) []int {
+	seeds := make([]int, n)
+	for i := range seeds {
+		seeds[i] = i + 1
+	}
+	return seeds
+}
+
+func TestSingleElimination(t *testing.T) {
+	matches, err := Generate(FormatSingleElimination, 8)
+	if err != nil {
+		t.Fatalf("Error generating %v", err)
+	}
+	if len(matches) != 7 {
+		t.Fatalf("Expected 7 matches but got %d", len(matches))
+	}
+
+	// best seeds meet last
+	firstRound := make([]Source, 0)
+	for _, match := range matches[:4] {
+		firstRound = append(firstRound, match.Top, match.Bottom)
+	}
+	expected := []Source{Seed(1), Seed(8), Seed(4), Seed(5), Seed(2), Seed(7), Seed(3), Seed(6)}
+	if !reflect.DeepEqual(firstRound, expected) {
+		t.Errorf("Expected first round %v but got %v", expected, firstRound)
+	}
+
+	seeds := entries(8)
+	if fought := play(t, FormatSingleElimination, matches, seeds); fought != 7 {
+		t.Errorf("Expected 7 fights but got %d", fought)
+	}
+	if places := Places(FormatSingleElimination, matches, seeds); !reflect.DeepEqual(places, []int{1, 2}) {
+		t.Errorf("Expected seed 1 then 2 but got %v", places)
+	}
+}
+
+func TestSingleEliminationByes(t *testing.T) {
+	matches, _ := Generate(FormatSingleElimination, BracketSize(FormatSingleElimination, 5))
+
+	// three byes, the top three seeds go straight through
+	seeds := entries(5)
+	if fought := play(t, FormatSingleElimination, matches, seeds); fought != 4 {
+		t.Errorf("Expected 4 fights for 5 entries but got %d", fought)
+	}
+	if places := Places(FormatSingleElimination, matches, seeds); !reflect.DeepEqual(places, []int{1, 2}) {
+		t.Errorf("Expected seed 1 then 2 but got %v", places)
+	}
+}
+
+func TestDoubleElimination(t *testing.T) {
+	for _, size := range []int{4, 8, 16} {
+		matches, err := Generate(FormatDoubleElimination, size)
+		if err != nil {
+			t.Fatalf("Error generating %v", err)
+		}
+
+		// everyone but the champion loses twice, less the final's loser who only lost once
+		if len(matches) != 2*size-2 {
+			t.Errorf("Expected %d matches for %d but got %d", 2*size-2, size, len(matches))
+		}
+
+		seeds := entries(size)
+		play(t, FormatDoubleElimination, matches, seeds)
+		if places := Places(FormatDoubleElimination, matches, seeds); !reflect.DeepEqual(places, []int{1, 2}) {
+			t.Errorf("Expected seed 1 then 2 for %d but got %v", size, places)
+		}
+
+		// every entry but the champion is out after its second loss
+		losses := make(map[int]int)
+		for _, match := range matches {
+			losses[match.Loser]++
+		}
+		for _, seed := range seeds[2:] {
+			if losses[seed] != 2 {
+				t.Errorf("Expected seed %d to lose twice in %d but lost %d", seed, size, losses[seed])
+			}
+		}
+	}
+}
+
+func TestRoundRobin(t *testing.T) {
+	matches, err := Generate(FormatRoundRobin, 5)
+	if err != nil {
+		t.Fatalf("Error generating %v", err)
+	}
+	if len(matches) != 10 {
+		t.Fatalf("Expected 10 matches but got %d", len(matches))
+	}
+
+	// every pair once, nobody twice in a round
+	pairs := make(map[string]bool)
+	rounds := make(map[string]bool)
+	for _, match := range matches {
+		pair := fmt.Sprintf("%s-%s", match.Top, match.Bottom)
+		if pairs[pair] {
+			t.Errorf("Pair %s plays twice", pair)
+		}
+		pairs[pair] = true
+
+		for _, source := range []Source{match.Top, match.Bottom} {
+			key := fmt.Sprintf("%d-%s", match.Round, source)
+			if rounds[key] {
+				t.Errorf("%s plays twice in round %d", source, match.Round)
+			}
+			rounds[key] = true
+		}
+	}
+
+	seeds := []int{10, 20, 30, 40, 50}
+	play(t, FormatRoundRobin, matches, seeds)
+	if places := Places(FormatRoundRobin, matches, seeds); !reflect.DeepEqual(places, seeds) {
+		t.Errorf("Expected places %v but got %v", seeds, places)
+	}
+}
+
+func TestValidateSize(t *testing.T) {
+	for _, invalid := range []struct {
+		format Format
+		size   int
+	}{{FormatSingleElimination, 6}, {FormatSingleElimination, 128}, {FormatDoubleElimination, 2}, {FormatRoundRobin, 17}, {"SWISS", 8}} {
+		if ValidateSize(invalid.format, invalid.size) == nil {
+			t.Errorf("Expected %s of %d to be invalid", invalid.format, invalid.size)
+		}
+	}
+}
+
+func TestPrizes(t *testing.T) {
+	split, err := ParseSplit("70, 30")
+	if err != nil {
+		t.Fatalf("Error parsing split %v", err)
+	}
+
+	if prizes := Prizes(100000001, split, 2); !reflect.DeepEqual(prizes, []int64{70000001, 30000000}) {
+		t.Errorf("Expected rounding to go to first but got %v", prizes)
+	}
+	if prizes := Prizes(100000000, split, 1); !reflect.DeepEqual(prizes, []int64{100000000}) {
+		t.Errorf("Expected the whole pool to go to first but got %v", prizes)
+	}
+
+	if _, err := ParseSplit("70,20"); err == nil {
+		t.Error("Expected a split not adding up to 100 to be invalid")
+	}
+}
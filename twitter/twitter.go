@@ -30,6 +30,9 @@ func TweetFight(alienPath string, fightPath string, tweetText string) (string, e
 	rSecret := os.Getenv("RESOURCE_SECRET")
 	tokenKey := os.Getenv("TOKEN_KEY")
 	tokenSecret := os.Getenv("TOKEN_SECRET")
+	if rKey == "" || tokenKey == "" {
+		return "", fmt.Errorf("Twitter credentials not configured")
+	}
 
 	// authenticate
 	config := oauth1.NewConfig(rKey, rSecret)
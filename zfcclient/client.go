@@ -0,0 +1,122 @@
+// Package zfcclient is a typed client of the zombie fight club http api, the methods and types are generated from
+// api/openapi.yaml
+package zfcclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+)
+
+type (
+	// ZfcClient struct to hold client, the HttpClient needs a cookie jar to keep a login session
+	ZfcClient struct {
+		HttpClient  http.Client
+		BaseUrl     string
+		BearerToken string
+	}
+
+	// ResponseError a response that wasn't a success
+	ResponseError struct {
+		StatusCode int
+		Message    string
+		Body       []byte
+	}
+)
+
+// NewClientFromEnvironment create new zfc client using env vars
+func NewClientFromEnvironment() ZfcClient {
+	httpClient := &http.Client{
+		Timeout: time.Second * 30,
+	}
+
+	client := ZfcClient{
+		HttpClient:  *httpClient,
+		BaseUrl:     os.Getenv("ZFC_API_URL"),
+		BearerToken: os.Getenv("ZFC_API_TOKEN"),
+	}
+
+	return client
+}
+
+func (e *ResponseError) Error() string {
+	return fmt.Sprintf("Request failed with status code %d: %s", e.StatusCode, e.Message)
+}
+
+// do sends the body as json and decodes the response into out, a response that isn't a 2xx is a *ResponseError
+func (client ZfcClient) do(ctx context.Context, method string, path string, query url.Values, body interface{}, out interface{}) error {
+	requestURL := client.BaseUrl + path
+	if len(query) > 0 {
+		requestURL += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewBuffer(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, requestURL, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if client.BearerToken != "" {
+		req.Header.Set("Authorization", "Bearer "+client.BearerToken)
+	}
+
+	resp, err := client.HttpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return newResponseError(resp.StatusCode, respBody)
+	}
+
+	respBody = bytes.TrimSpace(respBody)
+	if out == nil || len(respBody) == 0 || string(respBody) == "null" {
+		return nil
+	}
+	return json.Unmarshal(respBody, out)
+}
+
+// newResponseError reads the message out of the handlers' error bodies, {"message": ...} from echo and {"Error": ...} from
+// RenderError, anything else is the message as it is
+func newResponseError(statusCode int, body []byte) *ResponseError {
+	e := &ResponseError{StatusCode: statusCode, Body: body}
+
+	message := struct {
+		Message string `json:"message"`
+		Error   string `json:"Error"`
+	}{}
+	if json.Unmarshal(body, &message) == nil && (message.Message != "" || message.Error != "") {
+		e.Message = message.Message
+		if e.Message == "" {
+			e.Message = message.Error
+		}
+		return e
+	}
+
+	e.Message = string(bytes.TrimSpace(body))
+	return e
+}
@@ -0,0 +1,3 @@
+package zfcclient
+
+//go:generate go run ../api/gen -out zfcclient.gen.go
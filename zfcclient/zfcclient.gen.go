@@ -0,0 +1,837 @@
+// Code generated by go run ./api/gen. DO NOT EDIT.
+
+package zfcclient
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type (
+	// HTTPError why a request was turned away
+	HTTPError struct {
+		Message string `json:"message"`
+	}
+
+	// Error what went wrong on the server
+	Error struct {
+		Error string `json:"Error"`
+	}
+
+	// FightLimitError why a fight can't be created yet and when to try again
+	FightLimitError struct {
+		Code              string    `json:"code"`
+		Message           string    `json:"message"`
+		RetryAfterSeconds int       `json:"retryAfterSeconds"`
+		RetryAfter        time.Time `json:"retryAfter"`
+	}
+
+	// Version build version info
+	Version struct {
+		Sha       string `json:"sha"`
+		BuildTime string `json:"buildTime"`
+	}
+
+	// WalletLoginNonceRequest the stake address to log in as
+	WalletLoginNonceRequest struct {
+		StakeAddress string `json:"stakeAddress"`
+	}
+
+	// WalletLoginNonce a nonce for a stake address, the wallet signs the message with signData
+	WalletLoginNonce struct {
+		StakeAddress string    `json:"stakeAddress"`
+		Nonce        string    `json:"nonce"`
+		Message      string    `json:"message"`
+		ExpiresDate  time.Time `json:"expiresDate"`
+	}
+
+	// WalletLogin what signData returned for a nonce's message, signature is the COSE_Sign1 and key the COSE_Key both in hex
+	WalletLogin struct {
+		StakeAddress string `json:"stakeAddress"`
+		Nonce        string `json:"nonce"`
+		Signature    string `json:"signature"`
+		Key          string `json:"key"`
+	}
+
+	// Nft a zombie or hunter with its record
+	Nft struct {
+		ID   int    `json:"ID"`
+		Name string `json:"name"`
+		Type string `json:"type"`
+		// ipfs hash of the nft's image
+		Image string `json:"image"`
+		// what the owner is paid out of every fight it's in, only for listed nfts
+		ListedPriceAda  *int     `json:"listedPriceAda,omitempty"`
+		UserOwns        bool     `json:"userOwns"`
+		Wins            int      `json:"wins"`
+		Loses           int      `json:"loses"`
+		Rating          *float64 `json:"rating,omitempty"`
+		RatingDeviation *float64 `json:"ratingDeviation,omitempty"`
+	}
+
+	// ListNftRequest an nft to list and its list price, between 0 and 1000 ada
+	ListNftRequest struct {
+		Name           string `json:"name"`
+		ListedPriceAda int    `json:"listedPriceAda"`
+	}
+
+	// CreateFightRequest the zombie and hunter to fight
+	CreateFightRequest struct {
+		ZombieName string `json:"zombieName"`
+		HunterName string `json:"hunterName"`
+	}
+
+	// Fight a fight as its creator sees it
+	Fight struct {
+		ID                    int        `json:"ID"`
+		ZombieName            string     `json:"zombieName"`
+		HunterName            string     `json:"hunterName"`
+		PaymentAmountLovelace int64      `json:"paymentAmountLovelace"`
+		PaymentAmountAda      string     `json:"paymentAmountAda"`
+		PaymentAddress        string     `json:"paymentAddress"`
+		Status                string     `json:"status"`
+		CreatedDate           *time.Time `json:"createdDate"`
+		MintedDate            *time.Time `json:"mintedDate,omitempty"`
+		FightIPFS             string     `json:"fightIPFS,omitempty"`
+		AlienIPFS             string     `json:"alienIPFS,omitempty"`
+		MinutesUntilExpired   *int       `json:"minutesUntilExpired,omitempty"`
+		Winner                string     `json:"winner"`
+		Loser                 string     `json:"loser"`
+		TweetLink             string     `json:"tweetLink"`
+		HunterSendAddress     string     `json:"HunterSendAddress"`
+		ZombieSendAddress     string     `json:"ZombieSendAddress"`
+		Seed                  *FightSeed `json:"seed,omitempty"`
+	}
+
+	// FightSeed commit-reveal proof for a fight's outcome, the secret is revealed once the outcome is decided
+	FightSeed struct {
+		Commitment    string `json:"commitment"`
+		Secret        string `json:"secret,omitempty"`
+		PaymentTxHash string `json:"paymentTxHash,omitempty"`
+		Seed          string `json:"seed,omitempty"`
+	}
+
+	// CombatLog how a fight played out, round by round
+	CombatLog struct {
+		Version        int        `json:"version"`
+		ZombieStrength int        `json:"zombieStrength"`
+		HunterStrength int        `json:"hunterStrength"`
+		Breakdown      *Breakdown `json:"breakdown,omitempty"`
+		Rounds         []Round    `json:"rounds"`
+		Winner         string     `json:"winner"`
+		ZombieHealth   int        `json:"zombieHealth"`
+		HunterHealth   int        `json:"hunterHealth"`
+		ZombieLifeBar  int        `json:"zombieLifeBar"`
+		HunterLifeBar  int        `json:"hunterLifeBar"`
+		ZombieKo       bool       `json:"zombieKo"`
+		HunterKo       bool       `json:"hunterKo"`
+		ZombieBeatup   bool       `json:"zombieBeatup"`
+		HunterBeatup   bool       `json:"hunterBeatup"`
+	}
+
+	// Breakdown how both strengths in a fight were reached
+	Breakdown struct {
+		RulesVersion   int        `json:"rulesVersion"`
+		RuleSet        string     `json:"ruleSet"`
+		ZombieStrength int        `json:"zombieStrength"`
+		HunterStrength int        `json:"hunterStrength"`
+		Modifiers      []Modifier `json:"modifiers"`
+	}
+
+	// Modifier one rule that changed a side's strength
+	Modifier struct {
+		Side        string `json:"side"`
+		Source      string `json:"source"`
+		Description string `json:"description"`
+		Value       int    `json:"value"`
+	}
+
+	// Round the attacks in one round, in the order they happened
+	Round struct {
+		Number  int      `json:"number"`
+		Attacks []Attack `json:"attacks"`
+	}
+
+	// Attack one fighter's swing at the other and both healths after it
+	Attack struct {
+		Attacker     string `json:"attacker"`
+		Action       string `json:"action"`
+		Damage       int    `json:"damage"`
+		ZombieHealth int    `json:"zombieHealth"`
+		HunterHealth int    `json:"hunterHealth"`
+	}
+
+	// Refund a payment owed back to whoever sent it
+	Refund struct {
+		TxHash        string         `json:"txHash"`
+		OutputIndex   int            `json:"outputIndex"`
+		Address       string         `json:"address"`
+		Lovelace      int64          `json:"lovelace"`
+		Assets        []PaymentAsset `json:"assets,omitempty"`
+		Status        string         `json:"status"`
+		Attempts      int            `json:"attempts"`
+		RefundTxHash  string         `json:"refundTxHash,omitempty"`
+		CreatedDate   time.Time      `json:"createdDate"`
+		ConfirmedDate *time.Time     `json:"confirmedDate,omitempty"`
+	}
+
+	// PaymentAsset quantity of a policy id + hex asset name unit
+	PaymentAsset struct {
+		Unit     string `json:"unit"`
+		Quantity string `json:"quantity"`
+	}
+
+	// MatchmakingRequest the nft to queue
+	MatchmakingRequest struct {
+		NftName string `json:"nftName"`
+	}
+
+	// MatchmakingEntry a queued nft, with its fight once matched
+	MatchmakingEntry struct {
+		ID          int       `json:"id"`
+		NftName     string    `json:"nftName"`
+		NftType     string    `json:"nftType"`
+		Status      string    `json:"status"`
+		CreatedDate time.Time `json:"createdDate"`
+		ExpiresDate time.Time `json:"expiresDate"`
+		Fight       *Fight    `json:"fight,omitempty"`
+	}
+
+	// ChallengeRequest my nft, the nft it challenges and the stake offered
+	ChallengeRequest struct {
+		NftName         string `json:"nftName"`
+		OpponentNftName string `json:"opponentNftName"`
+		StakeAda        *int   `json:"stakeAda,omitempty"`
+	}
+
+	// ChallengeCounterRequest the stake offered instead
+	ChallengeCounterRequest struct {
+		StakeAda int `json:"stakeAda"`
+	}
+
+	// Challenge a challenge I sent or received
+	Challenge struct {
+		ID                int       `json:"id"`
+		Direction         string    `json:"direction"`
+		ChallengerNftName string    `json:"challengerNftName"`
+		OpponentNftName   string    `json:"opponentNftName"`
+		StakeLovelace     int64     `json:"stakeLovelace"`
+		StakeAda          string    `json:"stakeAda"`
+		Status            string    `json:"status"`
+		AwaitingResponse  bool      `json:"awaitingResponse"`
+		CreatedDate       time.Time `json:"createdDate"`
+		ExpiresDate       time.Time `json:"expiresDate"`
+		FightID           *int      `json:"fightId,omitempty"`
+		Fight             *Fight    `json:"fight,omitempty"`
+		Wager             *Wager    `json:"wager,omitempty"`
+	}
+
+	// Wager what one side sees of the wager on their challenge's fight
+	Wager struct {
+		Status            string    `json:"status"`
+		StakeLovelace     int64     `json:"stakeLovelace"`
+		StakeAda          string    `json:"stakeAda"`
+		HouseFeePercent   int       `json:"houseFeePercent"`
+		DepositAddress    string    `json:"depositAddress"`
+		Deposited         bool      `json:"deposited"`
+		OpponentDeposited bool      `json:"opponentDeposited"`
+		ExpiresDate       time.Time `json:"expiresDate"`
+		WinnerSide        string    `json:"winnerSide,omitempty"`
+		PayoutLovelace    *int64    `json:"payoutLovelace,omitempty"`
+		PayoutTxHash      string    `json:"payoutTxHash,omitempty"`
+	}
+
+	// Tournament a tournament and how far along it is
+	Tournament struct {
+		ID                int        `json:"id"`
+		Name              string     `json:"name"`
+		Format            string     `json:"format"`
+		Status            string     `json:"status"`
+		BracketSize       int        `json:"bracketSize"`
+		EntryFeeLovelace  int64      `json:"entryFeeLovelace"`
+		EntryFeeAda       string     `json:"entryFeeAda"`
+		PrizeSplit        []int      `json:"prizeSplit"`
+		RegistrationStart time.Time  `json:"registrationStart"`
+		RegistrationEnd   time.Time  `json:"registrationEnd"`
+		PaidEntries       int        `json:"paidEntries"`
+		CollectedLovelace int64      `json:"collectedLovelace"`
+		PayoutTxHash      string     `json:"payoutTxHash,omitempty"`
+		StartedDate       *time.Time `json:"startedDate,omitempty"`
+		FinishedDate      *time.Time `json:"finishedDate,omitempty"`
+	}
+
+	// TournamentDetail a tournament with its paid entries and bracket
+	TournamentDetail struct {
+		Tournament
+		Entries []TournamentEntry `json:"entries"`
+		Matches []TournamentMatch `json:"matches"`
+	}
+
+	// TournamentEntry a paid entry, seeded once the tournament starts and placed once it finishes
+	TournamentEntry struct {
+		ID         int    `json:"id"`
+		ZombieName string `json:"zombieName"`
+		HunterName string `json:"hunterName"`
+		Seed       *int   `json:"seed,omitempty"`
+		Place      *int   `json:"place,omitempty"`
+	}
+
+	// TournamentMatch one match of the bracket, entries are entry ids
+	TournamentMatch struct {
+		Number      int    `json:"number"`
+		Bracket     string `json:"bracket"`
+		Round       int    `json:"round"`
+		TopEntry    *int   `json:"topEntry,omitempty"`
+		BottomEntry *int   `json:"bottomEntry,omitempty"`
+		ZombieEntry *int   `json:"zombieEntry,omitempty"`
+		Winner      *int   `json:"winner,omitempty"`
+		Decided     bool   `json:"decided"`
+		FightID     *int   `json:"fightId,omitempty"`
+		FightStatus string `json:"fightStatus,omitempty"`
+	}
+
+	// TournamentEntryRequest the zombie and hunter to enter with
+	TournamentEntryRequest struct {
+		ZombieName string `json:"zombieName"`
+		HunterName string `json:"hunterName"`
+	}
+
+	// TournamentEntryPayment where and how much to pay for a new entry
+	TournamentEntryPayment struct {
+		ID                    int    `json:"id"`
+		TournamentID          int    `json:"tournamentId"`
+		ZombieName            string `json:"zombieName"`
+		HunterName            string `json:"hunterName"`
+		PaymentAddress        string `json:"paymentAddress"`
+		PaymentAmountLovelace int64  `json:"paymentAmountLovelace"`
+		PaymentAmountAda      string `json:"paymentAmountAda"`
+		MinutesUntilExpired   int    `json:"minutesUntilExpired"`
+	}
+
+	// Leaders the nfts with the most wins, most losses and best win percent
+	Leaders struct {
+		MostWins    []Nft `json:"mostWins"`
+		MostLoses   []Nft `json:"mostLoses"`
+		BestPercent []Nft `json:"bestPercent"`
+	}
+
+	// RatingLeaders the nfts with the best glicko-2 ratings, ranked by rating less twice the deviation
+	RatingLeaders struct {
+		Rating []Nft `json:"rating"`
+	}
+
+	// SeasonLeaders leaderboards from records in a season, with the final standings once the season is closed
+	SeasonLeaders struct {
+		Leaders
+		Season    Season           `json:"season"`
+		Standings []SeasonStanding `json:"standings,omitempty"`
+	}
+
+	// Season a stretch of time with its own records and leaderboards
+	Season struct {
+		ID         int        `json:"id"`
+		Name       string     `json:"name"`
+		StartDate  time.Time  `json:"startDate"`
+		EndDate    time.Time  `json:"endDate"`
+		ClosedDate *time.Time `json:"closedDate,omitempty"`
+	}
+
+	// SeasonStanding an nft's final place in a closed season, rated as it was when the season closed
+	SeasonStanding struct {
+		Rank int `json:"rank"`
+		Nft
+	}
+
+	// AdminFight a fight as operators see it, with the status the engine has it in
+	AdminFight struct {
+		ID                    int        `json:"id"`
+		Status                string     `json:"status"`
+		ZombieName            string     `json:"zombieName"`
+		HunterName            string     `json:"hunterName"`
+		CreatedDate           time.Time  `json:"createdDate"`
+		MintedDate            *time.Time `json:"mintedDate,omitempty"`
+		PaymentAddress        string     `json:"paymentAddress"`
+		PaymentAmountLovelace int64      `json:"paymentAmountLovelace"`
+		IncomingUtxo          string     `json:"incomingUtxo,omitempty"`
+		ZombieSendAddress     string     `json:"zombieSendAddress,omitempty"`
+		HunterSendAddress     string     `json:"hunterSendAddress,omitempty"`
+		SignedTxHash          string     `json:"signedTxHash,omitempty"`
+		TxID                  string     `json:"txId,omitempty"`
+		TournamentID          *int64     `json:"tournamentId,omitempty"`
+		RegenerateImages      bool       `json:"regenerateImages"`
+	}
+
+	// AdminFightAction why an operator is acting on a fight
+	AdminFightAction struct {
+		Reason string `json:"reason"`
+	}
+
+	// AdminRefund a utxo to send back to whoever sent it, the address index is needed for a fight's own address
+	AdminRefund struct {
+		TxHash              string `json:"txHash"`
+		OutputIndex         int    `json:"outputIndex"`
+		PaymentAddress      string `json:"paymentAddress"`
+		PaymentAddressIndex *int64 `json:"paymentAddressIndex,omitempty"`
+	}
+
+	// AdminFightMetadata the metadata the mint tx has, or will have, for the fight and its alien
+	AdminFightMetadata struct {
+		Fight json.RawMessage `json:"fight"`
+		Alien json.RawMessage `json:"alien"`
+	}
+
+	// AdminUserRoleRequest the role to give, empty takes it away
+	AdminUserRoleRequest struct {
+		Role string `json:"role"`
+	}
+
+	// AdminUserRole a user's role on the admin api, empty for players
+	AdminUserRole struct {
+		UserID int    `json:"userId"`
+		Role   string `json:"role"`
+	}
+
+	// AdminAudit one change made through the admin api
+	AdminAudit struct {
+		ID          int       `json:"id"`
+		UserID      int       `json:"userId"`
+		Action      string    `json:"action"`
+		Target      string    `json:"target,omitempty"`
+		Detail      string    `json:"detail,omitempty"`
+		StatusCode  int       `json:"statusCode"`
+		CreatedDate time.Time `json:"createdDate"`
+	}
+
+	// EngineHealth whether the engines are running and how much work is waiting on them
+	EngineHealth struct {
+		Healthy bool           `json:"healthy"`
+		Engines []EngineStatus `json:"engines"`
+		// fights by status
+		Fights map[string]int `json:"fights"`
+		// refunds by status
+		Refunds          map[string]int `json:"refunds"`
+		OldestQueuedDate *time.Time     `json:"oldestQueuedDate,omitempty"`
+		OldestStagedDate *time.Time     `json:"oldestStagedDate,omitempty"`
+		OldestMintedDate *time.Time     `json:"oldestMintedDate,omitempty"`
+	}
+
+	// EngineStatus an engine's latest pass, it's healthy if it had a good pass in the last 5 minutes
+	EngineStatus struct {
+		Engine              string     `json:"engine"`
+		Healthy             bool       `json:"healthy"`
+		LastPassDate        time.Time  `json:"lastPassDate"`
+		LastSuccessDate     *time.Time `json:"lastSuccessDate,omitempty"`
+		ConsecutiveFailures int        `json:"consecutiveFailures"`
+	}
+
+	// LoginParams the query parameters of Login
+	LoginParams struct {
+		// auth code nftkey.me redirected back with
+		Code  string
+		State string
+	}
+
+	// GetNftsToFightParams the query parameters of GetNftsToFight
+	GetNftsToFightParams struct {
+		Type string
+		// only my own nfts of the type
+		Owned *bool
+		// only listed nfts with this name
+		Name string
+	}
+
+	// GetLeadersParams the query parameters of GetLeaders
+	GetLeadersParams struct {
+		Board string
+		// a season id, or current for the season running now
+		Season string
+	}
+
+	// GetAdminFightsParams the query parameters of GetAdminFights
+	GetAdminFightsParams struct {
+		Status string
+		// between 1 and 500, 50 by default
+		Limit *int
+	}
+
+	// GetAdminAuditParams the query parameters of GetAdminAudit
+	GetAdminAuditParams struct {
+		// between 1 and 500, 50 by default
+		Limit *int
+	}
+)
+
+// Login exchange an nftkey.me auth code for a session
+func (client ZfcClient) Login(ctx context.Context, params LoginParams) error {
+	query := url.Values{}
+	if params.Code != "" {
+		query.Set("code", params.Code)
+	}
+	if params.State != "" {
+		query.Set("state", params.State)
+	}
+	return client.do(ctx, http.MethodPost, "/login", query, nil, nil)
+}
+
+// LoginCheck check the session is still good
+func (client ZfcClient) LoginCheck(ctx context.Context) error {
+	return client.do(ctx, http.MethodPost, "/login/check", nil, nil, nil)
+}
+
+// CreateWalletLoginNonce get a nonce for a stake address to sign with the wallet
+func (client ZfcClient) CreateWalletLoginNonce(ctx context.Context, body WalletLoginNonceRequest) (*WalletLoginNonce, error) {
+	out := new(WalletLoginNonce)
+	err := client.do(ctx, http.MethodPost, "/login/wallet/nonce", nil, body, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LoginWithWallet log in with a signed nonce
+func (client ZfcClient) LoginWithWallet(ctx context.Context, body WalletLogin) error {
+	return client.do(ctx, http.MethodPost, "/login/wallet", nil, body, nil)
+}
+
+// GetNftsToFight find nfts available to fight
+func (client ZfcClient) GetNftsToFight(ctx context.Context, params GetNftsToFightParams) ([]Nft, error) {
+	var out []Nft
+	query := url.Values{}
+	if params.Type != "" {
+		query.Set("type", params.Type)
+	}
+	if params.Owned != nil {
+		query.Set("owned", strconv.FormatBool(*params.Owned))
+	}
+	if params.Name != "" {
+		query.Set("name", params.Name)
+	}
+	err := client.do(ctx, http.MethodGet, "/fightnfts", query, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateFight create a fight between a listed zombie and hunter
+func (client ZfcClient) CreateFight(ctx context.Context, body CreateFightRequest) (*Fight, error) {
+	out := new(Fight)
+	err := client.do(ctx, http.MethodPost, "/fights", nil, body, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetFightById get one of my fights
+func (client ZfcClient) GetFightById(ctx context.Context, fightID int) (*Fight, error) {
+	out := new(Fight)
+	err := client.do(ctx, http.MethodGet, fmt.Sprintf("/fights/%d", fightID), nil, nil, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetFightLog get how a minted fight played out, round by round
+func (client ZfcClient) GetFightLog(ctx context.Context, fightID int) (*CombatLog, error) {
+	out := new(CombatLog)
+	err := client.do(ctx, http.MethodGet, fmt.Sprintf("/fights/%d/log", fightID), nil, nil, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMyNfts get the nfts I hold
+func (client ZfcClient) GetMyNfts(ctx context.Context) ([]Nft, error) {
+	var out []Nft
+	err := client.do(ctx, http.MethodGet, "/user/nfts", nil, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ListNftForFight list one of my nfts to fight, or change its list price
+func (client ZfcClient) ListNftForFight(ctx context.Context, name string, body ListNftRequest) (*Nft, error) {
+	out := new(Nft)
+	err := client.do(ctx, http.MethodPut, fmt.Sprintf("/user/nfts/%s", url.PathEscape(name)), nil, body, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteListedNft delist one of my nfts
+func (client ZfcClient) DeleteListedNft(ctx context.Context, name string) error {
+	return client.do(ctx, http.MethodDelete, fmt.Sprintf("/user/nfts/%s", url.PathEscape(name)), nil, nil, nil)
+}
+
+// GetMyFights get my fights
+func (client ZfcClient) GetMyFights(ctx context.Context) ([]Fight, error) {
+	var out []Fight
+	err := client.do(ctx, http.MethodGet, "/user/fights", nil, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMyRefunds get the payments owed back to me
+func (client ZfcClient) GetMyRefunds(ctx context.Context) ([]Refund, error) {
+	var out []Refund
+	err := client.do(ctx, http.MethodGet, "/user/refunds", nil, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LinkWalletAccount link a stake address I signed for with my wallet
+func (client ZfcClient) LinkWalletAccount(ctx context.Context, body WalletLogin) error {
+	return client.do(ctx, http.MethodPost, "/user/accounts", nil, body, nil)
+}
+
+// GetMyMatchmaking get my queued and matched nfts
+func (client ZfcClient) GetMyMatchmaking(ctx context.Context) ([]MatchmakingEntry, error) {
+	var out []MatchmakingEntry
+	err := client.do(ctx, http.MethodGet, "/user/matchmaking", nil, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// QueueForMatchmaking queue a zombie or hunter to be paired with a listed opponent
+func (client ZfcClient) QueueForMatchmaking(ctx context.Context, body MatchmakingRequest) (*MatchmakingEntry, error) {
+	out := new(MatchmakingEntry)
+	err := client.do(ctx, http.MethodPost, "/user/matchmaking", nil, body, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteMatchmakingEntry take a queued nft out of the queue
+func (client ZfcClient) DeleteMatchmakingEntry(ctx context.Context, entryID int) error {
+	return client.do(ctx, http.MethodDelete, fmt.Sprintf("/user/matchmaking/%d", entryID), nil, nil, nil)
+}
+
+// GetMyChallenges get challenges I sent or received
+func (client ZfcClient) GetMyChallenges(ctx context.Context) ([]Challenge, error) {
+	var out []Challenge
+	err := client.do(ctx, http.MethodGet, "/user/challenges", nil, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateChallenge challenge another owner's nft
+func (client ZfcClient) CreateChallenge(ctx context.Context, body ChallengeRequest) (*Challenge, error) {
+	out := new(Challenge)
+	err := client.do(ctx, http.MethodPost, "/user/challenges", nil, body, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// AcceptChallenge accept a challenge, creates the fight for the challenger to pay
+func (client ZfcClient) AcceptChallenge(ctx context.Context, challengeID int) (*Challenge, error) {
+	out := new(Challenge)
+	err := client.do(ctx, http.MethodPost, fmt.Sprintf("/user/challenges/%d/accept", challengeID), nil, nil, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeclineChallenge decline a challenge
+func (client ZfcClient) DeclineChallenge(ctx context.Context, challengeID int) (*Challenge, error) {
+	out := new(Challenge)
+	err := client.do(ctx, http.MethodPost, fmt.Sprintf("/user/challenges/%d/decline", challengeID), nil, nil, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CounterChallenge counter a challenge with a different stake
+func (client ZfcClient) CounterChallenge(ctx context.Context, challengeID int, body ChallengeCounterRequest) (*Challenge, error) {
+	out := new(Challenge)
+	err := client.do(ctx, http.MethodPost, fmt.Sprintf("/user/challenges/%d/counter", challengeID), nil, body, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CancelChallenge take back a challenge I sent
+func (client ZfcClient) CancelChallenge(ctx context.Context, challengeID int) error {
+	return client.do(ctx, http.MethodDelete, fmt.Sprintf("/user/challenges/%d", challengeID), nil, nil, nil)
+}
+
+// GetTournaments get every tournament
+func (client ZfcClient) GetTournaments(ctx context.Context) ([]Tournament, error) {
+	var out []Tournament
+	err := client.do(ctx, http.MethodGet, "/tournaments", nil, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetTournamentById get a tournament with its entries and bracket
+func (client ZfcClient) GetTournamentById(ctx context.Context, tournamentID int) (*TournamentDetail, error) {
+	out := new(TournamentDetail)
+	err := client.do(ctx, http.MethodGet, fmt.Sprintf("/tournaments/%d", tournamentID), nil, nil, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CreateTournamentEntry enter a zombie and hunter, paid to the tournament's address
+func (client ZfcClient) CreateTournamentEntry(ctx context.Context, tournamentID int, body TournamentEntryRequest) (*TournamentEntryPayment, error) {
+	out := new(TournamentEntryPayment)
+	err := client.do(ctx, http.MethodPost, fmt.Sprintf("/tournaments/%d/entries", tournamentID), nil, body, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetLeaders get the leaderboards
+func (client ZfcClient) GetLeaders(ctx context.Context, params GetLeadersParams) (json.RawMessage, error) {
+	var out json.RawMessage
+	query := url.Values{}
+	if params.Board != "" {
+		query.Set("board", params.Board)
+	}
+	if params.Season != "" {
+		query.Set("season", params.Season)
+	}
+	err := client.do(ctx, http.MethodGet, "/leaders", query, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetEngineHealth engine heartbeats and the work waiting on them
+func (client ZfcClient) GetEngineHealth(ctx context.Context) (*EngineHealth, error) {
+	out := new(EngineHealth)
+	err := client.do(ctx, http.MethodGet, "/admin/health", nil, nil, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAdminFights list fights in a status, oldest first
+func (client ZfcClient) GetAdminFights(ctx context.Context, params GetAdminFightsParams) ([]AdminFight, error) {
+	var out []AdminFight
+	query := url.Values{}
+	if params.Status != "" {
+		query.Set("status", params.Status)
+	}
+	if params.Limit != nil {
+		query.Set("limit", strconv.Itoa(*params.Limit))
+	}
+	err := client.do(ctx, http.MethodGet, "/admin/fights", query, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetFightMetadata rebuild the fight's mint metadata
+func (client ZfcClient) GetFightMetadata(ctx context.Context, fightID int) (*AdminFightMetadata, error) {
+	out := new(AdminFightMetadata)
+	err := client.do(ctx, http.MethodGet, fmt.Sprintf("/admin/fights/%d/metadata", fightID), nil, nil, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RetryFight drop a stuck signed mint tx, or confirm a minted fight
+func (client ZfcClient) RetryFight(ctx context.Context, fightID int) (*AdminFight, error) {
+	out := new(AdminFight)
+	err := client.do(ctx, http.MethodPost, fmt.Sprintf("/admin/fights/%d/retry", fightID), nil, nil, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// CancelFight void a pending or queued fight and refund it
+func (client ZfcClient) CancelFight(ctx context.Context, fightID int, body AdminFightAction) (*AdminFight, error) {
+	out := new(AdminFight)
+	err := client.do(ctx, http.MethodPost, fmt.Sprintf("/admin/fights/%d/cancel", fightID), nil, body, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RegenerateFightImages have the minter rebuild a staged fight's images
+func (client ZfcClient) RegenerateFightImages(ctx context.Context, fightID int) (*AdminFight, error) {
+	out := new(AdminFight)
+	err := client.do(ctx, http.MethodPost, fmt.Sprintf("/admin/fights/%d/images", fightID), nil, nil, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ForceRefund owe a utxo at a payment address back to its sender
+func (client ZfcClient) ForceRefund(ctx context.Context, body AdminRefund) (*Refund, error) {
+	out := new(Refund)
+	err := client.do(ctx, http.MethodPost, "/admin/refunds", nil, body, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetAdminAudit the latest changes made through the admin api
+func (client ZfcClient) GetAdminAudit(ctx context.Context, params GetAdminAuditParams) ([]AdminAudit, error) {
+	var out []AdminAudit
+	query := url.Values{}
+	if params.Limit != nil {
+		query.Set("limit", strconv.Itoa(*params.Limit))
+	}
+	err := client.do(ctx, http.MethodGet, "/admin/audit", query, nil, &out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SetUserRole give or take away a role
+func (client ZfcClient) SetUserRole(ctx context.Context, userID int, body AdminUserRoleRequest) (*AdminUserRole, error) {
+	out := new(AdminUserRole)
+	err := client.do(ctx, http.MethodPut, fmt.Sprintf("/admin/users/%d/role", userID), nil, body, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetVersion build version info
+func (client ZfcClient) GetVersion(ctx context.Context) (*Version, error) {
+	out := new(Version)
+	err := client.do(ctx, http.MethodGet, "/version", nil, nil, out)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
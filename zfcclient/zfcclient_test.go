@@ -0,0 +1,28 @@
+package zfcclient
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/reliablestaking/zombie-fight-club-server/api"
+)
+
+func TestGeneratedClientIsCurrent(t *testing.T) {
+	doc, err := api.Load()
+	if err != nil {
+		t.Fatalf("Error loading api spec %v", err)
+	}
+	src, err := api.GenerateClient(doc, "zfcclient")
+	if err != nil {
+		t.Fatalf("Error generating client %v", err)
+	}
+
+	generated, err := os.ReadFile("zfcclient.gen.go")
+	if err != nil {
+		t.Fatalf("Error reading generated client %v", err)
+	}
+	if !bytes.Equal(src, generated) {
+		t.Errorf("zfcclient.gen.go is out of date with api/openapi.yaml, run go generate ./zfcclient")
+	}
+}